@@ -0,0 +1,452 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// handleCommand 处理用户直接输入的控制命令
+// 命令必须以 / 开头，不经过 LLM，直接由 Loop 执行并返回结果；handled 为 false 时按普通消息处理
+func (l *Loop) handleCommand(msg *bus.InboundMessage) (response string, handled bool) {
+	content := strings.TrimSpace(msg.Content)
+	if !strings.HasPrefix(content, "/") {
+		return "", false
+	}
+	fields := strings.Fields(content[1:])
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "help":
+		if len(fields) != 1 {
+			return "", false
+		}
+		return l.buildHelp(), true
+	case "task":
+		return l.handleTaskCommand(msg, fields[1:])
+	case "temp", "temperature":
+		return l.handleTemperatureCommand(l.sessions.ResolveKey(msg.SessionKey()), fields[1:])
+	case "maxtokens":
+		return l.handleMaxTokensCommand(l.sessions.ResolveKey(msg.SessionKey()), fields[1:])
+	case "fork":
+		return l.handleForkCommand(msg.SessionKey(), fields[1:])
+	case "reasoning":
+		return l.handleReasoningCommand(l.sessions.ResolveKey(msg.SessionKey()), fields[1:])
+	case "toolstats":
+		if len(fields) != 1 {
+			return "", false
+		}
+		return formatToolStats(ToolStatsSnapshot()), true
+	case "pin":
+		return l.handlePinCommand(l.sessions.ResolveKey(msg.SessionKey()), strings.TrimSpace(content[len("/"+fields[0]):]))
+	case "unpin":
+		return l.handleUnpinCommand(l.sessions.ResolveKey(msg.SessionKey()), fields[1:])
+	case "stop":
+		// 正常情况下 /stop 在入队前已被拦截，走到这里说明没有需要取消的回合
+		if len(fields) != 1 {
+			return "", false
+		}
+		return "当前没有进行中的回合", true
+	case "clear":
+		if len(fields) != 1 {
+			return "", false
+		}
+		return l.handleClearCommand(l.sessions.ResolveKey(msg.SessionKey()))
+	default:
+		return "", false
+	}
+}
+
+// handleTaskCommand 处理后台任务命令，如 "/task cancel 000123"、"/task reply 000123 <回复>"
+// 只能操作当前聊天发起的任务，其他聊天的任务按不存在处理
+func (l *Loop) handleTaskCommand(msg *bus.InboundMessage, args []string) (string, bool) {
+	if len(args) < 2 {
+		return "", false
+	}
+	action := strings.ToLower(args[0])
+	if (action != "cancel" || len(args) != 2) && (action != "reply" || len(args) < 3) {
+		return "", false
+	}
+	if l.taskManager == nil {
+		return "错误: 任务管理器未配置", true
+	}
+
+	taskID := args[1]
+	if owned, found := l.taskManager.IsTaskOrigin(taskID, msg.Channel, msg.ChatID); found && !owned {
+		return "任务不存在", true
+	}
+	if action == "reply" {
+		if _, err := l.taskManager.ResumeTask(context.Background(), taskID, strings.Join(args[2:], " ")); err != nil {
+			return fmt.Sprintf("回复任务失败: %s", err), true
+		}
+		return fmt.Sprintf("已收到回复，任务 %s 继续执行", taskID), true
+	}
+
+	stopped, status, err := l.taskManager.StopTask(taskID)
+	if err != nil {
+		return fmt.Sprintf("取消任务失败: %s", err), true
+	}
+	if !stopped {
+		return fmt.Sprintf("任务 %s 已结束，状态: %s", taskID, status), true
+	}
+	return fmt.Sprintf("任务 %s 已取消", taskID), true
+}
+
+// prepareRegenerate 处理 "/retry" 和 "/edit <新内容>"
+// 回退会话中最后一轮对话后返回需要重新处理的消息；ok 为 false 表示不是这两个命令，reply 非空时直接回复用户
+func (l *Loop) prepareRegenerate(ctx context.Context, msg *bus.InboundMessage, sessionKey string) (regenerated *bus.InboundMessage, reply string, ok bool) {
+	content := strings.TrimSpace(msg.Content)
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return nil, "", false
+	}
+
+	var newContent string
+	switch strings.ToLower(fields[0]) {
+	case "/retry":
+		if len(fields) != 1 {
+			return nil, "", false
+		}
+	case "/edit":
+		newContent = strings.TrimSpace(content[len(fields[0]):])
+		if newContent == "" {
+			return nil, "用法: /edit <新的消息内容>", true
+		}
+	default:
+		return nil, "", false
+	}
+
+	if l.sessions == nil {
+		return nil, "错误: 会话管理器未配置", true
+	}
+	lastUser, err := l.sessions.RewindLastTurn(ctx, sessionKey)
+	if err != nil {
+		return nil, fmt.Sprintf("无法重新生成: %s", err), true
+	}
+	if newContent == "" {
+		newContent = lastUser
+	}
+
+	l.logger.Info("重新生成上一轮回复",
+		zap.String("session_key", sessionKey),
+		zap.Bool("edited", newContent != lastUser),
+	)
+	regenerated = bus.NewInboundMessage(msg.Channel, msg.SenderID, msg.ChatID, newContent)
+	regenerated.Media = msg.Media
+	regenerated.Metadata = msg.Metadata
+	return regenerated, "", true
+}
+
+// handleForkCommand 处理会话分支命令
+//   - "/fork": 复制当前会话到新分支并切换过去
+//   - "/fork list": 列出当前会话的所有分支
+//   - "/fork switch <会话键|main>": 切换到指定分支或主线
+func (l *Loop) handleForkCommand(baseKey string, args []string) (string, bool) {
+	if l.sessions == nil {
+		return "错误: 会话管理器未配置", true
+	}
+
+	switch {
+	case len(args) == 0:
+		newKey, err := l.sessions.Fork(context.Background(), baseKey)
+		if err != nil {
+			return fmt.Sprintf("创建分支失败: %s", err), true
+		}
+		return fmt.Sprintf("已创建分支 %s 并切换过去，之后的对话不影响原会话。使用 /fork switch main 切回主线", newKey), true
+
+	case len(args) == 1 && strings.ToLower(args[0]) == "list":
+		keys, err := l.sessions.ListBranches(context.Background(), baseKey)
+		if err != nil {
+			return fmt.Sprintf("列出分支失败: %s", err), true
+		}
+		current := l.sessions.ResolveKey(baseKey)
+		var sb strings.Builder
+		sb.WriteString("会话分支:")
+		for _, key := range keys {
+			marker := ""
+			if key == current {
+				marker = "（当前）"
+			}
+			name := key
+			if key == baseKey {
+				name = "main"
+			}
+			fmt.Fprintf(&sb, "\n  %s%s", name, marker)
+		}
+		return sb.String(), true
+
+	case len(args) == 2 && strings.ToLower(args[0]) == "switch":
+		target := args[1]
+		if strings.ToLower(target) == "main" {
+			target = baseKey
+		}
+		if err := l.sessions.Switch(context.Background(), baseKey, target); err != nil {
+			return fmt.Sprintf("切换分支失败: %s", err), true
+		}
+		return fmt.Sprintf("已切换到 %s", args[1]), true
+	}
+	return "", false
+}
+
+// handleClearCommand 处理 "/clear"，清空当前会话
+func (l *Loop) handleClearCommand(sessionKey string) (string, bool) {
+	if l.sessions == nil {
+		return "错误: 会话管理器未配置", true
+	}
+	path, err := l.sessions.Clear(context.Background(), sessionKey)
+	if err != nil {
+		return fmt.Sprintf("清空会话失败: %s", err), true
+	}
+	if path != "" {
+		return fmt.Sprintf("会话已清空，之前的对话已保存到 %s", path), true
+	}
+	return "会话已清空", true
+}
+
+// formatToolStats 格式化工具调用统计
+func formatToolStats(stats []ToolStats) string {
+	if len(stats) == 0 {
+		return "暂无工具调用记录"
+	}
+	var sb strings.Builder
+	sb.WriteString("工具调用统计:")
+	for _, s := range stats {
+		fmt.Fprintf(&sb, "\n  %s - 调用 %d 次，平均耗时 %s，最长 %s，失败 %d 次（%.0f%%）",
+			s.Name, s.Calls, s.AvgLatency().Round(time.Millisecond), s.MaxLatency.Round(time.Millisecond), s.Failures, s.ErrorRate()*100)
+	}
+	return sb.String()
+}
+
+// handlePinCommand 处理 "/pin <内容>"，置顶一条笔记；"/pin" 列出当前会话的置顶笔记
+func (l *Loop) handlePinCommand(sessionKey, text string) (string, bool) {
+	if l.sessions == nil {
+		return "错误: 会话管理器未配置", true
+	}
+	if text == "" {
+		pins := l.sessions.Pins(sessionKey)
+		if len(pins) == 0 {
+			return "当前会话没有置顶笔记，使用 /pin <内容> 添加", true
+		}
+		var sb strings.Builder
+		sb.WriteString("置顶笔记:")
+		for i, pin := range pins {
+			fmt.Fprintf(&sb, "\n  %d. %s", i+1, pin)
+		}
+		return sb.String(), true
+	}
+
+	count, err := l.sessions.Pin(sessionKey, text)
+	if err != nil {
+		return fmt.Sprintf("置顶失败: %s", err), true
+	}
+	return fmt.Sprintf("已置顶（第 %d 条），之后的对话会始终参考这条笔记", count), true
+}
+
+// handleUnpinCommand 处理 "/unpin <编号>" 取消一条置顶笔记，"/unpin all" 取消全部
+func (l *Loop) handleUnpinCommand(sessionKey string, args []string) (string, bool) {
+	if len(args) != 1 {
+		return "用法: /unpin <编号|all>，编号可通过 /pin 查看", true
+	}
+	if l.sessions == nil {
+		return "错误: 会话管理器未配置", true
+	}
+	if strings.ToLower(args[0]) == "all" {
+		count, err := l.sessions.UnpinAll(sessionKey)
+		if err != nil {
+			return fmt.Sprintf("取消置顶失败: %s", err), true
+		}
+		return fmt.Sprintf("已取消全部 %d 条置顶笔记", count), true
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "错误: 编号必须是整数，可通过 /pin 查看", true
+	}
+	removed, err := l.sessions.Unpin(sessionKey, index)
+	if err != nil {
+		return fmt.Sprintf("取消置顶失败: %s", err), true
+	}
+	return fmt.Sprintf("已取消置顶: %s", removed), true
+}
+
+// 会话级模型参数的取值范围
+const (
+	minTemperature = 0.0
+	maxTemperature = 2.0
+	minMaxTokens   = 16
+	maxMaxTokens   = 131072
+)
+
+// handleTemperatureCommand 处理 "/temp 0.2"，设置当前会话的温度；"/temp reset" 恢复默认
+func (l *Loop) handleTemperatureCommand(sessionKey string, args []string) (string, bool) {
+	if len(args) > 1 {
+		return "", false
+	}
+	if l.sessions == nil {
+		return "错误: 会话管理器未配置", true
+	}
+	if len(args) == 0 {
+		if temperature, _ := l.sessions.ModelOverrides(sessionKey); temperature != nil {
+			return fmt.Sprintf("当前会话温度: %g", *temperature), true
+		}
+		return "当前会话温度: 默认", true
+	}
+	if isResetArg(args[0]) {
+		l.sessions.SetTemperature(sessionKey, nil)
+		return "已恢复默认温度", true
+	}
+
+	value, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || value < minTemperature || value > maxTemperature {
+		return fmt.Sprintf("错误: 温度必须是 %g 到 %g 之间的数字", minTemperature, maxTemperature), true
+	}
+	l.sessions.SetTemperature(sessionKey, &value)
+	return fmt.Sprintf("已将当前会话温度设置为 %g", value), true
+}
+
+// handleMaxTokensCommand 处理 "/maxtokens 2048"，设置当前会话的最大输出 token；"/maxtokens reset" 恢复默认
+func (l *Loop) handleMaxTokensCommand(sessionKey string, args []string) (string, bool) {
+	if len(args) > 1 {
+		return "", false
+	}
+	if l.sessions == nil {
+		return "错误: 会话管理器未配置", true
+	}
+	if len(args) == 0 {
+		if _, maxTokens := l.sessions.ModelOverrides(sessionKey); maxTokens > 0 {
+			return fmt.Sprintf("当前会话最大输出 token: %d", maxTokens), true
+		}
+		return "当前会话最大输出 token: 默认", true
+	}
+	if isResetArg(args[0]) {
+		l.sessions.SetMaxTokens(sessionKey, 0)
+		return "已恢复默认最大输出 token", true
+	}
+
+	value, err := strconv.Atoi(args[0])
+	if err != nil || value < minMaxTokens || value > maxMaxTokens {
+		return fmt.Sprintf("错误: 最大输出 token 必须是 %d 到 %d 之间的整数", minMaxTokens, maxMaxTokens), true
+	}
+	l.sessions.SetMaxTokens(sessionKey, value)
+	return fmt.Sprintf("已将当前会话最大输出 token 设置为 %d", value), true
+}
+
+// handleReasoningCommand 处理 "/reasoning on|off"，设置当前会话是否展示推理模型的思考过程；"/reasoning reset" 恢复全局配置
+func (l *Loop) handleReasoningCommand(sessionKey string, args []string) (string, bool) {
+	if len(args) > 1 {
+		return "", false
+	}
+	if l.sessions == nil {
+		return "错误: 会话管理器未配置", true
+	}
+	defaultShow := l.cfg != nil && l.cfg.ThinkingProcess.Reasoning
+	if len(args) == 0 {
+		if l.sessions.ShowReasoning(sessionKey, defaultShow) {
+			return "当前会话思考过程: 显示", true
+		}
+		return "当前会话思考过程: 隐藏", true
+	}
+
+	var show bool
+	switch strings.ToLower(args[0]) {
+	case "on", "show", "显示":
+		show = true
+	case "off", "hide", "隐藏":
+		show = false
+	default:
+		if !isResetArg(args[0]) {
+			return "错误: 用法 /reasoning <on|off|reset>", true
+		}
+		l.sessions.SetShowReasoning(sessionKey, nil)
+		return "已恢复默认的思考过程展示设置", true
+	}
+	l.sessions.SetShowReasoning(sessionKey, &show)
+	if show {
+		return "当前会话将展示模型的思考过程", true
+	}
+	return "当前会话将隐藏模型的思考过程", true
+}
+
+// isResetArg 判断参数是否表示恢复默认值
+func isResetArg(arg string) bool {
+	switch strings.ToLower(arg) {
+	case "reset", "default", "默认":
+		return true
+	}
+	return false
+}
+
+// commandHelp 可用命令及说明，与 handleCommand 支持的命令保持一致
+var commandHelp = [][2]string{
+	{"/help", "显示本帮助"},
+	{"/task cancel <任务ID>", "取消后台任务"},
+	{"/task reply <任务ID> <回复>", "回复等待输入的后台任务并继续执行"},
+	{"/temp <0-2|reset>", "设置当前会话的温度"},
+	{"/maxtokens <数量|reset>", "设置当前会话的最大输出 token"},
+	{"/fork [list|switch <分支|main>]", "创建、列出或切换会话分支"},
+	{"/reasoning <on|off|reset>", "设置当前会话是否展示推理模型的思考过程"},
+	{"/pin [内容]", "置顶一条始终保留在上下文中的笔记，不带内容时列出置顶笔记"},
+	{"/unpin <编号|all>", "取消置顶笔记"},
+	{"/stop", "停止当前正在处理的回合，执行中的命令随之终止"},
+	{"/clear", "清空当前会话，之后的对话不再加载之前的历史"},
+	{"/toolstats", "显示各工具的调用次数、平均耗时和失败率"},
+	{"/retry", "丢弃上一条回复并重新生成"},
+	{"/edit <新内容>", "修改上一条消息并重新生成回复"},
+	{"/json <消息>", "要求以 JSON 对象回复"},
+}
+
+// buildHelp 生成帮助信息，包含可用命令、已启用工具和已加载技能
+func (l *Loop) buildHelp() string {
+	var sb strings.Builder
+	sb.WriteString("可用命令:\n")
+	for _, cmd := range commandHelp {
+		fmt.Fprintf(&sb, "  %s - %s\n", cmd[0], cmd[1])
+	}
+
+	if l.tools != nil {
+		lines := make([]string, 0)
+		for _, baseTool := range l.tools.GetTools() {
+			info, err := baseTool.Info(context.Background())
+			if err != nil || info == nil || info.Name == "" {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("  %s - %s", info.Name, firstLine(info.Desc)))
+		}
+		if len(lines) > 0 {
+			sort.Strings(lines)
+			sb.WriteString("\n可用工具:\n")
+			sb.WriteString(strings.Join(lines, "\n"))
+			sb.WriteString("\n")
+		}
+	}
+
+	if l.context != nil {
+		loader := l.context.GetSkillsLoader()
+		skills := loader.ListSkills(false)
+		if len(skills) > 0 {
+			sort.Slice(skills, func(i, j int) bool { return skills[i].Name < skills[j].Name })
+			sb.WriteString("\n已加载技能:\n")
+			for _, s := range skills {
+				fmt.Fprintf(&sb, "  %s - %s\n", s.Name, firstLine(loader.getSkillDescription(s.Name)))
+			}
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// firstLine 返回文本的第一行，用于生成单行描述
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if idx := strings.IndexByte(s, '\n'); idx >= 0 {
+		return strings.TrimSpace(s[:idx])
+	}
+	return s
+}
@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/weibaohui/nanobot-go/agent/tools"
+	"github.com/weibaohui/nanobot-go/agent/tools/calculator"
+	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/session"
+	"go.uber.org/zap"
+)
+
+// TestLoop_HandleCommand 测试控制命令处理
+func TestLoop_HandleCommand(t *testing.T) {
+	t.Run("普通消息不处理", func(t *testing.T) {
+		l := &Loop{}
+		if _, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", "帮我写个脚本")); handled {
+			t.Error("普通消息不应被当作命令处理")
+		}
+	})
+
+	t.Run("不以 / 开头的消息不处理", func(t *testing.T) {
+		l := &Loop{taskManager: newTestTaskManager(t)}
+		for _, content := range []string{"clear", "help", "fork", "toolstats", "temp 0.5", "task cancel 7", "reasoning on"} {
+			if _, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", content)); handled {
+				t.Errorf("%q 不应被当作命令处理", content)
+			}
+		}
+	})
+
+	t.Run("取消不存在的任务", func(t *testing.T) {
+		l := &Loop{taskManager: newTestTaskManager(t)}
+		resp, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", "/task cancel 000123"))
+		if !handled {
+			t.Fatal("task cancel 应被当作命令处理")
+		}
+		if !strings.Contains(resp, "取消任务失败") {
+			t.Errorf("响应 = %q, 期望包含 取消任务失败", resp)
+		}
+	})
+
+	t.Run("取消运行中的任务", func(t *testing.T) {
+		m := newTestTaskManager(t)
+		m.runningTasks["000007"] = &AgentTask{id: "000007", status: TaskRunning, logCapacity: 10, channel: "cli", chatID: "default"}
+		l := &Loop{taskManager: m}
+
+		resp, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", "/task cancel 7"))
+		if !handled {
+			t.Fatal("task cancel 应被当作命令处理")
+		}
+		if resp != "任务 7 已取消" {
+			t.Errorf("响应 = %q, 期望 任务 7 已取消", resp)
+		}
+	})
+
+	t.Run("回复未等待输入的任务", func(t *testing.T) {
+		m := newTestTaskManager(t)
+		m.runningTasks["000008"] = &AgentTask{id: "000008", status: TaskRunning, logCapacity: 10, channel: "cli", chatID: "default"}
+		l := &Loop{taskManager: m}
+
+		resp, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", "/task reply 8 使用 main 分支"))
+		if !handled {
+			t.Fatal("task reply 应被当作命令处理")
+		}
+		if !strings.Contains(resp, "回复任务失败") {
+			t.Errorf("响应 = %q, 期望包含 回复任务失败", resp)
+		}
+	})
+
+	t.Run("不能操作其他聊天的任务", func(t *testing.T) {
+		m := newTestTaskManager(t)
+		m.runningTasks["000009"] = &AgentTask{id: "000009", status: TaskWaitingInput, logCapacity: 10, channel: "feishu", chatID: "oc_other"}
+		l := &Loop{taskManager: m}
+
+		for _, content := range []string{"/task cancel 9", "/task reply 9 继续"} {
+			resp, handled := l.handleCommand(bus.NewInboundMessage("feishu", "user", "oc_mine", content))
+			if !handled || resp != "任务不存在" {
+				t.Errorf("%q: handleCommand() = (%q, %v), 期望 任务不存在", content, resp, handled)
+			}
+		}
+		if status := m.runningTasks["000009"].status; status != TaskWaitingInput {
+			t.Errorf("任务状态 = %q, 不应被其他聊天修改", status)
+		}
+	})
+
+	t.Run("任务管理器未配置", func(t *testing.T) {
+		l := &Loop{}
+		resp, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", "/task cancel 1"))
+		if !handled || resp != "错误: 任务管理器未配置" {
+			t.Errorf("handleCommand() = (%q, %v)", resp, handled)
+		}
+	})
+}
+
+// TestLoop_HandleHelp 测试 /help 命令
+func TestLoop_HandleHelp(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&calculator.Tool{})
+	l := &Loop{tools: registry, context: NewContextBuilder(t.TempDir())}
+
+	resp, handled := l.handleCommand(bus.NewInboundMessage("websocket", "user", "default", "/help"))
+	if !handled {
+		t.Fatal("/help 应被当作命令处理")
+	}
+	for _, want := range []string{"可用命令", "/task cancel", "可用工具", "calculator - "} {
+		if !strings.Contains(resp, want) {
+			t.Errorf("响应缺少 %q:\n%s", want, resp)
+		}
+	}
+
+	if _, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", "help me")); handled {
+		t.Error("带参数的 help 不应被当作命令处理")
+	}
+}
+
+// TestLoop_HandleModelOverrideCommands 测试 /temp 和 /maxtokens 命令
+func TestLoop_HandleModelOverrideCommands(t *testing.T) {
+	sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), nil)
+	l := &Loop{sessions: sessions}
+	run := func(content string) string {
+		resp, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", content))
+		if !handled {
+			t.Fatalf("%q 应被当作命令处理", content)
+		}
+		return resp
+	}
+
+	if resp := run("/temp 0.2"); resp != "已将当前会话温度设置为 0.2" {
+		t.Errorf("响应 = %q", resp)
+	}
+	if resp := run("/maxtokens 2048"); resp != "已将当前会话最大输出 token 设置为 2048" {
+		t.Errorf("响应 = %q", resp)
+	}
+	temperature, maxTokens := sessions.ModelOverrides("cli:default")
+	if temperature == nil || *temperature != 0.2 || maxTokens != 2048 {
+		t.Errorf("ModelOverrides() = (%v, %d)", temperature, maxTokens)
+	}
+
+	for _, invalid := range []string{"/temp 3", "/temp abc", "/maxtokens 0", "/maxtokens 99999999"} {
+		if resp := run(invalid); !strings.HasPrefix(resp, "错误:") {
+			t.Errorf("%q 响应 = %q, 期望错误", invalid, resp)
+		}
+	}
+
+	run("/temp reset")
+	if temperature, _ := sessions.ModelOverrides("cli:default"); temperature != nil {
+		t.Errorf("reset 后温度 = %v, 期望 nil", *temperature)
+	}
+	if resp := run("/maxtokens"); resp != "当前会话最大输出 token: 2048" {
+		t.Errorf("响应 = %q", resp)
+	}
+}
+
+// TestLoop_HandleReasoningCommand 测试 /reasoning 命令
+func TestLoop_HandleReasoningCommand(t *testing.T) {
+	sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), nil)
+	l := &Loop{sessions: sessions, cfg: config.DefaultConfig()}
+	run := func(content string) string {
+		resp, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", content))
+		if !handled {
+			t.Fatalf("%q 应被当作命令处理", content)
+		}
+		return resp
+	}
+
+	if resp := run("/reasoning"); resp != "当前会话思考过程: 隐藏" {
+		t.Errorf("默认应隐藏思考过程, 响应 = %q", resp)
+	}
+	run("/reasoning on")
+	if !sessions.ShowReasoning("cli:default", false) {
+		t.Error("/reasoning on 后应展示思考过程")
+	}
+	if resp := run("/reasoning maybe"); !strings.HasPrefix(resp, "错误:") {
+		t.Errorf("响应 = %q, 期望错误", resp)
+	}
+
+	run("/reasoning reset")
+	l.cfg.ThinkingProcess.Reasoning = true
+	if resp := run("/reasoning"); resp != "当前会话思考过程: 显示" {
+		t.Errorf("reset 后应使用全局配置, 响应 = %q", resp)
+	}
+}
+
+// TestLoop_HandleForkCommand 测试 /fork 命令
+func TestLoop_HandleForkCommand(t *testing.T) {
+	sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), nil)
+	l := &Loop{sessions: sessions}
+	msg := func(content string) *bus.InboundMessage {
+		return bus.NewInboundMessage("cli", "user", "default", content)
+	}
+
+	resp, handled := l.handleCommand(msg("/fork"))
+	if !handled || !strings.Contains(resp, "cli:default#fork-1") {
+		t.Fatalf("handleCommand(/fork) = (%q, %v)", resp, handled)
+	}
+	if got := l.sessions.ResolveKey(msg("继续聊").SessionKey()); got != "cli:default#fork-1" {
+		t.Errorf("ResolveKey() = %q, 期望分支键", got)
+	}
+
+	resp, _ = l.handleCommand(msg("/fork list"))
+	if !strings.Contains(resp, "main") || !strings.Contains(resp, "cli:default#fork-1（当前）") {
+		t.Errorf("/fork list 响应 = %q", resp)
+	}
+
+	resp, _ = l.handleCommand(msg("/fork switch main"))
+	if resp != "已切换到 main" || l.sessions.ResolveKey(msg("x").SessionKey()) != "cli:default" {
+		t.Errorf("/fork switch main 响应 = %q", resp)
+	}
+}
+
+// TestLoop_HandlePinCommand 测试 /pin 和 /unpin 命令
+func TestLoop_HandlePinCommand(t *testing.T) {
+	sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), nil)
+	l := &Loop{sessions: sessions}
+	run := func(content string) string {
+		resp, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", content))
+		if !handled {
+			t.Fatalf("%q 应作为命令处理", content)
+		}
+		return resp
+	}
+
+	if _, handled := l.handleCommand(bus.NewInboundMessage("cli", "user", "default", "pin 这条推文")); handled {
+		t.Error("不以 / 开头的消息不应作为 pin 命令处理")
+	}
+	if resp := run("/pin"); !strings.Contains(resp, "没有置顶笔记") {
+		t.Errorf("/pin 响应 = %q", resp)
+	}
+	run("/pin 我对花生过敏")
+	run("/pin 回复使用简体中文")
+	if resp := run("/pin"); !strings.Contains(resp, "1. 我对花生过敏") || !strings.Contains(resp, "2. 回复使用简体中文") {
+		t.Errorf("/pin 列表响应 = %q", resp)
+	}
+
+	if resp := run("/unpin 1"); resp != "已取消置顶: 我对花生过敏" {
+		t.Errorf("/unpin 1 响应 = %q", resp)
+	}
+	if resp := run("/unpin 5"); !strings.Contains(resp, "置顶笔记不存在") {
+		t.Errorf("/unpin 5 响应 = %q", resp)
+	}
+	if resp := run("/unpin all"); resp != "已取消全部 1 条置顶笔记" {
+		t.Errorf("/unpin all 响应 = %q", resp)
+	}
+}
+
+// TestLoop_PrepareRegenerate 测试 /retry 和 /edit
+func TestLoop_PrepareRegenerate(t *testing.T) {
+	sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), nil)
+	l := &Loop{sessions: sessions, logger: zap.NewNop()}
+	ctx := context.Background()
+	msg := func(content string) *bus.InboundMessage {
+		return bus.NewInboundMessage("cli", "user", "default", content)
+	}
+
+	if _, _, ok := l.prepareRegenerate(ctx, msg("普通消息"), "cli:default"); ok {
+		t.Error("普通消息不应被处理")
+	}
+	if _, reply, ok := l.prepareRegenerate(ctx, msg("/retry"), "cli:default"); !ok || !strings.Contains(reply, "无法重新生成") {
+		t.Errorf("无历史时 reply = %q", reply)
+	}
+
+	sess := sessions.GetOrCreate("cli:default")
+	sess.AddMessage("user", "写一首诗")
+	sess.AddMessage("assistant", "床前明月光")
+
+	regenerated, reply, ok := l.prepareRegenerate(ctx, msg("/retry"), "cli:default")
+	if !ok || reply != "" || regenerated.Content != "写一首诗" {
+		t.Fatalf("/retry = (%v, %q, %v)", regenerated, reply, ok)
+	}
+	if len(sess.Messages) != 0 {
+		t.Errorf("回退后消息数 = %d, 期望 0", len(sess.Messages))
+	}
+
+	sess.AddMessage("user", "写一首诗")
+	regenerated, _, _ = l.prepareRegenerate(ctx, msg("/edit 写一首关于秋天的诗"), "cli:default")
+	if regenerated == nil || regenerated.Content != "写一首关于秋天的诗" || regenerated.ChatID != "default" {
+		t.Errorf("/edit 结果 = %+v", regenerated)
+	}
+	if _, reply, _ := l.prepareRegenerate(ctx, msg("/edit"), "cli:default"); !strings.HasPrefix(reply, "用法") {
+		t.Errorf("/edit 无内容 reply = %q", reply)
+	}
+}
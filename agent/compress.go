@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/internal/models"
+	"github.com/weibaohui/nanobot-go/session"
+	"go.uber.org/zap"
+)
+
+// compressTimeout 单次压缩调用的超时时间
+const compressTimeout = 2 * time.Minute
+
+// compressSystemPrompt 压缩模型的系统提示词
+const compressSystemPrompt = `你是对话摘要助手。请将给出的对话压缩为简洁的摘要，保留：
+- 用户的目标、偏好和约束
+- 已确认的事实、结论和决定
+- 尚未完成的事项
+不要编造对话中没有的信息，直接输出摘要正文，不要添加额外说明。`
+
+// NewCompressModel 创建对话压缩使用的模型
+// 模型和提供商由 Compress 配置决定，可以与主对话模型不同（例如使用更便宜的小模型）
+func NewCompressModel(logger *zap.Logger, cfg *config.Config) (model.BaseChatModel, error) {
+	if cfg == nil {
+		return nil, ErrNilConfig
+	}
+	modelName, apiKey, apiBase := cfg.CompressModel()
+	if apiKey == "" {
+		return nil, fmt.Errorf("%w: 压缩模型 %s", ErrNilAPIKey, modelName)
+	}
+	return newAuxChatModel(logger, cfg, modelName, apiKey, apiBase)
+}
+
+// newAuxChatModel 创建压缩、翻译等辅助功能使用的 OpenAI 兼容模型
+func newAuxChatModel(logger *zap.Logger, cfg *config.Config, modelName, apiKey, apiBase string) (model.BaseChatModel, error) {
+	modelConfig := &openai.ChatModelConfig{
+		APIKey:  apiKey,
+		Model:   modelName,
+		BaseURL: apiBase,
+	}
+	if cfg.Providers.Debug && logger != nil {
+		modelConfig.HTTPClient = newDebugHTTPClient(logger)
+	}
+
+	chatModel, err := openai.NewChatModel(context.Background(), modelConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCreateChatModel, err)
+	}
+	return chatModel, nil
+}
+
+// Compressor 对话压缩器
+// 会话中未压缩的对话（消息数或实际 token 用量）超过阈值时，将较早的对话交给压缩模型生成摘要，
+// 之后的历史只加载摘要和最近的 MaxHistory 条消息，减少每轮的上下文长度
+type Compressor struct {
+	cfg      config.CompressConfig
+	model    model.BaseChatModel
+	sessions *session.Manager
+	logger   *zap.Logger
+
+	running sync.Map // 正在压缩的会话键，避免同一会话并发压缩
+}
+
+// NewCompressor 创建对话压缩器
+func NewCompressor(cfg config.CompressConfig, chatModel model.BaseChatModel, sessions *session.Manager, logger *zap.Logger) *Compressor {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Compressor{
+		cfg:      cfg,
+		model:    chatModel,
+		sessions: sessions,
+		logger:   logger,
+	}
+}
+
+// MaybeCompress 检查会话是否达到压缩阈值，达到时生成摘要，返回是否执行了压缩
+func (c *Compressor) MaybeCompress(ctx context.Context, sessionKey string) (bool, error) {
+	if _, loaded := c.running.LoadOrStore(sessionKey, struct{}{}); loaded {
+		return false, nil
+	}
+	defer c.running.Delete(sessionKey)
+
+	records, err := c.sessions.RecordsSinceSummary(ctx, sessionKey)
+	if err != nil {
+		return false, err
+	}
+	dialog := dialogRecords(records)
+	if !c.shouldCompress(dialog, tokenUsage(records)) {
+		return false, nil
+	}
+
+	keep := c.cfg.MaxHistory
+	if keep >= len(dialog) {
+		return false, nil
+	}
+	older := dialog[:len(dialog)-keep]
+	previous, _ := c.sessions.Summary(sessionKey)
+
+	ctx, cancel := context.WithTimeout(ctx, compressTimeout)
+	defer cancel()
+	resp, err := c.model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(compressSystemPrompt),
+		schema.UserMessage(buildCompressPrompt(previous, older)),
+	})
+	if err != nil {
+		return false, fmt.Errorf("生成对话摘要失败: %w", err)
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return false, fmt.Errorf("压缩模型返回了空摘要")
+	}
+
+	c.sessions.SetSummary(sessionKey, summary, older[len(older)-1].Timestamp)
+	c.logger.Info("对话已压缩",
+		zap.String("session", sessionKey),
+		zap.Int("compressed", len(older)),
+		zap.Int("kept", keep),
+		zap.Int("summary_chars", len([]rune(summary))),
+	)
+	return true, nil
+}
+
+// shouldCompress 判断未压缩的对话是否达到压缩阈值：消息数达到 MinMessages 或实际 token 用量达到 MinTokens
+func (c *Compressor) shouldCompress(dialog []models.ConversationRecord, tokens int) bool {
+	if c.cfg.MinMessages > 0 && len(dialog) >= c.cfg.MinMessages {
+		return true
+	}
+	return c.cfg.MinTokens > 0 && tokens >= c.cfg.MinTokens
+}
+
+// tokenUsage 累计对话记录中模型返回的实际 token 用量（含工具调用轮次）
+func tokenUsage(records []models.ConversationRecord) int {
+	total := 0
+	for _, record := range records {
+		total += record.TotalTokens
+	}
+	return total
+}
+
+// dialogRecords 过滤出用户和助手的对话记录，工具调用记录不参与摘要
+func dialogRecords(records []models.ConversationRecord) []models.ConversationRecord {
+	var dialog []models.ConversationRecord
+	for _, record := range records {
+		if (record.Role == "user" || record.Role == "assistant") && record.Content != "" {
+			dialog = append(dialog, record)
+		}
+	}
+	return dialog
+}
+
+// buildCompressPrompt 构建压缩请求内容，已有摘要时与新对话合并为一份摘要
+func buildCompressPrompt(previous string, records []models.ConversationRecord) string {
+	var sb strings.Builder
+	if previous != "" {
+		sb.WriteString("已有摘要：\n")
+		sb.WriteString(previous)
+		sb.WriteString("\n\n请将已有摘要与以下新对话合并为一份摘要。\n\n")
+	}
+	sb.WriteString("对话：\n")
+	for _, record := range records {
+		role := "用户"
+		if record.Role == "assistant" {
+			role = "助手"
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", role, record.Content)
+	}
+	return sb.String()
+}
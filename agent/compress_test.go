@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/internal/models"
+	"github.com/weibaohui/nanobot-go/session"
+	"go.uber.org/zap"
+)
+
+// compressConvRepo 用于压缩测试的内存对话记录仓库
+type compressConvRepo struct {
+	records []models.ConversationRecord
+}
+
+func (r *compressConvRepo) FindBySessionKey(ctx context.Context, sessionKey string, opts *models.QueryOptions) ([]models.ConversationRecord, error) {
+	var result []models.ConversationRecord
+	for _, record := range r.records {
+		if record.SessionKey == sessionKey {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
+
+func (r *compressConvRepo) FindSessionKeysByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for _, record := range r.records {
+		if strings.HasPrefix(record.SessionKey, prefix) && !slices.Contains(keys, record.SessionKey) {
+			keys = append(keys, record.SessionKey)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (r *compressConvRepo) CreateBatch(ctx context.Context, records []models.ConversationRecord) error {
+	r.records = append(r.records, records...)
+	return nil
+}
+
+func (r *compressConvRepo) DeleteByID(ctx context.Context, id uint) error {
+	return nil
+}
+
+// newDialogRepo 创建包含 n 条用户/助手交替消息的仓库
+func newDialogRepo(key string, n int) *compressConvRepo {
+	repo := &compressConvRepo{}
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < n; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		repo.records = append(repo.records, models.ConversationRecord{
+			SessionKey: key,
+			Role:       role,
+			Content:    fmt.Sprintf("消息 %d", i),
+			Timestamp:  start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	return repo
+}
+
+// TestCompressor_MaybeCompress 测试对话压缩
+func TestCompressor_MaybeCompress(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.CompressConfig{Enabled: true, MinMessages: 6, MaxHistory: 2}
+
+	t.Run("未达到阈值时不压缩", func(t *testing.T) {
+		sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), newDialogRepo("s", 5))
+		chatModel := &summaryChatModel{content: "摘要"}
+		compressed, err := NewCompressor(cfg, chatModel, sessions, nil).MaybeCompress(ctx, "s")
+		if err != nil || compressed {
+			t.Fatalf("compressed = %v, err = %v", compressed, err)
+		}
+		if chatModel.input != nil {
+			t.Error("未达到阈值时不应调用压缩模型")
+		}
+	})
+
+	t.Run("达到阈值时压缩较早的对话", func(t *testing.T) {
+		sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), newDialogRepo("s", 6))
+		chatModel := &summaryChatModel{content: "用户在讨论测试"}
+		compressed, err := NewCompressor(cfg, chatModel, sessions, nil).MaybeCompress(ctx, "s")
+		if err != nil || !compressed {
+			t.Fatalf("compressed = %v, err = %v", compressed, err)
+		}
+
+		prompt := chatModel.input[len(chatModel.input)-1].Content
+		if !strings.Contains(prompt, "消息 3") || strings.Contains(prompt, "消息 4") {
+			t.Errorf("应只压缩保留消息之前的对话, prompt = %q", prompt)
+		}
+
+		history := sessions.GetHistory(ctx, "s", 10)
+		if len(history) != 3 {
+			t.Fatalf("历史记录数量 = %d, 期望 3（摘要 + 2 条保留消息）", len(history))
+		}
+		if history[0]["role"] != "system" || !strings.Contains(history[0]["content"].(string), "用户在讨论测试") {
+			t.Errorf("第一条历史应为摘要, got %v", history[0])
+		}
+		if history[1]["content"] != "消息 4" {
+			t.Errorf("保留的第一条消息 = %v, 期望 消息 4", history[1]["content"])
+		}
+	})
+
+	t.Run("模型失败时不写入摘要", func(t *testing.T) {
+		sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), newDialogRepo("s", 6))
+		chatModel := &summaryChatModel{err: fmt.Errorf("超时")}
+		if _, err := NewCompressor(cfg, chatModel, sessions, nil).MaybeCompress(ctx, "s"); err == nil {
+			t.Fatal("模型失败时应返回错误")
+		}
+		if summary, _ := sessions.Summary("s"); summary != "" {
+			t.Errorf("模型失败时不应写入摘要, got %q", summary)
+		}
+	})
+}
+
+// TestCompressor_TokenThreshold 测试按实际 token 用量触发压缩的边界
+func TestCompressor_TokenThreshold(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.CompressConfig{Enabled: true, MinMessages: 100, MinTokens: 1000, MaxHistory: 2}
+
+	tests := []struct {
+		name       string
+		tokens     int
+		compressed bool
+	}{
+		{"低于阈值不压缩", 999, false},
+		{"恰好达到阈值时压缩", 1000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newDialogRepo("s", 4)
+			// 助手和工具记录的用量合计为 tt.tokens
+			repo.records[1].TotalTokens = 400
+			repo.records[3].TotalTokens = tt.tokens - 400 - 100
+			repo.records = append(repo.records, models.ConversationRecord{
+				SessionKey:  "s",
+				Role:        "tool",
+				TotalTokens: 100,
+				Timestamp:   repo.records[3].Timestamp.Add(time.Second),
+			})
+
+			sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), repo)
+			compressed, err := NewCompressor(cfg, &summaryChatModel{content: "摘要"}, sessions, nil).MaybeCompress(ctx, "s")
+			if err != nil {
+				t.Fatalf("MaybeCompress() error = %v", err)
+			}
+			if compressed != tt.compressed {
+				t.Errorf("tokens = %d, compressed = %v, 期望 %v", tt.tokens, compressed, tt.compressed)
+			}
+		})
+	}
+
+	t.Run("摘要之前的用量不再计入", func(t *testing.T) {
+		repo := newDialogRepo("s", 4)
+		repo.records[1].TotalTokens = 1500
+		sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), repo)
+		sessions.SetSummary("s", "旧摘要", repo.records[1].Timestamp)
+
+		compressed, err := NewCompressor(cfg, &summaryChatModel{content: "摘要"}, sessions, nil).MaybeCompress(ctx, "s")
+		if err != nil || compressed {
+			t.Errorf("compressed = %v, err = %v, 摘要之后的用量未达到阈值", compressed, err)
+		}
+	})
+}
+
+// TestNewCompressModel 测试压缩模型创建
+func TestNewCompressModel(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if _, err := NewCompressModel(zap.NewNop(), cfg); err == nil {
+		t.Error("缺少 API Key 时应返回错误")
+	}
+
+	cfg.Compress.Model = "gpt-4o-mini"
+	cfg.Compress.APIKey = "compress-key"
+	if _, err := NewCompressModel(zap.NewNop(), cfg); err != nil {
+		t.Errorf("配置独立提供商后应创建成功: %v", err)
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/config"
 )
 
 // BootstrapMode 引导文件加载模式
@@ -25,15 +27,20 @@ const (
 
 // ContextBuilder 上下文构建器
 type ContextBuilder struct {
-	workspace       string
-	memory          *MemoryStore
-	skills          *SkillsLoader
-	bootstrapMode   BootstrapMode // 引导文件加载模式
+	name           string // Agent 名称，用于系统提示中的身份
+	workspace      string
+	memory         *MemoryStore
+	skills         *SkillsLoader
+	bootstrapMode  BootstrapMode     // 引导文件加载模式
+	toolNames      []string          // 已启用的工具名称，nil 表示未设置
+	channelPrompts map[string]string // 按渠道追加的系统提示
+	developer      string            // 开发者指引（如工具使用规范），始终放在系统提示末尾
 }
 
 // NewContextBuilder 创建上下文构建器
 func NewContextBuilder(workspace string) *ContextBuilder {
 	return &ContextBuilder{
+		name:          config.DefaultAgentName,
 		workspace:     workspace,
 		memory:        NewMemoryStore(workspace),
 		skills:        NewSkillsLoader(workspace),
@@ -41,11 +48,53 @@ func NewContextBuilder(workspace string) *ContextBuilder {
 	}
 }
 
+// SetName 设置 Agent 名称，为空时使用默认名称
+func (c *ContextBuilder) SetName(name string) {
+	c.name = config.AgentDefaults{Name: name}.AgentName()
+}
+
+// Name 返回 Agent 名称
+func (c *ContextBuilder) Name() string {
+	return c.name
+}
+
 // SetBootstrapMode 设置引导文件加载模式
 func (c *ContextBuilder) SetBootstrapMode(mode BootstrapMode) {
 	c.bootstrapMode = mode
 }
 
+// SetToolNames 设置已启用的工具名称，用于生成系统提示中的能力列表
+func (c *ContextBuilder) SetToolNames(names []string) {
+	c.toolNames = append([]string{}, names...)
+}
+
+// SetChannelPrompts 设置按渠道追加的系统提示
+func (c *ContextBuilder) SetChannelPrompts(prompts map[string]string) {
+	c.channelPrompts = prompts
+}
+
+// SetDeveloperPrompt 设置开发者指引
+func (c *ContextBuilder) SetDeveloperPrompt(prompt string) {
+	c.developer = strings.TrimSpace(prompt)
+}
+
+// ChannelPrompt 获取指定渠道追加的系统提示
+func (c *ContextBuilder) ChannelPrompt(channel string) string {
+	return strings.TrimSpace(c.channelPrompts[channel])
+}
+
+// AppendChannelPrompt 将渠道提示追加到系统提示之后，渠道未配置时原样返回
+func (c *ContextBuilder) AppendChannelPrompt(systemPrompt, channel string) string {
+	channelPrompt := c.ChannelPrompt(channel)
+	if channelPrompt == "" {
+		return systemPrompt
+	}
+	if systemPrompt == "" {
+		return "## 渠道要求\n\n" + channelPrompt
+	}
+	return systemPrompt + "\n\n---\n\n## 渠道要求\n\n" + channelPrompt
+}
+
 // GetSkillsLoader 获取技能加载器
 func (c *ContextBuilder) GetSkillsLoader() *SkillsLoader {
 	return c.skills
@@ -56,6 +105,11 @@ func (c *ContextBuilder) BuildSystemPrompt() string {
 	return c.BuildSystemPromptWithMode(c.bootstrapMode)
 }
 
+// BuildSystemPromptForChannel 构建系统提示并追加渠道提示
+func (c *ContextBuilder) BuildSystemPromptForChannel(channel string) string {
+	return c.AppendChannelPrompt(c.BuildSystemPrompt(), channel)
+}
+
 // BuildSystemPromptWithMode 使用指定模式构建系统提示
 func (c *ContextBuilder) BuildSystemPromptWithMode(mode BootstrapMode) string {
 	var parts []string
@@ -96,6 +150,11 @@ available="false" 的技能需要先安装依赖 - 你可以尝试使用 apt/bre
 `+skillsSummary)
 	}
 
+	// 开发者指引放在最后，作为系统提示的一部分发送，不会被历史裁剪
+	if c.developer != "" {
+		parts = append(parts, "# 开发者指引\n\n"+c.developer)
+	}
+
 	return strings.Join(parts, "\n\n---\n\n")
 }
 
@@ -110,14 +169,10 @@ func (c *ContextBuilder) getIdentity() string {
 	}
 	goVersion := runtime.Version()
 
-	return fmt.Sprintf(`# nanobot 🐈
+	return fmt.Sprintf(`# %s
 
-你是 nanobot，一个有帮助的 AI 助手。你可以使用以下工具：
-- 读取、写入和编辑文件
-- 执行 shell 命令
-- 搜索网络和获取网页
-- 向用户发送消息到聊天渠道
-- 加载并使用技能（use_skill 工具）
+你是 %s，一个有帮助的 AI 助手。你可以使用以下工具：
+%s
 
 ## 当前时间
 %s (%s)
@@ -136,7 +191,61 @@ func (c *ContextBuilder) getIdentity() string {
 对于普通对话，只需回复文本 - 不要调用 message 工具。
 
 始终保持有帮助、准确和简洁。使用工具时，逐步思考：你知道什么、你需要什么、以及为什么选择这个工具。
-当记住某些内容时，写入 %s/memory/MEMORY.md`, now, tz, system, runtime.GOARCH, goVersion, workspacePath, workspacePath, workspacePath, workspacePath, workspacePath)
+当记住某些内容时，写入 %s/memory/MEMORY.md`, identityTitle(c.name), c.name, c.buildCapabilities(), now, tz, system, runtime.GOARCH, goVersion, workspacePath, workspacePath, workspacePath, workspacePath, workspacePath)
+}
+
+// identityTitle 返回系统提示身份部分的标题，默认名称带 🐈 标识
+func identityTitle(name string) string {
+	if name == config.DefaultAgentName {
+		return name + " 🐈"
+	}
+	return name
+}
+
+// toolCapabilities 工具与系统提示中能力描述的对应关系
+var toolCapabilities = []struct {
+	tools []string
+	desc  string
+}{
+	{[]string{"read_file", "write_file", "edit_file"}, "读取、写入和编辑文件"},
+	{[]string{"apply_patch"}, "以统一 diff 补丁修改文件（多行修改优先使用 apply_patch）"},
+	{[]string{"exec"}, "执行 shell 命令"},
+	{[]string{"web_search", "web_fetch"}, "搜索网络和获取网页"},
+	{[]string{"message"}, "向用户发送消息到聊天渠道"},
+	{[]string{"weather"}, "查询天气和天气预报"},
+	{[]string{"translate"}, "翻译文本并保留原文格式"},
+	{[]string{"calculator"}, "精确计算数学表达式（涉及数值计算时请使用 calculator 工具，不要心算）"},
+	{[]string{"use_skill"}, "加载并使用技能（use_skill 工具）"},
+}
+
+// buildCapabilities 根据已启用的工具生成能力列表
+// 未设置工具列表时返回全部能力，保持默认行为
+func (c *ContextBuilder) buildCapabilities() string {
+	enabled := make(map[string]bool, len(c.toolNames))
+	for _, name := range c.toolNames {
+		enabled[name] = true
+	}
+
+	var lines []string
+	for _, capability := range toolCapabilities {
+		active := c.toolNames == nil
+		for _, name := range capability.tools {
+			if enabled[name] {
+				active = true
+				break
+			}
+		}
+		if active {
+			lines = append(lines, "- "+capability.desc)
+		}
+	}
+
+	if c.toolNames != nil {
+		names := append([]string(nil), c.toolNames...)
+		sort.Strings(names)
+		lines = append(lines, "\n当前启用的工具: "+strings.Join(names, ", "))
+	}
+	return strings.Join(lines, "\n")
 }
 
 // loadBootstrapFiles 加载引导文件
@@ -181,7 +290,7 @@ func (c *ContextBuilder) BuildMessages(history []map[string]any, currentMessage
 	var messages []map[string]any
 
 	// 系统提示
-	systemPrompt := c.BuildSystemPrompt()
+	systemPrompt := c.BuildSystemPromptForChannel(channel)
 	if channel != "" && chatID != "" {
 		systemPrompt += fmt.Sprintf("\n\n## 当前会话\n渠道: %s\n聊天 ID: %s", channel, chatID)
 	}
@@ -305,6 +414,9 @@ func BuildMessageList(systemPrompt string, history []*schema.Message, userInput,
 		messages = append(messages, history...)
 	}
 
+	// 历史中的 system 消息（如记忆摘要）合并到首条系统消息
+	messages = mergeSystemMessages(messages)
+
 	// 添加当前用户消息
 	messages = append(messages, &schema.Message{
 		Role:    schema.User,
@@ -313,3 +425,32 @@ func BuildMessageList(systemPrompt string, history []*schema.Message, userInput,
 
 	return messages
 }
+
+// mergeSystemMessages 将所有 system 消息按原顺序合并为位于首位的一条
+// 部分提供商不接受多条或位于对话中间的 system 消息；只有一条且位于首位时原样返回
+func mergeSystemMessages(messages []*schema.Message) []*schema.Message {
+	count := 0
+	for _, msg := range messages {
+		if msg != nil && msg.Role == schema.System {
+			count++
+		}
+	}
+	if count == 0 || (count == 1 && messages[0] != nil && messages[0].Role == schema.System) {
+		return messages
+	}
+
+	var parts []string
+	rest := make([]*schema.Message, 0, len(messages)-count+1)
+	for _, msg := range messages {
+		if msg != nil && msg.Role == schema.System {
+			if content := strings.TrimSpace(msg.Content); content != "" {
+				parts = append(parts, content)
+			}
+			continue
+		}
+		rest = append(rest, msg)
+	}
+
+	merged := &schema.Message{Role: schema.System, Content: strings.Join(parts, "\n\n---\n\n")}
+	return append([]*schema.Message{merged}, rest...)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cloudwego/eino/schema"
@@ -49,6 +50,53 @@ func TestContextBuilder_BuildSystemPrompt(t *testing.T) {
 	}
 }
 
+// TestContextBuilder_SetToolNames 测试系统提示中的能力列表随启用工具变化
+func TestContextBuilder_SetToolNames(t *testing.T) {
+	t.Run("未设置时包含全部能力", func(t *testing.T) {
+		builder := NewContextBuilder(t.TempDir())
+		prompt := builder.BuildSystemPrompt()
+		if !contains(prompt, "执行 shell 命令") {
+			t.Error("默认系统提示应该包含 shell 能力")
+		}
+	})
+
+	t.Run("禁用工具后不再出现", func(t *testing.T) {
+		builder := NewContextBuilder(t.TempDir())
+		builder.SetToolNames([]string{"read_file", "message"})
+		prompt := builder.BuildSystemPrompt()
+		if contains(prompt, "执行 shell 命令") {
+			t.Error("未启用 exec 时系统提示不应该包含 shell 能力")
+		}
+		if !contains(prompt, "读取、写入和编辑文件") {
+			t.Error("系统提示应该包含文件能力")
+		}
+		if !contains(prompt, "当前启用的工具: message, read_file") {
+			t.Error("系统提示应该列出启用的工具")
+		}
+	})
+}
+
+// TestContextBuilder_ChannelPrompts 测试按渠道追加系统提示
+func TestContextBuilder_ChannelPrompts(t *testing.T) {
+	builder := NewContextBuilder(t.TempDir())
+	builder.SetChannelPrompts(map[string]string{"dingtalk": "回复保持简短"})
+
+	if prompt := builder.BuildSystemPromptForChannel("dingtalk"); !contains(prompt, "## 渠道要求\n\n回复保持简短") {
+		t.Error("钉钉渠道的系统提示应该包含渠道要求")
+	}
+	if prompt := builder.BuildSystemPromptForChannel("websocket"); contains(prompt, "渠道要求") {
+		t.Error("未配置的渠道不应该追加渠道要求")
+	}
+	if got := builder.AppendChannelPrompt("", "websocket"); got != "" {
+		t.Errorf("AppendChannelPrompt() = %q, 期望空字符串", got)
+	}
+
+	messages := builder.BuildMessages(nil, "你好", nil, nil, "dingtalk", "chat-1")
+	if content, _ := messages[0]["content"].(string); !contains(content, "回复保持简短") {
+		t.Error("BuildMessages 的系统消息应该包含渠道要求")
+	}
+}
+
 // TestContextBuilder_loadBootstrapFiles 测试加载引导文件
 func TestContextBuilder_loadBootstrapFiles(t *testing.T) {
 	t.Run("无引导文件", func(t *testing.T) {
@@ -286,6 +334,65 @@ func TestBuildMessageList_NoHistory(t *testing.T) {
 	}
 }
 
+// TestBuildMessageList_MergeSystem 测试历史中的 system 消息合并到首位
+func TestBuildMessageList_MergeSystem(t *testing.T) {
+	history := []*schema.Message{
+		{Role: schema.User, Content: "历史消息"},
+		{Role: schema.System, Content: "记忆摘要"},
+		{Role: schema.Assistant, Content: "历史回复"},
+	}
+
+	messages := BuildMessageList("系统提示", history, "当前输入", "", "")
+
+	if len(messages) != 4 {
+		t.Fatalf("BuildMessageList 返回 %d 条消息, 期望 4", len(messages))
+	}
+	for i, msg := range messages {
+		if (i == 0) != (msg.Role == schema.System) {
+			t.Errorf("messages[%d].Role = %s, 只有首条消息应为 System", i, msg.Role)
+		}
+	}
+	if messages[0].Content != "系统提示\n\n---\n\n记忆摘要" {
+		t.Errorf("系统消息 = %q", messages[0].Content)
+	}
+}
+
+// TestMergeSystemMessages 测试合并多条 system 消息
+func TestMergeSystemMessages(t *testing.T) {
+	t.Run("单条系统消息原样返回", func(t *testing.T) {
+		input := []*schema.Message{{Role: schema.System, Content: "a"}, {Role: schema.User, Content: "b"}}
+		if got := mergeSystemMessages(input); &got[0] != &input[0] {
+			t.Error("无需合并时应返回原切片")
+		}
+	})
+
+	t.Run("Instruction 与渠道提示合并", func(t *testing.T) {
+		input := []*schema.Message{
+			{Role: schema.System, Content: "主提示"},
+			{Role: schema.System, Content: "## 渠道要求"},
+			{Role: schema.User, Content: "你好"},
+		}
+		got := mergeSystemMessages(input)
+		if len(got) != 2 || got[0].Role != schema.System || got[1].Role != schema.User {
+			t.Fatalf("合并结果 = %v", got)
+		}
+		if got[0].Content != "主提示\n\n---\n\n## 渠道要求" {
+			t.Errorf("系统消息 = %q", got[0].Content)
+		}
+	})
+}
+
+// TestContextBuilder_SetDeveloperPrompt 测试开发者指引追加到系统提示末尾
+func TestContextBuilder_SetDeveloperPrompt(t *testing.T) {
+	builder := NewContextBuilder(t.TempDir())
+	builder.SetDeveloperPrompt("  调用 exec 前先说明目的  ")
+
+	prompt := builder.BuildSystemPrompt()
+	if !strings.HasSuffix(prompt, "# 开发者指引\n\n调用 exec 前先说明目的") {
+		t.Errorf("系统提示末尾缺少开发者指引:\n%s", prompt)
+	}
+}
+
 // TestContextBuilder_getIdentity 测试获取核心身份
 func TestContextBuilder_getIdentity(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -306,6 +413,21 @@ func TestContextBuilder_getIdentity(t *testing.T) {
 	}
 }
 
+// TestContextBuilder_SetName 测试配置 Agent 名称
+func TestContextBuilder_SetName(t *testing.T) {
+	builder := NewContextBuilder(t.TempDir())
+	builder.SetName("小助")
+	identity := builder.getIdentity()
+	if !strings.HasPrefix(identity, "# 小助\n\n你是 小助，") || strings.Contains(identity, "nanobot") {
+		t.Errorf("身份应使用配置的名称:\n%s", identity)
+	}
+
+	builder.SetName("  ")
+	if builder.Name() != "nanobot" || !strings.HasPrefix(builder.getIdentity(), "# nanobot 🐈") {
+		t.Errorf("名称为空时应使用默认名称, Name() = %q", builder.Name())
+	}
+}
+
 // 辅助函数
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// defaultFastChatMaxChars 闲聊快速模式默认的最大消息长度（字符）
+const defaultFastChatMaxChars = 30
+
+// casualPhrases 明确属于闲聊的短语，消息去掉标点后与其中之一相同时视为闲聊
+var casualPhrases = []string{
+	"你好", "您好", "早", "早上好", "中午好", "下午好", "晚上好", "晚安", "在吗", "在不在",
+	"谢谢", "多谢", "感谢", "好的", "好", "嗯", "哈哈", "哈哈哈", "再见", "拜拜", "辛苦了",
+	"hi", "hello", "hey", "thanks", "thank you", "ok", "okay", "bye", "good morning", "good night",
+}
+
+// taskKeywords 提示需要工具或多步处理的关键词，包含其中之一时不走快速模式
+var taskKeywords = []string{
+	"http", "www.", "```", "/", "\\",
+	"搜索", "查一下", "查询", "查找", "文件", "目录", "运行", "执行", "命令", "安装", "下载", "打开",
+	"提醒", "定时", "任务", "计算", "写", "代码", "脚本", "发送", "读取", "保存", "记住", "天气", "翻译",
+	"search", "file", "run", "exec", "install", "download", "remind", "schedule", "code", "script", "send",
+}
+
+// isCasualChat 判断消息是否为可以不调用工具直接回复的闲聊
+// 规则偏保守：命中闲聊短语，或足够短且不包含任务关键词
+func isCasualChat(content string, maxChars int) bool {
+	if maxChars <= 0 {
+		maxChars = defaultFastChatMaxChars
+	}
+	text := strings.ToLower(strings.TrimSpace(content))
+	if text == "" || strings.Contains(text, "\n") {
+		return false
+	}
+
+	normalized := strings.TrimRight(text, "!！?？。.,，~～ ")
+	for _, phrase := range casualPhrases {
+		if normalized == phrase {
+			return true
+		}
+	}
+
+	if utf8.RuneCountInString(text) > maxChars {
+		return false
+	}
+	for _, kw := range taskKeywords {
+		if strings.Contains(text, kw) {
+			return false
+		}
+	}
+	return true
+}
+
+// buildChatOnlyPrompt 闲聊快速模式使用的精简系统提示
+func buildChatOnlyPrompt(name string) string {
+	now := time.Now().Format("2006-01-02 15:04 (Monday)")
+	return fmt.Sprintf(`# %s
+
+你是 %s，一个有帮助的 AI 助手。当前为闲聊模式，没有可用的工具，请直接简洁、友好地回复。
+
+## 当前时间
+%s`, identityTitle(name), name, now)
+}
+
+// ProcessChatOnly 以不带工具的精简模式处理闲聊消息，跳过工具定义和完整系统提示以降低延迟
+// 会话存在待回复的中断时返回 handled=false，由调用方交给完整流程处理
+func (sa *MasterAgent) ProcessChatOnly(ctx context.Context, msg *bus.InboundMessage) (response string, handled bool, err error) {
+	if sa.chatModel == nil {
+		return "", false, nil
+	}
+	sessionKey := sa.sessions.ResolveKey(msg.SessionKey())
+	if sa.interruptManager != nil && sa.interruptManager.GetPendingInterrupt(sessionKey) != nil {
+		return "", false, nil
+	}
+
+	ctx = context.WithValue(ctx, SessionKeyContextKey, sessionKey)
+	ctx = context.WithValue(ctx, "session_key", sessionKey)
+	ctx, spanID := trace.StartSpan(ctx)
+	start := time.Now()
+
+	history := sa.loadHistory(ctx, sessionKey, msg.Channel)
+	name := config.DefaultAgentName
+	if sa.context != nil {
+		name = sa.context.Name()
+	}
+	systemPrompt := buildChatOnlyPrompt(name)
+	if sa.context != nil {
+		systemPrompt = sa.context.AppendChannelPrompt(systemPrompt, msg.Channel)
+	}
+	messages := BuildMessageList(systemPrompt, history, msg.Content, msg.Channel, msg.ChatID)
+
+	// 触发 PromptSubmitted 事件，让 SessionObserver 保存用户消息
+	if sa.hookManager != nil {
+		sa.hookManager.OnPromptSubmitted(ctx, msg.Content, messages, sessionKey)
+	}
+
+	ctx = withReplyNotice(ctx)
+	response, err = sa.retryOnEmpty(ctx, func(int) (string, error) {
+		reply, err := sa.chatModel.Generate(ctx, messages)
+		if err != nil {
+			return "", err
+		}
+		return reply.Content, nil
+	})
+	if err != nil {
+		errorMsg := fmt.Sprintf("处理失败: %v", err)
+		return errorMsg, true, fmt.Errorf("%s", errorMsg)
+	}
+
+	sa.logger.Info("闲聊快速模式完成",
+		zap.String("span_id", spanID),
+		zap.String("session_key", sessionKey),
+		zap.Duration("duration", time.Since(start)),
+	)
+	return response, true, nil
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// TestIsCasualChat 测试闲聊判断
+func TestIsCasualChat(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"你好", true},
+		{"早上好！", true},
+		{"Thanks!", true},
+		{"今天心情不错", true},
+		{"", false},
+		{"帮我搜索一下 Go 1.26 的新特性", false},
+		{"看看 https://example.com", false},
+		{"写一首关于秋天的诗", false},
+		{"第一行\n第二行", false},
+		{strings.Repeat("聊", 31), false},
+	}
+	for _, tt := range tests {
+		if got := isCasualChat(tt.content, 0); got != tt.want {
+			t.Errorf("isCasualChat(%q) = %v, 期望 %v", tt.content, got, tt.want)
+		}
+	}
+
+	if !isCasualChat(strings.Repeat("聊", 31), 40) {
+		t.Error("自定义最大长度应生效")
+	}
+}
+
+// TestMasterAgent_ProcessChatOnly 测试闲聊快速模式不带工具直接回复
+func TestMasterAgent_ProcessChatOnly(t *testing.T) {
+	msg := &bus.InboundMessage{Channel: "websocket", ChatID: "c1", Content: "你好"}
+
+	t.Run("直接调用模型", func(t *testing.T) {
+		llm := &summaryChatModel{content: "你好呀！"}
+		sa := &MasterAgent{interruptible: &interruptible{logger: zap.NewNop()}, logger: zap.NewNop(), chatModel: llm}
+
+		response, handled, err := sa.ProcessChatOnly(context.Background(), msg)
+		if err != nil || !handled || response != "你好呀！" {
+			t.Fatalf("ProcessChatOnly() = (%q, %v, %v)", response, handled, err)
+		}
+		if len(llm.input) != 2 || llm.input[0].Role != schema.System || !strings.Contains(llm.input[0].Content, "闲聊模式") {
+			t.Errorf("模型输入 = %+v, 期望精简系统提示加用户消息", llm.input)
+		}
+	})
+
+	t.Run("存在待回复的中断时交给完整流程", func(t *testing.T) {
+		mgr := NewInterruptManager(bus.NewMessageBus(zap.NewNop()), zap.NewNop())
+		mgr.HandleInterrupt(&InterruptInfo{CheckpointID: "cp", SessionKey: msg.SessionKey(), Question: "确认吗？"})
+		sa := &MasterAgent{
+			interruptible: &interruptible{logger: zap.NewNop(), interruptManager: mgr},
+			logger:        zap.NewNop(),
+			chatModel:     &summaryChatModel{content: "你好"},
+		}
+		if _, handled, _ := sa.ProcessChatOnly(context.Background(), msg); handled {
+			t.Error("有待回复的中断时不应走快速模式")
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// historyWindow 返回渠道使用的会话历史窗口，未提供配置时使用默认消息数
+func historyWindow(cfg *config.Config, channel string) config.HistoryWindow {
+	if cfg == nil {
+		return config.HistoryWindow{MaxMessages: config.DefaultHistoryMessages}
+	}
+	return cfg.Agents.History.Window(channel)
+}
+
+// loadHistory 按渠道的历史窗口加载会话历史，并按估算 token 上限裁剪，置顶笔记放在最前面
+func (i *interruptible) loadHistory(ctx context.Context, sessionKey, channel string) []*schema.Message {
+	if i.sessions == nil {
+		return nil
+	}
+	window := historyWindow(i.cfg, channel)
+	history := i.convertHistory(i.sessions.GetHistory(ctx, sessionKey, window.MaxMessages))
+	// 裁剪可能把工具调用与其结果拆开，重新配对以保证回放给模型的序列完整
+	trimmed := pairToolMessages(trimHistoryByTokens(history, window.MaxTokens))
+	if len(trimmed) < len(history) {
+		i.logger.Debug("历史消息超过 token 上限，已丢弃较早的消息",
+			zap.String("session_key", sessionKey),
+			zap.Int("max_tokens", window.MaxTokens),
+			zap.Int("dropped", len(history)-len(trimmed)),
+		)
+	}
+	// 置顶笔记在裁剪之后加入，始终保留
+	if pinned := i.sessions.PinnedContext(sessionKey); pinned != "" {
+		trimmed = append([]*schema.Message{schema.SystemMessage(pinned)}, trimmed...)
+	}
+	return trimmed
+}
+
+// trimHistoryByTokens 从最早的消息开始丢弃，直到历史的估算 token 数不超过 maxTokens
+// 开头的系统消息（压缩摘要）始终保留；maxTokens <= 0 时不裁剪
+func trimHistoryByTokens(history []*schema.Message, maxTokens int) []*schema.Message {
+	if maxTokens <= 0 || len(history) == 0 {
+		return history
+	}
+
+	var summary []*schema.Message
+	if history[0].Role == schema.System {
+		summary, history = history[:1], history[1:]
+		maxTokens -= messageTokens(summary[0])
+	}
+
+	total := 0
+	start := len(history)
+	for start > 0 {
+		tokens := messageTokens(history[start-1])
+		if total+tokens > maxTokens {
+			break
+		}
+		total += tokens
+		start--
+	}
+	return append(summary, history[start:]...)
+}
+
+// messageTokens 估算单条消息的 token 数，包括工具调用的名称和参数
+func messageTokens(msg *schema.Message) int {
+	tokens := estimateTokens(msg.Content)
+	for _, tc := range msg.ToolCalls {
+		tokens += estimateTokens(tc.Function.Name) + estimateTokens(tc.Function.Arguments)
+	}
+	return tokens
+}
+
+// pairToolMessages 保证历史中的工具调用序列完整：
+// 发起工具调用的助手消息只有在每个调用都有对应的 tool 结果时才保留调用，否则只保留其文本内容；
+// 找不到对应调用的 tool 消息直接丢弃。模型接口会拒绝不成对的工具调用与结果
+func pairToolMessages(history []*schema.Message) []*schema.Message {
+	result := make([]*schema.Message, 0, len(history))
+	for i := 0; i < len(history); i++ {
+		msg := history[i]
+		if msg.Role == schema.Tool {
+			continue
+		}
+		if msg.Role != schema.Assistant || len(msg.ToolCalls) == 0 {
+			result = append(result, msg)
+			continue
+		}
+
+		// 收集紧随其后的 tool 消息，只取属于本次调用的结果
+		pending := make(map[string]bool, len(msg.ToolCalls))
+		for _, tc := range msg.ToolCalls {
+			pending[tc.ID] = true
+		}
+		var results []*schema.Message
+		for i+1 < len(history) && history[i+1].Role == schema.Tool {
+			i++
+			if pending[history[i].ToolCallID] {
+				delete(pending, history[i].ToolCallID)
+				results = append(results, history[i])
+			}
+		}
+
+		if len(pending) == 0 {
+			result = append(result, msg)
+			result = append(result, results...)
+			continue
+		}
+		if msg.Content != "" {
+			result = append(result, &schema.Message{Role: schema.Assistant, Content: msg.Content})
+		}
+	}
+	return result
+}
+
+// estimateTokens 粗略估算文本的 token 数：ASCII 字符约 4 个一个 token，其他字符（如中文）约一个字符一个 token
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
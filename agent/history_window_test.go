@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/session"
+	"go.uber.org/zap"
+)
+
+// TestInterruptible_loadHistory 测试按渠道的历史窗口加载会话历史
+func TestInterruptible_loadHistory(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.History = config.HistoryConfig{
+		HistoryWindow: config.HistoryWindow{MaxMessages: 6},
+		Channels: map[string]config.HistoryWindow{
+			"dingtalk": {MaxMessages: 2},
+			"feishu":   {MaxTokens: 9},
+		},
+	}
+	sessions := session.NewManager(cfg, zap.NewNop(), t.TempDir(), newDialogRepo("s", 20))
+	i := &interruptible{cfg: cfg, sessions: sessions, logger: zap.NewNop()}
+	ctx := context.Background()
+
+	tests := []struct {
+		channel string
+		want    int
+		last    string
+	}{
+		{"websocket", 6, "消息 19"},
+		{"dingtalk", 2, "消息 19"},
+		{"feishu", 3, "消息 19"}, // 每条约 3 个 token，上限 9
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			history := i.loadHistory(ctx, "s", tt.channel)
+			if len(history) != tt.want {
+				t.Fatalf("历史消息数 = %d, 期望 %d", len(history), tt.want)
+			}
+			if history[len(history)-1].Content != tt.last {
+				t.Errorf("最后一条 = %q, 期望 %q", history[len(history)-1].Content, tt.last)
+			}
+		})
+	}
+
+	t.Run("未配置时使用默认窗口", func(t *testing.T) {
+		i := &interruptible{sessions: sessions, logger: zap.NewNop()}
+		if history := i.loadHistory(ctx, "s", "websocket"); len(history) != config.DefaultHistoryMessages {
+			t.Errorf("历史消息数 = %d, 期望 %d", len(history), config.DefaultHistoryMessages)
+		}
+	})
+
+	t.Run("置顶笔记不受 token 上限裁剪", func(t *testing.T) {
+		if _, err := sessions.Pin("s", "我对花生过敏，推荐食谱时避开花生"); err != nil {
+			t.Fatalf("Pin() 返回错误: %v", err)
+		}
+		defer sessions.UnpinAll("s")
+
+		history := i.loadHistory(ctx, "s", "feishu")
+		if len(history) != 4 || history[0].Role != schema.System || !strings.Contains(history[0].Content, "我对花生过敏") {
+			t.Fatalf("history = %v, 期望置顶笔记位于最前面且保留 3 条历史", history)
+		}
+	})
+}
+
+// TestTrimHistoryByTokens 测试按估算 token 数裁剪历史
+func TestTrimHistoryByTokens(t *testing.T) {
+	history := []*schema.Message{
+		schema.SystemMessage("摘要"),
+		schema.UserMessage("第一条消息"),
+		schema.AssistantMessage("第二条消息", nil),
+		schema.UserMessage("第三条消息"),
+	}
+
+	got := trimHistoryByTokens(history, 12)
+	if len(got) != 3 || got[0].Role != schema.System || got[1].Content != "第二条消息" {
+		t.Errorf("trimHistoryByTokens() = %v, 期望保留摘要和最近两条", got)
+	}
+	if got := trimHistoryByTokens(history, 0); len(got) != len(history) {
+		t.Error("上限为 0 时不应裁剪")
+	}
+}
+
+// TestPairToolMessages 测试工具调用与工具结果的配对
+func TestPairToolMessages(t *testing.T) {
+	call := func(ids ...string) *schema.Message {
+		var calls []schema.ToolCall
+		for _, id := range ids {
+			calls = append(calls, schema.ToolCall{ID: id, Function: schema.FunctionCall{Name: "read_file"}})
+		}
+		return schema.AssistantMessage("我来看看", calls)
+	}
+	result := func(id string) *schema.Message {
+		return schema.ToolMessage("结果 "+id, id)
+	}
+
+	tests := []struct {
+		name    string
+		history []*schema.Message
+		want    []schema.RoleType
+	}{
+		{"完整序列", []*schema.Message{schema.UserMessage("问"), call("a", "b"), result("b"), result("a"), schema.AssistantMessage("答", nil)},
+			[]schema.RoleType{schema.User, schema.Assistant, schema.Tool, schema.Tool, schema.Assistant}},
+		{"开头的孤立结果", []*schema.Message{result("a"), schema.AssistantMessage("答", nil)},
+			[]schema.RoleType{schema.Assistant}},
+		{"缺少结果的调用只保留文本", []*schema.Message{call("a", "b"), result("a"), schema.UserMessage("问")},
+			[]schema.RoleType{schema.Assistant, schema.User}},
+		{"不属于本次调用的结果", []*schema.Message{call("a"), result("x"), result("a")},
+			[]schema.RoleType{schema.Assistant, schema.Tool}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pairToolMessages(tt.history)
+			if len(got) != len(tt.want) {
+				t.Fatalf("pairToolMessages() 返回 %d 条消息, 期望 %d", len(got), len(tt.want))
+			}
+			for i, msg := range got {
+				if msg.Role != tt.want[i] {
+					t.Errorf("第 %d 条角色 = %v, 期望 %v", i, msg.Role, tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("缺少结果时去掉工具调用", func(t *testing.T) {
+		got := pairToolMessages([]*schema.Message{call("a")})
+		if len(got) != 1 || len(got[0].ToolCalls) != 0 || got[0].Content != "我来看看" {
+			t.Errorf("pairToolMessages() = %v", got)
+		}
+	})
+}
+
+// TestEstimateTokens 测试 token 估算
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world!", 3},
+		{"你好", 2},
+		{"你好 world", 4},
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.text); got != tt.want {
+			t.Errorf("estimateTokens(%q) = %d, 期望 %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/cloudwego/eino/schema"
 	hooks "github.com/weibaohui/nanobot-go/agent/hooks"
 	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
 	"go.uber.org/zap"
 )
 
@@ -30,6 +31,9 @@ func CreateHookCallback(hookManager *hooks.HookManager, logger *zap.Logger) Hook
 
 		// 创建事件并分发
 		ctx := context.Background()
+		if chatID, ok := data["chat_id"].(string); ok && chatID != "" {
+			ctx = trace.WithChatID(ctx, chatID)
+		}
 		baseEvent := &events.BaseEvent{
 			TraceID:   hooks.GetTraceID(ctx),
 			EventType: eventType,
@@ -79,9 +83,18 @@ func CreateHookCallback(hookManager *hooks.HookManager, logger *zap.Logger) Hook
 			if response, ok := data["response"].(string); ok {
 				event.ResponseContent = response
 			}
+			if reasoning, ok := data["reasoning"].(string); ok {
+				event.ReasoningContent = reasoning
+			}
 			if toolCalls, ok := data["tool_calls"].([]schema.ToolCall); ok {
 				event.ToolCalls = toolCalls
 			}
+			if finishReason, ok := data["finish_reason"].(string); ok {
+				event.FinishReason = finishReason
+			}
+			if cacheHit, ok := data["cache_hit"].(bool); ok {
+				event.CacheHit = cacheHit
+			}
 			hookManager.Dispatch(ctx, event, channel, sessionKey)
 
 		case events.EventLLMCallError:
@@ -7,6 +7,7 @@ import (
 	"github.com/cloudwego/eino/callbacks"
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
 	"github.com/weibaohui/nanobot-go/agent/hooks/dispatcher"
 	"github.com/weibaohui/nanobot-go/agent/hooks/events"
 	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
@@ -191,6 +192,7 @@ func (cb *EinoCallbackBridge) handleToolEnd(ctx context.Context, traceID, spanID
 	}
 
 	event := events.NewToolCompletedEvent(traceID, spanID, parentSpanID, info.Name, toolOutput.Response, true)
+	event.ToolCallID = compose.GetToolCallID(ctx)
 	cb.dispatcher.Dispatch(ctx, event, channel, sessionKey)
 }
 
@@ -7,6 +7,7 @@ import (
 	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
 	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/utils"
 )
 
 // Event 事件接口
@@ -61,6 +62,9 @@ const (
 	EventComponentStart EventType = "component_start" // 组件开始执行
 	EventComponentEnd   EventType = "component_end"   // 组件执行完成
 	EventComponentError EventType = "component_error" // 组件执行错误
+
+	// 定时任务相关事件
+	EventCronJobFailed EventType = "cron_job_failed" // 定时任务执行失败
 )
 
 // BaseEvent 事件基类
@@ -126,10 +130,7 @@ type MessageReceivedEvent struct {
 
 // NewMessageReceivedEvent 创建收到消息事件
 func NewMessageReceivedEvent(traceID, spanID, parentSpanID string, msg *bus.InboundMessage) *MessageReceivedEvent {
-	preview := msg.Content
-	if len(preview) > 100 {
-		preview = preview[:100] + "..."
-	}
+	preview := utils.TruncateString(msg.Content, 100)
 
 	return &MessageReceivedEvent{
 		BaseEvent:  NewBaseEvent(traceID, spanID, parentSpanID, EventMessageReceived),
@@ -155,10 +156,7 @@ type MessageSentEvent struct {
 
 // NewMessageSentEvent 创建发送消息事件
 func NewMessageSentEvent(traceID, spanID, parentSpanID string, msg *bus.OutboundMessage, sessionKey string) *MessageSentEvent {
-	preview := msg.Content
-	if len(preview) > 100 {
-		preview = preview[:100] + "..."
-	}
+	preview := utils.TruncateString(msg.Content, 100)
 
 	return &MessageSentEvent{
 		BaseEvent:  NewBaseEvent(traceID, spanID, parentSpanID, EventMessageSent),
@@ -228,10 +226,11 @@ func NewToolUsedEvent(traceID, spanID, parentSpanID string, toolName, toolArgume
 // ToolCompletedEvent 工具执行完成事件
 type ToolCompletedEvent struct {
 	*BaseEvent
-	ToolName       string `json:"tool_name"`       // 工具名称
-	Response       string `json:"response"`        // 响应内容
-	ResponseLength int    `json:"response_length"` // 响应长度
-	Success        bool   `json:"success"`         // 是否成功
+	ToolName       string `json:"tool_name"`              // 工具名称
+	ToolCallID     string `json:"tool_call_id,omitempty"` // 模型发起的工具调用 ID
+	Response       string `json:"response"`               // 响应内容
+	ResponseLength int    `json:"response_length"`        // 响应长度
+	Success        bool   `json:"success"`                // 是否成功
 }
 
 // NewToolCompletedEvent 创建工具执行完成事件
@@ -337,21 +336,33 @@ func NewLLMCallStartEvent(traceID, spanID, parentSpanID string, info *callbacks.
 // LLMCallEndEvent LLM 调用结束事件 (来自 Eino callbacks)
 type LLMCallEndEvent struct {
 	*BaseEvent
-	Component       string            `json:"component"`        // 组件名称
-	Model           string            `json:"model"`            // 模型名称
-	ResponseContent string            `json:"response_content"` // 响应内容
-	ToolCalls       []schema.ToolCall `json:"tool_calls"`       // 工具调用列表
-	TokenUsage      *model.TokenUsage `json:"token_usage"`      // Token 使用情况
-	DurationMs      int64             `json:"duration_ms"`      // 持续时间 (毫秒)
+	Component        string            `json:"component"`                   // 组件名称
+	Model            string            `json:"model"`                       // 模型名称
+	ResponseContent  string            `json:"response_content"`            // 响应内容
+	ToolCalls        []schema.ToolCall `json:"tool_calls"`                  // 工具调用列表
+	TokenUsage       *model.TokenUsage `json:"token_usage"`                 // Token 使用情况
+	DurationMs       int64             `json:"duration_ms"`                 // 持续时间 (毫秒)
+	FinishReason     string            `json:"finish_reason"`               // 结束原因，如 stop、length、content_filter
+	CacheHit         bool              `json:"cache_hit"`                   // 是否命中响应缓存（命中时未调用提供商）
+	LogProbs         *schema.LogProbs  `json:"logprobs,omitempty"`          // 输出 token 的对数概率，仅在 providers.logProbs 开启时存在
+	ReasoningContent string            `json:"reasoning_content,omitempty"` // 推理模型（如 deepseek-reasoner）的思考过程
 }
 
 // NewLLMCallEndEvent 创建 LLM 调用结束事件
 func NewLLMCallEndEvent(traceID, spanID, parentSpanID string, info *callbacks.RunInfo, output *model.CallbackOutput, durationMs int64) *LLMCallEndEvent {
 	responseContent := ""
 	toolCalls := []schema.ToolCall{}
+	finishReason := ""
+	var logProbs *schema.LogProbs
+	reasoningContent := ""
 	if output.Message != nil {
 		responseContent = output.Message.Content
+		reasoningContent = output.Message.ReasoningContent
 		toolCalls = output.Message.ToolCalls
+		if output.Message.ResponseMeta != nil {
+			finishReason = output.Message.ResponseMeta.FinishReason
+			logProbs = output.Message.ResponseMeta.LogProbs
+		}
 	}
 
 	return &LLMCallEndEvent{
@@ -362,6 +373,9 @@ func NewLLMCallEndEvent(traceID, spanID, parentSpanID string, info *callbacks.Ru
 		ToolCalls:       toolCalls,
 		TokenUsage:      output.TokenUsage,
 		DurationMs:      durationMs,
+		FinishReason:    finishReason,
+		LogProbs:        logProbs,
+		ReasoningContent: reasoningContent,
 	}
 }
 
@@ -444,3 +458,21 @@ func NewComponentErrorEvent(traceID, spanID, parentSpanID string, info *callback
 		DurationMs: durationMs,
 	}
 }
+
+// CronJobFailedEvent 定时任务执行失败事件
+type CronJobFailedEvent struct {
+	*BaseEvent
+	JobID   string `json:"job_id"`   // 任务 ID
+	JobName string `json:"job_name"` // 任务名称
+	Error   string `json:"error"`    // 错误信息
+}
+
+// NewCronJobFailedEvent 创建定时任务执行失败事件
+func NewCronJobFailedEvent(traceID, jobID, jobName string, err error) *CronJobFailedEvent {
+	return &CronJobFailedEvent{
+		BaseEvent: NewBaseEvent(traceID, "", "", EventCronJobFailed),
+		JobID:     jobID,
+		JobName:   jobName,
+		Error:     err.Error(),
+	}
+}
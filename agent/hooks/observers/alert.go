@@ -0,0 +1,241 @@
+package observers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/agent/hooks/observer"
+	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// AlertRule 告警规则
+// 每个事件都会交给规则评估，规则返回非空的告警内容时触发告警
+type AlertRule interface {
+	// Name 返回规则名称，用于告警冷却
+	Name() string
+
+	// Evaluate 评估事件，需要告警时返回告警内容
+	Evaluate(event events.Event) string
+}
+
+// highErrorRateRule 工具/模型调用错误率过高告警
+type highErrorRateRule struct {
+	threshold  float64
+	minSamples int
+	window     time.Duration
+	outcomes   []callOutcome
+}
+
+// callOutcome 一次调用的结果
+type callOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// NewHighErrorRateAlertRule 创建错误率告警规则：窗口内调用次数不少于 minSamples 且错误率达到 threshold 时告警
+func NewHighErrorRateAlertRule(threshold float64, minSamples int, window time.Duration) AlertRule {
+	return &highErrorRateRule{threshold: threshold, minSamples: minSamples, window: window}
+}
+
+// Name 返回规则名称
+func (r *highErrorRateRule) Name() string {
+	return "high_error_rate"
+}
+
+// Evaluate 评估事件
+func (r *highErrorRateRule) Evaluate(event events.Event) string {
+	var failed bool
+	switch e := event.(type) {
+	case *events.ToolCompletedEvent:
+		failed = !e.Success
+	case *events.ToolErrorEvent, *events.LLMCallErrorEvent:
+		failed = true
+	case *events.LLMCallEndEvent:
+		failed = false
+	default:
+		return ""
+	}
+
+	now := event.GetTimestamp()
+	r.outcomes = append(r.outcomes, callOutcome{at: now, failed: failed})
+	cutoff := now.Add(-r.window)
+	for len(r.outcomes) > 0 && r.outcomes[0].at.Before(cutoff) {
+		r.outcomes = r.outcomes[1:]
+	}
+
+	if len(r.outcomes) < r.minSamples {
+		return ""
+	}
+	errors := 0
+	for _, o := range r.outcomes {
+		if o.failed {
+			errors++
+		}
+	}
+	rate := float64(errors) / float64(len(r.outcomes))
+	if rate < r.threshold {
+		return ""
+	}
+	return fmt.Sprintf("最近 %s 内工具/模型调用错误率 %.0f%%（%d/%d），超过阈值 %.0f%%",
+		r.window, rate*100, errors, len(r.outcomes), r.threshold*100)
+}
+
+// slowResponseRule 单轮响应过慢告警
+type slowResponseRule struct {
+	threshold time.Duration
+	started   map[string]time.Time // traceID -> 收到消息时间
+}
+
+// NewSlowResponseAlertRule 创建响应过慢告警规则：从收到消息到发出回复超过 threshold 时告警
+func NewSlowResponseAlertRule(threshold time.Duration) AlertRule {
+	return &slowResponseRule{threshold: threshold, started: make(map[string]time.Time)}
+}
+
+// Name 返回规则名称
+func (r *slowResponseRule) Name() string {
+	return "slow_response"
+}
+
+// Evaluate 评估事件
+func (r *slowResponseRule) Evaluate(event events.Event) string {
+	switch e := event.(type) {
+	case *events.MessageReceivedEvent:
+		// 清理长时间没有回复的记录，避免无限增长
+		for traceID, at := range r.started {
+			if e.Timestamp.Sub(at) > time.Hour {
+				delete(r.started, traceID)
+			}
+		}
+		r.started[e.TraceID] = e.Timestamp
+	case *events.MessageSentEvent:
+		start, ok := r.started[e.TraceID]
+		if !ok {
+			return ""
+		}
+		delete(r.started, e.TraceID)
+		if elapsed := e.Timestamp.Sub(start); elapsed >= r.threshold {
+			return fmt.Sprintf("会话 %s 的响应耗时 %s，超过阈值 %s",
+				e.SessionKey, elapsed.Round(time.Second), r.threshold)
+		}
+	}
+	return ""
+}
+
+// cronJobFailedRule 定时任务执行失败告警
+type cronJobFailedRule struct{}
+
+// NewCronJobFailedAlertRule 创建定时任务失败告警规则：任一定时任务执行失败时告警
+func NewCronJobFailedAlertRule() AlertRule {
+	return cronJobFailedRule{}
+}
+
+// Name 返回规则名称
+func (cronJobFailedRule) Name() string {
+	return "cron_job_failed"
+}
+
+// Evaluate 评估事件
+func (cronJobFailedRule) Evaluate(event events.Event) string {
+	e, ok := event.(*events.CronJobFailedEvent)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("定时任务 '%s'（id: %s）执行失败: %s", e.JobName, e.JobID, e.Error)
+}
+
+// AlertObserver 告警观察器
+// 按规则评估事件，触发告警时通过消息总线发送到管理员渠道，同一规则在冷却时间内只告警一次
+type AlertObserver struct {
+	*observer.BaseObserver
+	messageBus *bus.MessageBus
+	channel    string
+	chatID     string
+	cooldown   time.Duration
+	logger     *zap.Logger
+
+	mu        sync.Mutex
+	rules     []AlertRule
+	lastFired map[string]time.Time
+}
+
+// NewAlertObserver 创建告警观察器
+func NewAlertObserver(messageBus *bus.MessageBus, channel, chatID string, cooldown time.Duration, logger *zap.Logger, rules ...AlertRule) *AlertObserver {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &AlertObserver{
+		BaseObserver: observer.NewBaseObserver("alert", nil),
+		messageBus:   messageBus,
+		channel:      channel,
+		chatID:       chatID,
+		cooldown:     cooldown,
+		logger:       logger,
+		rules:        rules,
+		lastFired:    make(map[string]time.Time),
+	}
+}
+
+// NewAlertObserverFromConfig 按配置创建告警观察器并注册预定义规则，未配置接收渠道时返回 nil
+func NewAlertObserverFromConfig(cfg config.AlertsConfig, messageBus *bus.MessageBus, logger *zap.Logger) *AlertObserver {
+	if cfg.Channel == "" || cfg.ChatID == "" {
+		return nil
+	}
+
+	errorRate := cfg.ErrorRate
+	if errorRate <= 0 {
+		errorRate = 0.5
+	}
+	minSamples := cfg.MinSamples
+	if minSamples <= 0 {
+		minSamples = 10
+	}
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+	if window <= 0 {
+		window = 10 * time.Minute
+	}
+	slow := time.Duration(cfg.SlowResponseSeconds) * time.Second
+	if slow <= 0 {
+		slow = 120 * time.Second
+	}
+	cooldown := time.Duration(cfg.CooldownMinutes) * time.Minute
+	if cooldown <= 0 {
+		cooldown = 30 * time.Minute
+	}
+
+	return NewAlertObserver(messageBus, cfg.Channel, cfg.ChatID, cooldown, logger,
+		NewHighErrorRateAlertRule(errorRate, minSamples, window),
+		NewSlowResponseAlertRule(slow),
+		NewCronJobFailedAlertRule(),
+	)
+}
+
+// OnEvent 处理事件
+func (o *AlertObserver) OnEvent(ctx context.Context, event events.Event) error {
+	o.mu.Lock()
+	var alerts []string
+	for _, rule := range o.rules {
+		content := rule.Evaluate(event)
+		if content == "" {
+			continue
+		}
+		if last, ok := o.lastFired[rule.Name()]; ok && event.GetTimestamp().Sub(last) < o.cooldown {
+			continue
+		}
+		o.lastFired[rule.Name()] = event.GetTimestamp()
+		alerts = append(alerts, content)
+	}
+	o.mu.Unlock()
+
+	for _, content := range alerts {
+		o.logger.Warn("触发告警", zap.String("alert", content))
+		if o.messageBus != nil {
+			o.messageBus.PublishOutbound(bus.NewOutboundMessage(o.channel, o.chatID, "⚠️ nanobot 告警: "+content))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package observers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// TestHighErrorRateAlertRule 测试错误率告警规则
+func TestHighErrorRateAlertRule(t *testing.T) {
+	rule := NewHighErrorRateAlertRule(0.5, 4, time.Minute)
+
+	outcomes := []events.Event{
+		events.NewToolCompletedEvent("t", "s", "", "exec", "ok", true),
+		events.NewToolErrorEvent("t", "s", "", "exec", "boom"),
+		events.NewToolCompletedEvent("t", "s", "", "exec", "ok", true),
+	}
+	for _, e := range outcomes {
+		if alert := rule.Evaluate(e); alert != "" {
+			t.Fatalf("样本不足时不应告警, got %q", alert)
+		}
+	}
+	// 非调用事件不计入样本
+	if alert := rule.Evaluate(events.NewSystemPromptBuiltEvent("t", "s", "", "prompt")); alert != "" {
+		t.Fatalf("非调用事件不应告警, got %q", alert)
+	}
+
+	alert := rule.Evaluate(events.NewToolErrorEvent("t", "s", "", "exec", "boom"))
+	if !strings.Contains(alert, "50%") || !strings.Contains(alert, "2/4") {
+		t.Errorf("错误率达到阈值时应告警, got %q", alert)
+	}
+}
+
+// TestCronJobFailedAlertRule 测试定时任务失败告警规则
+func TestCronJobFailedAlertRule(t *testing.T) {
+	rule := NewCronJobFailedAlertRule()
+	alert := rule.Evaluate(events.NewCronJobFailedEvent("t", "job-1", "每日总结", context.DeadlineExceeded))
+	if !strings.Contains(alert, "每日总结") || !strings.Contains(alert, "job-1") {
+		t.Errorf("定时任务失败时应告警, got %q", alert)
+	}
+	if alert := rule.Evaluate(events.NewToolErrorEvent("t", "s", "", "exec", "boom")); alert != "" {
+		t.Errorf("其他事件不应告警, got %q", alert)
+	}
+}
+
+// TestSlowResponseAlertRule 测试响应过慢告警规则
+func TestSlowResponseAlertRule(t *testing.T) {
+	rule := NewSlowResponseAlertRule(30 * time.Second)
+
+	received := events.NewMessageReceivedEvent("trace1", "s", "", &bus.InboundMessage{Channel: "websocket", ChatID: "c1"})
+	received.Timestamp = time.Now().Add(-time.Minute)
+	rule.Evaluate(received)
+
+	fast := events.NewMessageSentEvent("trace2", "s", "", bus.NewOutboundMessage("websocket", "c1", "hi"), "websocket:c1")
+	if alert := rule.Evaluate(fast); alert != "" {
+		t.Errorf("未记录开始时间的回复不应告警, got %q", alert)
+	}
+
+	slow := events.NewMessageSentEvent("trace1", "s", "", bus.NewOutboundMessage("websocket", "c1", "hi"), "websocket:c1")
+	if alert := rule.Evaluate(slow); !strings.Contains(alert, "websocket:c1") {
+		t.Errorf("响应超过阈值时应告警, got %q", alert)
+	}
+}
+
+// TestAlertObserver_OnEvent 测试告警发送到管理员渠道及冷却
+func TestAlertObserver_OnEvent(t *testing.T) {
+	messageBus := bus.NewMessageBus(zap.NewNop())
+	obs := NewAlertObserverFromConfig(config.AlertsConfig{
+		Channel:    "feishu",
+		ChatID:     "admin",
+		ErrorRate:  0.5,
+		MinSamples: 2,
+	}, messageBus, nil)
+	if obs == nil {
+		t.Fatal("配置接收渠道后应创建告警观察器")
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		_ = obs.OnEvent(ctx, events.NewToolErrorEvent("t", "s", "", "exec", "boom"))
+	}
+
+	readCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	msg, err := messageBus.ConsumeOutbound(readCtx)
+	if err != nil {
+		t.Fatalf("未收到告警消息: %v", err)
+	}
+	if msg.Channel != "feishu" || msg.ChatID != "admin" || !strings.Contains(msg.Content, "错误率") {
+		t.Errorf("告警消息 = %+v", msg)
+	}
+
+	// 冷却时间内不重复告警
+	shortCtx, shortCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer shortCancel()
+	if msg, err := messageBus.ConsumeOutbound(shortCtx); err == nil {
+		t.Errorf("冷却时间内不应重复告警, got %+v", msg)
+	}
+
+	if NewAlertObserverFromConfig(config.AlertsConfig{}, messageBus, nil) != nil {
+		t.Error("未配置接收渠道时不应创建告警观察器")
+	}
+}
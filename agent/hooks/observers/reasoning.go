@@ -0,0 +1,62 @@
+package observers
+
+import (
+	"context"
+
+	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/agent/hooks/observer"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// ReasoningObserver 推理过程观察器
+// 推理模型（如 deepseek-reasoner）返回思考过程时，向流式通道推送 thinking 片段，
+// 富客户端可以将其与回复正文分开展示；思考过程不会进入会话历史回放给模型
+type ReasoningObserver struct {
+	*observer.BaseObserver
+	messageBus *bus.MessageBus
+	show       func(sessionKey string) bool
+	logger     *zap.Logger
+}
+
+// NewReasoningObserver 创建推理过程观察器
+// show 判断会话是否展示思考过程，为 nil 时所有会话都展示
+func NewReasoningObserver(messageBus *bus.MessageBus, show func(sessionKey string) bool, logger *zap.Logger, filter *observer.ObserverFilter) *ReasoningObserver {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ReasoningObserver{
+		BaseObserver: observer.NewBaseObserver("reasoning", filter),
+		messageBus:   messageBus,
+		show:         show,
+		logger:       logger,
+	}
+}
+
+// OnEvent 处理事件
+func (o *ReasoningObserver) OnEvent(ctx context.Context, event events.Event) error {
+	if o.messageBus == nil {
+		return nil
+	}
+
+	e, ok := event.(*events.LLMCallEndEvent)
+	if !ok || e.ReasoningContent == "" {
+		return nil
+	}
+	if o.show != nil && !o.show(trace.GetSessionKey(ctx)) {
+		return nil
+	}
+
+	channel := trace.GetChannel(ctx)
+	chatID := trace.GetChatID(ctx)
+	if channel == "" || chatID == "" {
+		o.logger.Debug("缺少会话信息，跳过思考过程推送",
+			zap.String("trace_id", e.TraceID),
+		)
+		return nil
+	}
+
+	o.messageBus.PublishStream(bus.NewThinkingChunk(channel, chatID, e.ReasoningContent))
+	return nil
+}
@@ -0,0 +1,61 @@
+package observers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/callbacks"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// TestReasoningObserver_OnEvent 测试思考过程被转换为 thinking 片段
+func TestReasoningObserver_OnEvent(t *testing.T) {
+	messageBus := bus.NewMessageBus(zap.NewNop())
+	chunks := make(chan *bus.StreamChunk, 4)
+	messageBus.SubscribeStream("websocket", func(chunk *bus.StreamChunk) error {
+		chunks <- chunk
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messageBus.StartDispatcher(ctx)
+
+	obs := NewReasoningObserver(messageBus, func(sessionKey string) bool {
+		return sessionKey != "websocket:hidden"
+	}, nil, nil)
+	evCtx := trace.WithChatID(trace.WithSessionInfo(ctx, "websocket:chat1", "websocket"), "chat1")
+	newEvent := func(reasoning string) *events.LLMCallEndEvent {
+		return events.NewLLMCallEndEvent("t", "s", "", &callbacks.RunInfo{Component: "LLM"},
+			&model.CallbackOutput{Message: &schema.Message{Content: "42", ReasoningContent: reasoning}}, 10)
+	}
+
+	t.Run("有思考过程时推送 thinking 片段", func(t *testing.T) {
+		_ = obs.OnEvent(evCtx, newEvent("先算 6 乘 7"))
+		select {
+		case chunk := <-chunks:
+			if !chunk.IsThinking() || chunk.ChatID != "chat1" || chunk.Content != "先算 6 乘 7" {
+				t.Errorf("片段 = %+v", chunk)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("未收到 thinking 片段")
+		}
+	})
+
+	t.Run("没有思考过程、缺少会话信息或会话隐藏时跳过", func(t *testing.T) {
+		_ = obs.OnEvent(evCtx, newEvent(""))
+		_ = obs.OnEvent(ctx, newEvent("思考"))
+		hiddenCtx := trace.WithChatID(trace.WithSessionInfo(ctx, "websocket:hidden", "websocket"), "hidden")
+		_ = obs.OnEvent(hiddenCtx, newEvent("思考"))
+		select {
+		case chunk := <-chunks:
+			t.Errorf("不应推送片段: %+v", chunk)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
@@ -2,6 +2,7 @@ package observers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/weibaohui/nanobot-go/agent/hooks/events"
@@ -138,30 +139,44 @@ func (o *SQLiteObserver) handleLLMCallEnd(ctx context.Context, event events.Even
 
 	baseEvent := event.ToBaseEvent()
 
-	var role, content string
+	// 发起工具调用的助手消息保留完整的 tool_calls，回放历史时与后续的 tool 消息一一对应
+	var toolCalls string
 	if len(e.ToolCalls) > 0 {
-		role = "tool"
-		for _, tc := range e.ToolCalls {
-			content += tc.Function.Name + "(" + tc.Function.Arguments + ") "
+		data, err := json.Marshal(e.ToolCalls)
+		if err != nil {
+			o.logger.Error("序列化工具调用失败", zap.Error(err), zap.String("trace_id", baseEvent.TraceID))
+			return err
 		}
-	} else {
-		role = "assistant"
-		content = e.ResponseContent
+		toolCalls = string(data)
 	}
 
-	if content == "" {
+	if e.ResponseContent == "" && toolCalls == "" {
 		return nil
 	}
 
+	var logProbs string
+	if e.LogProbs != nil && len(e.LogProbs.Content) > 0 {
+		data, err := json.Marshal(e.LogProbs)
+		if err != nil {
+			o.logger.Warn("序列化 logprobs 失败", zap.Error(err), zap.String("trace_id", baseEvent.TraceID))
+		} else {
+			logProbs = string(data)
+		}
+	}
+
 	dto := &service.ConversationDTO{
-		TraceID:      baseEvent.TraceID,
-		SpanID:       baseEvent.SpanID,
-		ParentSpanID: baseEvent.ParentSpanID,
-		EventType:    string(baseEvent.EventType),
-		Timestamp:    baseEvent.Timestamp,
-		SessionKey:   sessionKey,
-		Role:         role,
-		Content:      content,
+		TraceID:          baseEvent.TraceID,
+		SpanID:           baseEvent.SpanID,
+		ParentSpanID:     baseEvent.ParentSpanID,
+		EventType:        string(baseEvent.EventType),
+		Timestamp:        baseEvent.Timestamp,
+		SessionKey:       sessionKey,
+		Role:             "assistant",
+		Content:          e.ResponseContent,
+		FinishReason:     e.FinishReason,
+		ToolCalls:        toolCalls,
+		LogProbs:         logProbs,
+		ReasoningContent: e.ReasoningContent,
 	}
 
 	if e.TokenUsage != nil {
@@ -210,8 +225,10 @@ func (o *SQLiteObserver) handleToolCompleted(ctx context.Context, event events.E
 		EventType:    string(baseEvent.EventType),
 		Timestamp:    baseEvent.Timestamp,
 		SessionKey:   sessionKey,
-		Role:         "tool_result",
-		Content:      e.ToolName + ": " + content,
+		Role:         "tool",
+		Content:      content,
+		ToolCallID:   e.ToolCallID,
+		ToolName:     e.ToolName,
 	}
 
 	if err := o.creator.Create(ctx, dto); err != nil {
@@ -219,21 +236,30 @@ func (o *SQLiteObserver) handleToolCompleted(ctx context.Context, event events.E
 		return err
 	}
 
-	o.deduplicate(ctx, baseEvent.TraceID, "tool_result", e.ToolName+": "+content)
+	o.deduplicate(ctx, baseEvent.TraceID, "tool", content, e.ToolCallID)
 	return nil
 }
 
-func (o *SQLiteObserver) deduplicate(ctx context.Context, traceID, role, content string) {
+// deduplicate 删除同一链路中重复写入的记录，只保留一条
+// 内容相同但工具调用 ID 不同的 tool 消息是不同调用的结果，不视为重复
+func (o *SQLiteObserver) deduplicate(ctx context.Context, traceID, role, content, toolCallID string) {
 	if o.repo == nil {
 		return
 	}
 
-	records, err := o.repo.FindByTraceIDRoleAndContent(ctx, traceID, role, content)
+	found, err := o.repo.FindByTraceIDRoleAndContent(ctx, traceID, role, content)
 	if err != nil {
 		o.logger.Error("查询重复记录失败", zap.Error(err))
 		return
 	}
 
+	var records []models.ConversationRecord
+	for _, r := range found {
+		if r.ToolCallID == toolCallID {
+			records = append(records, r)
+		}
+	}
+
 	if len(records) <= 1 {
 		return
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cloudwego/eino/callbacks"
@@ -121,6 +122,7 @@ func TestSQLiteObserver_ToolCompleted(t *testing.T) {
 
 	ctx := trace.WithSessionKey(context.Background(), "session-1")
 	event := events.NewToolCompletedEvent("trace-1", "span-1", "", "read_file", "文件内容", true)
+	event.ToolCallID = "call_1"
 
 	if err := obs.OnEvent(ctx, event); err != nil {
 		t.Fatalf("处理事件失败: %v", err)
@@ -133,8 +135,103 @@ func TestSQLiteObserver_ToolCompleted(t *testing.T) {
 	if len(result) != 1 {
 		t.Fatalf("记录数量错误: got %d, want 1", len(result))
 	}
-	if result[0].Role != "tool_result" {
-		t.Errorf("role 错误: got %s, want tool_result", result[0].Role)
+	if result[0].Role != "tool" {
+		t.Errorf("role 错误: got %s, want tool", result[0].Role)
+	}
+	if result[0].ToolCallID != "call_1" || result[0].ToolName != "read_file" || result[0].Content != "文件内容" {
+		t.Errorf("工具结果记录错误: %+v", result[0])
+	}
+}
+
+func TestSQLiteObserver_LLMCallEndWithToolCalls(t *testing.T) {
+	obs, dbClient, _, convService := createTestObserver(t)
+	defer dbClient.Close()
+
+	ctx := trace.WithSessionKey(context.Background(), "session-1")
+	event := events.NewLLMCallEndEvent("trace-1", "span-1", "",
+		&callbacks.RunInfo{Component: "LLM"},
+		&model.CallbackOutput{Message: &schema.Message{ToolCalls: []schema.ToolCall{
+			{ID: "call_1", Function: schema.FunctionCall{Name: "read_file", Arguments: `{"path":"a.txt"}`}},
+		}}},
+		100,
+	)
+
+	if err := obs.OnEvent(ctx, event); err != nil {
+		t.Fatalf("处理事件失败: %v", err)
+	}
+
+	result, err := convService.GetByTraceID(context.Background(), "trace-1")
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("记录数量错误: got %d, want 1", len(result))
+	}
+	if result[0].Role != "assistant" {
+		t.Errorf("role 错误: got %s, want assistant", result[0].Role)
+	}
+	if !strings.Contains(result[0].ToolCalls, `"id":"call_1"`) {
+		t.Errorf("tool_calls 错误: %s", result[0].ToolCalls)
+	}
+}
+
+func TestSQLiteObserver_LLMCallEndWithLogProbs(t *testing.T) {
+	obs, dbClient, _, convService := createTestObserver(t)
+	defer dbClient.Close()
+
+	ctx := trace.WithSessionKey(context.Background(), "session-1")
+	event := events.NewLLMCallEndEvent("trace-1", "span-1", "",
+		&callbacks.RunInfo{Component: "LLM"},
+		&model.CallbackOutput{Message: &schema.Message{Content: "晴", ResponseMeta: &schema.ResponseMeta{
+			LogProbs: &schema.LogProbs{Content: []schema.LogProb{{Token: "晴", LogProb: -0.5}}},
+		}}},
+		100,
+	)
+
+	if err := obs.OnEvent(ctx, event); err != nil {
+		t.Fatalf("处理事件失败: %v", err)
+	}
+
+	result, err := convService.GetByTraceID(context.Background(), "trace-1")
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(result) != 1 || !strings.Contains(result[0].LogProbs, `"logprob":-0.5`) {
+		t.Errorf("logprobs 未记录: %+v", result)
+	}
+}
+
+func TestSQLiteObserver_LLMCallEndWithReasoning(t *testing.T) {
+	obs, dbClient, _, convService := createTestObserver(t)
+	defer dbClient.Close()
+
+	ctx := trace.WithSessionKey(context.Background(), "session-1")
+	event := events.NewLLMCallEndEvent("trace-1", "span-1", "",
+		&callbacks.RunInfo{Component: "LLM"},
+		&model.CallbackOutput{
+			Message: &schema.Message{Content: "42", ReasoningContent: "先算 6 乘 7"},
+			TokenUsage: &model.TokenUsage{
+				CompletionTokens:        30,
+				TotalTokens:             40,
+				CompletionTokensDetails: model.CompletionTokensDetails{ReasoningTokens: 25},
+			},
+		},
+		100,
+	)
+
+	if err := obs.OnEvent(ctx, event); err != nil {
+		t.Fatalf("处理事件失败: %v", err)
+	}
+
+	result, err := convService.GetByTraceID(context.Background(), "trace-1")
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(result) != 1 || result[0].Content != "42" || result[0].ReasoningContent != "先算 6 乘 7" {
+		t.Fatalf("思考过程未单独记录: %+v", result)
+	}
+	if result[0].TokenUsage == nil || result[0].TokenUsage.ReasoningTokens != 25 {
+		t.Errorf("ReasoningTokens 未记录: %+v", result[0].TokenUsage)
 	}
 }
 
@@ -12,6 +12,7 @@ import (
 	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
 	"github.com/weibaohui/nanobot-go/bus"
 	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/utils"
 	"go.uber.org/zap"
 )
 
@@ -198,20 +199,14 @@ func (o *ThinkingProcessObserver) formatMessage(event events.Event) string {
 // formatToolUsed 格式化工具调用事件
 func (o *ThinkingProcessObserver) formatToolUsed(e *events.ToolUsedEvent) string {
 	// 简化参数显示
-	args := e.ToolArguments
-	if len(args) > 100 {
-		args = args[:100] + "..."
-	}
+	args := utils.TruncateString(e.ToolArguments, 100)
 	return fmt.Sprintf("🔧 **调用工具**: `%s`\n```\n%s\n```", e.ToolName, args)
 }
 
 // formatToolCompleted 格式化工具完成事件
 func (o *ThinkingProcessObserver) formatToolCompleted(e *events.ToolCompletedEvent) string {
 	// 简化响应显示
-	resp := e.Response
-	if len(resp) > 200 {
-		resp = resp[:200] + "..."
-	}
+	resp := utils.TruncateString(e.Response, 200)
 	// 清理响应中的多余空白
 	resp = strings.TrimSpace(resp)
 	if resp == "" {
@@ -238,10 +233,7 @@ func (o *ThinkingProcessObserver) formatLLMCallEnd(e *events.LLMCallEndEvent) st
 	}
 
 	// 限制内容长度，避免发送过长消息
-	content := e.ResponseContent
-	if len(content) > 500 {
-		content = content[:500] + "..."
-	}
+	content := utils.TruncateString(e.ResponseContent, 500)
 
 	// 如果有工具调用，显示工具调用信息而不是响应内容
 	if len(e.ToolCalls) > 0 {
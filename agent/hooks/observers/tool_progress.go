@@ -0,0 +1,74 @@
+package observers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/agent/hooks/observer"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// ToolProgressObserver 工具进度观察器
+// 在工具开始/结束时向流式通道推送轻量的状态片段（type=status），
+// 富客户端可以将其与回复正文分开展示，让多工具的长回合也能及时反馈进度
+type ToolProgressObserver struct {
+	*observer.BaseObserver
+	messageBus *bus.MessageBus
+	logger     *zap.Logger
+}
+
+// NewToolProgressObserver 创建工具进度观察器
+func NewToolProgressObserver(messageBus *bus.MessageBus, logger *zap.Logger, filter *observer.ObserverFilter) *ToolProgressObserver {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &ToolProgressObserver{
+		BaseObserver: observer.NewBaseObserver("tool_progress", filter),
+		messageBus:   messageBus,
+		logger:       logger,
+	}
+}
+
+// OnEvent 处理事件
+func (o *ToolProgressObserver) OnEvent(ctx context.Context, event events.Event) error {
+	if o.messageBus == nil {
+		return nil
+	}
+
+	status := formatToolProgress(event)
+	if status == "" {
+		return nil
+	}
+
+	channel := trace.GetChannel(ctx)
+	chatID := trace.GetChatID(ctx)
+	if channel == "" || chatID == "" {
+		o.logger.Debug("缺少会话信息，跳过工具进度推送",
+			zap.String("event_type", string(event.GetEventType())),
+		)
+		return nil
+	}
+
+	o.messageBus.PublishStream(bus.NewStatusChunk(channel, chatID, status))
+	return nil
+}
+
+// formatToolProgress 将工具事件格式化为简短的状态文本，非工具事件返回空字符串
+func formatToolProgress(event events.Event) string {
+	switch e := event.(type) {
+	case *events.ToolUsedEvent:
+		return fmt.Sprintf("正在调用工具 %s...", e.ToolName)
+	case *events.ToolCompletedEvent:
+		if !e.Success {
+			return fmt.Sprintf("工具 %s 执行未成功", e.ToolName)
+		}
+		return fmt.Sprintf("工具 %s 执行完成", e.ToolName)
+	case *events.ToolErrorEvent:
+		return fmt.Sprintf("工具 %s 执行出错", e.ToolName)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,66 @@
+package observers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// TestToolProgressObserver_OnEvent 测试工具事件被转换为状态片段
+func TestToolProgressObserver_OnEvent(t *testing.T) {
+	messageBus := bus.NewMessageBus(zap.NewNop())
+	chunks := make(chan *bus.StreamChunk, 4)
+	messageBus.SubscribeStream("websocket", func(chunk *bus.StreamChunk) error {
+		chunks <- chunk
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messageBus.StartDispatcher(ctx)
+
+	obs := NewToolProgressObserver(messageBus, nil, nil)
+
+	t.Run("工具开始与完成推送状态片段", func(t *testing.T) {
+		evCtx := trace.WithChatID(trace.WithSessionInfo(ctx, "websocket:chat1", "websocket"), "chat1")
+		_ = obs.OnEvent(evCtx, events.NewToolUsedEvent("t", "s", "", "web_search", `{"q":"go"}`))
+		_ = obs.OnEvent(evCtx, events.NewToolCompletedEvent("t", "s", "", "web_search", "ok", true))
+
+		want := []string{"正在调用工具 web_search...", "工具 web_search 执行完成"}
+		for _, w := range want {
+			select {
+			case chunk := <-chunks:
+				if !chunk.IsStatus() || chunk.ChatID != "chat1" || chunk.Content != w {
+					t.Errorf("片段 = %+v, 期望状态 %q", chunk, w)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("未收到状态片段 %q", w)
+			}
+		}
+	})
+
+	t.Run("缺少会话信息时跳过，工具出错时推送状态", func(t *testing.T) {
+		_ = obs.OnEvent(ctx, events.NewToolUsedEvent("t", "s", "", "web_search", "{}"))
+		evCtx := trace.WithChatID(trace.WithSessionInfo(ctx, "websocket:chat1", "websocket"), "chat1")
+		_ = obs.OnEvent(evCtx, events.NewToolErrorEvent("t", "s", "", "exec", "boom"))
+
+		select {
+		case chunk := <-chunks:
+			if chunk.Content != "工具 exec 执行出错" {
+				t.Errorf("意外的片段: %+v", chunk)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("未收到工具错误状态片段")
+		}
+
+		select {
+		case chunk := <-chunks:
+			t.Errorf("不应推送额外片段: %+v", chunk)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,265 @@
+package observers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/agent/hooks/observer"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// webhookQueueSize 待推送事件队列容量，队列满时丢弃新事件，避免拖慢主流程
+const webhookQueueSize = 1000
+
+// DefaultWebhookEvents 未配置事件类型时推送的生命周期事件
+var DefaultWebhookEvents = []events.EventType{
+	events.EventMessageReceived,
+	events.EventMessageSent,
+	events.EventToolUsed,
+	events.EventToolCompleted,
+	events.EventToolError,
+	events.EventLLMCallError,
+	events.EventComponentError,
+}
+
+// WebhookEvent 推送到 Webhook 的单个事件
+type WebhookEvent struct {
+	EventType  events.EventType `json:"event_type"`
+	TraceID    string           `json:"trace_id"`
+	Timestamp  time.Time        `json:"timestamp"`
+	Channel    string           `json:"channel,omitempty"`
+	SessionKey string           `json:"session_key,omitempty"`
+	Data       events.Event     `json:"data"` // 原始事件
+}
+
+// WebhookPayload 单次推送的请求体
+type WebhookPayload struct {
+	Events []WebhookEvent `json:"events"`
+}
+
+// WebhookObserver Webhook 观察器
+// 将事件缓存在队列中，攒满一批或到达刷新间隔后以 JSON POST 到外部地址，失败时按指数退避重试
+type WebhookObserver struct {
+	*observer.BaseObserver
+	url           string
+	headers       map[string]string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+	maxAttempts   int
+	backoff       time.Duration // 首次重试前的等待时间，之后每次翻倍
+	logger        *zap.Logger
+
+	queue   chan WebhookEvent
+	dropped atomic.Int64 // 队列已满被丢弃的事件数
+	failed  atomic.Int64 // 多次重试后仍推送失败的事件数
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// NewWebhookObserver 创建 Webhook 观察器并启动后台推送
+func NewWebhookObserver(cfg config.WebhookConfig, logger *zap.Logger) *WebhookObserver {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	eventTypes := DefaultWebhookEvents
+	if len(cfg.Events) > 0 {
+		eventTypes = make([]events.EventType, len(cfg.Events))
+		for i, e := range cfg.Events {
+			eventTypes[i] = events.EventType(e)
+		}
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 20
+	}
+	flushInterval := time.Duration(cfg.FlushIntervalMs) * time.Millisecond
+	if flushInterval <= 0 {
+		flushInterval = 2 * time.Second
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	o := &WebhookObserver{
+		BaseObserver:  observer.NewBaseObserver("webhook", &observer.ObserverFilter{EventTypes: eventTypes}),
+		url:           cfg.URL,
+		headers:       cfg.Headers,
+		client:        &http.Client{Timeout: timeout},
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		maxAttempts:   maxAttempts,
+		backoff:       time.Second,
+		logger:        logger,
+		queue:         make(chan WebhookEvent, webhookQueueSize),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go o.run()
+	return o
+}
+
+// OnEvent 处理事件，只入队不阻塞
+func (o *WebhookObserver) OnEvent(ctx context.Context, event events.Event) error {
+	channel := trace.GetChannel(ctx)
+	sessionKey := trace.GetSessionKey(ctx)
+	if !o.ShouldNotify(event.GetEventType(), channel, sessionKey) {
+		return nil
+	}
+
+	item := WebhookEvent{
+		EventType:  event.GetEventType(),
+		TraceID:    event.GetTraceID(),
+		Timestamp:  event.GetTimestamp(),
+		Channel:    channel,
+		SessionKey: sessionKey,
+		Data:       event,
+	}
+	select {
+	case <-o.done:
+		return nil
+	default:
+	}
+	select {
+	case o.queue <- item:
+	default:
+		o.dropped.Add(1)
+	}
+	return nil
+}
+
+// Dropped 返回因队列已满被丢弃的事件数
+func (o *WebhookObserver) Dropped() int64 {
+	return o.dropped.Load()
+}
+
+// Failed 返回多次重试后仍推送失败的事件数
+func (o *WebhookObserver) Failed() int64 {
+	return o.failed.Load()
+}
+
+// Close 停止后台推送，并尽量推送队列中剩余的事件
+func (o *WebhookObserver) Close() {
+	o.closeOnce.Do(func() {
+		close(o.done)
+		<-o.stopped
+	})
+}
+
+// run 后台批量推送循环
+func (o *WebhookObserver) run() {
+	defer close(o.stopped)
+
+	ticker := time.NewTicker(o.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]WebhookEvent, 0, o.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		o.send(batch)
+		batch = make([]WebhookEvent, 0, o.batchSize)
+	}
+
+	for {
+		select {
+		case item := <-o.queue:
+			batch = append(batch, item)
+			if len(batch) >= o.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-o.done:
+			// 推送队列中剩余的事件后退出
+			for {
+				select {
+				case item := <-o.queue:
+					batch = append(batch, item)
+					if len(batch) >= o.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// send 推送一批事件，失败时按指数退避重试，关闭时不再等待重试
+func (o *WebhookObserver) send(batch []WebhookEvent) {
+	body, err := json.Marshal(WebhookPayload{Events: batch})
+	if err != nil {
+		o.logger.Error("序列化 Webhook 事件失败", zap.Error(err))
+		o.failed.Add(int64(len(batch)))
+		return
+	}
+
+	wait := o.backoff
+	for attempt := 1; attempt <= o.maxAttempts; attempt++ {
+		if err = o.post(body); err == nil {
+			return
+		}
+		if attempt == o.maxAttempts {
+			break
+		}
+		select {
+		case <-time.After(wait):
+			wait *= 2
+		case <-o.done:
+			attempt = o.maxAttempts
+		}
+	}
+
+	o.failed.Add(int64(len(batch)))
+	o.logger.Warn("推送 Webhook 事件失败",
+		zap.String("url", o.url),
+		zap.Int("events", len(batch)),
+		zap.Error(err),
+	)
+}
+
+// post 发送一次请求，非 2xx 响应视为失败
+func (o *WebhookObserver) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range o.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package observers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/agent/hooks/events"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"github.com/weibaohui/nanobot-go/config"
+)
+
+// webhookPayloadRecorder 记录收到的 Webhook 请求
+type webhookPayloadRecorder struct {
+	mu       sync.Mutex
+	payloads []map[string]any
+	headers  []string
+}
+
+func (r *webhookPayloadRecorder) events() []map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []map[string]any
+	for _, p := range r.payloads {
+		for _, e := range p["events"].([]any) {
+			result = append(result, e.(map[string]any))
+		}
+	}
+	return result
+}
+
+// TestWebhookObserver_Batching 测试事件批量推送与过滤
+func TestWebhookObserver_Batching(t *testing.T) {
+	recorder := &webhookPayloadRecorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		recorder.mu.Lock()
+		recorder.payloads = append(recorder.payloads, payload)
+		recorder.headers = append(recorder.headers, r.Header.Get("Authorization"))
+		recorder.mu.Unlock()
+	}))
+	defer server.Close()
+
+	obs := NewWebhookObserver(config.WebhookConfig{
+		URL:             server.URL,
+		Headers:         map[string]string{"Authorization": "Bearer token"},
+		BatchSize:       2,
+		FlushIntervalMs: 50,
+	}, nil)
+
+	ctx := trace.WithSessionInfo(context.Background(), "websocket:chat1", "websocket")
+	_ = obs.OnEvent(ctx, events.NewToolUsedEvent("t1", "s1", "", "exec", "{}"))
+	_ = obs.OnEvent(ctx, events.NewToolErrorEvent("t1", "s2", "", "exec", "boom"))
+	// 默认事件列表不包含系统提示构建事件
+	_ = obs.OnEvent(ctx, events.NewSystemPromptBuiltEvent("t1", "s3", "", "prompt"))
+	_ = obs.OnEvent(ctx, events.NewToolCompletedEvent("t1", "s4", "", "exec", "ok", true))
+	obs.Close()
+
+	got := recorder.events()
+	if len(got) != 3 {
+		t.Fatalf("推送事件数 = %d, 期望 3", len(got))
+	}
+	if got[0]["event_type"] != "tool_used" || got[0]["session_key"] != "websocket:chat1" || got[0]["channel"] != "websocket" {
+		t.Errorf("第一条事件 = %v", got[0])
+	}
+	if data := got[1]["data"].(map[string]any); data["error"] != "boom" {
+		t.Errorf("事件数据应包含原始事件字段, got %v", data)
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.payloads) != 2 {
+		t.Errorf("请求次数 = %d, 期望 2（满批推送一次，关闭时推送剩余）", len(recorder.payloads))
+	}
+	if recorder.headers[0] != "Bearer token" {
+		t.Errorf("Authorization = %q, 期望附加配置的请求头", recorder.headers[0])
+	}
+}
+
+// TestWebhookObserver_Retry 测试推送失败时重试
+func TestWebhookObserver_Retry(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer server.Close()
+
+	obs := NewWebhookObserver(config.WebhookConfig{URL: server.URL, FlushIntervalMs: 20, Events: []string{"tool_error"}}, nil)
+	obs.backoff = 10 * time.Millisecond
+	_ = obs.OnEvent(context.Background(), events.NewToolErrorEvent("t", "s", "", "exec", "boom"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	obs.Close()
+
+	if calls.Load() != 2 {
+		t.Errorf("请求次数 = %d, 期望 2（首次失败后重试成功）", calls.Load())
+	}
+	if obs.Failed() != 0 {
+		t.Errorf("重试成功后不应计入失败, Failed = %d", obs.Failed())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 // TraceIDKey 是 context 中存储 TraceID 的 key
@@ -22,6 +23,9 @@ type SessionKeyKey struct{}
 // ChannelKey 是 context 中存储 Channel 的 key
 type ChannelKey struct{}
 
+// ChatIDKey 是 context 中存储 ChatID 的 key
+type ChatIDKey struct{}
+
 // NewTraceID 生成新的 TraceID
 func NewTraceID() string {
 	return uuid.New().String()
@@ -57,6 +61,11 @@ func WithChannel(ctx context.Context, channel string) context.Context {
 	return context.WithValue(ctx, ChannelKey{}, channel)
 }
 
+// WithChatID 将 ChatID 注入到 context 中
+func WithChatID(ctx context.Context, chatID string) context.Context {
+	return context.WithValue(ctx, ChatIDKey{}, chatID)
+}
+
 // WithSessionInfo 将会话信息（sessionKey 和 channel）注入到 context 中
 func WithSessionInfo(ctx context.Context, sessionKey, channel string) context.Context {
 	ctx = WithSessionKey(ctx, sessionKey)
@@ -104,6 +113,14 @@ func GetChannel(ctx context.Context) string {
 	return ""
 }
 
+// GetChatID 从 context 中获取 ChatID，如果不存在则返回空字符串
+func GetChatID(ctx context.Context) string {
+	if chatID, ok := ctx.Value(ChatIDKey{}).(string); ok {
+		return chatID
+	}
+	return ""
+}
+
 // MustGetTraceID 从 context 中获取 TraceID，如果不存在则返回空字符串
 func MustGetTraceID(ctx context.Context) string {
 	if traceID, ok := ctx.Value(TraceIDKey{}).(string); ok {
@@ -120,6 +137,14 @@ func MustGetSpanID(ctx context.Context) string {
 	return ""
 }
 
+// LogField 返回 context 中 TraceID 的日志字段，没有 TraceID 时不输出
+func LogField(ctx context.Context) zap.Field {
+	if traceID := MustGetTraceID(ctx); traceID != "" {
+		return zap.String("trace_id", traceID)
+	}
+	return zap.Skip()
+}
+
 // StartSpan 开始一个新的 Span，继承父 Span 的 TraceID，并设置 ParentSpanID
 func StartSpan(ctx context.Context) (context.Context, string) {
 	parentSpanID := MustGetSpanID(ctx) // 使用 MustGetSpanID 来检测是否真的有父 Span
@@ -2,10 +2,13 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
@@ -63,6 +66,8 @@ type interruptible struct {
 	agentType        string // "master" 或 "supervisor"
 	adkAgent         adk.Agent
 	hookManager      *hooks.HookManager
+	summaryModel     model.BaseChatModel // 达到最大迭代次数时用于生成进展总结
+	react            *reactAgent         // 模型不支持原生工具调用时改用的 ReAct 文本协议代理
 }
 
 // interruptibleConfig 中断处理能力的配置
@@ -129,7 +134,7 @@ func newInterruptible(ctx context.Context, cfg *interruptibleConfig) (*interrupt
 // BuildChatModelAdapter 创建并配置 ChatModelAdapter
 // 将 LLM 初始化逻辑集中在此，避免遗漏必要配置
 func (i *interruptible) BuildChatModelAdapter() (*ChatModelAdapter, error) {
-	llm, err := NewChatModelAdapter(i.logger, i.cfg, i.sessions)
+	llm, err := NewRoleChatModelAdapter(i.logger, i.cfg, i.sessions, config.RoleReact)
 	if err != nil {
 		return nil, err
 	}
@@ -155,7 +160,7 @@ func (i *interruptible) BuildChatModelAdapter() (*ChatModelAdapter, error) {
 // Process 处理用户消息的统一入口
 // 包含中断检查和恢复逻辑
 func (i *interruptible) Process(ctx context.Context, msg *bus.InboundMessage, buildMessagesFunc func(history []*schema.Message, userInput, channel, chatID string) []*schema.Message) (string, error) {
-	sessionKey := msg.SessionKey()
+	sessionKey := i.sessions.ResolveKey(msg.SessionKey())
 	sess := i.sessions.GetOrCreate(sessionKey)
 
 	ctx = context.WithValue(ctx, SessionKeyContextKey, sessionKey)
@@ -175,7 +180,7 @@ func (i *interruptible) Process(ctx context.Context, msg *bus.InboundMessage, bu
 	}
 
 	// Normal processing flow
-	history := i.convertHistory(i.sessions.GetHistory(ctx, sessionKey, 10))
+	history := i.loadHistory(ctx, sessionKey, msg.Channel)
 	messages := buildMessagesFunc(history, msg.Content, msg.Channel, msg.ChatID)
 	checkpointID := fmt.Sprintf("%s_%d", sessionKey, time.Now().UnixNano())
 
@@ -184,7 +189,20 @@ func (i *interruptible) Process(ctx context.Context, msg *bus.InboundMessage, bu
 		i.hookManager.OnPromptSubmitted(ctx, msg.Content, messages, sessionKey)
 	}
 
-	response, err := i.processNormal(ctx, messages, checkpointID, msg)
+	// 模型偶尔返回空消息，重试一次，避免向用户发送空回复
+	// 重试会重放整个执行，已调用过工具时不再重试，避免发送消息、执行命令等副作用重复发生
+	ctx = withToolActivity(ctx)
+	ctx = withReplyNotice(ctx)
+	response, err := i.retryOnEmpty(ctx, func(attempt int) (string, error) {
+		id := checkpointID
+		if attempt > 0 {
+			id = fmt.Sprintf("%s_retry%d", checkpointID, attempt)
+		}
+		if i.react.active() {
+			return i.processReAct(ctx, messages)
+		}
+		return i.processNormal(ctx, messages, id, msg)
+	})
 	if err != nil {
 		if IsInterruptError(err) {
 			return "", err
@@ -197,9 +215,38 @@ func (i *interruptible) Process(ctx context.Context, msg *bus.InboundMessage, bu
 	return response, nil
 }
 
+// emptyResponseReply 模型多次返回空响应时回复给用户的提示
+const emptyResponseReply = "未能生成回复，请重试"
+
+// retryOnEmpty 执行 run，响应为空或只有空白时重试一次，仍为空则返回提示消息
+// ctx 中记录到本次执行已调用过工具时不重试；模型调用记录的回复提示附加到响应末尾
+func (i *interruptible) retryOnEmpty(ctx context.Context, run func(attempt int) (string, error)) (string, error) {
+	const maxAttempts = 2
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		response, err := run(attempt)
+		if err != nil {
+			return response, err
+		}
+		// 截断、内容拦截等提示只附加到发给用户的回复，不写入模型消息
+		response = appendReplyNotice(ctx, response)
+		if strings.TrimSpace(response) != "" {
+			return response, nil
+		}
+		i.logger.Warn("Agent 返回空响应",
+			zap.String("agent_type", i.agentType),
+			zap.Int("attempt", attempt+1),
+			zap.String("raw_response", fmt.Sprintf("%q", response)),
+		)
+		if toolExecuted(ctx) {
+			break
+		}
+	}
+	return emptyResponseReply, nil
+}
+
 // processInterrupted 处理中断恢复流程
 func (i *interruptible) processInterrupted(ctx context.Context, sess *session.Session, msg *bus.InboundMessage, pendingInterrupt *InterruptInfo, buildMessagesFunc func(history []*schema.Message, userInput, channel, chatID string) []*schema.Message) (string, error) {
-	sessionKey := msg.SessionKey()
+	sessionKey := i.sessions.ResolveKey(msg.SessionKey())
 
 	// 使用原始 checkpoint ID 进行恢复
 	resumeCheckpointID := pendingInterrupt.OriginalCheckpointID
@@ -251,11 +298,25 @@ func (i *interruptible) processInterrupted(ctx context.Context, sess *session.Se
 	// 清理已完成的中断
 	i.interruptManager.ClearInterrupt(pendingInterrupt.CheckpointID)
 
+	// 恢复执行无法重放，空响应时直接提示用户
+	if strings.TrimSpace(result) == "" {
+		i.logger.Warn("恢复执行返回空响应",
+			zap.String("agent_type", i.agentType),
+			zap.String("raw_response", fmt.Sprintf("%q", result)),
+		)
+		return emptyResponseReply, nil
+	}
+
 	return result, nil
 }
 
 // buildResumePayload 构建恢复参数的有效载荷
 func (i *interruptible) buildResumePayload(isAskUser bool, userAnswer string) any {
+	return resumePayload(isAskUser, userAnswer)
+}
+
+// resumePayload 根据中断类型构建恢复执行时传给中断点的用户回复
+func resumePayload(isAskUser bool, userAnswer string) any {
 	if isAskUser {
 		return &askuser.AskUserInfo{
 			UserAnswer: userAnswer,
@@ -280,6 +341,7 @@ func (i *interruptible) processNormal(ctx context.Context, messages []*schema.Me
 
 	var response string
 	var lastEvent *adk.AgentEvent
+	var progress []*schema.Message
 
 	for {
 		event, ok := iter.Next()
@@ -288,6 +350,9 @@ func (i *interruptible) processNormal(ctx context.Context, messages []*schema.Me
 		}
 
 		if event.Err != nil {
+			if errors.Is(event.Err, adk.ErrExceedMaxIterations) {
+				return i.summarizeOnMaxIterations(ctx, append(messages, progress...)), nil
+			}
 			return "", fmt.Errorf("%s 执行失败: %w", i.agentType, event.Err)
 		}
 
@@ -297,6 +362,7 @@ func (i *interruptible) processNormal(ctx context.Context, messages []*schema.Me
 				continue
 			}
 			response = msgOutput.Content
+			progress = append(progress, msgOutput)
 		}
 
 		lastEvent = event
@@ -310,6 +376,19 @@ func (i *interruptible) processNormal(ctx context.Context, messages []*schema.Me
 	return response, nil
 }
 
+// processReAct 通过 ReAct 文本协议处理消息，用于不支持原生工具调用的模型
+func (i *interruptible) processReAct(ctx context.Context, messages []*schema.Message) (string, error) {
+	i.logger.Debug("模型不支持工具调用，使用 ReAct 文本协议", zap.String("agent_type", i.agentType))
+	response, progress, err := i.react.Run(ctx, messages)
+	if errors.Is(err, adk.ErrExceedMaxIterations) {
+		return i.summarizeOnMaxIterations(ctx, append(flattenToolMessages(messages), progress...)), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s 执行失败: %w", i.agentType, err)
+	}
+	return response, nil
+}
+
 // Resume 恢复被中断的执行
 func (i *interruptible) Resume(ctx context.Context, checkpointID string, resumeParams *adk.ResumeParams, msg *bus.InboundMessage) (string, error) {
 	if i.adkRunner == nil {
@@ -334,6 +413,7 @@ func (i *interruptible) Resume(ctx context.Context, checkpointID string, resumeP
 
 	var response string
 	var lastEvent *adk.AgentEvent
+	var progress []*schema.Message
 
 	for {
 		event, ok := iter.Next()
@@ -342,6 +422,9 @@ func (i *interruptible) Resume(ctx context.Context, checkpointID string, resumeP
 		}
 
 		if event.Err != nil {
+			if errors.Is(event.Err, adk.ErrExceedMaxIterations) {
+				return i.summarizeOnMaxIterations(ctx, progress), nil
+			}
 			return "", fmt.Errorf("%s 恢复后执行失败: %w", i.agentType, event.Err)
 		}
 
@@ -351,6 +434,7 @@ func (i *interruptible) Resume(ctx context.Context, checkpointID string, resumeP
 				continue
 			}
 			response = msgOutput.Content
+			progress = append(progress, msgOutput)
 		}
 
 		lastEvent = event
@@ -365,6 +449,44 @@ func (i *interruptible) Resume(ctx context.Context, checkpointID string, resumeP
 	return response, nil
 }
 
+// maxIterationsSummaryPrompt 达到最大迭代次数时要求模型总结进展的提示
+const maxIterationsSummaryPrompt = "你已达到本轮允许的最大迭代次数（%d 次），不能再调用任何工具。请用简洁的中文总结：已经完成了什么、得到了哪些结果、还有哪些步骤没有完成。"
+
+// summarizeOnMaxIterations 在达到最大迭代次数时生成进展总结，并附上停止原因
+func (i *interruptible) summarizeOnMaxIterations(ctx context.Context, history []*schema.Message) string {
+	i.logger.Warn("Agent 达到最大迭代次数",
+		zap.String("agent_type", i.agentType),
+		zap.Int("max_iterations", i.maxIterations),
+		zap.Int("progress_messages", len(history)),
+	)
+
+	notice := fmt.Sprintf("（已达到最大迭代次数 %d，任务尚未完成。回复“继续”可以让我接着处理。）", i.maxIterations)
+
+	summary := ""
+	if i.summaryModel != nil {
+		input := append(append([]*schema.Message{}, history...), schema.UserMessage(fmt.Sprintf(maxIterationsSummaryPrompt, i.maxIterations)))
+		resp, err := i.summaryModel.Generate(ctx, input)
+		if err != nil {
+			i.logger.Warn("生成进展总结失败", zap.Error(err))
+		} else if resp != nil {
+			summary = strings.TrimSpace(resp.Content)
+		}
+	}
+	if summary == "" {
+		// 无法生成总结时，退回到最后一条助手输出
+		for idx := len(history) - 1; idx >= 0; idx-- {
+			if history[idx].Role == schema.Assistant && strings.TrimSpace(history[idx].Content) != "" {
+				summary = strings.TrimSpace(history[idx].Content)
+				break
+			}
+		}
+	}
+	if summary == "" {
+		return notice
+	}
+	return summary + "\n\n" + notice
+}
+
 // handleInterrupt 处理中断
 func (i *interruptible) handleInterrupt(msg *bus.InboundMessage, checkpointID string, originalCheckpointID string, event *adk.AgentEvent) error {
 	if event.Action == nil || event.Action.Interrupted == nil {
@@ -380,31 +502,7 @@ func (i *interruptible) handleInterrupt(msg *bus.InboundMessage, checkpointID st
 	}
 
 	// 解析中断信息
-	var question string
-	var options []string
-	isAskUser := false
-
-	if info, ok := interruptCtx.Info.(*askuser.AskUserInfo); ok {
-		question = info.Question
-		options = append(options, info.Options...)
-		isAskUser = true
-	} else if info, ok := interruptCtx.Info.(map[string]any); ok {
-		if q, ok := info["question"].(string); ok {
-			question = q
-		}
-		if opts, ok := info["options"].([]any); ok {
-			for _, opt := range opts {
-				if s, ok := opt.(string); ok {
-					options = append(options, s)
-				}
-			}
-		}
-		if question != "" {
-			isAskUser = true
-		}
-	} else {
-		question = fmt.Sprintf("%v", interruptCtx.Info)
-	}
+	question, options, isAskUser := parseInterruptInfo(interruptCtx.Info)
 
 	// 发送中断请求
 	i.interruptManager.HandleInterrupt(&InterruptInfo{
@@ -415,7 +513,7 @@ func (i *interruptible) handleInterrupt(msg *bus.InboundMessage, checkpointID st
 		ChatID:               msg.ChatID,
 		Question:             question,
 		Options:              options,
-		SessionKey:           msg.SessionKey(),
+		SessionKey:           i.sessions.ResolveKey(msg.SessionKey()),
 		IsAskUser:            isAskUser,
 		IsMaster:             i.agentType == "master",
 		IsSupervisor:         i.agentType == "supervisor",
@@ -430,6 +528,27 @@ func (i *interruptible) handleInterrupt(msg *bus.InboundMessage, checkpointID st
 	return fmt.Errorf("%s%s:%s", interruptErrorPrefix, checkpointID, interruptID)
 }
 
+// parseInterruptInfo 从中断信息中解析问题和选项，isAskUser 表示恢复时需要 AskUserInfo 格式的回复
+func parseInterruptInfo(raw any) (question string, options []string, isAskUser bool) {
+	if info, ok := raw.(*askuser.AskUserInfo); ok {
+		return info.Question, append(options, info.Options...), true
+	}
+	if info, ok := raw.(map[string]any); ok {
+		if q, ok := info["question"].(string); ok {
+			question = q
+		}
+		if opts, ok := info["options"].([]any); ok {
+			for _, opt := range opts {
+				if s, ok := opt.(string); ok {
+					options = append(options, s)
+				}
+			}
+		}
+		return question, options, question != ""
+	}
+	return fmt.Sprintf("%v", raw), nil, false
+}
+
 // convertHistory 转换会话历史
 func (i *interruptible) convertHistory(history []map[string]any) []*schema.Message {
 	result := make([]*schema.Message, 0, len(history))
@@ -437,9 +556,9 @@ func (i *interruptible) convertHistory(history []map[string]any) []*schema.Messa
 		// 获取角色
 		roleStr, _ := h["role"].(string)
 
-		// 跳过工具相关消息（tool 和 tool_result）
-		// 工具调用上下文由 Eino 框架内部维护，不需要在 Session 中保存
-		if roleStr == "tool" || roleStr == "tool_result" {
+		// 旧版本记录的工具消息（tool_result 及没有调用 ID 的 tool）无法与工具调用对应，跳过
+		toolCallID, _ := h["tool_call_id"].(string)
+		if roleStr == "tool_result" || (roleStr == "tool" && toolCallID == "") {
 			i.logger.Debug("跳过工具消息",
 				zap.String("role", roleStr),
 				zap.String("content_preview", fmt.Sprintf("%.50v", h["content"])),
@@ -448,13 +567,26 @@ func (i *interruptible) convertHistory(history []map[string]any) []*schema.Messa
 		}
 
 		role := schema.User
-		if roleStr == "assistant" {
+		switch roleStr {
+		case "assistant":
 			role = schema.Assistant
+		case "tool":
+			role = schema.Tool
+		case "system":
+			// 保留 system 角色，构建消息时会合并到首条系统消息
+			role = schema.System
 		}
 
 		msg := &schema.Message{
 			Role: role,
 		}
+		if role == schema.Assistant {
+			msg.ToolCalls, _ = h["tool_calls"].([]schema.ToolCall)
+		}
+		if role == schema.Tool {
+			msg.ToolCallID = toolCallID
+			msg.ToolName, _ = h["name"].(string)
+		}
 
 		// 处理 content：可能是字符串或多部分内容
 		content := h["content"]
@@ -499,5 +631,5 @@ func (i *interruptible) convertHistory(history []map[string]any) []*schema.Messa
 
 		result = append(result, msg)
 	}
-	return result
+	return pairToolMessages(result)
 }
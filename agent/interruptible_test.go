@@ -2,8 +2,11 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
 	"github.com/weibaohui/nanobot-go/bus"
 	"github.com/weibaohui/nanobot-go/config"
@@ -369,3 +372,155 @@ func TestInterruptible_ConvertHistory_SkipOnlyToolMessages(t *testing.T) {
 		t.Fatalf("convertHistory() 返回 %d 条消息, 期望 0 (只有工具消息)", len(messages))
 	}
 }
+
+// TestInterruptible_ConvertHistory_ToolCalls 测试还原工具调用与工具结果
+func TestInterruptible_ConvertHistory_ToolCalls(t *testing.T) {
+	i := &interruptible{
+		logger: zap.NewNop(),
+	}
+
+	toolCalls := []schema.ToolCall{{ID: "call_1", Type: "function", Function: schema.FunctionCall{Name: "read_file", Arguments: `{"path":"a.txt"}`}}}
+	history := []map[string]any{
+		{"role": "user", "content": "看看 a.txt"},
+		{"role": "assistant", "content": "", "tool_calls": toolCalls},
+		{"role": "tool", "content": "文件内容", "tool_call_id": "call_1", "name": "read_file"},
+		{"role": "assistant", "content": "文件内容是……"},
+	}
+
+	messages := i.convertHistory(history)
+	if len(messages) != 4 {
+		t.Fatalf("convertHistory() 返回 %d 条消息, 期望 4", len(messages))
+	}
+	if len(messages[1].ToolCalls) != 1 || messages[1].ToolCalls[0].ID != "call_1" {
+		t.Errorf("messages[1].ToolCalls = %v, 期望包含 call_1", messages[1].ToolCalls)
+	}
+	if messages[2].Role != schema.Tool || messages[2].ToolCallID != "call_1" || messages[2].ToolName != "read_file" {
+		t.Errorf("messages[2] = %+v, 期望 call_1 的工具结果", messages[2])
+	}
+}
+
+// TestInterruptible_RetryOnEmpty 测试空响应重试
+func TestInterruptible_RetryOnEmpty(t *testing.T) {
+	i := &interruptible{logger: zap.NewNop(), agentType: "master"}
+
+	t.Run("重试后成功", func(t *testing.T) {
+		calls := 0
+		response, err := i.retryOnEmpty(context.Background(), func(attempt int) (string, error) {
+			calls++
+			if attempt == 0 {
+				return "  \n", nil
+			}
+			return "你好", nil
+		})
+		if err != nil || response != "你好" {
+			t.Errorf("retryOnEmpty() = (%q, %v), 期望 (你好, nil)", response, err)
+		}
+		if calls != 2 {
+			t.Errorf("调用次数 = %d, 期望 2", calls)
+		}
+	})
+
+	t.Run("多次为空返回提示", func(t *testing.T) {
+		response, err := i.retryOnEmpty(context.Background(), func(attempt int) (string, error) {
+			return "", nil
+		})
+		if err != nil || response != emptyResponseReply {
+			t.Errorf("retryOnEmpty() = (%q, %v), 期望 (%q, nil)", response, err, emptyResponseReply)
+		}
+	})
+
+	t.Run("调用过工具后不重试", func(t *testing.T) {
+		ctx := withToolActivity(context.Background())
+		calls := 0
+		response, err := i.retryOnEmpty(ctx, func(attempt int) (string, error) {
+			calls++
+			markToolExecuted(ctx)
+			return "", nil
+		})
+		if err != nil || response != emptyResponseReply {
+			t.Errorf("retryOnEmpty() = (%q, %v), 期望 (%q, nil)", response, err, emptyResponseReply)
+		}
+		if calls != 1 {
+			t.Errorf("调用次数 = %d, 期望 1", calls)
+		}
+	})
+
+	t.Run("内容被拦截时回复拦截说明", func(t *testing.T) {
+		ctx := withReplyNotice(context.Background())
+		calls := 0
+		response, err := i.retryOnEmpty(ctx, func(attempt int) (string, error) {
+			calls++
+			setReplyNotice(ctx, contentFilteredReply)
+			return "", nil
+		})
+		if err != nil || response != contentFilteredReply {
+			t.Errorf("retryOnEmpty() = (%q, %v), 期望 (%q, nil)", response, err, contentFilteredReply)
+		}
+		if calls != 1 {
+			t.Errorf("调用次数 = %d, 期望 1", calls)
+		}
+	})
+
+	t.Run("错误不重试", func(t *testing.T) {
+		calls := 0
+		_, err := i.retryOnEmpty(context.Background(), func(attempt int) (string, error) {
+			calls++
+			return "", errors.New("失败")
+		})
+		if err == nil || calls != 1 {
+			t.Errorf("错误时应直接返回, calls = %d, err = %v", calls, err)
+		}
+	})
+}
+
+// summaryChatModel 用于测试进展总结的模拟模型
+type summaryChatModel struct {
+	content string
+	err     error
+	input   []*schema.Message
+}
+
+func (m *summaryChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.input = input
+	if m.err != nil {
+		return nil, m.err
+	}
+	return schema.AssistantMessage(m.content, nil), nil
+}
+
+func (m *summaryChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not implemented")
+}
+
+// TestInterruptible_SummarizeOnMaxIterations 测试达到最大迭代次数时的总结
+func TestInterruptible_SummarizeOnMaxIterations(t *testing.T) {
+	history := []*schema.Message{
+		schema.UserMessage("整理项目文档"),
+		schema.AssistantMessage("已读取 README", nil),
+	}
+
+	t.Run("使用模型生成总结", func(t *testing.T) {
+		llm := &summaryChatModel{content: "已完成 README 阅读"}
+		i := &interruptible{logger: zap.NewNop(), maxIterations: 15, summaryModel: llm}
+
+		result := i.summarizeOnMaxIterations(context.Background(), history)
+		if !strings.HasPrefix(result, "已完成 README 阅读") {
+			t.Errorf("结果应以总结开头, 得到: %q", result)
+		}
+		if !strings.Contains(result, "最大迭代次数 15") {
+			t.Errorf("结果应包含最大迭代次数, 得到: %q", result)
+		}
+		if len(llm.input) != len(history)+1 {
+			t.Errorf("总结请求消息数 = %d, 期望 %d", len(llm.input), len(history)+1)
+		}
+	})
+
+	t.Run("模型失败时使用最后输出", func(t *testing.T) {
+		i := &interruptible{logger: zap.NewNop(), maxIterations: 15, summaryModel: &summaryChatModel{err: errors.New("失败")}}
+
+		result := i.summarizeOnMaxIterations(context.Background(), history)
+		if !strings.HasPrefix(result, "已读取 README") {
+			t.Errorf("结果应使用最后的助手输出, 得到: %q", result)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/utils"
+	"go.uber.org/zap"
+)
+
+// JSONModeContextKey 标记本次请求要求模型返回 JSON 的 context key
+const JSONModeContextKey ContextKey = "json_mode"
+
+// JSONModeMetadataKey 入站消息 Metadata 中要求 JSON 回复的标记，值为 true
+const JSONModeMetadataKey = "json_mode"
+
+// jsonModeCommand 要求 JSON 回复的消息前缀，如 "/json 列出三种水果及价格"
+const jsonModeCommand = "/json"
+
+const (
+	// jsonModeInstruction JSON 模式下追加的系统提示（OpenAI 要求 JSON 模式的消息中出现 JSON 字样）
+	jsonModeInstruction = "请只输出一个合法的 JSON 对象，不要输出 JSON 以外的任何文字或 Markdown 代码块。"
+	// jsonRetryPrompt 回复不是合法 JSON 时要求模型重新输出的提示
+	jsonRetryPrompt = "上一条回复不是合法的 JSON。请只输出一个合法的 JSON 对象，不要包含其他文字。"
+)
+
+// WithJSONMode 返回要求模型以 JSON 对象回复的 context
+func WithJSONMode(ctx context.Context) context.Context {
+	return context.WithValue(ctx, JSONModeContextKey, true)
+}
+
+// isJSONMode 判断 context 是否要求 JSON 回复
+func isJSONMode(ctx context.Context) bool {
+	on, _ := ctx.Value(JSONModeContextKey).(bool)
+	return on
+}
+
+// parseJSONModeRequest 判断消息是否要求 JSON 回复：Metadata 中带有 json_mode 标记，或以 /json 开头
+// 以 /json 开头时返回去掉前缀后的消息副本
+func parseJSONModeRequest(msg *bus.InboundMessage) (*bus.InboundMessage, bool) {
+	if on, _ := msg.Metadata[JSONModeMetadataKey].(bool); on {
+		return msg, true
+	}
+	content := strings.TrimSpace(msg.Content)
+	if content == jsonModeCommand || !strings.HasPrefix(content, jsonModeCommand+" ") {
+		return msg, false
+	}
+	stripped := *msg
+	stripped.Content = strings.TrimSpace(strings.TrimPrefix(content, jsonModeCommand))
+	return &stripped, true
+}
+
+// jsonModeOptions 追加 OpenAI 兼容接口的 response_format 参数
+func jsonModeOptions(opts []model.Option) []model.Option {
+	return append(opts, openai.WithExtraFields(map[string]any{
+		"response_format": map[string]any{"type": "json_object"},
+	}))
+}
+
+// withJSONInstruction 在系统提示中追加只输出 JSON 的要求
+func withJSONInstruction(input []*schema.Message) []*schema.Message {
+	messages := make([]*schema.Message, 0, len(input)+1)
+	messages = append(messages, input...)
+	messages = append(messages, schema.SystemMessage(jsonModeInstruction))
+	return mergeSystemMessages(messages)
+}
+
+// normalizeJSONContent 去掉回复外层的 Markdown 代码块，返回内容及其是否为合法 JSON
+func normalizeJSONContent(content string) (string, bool) {
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "```") && strings.HasSuffix(text, "```") {
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "```"), "```")
+		// 去掉代码块的语言标记，如 ```json
+		if idx := strings.Index(text, "\n"); idx >= 0 && !strings.ContainsAny(text[:idx], "{[") {
+			text = text[idx+1:]
+		}
+		text = strings.TrimSpace(text)
+	}
+	return text, json.Valid([]byte(text))
+}
+
+// ensureJSONResponse 校验 JSON 模式下的最终回复，不是合法 JSON 时让模型重新输出一次
+// 返回最终回复及其是否为合法 JSON；调用工具的中间回合不校验
+func (a *ChatModelAdapter) ensureJSONResponse(ctx context.Context, input []*schema.Message, response *schema.Message, opts []model.Option) (*schema.Message, bool) {
+	if len(response.ToolCalls) > 0 {
+		return response, true
+	}
+	if content, ok := normalizeJSONContent(response.Content); ok {
+		response.Content = content
+		return response, true
+	}
+
+	a.logger.Warn("JSON 模式回复不是合法 JSON，重试一次", zap.String("content", utils.TruncateString(response.Content, 200)))
+	retryInput := make([]*schema.Message, 0, len(input)+2)
+	retryInput = append(retryInput, input...)
+	retryInput = append(retryInput, schema.AssistantMessage(response.Content, nil), schema.UserMessage(jsonRetryPrompt))
+
+	retried, err := a.chatModel.Generate(ctx, retryInput, opts...)
+	if err != nil {
+		a.logger.Warn("JSON 模式重试失败", zap.Error(err))
+		return response, false
+	}
+	if content, ok := normalizeJSONContent(retried.Content); ok {
+		retried.Content = content
+		return retried, true
+	}
+	a.logger.Warn("JSON 模式重试后仍不是合法 JSON", zap.String("content", utils.TruncateString(retried.Content, 200)))
+	return retried, false
+}
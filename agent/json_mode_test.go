@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// TestParseJSONModeRequest 测试识别要求 JSON 回复的消息
+func TestParseJSONModeRequest(t *testing.T) {
+	t.Run("json 前缀", func(t *testing.T) {
+		msg, ok := parseJSONModeRequest(&bus.InboundMessage{Content: "/json 列出三种水果"})
+		if !ok || msg.Content != "列出三种水果" {
+			t.Errorf("parseJSONModeRequest() = (%q, %v)", msg.Content, ok)
+		}
+	})
+
+	t.Run("Metadata 标记", func(t *testing.T) {
+		original := &bus.InboundMessage{Content: "列出三种水果", Metadata: map[string]any{JSONModeMetadataKey: true}}
+		msg, ok := parseJSONModeRequest(original)
+		if !ok || msg != original {
+			t.Errorf("parseJSONModeRequest() = (%+v, %v)", msg, ok)
+		}
+	})
+
+	t.Run("普通消息", func(t *testing.T) {
+		for _, content := range []string{"你好", "/json", "/jsonify 数据"} {
+			if _, ok := parseJSONModeRequest(&bus.InboundMessage{Content: content}); ok {
+				t.Errorf("%q 不应视为 JSON 模式", content)
+			}
+		}
+	})
+}
+
+// TestNormalizeJSONContent 测试 JSON 回复校验
+func TestNormalizeJSONContent(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+		valid   bool
+	}{
+		{`{"a": 1}`, `{"a": 1}`, true},
+		{"```json\n{\"a\": 1}\n```", `{"a": 1}`, true},
+		{"```{\"a\": 1}```", `{"a": 1}`, true},
+		{"结果如下: {\"a\": 1}", "结果如下: {\"a\": 1}", false},
+	}
+	for _, tt := range tests {
+		got, valid := normalizeJSONContent(tt.content)
+		if got != tt.want || valid != tt.valid {
+			t.Errorf("normalizeJSONContent(%q) = (%q, %v), 期望 (%q, %v)", tt.content, got, valid, tt.want, tt.valid)
+		}
+	}
+}
+
+// TestChatModelAdapter_JSONMode 测试 JSON 模式的提示注入与解析失败重试
+func TestChatModelAdapter_JSONMode(t *testing.T) {
+	input := []*schema.Message{schema.SystemMessage("你是助手"), schema.UserMessage("列出三种水果")}
+	ctx := WithJSONMode(context.Background())
+
+	t.Run("解析失败重试一次", func(t *testing.T) {
+		replies := []string{"好的，水果有苹果、香蕉", `{"fruits": ["苹果", "香蕉", "橙子"]}`}
+		llm := &countingChatModel{}
+		llm.response = func() *schema.Message { return schema.AssistantMessage(replies[llm.calls-1], nil) }
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), chatModel: llm, registeredMap: map[string]bool{}}
+
+		got, err := adapter.Generate(ctx, input)
+		if err != nil {
+			t.Fatalf("Generate() 返回错误: %v", err)
+		}
+		if llm.calls != 2 || !strings.HasPrefix(got.Content, `{"fruits"`) {
+			t.Errorf("调用次数 = %d, 回复 = %q", llm.calls, got.Content)
+		}
+	})
+
+	t.Run("系统提示要求输出 JSON", func(t *testing.T) {
+		llm := &countingChatModel{response: func() *schema.Message {
+			return schema.AssistantMessage("```json\n{\"ok\": true}\n```", nil)
+		}}
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), chatModel: llm, registeredMap: map[string]bool{}}
+
+		got, err := adapter.Generate(ctx, input)
+		if err != nil || got.Content != `{"ok": true}` {
+			t.Fatalf("Generate() = (%q, %v)", got.Content, err)
+		}
+		if len(llm.input) != 2 || !strings.Contains(llm.input[0].Content, "JSON") {
+			t.Errorf("系统提示 = %q, 期望包含 JSON 要求", llm.input[0].Content)
+		}
+	})
+}
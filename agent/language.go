@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// ResponseLanguageContextKey 本回合回复语言的 context key
+const ResponseLanguageContextKey ContextKey = "response_language"
+
+// responseLanguageInstruction 要求模型使用指定语言回复的系统提示
+const responseLanguageInstruction = "请使用%s回复用户，除非用户明确要求使用其他语言。"
+
+// WithResponseLanguage 返回要求模型使用指定语言回复的 context
+func WithResponseLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, ResponseLanguageContextKey, lang)
+}
+
+// responseLanguage 返回 context 中要求的回复语言，未指定时返回空字符串
+func responseLanguage(ctx context.Context) string {
+	lang, _ := ctx.Value(ResponseLanguageContextKey).(string)
+	return lang
+}
+
+// withLanguageInstruction 在系统提示中追加回复语言的要求
+func withLanguageInstruction(input []*schema.Message, lang string) []*schema.Message {
+	messages := make([]*schema.Message, 0, len(input)+1)
+	messages = append(messages, input...)
+	messages = append(messages, schema.SystemMessage(fmt.Sprintf(responseLanguageInstruction, lang)))
+	return mergeSystemMessages(messages)
+}
+
+// latinStopwords 拉丁字母语言的常用词，用于区分使用拉丁字母的语言
+var latinStopwords = map[string][]string{
+	"英语":   {"the", "is", "are", "and", "you", "what", "how", "can", "please", "hello", "hi", "thanks", "this", "that", "with", "for", "my", "do", "i"},
+	"法语":   {"le", "la", "les", "est", "et", "vous", "je", "une", "des", "pour", "bonjour", "merci", "que", "pas", "avec"},
+	"德语":   {"der", "die", "das", "ist", "und", "ich", "nicht", "sie", "ein", "eine", "mit", "bitte", "danke", "hallo", "wie"},
+	"西班牙语": {"el", "los", "las", "es", "y", "que", "por", "para", "una", "hola", "gracias", "cómo", "qué", "está", "con"},
+}
+
+// latinLanguages 按优先级排列的拉丁字母语言，常用词命中数相同时取靠前的
+var latinLanguages = []string{"英语", "法语", "德语", "西班牙语"}
+
+// detectLanguage 按文字的书写系统粗略判断消息语言，无法判断时返回空字符串
+// 汉字、假名、泰文按字计数，其余文字按词计数，中文夹杂英文术语时仍判断为中文
+func detectLanguage(text string) string {
+	counts := make(map[string]int)
+	var latinWords []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		var latin strings.Builder
+		for _, r := range word {
+			switch {
+			case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+				counts["日语"]++
+			case unicode.Is(unicode.Han, r):
+				counts["中文"]++
+			case unicode.Is(unicode.Thai, r):
+				counts["泰语"]++
+			case unicode.Is(unicode.Latin, r):
+				latin.WriteRune(unicode.ToLower(r))
+			}
+		}
+		switch {
+		case latin.Len() > 0:
+			latinWords = append(latinWords, latin.String())
+		case hasScript(word, unicode.Hangul):
+			counts["韩语"]++
+		case hasScript(word, unicode.Cyrillic):
+			counts["俄语"]++
+		case hasScript(word, unicode.Arabic):
+			counts["阿拉伯语"]++
+		}
+	}
+	// 日文通常夹杂汉字，出现假名即判断为日文
+	if counts["日语"] > 0 {
+		counts["日语"] += counts["中文"]
+		delete(counts, "中文")
+	}
+
+	best, bestCount := "", 0
+	for _, lang := range []string{"中文", "日语", "韩语", "俄语", "阿拉伯语", "泰语"} {
+		if counts[lang] > bestCount {
+			best, bestCount = lang, counts[lang]
+		}
+	}
+	if len(latinWords) > bestCount {
+		return detectLatinLanguage(latinWords)
+	}
+	return best
+}
+
+// hasScript 判断单词是否包含指定书写系统的字符
+func hasScript(word string, script *unicode.RangeTable) bool {
+	return strings.IndexFunc(word, func(r rune) bool { return unicode.Is(script, r) }) >= 0
+}
+
+// detectLatinLanguage 按常用词判断拉丁字母语言，没有命中任何常用词时返回空字符串
+func detectLatinLanguage(words []string) string {
+	best, bestHits := "", 0
+	for _, lang := range latinLanguages {
+		hits := 0
+		for _, w := range words {
+			if slices.Contains(latinStopwords[lang], w) {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = lang, hits
+		}
+	}
+	return best
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// TestDetectLanguage 测试检测消息语言
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"帮我查一下明天的天气":                           "中文",
+		"帮我看看 Kubernetes deployment 的问题":       "中文",
+		"明日の天気を教えてください":                        "日语",
+		"내일 날씨 어때요?":                           "韩语",
+		"Какая погода завтра?":                 "俄语",
+		"What is the weather tomorrow?":        "英语",
+		"Bonjour, quel temps fait-il demain ?": "法语",
+		"Wie ist das Wetter morgen?":           "德语",
+		"Hola, ¿qué tiempo hará mañana?":       "西班牙语",
+		"kubectl get pods":                     "",
+		"12345 !!!":                            "",
+	}
+	for text, want := range cases {
+		if got := detectLanguage(text); got != want {
+			t.Errorf("detectLanguage(%q) = %q, 期望 %q", text, got, want)
+		}
+	}
+}
+
+// TestLoop_withResponseLanguage 测试按配置确定回复语言
+func TestLoop_withResponseLanguage(t *testing.T) {
+	newLoop := func(lang config.LanguageConfig) *Loop {
+		cfg := &config.Config{}
+		cfg.Agents.Language = lang
+		return &Loop{cfg: cfg, logger: zap.NewNop()}
+	}
+	ctx := context.Background()
+
+	if got := responseLanguage(newLoop(config.LanguageConfig{}).withResponseLanguage(ctx, "What time is it?")); got != "" {
+		t.Errorf("未启用检测时回复语言 = %q", got)
+	}
+	if got := responseLanguage(newLoop(config.LanguageConfig{Detect: true}).withResponseLanguage(ctx, "What time is it?")); got != "英语" {
+		t.Errorf("检测到的回复语言 = %q, 期望 英语", got)
+	}
+	if got := responseLanguage(newLoop(config.LanguageConfig{Detect: true, Response: "中文"}).withResponseLanguage(ctx, "What time is it?")); got != "中文" {
+		t.Errorf("固定回复语言 = %q, 期望 中文", got)
+	}
+
+	messages := withLanguageInstruction([]*schema.Message{schema.SystemMessage("你是助手"), schema.UserMessage("hi")}, "英语")
+	if len(messages) != 2 || !strings.HasSuffix(messages[0].Content, "请使用英语回复用户，除非用户明确要求使用其他语言。") {
+		t.Errorf("messages = %v", messages)
+	}
+}
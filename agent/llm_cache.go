@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/config"
+)
+
+// 响应缓存默认参数
+const (
+	defaultLLMCacheTTL        = 5 * time.Minute
+	defaultLLMCacheMaxEntries = 256
+)
+
+// responseCache 缓存相同请求的 LLM 响应
+// 键为 (模型, 消息, 工具, 采样参数) 的哈希，条目超过 TTL 后失效，超过容量时淘汰最久未使用的条目
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // 最近使用的在前
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// responseCacheEntry 缓存条目
+type responseCacheEntry struct {
+	key       string
+	msg       *schema.Message
+	expiresAt time.Time
+}
+
+// newResponseCache 创建响应缓存，参数不大于 0 时使用默认值
+func newResponseCache(ttl time.Duration, maxEntries int) *responseCache {
+	if ttl <= 0 {
+		ttl = defaultLLMCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultLLMCacheMaxEntries
+	}
+	return &responseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+var (
+	sharedCachesMu sync.Mutex
+	sharedCaches   = make(map[config.LLMCacheConfig]*responseCache)
+)
+
+// sharedResponseCache 返回同一缓存配置共享的响应缓存
+// 主循环、后台任务等各自创建适配器，共享缓存才能命中彼此的请求
+func sharedResponseCache(cfg config.LLMCacheConfig) *responseCache {
+	sharedCachesMu.Lock()
+	defer sharedCachesMu.Unlock()
+	if c, ok := sharedCaches[cfg]; ok {
+		return c
+	}
+	c := newResponseCache(time.Duration(cfg.TTLSeconds)*time.Second, cfg.MaxEntries)
+	sharedCaches[cfg] = c
+	return c
+}
+
+// Get 返回缓存的响应副本，未命中或已过期时返回 false
+func (c *responseCache) Get(key string) (*schema.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	entry := elem.Value.(*responseCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	c.hits.Add(1)
+	return cloneCachedMessage(entry.msg), true
+}
+
+// Put 缓存响应副本
+func (c *responseCache) Put(key string, msg *schema.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &responseCacheEntry{key: key, msg: cloneCachedMessage(msg), expiresAt: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*responseCacheEntry).key)
+	}
+}
+
+// Stats 返回命中与未命中次数
+func (c *responseCache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// cloneCachedMessage 复制响应，去掉 token 用量（命中缓存不产生用量）
+func cloneCachedMessage(msg *schema.Message) *schema.Message {
+	clone := *msg
+	if msg.ResponseMeta != nil {
+		meta := *msg.ResponseMeta
+		meta.Usage = nil
+		clone.ResponseMeta = &meta
+	}
+	return &clone
+}
+
+// cacheableRequest 判断请求是否可以使用缓存
+// 工具调用回合（输入中包含工具调用或工具结果）依赖外部状态，不使用缓存
+func cacheableRequest(input []*schema.Message) bool {
+	for _, msg := range input {
+		if msg.Role == schema.Tool || len(msg.ToolCalls) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheableResponse 判断响应是否可以缓存：只缓存正常结束且没有工具调用的回复
+func cacheableResponse(msg *schema.Message) bool {
+	if msg == nil || len(msg.ToolCalls) > 0 {
+		return false
+	}
+	reason := finishReasonOf(msg)
+	return reason == "" || reason == "stop"
+}
+
+// responseCacheKey 计算请求的缓存键
+func responseCacheKey(modelName string, input []*schema.Message, tools []*schema.ToolInfo, opts []model.Option, jsonMode bool) string {
+	type keyMessage struct {
+		Role         schema.RoleType           `json:"role"`
+		Content      string                    `json:"content"`
+		MultiContent []schema.ChatMessagePart  `json:"multi_content,omitempty"`
+		UserInput    []schema.MessageInputPart `json:"user_input,omitempty"`
+		Name         string                    `json:"name,omitempty"`
+	}
+	type keyTool struct {
+		Name string `json:"name"`
+		Desc string `json:"desc"`
+	}
+
+	common := model.GetCommonOptions(&model.Options{}, opts...)
+	key := struct {
+		Model       string       `json:"model"`
+		Messages    []keyMessage `json:"messages"`
+		Tools       []keyTool    `json:"tools,omitempty"`
+		Temperature *float32     `json:"temperature,omitempty"`
+		MaxTokens   *int         `json:"max_tokens,omitempty"`
+		TopP        *float32     `json:"top_p,omitempty"`
+		Stop        []string     `json:"stop,omitempty"`
+		JSONMode    bool         `json:"json_mode,omitempty"`
+	}{
+		Model:       modelName,
+		JSONMode:    jsonMode,
+		Temperature: common.Temperature,
+		MaxTokens:   common.MaxTokens,
+		TopP:        common.TopP,
+		Stop:        common.Stop,
+	}
+	if common.Model != nil {
+		key.Model = *common.Model
+	}
+	for _, msg := range input {
+		key.Messages = append(key.Messages, keyMessage{
+			Role:         msg.Role,
+			Content:      msg.Content,
+			MultiContent: msg.MultiContent,
+			UserInput:    msg.UserInputMultiContent,
+			Name:         msg.Name,
+		})
+	}
+	for _, tool := range tools {
+		key.Tools = append(key.Tools, keyTool{Name: tool.Name, Desc: tool.Desc})
+	}
+
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// countingChatModel 记录调用次数的模拟模型
+type countingChatModel struct {
+	calls    int
+	input    []*schema.Message // 最近一次调用的输入
+	response func() *schema.Message
+}
+
+func (m *countingChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	m.input = input
+	return m.response(), nil
+}
+
+func (m *countingChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, nil
+}
+
+func (m *countingChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// TestChatModelAdapter_ResponseCache 测试相同请求复用缓存的响应
+func TestChatModelAdapter_ResponseCache(t *testing.T) {
+	newAdapter := func(response func() *schema.Message) (*ChatModelAdapter, *countingChatModel) {
+		llm := &countingChatModel{response: response}
+		return &ChatModelAdapter{
+			logger:        zap.NewNop(),
+			chatModel:     llm,
+			registeredMap: map[string]bool{},
+			modelName:     "gpt-4o-mini",
+			cache:         newResponseCache(time.Minute, 10),
+		}, llm
+	}
+	reply := func() *schema.Message {
+		return &schema.Message{
+			Role:         schema.Assistant,
+			Content:      "早上好",
+			ResponseMeta: &schema.ResponseMeta{FinishReason: "stop", Usage: &schema.TokenUsage{TotalTokens: 10}},
+		}
+	}
+	ctx := context.Background()
+	input := []*schema.Message{schema.SystemMessage("你是助手"), schema.UserMessage("问候一下")}
+
+	t.Run("相同请求命中缓存", func(t *testing.T) {
+		adapter, llm := newAdapter(reply)
+		adapter.Generate(ctx, input)
+		got, err := adapter.Generate(ctx, input)
+		if err != nil {
+			t.Fatalf("Generate() 返回错误: %v", err)
+		}
+		if llm.calls != 1 {
+			t.Errorf("模型调用次数 = %d, 期望 1", llm.calls)
+		}
+		if got.Content != "早上好" || got.ResponseMeta.Usage != nil {
+			t.Errorf("缓存响应 = %+v, 期望内容相同且不带 token 用量", got)
+		}
+		if hits, _ := adapter.cache.Stats(); hits != 1 {
+			t.Errorf("命中次数 = %d, 期望 1", hits)
+		}
+	})
+
+	t.Run("采样参数不同不命中", func(t *testing.T) {
+		adapter, llm := newAdapter(reply)
+		adapter.Generate(ctx, input, model.WithTemperature(0.1))
+		adapter.Generate(ctx, input, model.WithTemperature(0.9))
+		if llm.calls != 2 {
+			t.Errorf("模型调用次数 = %d, 期望 2", llm.calls)
+		}
+	})
+
+	t.Run("工具调用回合不使用缓存", func(t *testing.T) {
+		adapter, llm := newAdapter(reply)
+		toolTurn := append(input,
+			schema.AssistantMessage("", []schema.ToolCall{{ID: "1", Function: schema.FunctionCall{Name: "exec"}}}),
+			schema.ToolMessage("ok", "1"),
+		)
+		adapter.Generate(ctx, toolTurn)
+		adapter.Generate(ctx, toolTurn)
+		if llm.calls != 2 {
+			t.Errorf("模型调用次数 = %d, 期望 2", llm.calls)
+		}
+	})
+
+	t.Run("调用工具的响应不缓存", func(t *testing.T) {
+		adapter, llm := newAdapter(func() *schema.Message {
+			return schema.AssistantMessage("", []schema.ToolCall{{ID: "1", Function: schema.FunctionCall{Name: "exec", Arguments: "{}"}}})
+		})
+		adapter.SetRegisteredTools([]string{"exec"})
+		adapter.Generate(ctx, input)
+		adapter.Generate(ctx, input)
+		if llm.calls != 2 {
+			t.Errorf("模型调用次数 = %d, 期望 2", llm.calls)
+		}
+	})
+
+	t.Run("未启用缓存", func(t *testing.T) {
+		adapter, llm := newAdapter(reply)
+		adapter.cache = nil
+		adapter.Generate(ctx, input)
+		adapter.Generate(ctx, input)
+		if llm.calls != 2 {
+			t.Errorf("模型调用次数 = %d, 期望 2", llm.calls)
+		}
+	})
+}
+
+// TestResponseCache_Expiry 测试缓存过期与容量淘汰
+func TestResponseCache_Expiry(t *testing.T) {
+	msg := schema.AssistantMessage("好的", nil)
+
+	c := newResponseCache(time.Millisecond, 10)
+	c.Put("a", msg)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("a"); ok {
+		t.Error("过期的条目不应命中")
+	}
+
+	c = newResponseCache(time.Minute, 2)
+	c.Put("a", msg)
+	c.Put("b", msg)
+	c.Get("a")
+	c.Put("c", msg)
+	if _, ok := c.Get("b"); ok {
+		t.Error("超过容量时应淘汰最久未使用的条目")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("最近使用的条目不应被淘汰")
+	}
+}
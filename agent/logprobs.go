@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxTopLogProbs OpenAI 兼容接口允许的每个位置最大候选数
+	maxTopLogProbs = 20
+	// lowestLogProbTokens 日志中列出的最低概率 token 数
+	lowestLogProbTokens = 5
+)
+
+// logProbsFields 返回请求 logprobs 时附加到请求体的字段
+func logProbsFields(topLogProbs int) map[string]any {
+	fields := map[string]any{"logprobs": true}
+	if topLogProbs > 0 {
+		fields["top_logprobs"] = min(topLogProbs, maxTopLogProbs)
+	}
+	return fields
+}
+
+// logLogProbs 在调试级别记录回复的 logprobs 摘要：token 数、平均概率和概率最低的几个 token
+// 模型在这些位置最“犹豫”，调整提示词时最值得关注
+func (a *ChatModelAdapter) logLogProbs(msg *schema.Message) {
+	if !a.logProbs || msg == nil || msg.ResponseMeta == nil || msg.ResponseMeta.LogProbs == nil {
+		return
+	}
+	tokens := msg.ResponseMeta.LogProbs.Content
+	if len(tokens) == 0 {
+		return
+	}
+	a.logger.Debug("[LLM] logprobs",
+		zap.Int("tokens", len(tokens)),
+		zap.Float64("avg_prob", averageProb(tokens)),
+		zap.String("lowest", lowestProbTokens(tokens, lowestLogProbTokens)),
+	)
+}
+
+// averageProb 返回 token 概率的几何平均值
+func averageProb(tokens []schema.LogProb) float64 {
+	if len(tokens) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, t := range tokens {
+		sum += t.LogProb
+	}
+	return math.Round(math.Exp(sum/float64(len(tokens)))*1000) / 1000
+}
+
+// lowestProbTokens 返回概率最低的 n 个 token，格式如 "天(0.12), 晴(0.35)"
+func lowestProbTokens(tokens []schema.LogProb, n int) string {
+	sorted := append([]schema.LogProb(nil), tokens...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LogProb < sorted[j].LogProb
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	parts := make([]string, 0, len(sorted))
+	for _, t := range sorted {
+		parts = append(parts, fmt.Sprintf("%q(%.2f)", t.Token, math.Exp(t.LogProb)))
+	}
+	return strings.Join(parts, ", ")
+}
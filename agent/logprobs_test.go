@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// TestLogProbsFields 测试请求 logprobs 的附加字段
+func TestLogProbsFields(t *testing.T) {
+	if fields := logProbsFields(0); fields["logprobs"] != true || fields["top_logprobs"] != nil {
+		t.Errorf("logProbsFields(0) = %v", fields)
+	}
+	if fields := logProbsFields(50); fields["top_logprobs"] != maxTopLogProbs {
+		t.Errorf("logProbsFields(50) = %v, 期望限制为 %d", fields, maxTopLogProbs)
+	}
+}
+
+// TestLogProbsSummary 测试 logprobs 摘要
+func TestLogProbsSummary(t *testing.T) {
+	tokens := []schema.LogProb{
+		{Token: "今天", LogProb: math.Log(0.9)},
+		{Token: "晴", LogProb: math.Log(0.2)},
+		{Token: "。", LogProb: math.Log(0.99)},
+	}
+
+	if got := averageProb(tokens); got != 0.563 {
+		t.Errorf("averageProb() = %v, 期望 0.563", got)
+	}
+	if got := lowestProbTokens(tokens, 2); got != `"晴"(0.20), "今天"(0.90)` {
+		t.Errorf("lowestProbTokens() = %s", got)
+	}
+}
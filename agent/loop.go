@@ -3,20 +3,36 @@ package agent
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/weibaohui/nanobot-go/agent/hooks"
 	"github.com/weibaohui/nanobot-go/agent/hooks/events"
 	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
 	"github.com/weibaohui/nanobot-go/agent/tools"
+	"github.com/weibaohui/nanobot-go/agent/tools/applypatch"
 	"github.com/weibaohui/nanobot-go/agent/tools/askuser"
+	"github.com/weibaohui/nanobot-go/agent/tools/broadcast"
+	"github.com/weibaohui/nanobot-go/agent/tools/calculator"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
 	toolcron "github.com/weibaohui/nanobot-go/agent/tools/cron"
+	"github.com/weibaohui/nanobot-go/agent/tools/datetime"
 	"github.com/weibaohui/nanobot-go/agent/tools/editfile"
 	"github.com/weibaohui/nanobot-go/agent/tools/exec"
+	"github.com/weibaohui/nanobot-go/agent/tools/httprequest"
 	"github.com/weibaohui/nanobot-go/agent/tools/listdir"
 	"github.com/weibaohui/nanobot-go/agent/tools/message"
+	"github.com/weibaohui/nanobot-go/agent/tools/plugin"
 	"github.com/weibaohui/nanobot-go/agent/tools/readfile"
+	"github.com/weibaohui/nanobot-go/agent/tools/scratch"
 	"github.com/weibaohui/nanobot-go/agent/tools/skill"
+	"github.com/weibaohui/nanobot-go/agent/tools/structurededit"
 	tasktool "github.com/weibaohui/nanobot-go/agent/tools/task"
+	"github.com/weibaohui/nanobot-go/agent/tools/translate"
+	"github.com/weibaohui/nanobot-go/agent/tools/weather"
 	"github.com/weibaohui/nanobot-go/agent/tools/webfetch"
 	"github.com/weibaohui/nanobot-go/agent/tools/websearch"
 	"github.com/weibaohui/nanobot-go/agent/tools/writefile"
@@ -24,6 +40,7 @@ import (
 	"github.com/weibaohui/nanobot-go/config"
 	"github.com/weibaohui/nanobot-go/cron"
 	"github.com/weibaohui/nanobot-go/session"
+	"github.com/weibaohui/nanobot-go/utils"
 	"go.uber.org/zap"
 )
 
@@ -40,6 +57,12 @@ type Loop struct {
 	sessions            *session.Manager
 	tools               *tools.Registry
 	running             bool
+	stopAccepting       context.CancelFunc            // 停止接收新的入站消息
+	inflight            sync.WaitGroup                // 正在处理中的回合
+	turns               sessionQueue                  // 同一会话的回合按到达顺序逐个处理
+	turnCancels         map[string]context.CancelFunc // 进行中回合的取消函数，按会话索引
+	toolErrorRetries    atomic.Int64                  // 工具错误反馈重试的累计次数
+	mu                  sync.Mutex
 	logger              *zap.Logger
 	hookManager         *hooks.HookManager
 	hookCallback        func(eventType events.EventType, data map[string]interface{}) // Hook 回调
@@ -47,6 +70,7 @@ type Loop struct {
 	interruptManager *InterruptManager
 	masterAgent      *MasterAgent
 	taskManager      *AgentTaskManager
+	compressor       *Compressor // 对话压缩器，未启用压缩时为 nil
 }
 
 // LoopConfig Loop 配置
@@ -98,6 +122,11 @@ func NewLoop(cfg *LoopConfig) *Loop {
 
 	loop.interruptManager = NewInterruptManager(cfg.MessageBus, logger)
 
+	// 按配置过滤工具，被禁用的工具不会注册
+	if cfg.Config != nil {
+		loop.tools.SetToolFilter(cfg.Config.Tools.Enabled, cfg.Config.Tools.Disabled)
+	}
+
 	loop.registerDefaultTools()
 
 	loop.taskManager = loop.createBackgroundAgentTaskManager()
@@ -117,6 +146,16 @@ func NewLoop(cfg *LoopConfig) *Loop {
 		loop.taskManager.SetRegisteredTools(toolNames)
 	}
 
+	// 系统提示中的能力列表与实际启用的工具保持一致
+	loop.context.SetToolNames(toolNames)
+	if loop.cfg != nil {
+		loop.context.SetName(loop.cfg.Agents.Defaults.Name)
+		loop.context.SetChannelPrompts(loop.cfg.Agents.ChannelPrompts)
+		loop.context.SetDeveloperPrompt(loop.cfg.Agents.DeveloperPrompt)
+	}
+
+	loop.compressor = loop.createCompressor()
+
 	adapter, err := NewChatModelAdapter(logger, loop.cfg, loop.sessions)
 	if err != nil {
 		logger.Error("创建 Provider 适配器失败", zap.Error(err))
@@ -154,33 +193,124 @@ func NewLoop(cfg *LoopConfig) *Loop {
 	return loop
 }
 
+// toolAllowedDirs 文件和命令工具允许访问的目录，为空表示不限制
+type toolAllowedDirs struct {
+	read  string // 读取类工具
+	write string // 写入类工具
+	exec  string // 命令执行工具
+}
+
+// toolAllowedDirs 按 restrictToWorkspace 及按工具类型的覆盖配置返回各类工具允许访问的目录
+func (l *Loop) toolAllowedDirs() toolAllowedDirs {
+	restrictRead, restrictWrite, restrictExec := l.restrictToWorkspace, l.restrictToWorkspace, l.restrictToWorkspace
+	if l.cfg != nil {
+		restrictRead, restrictWrite, restrictExec = l.cfg.Tools.Restrict.Resolve(l.restrictToWorkspace)
+	}
+	var dirs toolAllowedDirs
+	if restrictRead {
+		dirs.read = l.workspace
+	}
+	if restrictWrite {
+		dirs.write = l.workspace
+	}
+	if restrictExec {
+		dirs.exec = l.workspace
+	}
+	return dirs
+}
+
 // registerDefaultTools 注册默认工具
 func (l *Loop) registerDefaultTools() {
-	allowedDir := ""
-	if l.restrictToWorkspace {
-		allowedDir = l.workspace
-	}
+	dirs := l.toolAllowedDirs()
 
 	// 文件工具
-	l.tools.Register(&readfile.Tool{AllowedDir: allowedDir})
-	l.tools.Register(&writefile.Tool{AllowedDir: allowedDir})
-	l.tools.Register(&editfile.Tool{AllowedDir: allowedDir})
-	l.tools.Register(&listdir.Tool{AllowedDir: allowedDir})
-
-	// Shell 工具
-	l.tools.Register(&exec.Tool{Timeout: l.execTimeout, WorkingDir: l.workspace, RestrictToWorkspace: l.restrictToWorkspace})
+	readFileTool := &readfile.Tool{AllowedDir: dirs.read}
+	if l.cfg != nil {
+		readFileTool.MaxBytes = l.cfg.Tools.ReadFile.MaxBytes
+		readFileTool.HexdumpBytes = l.cfg.Tools.ReadFile.HexdumpBytes
+	}
+	l.tools.Register(readFileTool)
+	l.tools.Register(&writefile.Tool{AllowedDir: dirs.write})
+	l.tools.Register(&editfile.Tool{AllowedDir: dirs.write})
+	l.tools.Register(&listdir.Tool{AllowedDir: dirs.read})
+	l.tools.Register(&structurededit.Tool{AllowedDir: dirs.write})
+	l.tools.Register(&applypatch.Tool{AllowedDir: dirs.write, WorkingDir: l.workspace})
+
+	// Shell 工具：命令可以通过变量展开、cd 等方式访问任意路径，启用工作区隔离时不提供
+	if l.workspaceIsolated() {
+		l.logger.Info("已启用工作区隔离，不注册 exec 工具")
+	} else {
+		l.tools.Register(&exec.Tool{Timeout: l.execTimeout, WorkingDir: l.workspace, RestrictToWorkspace: dirs.exec != ""})
+	}
 
 	// Web 工具
 	l.tools.Register(&websearch.Tool{MaxResults: 5})
 	l.tools.Register(&webfetch.Tool{MaxChars: 50000})
 
-	// 消息工具
-	l.tools.Register(&message.Tool{SendCallback: func(msg any) error {
+	// 计算器工具
+	l.tools.Register(&calculator.Tool{})
+
+	// 日期时间工具
+	l.tools.Register(&datetime.Tool{Location: l.loadTimezone()})
+
+	// 天气查询工具
+	weatherTool := &weather.Tool{}
+	if l.cfg != nil {
+		weatherCfg := l.cfg.Tools.Weather
+		weatherTool = &weather.Tool{
+			Provider:        weatherCfg.Provider,
+			APIKey:          weatherCfg.APIKey,
+			Units:           weatherCfg.Units,
+			DefaultLocation: weatherCfg.DefaultLocation,
+			Timeout:         time.Duration(weatherCfg.Timeout) * time.Second,
+		}
+	}
+	l.tools.Register(weatherTool)
+
+	// 翻译工具
+	l.registerTranslateTool()
+
+	// HTTP 请求工具（需在配置中显式启用并设置主机白名单）
+	if l.cfg != nil && l.cfg.Tools.HTTP.Enabled {
+		httpCfg := l.cfg.Tools.HTTP
+		l.tools.Register(&httprequest.Tool{
+			AllowedHosts:     httpCfg.AllowedHosts,
+			MaxResponseBytes: httpCfg.MaxResponseBytes,
+			Timeout:          time.Duration(httpCfg.Timeout) * time.Second,
+		})
+	}
+
+	// 消息工具，未指定目标时发送到配置的默认目标；同步等待渠道发送结果，失败时如实告知 Agent
+	sendOutbound := func(msg any) error {
 		if outMsg, ok := msg.(*bus.OutboundMessage); ok {
-			l.bus.PublishOutbound(outMsg)
+			return l.bus.SendOutbound(outMsg)
 		}
 		return nil
-	}})
+	}
+	var contacts map[string]common.Contact
+	if l.cfg != nil && len(l.cfg.Contacts) > 0 {
+		contacts = make(map[string]common.Contact, len(l.cfg.Contacts))
+		for name, c := range l.cfg.Contacts {
+			contacts[name] = common.Contact{Channel: c.Channel, ChatID: c.ChatID}
+		}
+	}
+	messageTool := &message.Tool{SendCallback: sendOutbound, Contacts: contacts}
+	if l.cfg != nil {
+		messageTool.SetContext(l.cfg.Tools.Message.DefaultChannel, l.cfg.Tools.Message.DefaultChatID)
+	}
+	l.tools.Register(messageTool)
+
+	// 广播工具（需在配置中显式启用，只能发送到配置的分组和允许目标）
+	if l.cfg != nil && l.cfg.Tools.Broadcast.Enabled {
+		broadcastCfg := l.cfg.Tools.Broadcast
+		l.tools.Register(&broadcast.Tool{
+			SendCallback:   sendOutbound,
+			Groups:         broadcastCfg.Groups,
+			AllowedTargets: broadcastCfg.AllowedTargets,
+			MaxTargets:     broadcastCfg.MaxTargets,
+			Contacts:       contacts,
+		})
+	}
 
 	// Cron 工具
 	if l.cronService != nil {
@@ -194,9 +324,83 @@ func (l *Loop) registerDefaultTools() {
 		return "", nil
 	}))
 
+	// 会话草稿区工具
+	if l.sessions != nil {
+		l.tools.Register(&scratch.SetTool{Store: l.sessions, SessionKey: trace.GetSessionKey})
+		l.tools.Register(&scratch.GetTool{Store: l.sessions, SessionKey: trace.GetSessionKey})
+	}
+
 	// 注册通用技能工具（用于拦截后的技能调用）
 	l.tools.Register(skill.NewGenericSkillTool(l.context.GetSkillsLoader().LoadSkill))
 
+	// 外部可执行文件插件工具
+	l.registerPluginTools()
+}
+
+// registerTranslateTool 按配置创建翻译模型并注册翻译工具，模型没有可用的 API Key 时不注册
+func (l *Loop) registerTranslateTool() {
+	if l.cfg == nil {
+		return
+	}
+	modelName, apiKey, apiBase := l.cfg.TranslateModel()
+	if apiKey == "" {
+		l.logger.Debug("翻译模型未配置 API Key，跳过翻译工具", zap.String("model", modelName))
+		return
+	}
+	translateModel, err := newAuxChatModel(l.logger, l.cfg, modelName, apiKey, apiBase)
+	if err != nil {
+		l.logger.Warn("创建翻译模型失败，翻译工具已禁用", zap.Error(err))
+		return
+	}
+	l.tools.Register(&translate.Tool{Model: translateModel})
+}
+
+// registerPluginTools 加载插件目录中的外部工具，与内置工具同名的插件会被忽略
+// 插件是任意外部程序，启用工作区隔离时不加载
+func (l *Loop) registerPluginTools() {
+	if l.workspaceIsolated() {
+		l.logger.Info("已启用工作区隔离，不加载插件工具")
+		return
+	}
+	base := plugin.Tool{WorkingDir: l.workspace}
+	dir := filepath.Join(l.workspace, "plugins")
+	if l.cfg != nil {
+		pluginCfg := l.cfg.Tools.Plugins
+		base.Timeout = pluginCfg.Timeout
+		base.MaxOutputBytes = pluginCfg.MaxOutputBytes
+		if pluginCfg.Dir != "" {
+			dir = config.ExpandPath(pluginCfg.Dir)
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(l.workspace, dir)
+			}
+		}
+	}
+
+	plugins, errs := plugin.LoadDir(dir, base)
+	for _, err := range errs {
+		l.logger.Warn("加载插件工具失败", zap.String("dir", dir), zap.Error(err))
+	}
+	for _, t := range plugins {
+		if l.tools.Get(t.Name()) != nil {
+			l.logger.Warn("插件工具与已有工具同名，已忽略", zap.String("tool", t.Name()))
+			continue
+		}
+		l.tools.Register(t)
+		l.logger.Info("已加载插件工具", zap.String("tool", t.Name()), zap.String("path", t.Path))
+	}
+}
+
+// loadTimezone 加载配置的默认时区，未配置或无效时使用本地时区
+func (l *Loop) loadTimezone() *time.Location {
+	if l.cfg == nil || l.cfg.Agents.Defaults.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(l.cfg.Agents.Defaults.Timezone)
+	if err != nil {
+		l.logger.Warn("解析时区失败，使用本地时区", zap.Error(err), zap.String("timezone", l.cfg.Agents.Defaults.Timezone))
+		return time.Local
+	}
+	return loc
 }
 
 // registerTaskTools 注册后台任务工具
@@ -207,11 +411,16 @@ func (l *Loop) registerTaskTools(manager tasktool.Manager) {
 	l.tools.Register(&tasktool.StartTool{Manager: manager, Logger: l.logger})
 	l.tools.Register(&tasktool.GetTool{Manager: manager, Logger: l.logger})
 	l.tools.Register(&tasktool.StopTool{Manager: manager, Logger: l.logger})
+	l.tools.Register(&tasktool.ResumeTool{Manager: manager, Logger: l.logger})
 	l.tools.Register(&tasktool.ListTool{Manager: manager, Logger: l.logger})
 }
 
 // createBackgroundAgentTaskManager 创建任务管理器
 func (l *Loop) createBackgroundAgentTaskManager() *AgentTaskManager {
+	var tasksCfg config.TasksConfig
+	if l.cfg != nil {
+		tasksCfg = l.cfg.Tasks
+	}
 	taskManager, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{
 		Cfg:             l.cfg,
 		Workspace:       l.workspace,
@@ -230,11 +439,17 @@ func (l *Loop) createBackgroundAgentTaskManager() *AgentTaskManager {
 				TaskStopped:  "已停止",
 			}[status]
 			msg := fmt.Sprintf("后台任务 %s\n状态: %s\n任务ID: %s", statusText, statusText, taskID)
-			if result != "" && status == TaskFinished {
+			switch {
+			case status == TaskWaitingInput:
+				msg = fmt.Sprintf("后台任务需要补充信息\n任务ID: %s\n\n%s\n\n回复 /task reply %s <内容> 继续执行任务", taskID, result, taskID)
+			case result != "" && status == TaskFinished:
 				msg = fmt.Sprintf("后台任务完成\n任务ID: %s\n\n%s", taskID, result)
 			}
 			l.bus.PublishOutbound(bus.NewOutboundMessage(channel, chatID, msg))
 		},
+		MaxConcurrentTasks: tasksCfg.MaxConcurrentTasks,
+		MaxQueuedTasks:     tasksCfg.MaxQueuedTasks,
+		Store:              tasksCfg.Store,
 	})
 	if err != nil {
 		l.logger.Error("创建任务管理器失败", zap.Error(err))
@@ -245,13 +460,37 @@ func (l *Loop) createBackgroundAgentTaskManager() *AgentTaskManager {
 
 // Run 运行代理循环
 func (l *Loop) Run(ctx context.Context) error {
+	// 接收新消息使用独立的 context，Stop 时只停止接收，正在处理的回合继续使用 ctx 完成
+	acceptCtx, stopAccepting := context.WithCancel(ctx)
+	defer stopAccepting()
+	l.mu.Lock()
 	l.running = true
-	l.logger.Info("消息监听循环处理功能已启动")
+	l.stopAccepting = stopAccepting
+	l.mu.Unlock()
+	// /stop 需要在回合进行中立即生效，不能排在同一会话的回合之后，因此在入队前拦截
+	l.bus.SetInboundInterceptor(l.interceptStop)
+	defer l.bus.SetInboundInterceptor(nil)
+	workers := l.concurrency()
+	l.logger.Info("消息监听循环处理功能已启动", zap.Int("concurrency", workers))
+	return l.consume(ctx, acceptCtx, workers, l.handleTurn)
+}
+
+// consume 从总线取出入站消息交给 handle 处理，最多 workers 个回合同时进行
+// 同一会话的回合按取出顺序逐个处理；acceptCtx 取消后停止取消息，已取出的回合继续使用 ctx 完成
+func (l *Loop) consume(ctx, acceptCtx context.Context, workers int, handle func(ctx context.Context, msg *bus.InboundMessage)) error {
+	// slots 限制同时处理的回合数，先占用空位再取消息，避免取出的消息在停止时无人处理
+	slots := make(chan struct{}, workers)
+	for l.isRunning() {
+		select {
+		case slots <- struct{}{}:
+		case <-acceptCtx.Done():
+			return nil
+		}
 
-	for l.running {
 		// 等待消息
-		msg, err := l.bus.ConsumeInbound(ctx)
+		msg, err := l.bus.ConsumeInbound(acceptCtx)
 		if err != nil {
+			<-slots
 			if err == context.DeadlineExceeded {
 				continue
 			}
@@ -261,58 +500,216 @@ func (l *Loop) Run(ctx context.Context) error {
 			return err
 		}
 
-		// 处理消息
-		if err := l.processMessage(ctx, msg); err != nil {
-			l.logger.Error("处理消息失败", zap.Error(err))
-			outMsg := bus.NewOutboundMessage(msg.Channel, msg.ChatID, fmt.Sprintf("抱歉，我遇到了错误: %s", err))
-			// 传递原始消息的 message_id
-			if msg.Metadata != nil {
-				if msgID, ok := msg.Metadata["message_id"].(string); ok {
-					outMsg.Metadata["reply_to_message_id"] = msgID
-				}
-			}
-			l.bus.PublishOutbound(outMsg)
+		if workers == 1 {
+			handle(ctx, msg)
+			<-slots
+			continue
 		}
+
+		// 按取出顺序登记到会话队列，同一会话的回合等待前一个回合结束后再处理
+		// 等待期间让出处理名额，避免排队的回合占满名额而阻塞其他会话
+		wait, done := l.turns.enqueue(msg.SessionKey())
+		l.inflight.Add(1)
+		go func() {
+			defer l.inflight.Done()
+			defer done()
+			if wait != nil {
+				<-slots
+				<-wait
+				slots <- struct{}{}
+			}
+			defer func() { <-slots }()
+			handle(ctx, msg)
+		}()
 	}
 
 	return nil
 }
 
+// concurrency 返回同时处理的入站消息数
+func (l *Loop) concurrency() int {
+	if l.cfg == nil || l.cfg.Agents.Concurrency <= 1 {
+		return 1
+	}
+	return l.cfg.Agents.Concurrency
+}
+
+// handleTurn 处理一个回合，并登记为进行中，供 Drain 等待
+// 无论成功与否，回合结束后都发布回合结束事件，渠道据此停止输入状态
+func (l *Loop) handleTurn(ctx context.Context, msg *bus.InboundMessage) {
+	l.inflight.Add(1)
+	defer l.inflight.Done()
+	defer l.bus.PublishTurnEnd(msg.Channel, msg.ChatID)
+
+	ctx, cancel := l.beginTurn(ctx, msg.SessionKey())
+	defer cancel()
+
+	if err := l.processMessage(ctx, msg); err != nil {
+		l.logger.Error("处理消息失败", zap.Error(err), zap.String("trace_id", msg.TraceID))
+		if ctx.Err() != nil {
+			// 回合已被 /stop 取消或服务正在关闭，不再回复错误
+			return
+		}
+		l.bus.PublishOutbound(newReplyMessage(msg, fmt.Sprintf("抱歉，我遇到了错误: %s", err)))
+	}
+}
+
+// beginTurn 为回合创建可取消的上下文并登记，工具收到的上下文都由它派生
+// 返回的 cancel 必须在回合结束时调用，同时注销登记
+func (l *Loop) beginTurn(ctx context.Context, sessionKey string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	l.mu.Lock()
+	if l.turnCancels == nil {
+		l.turnCancels = make(map[string]context.CancelFunc)
+	}
+	l.turnCancels[sessionKey] = cancel
+	l.mu.Unlock()
+
+	return ctx, func() {
+		l.mu.Lock()
+		delete(l.turnCancels, sessionKey)
+		l.mu.Unlock()
+		cancel()
+	}
+}
+
+// CancelTurn 取消会话正在处理的回合，执行中的工具（如 exec 启动的命令）随之终止
+// 会话没有进行中的回合时返回 false
+func (l *Loop) CancelTurn(sessionKey string) bool {
+	l.mu.Lock()
+	cancel, ok := l.turnCancels[sessionKey]
+	l.mu.Unlock()
+	if ok {
+		cancel()
+		l.logger.Info("已取消进行中的回合", zap.String("会话", sessionKey))
+	}
+	return ok
+}
+
+// interceptStop 拦截 "/stop" 命令，取消发送方会话进行中的回合
+func (l *Loop) interceptStop(msg *bus.InboundMessage) bool {
+	if !strings.EqualFold(strings.TrimSpace(msg.Content), "/stop") {
+		return false
+	}
+	reply := "当前没有进行中的回合"
+	if l.CancelTurn(msg.SessionKey()) {
+		reply = "已停止当前回合"
+	}
+	l.bus.PublishOutbound(newReplyMessage(msg, reply))
+	return true
+}
+
+// isRunning 返回循环是否仍在接收消息
+func (l *Loop) isRunning() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.running
+}
+
 // Stop 停止代理循环
+// 只停止接收新的入站消息，正在处理的回合不受影响，可配合 Drain 等待其完成
 func (l *Loop) Stop() {
+	l.mu.Lock()
 	l.running = false
+	stopAccepting := l.stopAccepting
+	l.mu.Unlock()
+	if stopAccepting != nil {
+		stopAccepting()
+	}
 	l.logger.Info("代理循环正在停止")
 }
 
+// toolErrorRetryLimit 返回单个回合内允许的工具错误重试次数
+func (l *Loop) toolErrorRetryLimit() int {
+	if l.cfg == nil {
+		return 0
+	}
+	return l.cfg.Agents.Defaults.ToolErrorRetries
+}
+
+// ToolErrorRetries 返回工具错误反馈重试的累计次数
+func (l *Loop) ToolErrorRetries() int64 {
+	return l.toolErrorRetries.Load()
+}
+
+// Drain 等待正在处理的回合完成，超时返回 false
+func (l *Loop) Drain(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		l.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
 // processMessage 处理单条消息
 func (l *Loop) processMessage(ctx context.Context, msg *bus.InboundMessage) error {
-	preview := msg.Content
-	if len(preview) > 80 {
-		preview = preview[:80] + "..."
+	// 沿用发布入站消息时生成的 TraceID，使渠道、总线和 Agent 的日志可以按回合关联
+	if msg.TraceID == "" {
+		msg.TraceID = trace.NewTraceID()
 	}
+	// 为每条消息创建根 span，建立完整的调用链
+	ctx = trace.WithTraceID(ctx, msg.TraceID)
+	ctx = trace.WithSpanID(ctx, trace.NewSpanID())
+	// 根 span 没有 parentSpanID
+
+	preview := utils.TruncateString(msg.Content, 80)
 	l.logger.Info("处理消息",
 		zap.String("渠道", msg.Channel),
 		zap.String("发送者", msg.SenderID),
 		zap.String("内容", preview),
+		trace.LogField(ctx),
 	)
 
-	// 为每条消息创建根 span，建立完整的调用链
-	ctx = trace.WithTraceID(ctx, trace.NewTraceID())
-	ctx = trace.WithSpanID(ctx, trace.NewSpanID())
-	// 根 span 没有 parentSpanID
-
 	// 注入会话信息到 context，用于事件分发时获取
-	sessionKey := msg.SessionKey()
+	sessionKey := l.sessions.ResolveKey(msg.SessionKey())
 	ctx = trace.WithSessionInfo(ctx, sessionKey, msg.Channel)
+	ctx = trace.WithChatID(ctx, msg.ChatID)
+	ctx = withToolErrorBudget(ctx, l.toolErrorRetryLimit(), &l.toolErrorRetries)
+	ctx, err := l.withTurnWorkspace(ctx, msg)
+	if err != nil {
+		return err
+	}
 
 	// 触发收到消息事件
 	if l.hookManager != nil {
 		l.hookManager.OnMessageReceived(ctx, msg)
 	}
 
-	// 使用 Master Agent 处理消息（包括中断恢复和正常处理）
-	l.logger.Info("使用 Master Agent 处理消息")
-	response, err := l.masterAgent.Process(ctx, msg)
+	// /retry、/edit 先回退上一轮对话，再以原消息或修改后的消息重新交给 Agent
+	if regenerated, reply, ok := l.prepareRegenerate(ctx, msg, sessionKey); ok {
+		if reply != "" {
+			l.publishReply(ctx, msg, sessionKey, reply)
+			return nil
+		}
+		msg = regenerated
+	}
+
+	// 控制命令直接处理，不经过 LLM
+	if response, handled := l.handleCommand(msg); handled {
+		l.publishReply(ctx, msg, sessionKey, response)
+		return nil
+	}
+
+	// 要求 JSON 回复的消息（/json 前缀或 Metadata 标记）
+	if stripped, ok := parseJSONModeRequest(msg); ok {
+		msg = stripped
+		ctx = WithJSONMode(ctx)
+	}
+	ctx = l.withResponseLanguage(ctx, msg.Content)
+
+	// 简短闲聊走不带工具的快速模式，其余消息使用 Master Agent 处理（包括中断恢复和正常处理）
+	response, handled, err := l.processFastChat(ctx, msg)
+	if !handled {
+		l.logger.Info("使用 Master Agent 处理消息", trace.LogField(ctx))
+		response, err = l.masterAgent.Process(ctx, msg)
+	}
 
 	if err != nil {
 		// 检查是否是中断
@@ -321,34 +718,147 @@ func (l *Loop) processMessage(ctx context.Context, msg *bus.InboundMessage) erro
 		}
 		// 非中断错误：如果 response 包含错误信息（由 interruptible 构造），直接发送
 		// 否则构造默认错误消息
-		outMsg := bus.NewOutboundMessage(msg.Channel, msg.ChatID, response)
 		if response != "" {
-			l.logger.Error("Master Agent 处理失败", zap.Error(err), zap.String("response", response))
+			l.logger.Error("Master Agent 处理失败", zap.Error(err), zap.String("response", response), trace.LogField(ctx))
 		} else {
-			l.logger.Error("Master Agent 处理失败", zap.Error(err))
-			outMsg.Content = fmt.Sprintf("抱歉，处理消息时遇到错误: %v", err)
+			l.logger.Error("Master Agent 处理失败", zap.Error(err), trace.LogField(ctx))
+			response = fmt.Sprintf("抱歉，处理消息时遇到错误: %v", err)
 		}
-		// 传递原始消息的 message_id 用于渠道特定功能（如飞书删除反应表情）
-		if msg.Metadata != nil {
-			if msgID, ok := msg.Metadata["message_id"].(string); ok {
-				outMsg.Metadata["reply_to_message_id"] = msgID
-			}
-		}
-		l.bus.PublishOutbound(outMsg)
+		l.publishReply(ctx, msg, sessionKey, response)
 		return nil
 	}
 
 	// 发布响应
-	outMsg := bus.NewOutboundMessage(msg.Channel, msg.ChatID, response)
-	// 传递原始消息的 message_id 用于渠道特定功能（如飞书删除反应表情）
+	l.publishReply(ctx, msg, sessionKey, response)
+	l.compressAsync(sessionKey)
+	return nil
+
+}
+
+// ProcessDirect 不经过消息总线，直接将一条消息交给 Master Agent 处理并返回回复（可使用全部工具）
+// 供定时任务等内部调用方使用；sessionKey 不为空时使用独立的会话，channel/chatID 仍作为工具的投递目标
+func (l *Loop) ProcessDirect(ctx context.Context, content, sessionKey, channel, chatID string) (string, error) {
+	l.inflight.Add(1)
+	defer l.inflight.Done()
+
+	msg := &bus.InboundMessage{
+		Channel:   channel,
+		SenderID:  "system",
+		ChatID:    chatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata:  map[string]any{},
+		TraceID:   trace.NewTraceID(),
+	}
+	if sessionKey != "" {
+		msg.Metadata[bus.SessionKeyMetadataKey] = sessionKey
+	}
+
+	ctx = trace.WithTraceID(ctx, msg.TraceID)
+	ctx = trace.WithSpanID(ctx, trace.NewSpanID())
+	sessionKey = l.sessions.ResolveKey(msg.SessionKey())
+	ctx = trace.WithSessionInfo(ctx, sessionKey, msg.Channel)
+	ctx = trace.WithChatID(ctx, msg.ChatID)
+	ctx = withToolErrorBudget(ctx, l.toolErrorRetryLimit(), &l.toolErrorRetries)
+	ctx, err := l.withTurnWorkspace(ctx, msg)
+	if err != nil {
+		return "", err
+	}
+	ctx = l.withResponseLanguage(ctx, content)
+
+	l.logger.Info("直接处理消息",
+		zap.String("session_key", sessionKey),
+		zap.String("内容", utils.TruncateString(content, 80)),
+		trace.LogField(ctx),
+	)
+	response, err := l.masterAgent.Process(ctx, msg)
+	if err != nil {
+		return response, err
+	}
+	l.compressAsync(sessionKey)
+	return response, nil
+}
+
+// withResponseLanguage 按配置确定本回合的回复语言：优先使用固定语言，其次检测用户消息的语言
+func (l *Loop) withResponseLanguage(ctx context.Context, content string) context.Context {
+	if l.cfg == nil {
+		return ctx
+	}
+	lang := l.cfg.Agents.Language.Response
+	if lang == "" && l.cfg.Agents.Language.Detect {
+		lang = detectLanguage(content)
+	}
+	if lang == "" {
+		return ctx
+	}
+	l.logger.Debug("本回合回复语言", zap.String("language", lang), trace.LogField(ctx))
+	return WithResponseLanguage(ctx, lang)
+}
+
+// processFastChat 闲聊快速模式已启用且消息为简短闲聊时，不带工具直接回复
+func (l *Loop) processFastChat(ctx context.Context, msg *bus.InboundMessage) (string, bool, error) {
+	if l.cfg == nil || !l.cfg.Agents.FastChat.Enabled || isJSONMode(ctx) || !isCasualChat(msg.Content, l.cfg.Agents.FastChat.MaxChars) {
+		return "", false, nil
+	}
+	l.logger.Info("使用闲聊快速模式处理消息", trace.LogField(ctx))
+	return l.masterAgent.ProcessChatOnly(ctx, msg)
+}
+
+// createCompressor 按配置创建对话压缩器，未启用或创建失败时返回 nil
+func (l *Loop) createCompressor() *Compressor {
+	if l.cfg == nil || !l.cfg.Compress.Enabled || l.sessions == nil {
+		return nil
+	}
+	compressModel, err := NewCompressModel(l.logger, l.cfg)
+	if err != nil {
+		l.logger.Warn("创建压缩模型失败，对话压缩已禁用", zap.Error(err))
+		return nil
+	}
+	modelName, _, _ := l.cfg.CompressModel()
+	l.logger.Info("对话压缩已启用", zap.String("model", modelName))
+	return NewCompressor(l.cfg.Compress, compressModel, l.sessions, l.logger)
+}
+
+// compressAsync 回合结束后在后台检查并压缩会话，不阻塞消息处理
+func (l *Loop) compressAsync(sessionKey string) {
+	if l.compressor == nil {
+		return
+	}
+	go func() {
+		if _, err := l.compressor.MaybeCompress(context.Background(), sessionKey); err != nil {
+			l.logger.Warn("对话压缩失败", zap.String("session", sessionKey), zap.Error(err))
+		}
+	}()
+}
+
+// publishReply 发布对入站消息的回复，并触发发送消息事件（回合结束）
+func (l *Loop) publishReply(ctx context.Context, msg *bus.InboundMessage, sessionKey, content string) {
+	outMsg := newReplyMessage(msg, content)
+	l.bus.PublishOutbound(outMsg)
+	if l.hookManager != nil {
+		l.hookManager.OnMessageSent(ctx, outMsg, sessionKey)
+	}
+}
+
+// newReplyMessage 创建对入站消息的回复
+// 传递原始消息的 message_id 用于渠道特定功能（如飞书删除反应表情）
+func newReplyMessage(msg *bus.InboundMessage, content string) *bus.OutboundMessage {
+	outMsg := bus.NewOutboundMessage(msg.Channel, msg.ChatID, content)
+	outMsg.TraceID = msg.TraceID
 	if msg.Metadata != nil {
 		if msgID, ok := msg.Metadata["message_id"].(string); ok {
 			outMsg.Metadata["reply_to_message_id"] = msgID
 		}
 	}
-	l.bus.PublishOutbound(outMsg)
-	return nil
+	return outMsg
+}
 
+// GetTaskManager 获取后台任务管理器，未启用后台任务时返回 nil
+func (l *Loop) GetTaskManager() *TaskManagerAdapter {
+	if l.taskManager == nil {
+		return nil
+	}
+	return NewTaskManagerAdapter(l.taskManager)
 }
 
 // GetMasterAgent 获取 Master Agent
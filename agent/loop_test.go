@@ -1,8 +1,16 @@
 package agent
 
 import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/weibaohui/nanobot-go/agent/tools"
+	"github.com/weibaohui/nanobot-go/agent/tools/exec"
 	"github.com/weibaohui/nanobot-go/bus"
 	"github.com/weibaohui/nanobot-go/config"
 	"github.com/weibaohui/nanobot-go/session"
@@ -158,3 +166,231 @@ func TestLoopConfig(t *testing.T) {
 		t.Error("LoopConfig.RestrictToWorkspace 应该为 true")
 	}
 }
+
+// TestLoop_ToolAllowedDirs 测试按工具类型覆盖工作区限制
+func TestLoop_ToolAllowedDirs(t *testing.T) {
+	allow, deny := false, true
+
+	t.Run("未覆盖时使用 restrictToWorkspace", func(t *testing.T) {
+		loop := &Loop{workspace: "/ws", restrictToWorkspace: true, cfg: &config.Config{}}
+		want := toolAllowedDirs{read: "/ws", write: "/ws", exec: "/ws"}
+		if got := loop.toolAllowedDirs(); got != want {
+			t.Errorf("toolAllowedDirs() = %+v, 期望 %+v", got, want)
+		}
+	})
+
+	t.Run("允许读取工作区外文件，写入仍受限", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.Tools.Restrict.Read = &allow
+		loop := &Loop{workspace: "/ws", restrictToWorkspace: true, cfg: cfg}
+		want := toolAllowedDirs{read: "", write: "/ws", exec: "/ws"}
+		if got := loop.toolAllowedDirs(); got != want {
+			t.Errorf("toolAllowedDirs() = %+v, 期望 %+v", got, want)
+		}
+	})
+
+	t.Run("全局不限制时只限制命令", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.Tools.Restrict.Exec = &deny
+		loop := &Loop{workspace: "/ws", cfg: cfg}
+		want := toolAllowedDirs{exec: "/ws"}
+		if got := loop.toolAllowedDirs(); got != want {
+			t.Errorf("toolAllowedDirs() = %+v, 期望 %+v", got, want)
+		}
+	})
+
+	t.Run("注册的命令工具拒绝工作区外的路径", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.Tools.Restrict.Exec = &deny
+		workspace := t.TempDir()
+		loop := &Loop{workspace: workspace, cfg: cfg, tools: tools.NewRegistry(), context: NewContextBuilder(workspace), logger: zap.NewNop()}
+		loop.registerDefaultTools()
+
+		execTool, ok := loop.tools.Get("exec").(tool.InvokableTool)
+		if !ok {
+			t.Fatal("未注册 exec 工具")
+		}
+		out, err := execTool.InvokableRun(context.Background(), `{"command": "cat /etc/hostname"}`)
+		if err != nil || !strings.Contains(out, "不在允许的目录内") {
+			t.Errorf("InvokableRun() = (%q, %v), 期望拒绝工作区外的路径", out, err)
+		}
+		out, err = execTool.InvokableRun(context.Background(), `{"command": "echo ok"}`)
+		if err != nil || strings.TrimSpace(out) != "ok" {
+			t.Errorf("InvokableRun() = (%q, %v), 期望 ok", out, err)
+		}
+	})
+}
+
+// TestLoop_StopAndDrain 测试停止接收新消息并等待进行中的回合
+func TestLoop_StopAndDrain(t *testing.T) {
+	logger := zap.NewNop()
+	messageBus := bus.NewMessageBus(logger)
+	loop := &Loop{bus: messageBus, logger: logger}
+
+	t.Run("Stop 后 Run 立即返回", func(t *testing.T) {
+		done := make(chan error, 1)
+		go func() { done <- loop.Run(context.Background()) }()
+
+		time.Sleep(20 * time.Millisecond)
+		loop.Stop()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Run() 返回错误: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Stop() 后 Run() 未返回")
+		}
+	})
+
+	t.Run("Drain 等待进行中的回合", func(t *testing.T) {
+		loop.inflight.Add(1)
+		if loop.Drain(20 * time.Millisecond) {
+			t.Error("有进行中的回合时 Drain 应超时")
+		}
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			loop.inflight.Done()
+		}()
+		if !loop.Drain(time.Second) {
+			t.Error("回合完成后 Drain 应返回 true")
+		}
+	})
+}
+
+// TestLoop_ConcurrentTurns 测试并发处理时不同会话并行推进，同一会话按顺序处理
+func TestLoop_ConcurrentTurns(t *testing.T) {
+	logger := zap.NewNop()
+	messageBus := bus.NewMessageBus(logger)
+	loop := &Loop{bus: messageBus, logger: logger, running: true}
+
+	var mu sync.Mutex
+	var order []string
+	started := make(chan string, 4)
+	release := make(chan struct{})
+	handle := func(ctx context.Context, msg *bus.InboundMessage) {
+		started <- msg.Content
+		if msg.ChatID == "slow" {
+			<-release
+		}
+		mu.Lock()
+		order = append(order, msg.Content)
+		mu.Unlock()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go loop.consume(context.Background(), ctx, 2, handle)
+
+	messageBus.PublishInbound(bus.NewInboundMessage("cli", "u1", "slow", "慢1"))
+	messageBus.PublishInbound(bus.NewInboundMessage("cli", "u1", "slow", "慢2"))
+	messageBus.PublishInbound(bus.NewInboundMessage("cli", "u2", "fast", "快"))
+
+	// 慢会话的第一个回合阻塞时，另一个会话仍能被处理；两者并行，开始顺序不确定
+	var first []string
+	for range 2 {
+		select {
+		case got := <-started:
+			first = append(first, got)
+		case <-time.After(time.Second):
+			t.Fatalf("已开始处理 %v, 存在队头阻塞", first)
+		}
+	}
+	if slices.Sort(first); strings.Join(first, ",") != "快,慢1" {
+		t.Fatalf("开始处理 %v, 期望 慢1 和 快", first)
+	}
+
+	close(release)
+	select {
+	case got := <-started:
+		if got != "慢2" {
+			t.Fatalf("开始处理 %q, 期望 慢2", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("同一会话的后续回合未被处理")
+	}
+	loop.Drain(time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(order, ",") != "快,慢1,慢2" {
+		t.Errorf("完成顺序 = %v", order)
+	}
+}
+
+// TestLoop_CancelTurn 测试取消回合时终止正在执行的命令
+func TestLoop_CancelTurn(t *testing.T) {
+	loop := &Loop{logger: zap.NewNop()}
+	if loop.CancelTurn("cli:direct") {
+		t.Error("没有进行中的回合时 CancelTurn 应返回 false")
+	}
+
+	ctx, done := loop.beginTurn(context.Background(), "cli:direct")
+	defer done()
+
+	result := make(chan string, 1)
+	go func() {
+		out, _ := (&exec.Tool{}).InvokableRun(ctx, `{"command": "sleep 30"}`)
+		result <- out
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if !loop.CancelTurn("cli:direct") {
+		t.Fatal("CancelTurn 应返回 true")
+	}
+	select {
+	case out := <-result:
+		if !strings.Contains(out, "命令已取消") {
+			t.Errorf("命令结果 = %q", out)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("取消回合后命令仍在运行")
+	}
+
+	done()
+	if loop.CancelTurn("cli:direct") {
+		t.Error("回合结束后应注销取消函数")
+	}
+}
+
+// TestLoop_InterceptStop 测试 /stop 在入队前取消发送方会话的回合
+func TestLoop_InterceptStop(t *testing.T) {
+	messageBus := bus.NewMessageBus(zap.NewNop())
+	loop := &Loop{bus: messageBus, logger: zap.NewNop()}
+	messageBus.SetInboundInterceptor(loop.interceptStop)
+
+	messageBus.PublishInbound(bus.NewInboundMessage("cli", "user", "direct", "你好"))
+	if messageBus.InboundSize() != 1 {
+		t.Fatalf("普通消息应入队，InboundSize() = %d", messageBus.InboundSize())
+	}
+
+	ctx, done := loop.beginTurn(context.Background(), "cli:direct")
+	defer done()
+	messageBus.PublishInbound(bus.NewInboundMessage("cli", "user", "direct", "/stop"))
+	if messageBus.InboundSize() != 1 {
+		t.Errorf("/stop 不应入队，InboundSize() = %d", messageBus.InboundSize())
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Error("/stop 后回合上下文应被取消")
+	}
+	reply, err := messageBus.ConsumeOutbound(context.Background())
+	if err != nil || reply.Content != "已停止当前回合" {
+		t.Errorf("回复 = %+v, err = %v", reply, err)
+	}
+}
+
+// TestNewReplyMessage 测试回复沿用入站消息的 TraceID 和消息 ID
+func TestNewReplyMessage(t *testing.T) {
+	msg := bus.NewInboundMessage("feishu", "ou_1", "oc_1", "你好")
+	msg.TraceID = "trace-1"
+	msg.Metadata["message_id"] = "om_1"
+
+	reply := newReplyMessage(msg, "收到")
+	if reply.TraceID != "trace-1" || reply.Metadata["reply_to_message_id"] != "om_1" {
+		t.Errorf("reply = %+v", reply)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
 	"github.com/cloudwego/eino/schema"
@@ -29,6 +30,7 @@ type MasterAgent struct {
 	context   *ContextBuilder
 
 	adkRunner *adk.Runner
+	chatModel model.BaseChatModel // 不绑定工具的模型，用于闲聊快速模式
 }
 
 // MasterAgentConfig Master 配置
@@ -100,18 +102,26 @@ func NewMasterAgent(ctx context.Context, cfg *MasterAgentConfig) (*MasterAgent,
 	if len(cfg.Tools) > 0 {
 		toolsConfig = adk.ToolsConfig{
 			ToolsNodeConfig: compose.ToolsNodeConfig{
-				Tools: cfg.Tools,
+				Tools:               cfg.Tools,
+				ToolCallMiddlewares: []compose.ToolMiddleware{invalidToolArgsMiddleware(), toolErrorRetryMiddleware(logger), toolMetricsMiddleware()},
 			},
 		}
 	}
 
+	// 配置了 Agent 名称时用作 ADK Agent 名称，未配置时保持 Master
+	agentName := "Master"
+	if cfg.Cfg != nil && cfg.Cfg.Agents.Defaults.Name != "" {
+		agentName = cfg.Cfg.Agents.Defaults.AgentName()
+	}
+
 	masterAgent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
-		Name:        "Master",
-		Description: "主智能体",
-		Instruction: sa.context.BuildSystemPrompt(),
-		Model:       llm,
-		ToolsConfig: toolsConfig,
-		Exit:        &adk.ExitTool{},
+		Name:          agentName,
+		Description:   "主智能体",
+		Instruction:   sa.context.BuildSystemPrompt(),
+		Model:         llm,
+		ToolsConfig:   toolsConfig,
+		MaxIterations: interruptible.maxIterations,
+		Exit:          &adk.ExitTool{},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrAgentCreate, err)
@@ -124,6 +134,18 @@ func NewMasterAgent(ctx context.Context, cfg *MasterAgentConfig) (*MasterAgent,
 
 	// 设置 ADK Runner 到 interruptible
 	interruptible.adkRunner = sa.adkRunner
+	interruptible.summaryModel = llm
+	interruptible.react = newReActAgent(llm, cfg.Tools, interruptible.maxIterations, logger)
+	sa.chatModel = llm
+	// 闲聊配置了单独的模型时使用该模型，创建失败时沿用主模型
+	if cfg.Cfg != nil && cfg.Cfg.RoleModel(config.RoleChat) != cfg.Cfg.RoleModel(config.RoleReact) {
+		if chatLLM, err := NewRoleChatModelAdapter(logger, cfg.Cfg, cfg.Sessions, config.RoleChat); err != nil {
+			logger.Error("创建闲聊模型失败，使用主模型", zap.Error(err))
+		} else {
+			chatLLM.SetHookCallback(CreateHookCallback(cfg.HookManager, logger))
+			sa.chatModel = chatLLM
+		}
+	}
 
 	logger.Info("Master Agent 创建成功",
 		zap.String("model", cfg.Workspace),
@@ -139,6 +161,6 @@ func (sa *MasterAgent) Process(ctx context.Context, msg *bus.InboundMessage) (st
 
 // buildMessages 构建消息列表
 func (sa *MasterAgent) buildMessages(history []*schema.Message, userInput, channel, chatID string) []*schema.Message {
-	// 复用公共方法构建消息列表
-	return BuildMessageList("", history, userInput, channel, chatID)
+	// 复用公共方法构建消息列表，主系统提示已作为 Instruction 设置，这里只追加渠道提示
+	return BuildMessageList(sa.context.AppendChannelPrompt("", channel), history, userInput, channel, chatID)
 }
@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// modelListTTL 模型列表缓存时间
+const modelListTTL = 5 * time.Minute
+
+// ModelLister 通过 OpenAI 兼容接口 /models 查询提供商的可用模型，结果短时间缓存
+type ModelLister struct {
+	apiKey  string
+	apiBase string
+	client  *http.Client
+	ttl     time.Duration
+
+	mu        sync.Mutex
+	models    []string
+	fetchedAt time.Time
+}
+
+// NewModelLister 创建模型列表查询器，apiBase 为空时使用 OpenAI 官方地址
+func NewModelLister(apiKey, apiBase string) *ModelLister {
+	if apiBase == "" {
+		apiBase = "https://api.openai.com/v1"
+	}
+	return &ModelLister{
+		apiKey:  apiKey,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		client:  &http.Client{Timeout: 15 * time.Second},
+		ttl:     modelListTTL,
+	}
+}
+
+// ListModels 返回提供商的可用模型 ID（已排序），缓存未过期时直接返回缓存
+func (l *ModelLister) ListModels(ctx context.Context) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.models != nil && time.Since(l.fetchedAt) < l.ttl {
+		return l.models, nil
+	}
+
+	models, err := l.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l.models = models
+	l.fetchedAt = time.Now()
+	return models, nil
+}
+
+// fetch 请求 /models 接口
+func (l *ModelLister) fetch(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.apiBase+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建模型列表请求失败: %w", err)
+	}
+	if l.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.apiKey)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求模型列表失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取模型列表失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求模型列表失败: HTTP %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析模型列表失败: %w", err)
+	}
+
+	models := make([]string, 0, len(result.Data))
+	for _, m := range result.Data {
+		if m.ID != "" {
+			models = append(models, m.ID)
+		}
+	}
+	sort.Strings(models)
+	return models, nil
+}
+
+// ValidateModel 检查模型是否在提供商的模型列表中，不存在时返回的错误附带名称相近的模型
+func (l *ModelLister) ValidateModel(ctx context.Context, model string) error {
+	models, err := l.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range models {
+		if m == model {
+			return nil
+		}
+	}
+	if similar := similarModels(models, model, 5); len(similar) > 0 {
+		return fmt.Errorf("模型 %q 不存在，是否是: %s", model, strings.Join(similar, ", "))
+	}
+	return fmt.Errorf("模型 %q 不存在（提供商共有 %d 个模型）", model, len(models))
+}
+
+// similarModels 返回与 model 名称相近的模型：忽略大小写后互相包含，或去掉组织前缀后相同
+func similarModels(models []string, model string, limit int) []string {
+	target := strings.ToLower(model)
+	if idx := strings.LastIndex(target, "/"); idx >= 0 {
+		target = target[idx+1:]
+	}
+	if target == "" {
+		return nil
+	}
+
+	var result []string
+	for _, m := range models {
+		name := strings.ToLower(m)
+		if idx := strings.LastIndex(name, "/"); idx >= 0 {
+			name = name[idx+1:]
+		}
+		if strings.Contains(name, target) || strings.Contains(target, name) {
+			result = append(result, m)
+			if len(result) >= limit {
+				break
+			}
+		}
+	}
+	return result
+}
+
+// ValidateDefaultModel 检查配置的默认模型在对应提供商处是否存在
+func ValidateDefaultModel(ctx context.Context, cfg *config.Config) error {
+	if cfg == nil {
+		return ErrNilConfig
+	}
+	model := cfg.Agents.Defaults.Model
+	providerCfg := cfg.GetProvider(model)
+	if providerCfg == nil || providerCfg.APIKey == "" {
+		return fmt.Errorf("模型 %q 未匹配到配置了 API Key 的提供商", model)
+	}
+	return NewModelLister(providerCfg.APIKey, providerCfg.APIBase).ValidateModel(ctx, model)
+}
+
+// PingDefaultModel 向默认模型发送一次极小的补全请求，返回请求耗时，用于检查提供商是否可达
+func PingDefaultModel(ctx context.Context, cfg *config.Config) (time.Duration, error) {
+	apiKey, apiBase, modelName, err := createChatModelConfig(zap.NewNop(), cfg, "")
+	if err != nil {
+		return 0, err
+	}
+	chatModel, err := newAuxChatModel(nil, cfg, modelName, apiKey, apiBase)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	if _, err := chatModel.Generate(ctx, []*schema.Message{schema.UserMessage("ping")}, model.WithMaxTokens(1)); err != nil {
+		return time.Since(start), fmt.Errorf("补全请求失败: %w", err)
+	}
+	return time.Since(start), nil
+}
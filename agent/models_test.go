@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/weibaohui/nanobot-go/config"
+)
+
+// newModelsServer 创建返回固定模型列表的 /models 测试服务
+func newModelsServer(t *testing.T, calls *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[{"id":"gpt-4o-mini"},{"id":"Qwen/Qwen2.5-7B-Instruct"},{"id":"gpt-4o"}]}`))
+	}))
+}
+
+// TestModelLister 测试模型列表查询、缓存与模型校验
+func TestModelLister(t *testing.T) {
+	var calls int32
+	server := newModelsServer(t, &calls)
+	defer server.Close()
+
+	lister := NewModelLister("test-key", server.URL+"/v1/")
+	ctx := context.Background()
+
+	models, err := lister.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels() 返回错误: %v", err)
+	}
+	if strings.Join(models, ",") != "Qwen/Qwen2.5-7B-Instruct,gpt-4o,gpt-4o-mini" {
+		t.Errorf("ListModels() = %v", models)
+	}
+
+	t.Run("缓存有效期内不重复请求", func(t *testing.T) {
+		lister.ListModels(ctx)
+		if n := atomic.LoadInt32(&calls); n != 1 {
+			t.Errorf("请求次数 = %d, 期望 1", n)
+		}
+	})
+
+	t.Run("模型存在", func(t *testing.T) {
+		if err := lister.ValidateModel(ctx, "Qwen/Qwen2.5-7B-Instruct"); err != nil {
+			t.Errorf("ValidateModel() 返回错误: %v", err)
+		}
+	})
+
+	t.Run("模型不存在时提示相近模型", func(t *testing.T) {
+		err := lister.ValidateModel(ctx, "qwen/qwen2.5-7b-instruct")
+		if err == nil || !strings.Contains(err.Error(), "Qwen/Qwen2.5-7B-Instruct") {
+			t.Errorf("ValidateModel() = %v, 期望提示相近模型", err)
+		}
+	})
+
+	t.Run("认证失败", func(t *testing.T) {
+		_, err := NewModelLister("wrong", server.URL+"/v1").ListModels(ctx)
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("ListModels() = %v, 期望 HTTP 401 错误", err)
+		}
+	})
+}
+
+// TestValidateDefaultModel 测试校验配置中的默认模型
+func TestValidateDefaultModel(t *testing.T) {
+	var calls int32
+	server := newModelsServer(t, &calls)
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.Providers.OpenAI = config.ProviderConfig{APIKey: "test-key", APIBase: server.URL + "/v1"}
+
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	if err := ValidateDefaultModel(context.Background(), cfg); err != nil {
+		t.Errorf("ValidateDefaultModel() 返回错误: %v", err)
+	}
+
+	cfg.Agents.Defaults.Model = "gpt-4o-mni"
+	if err := ValidateDefaultModel(context.Background(), cfg); err == nil {
+		t.Error("模型名拼写错误时应返回错误")
+	}
+
+	cfg.Providers.OpenAI.APIKey = ""
+	if err := ValidateDefaultModel(context.Background(), cfg); err == nil {
+		t.Error("未配置 API Key 时应返回错误")
+	}
+}
+
+// TestPingDefaultModel 测试向默认模型发送探测请求
+func TestPingDefaultModel(t *testing.T) {
+	var maxTokens float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		maxTokens, _ = body["max_tokens"].(float64)
+		if body["model"] != "gpt-4o" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"model not found"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"p"},"finish_reason":"length"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.Providers.OpenAI = config.ProviderConfig{APIKey: "test-key", APIBase: server.URL + "/v1"}
+
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	if _, err := PingDefaultModel(context.Background(), cfg); err != nil {
+		t.Errorf("PingDefaultModel() 返回错误: %v", err)
+	}
+	if maxTokens != 1 {
+		t.Errorf("max_tokens = %v, 期望 1", maxTokens)
+	}
+
+	cfg.Agents.Defaults.Model = "gpt-5"
+	if _, err := PingDefaultModel(context.Background(), cfg); err == nil {
+		t.Error("模型不存在时应返回错误")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync/atomic"
 
 	"github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/model"
@@ -12,6 +14,7 @@ import (
 	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
 	"github.com/weibaohui/nanobot-go/config"
 	"github.com/weibaohui/nanobot-go/session"
+	"github.com/weibaohui/nanobot-go/utils"
 	"go.uber.org/zap"
 )
 
@@ -34,10 +37,20 @@ type HookCallback func(eventType events.EventType, data map[string]interface{})
 type ChatModelAdapter struct {
 	logger        *zap.Logger
 	chatModel     model.ToolCallingChatModel
-	registeredMap map[string]bool  // 已注册的工具名称
-	skillLoader   SkillLoader      // 技能加载器
-	sessions      *session.Manager // 会话管理器，用于记录 token 用量
-	hookCallback  HookCallback     // Hook 回调函数
+	registeredMap map[string]bool            // 已注册的工具名称
+	skillLoader   SkillLoader                // 技能加载器
+	sessions      *session.Manager           // 会话管理器，用于记录 token 用量
+	hookCallback  HookCallback               // Hook 回调函数
+	models        *ModelLister               // 提供商模型列表
+	modelName     string                     // 模型名称，用于计算缓存键
+	tools         []*schema.ToolInfo         // 绑定的工具，用于计算缓存键
+	cache         *responseCache             // 响应缓存，未启用时为 nil
+	baseModel     model.ToolCallingChatModel // 未绑定工具的模型，模型不支持工具调用时改用它
+	noToolCalling bool                       // 配置声明模型不支持工具调用
+	stop          []string                   // 配置的默认停止序列
+	logProbs      bool                       // 是否请求了 logprobs
+	argsRetries   int                        // 工具调用参数不是合法 JSON 时重新生成的次数
+	limiter       *requestLimiter            // 提供商请求并发限制，未配置时为 nil
 }
 
 // Sentinel errors 定义包级别的错误常量
@@ -47,12 +60,14 @@ var (
 	ErrNilAPIKey       = fmt.Errorf("API Key 不能为空")
 )
 
-func createChatModelConfig(logger *zap.Logger, cfg *config.Config) (apiKey, apiBase, modelName string, err error) {
+// createChatModelConfig 返回指定用途使用的模型及其提供商的 API Key、API Base，role 为空时使用默认模型
+func createChatModelConfig(logger *zap.Logger, cfg *config.Config, role string) (apiKey, apiBase, modelName string, err error) {
 	if cfg == nil {
 		return "", "", "", ErrNilConfig
 	}
 
-	providerCfg := cfg.GetProvider(cfg.Agents.Defaults.Model)
+	modelName = cfg.RoleModel(role)
+	providerCfg := cfg.GetProvider(modelName)
 	if providerCfg == nil || providerCfg.APIKey == "" {
 		logger.Warn("未找到有效的 API Key，请设置环境变量")
 		return "", "", "gpt-4o-mini", ErrNilAPIKey
@@ -63,21 +78,35 @@ func createChatModelConfig(logger *zap.Logger, cfg *config.Config) (apiKey, apiB
 		apiBase = "https://api.openai.com/v1"
 	}
 
-	return providerCfg.APIKey, apiBase, cfg.Agents.Defaults.Model, nil
+	return providerCfg.APIKey, apiBase, modelName, nil
 }
 
-// NewChatModelAdapter 创建 ChatModel 适配器
+// NewChatModelAdapter 创建使用默认模型的 ChatModel 适配器
 func NewChatModelAdapter(logger *zap.Logger, cfg *config.Config, sessions *session.Manager) (*ChatModelAdapter, error) {
-	apiKey, apiBase, modelName, err := createChatModelConfig(logger, cfg)
+	return NewRoleChatModelAdapter(logger, cfg, sessions, "")
+}
+
+// NewRoleChatModelAdapter 创建指定用途的 ChatModel 适配器，使用 agents.roles 中该用途配置的模型，未配置时使用默认模型
+func NewRoleChatModelAdapter(logger *zap.Logger, cfg *config.Config, sessions *session.Manager, role string) (*ChatModelAdapter, error) {
+	apiKey, apiBase, modelName, err := createChatModelConfig(logger, cfg, role)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrNilConfig, err)
 	}
 
-	chatModel, err := openai.NewChatModel(context.Background(), &openai.ChatModelConfig{
+	modelConfig := &openai.ChatModelConfig{
 		APIKey:  apiKey,
 		Model:   modelName,
 		BaseURL: apiBase,
-	})
+	}
+	// 开启提供商调试时记录原始请求/响应 JSON
+	if cfg.Providers.Debug && logger != nil {
+		modelConfig.HTTPClient = newDebugHTTPClient(logger)
+	}
+	if cfg.Providers.LogProbs {
+		modelConfig.ExtraFields = logProbsFields(cfg.Providers.TopLogProbs)
+	}
+
+	chatModel, err := openai.NewChatModel(context.Background(), modelConfig)
 	if err != nil {
 		if logger != nil {
 			logger.Error("创建 OpenAI ChatModel 失败", zap.Error(err))
@@ -85,12 +114,32 @@ func NewChatModelAdapter(logger *zap.Logger, cfg *config.Config, sessions *sessi
 		return nil, fmt.Errorf("%w: %w", ErrCreateChatModel, err)
 	}
 
-	return &ChatModelAdapter{
+	adapter := &ChatModelAdapter{
 		logger:        logger,
 		chatModel:     chatModel,
 		registeredMap: make(map[string]bool),
 		sessions:      sessions,
-	}, nil
+		models:        NewModelLister(apiKey, apiBase),
+		modelName:     modelName,
+		baseModel:     chatModel,
+		noToolCalling: !cfg.SupportsToolCalling(modelName),
+		stop:          cfg.Agents.Defaults.Stop,
+		logProbs:      cfg.Providers.LogProbs,
+		argsRetries:   cfg.Agents.Defaults.ToolArgsRetries,
+		limiter:       sharedRequestLimiter(apiBase, cfg.Providers.MaxConcurrency),
+	}
+	if cfg.LLMCache.Enabled {
+		adapter.cache = sharedResponseCache(cfg.LLMCache)
+	}
+	return adapter, nil
+}
+
+// ListModels 返回当前提供商的可用模型列表
+func (a *ChatModelAdapter) ListModels(ctx context.Context) ([]string, error) {
+	if a.models == nil {
+		return nil, fmt.Errorf("未配置提供商")
+	}
+	return a.models.ListModels(ctx)
 }
 
 // SetSkillLoader 设置技能加载器
@@ -137,6 +186,23 @@ func (a *ChatModelAdapter) Generate(ctx context.Context, input []*schema.Message
 		zap.Int("message_count", len(input)),
 	)
 
+	// 保证只有一条位于首位的系统消息（Instruction 与渠道提示等会各自生成 system 消息）
+	input = mergeSystemMessages(input)
+
+	// 追加会话级温度和最大 token 覆盖
+	opts = a.appendSessionOptions(ctx, opts)
+	opts = a.appendStopOptions(opts)
+
+	// JSON 模式：要求提供商返回 JSON 对象
+	jsonMode := isJSONMode(ctx)
+	if jsonMode {
+		input = withJSONInstruction(input)
+		opts = jsonModeOptions(opts)
+	}
+	if lang := responseLanguage(ctx); lang != "" {
+		input = withLanguageInstruction(input, lang)
+	}
+
 	// 触发 LLM 调用开始事件
 	a.triggerLLMCallStart(ctx, input)
 
@@ -146,35 +212,53 @@ func (a *ChatModelAdapter) Generate(ctx context.Context, input []*schema.Message
 			a.logger.Debug("[LLM] 发送消息",
 				zap.Int("index", i),
 				zap.String("role", string(msg.Role)),
-				zap.String("content_preview", func() string {
-					preview := msg.Content
-					if len(preview) > 200 {
-						preview = preview[:200] + "..."
-					}
-					return preview
-				}()),
+				zap.String("content_preview", utils.TruncateString(msg.Content, 200)),
 			)
 		}
 	}
 
-	// 调用底层 ChatModel
-	response, err := a.chatModel.Generate(ctx, input, opts...)
-	if err != nil {
-		if a.logger != nil {
-			a.logger.Error("调用 LLM 失败", zap.Error(err))
+	// 相同请求命中缓存时不再调用提供商
+	var cacheKey string
+	if a.cache != nil && cacheableRequest(input) {
+		cacheKey = responseCacheKey(a.modelName, input, a.tools, opts, jsonMode)
+	}
+	response, cacheHit := a.lookupCache(cacheKey)
+
+	if !cacheHit {
+		// 调用底层 ChatModel
+		var err error
+		response, err = a.generateWithValidArgs(ctx, input, opts...)
+		if err != nil {
+			if a.logger != nil {
+				a.logger.Error("调用 LLM 失败", zap.Error(err), trace.LogField(ctx))
+			}
+			// 触发 LLM 调用错误事件
+			a.triggerLLMCallError(ctx, err)
+			return nil, err
+		}
+		valid := true
+		if jsonMode {
+			response, valid = a.ensureJSONResponse(ctx, input, response, opts)
+		}
+		if cacheKey != "" && valid && cacheableResponse(response) {
+			a.cache.Put(cacheKey, response)
 		}
-		// 触发 LLM 调用错误事件
-		a.triggerLLMCallError(ctx, err)
-		return nil, err
 	}
 
 	a.logger.Debug("LLM 调用完成",
 		zap.String("span_id", llmSpanID),
 		zap.Int("tool_calls", len(response.ToolCalls)),
+		zap.String("finish_reason", finishReasonOf(response)),
+		zap.Bool("cache_hit", cacheHit),
 	)
 
+	a.logLogProbs(response)
+
+	// 根据结束原因提示截断或内容拦截
+	a.applyFinishReason(ctx, response)
+
 	// 触发 LLM 调用结束事件（包含 Token 使用）
-	a.triggerLLMCallEnd(ctx, response)
+	a.triggerLLMCallEnd(ctx, response, cacheHit)
 
 	// 拦截并转换工具调用
 	a.interceptToolCalls(response)
@@ -182,6 +266,234 @@ func (a *ChatModelAdapter) Generate(ctx context.Context, input []*schema.Message
 	return response, nil
 }
 
+// generate 调用底层 ChatModel
+// 模型不支持工具调用时不带工具请求；请求因不支持工具而失败时记录该模型并去掉工具重试
+func (a *ChatModelAdapter) generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	// 配置了并发限制时，名额已满的请求在这里排队
+	release, waited, err := a.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if waited > 0 {
+		a.logger.Debug("等待提供商请求名额", zap.Duration("waited", waited))
+	}
+
+	if !a.toolsBound() {
+		return a.chatModel.Generate(ctx, input, opts...)
+	}
+	if !a.supportsToolCalling() {
+		return a.baseModel.Generate(ctx, flattenToolMessages(input), opts...)
+	}
+
+	response, err := a.chatModel.Generate(ctx, input, opts...)
+	if err != nil && isToolsUnsupportedError(err) {
+		markToolCallingUnsupported(a.modelName)
+		a.logger.Warn("模型不支持工具调用，已对该模型停用工具",
+			zap.String("model", a.modelName),
+			trace.LogField(ctx),
+			zap.Error(err),
+		)
+		return a.baseModel.Generate(ctx, flattenToolMessages(input), opts...)
+	}
+	return response, err
+}
+
+// generateWithValidArgs 调用模型并校验工具调用参数
+// 常见的格式问题（单引号、结尾逗号等）直接修复；参数残缺（如流式拼接出错、输出被截断）时重新生成，
+// 超过重试次数后保留原始参数，由 invalidToolArgsMiddleware 把解析错误反馈给模型修正
+func (a *ChatModelAdapter) generateWithValidArgs(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	for attempt := 0; ; attempt++ {
+		response, err := a.generate(ctx, input, opts...)
+		if err != nil {
+			return nil, err
+		}
+		invalid, repaired := invalidToolCallArgs(response)
+		if len(repaired) > 0 {
+			a.logger.Info("已修复工具调用参数的 JSON 格式", zap.Strings("tools", repaired), trace.LogField(ctx))
+		}
+		if len(invalid) == 0 {
+			return response, nil
+		}
+		if attempt >= a.argsRetries {
+			a.logger.Warn("工具调用参数仍不是合法的 JSON，将错误反馈给模型",
+				zap.Strings("tools", invalid),
+				trace.LogField(ctx),
+				zap.Int("attempts", attempt+1),
+			)
+			return response, nil
+		}
+		a.logger.Warn("工具调用参数不是合法的 JSON，重新生成",
+			zap.Strings("tools", invalid),
+			trace.LogField(ctx),
+			zap.Int("attempt", attempt+1),
+		)
+	}
+}
+
+// invalidToolCallArgs 返回参数不是合法 JSON 对象的工具调用名称，以及参数已被修复的工具调用名称
+// 空参数视为无参数调用，规范化为 "{}"
+func invalidToolCallArgs(msg *schema.Message) (invalid, repaired []string) {
+	for i, tc := range msg.ToolCalls {
+		args := strings.TrimSpace(tc.Function.Arguments)
+		if args == "" {
+			msg.ToolCalls[i].Function.Arguments = "{}"
+			continue
+		}
+		if checkToolArgs(args) == nil {
+			continue
+		}
+		if fixed, ok := repairToolArgs(args); ok {
+			msg.ToolCalls[i].Function.Arguments = fixed
+			repaired = append(repaired, tc.Function.Name)
+			continue
+		}
+		invalid = append(invalid, tc.Function.Name)
+	}
+	return invalid, repaired
+}
+
+// toolsBound 返回是否绑定了工具且保留了未绑定工具的模型
+func (a *ChatModelAdapter) toolsBound() bool {
+	return len(a.tools) > 0 && a.baseModel != nil
+}
+
+// supportsToolCalling 返回模型是否支持原生工具调用（配置声明或运行中探测）
+func (a *ChatModelAdapter) supportsToolCalling() bool {
+	return !a.noToolCalling && !toolCallingDisabled(a.modelName)
+}
+
+// lookupCache 查询响应缓存，key 为空表示本次请求不使用缓存
+func (a *ChatModelAdapter) lookupCache(key string) (*schema.Message, bool) {
+	if key == "" {
+		return nil, false
+	}
+	response, ok := a.cache.Get(key)
+	if ok {
+		hits, misses := a.cache.Stats()
+		a.logger.Info("LLM 响应命中缓存", zap.Int64("hits", hits), zap.Int64("misses", misses))
+	}
+	return response, ok
+}
+
+// appendSessionOptions 根据会话中通过 /temp、/maxtokens 设置的覆盖值追加模型参数
+func (a *ChatModelAdapter) appendSessionOptions(ctx context.Context, opts []model.Option) []model.Option {
+	if a.sessions == nil {
+		return opts
+	}
+	sessionKey, _ := ctx.Value(SessionKeyContextKey).(string)
+	if sessionKey == "" {
+		return opts
+	}
+	temperature, maxTokens := a.sessions.ModelOverrides(sessionKey)
+	if temperature != nil {
+		opts = append(opts, model.WithTemperature(float32(*temperature)))
+	}
+	if maxTokens > 0 {
+		opts = append(opts, model.WithMaxTokens(maxTokens))
+	}
+	return opts
+}
+
+// maxStopSequences OpenAI 兼容接口允许的最大停止序列数
+const maxStopSequences = 4
+
+// appendStopOptions 合并配置的默认停止序列与调用方（如 ReAct 代理）指定的停止序列
+// 调用方指定的优先，超出上限的部分丢弃；都为空时不设置，请求中也不会出现 stop 字段
+func (a *ChatModelAdapter) appendStopOptions(opts []model.Option) []model.Option {
+	if len(a.stop) == 0 {
+		return opts
+	}
+	requested := model.GetCommonOptions(&model.Options{}, opts...).Stop
+	stop := make([]string, 0, maxStopSequences)
+	seen := make(map[string]bool)
+	for _, s := range append(append([]string{}, requested...), a.stop...) {
+		if s == "" || seen[s] {
+			continue
+		}
+		if len(stop) == maxStopSequences {
+			a.logger.Warn("停止序列超过上限，多余的已忽略", zap.Int("max", maxStopSequences), zap.String("ignored", s))
+			continue
+		}
+		seen[s] = true
+		stop = append(stop, s)
+	}
+	return append(opts, model.WithStop(stop))
+}
+
+// 模型返回的结束原因
+const (
+	finishReasonLength        = "length"
+	finishReasonContentFilter = "content_filter"
+)
+
+const (
+	// truncatedNotice 回复因 max_tokens 限制被截断时追加的提示
+	truncatedNotice = "（回复因长度限制被截断）"
+	// contentFilteredReply 回复被内容安全策略拦截时返回给用户的提示
+	contentFilteredReply = "抱歉，该回复被模型的内容安全策略拦截，请调整问题后重试。"
+)
+
+// finishReasonOf 返回响应的结束原因，没有时返回空字符串
+func finishReasonOf(msg *schema.Message) string {
+	if msg == nil || msg.ResponseMeta == nil {
+		return ""
+	}
+	return msg.ResponseMeta.FinishReason
+}
+
+// applyFinishReason 根据结束原因记录需要附加到回复末尾的提示
+// length: 截断提示；content_filter: 明确的拦截说明
+// 提示不写入模型消息，避免保存到历史后在之后的回合中回放给模型
+func (a *ChatModelAdapter) applyFinishReason(ctx context.Context, msg *schema.Message) {
+	switch finishReasonOf(msg) {
+	case finishReasonLength:
+		if a.logger != nil {
+			a.logger.Warn("LLM 回复因长度限制被截断", zap.Int("tool_calls", len(msg.ToolCalls)))
+		}
+		// 工具调用参数被截断时无法补救，交给工具参数校验处理
+		if len(msg.ToolCalls) == 0 && msg.Content != "" {
+			setReplyNotice(ctx, truncatedNotice)
+		}
+	case finishReasonContentFilter:
+		if a.logger != nil {
+			a.logger.Warn("LLM 回复被内容安全策略拦截")
+		}
+		setReplyNotice(ctx, contentFilteredReply)
+	}
+}
+
+// replyNoticeKey context 中记录本次执行需要附加到回复末尾的提示的 key
+type replyNoticeKey struct{}
+
+// withReplyNotice 为一次执行注入回复提示，模型调用记录的提示由 appendReplyNotice 附加到最终回复
+func withReplyNotice(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replyNoticeKey{}, new(atomic.Pointer[string]))
+}
+
+// setReplyNotice 记录当前执行的回复提示，以最后一次模型调用为准
+func setReplyNotice(ctx context.Context, notice string) {
+	if holder, ok := ctx.Value(replyNoticeKey{}).(*atomic.Pointer[string]); ok {
+		holder.Store(&notice)
+	}
+}
+
+// appendReplyNotice 将当前执行记录的提示附加到回复末尾，回复为空时直接返回提示
+func appendReplyNotice(ctx context.Context, reply string) string {
+	holder, ok := ctx.Value(replyNoticeKey{}).(*atomic.Pointer[string])
+	if !ok {
+		return reply
+	}
+	notice := holder.Load()
+	if notice == nil {
+		return reply
+	}
+	if strings.TrimSpace(reply) == "" {
+		return *notice
+	}
+	return reply + "\n\n" + *notice
+}
+
 // interceptToolCall 拦截工具调用，如果工具不存在则转换为技能调用
 func (a *ChatModelAdapter) interceptToolCall(toolName string, argumentsJSON string) (string, string, error) {
 
@@ -332,7 +644,7 @@ func (a *ChatModelAdapter) triggerLLMCallStart(ctx context.Context, input []*sch
 }
 
 // triggerLLMCallEnd 触发 LLM 调用结束事件
-func (a *ChatModelAdapter) triggerLLMCallEnd(ctx context.Context, response *schema.Message) {
+func (a *ChatModelAdapter) triggerLLMCallEnd(ctx context.Context, response *schema.Message, cacheHit bool) {
 	if a.hookCallback == nil {
 		return
 	}
@@ -342,6 +654,7 @@ func (a *ChatModelAdapter) triggerLLMCallEnd(ctx context.Context, response *sche
 	parentSpanID := trace.GetParentSpanID(ctx)
 	sessionKey := trace.GetSessionKey(ctx)
 	channel := trace.GetChannel(ctx)
+	chatID := trace.GetChatID(ctx)
 
 	// 提取 Token 使用信息
 	var tokenUsage *schema.TokenUsage
@@ -360,9 +673,13 @@ func (a *ChatModelAdapter) triggerLLMCallEnd(ctx context.Context, response *sche
 		"parent_span_id": parentSpanID,
 		"session_key":    sessionKey,
 		"channel":        channel,
+		"chat_id":        chatID,
 		"response":       response.Content,
+		"reasoning":      response.ReasoningContent,
 		"tool_calls":     toolCalls,
 		"token_usage":    tokenUsage,
+		"finish_reason":  finishReasonOf(response),
+		"cache_hit":      cacheHit,
 	}
 	a.hookCallback(events.EventLLMCallEnd, data)
 }
@@ -426,5 +743,15 @@ func (a *ChatModelAdapter) WithTools(tools []*schema.ToolInfo) (model.ToolCallin
 		skillLoader:   a.skillLoader,
 		sessions:      a.sessions,
 		hookCallback:  a.hookCallback, // 复制 hookCallback
+		models:        a.models,
+		modelName:     a.modelName,
+		tools:         tools,
+		cache:         a.cache,
+		baseModel:     a.baseModel,
+		noToolCalling: a.noToolCalling,
+		stop:          a.stop,
+		logProbs:      a.logProbs,
+		argsRetries:   a.argsRetries,
+		limiter:       a.limiter,
 	}, nil
 }
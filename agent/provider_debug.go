@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxDebugBodyBytes 调试日志中记录的请求/响应体最大字节数
+const maxDebugBodyBytes = 8000
+
+// debugTransport 记录发往提供商的原始请求和响应 JSON
+// 只在 providers.debug 开启时使用；日志经过全局脱敏，Authorization 头不会被记录
+type debugTransport struct {
+	base   http.RoundTripper
+	logger *zap.Logger
+}
+
+// newDebugHTTPClient 创建记录请求/响应体的 HTTP 客户端
+func newDebugHTTPClient(logger *zap.Logger) *http.Client {
+	return &http.Client{Transport: &debugTransport{base: http.DefaultTransport, logger: logger}}
+}
+
+// RoundTrip 发送请求并在调试级别记录请求体和响应体
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = data
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	t.logger.Debug("[Provider] 请求",
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.String()),
+		zap.String("body", truncateDebugBody(reqBody)),
+	)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.logger.Debug("[Provider] 请求失败", zap.Error(err), zap.Duration("duration", time.Since(start)))
+		return nil, err
+	}
+
+	// 流式响应逐段返回，读取完整响应体会破坏流式语义，只记录状态
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.logger.Debug("[Provider] 流式响应", zap.Int("status", resp.StatusCode), zap.Duration("duration", time.Since(start)))
+		return resp, nil
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	t.logger.Debug("[Provider] 响应",
+		zap.Int("status", resp.StatusCode),
+		zap.Duration("duration", time.Since(start)),
+		zap.String("body", truncateDebugBody(respBody)),
+	)
+	return resp, nil
+}
+
+// truncateDebugBody 截断过长的请求/响应体
+func truncateDebugBody(body []byte) string {
+	if len(body) <= maxDebugBodyBytes {
+		return string(body)
+	}
+	// 截断位置可能落在多字节字符中间，去掉不完整的字符
+	return strings.ToValidUTF8(string(body[:maxDebugBodyBytes]), "") + "...(已截断，共 " + strconv.Itoa(len(body)) + " 字节)"
+}
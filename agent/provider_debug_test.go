@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestDebugTransport 测试记录请求和响应体且不影响请求本身
+func TestDebugTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"echo":` + string(body) + `}`))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	client := newDebugHTTPClient(zap.New(core))
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{"model":"gpt"}`))
+	if err != nil {
+		t.Fatalf("Post() 返回错误: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"echo":{"model":"gpt"}}` {
+		t.Errorf("响应体 = %q", body)
+	}
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("日志条数 = %d, 期望 2", len(entries))
+	}
+	if got := entries[0].ContextMap()["body"]; got != `{"model":"gpt"}` {
+		t.Errorf("请求日志 body = %v", got)
+	}
+	if got := entries[1].ContextMap()["body"]; got != string(body) {
+		t.Errorf("响应日志 body = %v", got)
+	}
+}
+
+// TestTruncateDebugBody 测试超长请求体截断
+func TestTruncateDebugBody(t *testing.T) {
+	long := strings.Repeat("中", maxDebugBodyBytes)
+	got := truncateDebugBody([]byte(long))
+	if !strings.Contains(got, "已截断") || len(got) > maxDebugBodyBytes+100 {
+		t.Errorf("截断结果长度 = %d", len(got))
+	}
+	if strings.ContainsRune(got, '�') {
+		t.Error("截断结果不应包含无效字符")
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProviderRequestStats 提供商请求并发限制统计
+type ProviderRequestStats struct {
+	Requests int64         `json:"requests"`  // 经过限制的请求数
+	Waits    int64         `json:"waits"`     // 因名额已满而排队的请求数
+	WaitTime time.Duration `json:"wait_time"` // 累计排队时间
+}
+
+// requestLimiter 限制同时发往同一提供商的请求数，超出的请求排队等待
+type requestLimiter struct {
+	slots    chan struct{}
+	requests atomic.Int64
+	waits    atomic.Int64
+	waitTime atomic.Int64 // 纳秒
+}
+
+// requestLimiterKey 共享限制器的键，同一提供商地址和名额数共享一个限制器
+type requestLimiterKey struct {
+	apiBase string
+	limit   int
+}
+
+var (
+	sharedLimitersMu sync.Mutex
+	sharedLimiters   = make(map[requestLimiterKey]*requestLimiter)
+)
+
+// sharedRequestLimiter 返回提供商共享的请求限制器，limit 不大于 0 时返回 nil 表示不限制
+// 主循环、后台任务等各自创建适配器，共享限制器才能限制发往同一提供商的总请求数
+func sharedRequestLimiter(apiBase string, limit int) *requestLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+	key := requestLimiterKey{apiBase: apiBase, limit: limit}
+	if l, ok := sharedLimiters[key]; ok {
+		return l
+	}
+	l := &requestLimiter{slots: make(chan struct{}, limit)}
+	sharedLimiters[key] = l
+	return l
+}
+
+// acquire 占用一个请求名额，名额已满时等待，context 取消时返回错误
+// 返回的 waited 为排队时间，没有排队时为 0；请求结束后必须调用 release
+func (l *requestLimiter) acquire(ctx context.Context) (release func(), waited time.Duration, err error) {
+	if l == nil {
+		return func() {}, 0, nil
+	}
+	l.requests.Add(1)
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, 0, nil
+	default:
+	}
+
+	l.waits.Add(1)
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+		waited = time.Since(start)
+		l.waitTime.Add(int64(waited))
+		return l.release, waited, nil
+	case <-ctx.Done():
+		l.waitTime.Add(int64(time.Since(start)))
+		return nil, 0, ctx.Err()
+	}
+}
+
+// release 归还请求名额
+func (l *requestLimiter) release() {
+	<-l.slots
+}
+
+// ProviderRequestStatsSnapshot 返回所有提供商请求限制器的累计统计，未配置并发限制时为零值
+func ProviderRequestStatsSnapshot() ProviderRequestStats {
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+	var stats ProviderRequestStats
+	for _, l := range sharedLimiters {
+		stats.Requests += l.requests.Load()
+		stats.Waits += l.waits.Load()
+		stats.WaitTime += time.Duration(l.waitTime.Load())
+	}
+	return stats
+}
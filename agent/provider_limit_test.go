@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// slowChatModel 记录同时进行中的请求数的模拟模型
+type slowChatModel struct {
+	inflight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (m *slowChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	n := m.inflight.Add(1)
+	defer m.inflight.Add(-1)
+	for {
+		peak := m.peak.Load()
+		if n <= peak || m.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return schema.AssistantMessage("好的", nil), nil
+}
+
+func (m *slowChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, nil
+}
+
+func (m *slowChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// TestChatModelAdapter_RequestLimit 测试提供商请求并发限制
+func TestChatModelAdapter_RequestLimit(t *testing.T) {
+	if sharedRequestLimiter("https://api.example.com/v1", 0) != nil {
+		t.Error("未配置并发限制时应返回 nil")
+	}
+	limiter := sharedRequestLimiter("https://limit.example.com/v1", 2)
+	if sharedRequestLimiter("https://limit.example.com/v1", 2) != limiter {
+		t.Error("同一提供商应共享限制器")
+	}
+
+	llm := &slowChatModel{}
+	input := []*schema.Message{schema.UserMessage("你好")}
+	before := ProviderRequestStatsSnapshot()
+
+	t.Run("超出名额的请求排队", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 5 {
+			// 各自创建适配器，模拟主循环和后台任务共享同一提供商
+			adapter := &ChatModelAdapter{logger: zap.NewNop(), chatModel: llm, registeredMap: map[string]bool{}, limiter: limiter}
+			wg.Go(func() {
+				if _, err := adapter.Generate(context.Background(), input); err != nil {
+					t.Errorf("Generate() 返回错误: %v", err)
+				}
+			})
+		}
+		wg.Wait()
+
+		if peak := llm.peak.Load(); peak != 2 {
+			t.Errorf("同时进行的请求数峰值 = %d, 期望 2", peak)
+		}
+		stats := ProviderRequestStatsSnapshot()
+		if stats.Requests-before.Requests != 5 || stats.Waits-before.Waits < 3 || stats.WaitTime <= before.WaitTime {
+			t.Errorf("统计 = %+v, 之前 %+v", stats, before)
+		}
+	})
+
+	t.Run("排队时取消返回错误", func(t *testing.T) {
+		release, _, _ := limiter.acquire(context.Background())
+		release2, _, _ := limiter.acquire(context.Background())
+		defer release()
+		defer release2()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), chatModel: llm, registeredMap: map[string]bool{}, limiter: limiter}
+		if _, err := adapter.Generate(ctx, input); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Generate() error = %v, 期望 DeadlineExceeded", err)
+		}
+	})
+}
@@ -1,11 +1,15 @@
 package agent
 
 import (
+	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/cloudwego/eino/components/model"
 	"github.com/cloudwego/eino/schema"
 	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/session"
 	"go.uber.org/zap"
 )
 
@@ -13,7 +17,7 @@ import (
 func TestCreateChatModelConfig(t *testing.T) {
 	t.Run("配置为空", func(t *testing.T) {
 		logger := zap.NewNop()
-		apiKey, apiBase, modelName, err := createChatModelConfig(logger, nil)
+		apiKey, apiBase, modelName, err := createChatModelConfig(logger, nil, "")
 
 		if err != ErrNilConfig {
 			t.Errorf("createChatModelConfig() error = %v, 期望 %v", err, ErrNilConfig)
@@ -33,7 +37,7 @@ func TestCreateChatModelConfig(t *testing.T) {
 		logger := zap.NewNop()
 		cfg := &config.Config{}
 
-		apiKey, _, modelName, err := createChatModelConfig(logger, cfg)
+		apiKey, _, modelName, err := createChatModelConfig(logger, cfg, "")
 
 		if err != ErrNilAPIKey {
 			t.Errorf("createChatModelConfig() error = %v, 期望 %v", err, ErrNilAPIKey)
@@ -45,6 +49,23 @@ func TestCreateChatModelConfig(t *testing.T) {
 			t.Errorf("createChatModelConfig() modelName = %q, 期望 gpt-4o-mini", modelName)
 		}
 	})
+
+	t.Run("按用途使用不同提供商的模型", func(t *testing.T) {
+		cfg := &config.Config{}
+		cfg.Agents.Defaults.Model = "openai/gpt-4o"
+		cfg.Agents.Roles.Chat = "deepseek-chat"
+		cfg.Providers.OpenAI.APIKey = "openai-key"
+		cfg.Providers.DeepSeek.APIKey = "deepseek-key"
+
+		apiKey, _, modelName, err := createChatModelConfig(zap.NewNop(), cfg, config.RoleChat)
+		if err != nil || apiKey != "deepseek-key" || modelName != "deepseek-chat" {
+			t.Errorf("chat = (%q, %q, %v), 期望使用 deepseek-chat", apiKey, modelName, err)
+		}
+		apiKey, _, modelName, err = createChatModelConfig(zap.NewNop(), cfg, config.RoleReact)
+		if err != nil || apiKey != "openai-key" || modelName != "openai/gpt-4o" {
+			t.Errorf("react = (%q, %q, %v), 期望使用默认模型", apiKey, modelName, err)
+		}
+	})
 }
 
 // TestChatModelAdapter_SetSkillLoader 测试设置技能加载器
@@ -318,3 +339,142 @@ func containsSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// TestChatModelAdapter_ApplyFinishReason 测试根据结束原因调整响应
+func TestChatModelAdapter_ApplyFinishReason(t *testing.T) {
+	adapter := &ChatModelAdapter{logger: zap.NewNop()}
+	newMsg := func(content, reason string) *schema.Message {
+		return &schema.Message{Role: schema.Assistant, Content: content, ResponseMeta: &schema.ResponseMeta{FinishReason: reason}}
+	}
+
+	tests := []struct {
+		name string
+		msg  *schema.Message
+		want string
+	}{
+		{"正常结束", newMsg("你好", "stop"), "你好"},
+		{"长度截断", newMsg("这是一段很长的", finishReasonLength), "这是一段很长的\n\n" + truncatedNotice},
+		{"内容拦截无内容", newMsg("", finishReasonContentFilter), contentFilteredReply},
+		{"内容拦截有部分内容", newMsg("部分", finishReasonContentFilter), "部分\n\n" + contentFilteredReply},
+		{"无 ResponseMeta", &schema.Message{Role: schema.Assistant, Content: "原样"}, "原样"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := withReplyNotice(context.Background())
+			content := tt.msg.Content
+			adapter.applyFinishReason(ctx, tt.msg)
+			if tt.msg.Content != content {
+				t.Errorf("模型消息被修改为 %q，提示不应写入历史", tt.msg.Content)
+			}
+			if got := appendReplyNotice(ctx, tt.msg.Content); got != tt.want {
+				t.Errorf("回复 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("未注入回复提示时不附加", func(t *testing.T) {
+		msg := newMsg("部分", finishReasonContentFilter)
+		adapter.applyFinishReason(context.Background(), msg)
+		if got := appendReplyNotice(context.Background(), msg.Content); got != "部分" {
+			t.Errorf("回复 = %q, 期望 部分", got)
+		}
+	})
+}
+
+// TestChatModelAdapter_AppendSessionOptions 测试追加会话级模型参数
+func TestChatModelAdapter_AppendSessionOptions(t *testing.T) {
+	sessions := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), nil)
+	adapter := &ChatModelAdapter{logger: zap.NewNop(), sessions: sessions}
+	ctx := context.WithValue(context.Background(), SessionKeyContextKey, "cli:default")
+
+	if opts := adapter.appendSessionOptions(ctx, nil); len(opts) != 0 {
+		t.Errorf("未设置覆盖时 len(opts) = %d, 期望 0", len(opts))
+	}
+
+	temperature := 0.3
+	sessions.SetTemperature("cli:default", &temperature)
+	sessions.SetMaxTokens("cli:default", 1024)
+	options := model.GetCommonOptions(nil, adapter.appendSessionOptions(ctx, nil)...)
+	if options.Temperature == nil || *options.Temperature != float32(0.3) {
+		t.Errorf("Temperature = %v, 期望 0.3", options.Temperature)
+	}
+	if options.MaxTokens == nil || *options.MaxTokens != 1024 {
+		t.Errorf("MaxTokens = %v, 期望 1024", options.MaxTokens)
+	}
+}
+
+// TestChatModelAdapter_AppendStopOptions 测试合并默认停止序列
+func TestChatModelAdapter_AppendStopOptions(t *testing.T) {
+	t.Run("未配置时不设置", func(t *testing.T) {
+		adapter := &ChatModelAdapter{logger: zap.NewNop()}
+		if opts := adapter.appendStopOptions(nil); len(opts) != 0 {
+			t.Errorf("len(opts) = %d, 期望 0", len(opts))
+		}
+	})
+
+	t.Run("与调用方指定的合并", func(t *testing.T) {
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), stop: []string{"</answer>", "Observation:", "###", "END", "STOP"}}
+		opts := adapter.appendStopOptions([]model.Option{model.WithStop([]string{"Observation:"})})
+		got := model.GetCommonOptions(nil, opts...).Stop
+		want := []string{"Observation:", "</answer>", "###", "END"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("Stop = %v, 期望 %v", got, want)
+		}
+	})
+}
+
+// TestChatModelAdapter_InvalidToolArgs 测试工具调用参数不是合法 JSON 时重新生成
+func TestChatModelAdapter_InvalidToolArgs(t *testing.T) {
+	toolCall := func(args string) *schema.Message {
+		return schema.AssistantMessage("", []schema.ToolCall{{ID: "call_1", Function: schema.FunctionCall{Name: "read_file", Arguments: args}}})
+	}
+	newAdapter := func(retries int, outputs ...*schema.Message) (*ChatModelAdapter, *countingChatModel) {
+		llm := &countingChatModel{}
+		llm.response = func() *schema.Message {
+			return outputs[min(llm.calls, len(outputs))-1]
+		}
+		return &ChatModelAdapter{logger: zap.NewNop(), chatModel: llm, registeredMap: map[string]bool{"read_file": true}, argsRetries: retries}, llm
+	}
+	input := []*schema.Message{schema.UserMessage("读取 a.txt")}
+
+	t.Run("参数残缺时重新生成", func(t *testing.T) {
+		adapter, llm := newAdapter(1, toolCall(`{"path": "a.t`), toolCall(`{"path": "a.txt"}`))
+		got, err := adapter.Generate(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Generate() 返回错误: %v", err)
+		}
+		if llm.calls != 2 || got.ToolCalls[0].Function.Arguments != `{"path": "a.txt"}` {
+			t.Errorf("调用 %d 次, 参数 = %s", llm.calls, got.ToolCalls[0].Function.Arguments)
+		}
+	})
+
+	t.Run("超过重试次数保留原始参数", func(t *testing.T) {
+		adapter, llm := newAdapter(1, toolCall(`{"path":`))
+		got, err := adapter.Generate(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Generate() 返回错误: %v", err)
+		}
+		if llm.calls != 2 || got.ToolCalls[0].Function.Arguments != `{"path":` {
+			t.Errorf("调用 %d 次, 参数 = %s, 期望调用 2 次并交给工具节点反馈错误", llm.calls, got.ToolCalls[0].Function.Arguments)
+		}
+	})
+
+	t.Run("格式问题直接修复不重新生成", func(t *testing.T) {
+		adapter, llm := newAdapter(1, toolCall(`{'path': 'a.txt',}`))
+		got, err := adapter.Generate(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Generate() 返回错误: %v", err)
+		}
+		if llm.calls != 1 || got.ToolCalls[0].Function.Arguments != `{"path": "a.txt"}` {
+			t.Errorf("调用 %d 次, 参数 = %s", llm.calls, got.ToolCalls[0].Function.Arguments)
+		}
+	})
+
+	t.Run("空参数视为无参数调用", func(t *testing.T) {
+		adapter, _ := newAdapter(0, toolCall(" "))
+		got, err := adapter.Generate(context.Background(), input)
+		if err != nil || got.ToolCalls[0].Function.Arguments != "{}" {
+			t.Errorf("Generate() = %v, %v, 期望参数为 {}", got, err)
+		}
+	})
+}
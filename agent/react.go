@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// ReAct 文本协议的关键字
+const (
+	reactThought     = "Thought:"
+	reactAction      = "Action:"
+	reactActionInput = "Action Input:"
+	reactObservation = "Observation:"
+	reactFinalAnswer = "Final Answer:"
+)
+
+// reactPromptTemplate 引导不支持原生工具调用的模型按 ReAct 文本协议使用工具
+const reactPromptTemplate = `你可以使用以下工具：
+
+%s
+使用工具时严格按以下格式输出，每次只调用一个工具，输出 Action Input 后立即停止，等待工具结果：
+
+Thought: 思考下一步要做什么
+Action: 工具名称（必须是上面列出的工具之一）
+Action Input: 工具参数，必须是单行 JSON 对象
+
+工具结果会以 "Observation: ..." 的形式返回给你。可以多次重复以上步骤。
+不需要再使用工具时，按以下格式给出最终回复：
+
+Thought: 我已经可以回答了
+Final Answer: 给用户的最终回复`
+
+// reactStep 模型单次输出解析出的 ReAct 步骤
+type reactStep struct {
+	Thought     string
+	Action      string
+	ActionInput string // JSON 对象
+	FinalAnswer string
+}
+
+// isFinal 返回该步骤是否是最终回复
+func (s *reactStep) isFinal() bool {
+	return s.Action == ""
+}
+
+// parseReActOutput 解析模型按 ReAct 协议输出的文本
+// 既没有 Action 也没有 Final Answer 时，把整段文本视为最终回复（模型直接作答）
+func parseReActOutput(text string) (*reactStep, error) {
+	text = strings.TrimSpace(text)
+	// 模型可能自行编造工具结果，从第一个 Observation 起全部丢弃
+	if idx := strings.Index(text, reactObservation); idx >= 0 {
+		text = strings.TrimSpace(text[:idx])
+	}
+
+	step := &reactStep{}
+	actionIdx := strings.Index(text, reactAction)
+	finalIdx := strings.Index(text, reactFinalAnswer)
+	if thoughtIdx := strings.Index(text, reactThought); thoughtIdx >= 0 {
+		step.Thought = strings.TrimSpace(sectionAfter(text, thoughtIdx+len(reactThought), actionIdx, finalIdx))
+	}
+
+	switch {
+	case actionIdx >= 0 && finalIdx >= 0:
+		return nil, fmt.Errorf("同时输出了 Action 和 Final Answer，每次只能二选一")
+	case finalIdx >= 0:
+		step.FinalAnswer = strings.TrimSpace(text[finalIdx+len(reactFinalAnswer):])
+		if step.FinalAnswer == "" {
+			return nil, fmt.Errorf("Final Answer 内容为空")
+		}
+		return step, nil
+	case actionIdx < 0:
+		if text == "" {
+			return nil, fmt.Errorf("输出为空")
+		}
+		step.FinalAnswer = strings.TrimSpace(strings.Replace(text, reactThought, "", 1))
+		return step, nil
+	}
+
+	rest := text[actionIdx+len(reactAction):]
+	inputIdx := strings.Index(rest, reactActionInput)
+	if inputIdx < 0 {
+		return nil, fmt.Errorf("缺少 Action Input")
+	}
+	step.Action = strings.Trim(strings.TrimSpace(rest[:inputIdx]), "`\"'")
+	if step.Action == "" {
+		return nil, fmt.Errorf("Action 缺少工具名称")
+	}
+
+	input := strings.TrimSpace(rest[inputIdx+len(reactActionInput):])
+	input = strings.TrimPrefix(input, "```json")
+	input = strings.Trim(strings.TrimSpace(input), "`")
+	input = strings.TrimSpace(input)
+	if input == "" {
+		input = "{}"
+	}
+	var args map[string]any
+	if err := json.Unmarshal([]byte(input), &args); err != nil {
+		return nil, fmt.Errorf("Action Input 不是合法的 JSON 对象: %v", err)
+	}
+	step.ActionInput = input
+	return step, nil
+}
+
+// sectionAfter 返回从 start 开始、到之后最近的一个结束位置为止的文本
+func sectionAfter(text string, start int, ends ...int) string {
+	end := len(text)
+	for _, e := range ends {
+		if e >= start && e < end {
+			end = e
+		}
+	}
+	return text[start:end]
+}
+
+// reactAgent 面向不支持原生工具调用模型的 ReAct 文本协议代理
+// 模型以 Action/Action Input 文本描述工具调用，代理执行工具后以 Observation 反馈结果
+type reactAgent struct {
+	model         *ChatModelAdapter
+	tools         []tool.BaseTool
+	maxIterations int
+	logger        *zap.Logger
+}
+
+// newReActAgent 创建 ReAct 文本协议代理
+func newReActAgent(llm *ChatModelAdapter, tools []tool.BaseTool, maxIterations int, logger *zap.Logger) *reactAgent {
+	return &reactAgent{
+		model:         llm,
+		tools:         tools,
+		maxIterations: maxIterations,
+		logger:        logger,
+	}
+}
+
+// active 返回当前是否应使用 ReAct 协议（模型不支持原生工具调用）
+func (r *reactAgent) active() bool {
+	return r != nil && len(r.tools) > 0 && !r.model.supportsToolCalling()
+}
+
+// Run 按 ReAct 协议执行一轮对话，返回最终回复和过程中产生的消息
+// 超过最大迭代次数时返回 adk.ErrExceedMaxIterations
+func (r *reactAgent) Run(ctx context.Context, messages []*schema.Message) (string, []*schema.Message, error) {
+	invokable, prompt, err := r.describeTools(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	input := append(flattenToolMessages(messages), schema.SystemMessage(prompt))
+	var progress []*schema.Message
+	for iteration := 0; iteration < r.maxIterations; iteration++ {
+		resp, err := r.model.Generate(ctx, append(input, progress...), model.WithStop([]string{reactObservation}))
+		if err != nil {
+			return "", progress, err
+		}
+		progress = append(progress, schema.AssistantMessage(resp.Content, nil))
+
+		step, err := parseReActOutput(resp.Content)
+		if err != nil {
+			r.logger.Debug("ReAct 输出格式错误", zap.Error(err), zap.String("content", resp.Content))
+			progress = append(progress, schema.UserMessage(fmt.Sprintf("%s 格式错误: %v。请严格按约定格式输出 Action/Action Input 或 Final Answer。", reactObservation, err)))
+			continue
+		}
+		if step.isFinal() {
+			return step.FinalAnswer, progress, nil
+		}
+
+		observation := r.runTool(ctx, invokable, step)
+		progress = append(progress, schema.UserMessage(reactObservation+" "+observation))
+	}
+	return "", progress, adk.ErrExceedMaxIterations
+}
+
+// runTool 执行模型选择的工具，错误信息作为观察结果返回给模型
+func (r *reactAgent) runTool(ctx context.Context, invokable map[string]tool.InvokableTool, step *reactStep) string {
+	t, ok := invokable[step.Action]
+	if !ok {
+		names := make([]string, 0, len(invokable))
+		for name := range invokable {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Sprintf("错误: 工具 %s 不存在，可用工具: %s", step.Action, strings.Join(names, ", "))
+	}
+
+	r.logger.Info("ReAct 调用工具", zap.String("tool", step.Action), zap.String("arguments", step.ActionInput))
+	markToolExecuted(ctx)
+	result, err := t.InvokableRun(ctx, step.ActionInput)
+	if err != nil {
+		return fmt.Sprintf("错误: %v", err)
+	}
+	if result == "" {
+		return "(无输出)"
+	}
+	return result
+}
+
+// describeTools 收集可直接调用的工具，并生成介绍工具与协议的系统提示
+func (r *reactAgent) describeTools(ctx context.Context) (map[string]tool.InvokableTool, string, error) {
+	invokable := make(map[string]tool.InvokableTool, len(r.tools))
+	var sb strings.Builder
+	for _, t := range r.tools {
+		it, ok := t.(tool.InvokableTool)
+		if !ok {
+			continue
+		}
+		info, err := t.Info(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("获取工具信息失败: %w", err)
+		}
+		invokable[info.Name] = it
+
+		fmt.Fprintf(&sb, "- %s: %s\n", info.Name, info.Desc)
+		if info.ParamsOneOf != nil {
+			if params, err := info.ParamsOneOf.ToJSONSchema(); err == nil && params != nil {
+				if data, err := json.Marshal(params); err == nil {
+					fmt.Fprintf(&sb, "  参数: %s\n", data)
+				}
+			}
+		}
+	}
+	if len(invokable) == 0 {
+		return nil, "", errors.New("没有可用的工具")
+	}
+	return invokable, fmt.Sprintf(reactPromptTemplate, sb.String()), nil
+}
@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// echoTool 返回参数原文的模拟工具
+type echoTool struct {
+	calls []string
+}
+
+func (t *echoTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: "echo",
+		Desc: "原样返回参数",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"text": {Type: schema.String, Desc: "文本", Required: true},
+		}),
+	}, nil
+}
+
+func (t *echoTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	t.calls = append(t.calls, argumentsInJSON)
+	return "echo: " + argumentsInJSON, nil
+}
+
+// TestParseReActOutput 测试解析 ReAct 文本协议输出
+func TestParseReActOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    reactStep
+		wantErr string
+	}{
+		{
+			name: "工具调用",
+			text: "Thought: 需要查一下\nAction: echo\nAction Input: {\"text\": \"你好\"}",
+			want: reactStep{Thought: "需要查一下", Action: "echo", ActionInput: `{"text": "你好"}`},
+		},
+		{
+			name: "参数包在代码块中",
+			text: "Action: `echo`\nAction Input: ```json\n{\"text\": \"a\"}\n```",
+			want: reactStep{Action: "echo", ActionInput: `{"text": "a"}`},
+		},
+		{
+			name: "丢弃模型编造的 Observation",
+			text: "Action: echo\nAction Input: {}\nObservation: 编造的结果\nFinal Answer: 完成",
+			want: reactStep{Action: "echo", ActionInput: "{}"},
+		},
+		{
+			name: "缺少参数视为空对象",
+			text: "Action: echo\nAction Input:",
+			want: reactStep{Action: "echo", ActionInput: "{}"},
+		},
+		{
+			name: "最终回复",
+			text: "Thought: 可以回答了\nFinal Answer: 今天是晴天。\n适合出门。",
+			want: reactStep{Thought: "可以回答了", FinalAnswer: "今天是晴天。\n适合出门。"},
+		},
+		{
+			name: "直接作答",
+			text: "你好，有什么可以帮你？",
+			want: reactStep{FinalAnswer: "你好，有什么可以帮你？"},
+		},
+		{name: "缺少 Action Input", text: "Action: echo", wantErr: "缺少 Action Input"},
+		{name: "缺少工具名称", text: "Action:\nAction Input: {}", wantErr: "缺少工具名称"},
+		{name: "参数不是 JSON", text: "Action: echo\nAction Input: text=你好", wantErr: "不是合法的 JSON 对象"},
+		{name: "参数是数组", text: "Action: echo\nAction Input: [1, 2]", wantErr: "不是合法的 JSON 对象"},
+		{name: "同时输出两者", text: "Final Answer: 好的\nAction: echo\nAction Input: {}", wantErr: "二选一"},
+		{name: "最终回复为空", text: "Final Answer:  ", wantErr: "内容为空"},
+		{name: "输出为空", text: "  ", wantErr: "输出为空"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReActOutput(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseReActOutput() error = %v, 期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseReActOutput() 返回错误: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("parseReActOutput() = %+v, 期望 %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReActAgent_Run 测试按 ReAct 协议调用工具并给出最终回复
+func TestReActAgent_Run(t *testing.T) {
+	newAgent := func(outputs ...string) (*reactAgent, *countingChatModel, *echoTool) {
+		llm := &countingChatModel{}
+		llm.response = func() *schema.Message {
+			return schema.AssistantMessage(outputs[min(llm.calls, len(outputs))-1], nil)
+		}
+		echo := &echoTool{}
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), chatModel: llm, registeredMap: map[string]bool{}, noToolCalling: true}
+		return newReActAgent(adapter, []tool.BaseTool{echo}, 3, zap.NewNop()), llm, echo
+	}
+	messages := []*schema.Message{schema.SystemMessage("你是助手"), schema.UserMessage("回显 你好")}
+
+	t.Run("调用工具后回复", func(t *testing.T) {
+		agent, llm, echo := newAgent(
+			"Thought: 调用 echo\nAction: echo\nAction Input: {\"text\":\"你好\"}",
+			"Final Answer: 工具返回了你好",
+		)
+		if !agent.active() {
+			t.Fatal("模型不支持工具调用时应启用 ReAct")
+		}
+
+		answer, progress, err := agent.Run(context.Background(), messages)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if answer != "工具返回了你好" {
+			t.Errorf("Run() = %q", answer)
+		}
+		if len(echo.calls) != 1 || echo.calls[0] != `{"text":"你好"}` {
+			t.Errorf("工具调用 = %v", echo.calls)
+		}
+		if len(progress) != 3 || progress[1].Content != `Observation: echo: {"text":"你好"}` {
+			t.Errorf("过程消息 = %v", progress)
+		}
+		if !strings.Contains(llm.input[0].Content, "- echo: 原样返回参数") || !strings.Contains(llm.input[0].Content, "Action Input:") {
+			t.Errorf("系统提示缺少工具说明: %s", llm.input[0].Content)
+		}
+	})
+
+	t.Run("格式错误与未知工具反馈给模型", func(t *testing.T) {
+		agent, llm, _ := newAgent(
+			"Action: echo\nAction Input: 不是 JSON",
+			"Action: search\nAction Input: {}",
+			"Final Answer: 好的",
+		)
+		answer, progress, err := agent.Run(context.Background(), messages)
+		if err != nil || answer != "好的" {
+			t.Fatalf("Run() = %q, %v", answer, err)
+		}
+		if !strings.Contains(progress[1].Content, "格式错误") || !strings.Contains(progress[3].Content, "工具 search 不存在，可用工具: echo") {
+			t.Errorf("过程消息 = %v", progress)
+		}
+		if llm.calls != 3 {
+			t.Errorf("模型调用 %d 次, 期望 3", llm.calls)
+		}
+	})
+
+	t.Run("超过最大迭代次数", func(t *testing.T) {
+		agent, _, echo := newAgent("Action: echo\nAction Input: {}")
+		_, _, err := agent.Run(context.Background(), messages)
+		if !errors.Is(err, adk.ErrExceedMaxIterations) {
+			t.Errorf("Run() error = %v, 期望 ErrExceedMaxIterations", err)
+		}
+		if len(echo.calls) != 3 {
+			t.Errorf("工具调用 %d 次, 期望 3", len(echo.calls))
+		}
+	})
+
+	t.Run("支持工具调用时不启用", func(t *testing.T) {
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), modelName: "gpt-4o"}
+		if newReActAgent(adapter, []tool.BaseTool{&echoTool{}}, 3, zap.NewNop()).active() {
+			t.Error("支持工具调用的模型不应启用 ReAct")
+		}
+		var nilAgent *reactAgent
+		if nilAgent.active() {
+			t.Error("未配置时不应启用 ReAct")
+		}
+	})
+}
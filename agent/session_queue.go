@@ -0,0 +1,35 @@
+package agent
+
+import "sync"
+
+// sessionQueue 会话回合队列
+// 并发处理入站消息时，保证同一会话的回合按登记顺序逐个执行，不同会话互不阻塞
+type sessionQueue struct {
+	mu    sync.Mutex
+	tails map[string]chan struct{} // 每个会话最后登记的回合，回合结束时关闭
+}
+
+// enqueue 为会话登记一个回合
+// 返回前一个回合的结束信号（没有进行中的回合时为 nil），以及本回合结束时必须调用的 done
+func (q *sessionQueue) enqueue(key string) (wait <-chan struct{}, done func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.tails == nil {
+		q.tails = make(map[string]chan struct{})
+	}
+
+	prev, ok := q.tails[key]
+	current := make(chan struct{})
+	q.tails[key] = current
+	if ok {
+		wait = prev
+	}
+	return wait, func() {
+		q.mu.Lock()
+		if q.tails[key] == current {
+			delete(q.tails, key)
+		}
+		q.mu.Unlock()
+		close(current)
+	}
+}
@@ -2,9 +2,10 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,7 +19,6 @@ import (
 	"github.com/weibaohui/nanobot-go/config"
 	"github.com/weibaohui/nanobot-go/session"
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
 
 	hooks "github.com/weibaohui/nanobot-go/agent/hooks"
 )
@@ -27,16 +27,23 @@ type TaskStatus string
 
 const (
 	TaskPending  TaskStatus = "pending"
+	TaskQueued   TaskStatus = "queued"
 	TaskRunning  TaskStatus = "running"
 	TaskFinished TaskStatus = "finished"
 	TaskFailed   TaskStatus = "failed"
 	TaskStopped  TaskStatus = "stopped"
+	// TaskWaitingInput 任务执行中需要用户补充信息，收到回复后从检查点继续执行
+	TaskWaitingInput TaskStatus = "waiting_input"
 )
 
 type TaskInfo struct {
 	ID            string
 	Status        TaskStatus
 	ResultSummary string
+	QueuePosition int // 排队位置（从1开始），仅排队中的任务有效
+	CreatedAt     time.Time
+	Channel       string // 发起任务的渠道
+	ChatID        string // 发起任务的聊天ID
 }
 
 type AgentTaskManagerConfig struct {
@@ -49,9 +56,11 @@ type AgentTaskManagerConfig struct {
 	MaxIterations         int
 	RegisteredTools       []string
 	MaxConcurrentTasks    int
+	MaxQueuedTasks        int // 并发已满时允许排队的最大任务数，0 表示不排队
 	TaskTimeoutSeconds    int
 	TaskLogCapacity       int
 	TaskMaxToolIterations int
+	Store                 string           // 任务持久化存储类型: yaml（默认）或 sqlite
 	Sessions              *session.Manager // 会话管理器
 	// OnTaskComplete 任务完成回调，用于发送完成通知
 	OnTaskComplete func(channel, chatID, taskID string, status TaskStatus, result string)
@@ -71,6 +80,7 @@ type AgentTaskManager struct {
 	sessions        *session.Manager // 会话管理器，用于记录 token 用量
 
 	maxConcurrent int
+	maxQueued     int
 	taskTimeout   time.Duration
 	logCapacity   int
 
@@ -83,16 +93,18 @@ type AgentTaskManager struct {
 	// taskCounter 任务ID计数器（0-999999循环）
 	taskCounter uint32
 
-	// tasksDir 任务存储目录
-	tasksDir string
+	// store 任务持久化存储
+	store TaskStore
 
 	// mu 保护内存中的任务
 	mu sync.RWMutex
-	// runningTasks 内存中的运行中/待处理任务（必须保留在内存中以便管理）
+	// runningTasks 内存中的运行中/待处理/排队任务（必须保留在内存中以便管理）
 	runningTasks map[string]*AgentTask
+	// queue 等待并发槽位的任务，按提交顺序排列
+	queue []*AgentTask
 
-	// persistMu 保护文件写入
-	persistMu sync.Mutex
+	// execute 执行任务，测试中可替换；answer 非空时从等待输入的检查点恢复
+	execute func(ctx context.Context, task *AgentTask, answer string) (string, error)
 }
 
 type AgentTask struct {
@@ -109,20 +121,47 @@ type AgentTask struct {
 	// 任务上下文，用于完成回调
 	channel string
 	chatID  string
+	// delivery 任务结果的投递目标，为空时投递到发起渠道
+	delivery TaskDelivery
 	// 创建时间
 	createdAt time.Time
+	// startCtx 排队任务启动时使用的上下文
+	startCtx context.Context
+	// pendingInput 等待用户回复的中断信息，仅 waiting_input 状态有效
+	pendingInput *taskInputRequest
+}
+
+// taskInputRequest 后台任务执行中需要用户补充的信息
+type taskInputRequest struct {
+	checkpointID string
+	interruptID  string
+	question     string
+	options      []string
+	isAskUser    bool
+}
+
+// taskNeedsInputError 任务被中断、需要用户回复后才能继续
+type taskNeedsInputError struct {
+	request *taskInputRequest
+}
+
+func (e *taskNeedsInputError) Error() string {
+	return "任务需要用户输入: " + e.request.question
 }
 
 // PersistedTask 持久化的任务结构（用于YAML存储）
 type PersistedTask struct {
-	ID          string     `yaml:"id"`
-	Work        string     `yaml:"work,omitempty"`
-	Status      TaskStatus `yaml:"status"`
-	Result      string     `yaml:"result,omitempty"`
-	Channel     string     `yaml:"channel,omitempty"`
-	ChatID      string     `yaml:"chat_id,omitempty"`
-	CreatedAt   time.Time  `yaml:"created_at"`
-	CompletedAt time.Time  `yaml:"completed_at,omitempty"`
+	ID             string     `yaml:"id"`
+	Work           string     `yaml:"work,omitempty"`
+	Status         TaskStatus `yaml:"status"`
+	Result         string     `yaml:"result,omitempty"`
+	Channel        string     `yaml:"channel,omitempty"`
+	ChatID         string     `yaml:"chat_id,omitempty"`
+	DeliverChannel string     `yaml:"deliver_channel,omitempty"` // 任务结果的投递渠道，为空时投递到发起渠道
+	DeliverChatID  string     `yaml:"deliver_chat_id,omitempty"` // 任务结果的投递聊天ID
+	CreatedAt      time.Time  `yaml:"created_at"`
+	CompletedAt    time.Time  `yaml:"completed_at,omitempty"`
+	Logs           []string   `yaml:"logs,omitempty"` // 任务结束时的最近日志
 }
 
 // TaskFile YAML文件结构
@@ -148,6 +187,10 @@ func NewBackgroundAgentTaskManager(cfg *AgentTaskManagerConfig) (*AgentTaskManag
 	if maxConcurrent <= 0 {
 		maxConcurrent = 3
 	}
+	maxQueued := cfg.MaxQueuedTasks
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
 	logCapacity := cfg.TaskLogCapacity
 	if logCapacity <= 0 {
 		logCapacity = 10
@@ -158,7 +201,10 @@ func NewBackgroundAgentTaskManager(cfg *AgentTaskManagerConfig) (*AgentTaskManag
 	}
 
 	// 任务存储目录
-	tasksDir := filepath.Join(cfg.Workspace, "tasks")
+	store, err := newTaskStore(cfg.Store, filepath.Join(cfg.Workspace, "tasks"), logger)
+	if err != nil {
+		return nil, err
+	}
 
 	m := &AgentTaskManager{
 		cfg:             cfg.Cfg,
@@ -171,17 +217,22 @@ func NewBackgroundAgentTaskManager(cfg *AgentTaskManagerConfig) (*AgentTaskManag
 		registeredTools: cfg.RegisteredTools,
 		sessions:        cfg.Sessions,
 		maxConcurrent:   maxConcurrent,
+		maxQueued:       maxQueued,
 		taskTimeout:     timeout,
 		logCapacity:     logCapacity,
 		onTaskComplete:  cfg.OnTaskComplete,
-		tasksDir:        tasksDir,
+		store:           store,
 		runningTasks:    make(map[string]*AgentTask),
 		hookManager:     cfg.HookManager,
 	}
+	m.execute = m.executeTask
 
 	// 加载计数器状态
 	m.loadCounter()
 
+	// 清理上次运行遗留的未完成任务
+	m.recoverInterruptedTasks()
+
 	return m, nil
 }
 
@@ -190,12 +241,37 @@ func (m *AgentTaskManager) SetRegisteredTools(names []string) {
 	m.registeredTools = append([]string(nil), names...)
 }
 
+// TaskDelivery 任务结果的投递目标
+type TaskDelivery struct {
+	Channel string
+	ChatID  string
+}
+
 func (m *AgentTaskManager) StartTask(ctx context.Context, work, channel, chatID string) (string, TaskStatus, error) {
+	return m.StartTaskWithDelivery(ctx, work, channel, chatID, TaskDelivery{})
+}
+
+// StartTaskWithDelivery 启动任务，并将任务结果投递到指定目标而不是发起渠道
+// 目标渠道为空时沿用发起渠道，目标渠道必须已启用
+func (m *AgentTaskManager) StartTaskWithDelivery(ctx context.Context, work, channel, chatID string, delivery TaskDelivery) (string, TaskStatus, error) {
 	if work == "" {
 		return "", "", fmt.Errorf("任务内容不能为空")
 	}
-	if m.reachedLimit() {
-		return "", "", fmt.Errorf("任务并发已达上限")
+	delivery, err := m.resolveDelivery(channel, delivery)
+	if err != nil {
+		return "", "", err
+	}
+	// 后台任务的生命周期独立于发起它的回合，回合结束时取消的上下文不能传给任务
+	ctx = context.WithoutCancel(ctx)
+
+	m.mu.Lock()
+	queued := m.runningCountLocked() >= m.maxConcurrent
+	if queued && len(m.queue) >= m.maxQueued {
+		m.mu.Unlock()
+		if m.maxQueued == 0 {
+			return "", "", fmt.Errorf("任务并发已达上限")
+		}
+		return "", "", fmt.Errorf("任务并发已达上限，排队任务已满（%d）", m.maxQueued)
 	}
 
 	// 生成6位数字任务ID（000000-999999循环）
@@ -208,19 +284,97 @@ func (m *AgentTaskManager) StartTask(ctx context.Context, work, channel, chatID
 		done:        make(chan struct{}),
 		channel:     channel,
 		chatID:      chatID,
+		delivery:    delivery,
 		createdAt:   time.Now(),
 	}
 	task.appendLog("任务已创建")
-
-	m.mu.Lock()
+	if queued {
+		// 并发已满，进入排队队列，等待运行中的任务结束后再启动
+		task.status = TaskQueued
+		task.startCtx = ctx
+		task.appendLog("任务排队等待中")
+		m.queue = append(m.queue, task)
+	}
 	m.runningTasks[taskID] = task
 	m.mu.Unlock()
 
-	go m.runTask(ctx, task, channel, chatID)
+	// 先持久化待处理状态，服务重启后可据此识别被中断的任务
+	m.persistTask(task)
+
+	if queued {
+		return taskID, TaskQueued, nil
+	}
+
+	go m.runTask(ctx, task, "")
 
 	return taskID, TaskRunning, nil
 }
 
+// resolveDelivery 校验并补全投递目标
+func (m *AgentTaskManager) resolveDelivery(channel string, delivery TaskDelivery) (TaskDelivery, error) {
+	if delivery.Channel == "" && delivery.ChatID == "" {
+		return delivery, nil
+	}
+	if delivery.ChatID == "" {
+		return delivery, fmt.Errorf("投递目标缺少聊天ID")
+	}
+	if delivery.Channel == "" {
+		delivery.Channel = channel
+	}
+	if delivery.Channel != channel && (m.cfg == nil || !m.cfg.Channels.IsEnabled(delivery.Channel)) {
+		return delivery, fmt.Errorf("投递渠道 %s 未启用", delivery.Channel)
+	}
+	return delivery, nil
+}
+
+// startQueuedTasks 在有空闲并发槽位时启动排队中的任务
+func (m *AgentTaskManager) startQueuedTasks() {
+	m.mu.Lock()
+	var toStart []*AgentTask
+	for len(m.queue) > 0 && m.runningCountLocked()+len(toStart) < m.maxConcurrent {
+		task := m.queue[0]
+		m.queue = m.queue[1:]
+		task.mu.Lock()
+		queued := task.status == TaskQueued
+		if queued {
+			task.status = TaskPending
+		}
+		task.mu.Unlock()
+		if queued {
+			toStart = append(toStart, task)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, task := range toStart {
+		go m.runTask(task.startCtx, task, "")
+	}
+}
+
+// removeFromQueue 从排队队列中移除任务
+func (m *AgentTaskManager) removeFromQueue(taskID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, task := range m.queue {
+		if task.id == taskID {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// queuePosition 返回任务在排队队列中的位置（从1开始），不在队列中返回0
+func (m *AgentTaskManager) queuePosition(taskID string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i, task := range m.queue {
+		if task.id == taskID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
 // generateTaskID 生成6位数字任务ID
 func (m *AgentTaskManager) generateTaskID() string {
 	// 原子递增，取模1000000实现循环
@@ -247,12 +401,16 @@ func (m *AgentTaskManager) GetTask(taskID string) (*TaskInfo, error) {
 	m.mu.RUnlock()
 
 	if ok {
+		position := m.queuePosition(task.id)
 		task.mu.Lock()
 		defer task.mu.Unlock()
 		return &TaskInfo{
 			ID:            task.id,
 			Status:        task.status,
 			ResultSummary: task.result,
+			QueuePosition: position,
+			Channel:       task.channel,
+			ChatID:        task.chatID,
 		}, nil
 	}
 
@@ -260,6 +418,47 @@ func (m *AgentTaskManager) GetTask(taskID string) (*TaskInfo, error) {
 	return m.loadTaskFromFile(normalizedID)
 }
 
+// IsTaskOrigin 判断任务是否由指定渠道和聊天发起
+// 任务不存在时 found 为 false，由调用方按原有流程报告错误
+func (m *AgentTaskManager) IsTaskOrigin(taskID, channel, chatID string) (owned, found bool) {
+	normalizedID := normalizeTaskID(taskID)
+
+	m.mu.RLock()
+	task, ok := m.runningTasks[normalizedID]
+	m.mu.RUnlock()
+	if ok {
+		return task.channel == channel && task.chatID == chatID, true
+	}
+
+	pt, err := m.findPersistedTask(normalizedID)
+	if err != nil {
+		return false, false
+	}
+	return pt.Channel == channel && pt.ChatID == chatID, true
+}
+
+// GetTaskLogs 获取任务最近日志
+// 运行中的任务返回内存中的日志，已结束的任务返回持久化的日志
+func (m *AgentTaskManager) GetTaskLogs(taskID string) ([]string, error) {
+	normalizedID := normalizeTaskID(taskID)
+
+	m.mu.RLock()
+	task, ok := m.runningTasks[normalizedID]
+	m.mu.RUnlock()
+
+	if ok {
+		task.mu.Lock()
+		defer task.mu.Unlock()
+		return append([]string(nil), task.lastLogs...), nil
+	}
+
+	pt, err := m.findPersistedTask(normalizedID)
+	if err != nil {
+		return nil, err
+	}
+	return pt.Logs, nil
+}
+
 func (m *AgentTaskManager) StopTask(taskID string) (bool, TaskStatus, error) {
 	normalizedID := normalizeTaskID(taskID)
 
@@ -268,10 +467,38 @@ func (m *AgentTaskManager) StopTask(taskID string) (bool, TaskStatus, error) {
 	m.mu.RUnlock()
 
 	if !ok {
+		// 不在内存中的任务已经结束（含重启前被中断的任务），返回其最终状态
+		if pt, err := m.findPersistedTask(normalizedID); err == nil {
+			return false, pt.Status, nil
+		}
 		return false, "", fmt.Errorf("任务不存在或已完成")
 	}
 
 	task.mu.Lock()
+	if task.status == TaskQueued {
+		// 排队中的任务尚未启动执行协程，需要在此完成收尾
+		task.status = TaskStopped
+		task.appendLog("任务已停止")
+		close(task.done)
+		task.mu.Unlock()
+		m.removeFromQueue(task.id)
+		m.persistTask(task)
+		m.notifyComplete(task, "")
+		m.removeFromRunning(task.id)
+		return true, TaskStopped, nil
+	}
+	if task.status == TaskWaitingInput {
+		// 等待输入的任务没有执行协程，需要在此完成收尾
+		task.status = TaskStopped
+		task.pendingInput = nil
+		task.appendLog("任务已停止")
+		close(task.done)
+		task.mu.Unlock()
+		m.persistTask(task)
+		m.notifyComplete(task, "")
+		m.removeFromRunning(task.id)
+		return true, TaskStopped, nil
+	}
 	defer task.mu.Unlock()
 	switch task.status {
 	case TaskFinished, TaskFailed, TaskStopped:
@@ -293,12 +520,19 @@ func (m *AgentTaskManager) ListTasks() ([]*TaskInfo, error) {
 
 	// 运行中的任务
 	m.mu.RLock()
+	positions := make(map[string]int, len(m.queue))
+	for i, task := range m.queue {
+		positions[task.id] = i + 1
+	}
 	for _, task := range m.runningTasks {
 		task.mu.Lock()
 		info := &TaskInfo{
 			ID:            task.id,
 			Status:        task.status,
 			ResultSummary: task.result,
+			QueuePosition: positions[task.id],
+			Channel:       task.channel,
+			ChatID:        task.chatID,
 		}
 		task.mu.Unlock()
 		results = append(results, info)
@@ -316,27 +550,113 @@ func (m *AgentTaskManager) ListTasks() ([]*TaskInfo, error) {
 	return results, nil
 }
 
-func (m *AgentTaskManager) runTask(ctx context.Context, task *AgentTask, channel, chatID string) {
+// ListTasksFiltered 按创建日期范围和状态筛选任务，包含内存中未结束的任务和持久化的历史任务
+// from/to 只取日期部分且包含两端，status 为空时不按状态筛选，结果按创建时间排序
+func (m *AgentTaskManager) ListTasksFiltered(from, to time.Time, status TaskStatus) ([]*TaskInfo, error) {
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	if fromDate > toDate {
+		return nil, fmt.Errorf("开始日期 %s 晚于结束日期 %s", fromDate, toDate)
+	}
+	matches := func(s TaskStatus, createdAt time.Time) bool {
+		date := createdAt.Format("2006-01-02")
+		return (status == "" || s == status) && date >= fromDate && date <= toDate
+	}
+
+	results := make([]*TaskInfo, 0)
+	m.mu.RLock()
+	positions := make(map[string]int, len(m.queue))
+	for i, task := range m.queue {
+		positions[task.id] = i + 1
+	}
+	for _, task := range m.runningTasks {
+		task.mu.Lock()
+		if matches(task.status, task.createdAt) {
+			results = append(results, &TaskInfo{
+				ID:            task.id,
+				Status:        task.status,
+				ResultSummary: task.result,
+				QueuePosition: positions[task.id],
+				CreatedAt:     task.createdAt,
+				Channel:       task.channel,
+				ChatID:        task.chatID,
+			})
+		}
+		task.mu.Unlock()
+	}
+	m.mu.RUnlock()
+
+	persisted, err := m.store.ListRange(fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, pt := range persisted {
+		// 未结束的任务以内存中的状态为准
+		if isActiveStatus(pt.Status) || !matches(pt.Status, pt.CreatedAt) {
+			continue
+		}
+		results = append(results, &TaskInfo{
+			ID:            pt.ID,
+			Status:        pt.Status,
+			ResultSummary: pt.Result,
+			CreatedAt:     pt.CreatedAt,
+			Channel:       pt.Channel,
+			ChatID:        pt.ChatID,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].CreatedAt.Before(results[j].CreatedAt)
+	})
+	return results, nil
+}
+
+// runTask 执行任务直到结束或需要用户输入，answer 非空时表示从等待输入的检查点恢复
+func (m *AgentTaskManager) runTask(ctx context.Context, task *AgentTask, answer string) {
 	execCtx, cancel := m.buildTaskContext(ctx)
 	task.mu.Lock()
+	if task.status == TaskStopped {
+		// 启动前已被停止：排队中停止时 StopTask 已完成收尾，其余情况（已出队或已提交回复）在此收尾
+		finalize := task.stopRequested
+		if finalize {
+			close(task.done)
+		}
+		task.mu.Unlock()
+		cancel()
+		if finalize {
+			m.persistTask(task)
+			m.notifyComplete(task, "")
+			m.removeFromRunning(task.id)
+		}
+		return
+	}
 	task.cancel = cancel
 	task.status = TaskRunning
-	task.appendLog("任务启动")
+	if answer == "" {
+		task.appendLog("任务启动")
+	}
 	task.mu.Unlock()
 
-	result, err := m.executeTask(execCtx, task.work, channel, chatID)
+	result, err := m.execute(execCtx, task, answer)
+	var needInput *taskNeedsInputError
 	task.mu.Lock()
-	defer task.mu.Unlock()
-	if task.stopRequested || execCtx.Err() == context.Canceled {
-		task.status = TaskStopped
-		task.appendLog("任务已停止")
-		close(task.done)
+	if !task.stopRequested && errors.As(err, &needInput) {
+		// 后台任务不能直接提问，改为通知发起者，收到回复后再从检查点继续
+		task.status = TaskWaitingInput
+		task.pendingInput = needInput.request
+		task.appendLog("任务等待用户输入: " + needInput.request.question)
+		task.mu.Unlock()
+		cancel()
+
 		m.persistTask(task)
-		m.notifyComplete(task, "")
-		m.removeFromRunning(task.id)
+		m.notifyComplete(task, formatTaskQuestion(needInput.request))
+		m.startQueuedTasks()
 		return
 	}
-	if err != nil {
+	if task.stopRequested || execCtx.Err() == context.Canceled {
+		task.status = TaskStopped
+		task.appendLog("任务已停止")
+		result = ""
+	} else if err != nil {
 		task.status = TaskFailed
 		task.appendLog(fmt.Sprintf("任务失败: %v", err))
 	} else {
@@ -345,49 +665,133 @@ func (m *AgentTaskManager) runTask(ctx context.Context, task *AgentTask, channel
 		task.appendLog("任务完成")
 	}
 	close(task.done)
+	task.mu.Unlock()
+
+	// 释放任务锁后再收尾：removeFromRunning 需要获取 m.mu，
+	// 而统计并发数时会在持有 m.mu 的情况下获取各任务锁，避免锁顺序相反导致死锁
 	m.persistTask(task)
 	m.notifyComplete(task, result)
 	m.removeFromRunning(task.id)
 }
 
-// removeFromRunning 从运行中任务列表移除
+// removeFromRunning 从运行中任务列表移除，并尝试启动排队中的任务
 func (m *AgentTaskManager) removeFromRunning(taskID string) {
 	m.mu.Lock()
 	delete(m.runningTasks, taskID)
 	m.mu.Unlock()
+	m.startQueuedTasks()
 }
 
 // persistTask 持久化任务到文件
 func (m *AgentTaskManager) persistTask(task *AgentTask) {
+	// 状态、结果和日志会被执行协程并发修改，在锁内取快照
+	task.mu.Lock()
+	pt := &PersistedTask{
+		ID:        task.id,
+		Work:      task.work,
+		Status:    task.status,
+		Result:    task.result,
+		Channel:   task.channel,
+		ChatID:    task.chatID,
+		CreatedAt: task.createdAt,
+		Logs:      append([]string(nil), task.lastLogs...),
+	}
+	task.mu.Unlock()
+	pt.DeliverChannel, pt.DeliverChatID = task.delivery.Channel, task.delivery.ChatID
+	if !isActiveStatus(pt.Status) {
+		pt.CompletedAt = time.Now()
+	}
+
 	m.logger.Info("persistTask 被调用",
-		zap.String("task_id", task.id),
-		zap.String("status", string(task.status)),
-		zap.Time("created_at", task.createdAt),
+		zap.String("task_id", pt.ID),
+		zap.String("status", string(pt.Status)),
+		zap.Time("created_at", pt.CreatedAt),
 	)
 
-	pt := &PersistedTask{
-		ID:          task.id,
-		Work:        task.work,
-		Status:      task.status,
-		Result:      task.result,
-		Channel:     task.channel,
-		ChatID:      task.chatID,
-		CreatedAt:   task.createdAt,
-		CompletedAt: time.Now(),
+	if err := m.store.Save(pt, atomic.LoadUint32(&m.taskCounter)); err != nil {
+		m.logger.Error("持久化任务失败", zap.Error(err), zap.String("task_id", pt.ID))
+		return
+	}
+	m.logger.Info("持久化任务成功", zap.String("task_id", pt.ID))
+}
+
+// isActiveStatus 判断任务是否处于未结束状态
+func isActiveStatus(status TaskStatus) bool {
+	return status == TaskPending || status == TaskRunning || status == TaskQueued || status == TaskWaitingInput
+}
+
+// recoverInterruptedTasks 将持久化存储中遗留的 pending/running 任务标记为失败
+// 这些任务的执行协程已随上次进程退出而终止，无法再被停止或完成，
+// 若不处理会一直以运行中状态残留在存储中
+func (m *AgentTaskManager) recoverInterruptedTasks() {
+	recovered, err := m.store.MarkInterrupted("任务因服务重启而中断")
+	if err != nil {
+		m.logger.Warn("清理未完成任务失败", zap.Error(err))
+	}
+	if recovered > 0 {
+		m.logger.Info("已将重启前未完成的任务标记为失败", zap.Int("count", recovered))
 	}
+}
+
+// ResumeTask 使用用户回复恢复等待输入的任务，任务从中断时的检查点继续执行
+func (m *AgentTaskManager) ResumeTask(ctx context.Context, taskID, answer string) (TaskStatus, error) {
+	if strings.TrimSpace(answer) == "" {
+		return "", fmt.Errorf("回复内容不能为空")
+	}
+	normalizedID := normalizeTaskID(taskID)
+
+	m.mu.Lock()
+	task, ok := m.runningTasks[normalizedID]
+	if !ok {
+		m.mu.Unlock()
+		return "", fmt.Errorf("任务不存在或已结束")
+	}
+	if m.runningCountLocked() >= m.maxConcurrent {
+		m.mu.Unlock()
+		return "", fmt.Errorf("任务并发已达上限，请稍后再试")
+	}
+	task.mu.Lock()
+	if task.status != TaskWaitingInput {
+		status := task.status
+		task.mu.Unlock()
+		m.mu.Unlock()
+		return status, fmt.Errorf("任务 %s 当前状态为 %s，不需要用户输入", normalizedID, status)
+	}
+	task.status = TaskPending
+	task.appendLog("收到用户回复，任务继续执行")
+	task.mu.Unlock()
+	m.mu.Unlock()
+
+	go m.runTask(context.WithoutCancel(ctx), task, answer)
+	return TaskRunning, nil
+}
 
-	m.appendTaskToFile(pt)
+// formatTaskQuestion 格式化任务向用户提出的问题
+func formatTaskQuestion(request *taskInputRequest) string {
+	if len(request.options) == 0 {
+		return request.question
+	}
+	return fmt.Sprintf("%s\n选项: %s", request.question, strings.Join(request.options, " / "))
 }
 
 // notifyComplete 通知任务完成
+// 任务结果发送到指定的投递目标；等待输入的提问仍发给发起者，便于其直接回复
 func (m *AgentTaskManager) notifyComplete(task *AgentTask, result string) {
-	if m.onTaskComplete != nil {
-		m.onTaskComplete(task.channel, task.chatID, task.id, task.status, result)
+	if m.onTaskComplete == nil {
+		return
+	}
+	channel, chatID := task.channel, task.chatID
+	if task.status != TaskWaitingInput && task.delivery.Channel != "" {
+		channel, chatID = task.delivery.Channel, task.delivery.ChatID
 	}
+	m.onTaskComplete(channel, chatID, task.id, task.status, result)
 }
 
-func (m *AgentTaskManager) executeTask(ctx context.Context, work, channel, chatID string) (string, error) {
-	adapter, err := NewChatModelAdapter(m.logger, m.cfg, m.sessions)
+// executeTask 执行任务，answer 非空时使用用户回复从等待输入的检查点恢复执行
+// 任务被中断时返回 taskNeedsInputError，由调用方通知用户并等待回复
+func (m *AgentTaskManager) executeTask(ctx context.Context, task *AgentTask, answer string) (string, error) {
+	channel, chatID := task.channel, task.chatID
+	adapter, err := NewRoleChatModelAdapter(m.logger, m.cfg, m.sessions, config.RoleTask)
 	if err != nil {
 		return "", err
 	}
@@ -406,7 +810,8 @@ func (m *AgentTaskManager) executeTask(ctx context.Context, work, channel, chatI
 	if len(m.tools) > 0 {
 		toolsConfig = adk.ToolsConfig{
 			ToolsNodeConfig: compose.ToolsNodeConfig{
-				Tools: m.tools,
+				Tools:               m.tools,
+				ToolCallMiddlewares: []compose.ToolMiddleware{invalidToolArgsMiddleware(), toolMetricsMiddleware()},
 			},
 		}
 	}
@@ -427,20 +832,39 @@ func (m *AgentTaskManager) executeTask(ctx context.Context, work, channel, chatI
 		CheckPointStore: m.checkpointStore,
 	})
 
-	// 使用轻量模式加载引导文件，只加载 AGENTS.md 和 TOOLS.md
-	// 不加载 SOUL.md、USER.md 等个性化配置，保持后台任务的独立性
-	systemPrompt := ""
-	if m.context != nil {
-		systemPrompt = m.context.BuildSystemPromptWithMode(BootstrapLight)
-	}
-	messages := BuildMessageList(systemPrompt, nil, work, channel, chatID)
-
 	// 为后台任务创建唯一的 session key，用于记录 token 用量
 	// 不再包含时间戳，日期由 session manager 的 getSessionPath 方法自动添加
 	sessionKey := fmt.Sprintf("task_%s_%s", channel, chatID)
 	ctx = context.WithValue(ctx, SessionKeyContextKey, sessionKey)
+	// 任务的截断等提示附加到任务结果，不混入发起任务的回合
+	ctx = withReplyNotice(ctx)
 
-	iter := runner.Run(ctx, messages)
+	task.mu.Lock()
+	pending := task.pendingInput
+	task.pendingInput = nil
+	task.mu.Unlock()
+
+	var iter *adk.AsyncIterator[*adk.AgentEvent]
+	checkpointID := fmt.Sprintf("task_%s_%d", task.id, time.Now().UnixNano())
+	if pending != nil && answer != "" {
+		// 从中断时的检查点继续执行，把用户回复交给中断点
+		checkpointID = pending.checkpointID
+		iter, err = runner.ResumeWithParams(ctx, checkpointID, &adk.ResumeParams{
+			Targets: map[string]any{pending.interruptID: resumePayload(pending.isAskUser, answer)},
+		})
+		if err != nil {
+			return "", fmt.Errorf("恢复任务失败: %w", err)
+		}
+	} else {
+		// 使用轻量模式加载引导文件，只加载 AGENTS.md 和 TOOLS.md
+		// 不加载 SOUL.md、USER.md 等个性化配置，保持后台任务的独立性
+		systemPrompt := ""
+		if m.context != nil {
+			systemPrompt = m.context.AppendChannelPrompt(m.context.BuildSystemPromptWithMode(BootstrapLight), channel)
+		}
+		messages := BuildMessageList(systemPrompt, nil, task.work, channel, chatID)
+		iter = runner.Run(ctx, messages, adk.WithCheckPointID(checkpointID))
+	}
 
 	var response string
 	var lastEvent *adk.AgentEvent
@@ -461,13 +885,21 @@ func (m *AgentTaskManager) executeTask(ctx context.Context, work, channel, chatI
 		lastEvent = event
 	}
 	if lastEvent != nil && lastEvent.Action != nil && lastEvent.Action.Interrupted != nil {
-		return "", fmt.Errorf("任务需要用户输入，已中断")
+		request := &taskInputRequest{checkpointID: checkpointID}
+		if contexts := lastEvent.Action.Interrupted.InterruptContexts; len(contexts) > 0 {
+			request.interruptID = contexts[0].ID
+			request.question, request.options, request.isAskUser = parseInterruptInfo(contexts[0].Info)
+		}
+		if request.question == "" {
+			request.question = "任务需要补充信息才能继续"
+		}
+		return "", &taskNeedsInputError{request: request}
 	}
-	return response, nil
+	return appendReplyNotice(ctx, response), nil
 }
 
 func (m *AgentTaskManager) buildTaskPrompt() string {
-	return `你是一个后台任务执行 Agent，请独立完成任务，不要向用户提问。若必须获取用户信息，返回明确的缺失项说明。`
+	return `你是一个后台任务执行 Agent，请尽量独立完成任务。只有缺少无法推断的关键信息时才向用户提问，问题会转发给用户，收到回复后任务继续执行。`
 }
 
 func (m *AgentTaskManager) buildTaskContext(ctx context.Context) (context.Context, context.CancelFunc) {
@@ -480,6 +912,11 @@ func (m *AgentTaskManager) buildTaskContext(ctx context.Context) (context.Contex
 func (m *AgentTaskManager) reachedLimit() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return m.runningCountLocked() >= m.maxConcurrent
+}
+
+// runningCountLocked 统计占用并发槽位的任务数（不含排队任务），调用方需持有 m.mu
+func (m *AgentTaskManager) runningCountLocked() int {
 	running := 0
 	for _, task := range m.runningTasks {
 		task.mu.Lock()
@@ -489,81 +926,42 @@ func (m *AgentTaskManager) reachedLimit() bool {
 			running++
 		}
 	}
-	return running >= m.maxConcurrent
-}
-
-// getTaskFilePath 获取任务文件路径
-func (m *AgentTaskManager) getTaskFilePath(date string) string {
-	return filepath.Join(m.tasksDir, date+".yaml")
+	return running
 }
 
 // loadCounter 加载计数器状态
 func (m *AgentTaskManager) loadCounter() {
 	today := time.Now().Format("2006-01-02")
-	filePath := m.getTaskFilePath(today)
-
-	data, err := os.ReadFile(filePath)
+	lastID, err := m.store.LastID(today)
 	if err != nil {
-		m.logger.Info("当天任务文件不存在，计数器从0开始", zap.String("date", today))
+		m.logger.Warn("读取任务计数器失败", zap.Error(err))
 		return
 	}
-
-	var tf TaskFile
-	if err := yaml.Unmarshal(data, &tf); err != nil {
-		m.logger.Warn("解析任务文件失败", zap.Error(err))
+	if lastID == 0 {
+		m.logger.Info("当天没有任务记录，计数器从0开始", zap.String("date", today))
 		return
 	}
-
-	// 优先使用文件中保存的 LastID
-	if tf.LastID > 0 {
-		atomic.StoreUint32(&m.taskCounter, tf.LastID)
-		m.logger.Info("从文件恢复任务计数器", zap.Uint32("last_id", tf.LastID))
-		return
-	}
-
-	// 如果 LastID 为0，从任务列表中计算最大ID
-	maxID := uint32(0)
-	for _, pt := range tf.Tasks {
-		// 解析任务ID
-		var id uint32
-		if _, err := fmt.Sscanf(pt.ID, "%d", &id); err == nil {
-			if id > maxID {
-				maxID = id
-			}
-		}
-	}
-	if maxID > 0 {
-		atomic.StoreUint32(&m.taskCounter, maxID)
-		m.logger.Info("从任务列表恢复计数器", zap.Uint32("max_id", maxID))
-	}
+	atomic.StoreUint32(&m.taskCounter, lastID)
+	m.logger.Info("恢复任务计数器", zap.Uint32("last_id", lastID))
 }
 
 // loadTodayCompletedTasks 加载当天已完成的任务
 func (m *AgentTaskManager) loadTodayCompletedTasks() ([]*TaskInfo, error) {
-	today := time.Now().Format("2006-01-02")
-	filePath := m.getTaskFilePath(today)
-
-	data, err := os.ReadFile(filePath)
+	tasks, err := m.store.ListByDate(time.Now().Format("2006-01-02"))
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, err
-	}
-
-	var tf TaskFile
-	if err := yaml.Unmarshal(data, &tf); err != nil {
 		return nil, err
 	}
 
-	results := make([]*TaskInfo, 0, len(tf.Tasks))
-	for _, pt := range tf.Tasks {
+	results := make([]*TaskInfo, 0, len(tasks))
+	for _, pt := range tasks {
 		// 只返回已完成的任务
-		if pt.Status != TaskPending && pt.Status != TaskRunning {
+		if !isActiveStatus(pt.Status) {
 			results = append(results, &TaskInfo{
 				ID:            pt.ID,
 				Status:        pt.Status,
 				ResultSummary: pt.Result,
+				Channel:       pt.Channel,
+				ChatID:        pt.ChatID,
 			})
 		}
 	}
@@ -573,97 +971,29 @@ func (m *AgentTaskManager) loadTodayCompletedTasks() ([]*TaskInfo, error) {
 
 // loadTaskFromFile 从文件加载任务
 func (m *AgentTaskManager) loadTaskFromFile(taskID string) (*TaskInfo, error) {
-	// 遍历任务目录下的所有yaml文件
-	files, err := filepath.Glob(filepath.Join(m.tasksDir, "*.yaml"))
+	pt, err := m.findPersistedTask(taskID)
 	if err != nil {
-		return nil, fmt.Errorf("读取任务目录失败: %w", err)
-	}
-
-	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			continue
-		}
-
-		var tf TaskFile
-		if err := yaml.Unmarshal(data, &tf); err != nil {
-			continue
-		}
-
-		for _, pt := range tf.Tasks {
-			if normalizeTaskID(pt.ID) == taskID {
-				return &TaskInfo{
-					ID:            pt.ID,
-					Status:        pt.Status,
-					ResultSummary: pt.Result,
-				}, nil
-			}
-		}
+		return nil, err
 	}
-
-	return nil, fmt.Errorf("任务不存在")
+	return &TaskInfo{
+		ID:            pt.ID,
+		Status:        pt.Status,
+		ResultSummary: pt.Result,
+		Channel:       pt.Channel,
+		ChatID:        pt.ChatID,
+	}, nil
 }
 
-// appendTaskToFile 追加任务到文件（根据创建时间决定写入哪一天的文件）
-func (m *AgentTaskManager) appendTaskToFile(pt *PersistedTask) {
-	m.persistMu.Lock()
-	defer m.persistMu.Unlock()
-
-	// 根据任务创建时间决定写入哪一天的文件
-	date := pt.CreatedAt.Format("2006-01-02")
-	filePath := m.getTaskFilePath(date)
-
-	m.logger.Info("准备持久化任务",
-		zap.String("task_id", pt.ID),
-		zap.String("status", string(pt.Status)),
-		zap.String("tasks_dir", m.tasksDir),
-		zap.String("file", filePath),
-	)
-
-	// 确保目录存在
-	if err := os.MkdirAll(m.tasksDir, 0755); err != nil {
-		m.logger.Error("创建任务目录失败", zap.Error(err), zap.String("tasks_dir", m.tasksDir))
-		return
-	}
-
-	// 读取现有文件
-	var tf TaskFile
-	data, err := os.ReadFile(filePath)
-	if err == nil {
-		yaml.Unmarshal(data, &tf)
-	}
-
-	// 检查是否已存在（避免重复追加）
-	for _, existing := range tf.Tasks {
-		if existing.ID == pt.ID {
-			m.logger.Debug("任务已存在，跳过追加", zap.String("task_id", pt.ID))
-			return
-		}
-	}
-
-	// 更新日期、计数器和任务列表
-	tf.Date = date
-	tf.LastID = atomic.LoadUint32(&m.taskCounter)
-	tf.Tasks = append(tf.Tasks, pt)
-
-	// 写入文件
-	out, err := yaml.Marshal(&tf)
-	if err != nil {
-		m.logger.Error("序列化任务失败", zap.Error(err))
-		return
-	}
-
-	if err := os.WriteFile(filePath, out, 0644); err != nil {
-		m.logger.Error("写入任务文件失败", zap.Error(err), zap.String("file", filePath))
-		return
-	}
-
-	m.logger.Info("持久化任务成功", zap.String("task_id", pt.ID), zap.String("file", filePath))
+// findPersistedTask 在持久化存储中查找任务
+func (m *AgentTaskManager) findPersistedTask(taskID string) (*PersistedTask, error) {
+	return m.store.Find(taskID)
 }
 
-// Close 关闭任务管理器
+// Close 关闭任务管理器，任务完成时已持久化，只需释放存储资源
 func (m *AgentTaskManager) Close() {
-	// 无需额外操作，任务完成时已持久化
+	if err := m.store.Close(); err != nil {
+		m.logger.Warn("关闭任务存储失败", zap.Error(err))
+	}
 }
 
 func (t *AgentTask) appendLog(message string) {
@@ -693,6 +1023,15 @@ func (a *TaskManagerAdapter) StartTask(ctx context.Context, work, channel, chatI
 	return taskID, string(status), err
 }
 
+// StartTaskWithDelivery 启动任务并指定结果投递目标
+func (a *TaskManagerAdapter) StartTaskWithDelivery(ctx context.Context, work, channel, chatID, deliverChannel, deliverChatID string) (string, string, error) {
+	if a.manager == nil {
+		return "", "", fmt.Errorf("任务管理器未初始化")
+	}
+	taskID, status, err := a.manager.StartTaskWithDelivery(ctx, work, channel, chatID, TaskDelivery{Channel: deliverChannel, ChatID: deliverChatID})
+	return taskID, string(status), err
+}
+
 // GetTask 查询任务信息
 func (a *TaskManagerAdapter) GetTask(ctx context.Context, taskID string) (*tasktools.TaskInfo, error) {
 	if a.manager == nil {
@@ -706,9 +1045,20 @@ func (a *TaskManagerAdapter) GetTask(ctx context.Context, taskID string) (*taskt
 		ID:            info.ID,
 		Status:        string(info.Status),
 		ResultSummary: info.ResultSummary,
+		QueuePosition: info.QueuePosition,
+		Channel:       info.Channel,
+		ChatID:        info.ChatID,
 	}, nil
 }
 
+// GetTaskLogs 查询任务最近日志
+func (a *TaskManagerAdapter) GetTaskLogs(ctx context.Context, taskID string) ([]string, error) {
+	if a.manager == nil {
+		return nil, fmt.Errorf("任务管理器未初始化")
+	}
+	return a.manager.GetTaskLogs(taskID)
+}
+
 // StopTask 停止任务并返回结果
 func (a *TaskManagerAdapter) StopTask(ctx context.Context, taskID string) (bool, string, error) {
 	if a.manager == nil {
@@ -718,6 +1068,47 @@ func (a *TaskManagerAdapter) StopTask(ctx context.Context, taskID string) (bool,
 	return stopped, string(status), err
 }
 
+// ResumeTask 使用用户回复恢复等待输入的任务
+func (a *TaskManagerAdapter) ResumeTask(ctx context.Context, taskID, answer string) (string, error) {
+	if a.manager == nil {
+		return "", fmt.Errorf("任务管理器未初始化")
+	}
+	status, err := a.manager.ResumeTask(ctx, taskID, answer)
+	return string(status), err
+}
+
+// IsTaskOrigin 判断任务是否由指定渠道和聊天发起
+func (a *TaskManagerAdapter) IsTaskOrigin(ctx context.Context, taskID, channel, chatID string) (bool, bool) {
+	if a.manager == nil {
+		return false, false
+	}
+	return a.manager.IsTaskOrigin(taskID, channel, chatID)
+}
+
+// ListTasksFiltered 按日期范围和状态筛选任务
+func (a *TaskManagerAdapter) ListTasksFiltered(ctx context.Context, from, to time.Time, status string) ([]*tasktools.TaskInfo, error) {
+	if a.manager == nil {
+		return nil, fmt.Errorf("任务管理器未初始化")
+	}
+	items, err := a.manager.ListTasksFiltered(from, to, TaskStatus(status))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*tasktools.TaskInfo, 0, len(items))
+	for _, item := range items {
+		result = append(result, &tasktools.TaskInfo{
+			ID:            item.ID,
+			Status:        string(item.Status),
+			ResultSummary: item.ResultSummary,
+			QueuePosition: item.QueuePosition,
+			CreatedAt:     item.CreatedAt,
+			Channel:       item.Channel,
+			ChatID:        item.ChatID,
+		})
+	}
+	return result, nil
+}
+
 // ListTasks 获取任务列表
 func (a *TaskManagerAdapter) ListTasks() ([]*tasktools.TaskInfo, error) {
 	if a.manager == nil {
@@ -733,6 +1124,9 @@ func (a *TaskManagerAdapter) ListTasks() ([]*tasktools.TaskInfo, error) {
 			ID:            item.ID,
 			Status:        string(item.Status),
 			ResultSummary: item.ResultSummary,
+			QueuePosition: item.QueuePosition,
+			Channel:       item.Channel,
+			ChatID:        item.ChatID,
 		})
 	}
 	return result, nil
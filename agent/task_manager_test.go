@@ -0,0 +1,400 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// newTestTaskManager 创建使用临时工作区的任务管理器
+func newTestTaskManager(t *testing.T) *AgentTaskManager {
+	t.Helper()
+	m, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{
+		Workspace: t.TempDir(),
+		Logger:    zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+	return m
+}
+
+// TestAgentTaskManager_GetTaskLogs 测试获取任务日志
+func TestAgentTaskManager_GetTaskLogs(t *testing.T) {
+	t.Run("运行中的任务", func(t *testing.T) {
+		m := newTestTaskManager(t)
+		task := &AgentTask{id: "000001", status: TaskRunning, logCapacity: 2}
+		task.appendLog("任务已创建")
+		task.appendLog("任务启动")
+		task.appendLog("调用工具")
+		m.runningTasks[task.id] = task
+
+		logs, err := m.GetTaskLogs("1")
+		if err != nil {
+			t.Fatalf("GetTaskLogs() 返回错误: %v", err)
+		}
+		if len(logs) != 2 {
+			t.Fatalf("len(logs) = %d, 期望 2", len(logs))
+		}
+	})
+
+	t.Run("已持久化的任务", func(t *testing.T) {
+		m := newTestTaskManager(t)
+		task := &AgentTask{id: "000002", status: TaskFinished, logCapacity: 10, createdAt: time.Now()}
+		task.appendLog("任务已创建")
+		task.appendLog("任务完成")
+		m.persistTask(task)
+
+		logs, err := m.GetTaskLogs("000002")
+		if err != nil {
+			t.Fatalf("GetTaskLogs() 返回错误: %v", err)
+		}
+		if len(logs) != 2 {
+			t.Fatalf("len(logs) = %d, 期望 2", len(logs))
+		}
+	})
+
+	t.Run("任务不存在", func(t *testing.T) {
+		m := newTestTaskManager(t)
+		if _, err := m.GetTaskLogs("999999"); err == nil {
+			t.Error("GetTaskLogs() 应该返回错误")
+		}
+	})
+}
+
+// TestAgentTaskManager_RecoverInterruptedTasks 测试重启时清理未完成任务
+func TestAgentTaskManager_RecoverInterruptedTasks(t *testing.T) {
+	workspace := t.TempDir()
+	first, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{Workspace: workspace, Logger: zap.NewNop()})
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+	first.persistTask(&AgentTask{id: "000001", status: TaskRunning, logCapacity: 10, createdAt: time.Now()})
+	first.persistTask(&AgentTask{id: "000002", status: TaskFinished, result: "完成", logCapacity: 10, createdAt: time.Now()})
+
+	// 模拟重启
+	second, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{Workspace: workspace, Logger: zap.NewNop()})
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+
+	info, err := second.GetTask("000001")
+	if err != nil {
+		t.Fatalf("GetTask() 返回错误: %v", err)
+	}
+	if info.Status != TaskFailed {
+		t.Errorf("Status = %q, 期望 %q", info.Status, TaskFailed)
+	}
+
+	info, err = second.GetTask("000002")
+	if err != nil {
+		t.Fatalf("GetTask() 返回错误: %v", err)
+	}
+	if info.Status != TaskFinished {
+		t.Errorf("Status = %q, 期望 %q", info.Status, TaskFinished)
+	}
+
+	stopped, status, err := second.StopTask("000001")
+	if err != nil {
+		t.Fatalf("StopTask() 返回错误: %v", err)
+	}
+	if stopped || status != TaskFailed {
+		t.Errorf("StopTask() = (%v, %q), 期望 (false, %q)", stopped, status, TaskFailed)
+	}
+}
+
+// TestAgentTaskManager_Queue 测试并发已满时的任务排队
+func TestAgentTaskManager_Queue(t *testing.T) {
+	m, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{
+		Workspace:          t.TempDir(),
+		Logger:             zap.NewNop(),
+		MaxConcurrentTasks: 1,
+		MaxQueuedTasks:     1,
+	})
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+	// 占用唯一的并发槽位
+	m.runningTasks["000100"] = &AgentTask{id: "000100", status: TaskRunning, logCapacity: 10}
+
+	taskID, status, err := m.StartTask(context.Background(), "排队任务", "cli", "default")
+	if err != nil {
+		t.Fatalf("StartTask() 返回错误: %v", err)
+	}
+	if status != TaskQueued {
+		t.Fatalf("status = %q, 期望 %q", status, TaskQueued)
+	}
+	info, err := m.GetTask(taskID)
+	if err != nil {
+		t.Fatalf("GetTask() 返回错误: %v", err)
+	}
+	if info.QueuePosition != 1 {
+		t.Errorf("QueuePosition = %d, 期望 1", info.QueuePosition)
+	}
+
+	if _, _, err := m.StartTask(context.Background(), "超出队列", "cli", "default"); err == nil {
+		t.Error("队列已满时 StartTask() 应该返回错误")
+	}
+
+	queuedTask := m.runningTasks[taskID]
+	stopped, status, err := m.StopTask(taskID)
+	if err != nil {
+		t.Fatalf("StopTask() 返回错误: %v", err)
+	}
+	if !stopped || status != TaskStopped {
+		t.Errorf("StopTask() = (%v, %q), 期望 (true, %q)", stopped, status, TaskStopped)
+	}
+	select {
+	case <-queuedTask.done:
+	default:
+		t.Error("停止排队任务后应关闭 done")
+	}
+	if len(m.queue) != 0 {
+		t.Errorf("len(queue) = %d, 期望 0", len(m.queue))
+	}
+	info, err = m.GetTask(taskID)
+	if err != nil {
+		t.Fatalf("GetTask() 返回错误: %v", err)
+	}
+	if info.Status != TaskStopped {
+		t.Errorf("Status = %q, 期望 %q", info.Status, TaskStopped)
+	}
+}
+
+// TestAgentTaskManager_StopBeforeStart 测试任务出队后、执行前被停止时不再执行
+func TestAgentTaskManager_StopBeforeStart(t *testing.T) {
+	m := newTestTaskManager(t)
+	executed := false
+	m.execute = func(ctx context.Context, task *AgentTask, answer string) (string, error) {
+		executed = true
+		return "完成", nil
+	}
+	task := &AgentTask{id: "000200", status: TaskPending, logCapacity: 10, done: make(chan struct{}), createdAt: time.Now()}
+	m.runningTasks[task.id] = task
+
+	if stopped, status, err := m.StopTask(task.id); err != nil || !stopped || status != TaskStopped {
+		t.Fatalf("StopTask() = (%v, %q, %v), 期望 (true, %q, nil)", stopped, status, err, TaskStopped)
+	}
+	m.runTask(context.Background(), task, "")
+
+	if executed {
+		t.Error("已停止的任务不应再执行")
+	}
+	select {
+	case <-task.done:
+	default:
+		t.Error("已停止的任务应关闭 done")
+	}
+	info, err := m.GetTask(task.id)
+	if err != nil || info.Status != TaskStopped {
+		t.Errorf("GetTask() = %+v, %v, 期望状态 %q", info, err, TaskStopped)
+	}
+}
+
+// TestAgentTaskManager_QueueDisabled 测试未配置排队时直接拒绝
+func TestAgentTaskManager_QueueDisabled(t *testing.T) {
+	m, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{
+		Workspace:          t.TempDir(),
+		Logger:             zap.NewNop(),
+		MaxConcurrentTasks: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+	m.runningTasks["000100"] = &AgentTask{id: "000100", status: TaskRunning, logCapacity: 10}
+
+	_, _, err = m.StartTask(context.Background(), "任务", "cli", "default")
+	if err == nil || err.Error() != "任务并发已达上限" {
+		t.Errorf("StartTask() error = %v, 期望 任务并发已达上限", err)
+	}
+}
+
+// TestAgentTaskManager_WaitingInput 测试任务等待用户输入并在回复后继续执行
+func TestAgentTaskManager_WaitingInput(t *testing.T) {
+	notified := make(chan string, 4)
+	newManager := func(t *testing.T) *AgentTaskManager {
+		m, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{
+			Workspace: t.TempDir(),
+			Logger:    zap.NewNop(),
+			OnTaskComplete: func(channel, chatID, taskID string, status TaskStatus, result string) {
+				notified <- string(status) + ":" + result
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+		}
+		m.execute = func(ctx context.Context, task *AgentTask, answer string) (string, error) {
+			if answer == "" {
+				return "", &taskNeedsInputError{request: &taskInputRequest{
+					checkpointID: "cp", interruptID: "int", question: "部署到哪个环境？", options: []string{"测试", "生产"},
+				}}
+			}
+			return "已部署到" + answer, nil
+		}
+		return m
+	}
+	waitStatus := func(t *testing.T, m *AgentTaskManager, taskID string, want TaskStatus) {
+		t.Helper()
+		select {
+		case got := <-notified:
+			if !strings.HasPrefix(got, string(want)+":") {
+				t.Fatalf("通知 = %q, 期望状态 %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待任务状态 %q 超时", want)
+		}
+		info, err := m.GetTask(taskID)
+		if err != nil || info.Status != want {
+			t.Fatalf("GetTask() = %+v, %v, 期望状态 %q", info, err, want)
+		}
+	}
+
+	t.Run("回复后继续执行", func(t *testing.T) {
+		m := newManager(t)
+		taskID, _, err := m.StartTask(context.Background(), "部署服务", "cli", "default")
+		if err != nil {
+			t.Fatalf("StartTask() 返回错误: %v", err)
+		}
+		waitStatus(t, m, taskID, TaskWaitingInput)
+
+		if _, err := m.ResumeTask(context.Background(), taskID, ""); err == nil {
+			t.Error("空回复应返回错误")
+		}
+		if _, err := m.ResumeTask(context.Background(), taskID, "测试"); err != nil {
+			t.Fatalf("ResumeTask() 返回错误: %v", err)
+		}
+		waitStatus(t, m, taskID, TaskFinished)
+
+		if _, err := m.ResumeTask(context.Background(), taskID, "生产"); err == nil {
+			t.Error("任务结束后 ResumeTask() 应返回错误")
+		}
+	})
+
+	t.Run("等待输入时可以停止", func(t *testing.T) {
+		m := newManager(t)
+		taskID, _, err := m.StartTask(context.Background(), "部署服务", "cli", "default")
+		if err != nil {
+			t.Fatalf("StartTask() 返回错误: %v", err)
+		}
+		waitStatus(t, m, taskID, TaskWaitingInput)
+
+		stopped, status, err := m.StopTask(taskID)
+		if err != nil || !stopped || status != TaskStopped {
+			t.Fatalf("StopTask() = (%v, %q, %v), 期望 (true, %q, nil)", stopped, status, err, TaskStopped)
+		}
+		waitStatus(t, m, taskID, TaskStopped)
+	})
+}
+
+// TestAgentTaskManager_Delivery 测试任务结果投递到指定目标
+func TestAgentTaskManager_Delivery(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Channels.Feishu.Enabled = true
+	notified := make(chan string, 2)
+	m, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{
+		Cfg:       cfg,
+		Workspace: t.TempDir(),
+		Logger:    zap.NewNop(),
+		OnTaskComplete: func(channel, chatID, taskID string, status TaskStatus, result string) {
+			notified <- channel + ":" + chatID
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+	m.execute = func(ctx context.Context, task *AgentTask, answer string) (string, error) {
+		return "完成", nil
+	}
+
+	errorCases := []struct {
+		name     string
+		delivery TaskDelivery
+	}{
+		{"渠道未启用", TaskDelivery{Channel: "matrix", ChatID: "!room"}},
+		{"缺少聊天ID", TaskDelivery{Channel: "feishu"}},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := m.StartTaskWithDelivery(context.Background(), "任务", "websocket", "chat1", tc.delivery); err == nil {
+				t.Error("StartTaskWithDelivery() 应返回错误")
+			}
+		})
+	}
+
+	t.Run("结果投递到指定渠道", func(t *testing.T) {
+		taskID, _, err := m.StartTaskWithDelivery(context.Background(), "任务", "websocket", "chat1", TaskDelivery{Channel: "feishu", ChatID: "oc_123"})
+		if err != nil {
+			t.Fatalf("StartTaskWithDelivery() 返回错误: %v", err)
+		}
+		select {
+		case got := <-notified:
+			if got != "feishu:oc_123" {
+				t.Errorf("通知目标 = %q, 期望 feishu:oc_123", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("等待任务完成超时")
+		}
+		pt, err := m.findPersistedTask(taskID)
+		if err != nil || pt.DeliverChannel != "feishu" || pt.DeliverChatID != "oc_123" {
+			t.Errorf("持久化的投递目标 = %+v, %v", pt, err)
+		}
+	})
+
+	t.Run("只指定聊天ID时沿用发起渠道", func(t *testing.T) {
+		if _, _, err := m.StartTaskWithDelivery(context.Background(), "任务", "websocket", "chat1", TaskDelivery{ChatID: "chat2"}); err != nil {
+			t.Fatalf("StartTaskWithDelivery() 返回错误: %v", err)
+		}
+		select {
+		case got := <-notified:
+			if got != "websocket:chat2" {
+				t.Errorf("通知目标 = %q, 期望 websocket:chat2", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("等待任务完成超时")
+		}
+	})
+}
+
+// TestAgentTaskManager_OutlivesTurn 测试发起任务的回合结束后任务继续执行
+func TestAgentTaskManager_OutlivesTurn(t *testing.T) {
+	notified := make(chan TaskStatus, 1)
+	m, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{
+		Workspace: t.TempDir(),
+		Logger:    zap.NewNop(),
+		OnTaskComplete: func(channel, chatID, taskID string, status TaskStatus, result string) {
+			notified <- status
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+	release := make(chan struct{})
+	m.execute = func(ctx context.Context, task *AgentTask, answer string) (string, error) {
+		<-release
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return "完成", nil
+	}
+
+	loop := &Loop{logger: zap.NewNop()}
+	turnCtx, endTurn := loop.beginTurn(context.Background(), "cli:direct")
+	if _, _, err := m.StartTask(turnCtx, "长任务", "cli", "direct"); err != nil {
+		t.Fatalf("StartTask() 返回错误: %v", err)
+	}
+	endTurn()
+	close(release)
+
+	select {
+	case status := <-notified:
+		if status != TaskFinished {
+			t.Errorf("回合结束后任务状态 = %q, 期望 %q", status, TaskFinished)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待任务完成超时")
+	}
+}
@@ -0,0 +1,468 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 任务持久化存储类型
+const (
+	TaskStoreYAML   = "yaml"   // 按天写入 YAML 文件（默认）
+	TaskStoreSQLite = "sqlite" // 写入 SQLite 数据库，按任务ID和日期建立索引
+)
+
+// errTaskNotFound 持久化存储中不存在该任务
+var errTaskNotFound = errors.New("任务不存在")
+
+// TaskStore 任务持久化存储
+type TaskStore interface {
+	// Save 保存任务，已存在（同一天同一任务ID）时更新；lastID 为当前任务计数器
+	Save(pt *PersistedTask, lastID uint32) error
+
+	// Find 按标准化后的任务ID查找任务，任务ID每天从头计数，重复时返回最近创建的任务
+	Find(taskID string) (*PersistedTask, error)
+
+	// ListByDate 列出指定日期（2006-01-02）创建的任务，按创建时间升序
+	ListByDate(date string) ([]*PersistedTask, error)
+
+	// ListRange 列出创建日期在 [from, to] 之间的任务（日期格式 2006-01-02，含两端），按创建时间升序
+	ListRange(from, to string) ([]*PersistedTask, error)
+
+	// LastID 返回指定日期的任务计数器，没有记录时返回 0
+	LastID(date string) (uint32, error)
+
+	// MarkInterrupted 将所有未结束的任务标记为失败，返回处理的任务数
+	MarkInterrupted(reason string) (int, error)
+
+	// Close 释放存储资源
+	Close() error
+}
+
+// newTaskStore 按类型创建任务存储，dir 为任务存储目录
+func newTaskStore(kind, dir string, logger *zap.Logger) (TaskStore, error) {
+	switch kind {
+	case "", TaskStoreYAML:
+		return &yamlTaskStore{dir: dir, logger: logger}, nil
+	case TaskStoreSQLite:
+		return newSQLiteTaskStore(filepath.Join(dir, "tasks.db"))
+	default:
+		return nil, fmt.Errorf("不支持的任务存储类型: %s", kind)
+	}
+}
+
+// yamlTaskStore 按任务创建日期写入 tasks/<date>.yaml
+type yamlTaskStore struct {
+	dir    string
+	logger *zap.Logger
+	// mu 保护文件写入
+	mu sync.Mutex
+}
+
+// filePath 获取任务文件路径
+func (s *yamlTaskStore) filePath(date string) string {
+	return filepath.Join(s.dir, date+".yaml")
+}
+
+// readFile 读取任务文件，文件不存在时返回空结构
+func (s *yamlTaskStore) readFile(path string) (*TaskFile, error) {
+	var tf TaskFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &tf, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &tf); err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// Save 保存任务到文件（根据创建时间决定写入哪一天的文件）
+// 已存在的任务记录会被更新，不存在时追加
+func (s *yamlTaskStore) Save(pt *PersistedTask, lastID uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 根据任务创建时间决定写入哪一天的文件
+	date := pt.CreatedAt.Format("2006-01-02")
+	filePath := s.filePath(date)
+
+	s.logger.Info("准备持久化任务",
+		zap.String("task_id", pt.ID),
+		zap.String("status", string(pt.Status)),
+		zap.String("tasks_dir", s.dir),
+		zap.String("file", filePath),
+	)
+
+	// 确保目录存在
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("创建任务目录失败: %w", err)
+	}
+
+	// 读取现有文件，解析失败时覆盖写入
+	tf, err := s.readFile(filePath)
+	if err != nil {
+		tf = &TaskFile{}
+	}
+
+	// 已存在则原地更新状态（任务启动时会先写入 pending 记录）
+	updated := false
+	for i, existing := range tf.Tasks {
+		if existing.ID == pt.ID {
+			tf.Tasks[i] = pt
+			updated = true
+			break
+		}
+	}
+
+	// 更新日期、计数器和任务列表
+	tf.Date = date
+	tf.LastID = lastID
+	if !updated {
+		tf.Tasks = append(tf.Tasks, pt)
+	}
+
+	out, err := yaml.Marshal(tf)
+	if err != nil {
+		return fmt.Errorf("序列化任务失败: %w", err)
+	}
+	if err := os.WriteFile(filePath, out, 0644); err != nil {
+		return fmt.Errorf("写入任务文件失败: %w", err)
+	}
+	return nil
+}
+
+// Find 在任务目录的所有 yaml 文件中查找任务，ID 重复时返回最近创建的任务
+func (s *yamlTaskStore) Find(taskID string) (*PersistedTask, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("读取任务目录失败: %w", err)
+	}
+
+	var found *PersistedTask
+	for _, file := range files {
+		tf, err := s.readFile(file)
+		if err != nil {
+			continue
+		}
+		for _, pt := range tf.Tasks {
+			if normalizeTaskID(pt.ID) == taskID && (found == nil || !pt.CreatedAt.Before(found.CreatedAt)) {
+				found = pt
+			}
+		}
+	}
+	if found == nil {
+		return nil, errTaskNotFound
+	}
+	return found, nil
+}
+
+// ListByDate 列出指定日期文件中的任务
+func (s *yamlTaskStore) ListByDate(date string) ([]*PersistedTask, error) {
+	tf, err := s.readFile(s.filePath(date))
+	if err != nil {
+		return nil, err
+	}
+	sortTasksByCreatedAt(tf.Tasks)
+	return tf.Tasks, nil
+}
+
+// ListRange 按文件名中的日期筛选任务文件，依次列出其中的任务
+func (s *yamlTaskStore) ListRange(from, to string) ([]*PersistedTask, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("读取任务目录失败: %w", err)
+	}
+
+	var results []*PersistedTask
+	for _, file := range files {
+		date := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		if date < from || date > to {
+			continue
+		}
+		tf, err := s.readFile(file)
+		if err != nil {
+			continue
+		}
+		results = append(results, tf.Tasks...)
+	}
+	sortTasksByCreatedAt(results)
+	return results, nil
+}
+
+// sortTasksByCreatedAt 按创建时间升序排列任务，创建时间相同时保持原有顺序，与 SQLite 存储的顺序一致
+func sortTasksByCreatedAt(tasks []*PersistedTask) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+}
+
+// LastID 返回指定日期文件中保存的计数器，为 0 时从任务列表中计算最大ID
+func (s *yamlTaskStore) LastID(date string) (uint32, error) {
+	tf, err := s.readFile(s.filePath(date))
+	if err != nil {
+		return 0, err
+	}
+	if tf.LastID > 0 {
+		return tf.LastID, nil
+	}
+
+	maxID := uint32(0)
+	for _, pt := range tf.Tasks {
+		var id uint32
+		if _, err := fmt.Sscanf(pt.ID, "%d", &id); err == nil && id > maxID {
+			maxID = id
+		}
+	}
+	return maxID, nil
+}
+
+// MarkInterrupted 将所有任务文件中未结束的任务标记为失败
+func (s *yamlTaskStore) MarkInterrupted(reason string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.yaml"))
+	if err != nil {
+		return 0, fmt.Errorf("读取任务目录失败: %w", err)
+	}
+
+	total := 0
+	for _, file := range files {
+		tf, err := s.readFile(file)
+		if err != nil {
+			continue
+		}
+
+		recovered := 0
+		for _, pt := range tf.Tasks {
+			if !isActiveStatus(pt.Status) {
+				continue
+			}
+			markTaskInterrupted(pt, reason)
+			recovered++
+		}
+		if recovered == 0 {
+			continue
+		}
+
+		out, err := yaml.Marshal(tf)
+		if err != nil {
+			s.logger.Error("序列化任务失败", zap.Error(err))
+			continue
+		}
+		if err := os.WriteFile(file, out, 0644); err != nil {
+			s.logger.Error("写入任务文件失败", zap.Error(err), zap.String("file", file))
+			continue
+		}
+		total += recovered
+	}
+	return total, nil
+}
+
+// Close YAML 存储无需释放资源
+func (s *yamlTaskStore) Close() error {
+	return nil
+}
+
+// markTaskInterrupted 将任务标记为因中断而失败
+func markTaskInterrupted(pt *PersistedTask, reason string) {
+	pt.Status = TaskFailed
+	pt.Result = reason
+	pt.CompletedAt = time.Now()
+	pt.Logs = append(pt.Logs, fmt.Sprintf("%s %s", time.Now().Format("2006-01-02 15:04:05"), reason))
+}
+
+// taskRecord SQLite 中的任务记录
+// 任务ID每天从头计数，因此以任务ID和创建日期共同确定一条记录
+type taskRecord struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement"`
+	TaskID         string     `gorm:"column:task_id;size:16;not null;uniqueIndex:idx_tasks_task_date"`
+	Date           string     `gorm:"column:date;size:10;not null;uniqueIndex:idx_tasks_task_date;index"`
+	Work           string     `gorm:"column:work;type:text"`
+	Status         TaskStatus `gorm:"column:status;size:32;index"`
+	Result         string     `gorm:"column:result;type:text"`
+	Channel        string     `gorm:"column:channel;size:64"`
+	ChatID         string     `gorm:"column:chat_id;size:255"`
+	DeliverChannel string     `gorm:"column:deliver_channel;size:64"`
+	DeliverChatID  string     `gorm:"column:deliver_chat_id;size:255"`
+	CreatedAt      time.Time  `gorm:"column:created_at;index"`
+	CompletedAt    time.Time  `gorm:"column:completed_at"`
+	Logs           []string   `gorm:"column:logs;serializer:json"`
+}
+
+// TableName 指定表名
+func (taskRecord) TableName() string {
+	return "tasks"
+}
+
+// toPersisted 转换为持久化任务结构
+func (r *taskRecord) toPersisted() *PersistedTask {
+	return &PersistedTask{
+		ID:             r.TaskID,
+		Work:           r.Work,
+		Status:         r.Status,
+		Result:         r.Result,
+		Channel:        r.Channel,
+		ChatID:         r.ChatID,
+		DeliverChannel: r.DeliverChannel,
+		DeliverChatID:  r.DeliverChatID,
+		CreatedAt:      r.CreatedAt,
+		CompletedAt:    r.CompletedAt,
+		Logs:           r.Logs,
+	}
+}
+
+// sqliteTaskStore 基于 SQLite 的任务存储，按任务ID、日期和状态建立索引
+type sqliteTaskStore struct {
+	db *gorm.DB
+}
+
+// newSQLiteTaskStore 打开（必要时创建）任务数据库
+func newSQLiteTaskStore(dbPath string) (*sqliteTaskStore, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建任务目录失败: %w", err)
+	}
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("打开任务数据库失败: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	// SQLite 建议单连接
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&taskRecord{}); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("创建 tasks 表失败: %w", err)
+	}
+	return &sqliteTaskStore{db: db}, nil
+}
+
+// Save 保存任务，同一天同一任务ID的记录原地更新
+func (s *sqliteTaskStore) Save(pt *PersistedTask, lastID uint32) error {
+	record := taskRecord{
+		TaskID:         pt.ID,
+		Date:           pt.CreatedAt.Format("2006-01-02"),
+		Work:           pt.Work,
+		Status:         pt.Status,
+		Result:         pt.Result,
+		Channel:        pt.Channel,
+		ChatID:         pt.ChatID,
+		DeliverChannel: pt.DeliverChannel,
+		DeliverChatID:  pt.DeliverChatID,
+		CreatedAt:      pt.CreatedAt,
+		CompletedAt:    pt.CompletedAt,
+		Logs:           pt.Logs,
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var existing taskRecord
+		err := tx.Where("task_id = ? AND date = ?", record.TaskID, record.Date).Take(&existing).Error
+		switch {
+		case err == nil:
+			record.ID = existing.ID
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		return tx.Save(&record).Error
+	})
+}
+
+// Find 按任务ID查找任务，ID 重复时返回最近创建的任务
+func (s *sqliteTaskStore) Find(taskID string) (*PersistedTask, error) {
+	var record taskRecord
+	err := s.db.Where("task_id = ?", taskID).Order("created_at DESC, id DESC").Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record.toPersisted(), nil
+}
+
+// ListByDate 列出指定日期创建的任务
+func (s *sqliteTaskStore) ListByDate(date string) ([]*PersistedTask, error) {
+	var records []taskRecord
+	if err := s.db.Where("date = ?", date).Order("created_at ASC, id ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	results := make([]*PersistedTask, len(records))
+	for i := range records {
+		results[i] = records[i].toPersisted()
+	}
+	return results, nil
+}
+
+// ListRange 列出创建日期在 [from, to] 之间的任务
+func (s *sqliteTaskStore) ListRange(from, to string) ([]*PersistedTask, error) {
+	var records []taskRecord
+	if err := s.db.Where("date BETWEEN ? AND ?", from, to).Order("created_at ASC, id ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	results := make([]*PersistedTask, len(records))
+	for i := range records {
+		results[i] = records[i].toPersisted()
+	}
+	return results, nil
+}
+
+// LastID 返回指定日期最大的任务ID
+func (s *sqliteTaskStore) LastID(date string) (uint32, error) {
+	var maxID string
+	err := s.db.Model(&taskRecord{}).Where("date = ?", date).
+		Select("COALESCE(MAX(task_id), '')").Scan(&maxID).Error
+	if err != nil || maxID == "" {
+		return 0, err
+	}
+	var id uint32
+	if _, err := fmt.Sscanf(maxID, "%d", &id); err != nil {
+		return 0, nil
+	}
+	return id, nil
+}
+
+// MarkInterrupted 将所有未结束的任务标记为失败
+func (s *sqliteTaskStore) MarkInterrupted(reason string) (int, error) {
+	var records []taskRecord
+	active := []TaskStatus{TaskPending, TaskRunning, TaskQueued, TaskWaitingInput}
+	if err := s.db.Where("status IN ?", active).Find(&records).Error; err != nil {
+		return 0, err
+	}
+	for i := range records {
+		pt := records[i].toPersisted()
+		markTaskInterrupted(pt, reason)
+		records[i].Status, records[i].Result = pt.Status, pt.Result
+		records[i].CompletedAt, records[i].Logs = pt.CompletedAt, pt.Logs
+		if err := s.db.Save(&records[i]).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// Close 关闭数据库连接
+func (s *sqliteTaskStore) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TestTaskStore 测试 YAML 与 SQLite 任务存储的行为一致
+func TestTaskStore(t *testing.T) {
+	for _, kind := range []string{TaskStoreYAML, TaskStoreSQLite} {
+		t.Run(kind, func(t *testing.T) {
+			store, err := newTaskStore(kind, t.TempDir(), zap.NewNop())
+			if err != nil {
+				t.Fatalf("newTaskStore() 返回错误: %v", err)
+			}
+			defer store.Close()
+
+			today := time.Now()
+			yesterday := today.AddDate(0, 0, -1)
+			date := today.Format("2006-01-02")
+
+			mustSave := func(pt *PersistedTask, lastID uint32) {
+				t.Helper()
+				if err := store.Save(pt, lastID); err != nil {
+					t.Fatalf("Save() 返回错误: %v", err)
+				}
+			}
+			mustSave(&PersistedTask{ID: "000001", Status: TaskRunning, CreatedAt: yesterday}, 1)
+			mustSave(&PersistedTask{ID: "000001", Status: TaskRunning, CreatedAt: today}, 1)
+			mustSave(&PersistedTask{ID: "000002", Status: TaskPending, CreatedAt: today}, 2)
+			// 同一天同一任务ID更新原记录
+			mustSave(&PersistedTask{ID: "000002", Status: TaskFinished, Result: "完成", CreatedAt: today, Logs: []string{"任务完成"}}, 2)
+
+			tasks, err := store.ListByDate(date)
+			if err != nil {
+				t.Fatalf("ListByDate() 返回错误: %v", err)
+			}
+			if len(tasks) != 2 {
+				t.Fatalf("len(tasks) = %d, 期望 2", len(tasks))
+			}
+
+			pt, err := store.Find("000002")
+			if err != nil {
+				t.Fatalf("Find() 返回错误: %v", err)
+			}
+			if pt.Status != TaskFinished || pt.Result != "完成" || len(pt.Logs) != 1 {
+				t.Errorf("Find() = %+v", pt)
+			}
+			if pt, err := store.Find("000001"); err != nil || !pt.CreatedAt.Equal(today) {
+				t.Errorf("ID 重复时 Find() 应返回最近创建的任务, got %+v, %v", pt, err)
+			}
+			if _, err := store.Find("999999"); err == nil {
+				t.Error("任务不存在时 Find() 应返回错误")
+			}
+
+			lastID, err := store.LastID(date)
+			if err != nil || lastID != 2 {
+				t.Errorf("LastID() = (%d, %v), 期望 2", lastID, err)
+			}
+			if lastID, _ := store.LastID("2000-01-01"); lastID != 0 {
+				t.Errorf("没有记录的日期 LastID() = %d, 期望 0", lastID)
+			}
+
+			recovered, err := store.MarkInterrupted("服务重启")
+			if err != nil || recovered != 2 {
+				t.Fatalf("MarkInterrupted() = (%d, %v), 期望 2", recovered, err)
+			}
+			tasks, _ = store.ListByDate(date)
+			for _, pt := range tasks {
+				if pt.ID == "000001" && (pt.Status != TaskFailed || pt.Result != "服务重启") {
+					t.Errorf("未结束的任务应标记为失败, got %+v", pt)
+				}
+			}
+
+			// 列表按创建时间升序，与写入顺序无关
+			day := time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)
+			mustSave(&PersistedTask{ID: "000002", Status: TaskFinished, CreatedAt: day.Add(10 * time.Hour)}, 2)
+			mustSave(&PersistedTask{ID: "000001", Status: TaskFinished, CreatedAt: day.Add(9 * time.Hour)}, 2)
+			mustSave(&PersistedTask{ID: "000001", Status: TaskFinished, CreatedAt: day.AddDate(0, 0, -1)}, 1)
+			ids := func(tasks []*PersistedTask) string {
+				var parts []string
+				for _, pt := range tasks {
+					parts = append(parts, pt.CreatedAt.Format("01-02 15")+" "+pt.ID)
+				}
+				return strings.Join(parts, ",")
+			}
+			tasks, _ = store.ListByDate("2026-01-02")
+			if got, want := ids(tasks), "01-02 09 000001,01-02 10 000002"; got != want {
+				t.Errorf("ListByDate() = %s, 期望 %s", got, want)
+			}
+			tasks, _ = store.ListRange("2026-01-01", "2026-01-02")
+			if got, want := ids(tasks), "01-01 00 000001,01-02 09 000001,01-02 10 000002"; got != want {
+				t.Errorf("ListRange() = %s, 期望 %s", got, want)
+			}
+		})
+	}
+
+	t.Run("不支持的存储类型", func(t *testing.T) {
+		if _, err := newTaskStore("redis", t.TempDir(), zap.NewNop()); err == nil {
+			t.Error("不支持的存储类型应返回错误")
+		}
+	})
+}
+
+// TestAgentTaskManager_SQLiteStore 测试使用 SQLite 存储时重启后的任务查询
+func TestAgentTaskManager_SQLiteStore(t *testing.T) {
+	workspace := t.TempDir()
+	cfg := &AgentTaskManagerConfig{Workspace: workspace, Logger: zap.NewNop(), Store: TaskStoreSQLite}
+	first, err := NewBackgroundAgentTaskManager(cfg)
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+	first.taskCounter = 7
+	first.persistTask(&AgentTask{id: "000007", status: TaskFinished, result: "完成", logCapacity: 10, createdAt: time.Now()})
+	first.Close()
+
+	second, err := NewBackgroundAgentTaskManager(cfg)
+	if err != nil {
+		t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+	}
+	defer second.Close()
+
+	if second.taskCounter != 7 {
+		t.Errorf("taskCounter = %d, 期望从存储恢复为 7", second.taskCounter)
+	}
+	info, err := second.GetTask("7")
+	if err != nil || info.Status != TaskFinished {
+		t.Fatalf("GetTask() = (%+v, %v)", info, err)
+	}
+	items, err := second.ListTasks()
+	if err != nil || len(items) != 1 {
+		t.Errorf("ListTasks() = (%d, %v), 期望 1 个当天已完成的任务", len(items), err)
+	}
+}
+
+// TestAgentTaskManager_ListTasksFiltered 测试按日期范围和状态筛选任务
+func TestAgentTaskManager_ListTasksFiltered(t *testing.T) {
+	for _, kind := range []string{TaskStoreYAML, TaskStoreSQLite} {
+		t.Run(kind, func(t *testing.T) {
+			m, err := NewBackgroundAgentTaskManager(&AgentTaskManagerConfig{Workspace: t.TempDir(), Logger: zap.NewNop(), Store: kind})
+			if err != nil {
+				t.Fatalf("NewBackgroundAgentTaskManager() 返回错误: %v", err)
+			}
+			defer m.Close()
+
+			now := time.Now()
+			yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 10, 0, 0, 0, time.Local)
+			lastWeek := now.AddDate(0, 0, -7)
+			m.persistTask(&AgentTask{id: "000001", status: TaskFinished, createdAt: lastWeek})
+			m.persistTask(&AgentTask{id: "000001", status: TaskFailed, createdAt: yesterday})
+			m.persistTask(&AgentTask{id: "000002", status: TaskFinished, createdAt: yesterday.Add(time.Minute)})
+			m.persistTask(&AgentTask{id: "000001", status: TaskRunning, createdAt: now})
+			m.runningTasks["000001"] = &AgentTask{id: "000001", status: TaskRunning, createdAt: now}
+
+			items, err := m.ListTasksFiltered(yesterday, yesterday, "")
+			if err != nil {
+				t.Fatalf("ListTasksFiltered() 返回错误: %v", err)
+			}
+			if len(items) != 2 || items[0].Status != TaskFailed || items[1].ID != "000002" {
+				t.Errorf("昨天的任务 = %+v, 期望按创建时间排序的 2 个任务", items)
+			}
+
+			items, _ = m.ListTasksFiltered(lastWeek, now, TaskFinished)
+			if len(items) != 2 {
+				t.Errorf("已完成任务数 = %d, 期望 2", len(items))
+			}
+
+			items, _ = m.ListTasksFiltered(now, now, "")
+			if len(items) != 1 || items[0].Status != TaskRunning {
+				t.Errorf("今天的任务 = %+v, 期望只包含内存中运行的任务", items)
+			}
+
+			if _, err := m.ListTasksFiltered(now, yesterday, ""); err == nil {
+				t.Error("开始日期晚于结束日期时应返回错误")
+			}
+		})
+	}
+}
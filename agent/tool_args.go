@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/weibaohui/nanobot-go/utils"
+)
+
+// maxEchoedArgs 参数错误反馈中回显的原始参数最大长度（字符）
+const maxEchoedArgs = 500
+
+// checkToolArgs 校验工具调用参数是合法的 JSON 对象
+func checkToolArgs(args string) error {
+	var obj map[string]any
+	return json.Unmarshal([]byte(args), &obj)
+}
+
+// repairToolArgs 尝试修复模型常见的 JSON 格式问题：代码块包裹、单引号字符串、多余的结尾逗号
+// 修复后是合法的 JSON 对象时返回修复结果，否则返回 false
+// 不补齐残缺的括号，被截断的参数需要重新生成
+func repairToolArgs(args string) (string, bool) {
+	fixed := stripCodeFence(args)
+	fixed = normalizeJSONSyntax(fixed)
+	if fixed == args || checkToolArgs(fixed) != nil {
+		return args, false
+	}
+	return fixed, true
+}
+
+// stripCodeFence 去掉包裹 JSON 的 Markdown 代码块
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") || len(s) < 6 {
+		return s
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "```"), "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 && !strings.ContainsAny(s[:i], "{[") {
+		s = s[i+1:] // 去掉语言标记，如 ```json
+	}
+	return strings.TrimSpace(s)
+}
+
+// normalizeJSONSyntax 把单引号字符串转换为双引号字符串，并去掉 } 和 ] 前多余的逗号
+func normalizeJSONSyntax(s string) string {
+	var sb strings.Builder
+	var quote byte // 当前所在字符串的引号，0 表示不在字符串中
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0 && ch == '\\' && i+1 < len(s):
+			next := s[i+1]
+			i++
+			if quote == '\'' && next == '\'' {
+				sb.WriteByte('\'') // 单引号字符串中的 \' 在 JSON 中不需要转义
+				continue
+			}
+			sb.WriteByte(ch)
+			sb.WriteByte(next)
+		case quote != 0 && ch == quote:
+			quote = 0
+			sb.WriteByte('"')
+		case quote == '\'' && ch == '"':
+			sb.WriteString(`\"`)
+		case quote != 0:
+			sb.WriteByte(ch)
+		case ch == '"' || ch == '\'':
+			quote = ch
+			sb.WriteByte('"')
+		case ch == ',' && closesNext(s[i+1:]):
+			// 跳过结尾逗号
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return sb.String()
+}
+
+// closesNext 判断跳过空白后的下一个字符是否为 } 或 ]
+func closesNext(rest string) bool {
+	rest = strings.TrimLeft(rest, " \t\r\n")
+	return rest != "" && (rest[0] == '}' || rest[0] == ']')
+}
+
+// invalidToolArgsMiddleware 工具参数校验中间件
+// 重新生成后仍不是合法 JSON 的参数不交给工具执行，而是把解析错误作为工具结果返回，让模型修正后重新调用
+func invalidToolArgsMiddleware() compose.ToolMiddleware {
+	return compose.ToolMiddleware{
+		Invokable: func(next compose.InvokableToolEndpoint) compose.InvokableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+				err := checkToolArgs(input.Arguments)
+				if err == nil || strings.TrimSpace(input.Arguments) == "" {
+					return next(ctx, input)
+				}
+				return &compose.ToolOutput{
+					Result: fmt.Sprintf("错误: 工具 %s 的参数不是合法的 JSON（%v），未执行。\n收到的参数: %s\n请输出一个合法的 JSON 对象作为参数后重新调用。",
+						input.Name, err, utils.TruncateString(input.Arguments, maxEchoedArgs)),
+				}, nil
+			}
+		},
+	}
+}
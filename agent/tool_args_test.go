@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// TestRepairToolArgs 测试修复常见的 JSON 格式问题
+func TestRepairToolArgs(t *testing.T) {
+	cases := []struct {
+		name string
+		args string
+		want string
+		ok   bool
+	}{
+		{"结尾逗号", `{"path": "a.txt", "lines": [1, 2,],}`, `{"path": "a.txt", "lines": [1, 2]}`, true},
+		{"单引号字符串", `{'path': 'it\'s "a".txt'}`, `{"path": "it's \"a\".txt"}`, true},
+		{"代码块包裹", "```json\n{\"path\": \"a.txt\"}\n```", `{"path": "a.txt"}`, true},
+		{"字符串中的逗号和引号不变", `{"text": "a,}", 'b': "x'y"}`, `{"text": "a,}", "b": "x'y"}`, true},
+		{"残缺的参数不修复", `{"path": "a.t`, "", false},
+		{"不是对象", `['a.txt']`, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := repairToolArgs(tc.args)
+			if ok != tc.ok || (ok && got != tc.want) {
+				t.Errorf("repairToolArgs(%s) = %s, %v, 期望 %s, %v", tc.args, got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+// TestInvalidToolArgsMiddleware 测试参数不是合法 JSON 时把错误反馈给模型
+func TestInvalidToolArgsMiddleware(t *testing.T) {
+	var called int
+	endpoint := invalidToolArgsMiddleware().Invokable(
+		func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+			called++
+			return &compose.ToolOutput{Result: "ok"}, nil
+		},
+	)
+	ctx := context.Background()
+
+	output, err := endpoint(ctx, &compose.ToolInput{Name: "read_file", Arguments: `{"path":`})
+	if err != nil {
+		t.Fatalf("参数错误应反馈为结果，得到错误: %v", err)
+	}
+	if called != 0 {
+		t.Error("参数不合法时不应执行工具")
+	}
+	if !strings.HasPrefix(output.Result, "错误: 工具 read_file 的参数不是合法的 JSON") || !strings.Contains(output.Result, `收到的参数: {"path":`) {
+		t.Errorf("Result = %q", output.Result)
+	}
+
+	for _, args := range []string{`{"path": "a.txt"}`, ""} {
+		if output, _ := endpoint(ctx, &compose.ToolInput{Name: "read_file", Arguments: args}); output.Result != "ok" {
+			t.Errorf("参数 %q 应正常执行, Result = %q", args, output.Result)
+		}
+	}
+}
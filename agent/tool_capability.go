@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// toolsUnsupportedMarkers 提供商返回“不支持工具调用”时错误信息中的常见片段（小写）
+var toolsUnsupportedMarkers = []string{
+	"does not support tools",
+	"does not support tool",
+	"does not support function calling",
+	"tools is not supported",
+	"tools are not supported",
+	"tool calling is not supported",
+	"tool use is not supported",
+	"function calling is not supported",
+	"'tools' is not supported",
+	"\"tools\" is not supported",
+	"unrecognized request argument supplied: tools",
+	"unknown parameter: tools",
+	"extra inputs are not permitted: tools",
+	"不支持工具调用",
+	"不支持函数调用",
+}
+
+// isToolsUnsupportedError 判断错误是否表示模型不支持工具调用
+func isToolsUnsupportedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range toolsUnsupportedMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolCallingUnsupported 运行中探测到不支持工具调用的模型，进程内共享，重启后重新探测
+var toolCallingUnsupported sync.Map
+
+// markToolCallingUnsupported 记录模型不支持工具调用
+func markToolCallingUnsupported(model string) {
+	toolCallingUnsupported.Store(model, true)
+}
+
+// toolCallingDisabled 返回运行中是否已探测到模型不支持工具调用
+func toolCallingDisabled(model string) bool {
+	_, ok := toolCallingUnsupported.Load(model)
+	return ok
+}
+
+// flattenToolMessages 把工具调用和工具结果转换为普通文本消息
+// 不带工具请求不支持工具调用的模型时，历史中结构化的工具消息同样会被拒绝
+func flattenToolMessages(input []*schema.Message) []*schema.Message {
+	result := make([]*schema.Message, 0, len(input))
+	for _, msg := range input {
+		switch {
+		case msg.Role == schema.Tool:
+			name := msg.ToolName
+			if name == "" {
+				name = "工具"
+			}
+			result = append(result, schema.UserMessage(fmt.Sprintf("[%s 返回结果]\n%s", name, msg.Content)))
+		case msg.Role == schema.Assistant && len(msg.ToolCalls) > 0:
+			var sb strings.Builder
+			sb.WriteString(msg.Content)
+			for _, tc := range msg.ToolCalls {
+				if sb.Len() > 0 {
+					sb.WriteString("\n")
+				}
+				fmt.Fprintf(&sb, "[调用工具 %s] %s", tc.Function.Name, tc.Function.Arguments)
+			}
+			flat := *msg
+			flat.Content = sb.String()
+			flat.ToolCalls = nil
+			result = append(result, &flat)
+		default:
+			result = append(result, msg)
+		}
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"go.uber.org/zap"
+)
+
+// errorChatModel 总是返回指定错误的模拟模型
+type errorChatModel struct {
+	err   error
+	calls int
+}
+
+func (m *errorChatModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.calls++
+	return nil, m.err
+}
+
+func (m *errorChatModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *errorChatModel) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	return m, nil
+}
+
+// TestIsToolsUnsupportedError 测试识别不支持工具调用的错误
+func TestIsToolsUnsupportedError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("model llama2 does not support tools"), true},
+		{errors.New(`400 Bad Request: "tools" is not supported for this model`), true},
+		{errors.New("Function calling is not supported by this model"), true},
+		{errors.New("rate limit exceeded"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isToolsUnsupportedError(tt.err); got != tt.want {
+			t.Errorf("isToolsUnsupportedError(%v) = %v, 期望 %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestFlattenToolMessages 测试把工具消息转换为普通文本
+func TestFlattenToolMessages(t *testing.T) {
+	call := schema.AssistantMessage("我来看看", []schema.ToolCall{{ID: "call_1", Function: schema.FunctionCall{Name: "read_file", Arguments: `{"path":"a.txt"}`}}})
+	result := schema.ToolMessage("文件内容", "call_1", schema.WithToolName("read_file"))
+	input := []*schema.Message{schema.UserMessage("看看 a.txt"), call, result}
+
+	got := flattenToolMessages(input)
+	if len(got) != 3 {
+		t.Fatalf("flattenToolMessages() 返回 %d 条消息, 期望 3", len(got))
+	}
+	if len(got[1].ToolCalls) != 0 || got[1].Content != "我来看看\n[调用工具 read_file] {\"path\":\"a.txt\"}" {
+		t.Errorf("助手消息 = %+v", got[1])
+	}
+	if got[2].Role != schema.User || got[2].Content != "[read_file 返回结果]\n文件内容" {
+		t.Errorf("工具结果 = %+v", got[2])
+	}
+	if len(call.ToolCalls) != 1 {
+		t.Error("不应修改原消息")
+	}
+}
+
+// TestChatModelAdapter_ToolCallingFallback 测试模型不支持工具调用时去掉工具请求
+func TestChatModelAdapter_ToolCallingFallback(t *testing.T) {
+	tools := []*schema.ToolInfo{{Name: "read_file"}}
+	input := []*schema.Message{schema.UserMessage("你好")}
+	reply := func() *schema.Message { return schema.AssistantMessage("你好！", nil) }
+	unsupported := errors.New(`error, status code: 400, message: registry.ollama.ai/library/gemma:2b does not support tools`)
+
+	t.Run("探测到不支持后停用工具", func(t *testing.T) {
+		bound := &errorChatModel{err: unsupported}
+		base := &countingChatModel{response: reply}
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), chatModel: bound, baseModel: base, modelName: "gemma:2b-probe", tools: tools}
+
+		got, err := adapter.Generate(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Generate() 返回错误: %v", err)
+		}
+		if got.Content != "你好！" || bound.calls != 1 || base.calls != 1 {
+			t.Errorf("Generate() = %q, 带工具调用 %d 次, 不带工具调用 %d 次", got.Content, bound.calls, base.calls)
+		}
+		if !toolCallingDisabled("gemma:2b-probe") {
+			t.Error("应记录模型不支持工具调用")
+		}
+
+		// 之后的请求直接不带工具
+		adapter.Generate(context.Background(), input)
+		if bound.calls != 1 || base.calls != 2 {
+			t.Errorf("带工具调用 %d 次, 不带工具调用 %d 次, 期望 1 和 2", bound.calls, base.calls)
+		}
+	})
+
+	t.Run("配置声明不支持", func(t *testing.T) {
+		bound := &errorChatModel{err: unsupported}
+		base := &countingChatModel{response: reply}
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), chatModel: bound, baseModel: base, modelName: "gemma:2b-config", tools: tools, noToolCalling: true}
+
+		if _, err := adapter.Generate(context.Background(), input); err != nil {
+			t.Fatalf("Generate() 返回错误: %v", err)
+		}
+		if bound.calls != 0 || base.calls != 1 {
+			t.Errorf("带工具调用 %d 次, 不带工具调用 %d 次, 期望 0 和 1", bound.calls, base.calls)
+		}
+	})
+
+	t.Run("其他错误照常返回", func(t *testing.T) {
+		base := &countingChatModel{response: reply}
+		adapter := &ChatModelAdapter{logger: zap.NewNop(), chatModel: &errorChatModel{err: errors.New("rate limit")}, baseModel: base, modelName: "gpt-4o", tools: tools}
+
+		if _, err := adapter.Generate(context.Background(), input); err == nil {
+			t.Error("Generate() 应返回错误")
+		}
+		if base.calls != 0 || toolCallingDisabled("gpt-4o") {
+			t.Error("其他错误不应停用工具")
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// ToolStats 单个工具的调用统计
+type ToolStats struct {
+	Name         string        `json:"name"`          // 工具名称
+	Calls        int64         `json:"calls"`         // 调用次数
+	Failures     int64         `json:"failures"`      // 失败次数，包括返回错误和返回 "错误: ..." 结果
+	TotalLatency time.Duration `json:"total_latency"` // 累计耗时
+	MaxLatency   time.Duration `json:"max_latency"`   // 最长单次耗时
+}
+
+// AvgLatency 返回平均耗时
+func (s ToolStats) AvgLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Calls)
+}
+
+// ErrorRate 返回失败比例（0-1）
+func (s ToolStats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Calls)
+}
+
+// toolMetrics 进程内所有 Agent 共享的工具调用统计
+var toolMetrics = struct {
+	mu    sync.Mutex
+	stats map[string]*ToolStats
+}{stats: make(map[string]*ToolStats)}
+
+// recordToolCall 记录一次工具调用
+func recordToolCall(name string, latency time.Duration, failed bool) {
+	toolMetrics.mu.Lock()
+	defer toolMetrics.mu.Unlock()
+	stats, ok := toolMetrics.stats[name]
+	if !ok {
+		stats = &ToolStats{Name: name}
+		toolMetrics.stats[name] = stats
+	}
+	stats.Calls++
+	if failed {
+		stats.Failures++
+	}
+	stats.TotalLatency += latency
+	stats.MaxLatency = max(stats.MaxLatency, latency)
+}
+
+// ToolStatsSnapshot 返回各工具的累计调用统计，按工具名称排序
+func ToolStatsSnapshot() []ToolStats {
+	toolMetrics.mu.Lock()
+	defer toolMetrics.mu.Unlock()
+	snapshot := make([]ToolStats, 0, len(toolMetrics.stats))
+	for _, stats := range toolMetrics.stats {
+		snapshot = append(snapshot, *stats)
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Name < snapshot[j].Name })
+	return snapshot
+}
+
+// isToolFailure 判断工具调用是否失败
+// 中断（如 ask_user）和用户取消不计为失败，超时计为失败
+func isToolFailure(output *compose.ToolOutput, err error) bool {
+	if err != nil {
+		if _, ok := compose.IsInterruptRerunError(err); ok || isInterruptError(err) {
+			return false
+		}
+		return !errors.Is(err, context.Canceled)
+	}
+	return output != nil && strings.HasPrefix(strings.TrimSpace(output.Result), "错误")
+}
+
+// toolMetricsMiddleware 工具调用统计中间件，记录每个工具的调用次数、耗时和失败次数
+func toolMetricsMiddleware() compose.ToolMiddleware {
+	return compose.ToolMiddleware{
+		Invokable: func(next compose.InvokableToolEndpoint) compose.InvokableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+				start := time.Now()
+				markToolExecuted(ctx)
+				output, err := next(ctx, input)
+				recordToolCall(input.Name, time.Since(start), isToolFailure(output, err))
+				return output, err
+			}
+		},
+	}
+}
+
+// toolActivityKey context 中记录本次执行是否调用过工具的 key
+type toolActivityKey struct{}
+
+// withToolActivity 为一次执行注入工具调用标记，供调用方判断能否安全重放整个执行
+func withToolActivity(ctx context.Context) context.Context {
+	return context.WithValue(ctx, toolActivityKey{}, new(atomic.Bool))
+}
+
+// markToolExecuted 标记当前执行已调用工具
+func markToolExecuted(ctx context.Context) {
+	if executed, ok := ctx.Value(toolActivityKey{}).(*atomic.Bool); ok {
+		executed.Store(true)
+	}
+}
+
+// toolExecuted 返回当前执行是否调用过工具
+func toolExecuted(ctx context.Context) bool {
+	executed, ok := ctx.Value(toolActivityKey{}).(*atomic.Bool)
+	return ok && executed.Load()
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/compose"
+)
+
+// toolStatsByName 从统计快照中查找指定工具
+func toolStatsByName(name string) (ToolStats, bool) {
+	for _, stats := range ToolStatsSnapshot() {
+		if stats.Name == name {
+			return stats, true
+		}
+	}
+	return ToolStats{}, false
+}
+
+// TestToolMetricsMiddleware 测试工具调用统计
+func TestToolMetricsMiddleware(t *testing.T) {
+	results := []struct {
+		result string
+		err    error
+	}{
+		{"ok", nil},
+		{"错误: 文件不存在", nil},
+		{"", context.DeadlineExceeded},
+		{"", context.Canceled},
+		{"", errors.New("INTERRUPT: 需要用户输入")},
+	}
+	call := 0
+	endpoint := toolMetricsMiddleware().Invokable(
+		func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+			r := results[call]
+			call++
+			time.Sleep(time.Millisecond)
+			if r.err != nil {
+				return nil, r.err
+			}
+			return &compose.ToolOutput{Result: r.result}, nil
+		},
+	)
+
+	input := &compose.ToolInput{Name: "metrics_test_tool", Arguments: "{}"}
+	for range results {
+		endpoint(context.Background(), input)
+	}
+
+	stats, ok := toolStatsByName("metrics_test_tool")
+	if !ok {
+		t.Fatal("快照中应包含 metrics_test_tool")
+	}
+	if stats.Calls != 5 {
+		t.Errorf("Calls = %d, 期望 5", stats.Calls)
+	}
+	// 错误结果和超时计为失败，取消和中断不计
+	if stats.Failures != 2 {
+		t.Errorf("Failures = %d, 期望 2", stats.Failures)
+	}
+	if stats.ErrorRate() != 0.4 {
+		t.Errorf("ErrorRate() = %v, 期望 0.4", stats.ErrorRate())
+	}
+	if stats.AvgLatency() < time.Millisecond || stats.MaxLatency < stats.AvgLatency() {
+		t.Errorf("AvgLatency() = %v, MaxLatency = %v", stats.AvgLatency(), stats.MaxLatency)
+	}
+}
+
+// TestFormatToolStats 测试工具统计的展示格式
+func TestFormatToolStats(t *testing.T) {
+	if got := formatToolStats(nil); got != "暂无工具调用记录" {
+		t.Errorf("formatToolStats(nil) = %q", got)
+	}
+
+	got := formatToolStats([]ToolStats{{Name: "web_fetch", Calls: 4, Failures: 1, TotalLatency: 2 * time.Second, MaxLatency: 1500 * time.Millisecond}})
+	want := "web_fetch - 调用 4 次，平均耗时 500ms，最长 1.5s，失败 1 次（25%）"
+	if !strings.Contains(got, want) {
+		t.Errorf("formatToolStats() = %q, 应包含 %q", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/cloudwego/eino/compose"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"go.uber.org/zap"
+)
+
+// toolErrorBudget 单个回合内允许的工具错误重试预算
+// 工具出错且预算未用完时，错误会作为工具结果反馈给模型，让模型调整参数后重试
+type toolErrorBudget struct {
+	limit int32
+	used  atomic.Int32
+	total *atomic.Int64 // 累计重试次数（跨回合统计）
+}
+
+// toolErrorBudgetKey context 中存储工具错误预算的 key
+type toolErrorBudgetKey struct{}
+
+// withToolErrorBudget 为当前回合注入工具错误重试预算，limit 小于等于 0 时不注入
+func withToolErrorBudget(ctx context.Context, limit int, total *atomic.Int64) context.Context {
+	if limit <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, toolErrorBudgetKey{}, &toolErrorBudget{limit: int32(limit), total: total})
+}
+
+// toolErrorBudgetFrom 从 context 获取工具错误重试预算
+func toolErrorBudgetFrom(ctx context.Context) *toolErrorBudget {
+	budget, _ := ctx.Value(toolErrorBudgetKey{}).(*toolErrorBudget)
+	return budget
+}
+
+// take 占用一次重试机会，预算用完返回 false
+func (b *toolErrorBudget) take() (int32, bool) {
+	used := b.used.Add(1)
+	if used > b.limit {
+		return used - 1, false
+	}
+	if b.total != nil {
+		b.total.Add(1)
+	}
+	return used, true
+}
+
+// isRecoverableToolError 判断工具错误是否可以反馈给模型重试
+// 中断（如 ask_user）和 context 取消/超时需要原样向上传递
+func isRecoverableToolError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := compose.IsInterruptRerunError(err); ok || isInterruptError(err) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// toolErrorRetryMiddleware 工具错误重试中间件
+// 回合内工具出现可恢复错误时，将错误作为结果返回给模型，超过预算则中止本轮并给出说明
+func toolErrorRetryMiddleware(logger *zap.Logger) compose.ToolMiddleware {
+	return compose.ToolMiddleware{
+		Invokable: func(next compose.InvokableToolEndpoint) compose.InvokableToolEndpoint {
+			return func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+				output, err := next(ctx, input)
+				if !isRecoverableToolError(err) {
+					return output, err
+				}
+				budget := toolErrorBudgetFrom(ctx)
+				if budget == nil {
+					return output, err
+				}
+
+				used, ok := budget.take()
+				if !ok {
+					return nil, fmt.Errorf("工具 %s 多次执行失败（已重试 %d 次），本轮已中止，最后一次错误: %w", input.Name, used, err)
+				}
+
+				logger.Warn("工具执行失败，将错误反馈给模型重试",
+					zap.String("tool", input.Name),
+					trace.LogField(ctx),
+					zap.Int32("retry", used),
+					zap.Int32("limit", budget.limit),
+					zap.Error(err),
+				)
+				return &compose.ToolOutput{
+					Result: fmt.Sprintf("错误: 工具 %s 执行失败: %v\n请根据错误信息调整参数后重试，或改用其他方法（剩余重试次数 %d）。", input.Name, err, budget.limit-used),
+				}, nil
+			}
+		},
+	}
+}
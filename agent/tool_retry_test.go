@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cloudwego/eino/compose"
+	"go.uber.org/zap"
+)
+
+// TestToolErrorRetryMiddleware 测试工具错误反馈重试
+func TestToolErrorRetryMiddleware(t *testing.T) {
+	toolErr := errors.New("search backend timeout")
+	endpoint := toolErrorRetryMiddleware(zap.NewNop()).Invokable(
+		func(ctx context.Context, input *compose.ToolInput) (*compose.ToolOutput, error) {
+			return nil, toolErr
+		},
+	)
+	input := &compose.ToolInput{Name: "web_search", Arguments: "{}"}
+
+	t.Run("预算内错误反馈给模型", func(t *testing.T) {
+		var total atomic.Int64
+		ctx := withToolErrorBudget(context.Background(), 2, &total)
+
+		for i := 0; i < 2; i++ {
+			output, err := endpoint(ctx, input)
+			if err != nil {
+				t.Fatalf("第 %d 次应反馈为结果，得到错误: %v", i+1, err)
+			}
+			if !strings.HasPrefix(output.Result, "错误: 工具 web_search 执行失败") {
+				t.Errorf("Result = %q", output.Result)
+			}
+		}
+
+		_, err := endpoint(ctx, input)
+		if err == nil || !errors.Is(err, toolErr) {
+			t.Fatalf("超过预算应中止本轮，得到: %v", err)
+		}
+		if !strings.Contains(err.Error(), "已重试 2 次") {
+			t.Errorf("错误信息应包含重试次数: %v", err)
+		}
+		if total.Load() != 2 {
+			t.Errorf("累计重试次数 = %d, 期望 2", total.Load())
+		}
+	})
+
+	t.Run("未设置预算时错误原样返回", func(t *testing.T) {
+		if _, err := endpoint(context.Background(), input); !errors.Is(err, toolErr) {
+			t.Errorf("期望原始错误，得到: %v", err)
+		}
+	})
+
+	t.Run("中断和取消不可恢复", func(t *testing.T) {
+		if isRecoverableToolError(context.Canceled) {
+			t.Error("context 取消不应重试")
+		}
+		if isRecoverableToolError(errors.New("INTERRUPT: 需要用户输入")) {
+			t.Error("中断错误不应重试")
+		}
+		if !isRecoverableToolError(toolErr) {
+			t.Error("普通工具错误应可重试")
+		}
+	})
+}
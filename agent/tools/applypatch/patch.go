@@ -0,0 +1,267 @@
+package applypatch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderPattern 匹配 hunk 头，如 "@@ -1,3 +1,4 @@"
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// filePatch 单个文件的补丁
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []*hunk
+}
+
+// hunk 补丁中的一个修改块
+type hunk struct {
+	oldStart int
+	oldLines []string // 修改前的内容（上下文 + 删除行）
+	newLines []string // 修改后的内容（上下文 + 新增行）
+	// 修改前/后的最后一行是否没有换行符
+	oldNoNewline bool
+	newNoNewline bool
+}
+
+// isCreate 是否为新建文件
+func (p *filePatch) isCreate() bool {
+	return p.oldPath == ""
+}
+
+// isDelete 是否为删除文件
+func (p *filePatch) isDelete() bool {
+	return p.newPath == ""
+}
+
+// targetPath 返回补丁作用的文件路径
+func (p *filePatch) targetPath() string {
+	if p.isDelete() {
+		return p.oldPath
+	}
+	return p.newPath
+}
+
+// parsePatch 解析统一 diff 格式的补丁
+func parsePatch(text string) ([]*filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var patches []*filePatch
+	var current *filePatch
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			current = &filePatch{
+				oldPath: parseFileHeader(line[4:]),
+				newPath: parseFileHeader(lines[i+1][4:]),
+			}
+			if current.oldPath == "" && current.newPath == "" {
+				return nil, fmt.Errorf("第 %d 行: 文件头缺少路径", i+1)
+			}
+			patches = append(patches, current)
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("第 %d 行: hunk 之前缺少文件头", i+1)
+			}
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			current.hunks = append(current.hunks, h)
+			i = next - 1
+		}
+		// 其他行（diff --git、index 等）忽略
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("补丁中没有找到文件修改")
+	}
+	for _, p := range patches {
+		if len(p.hunks) == 0 && !p.isDelete() {
+			return nil, fmt.Errorf("文件 %s 的补丁没有 hunk", p.targetPath())
+		}
+	}
+	return patches, nil
+}
+
+// parseFileHeader 解析文件头中的路径，去掉 a/、b/ 前缀和时间戳
+func parseFileHeader(header string) string {
+	path := header
+	if idx := strings.IndexByte(path, '\t'); idx >= 0 {
+		path = path[:idx]
+	}
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		path = path[2:]
+	}
+	return path
+}
+
+// parseHunk 解析从 start 行开始的 hunk，返回 hunk 和下一个待处理行号
+func parseHunk(lines []string, start int) (*hunk, int, error) {
+	m := hunkHeaderPattern.FindStringSubmatch(lines[start])
+	if m == nil {
+		return nil, 0, fmt.Errorf("第 %d 行: 无效的 hunk 头: %s", start+1, lines[start])
+	}
+	oldStart, _ := strconv.Atoi(m[1])
+	oldCount := parseCount(m[2])
+	newCount := parseCount(m[4])
+
+	h := &hunk{oldStart: oldStart}
+	oldSeen, newSeen := 0, 0
+	lastKind := byte(0)
+	i := start + 1
+	for ; i < len(lines) && (oldSeen < oldCount || newSeen < newCount); i++ {
+		line := lines[i]
+		if line == "" {
+			// 部分编辑器会去掉空上下文行前的空格
+			line = " "
+		}
+		switch line[0] {
+		case ' ':
+			h.oldLines = append(h.oldLines, line[1:])
+			h.newLines = append(h.newLines, line[1:])
+			oldSeen++
+			newSeen++
+		case '-':
+			h.oldLines = append(h.oldLines, line[1:])
+			oldSeen++
+		case '+':
+			h.newLines = append(h.newLines, line[1:])
+			newSeen++
+		case '\\':
+			h.markNoNewline(lastKind)
+			continue
+		default:
+			return nil, 0, fmt.Errorf("第 %d 行: 无效的 hunk 内容: %s", i+1, line)
+		}
+		lastKind = line[0]
+	}
+	if oldSeen != oldCount || newSeen != newCount {
+		return nil, 0, fmt.Errorf("第 %d 行: hunk 行数与头部声明不一致", start+1)
+	}
+	// 处理紧跟在 hunk 末尾的 "\ No newline at end of file"
+	if i < len(lines) && strings.HasPrefix(lines[i], "\\") {
+		h.markNoNewline(lastKind)
+		i++
+	}
+	return h, i, nil
+}
+
+// markNoNewline 根据上一行的类型标记缺少结尾换行
+func (h *hunk) markNoNewline(kind byte) {
+	switch kind {
+	case '-':
+		h.oldNoNewline = true
+	case '+':
+		h.newNoNewline = true
+	default:
+		h.oldNoNewline = true
+		h.newNoNewline = true
+	}
+}
+
+// parseCount 解析 hunk 头中的行数，省略时为 1
+func parseCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// applyHunks 将 hunk 应用到文件内容，任一 hunk 无法精确匹配时返回错误
+func applyHunks(content string, hunks []*hunk) (string, error) {
+	lines, hasTrailingNewline := splitContent(content)
+	noNewline := !hasTrailingNewline
+
+	offset := 0
+	minPos := 0
+	for idx, h := range hunks {
+		expected := h.oldStart - 1 + offset
+		if len(h.oldLines) == 0 {
+			// 纯新增的 hunk，oldStart 表示插入位置之前的行
+			expected = h.oldStart + offset
+		}
+		pos := findHunk(lines, h.oldLines, expected, minPos)
+		if pos < 0 {
+			return "", fmt.Errorf("第 %d 个 hunk（原文件第 %d 行）无法匹配", idx+1, h.oldStart)
+		}
+		if pos+len(h.oldLines) == len(lines) {
+			// hunk 覆盖到文件末尾，结尾换行以补丁为准
+			noNewline = h.newNoNewline
+		}
+
+		updated := make([]string, 0, len(lines)-len(h.oldLines)+len(h.newLines))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, h.newLines...)
+		updated = append(updated, lines[pos+len(h.oldLines):]...)
+		lines = updated
+
+		offset += len(h.newLines) - len(h.oldLines)
+		minPos = pos + len(h.newLines)
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+	result := strings.Join(lines, "\n")
+	if !noNewline {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// findHunk 在期望位置附近查找与 hunk 原内容完全一致的位置
+func findHunk(lines, oldLines []string, expected, minPos int) int {
+	if expected < minPos {
+		expected = minPos
+	}
+	maxPos := len(lines) - len(oldLines)
+	if expected > maxPos {
+		expected = maxPos
+	}
+	for delta := 0; ; delta++ {
+		before, after := expected-delta, expected+delta
+		if before < minPos && after > maxPos {
+			return -1
+		}
+		if after <= maxPos && matchAt(lines, oldLines, after) {
+			return after
+		}
+		if before >= minPos && before != after && matchAt(lines, oldLines, before) {
+			return before
+		}
+	}
+}
+
+// matchAt 判断 lines 从 pos 开始是否与 oldLines 完全一致
+func matchAt(lines, oldLines []string, pos int) bool {
+	if pos < 0 || pos+len(oldLines) > len(lines) {
+		return false
+	}
+	for i, line := range oldLines {
+		if lines[pos+i] != line {
+			return false
+		}
+	}
+	return true
+}
+
+// splitContent 按行切分文件内容，返回是否以换行结尾
+func splitContent(content string) ([]string, bool) {
+	if content == "" {
+		return nil, true
+	}
+	hasTrailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	return lines, hasTrailingNewline
+}
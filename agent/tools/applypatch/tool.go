@@ -0,0 +1,211 @@
+package applypatch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+)
+
+// Tool 补丁应用工具
+// 以统一 diff 格式修改一个或多个文件，任一 hunk 无法应用时整个补丁都不会生效
+type Tool struct {
+	AllowedDir string
+	WorkingDir string // 补丁中相对路径的基准目录
+}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return "apply_patch"
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "应用统一 diff 格式（diff -u / git diff）的补丁，适合多行或多文件修改。所有 hunk 必须与文件内容完全匹配，否则整个补丁被拒绝",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"patch": {
+				Type:     schema.DataType("string"),
+				Desc:     "统一 diff 格式的补丁内容，包含 ---/+++ 文件头和 @@ hunk",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+// change 待写入的文件修改
+type change struct {
+	path     string
+	display  string
+	content  string
+	existed  bool
+	original string
+	remove   bool
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Patch string `json:"patch"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(args.Patch) == "" {
+		return "错误: 补丁内容不能为空", nil
+	}
+
+	patches, err := parsePatch(args.Patch)
+	if err != nil {
+		return fmt.Sprintf("错误: 解析补丁失败: %s", err), nil
+	}
+
+	// 先计算所有文件的新内容，全部成功后再写入
+	scoped := *t
+	scoped.WorkingDir, scoped.AllowedDir = common.ScopeDirs(ctx, t.WorkingDir, t.AllowedDir)
+	// 同一文件出现多次时，后面的补丁基于前面补丁的结果计算，合并为一次修改
+	changes := make([]*change, 0, len(patches))
+	pending := make(map[string]*change, len(patches))
+	for _, p := range patches {
+		c, err := scoped.prepare(p, pending)
+		if err != nil {
+			return fmt.Sprintf("错误: %s，补丁未应用", err), nil
+		}
+		if _, ok := pending[c.path]; !ok {
+			pending[c.path] = c
+			changes = append(changes, c)
+		}
+	}
+
+	if err := commit(changes); err != nil {
+		return fmt.Sprintf("错误: 写入文件失败: %s，已回滚", err), nil
+	}
+
+	var summary []string
+	for _, c := range changes {
+		switch {
+		case c.remove:
+			summary = append(summary, "删除 "+c.display)
+		case !c.existed:
+			summary = append(summary, "新建 "+c.display)
+		default:
+			summary = append(summary, "修改 "+c.display)
+		}
+	}
+	return fmt.Sprintf("成功应用补丁:\n%s", strings.Join(summary, "\n")), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// prepare 计算单个文件应用补丁后的内容
+// pending 中已有该文件的修改时基于修改后的内容计算，并更新该修改
+func (t *Tool) prepare(p *filePatch, pending map[string]*change) (*change, error) {
+	display := p.targetPath()
+	path := display
+	if !filepath.IsAbs(path) && t.WorkingDir != "" {
+		path = filepath.Join(t.WorkingDir, path)
+	}
+	resolved, err := common.ValidatePath(path, t.AllowedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ok := pending[resolved]
+	if !ok {
+		c = &change{path: resolved, display: display}
+		data, err := os.ReadFile(resolved)
+		switch {
+		case err == nil:
+			c.existed = true
+			c.original = string(data)
+			c.content = c.original
+		case os.IsNotExist(err):
+			c.remove = true
+		default:
+			return nil, fmt.Errorf("读取文件 %s 失败: %s", display, err)
+		}
+	}
+	exists := !c.remove
+	if !exists && !p.isCreate() {
+		return nil, fmt.Errorf("文件不存在: %s", display)
+	}
+	if p.isCreate() && exists {
+		return nil, fmt.Errorf("文件已存在，无法新建: %s", display)
+	}
+
+	content, err := applyHunks(c.content, p.hunks)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", display, err)
+	}
+	if p.isDelete() && content != "" {
+		return nil, fmt.Errorf("%s: 删除文件的补丁与文件内容不一致", display)
+	}
+	c.remove = p.isDelete()
+	c.content = content
+	return c, nil
+}
+
+// commit 写入所有修改，任一文件失败时恢复已写入的文件
+func commit(changes []*change) error {
+	for i, c := range changes {
+		if err := writeChange(c); err != nil {
+			for _, done := range changes[:i] {
+				rollback(done)
+			}
+			return fmt.Errorf("%s: %w", c.display, err)
+		}
+	}
+	return nil
+}
+
+// writeChange 写入单个文件修改，先写临时文件再重命名
+func writeChange(c *change) error {
+	if c.remove {
+		// 同一补丁中新建后又删除的文件本来就不存在
+		if err := os.Remove(c.path); err != nil && (c.existed || !os.IsNotExist(err)) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(c.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".patch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(c.content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// rollback 恢复文件到修改前的状态
+func rollback(c *change) {
+	if !c.existed {
+		_ = os.Remove(c.path)
+		return
+	}
+	_ = os.WriteFile(c.path, []byte(c.original), 0644)
+}
@@ -0,0 +1,188 @@
+package applypatch
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runPatch 执行补丁工具并返回结果
+func runPatch(t *testing.T, tool *Tool, patch string) string {
+	t.Helper()
+	data, _ := json.Marshal(map[string]string{"patch": patch})
+	result, err := tool.Run(context.Background(), string(data))
+	if err != nil {
+		t.Fatalf("Run() 返回错误: %v", err)
+	}
+	return result
+}
+
+// writeFile 写入测试文件
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile 读取测试文件
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestTool_Name 测试工具名称
+func TestTool_Name(t *testing.T) {
+	tool := &Tool{}
+	if tool.Name() != "apply_patch" {
+		t.Errorf("Name() = %q, 期望 apply_patch", tool.Name())
+	}
+}
+
+// TestTool_Run 测试应用补丁
+func TestTool_Run(t *testing.T) {
+	t.Run("多文件多 hunk", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.go"), "package a\n\nfunc A() {\n\treturn\n}\n\nfunc B() {\n\treturn\n}\n")
+		writeFile(t, filepath.Join(dir, "old.txt"), "bye\n")
+		tool := &Tool{AllowedDir: dir, WorkingDir: dir}
+
+		patch := `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -3,3 +3,4 @@
+ func A() {
++	println("a")
+ 	return
+ }
+@@ -7,3 +8,3 @@
+ func B() {
+-	return
++	println("b")
+ }
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+		result := runPatch(t, tool, patch)
+		if !strings.HasPrefix(result, "成功应用补丁") {
+			t.Fatalf("Run() = %q", result)
+		}
+		want := "package a\n\nfunc A() {\n\tprintln(\"a\")\n\treturn\n}\n\nfunc B() {\n\tprintln(\"b\")\n}\n"
+		if got := readFile(t, filepath.Join(dir, "a.go")); got != want {
+			t.Errorf("a.go = %q, 期望 %q", got, want)
+		}
+		if got := readFile(t, filepath.Join(dir, "new.txt")); got != "hello\nworld\n" {
+			t.Errorf("new.txt = %q", got)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+			t.Error("old.txt 应该被删除")
+		}
+	})
+
+	t.Run("行号偏移仍可应用", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.txt"), "x\ny\none\ntwo\nthree\n")
+		tool := &Tool{WorkingDir: dir}
+
+		result := runPatch(t, tool, "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+2\n")
+		if !strings.HasPrefix(result, "成功应用补丁") {
+			t.Fatalf("Run() = %q", result)
+		}
+		if got := readFile(t, filepath.Join(dir, "a.txt")); got != "x\ny\none\n2\nthree\n" {
+			t.Errorf("a.txt = %q", got)
+		}
+	})
+
+	t.Run("无结尾换行", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.txt"), "a\nb")
+		tool := &Tool{WorkingDir: dir}
+
+		result := runPatch(t, tool, "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n")
+		if !strings.HasPrefix(result, "成功应用补丁") {
+			t.Fatalf("Run() = %q", result)
+		}
+		if got := readFile(t, filepath.Join(dir, "a.txt")); got != "a\nc\n" {
+			t.Errorf("a.txt = %q", got)
+		}
+	})
+
+	t.Run("任一 hunk 失败时整体拒绝", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.txt"), "one\ntwo\n")
+		writeFile(t, filepath.Join(dir, "b.txt"), "three\n")
+		tool := &Tool{WorkingDir: dir}
+
+		patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+1\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-four\n+4\n"
+		result := runPatch(t, tool, patch)
+		if !strings.HasPrefix(result, "错误") {
+			t.Fatalf("Run() = %q, 期望返回错误", result)
+		}
+		if got := readFile(t, filepath.Join(dir, "a.txt")); got != "one\ntwo\n" {
+			t.Errorf("a.txt 不应被修改, 得到 %q", got)
+		}
+	})
+
+	t.Run("同一文件的多个补丁依次应用", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.txt"), "one\ntwo\nthree\n")
+		tool := &Tool{WorkingDir: dir}
+
+		patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+1\n" +
+			"--- a/a.txt\n+++ b/a.txt\n@@ -3 +3 @@\n-three\n+3\n"
+		result := runPatch(t, tool, patch)
+		if result != "成功应用补丁:\n修改 a.txt" {
+			t.Fatalf("Run() = %q", result)
+		}
+		if got := readFile(t, filepath.Join(dir, "a.txt")); got != "1\ntwo\n3\n" {
+			t.Errorf("a.txt = %q, 期望两个补丁都生效", got)
+		}
+	})
+
+	t.Run("后续补丁与前面补丁的结果不匹配时整体拒绝", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.txt"), "one\n")
+		tool := &Tool{WorkingDir: dir}
+
+		patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+1\n" +
+			"--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+uno\n"
+		result := runPatch(t, tool, patch)
+		if !strings.HasPrefix(result, "错误") {
+			t.Fatalf("Run() = %q, 期望返回错误", result)
+		}
+		if got := readFile(t, filepath.Join(dir, "a.txt")); got != "one\n" {
+			t.Errorf("a.txt 不应被修改, 得到 %q", got)
+		}
+	})
+
+	t.Run("超出允许目录", func(t *testing.T) {
+		dir := t.TempDir()
+		tool := &Tool{AllowedDir: dir, WorkingDir: dir}
+		result := runPatch(t, tool, "--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n")
+		if !strings.Contains(result, "不在允许的目录内") {
+			t.Errorf("Run() = %q, 期望拒绝", result)
+		}
+	})
+
+	t.Run("无效补丁", func(t *testing.T) {
+		tool := &Tool{}
+		result := runPatch(t, tool, "not a patch")
+		if !strings.HasPrefix(result, "错误: 解析补丁失败") {
+			t.Errorf("Run() = %q", result)
+		}
+	})
+}
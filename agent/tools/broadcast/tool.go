@@ -0,0 +1,194 @@
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	"github.com/weibaohui/nanobot-go/bus"
+)
+
+// defaultMaxTargets 单次广播默认最多发送的目标数
+const defaultMaxTargets = 20
+
+// Tool 广播工具，把同一条消息发送到多个渠道/聊天
+// 只能发送到配置的分组成员、联系人或 AllowedTargets 中的目标，防止被用来群发骚扰
+type Tool struct {
+	SendCallback   func(msg any) error       // 与消息工具相同的发送路径，返回渠道的发送结果
+	Groups         map[string][]string       // 命名分组，值为 "渠道:聊天ID" 形式的目标或联系人别名
+	AllowedTargets []string                  // 额外允许的目标，支持 "渠道:*" 允许该渠道下所有聊天
+	MaxTargets     int                       // 单次广播最多发送的目标数
+	Contacts       map[string]common.Contact // 联系人别名，按别名指定的目标视为已允许
+}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return "broadcast"
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	groups := make([]string, 0, len(t.Groups))
+	for name := range t.Groups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	groupDesc := "配置的目标分组名称"
+	if len(groups) > 0 {
+		groupDesc += "，可用分组: " + strings.Join(groups, ", ")
+	}
+
+	targetsDesc := "目标列表，每项为 \"渠道:聊天ID\"（如 \"feishu:oc_123\"）或联系人别名"
+	if len(t.Contacts) > 0 {
+		targetsDesc += "，可用联系人: " + strings.Join(common.ContactNames(t.Contacts), ", ")
+	}
+
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "把同一条消息发送给多个聊天，返回每个目标的发送结果。只能发送到配置允许的目标",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"content": {
+				Type:     schema.DataType("string"),
+				Desc:     "消息内容",
+				Required: true,
+			},
+			"targets": {
+				Type:     schema.DataType("array"),
+				Desc:     targetsDesc,
+				ElemInfo: &schema.ParameterInfo{Type: schema.String},
+			},
+			"group": {
+				Type: schema.DataType("string"),
+				Desc: groupDesc,
+			},
+		}),
+	}, nil
+}
+
+// target 广播目标
+type target struct {
+	channel string
+	chatID  string
+}
+
+func (t target) String() string {
+	return t.channel + ":" + t.chatID
+}
+
+// parseTarget 解析 "渠道:聊天ID" 形式的目标，聊天 ID 中可以包含冒号
+func parseTarget(s string) (target, bool) {
+	channel, chatID, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || channel == "" || chatID == "" {
+		return target{}, false
+	}
+	return target{channel: channel, chatID: chatID}, true
+}
+
+// resolveContact 按联系人别名查找目标
+func (t *Tool) resolveContact(name string) (target, bool) {
+	contact, ok := t.Contacts[strings.TrimSpace(name)]
+	if !ok {
+		return target{}, false
+	}
+	return target{channel: contact.Channel, chatID: contact.ChatID}, true
+}
+
+// allowed 判断目标是否在分组成员或允许列表中
+func (t *Tool) allowed(tg target) bool {
+	for _, members := range t.Groups {
+		if slices.Contains(members, tg.String()) {
+			return true
+		}
+	}
+	for _, entry := range t.AllowedTargets {
+		if entry == tg.String() || entry == tg.channel+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Content string   `json:"content"`
+		Targets []string `json:"targets"`
+		Group   string   `json:"group"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(args.Content) == "" {
+		return "错误: 消息内容不能为空", nil
+	}
+	if t.SendCallback == nil {
+		return "错误: 消息发送未配置", nil
+	}
+
+	raw := args.Targets
+	if args.Group != "" {
+		members, ok := t.Groups[args.Group]
+		if !ok {
+			return fmt.Sprintf("错误: 分组 %s 不存在", args.Group), nil
+		}
+		raw = append(raw, members...)
+	}
+	if len(raw) == 0 {
+		return "错误: 需要指定 targets 或 group", nil
+	}
+
+	var targets []target
+	seen := make(map[string]bool)
+	for _, s := range raw {
+		tg, isContact := t.resolveContact(s)
+		if !isContact {
+			var ok bool
+			if tg, ok = parseTarget(s); !ok {
+				return fmt.Sprintf("错误: 目标 %q 既不是联系人也不是 \"渠道:聊天ID\" 格式", s), nil
+			}
+		}
+		if seen[tg.String()] {
+			continue
+		}
+		seen[tg.String()] = true
+		if !isContact && !t.allowed(tg) {
+			return fmt.Sprintf("错误: 目标 %s 不在允许列表中", tg), nil
+		}
+		targets = append(targets, tg)
+	}
+
+	maxTargets := t.MaxTargets
+	if maxTargets <= 0 {
+		maxTargets = defaultMaxTargets
+	}
+	if len(targets) > maxTargets {
+		return fmt.Sprintf("错误: 目标数 %d 超过上限 %d", len(targets), maxTargets), nil
+	}
+
+	var sb strings.Builder
+	failed := 0
+	for _, tg := range targets {
+		if ctx.Err() != nil {
+			failed++
+			fmt.Fprintf(&sb, "✗ %s: 已取消\n", tg)
+			continue
+		}
+		if err := t.SendCallback(bus.NewOutboundMessage(tg.channel, tg.chatID, args.Content)); err != nil {
+			failed++
+			fmt.Fprintf(&sb, "✗ %s: %s\n", tg, err)
+			continue
+		}
+		fmt.Fprintf(&sb, "✓ %s\n", tg)
+	}
+	return fmt.Sprintf("已发送 %d/%d 个目标\n%s", len(targets)-failed, len(targets), sb.String()), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
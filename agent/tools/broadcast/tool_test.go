@@ -0,0 +1,110 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	"github.com/weibaohui/nanobot-go/bus"
+)
+
+// recorder 记录发送的目标，对指定渠道返回发送失败
+type recorder struct {
+	sent       []string
+	failOnChan string
+}
+
+func (r *recorder) send(msg any) error {
+	out := msg.(*bus.OutboundMessage)
+	if out.Channel == r.failOnChan {
+		return errors.New("渠道不可用")
+	}
+	r.sent = append(r.sent, out.Channel+":"+out.ChatID+"="+out.Content)
+	return nil
+}
+
+// TestTool_Run 测试广播到多个目标
+func TestTool_Run(t *testing.T) {
+	newTool := func(r *recorder) *Tool {
+		return &Tool{
+			SendCallback:   r.send,
+			Groups:         map[string][]string{"ops": {"feishu:oc_1", "matrix:!room:example.org"}},
+			AllowedTargets: []string{"dingtalk:*"},
+			Contacts:       map[string]common.Contact{"老板": {Channel: "feishu", ChatID: "ou_boss"}},
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("按分组发送", func(t *testing.T) {
+		r := &recorder{}
+		result, err := newTool(r).Run(ctx, `{"content": "开会了", "group": "ops"}`)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if !strings.HasPrefix(result, "已发送 2/2 个目标") {
+			t.Errorf("Run() = %q", result)
+		}
+		if strings.Join(r.sent, ",") != "feishu:oc_1=开会了,matrix:!room:example.org=开会了" {
+			t.Errorf("发送记录 = %v", r.sent)
+		}
+	})
+
+	t.Run("逐个返回发送结果", func(t *testing.T) {
+		r := &recorder{failOnChan: "matrix"}
+		result, _ := newTool(r).Run(ctx, `{"content": "hi", "targets": ["dingtalk:cid_9", "feishu:oc_1"], "group": "ops"}`)
+		if !strings.Contains(result, "已发送 2/3 个目标") || !strings.Contains(result, "✗ matrix:!room:example.org: 渠道不可用") {
+			t.Errorf("Run() = %q", result)
+		}
+		if len(r.sent) != 2 {
+			t.Errorf("重复目标应只发送一次, 发送记录 = %v", r.sent)
+		}
+	})
+
+	t.Run("拒绝不在允许列表中的目标", func(t *testing.T) {
+		r := &recorder{}
+		result, _ := newTool(r).Run(ctx, `{"content": "hi", "targets": ["feishu:oc_1", "feishu:oc_2"]}`)
+		if result != "错误: 目标 feishu:oc_2 不在允许列表中" {
+			t.Errorf("Run() = %q", result)
+		}
+		if len(r.sent) != 0 {
+			t.Error("校验失败时不应发送任何消息")
+		}
+	})
+
+	t.Run("按联系人别名发送", func(t *testing.T) {
+		r := &recorder{}
+		result, _ := newTool(r).Run(ctx, `{"content": "日报", "targets": ["老板", "dingtalk:cid_9"]}`)
+		if !strings.HasPrefix(result, "已发送 2/2 个目标") {
+			t.Errorf("Run() = %q", result)
+		}
+		if strings.Join(r.sent, ",") != "feishu:ou_boss=日报,dingtalk:cid_9=日报" {
+			t.Errorf("发送记录 = %v", r.sent)
+		}
+	})
+
+	t.Run("超过目标数上限", func(t *testing.T) {
+		tool := newTool(&recorder{})
+		tool.MaxTargets = 1
+		result, _ := tool.Run(ctx, `{"content": "hi", "group": "ops"}`)
+		if result != "错误: 目标数 2 超过上限 1" {
+			t.Errorf("Run() = %q", result)
+		}
+	})
+
+	t.Run("参数错误", func(t *testing.T) {
+		tool := newTool(&recorder{})
+		cases := map[string]string{
+			`{"content": "hi"}`:                      "错误: 需要指定 targets 或 group",
+			`{"content": "hi", "group": "dev"}`:      "错误: 分组 dev 不存在",
+			`{"content": "hi", "targets": ["oc_1"]}`: `错误: 目标 "oc_1" 既不是联系人也不是 "渠道:聊天ID" 格式`,
+			`{"content": " ", "group": "ops"}`:       "错误: 消息内容不能为空",
+		}
+		for args, want := range cases {
+			if result, _ := tool.Run(ctx, args); result != want {
+				t.Errorf("Run(%s) = %q, 期望 %q", args, result, want)
+			}
+		}
+	})
+}
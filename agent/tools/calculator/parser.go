@@ -0,0 +1,321 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// constants 支持的常量
+var constants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// function 数学函数定义
+type function struct {
+	minArgs int
+	maxArgs int
+	call    func(args []float64) (float64, error)
+}
+
+// functions 支持的函数
+var functions = map[string]function{
+	"sqrt": {1, 1, func(a []float64) (float64, error) {
+		if a[0] < 0 {
+			return 0, fmt.Errorf("sqrt 的参数不能为负数")
+		}
+		return math.Sqrt(a[0]), nil
+	}},
+	"abs": {1, 1, unary(math.Abs)},
+	"sin": {1, 1, unary(math.Sin)},
+	"cos": {1, 1, unary(math.Cos)},
+	"tan": {1, 1, unary(math.Tan)},
+	"ln": {1, 1, func(a []float64) (float64, error) {
+		if a[0] <= 0 {
+			return 0, fmt.Errorf("ln 的参数必须为正数")
+		}
+		return math.Log(a[0]), nil
+	}},
+	"log": {1, 2, func(a []float64) (float64, error) {
+		// log(x) 为常用对数，log(x, base) 为指定底数
+		if a[0] <= 0 {
+			return 0, fmt.Errorf("log 的参数必须为正数")
+		}
+		if len(a) == 1 {
+			return math.Log10(a[0]), nil
+		}
+		if a[1] <= 0 || a[1] == 1 {
+			return 0, fmt.Errorf("log 的底数必须为正数且不等于 1")
+		}
+		return math.Log(a[0]) / math.Log(a[1]), nil
+	}},
+	"log10": {1, 1, func(a []float64) (float64, error) {
+		if a[0] <= 0 {
+			return 0, fmt.Errorf("log10 的参数必须为正数")
+		}
+		return math.Log10(a[0]), nil
+	}},
+	"exp":   {1, 1, unary(math.Exp)},
+	"floor": {1, 1, unary(math.Floor)},
+	"ceil":  {1, 1, unary(math.Ceil)},
+	"round": {1, 1, unary(math.Round)},
+	"pow":   {2, 2, func(a []float64) (float64, error) { return math.Pow(a[0], a[1]), nil }},
+	"min": {1, -1, func(a []float64) (float64, error) {
+		result := a[0]
+		for _, v := range a[1:] {
+			result = math.Min(result, v)
+		}
+		return result, nil
+	}},
+	"max": {1, -1, func(a []float64) (float64, error) {
+		result := a[0]
+		for _, v := range a[1:] {
+			result = math.Max(result, v)
+		}
+		return result, nil
+	}},
+}
+
+// unary 将单参数数学函数包装为 function 调用
+func unary(fn func(float64) float64) func([]float64) (float64, error) {
+	return func(a []float64) (float64, error) {
+		return fn(a[0]), nil
+	}
+}
+
+// Evaluate 计算数学表达式
+// 语法（优先级从低到高）：加减、乘除取模、一元正负、乘方（右结合）、数字/常量/函数/括号
+func Evaluate(expression string) (float64, error) {
+	p := &parser{input: []rune(normalizeOperators(expression))}
+	value, err := p.parseExpression()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("表达式在位置 %d 处有无法识别的字符 %q", p.pos+1, string(p.input[p.pos]))
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("计算结果无效")
+	}
+	return value, nil
+}
+
+// normalizeOperators 将常见的全角/数学符号转换为 ASCII 运算符
+func normalizeOperators(expression string) string {
+	replacer := strings.NewReplacer(
+		"×", "*", "÷", "/", "−", "-", "（", "(", "）", ")", "，", ",", "**", "^",
+	)
+	return replacer.Replace(expression)
+}
+
+// parser 递归下降表达式解析器
+type parser struct {
+	input []rune
+	pos   int
+}
+
+// skipSpaces 跳过空白字符
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// peek 返回下一个非空白字符
+func (p *parser) peek() rune {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// parseExpression 解析加减运算
+func (p *parser) parseExpression() (float64, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+// parseTerm 解析乘除与取模运算
+func (p *parser) parseTerm() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, fmt.Errorf("除数不能为 0")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, fmt.Errorf("取模的除数不能为 0")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+// parseUnary 解析一元正负号
+func (p *parser) parseUnary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+// parsePower 解析乘方运算（右结合，-2^2 = -4）
+func (p *parser) parsePower() (float64, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != '^' {
+		return base, nil
+	}
+	p.pos++
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+// parsePrimary 解析数字、常量、函数调用和括号表达式
+func (p *parser) parsePrimary() (float64, error) {
+	ch := p.peek()
+	switch {
+	case ch == 0:
+		return 0, fmt.Errorf("表达式不完整")
+	case ch == '(':
+		p.pos++
+		value, err := p.parseExpression()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("缺少右括号")
+		}
+		p.pos++
+		return value, nil
+	case unicode.IsDigit(ch) || ch == '.':
+		return p.parseNumber()
+	case unicode.IsLetter(ch):
+		return p.parseIdentifier()
+	default:
+		return 0, fmt.Errorf("表达式在位置 %d 处有无法识别的字符 %q", p.pos+1, string(ch))
+	}
+}
+
+// parseNumber 解析数字，支持小数、下划线分隔和科学计数法
+func (p *parser) parseNumber() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.' || p.input[p.pos] == '_') {
+		p.pos++
+	}
+	// 科学计数法，如 1.5e3
+	if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+		next := p.pos + 1
+		if next < len(p.input) && (p.input[next] == '+' || p.input[next] == '-') {
+			next++
+		}
+		if next < len(p.input) && unicode.IsDigit(p.input[next]) {
+			p.pos = next
+			for p.pos < len(p.input) && unicode.IsDigit(p.input[p.pos]) {
+				p.pos++
+			}
+		}
+	}
+	text := strings.ReplaceAll(string(p.input[start:p.pos]), "_", "")
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的数字: %s", text)
+	}
+	return value, nil
+}
+
+// parseIdentifier 解析常量或函数调用
+func (p *parser) parseIdentifier() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos])) {
+		p.pos++
+	}
+	name := strings.ToLower(string(p.input[start:p.pos]))
+
+	if p.peek() != '(' {
+		if value, ok := constants[name]; ok {
+			return value, nil
+		}
+		return 0, fmt.Errorf("未知的常量: %s", name)
+	}
+
+	fn, ok := functions[name]
+	if !ok {
+		return 0, fmt.Errorf("未知的函数: %s", name)
+	}
+	p.pos++
+	var args []float64
+	if p.peek() != ')' {
+		for {
+			value, err := p.parseExpression()
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, value)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+	}
+	if p.peek() != ')' {
+		return 0, fmt.Errorf("函数 %s 缺少右括号", name)
+	}
+	p.pos++
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return 0, fmt.Errorf("函数 %s 的参数个数不正确", name)
+	}
+	return fn.call(args)
+}
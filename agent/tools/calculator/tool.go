@@ -0,0 +1,73 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+)
+
+// Tool 计算器工具
+// 在进程内解析并计算数学表达式，避免模型心算出错
+type Tool struct{}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return "calculator"
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "计算数学表达式并返回精确结果。支持 + - * / % ^、括号、常量 pi/e 以及函数 sqrt、abs、sin、cos、tan、ln、log、log10、exp、floor、ceil、round、min、max、pow",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"expression": {
+				Type:     schema.DataType("string"),
+				Desc:     "要计算的表达式，例如 (1200 - 350) * 12 / 7",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(args.Expression) == "" {
+		return "错误: 表达式不能为空", nil
+	}
+
+	value, err := Evaluate(args.Expression)
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+	return fmt.Sprintf("%s = %s", strings.TrimSpace(args.Expression), FormatNumber(value)), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// FormatNumber 格式化计算结果，消除浮点运算的尾差（如 0.1+0.2）
+func FormatNumber(value float64) string {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'g', 15, 64), 64)
+	if math.Abs(rounded) >= 1e21 || (rounded != 0 && math.Abs(rounded) < 1e-9) {
+		return strconv.FormatFloat(rounded, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
@@ -0,0 +1,80 @@
+package calculator
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestTool_Name 测试工具名称
+func TestTool_Name(t *testing.T) {
+	tool := &Tool{}
+	if tool.Name() != "calculator" {
+		t.Errorf("Name() = %q, 期望 calculator", tool.Name())
+	}
+}
+
+// TestEvaluate 测试表达式计算
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(1 + 2) * 3", "9"},
+		{"0.1 + 0.2", "0.3"},
+		{"2 ^ 3 ^ 2", "512"},
+		{"-2 ^ 2", "-4"},
+		{"2 ** 10", "1024"},
+		{"10 % 3", "1"},
+		{"12 × 3 ÷ 4 − 1", "8"},
+		{"sqrt(16) + abs(-3)", "7"},
+		{"max(1, 5, 3) - min(4, 2)", "3"},
+		{"log(8, 2)", "3"},
+		{"round(pi * 100) / 100", "3.14"},
+		{"1.5e3 + 1_000", "2500"},
+	}
+	for _, tt := range tests {
+		value, err := Evaluate(tt.expr)
+		if err != nil {
+			t.Errorf("Evaluate(%q) 返回错误: %v", tt.expr, err)
+			continue
+		}
+		if got := FormatNumber(value); got != tt.want {
+			t.Errorf("Evaluate(%q) = %s, 期望 %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+// TestEvaluate_Errors 测试非法表达式
+func TestEvaluate_Errors(t *testing.T) {
+	exprs := []string{"1 / 0", "(1 + 2", "2 +", "foo(1)", "sqrt(-1)", "1 $ 2", "pow(2)", "x"}
+	for _, expr := range exprs {
+		if _, err := Evaluate(expr); err == nil {
+			t.Errorf("Evaluate(%q) 应该返回错误", expr)
+		}
+	}
+}
+
+// TestTool_Run 测试执行工具
+func TestTool_Run(t *testing.T) {
+	tool := &Tool{}
+	ctx := context.Background()
+
+	t.Run("正常计算", func(t *testing.T) {
+		result, err := tool.Run(ctx, `{"expression": "(1200 - 350) * 12"}`)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if result != "(1200 - 350) * 12 = 10200" {
+			t.Errorf("Run() = %q", result)
+		}
+	})
+
+	t.Run("空表达式", func(t *testing.T) {
+		result, _ := tool.Run(ctx, `{"expression": ""}`)
+		if !strings.HasPrefix(result, "错误") {
+			t.Errorf("Run() = %q, 期望返回错误", result)
+		}
+	})
+}
@@ -1,12 +1,17 @@
 package common
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/weibaohui/nanobot-go/utils"
 )
 
@@ -117,14 +122,118 @@ func ResolvePath(path, allowedDir string) string {
 	absPath, _ := filepath.Abs(path)
 	if allowedDir != "" {
 		allowedAbs, _ := filepath.Abs(allowedDir)
-		if !strings.HasPrefix(absPath, allowedAbs) {
+		if !isWithin(allowedAbs, absPath) {
 			return path
 		}
 	}
 	return absPath
 }
 
+// isWithin 判断绝对路径 path 是否位于 dir 内（包括 dir 本身）
+// 按路径分段比较，/ws-evil 不会被视为位于 /ws 内
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// workspaceKey 回合独立工作区在 context 中的键
+type workspaceKey struct{}
+
+// WithWorkspace 把回合的独立工作区注入 context，文件和命令工具据此限定可访问的目录
+func WithWorkspace(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, dir)
+}
+
+// WorkspaceFromContext 返回回合的独立工作区，未启用工作区隔离时返回空字符串
+func WorkspaceFromContext(ctx context.Context) string {
+	dir, _ := ctx.Value(workspaceKey{}).(string)
+	return dir
+}
+
+// ScopeDirs 回合有独立工作区时，用它替换工具的工作目录和允许目录，与 ResolveScopedPath 的优先级相同
+func ScopeDirs(ctx context.Context, workingDir, allowedDir string) (string, string) {
+	if dir := WorkspaceFromContext(ctx); dir != "" {
+		return dir, dir
+	}
+	return workingDir, allowedDir
+}
+
+// ResolveScopedPath 按回合工作区解析路径
+// 没有独立工作区时与 ValidatePath 相同；有独立工作区时相对路径基于该工作区解析，超出工作区返回错误
+// 独立工作区优先于 allowedDir：即使工具允许访问工作区外（allowedDir 为空），也不能跳出独立工作区
+func ResolveScopedPath(ctx context.Context, path, allowedDir string) (string, error) {
+	dir := WorkspaceFromContext(ctx)
+	if dir == "" {
+		return ValidatePath(path, allowedDir)
+	}
+	if !filepath.IsAbs(path) && !strings.HasPrefix(path, "~") {
+		path = filepath.Join(dir, path)
+	}
+	return ValidatePath(path, dir)
+}
+
+// ValidatePath 解析路径并校验其位于 allowedDir 内
+// allowedDir 为空时不做限制
+func ValidatePath(path, allowedDir string) (string, error) {
+	resolved := ResolvePath(path, allowedDir)
+	if allowedDir == "" {
+		return resolved, nil
+	}
+	absPath, err := filepath.Abs(resolved)
+	if err != nil {
+		return "", fmt.Errorf("无效的路径: %s", path)
+	}
+	allowedAbs, err := filepath.Abs(allowedDir)
+	if err != nil {
+		return "", fmt.Errorf("无效的允许目录: %s", allowedDir)
+	}
+	if !isWithin(allowedAbs, absPath) {
+		return "", fmt.Errorf("路径 %s 不在允许的目录内", path)
+	}
+	return absPath, nil
+}
+
+// UnifiedDiff 生成文件修改前后的统一 diff
+func UnifiedDiff(name, before, after string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(before),
+		B:        splitLines(after),
+		FromFile: "a/" + name,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// splitLines 按行切分文本，每行保留换行符
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n"
+	}
+	return lines
+}
+
 // TruncateString 截断字符串
 func TruncateString(s string, maxLen int) string {
 	return utils.TruncateString(s, maxLen)
 }
+
+// Contact 联系人别名对应的消息目标
+type Contact struct {
+	Channel string
+	ChatID  string
+}
+
+// ContactNames 返回排序后的联系人别名，用于在工具说明和错误提示中列出
+func ContactNames(contacts map[string]Contact) []string {
+	return slices.Sorted(maps.Keys(contacts))
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"testing"
 )
 
@@ -182,6 +183,77 @@ func TestResolvePath(t *testing.T) {
 	})
 }
 
+// TestResolveScopedPath 测试按回合独立工作区解析路径
+func TestResolveScopedPath(t *testing.T) {
+	ctx := WithWorkspace(context.Background(), "/tmp/ws/feishu/ou_1")
+
+	t.Run("相对路径基于独立工作区", func(t *testing.T) {
+		got, err := ResolveScopedPath(ctx, "notes/a.md", "/tmp/ws")
+		if err != nil || got != "/tmp/ws/feishu/ou_1/notes/a.md" {
+			t.Errorf("ResolveScopedPath() = %q, %v", got, err)
+		}
+	})
+
+	t.Run("拒绝其他用户的目录", func(t *testing.T) {
+		for _, path := range []string{"/tmp/ws/feishu/ou_2/a.md", "../ou_2/a.md", "/etc/hosts"} {
+			if got, err := ResolveScopedPath(ctx, path, ""); err == nil {
+				t.Errorf("ResolveScopedPath(%q) = %q, 期望返回错误", path, got)
+			}
+		}
+	})
+
+	t.Run("未启用隔离时按原规则解析", func(t *testing.T) {
+		got, err := ResolveScopedPath(context.Background(), "/etc/hosts", "")
+		if err != nil || got != "/etc/hosts" {
+			t.Errorf("ResolveScopedPath() = %q, %v", got, err)
+		}
+		if dir, allowed := ScopeDirs(context.Background(), "/tmp/ws", ""); dir != "/tmp/ws" || allowed != "" {
+			t.Errorf("ScopeDirs() = %q, %q", dir, allowed)
+		}
+	})
+
+	t.Run("未启用隔离时按允许目录校验", func(t *testing.T) {
+		for _, path := range []string{"/etc/hosts", "/tmp/ws/../ws-evil/a.md", "/tmp/ws-evil/a.md"} {
+			if got, err := ResolveScopedPath(context.Background(), path, "/tmp/ws"); err == nil {
+				t.Errorf("ResolveScopedPath(%q) = %q, 期望返回错误", path, got)
+			}
+		}
+		if got, err := ResolveScopedPath(context.Background(), "/tmp/ws/a.md", "/tmp/ws"); err != nil || got != "/tmp/ws/a.md" {
+			t.Errorf("ResolveScopedPath() = %q, %v", got, err)
+		}
+	})
+}
+
+// TestValidatePath 测试路径范围校验
+func TestValidatePath(t *testing.T) {
+	t.Run("允许目录内", func(t *testing.T) {
+		if _, err := ValidatePath("/tmp/work/a.txt", "/tmp/work"); err != nil {
+			t.Errorf("ValidatePath() 返回错误: %v", err)
+		}
+	})
+
+	t.Run("前缀相同的其他目录", func(t *testing.T) {
+		if _, err := ValidatePath("/tmp/workspace2/a.txt", "/tmp/workspace"); err == nil {
+			t.Error("ValidatePath() 应该拒绝允许目录之外的路径")
+		}
+	})
+
+	t.Run("未限制目录", func(t *testing.T) {
+		if _, err := ValidatePath("/etc/hosts", ""); err != nil {
+			t.Errorf("ValidatePath() 返回错误: %v", err)
+		}
+	})
+}
+
+// TestUnifiedDiff 测试生成 diff
+func TestUnifiedDiff(t *testing.T) {
+	diff := UnifiedDiff("a.txt", "hello\n", "world\n")
+	want := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-hello\n+world\n"
+	if diff != want {
+		t.Errorf("UnifiedDiff() = %q, 期望 %q", diff, want)
+	}
+}
+
 // TestTruncateString 测试字符串截断
 func TestTruncateString(t *testing.T) {
 	tests := []struct {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
@@ -11,6 +12,14 @@ import (
 	"github.com/weibaohui/nanobot-go/cron"
 )
 
+// cronExprHelp cron 表达式格式说明，表达式无效时返回给模型
+const cronExprHelp = `cron 表达式格式为 "分 时 日 月 周"，例如:
+- "0 9 * * *": 每天 9:00
+- "*/30 * * * *": 每 30 分钟
+- "0 9 * * 1-5": 工作日 9:00
+- "0 0 1 * *": 每月 1 日 0:00
+也支持 @hourly、@daily、@weekly 等描述符`
+
 // Tool 定时任务工具
 type Tool struct {
 	CronService *cron.Service
@@ -24,7 +33,10 @@ type Args struct {
 	Message      string  `json:"message"`
 	EverySeconds float64 `json:"every_seconds"`
 	CronExpr     string  `json:"cron_expr"`
+	At           string  `json:"at"`
 	JobID        string  `json:"job_id"`
+	RunAgent     bool    `json:"run_agent"`
+	CatchUp      string  `json:"catch_up"`
 }
 
 // Name 返回工具名称
@@ -36,29 +48,42 @@ func (t *Tool) Name() string {
 func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 	return &schema.ToolInfo{
 		Name: t.Name(),
-		Desc: "调度提醒和周期性任务",
+		Desc: "调度提醒和周期性任务；设置 run_agent 可定时让 Agent 执行指令（如每天早上总结收件箱）并发送结果",
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"action": {
 				Type:     schema.DataType("string"),
-				Desc:     "操作: add, list, remove",
+				Desc:     "操作: add, list, show（查看任务详情与最近执行记录）, remove",
 				Required: true,
 			},
 			"message": {
 				Type: schema.DataType("string"),
-				Desc: "提醒消息",
+				Desc: "提醒消息；run_agent 为 true 时为到期后交给 Agent 执行的指令，如 \"总结今天的未读邮件\"",
 			},
 			"every_seconds": {
 				Type: schema.DataType("integer"),
-				Desc: "间隔秒数",
+				Desc: "间隔秒数，至少 10 秒",
 			},
 			"cron_expr": {
 				Type: schema.DataType("string"),
-				Desc: "Cron表达式",
+				Desc: "Cron表达式（分 时 日 月 周），如 \"0 9 * * *\" 表示每天 9:00",
+			},
+			"at": {
+				Type: schema.DataType("string"),
+				Desc: "一次性提醒的执行时间（RFC3339 格式，可由 datetime 工具计算得到）",
 			},
 			"job_id": {
 				Type: schema.DataType("string"),
 				Desc: "任务ID",
 			},
+			"run_agent": {
+				Type: schema.DataType("boolean"),
+				Desc: "为 true 时到期后由 Agent 执行 message 中的指令（可使用工具）并发送执行结果；默认 false，直接发送提醒消息",
+			},
+			"catch_up": {
+				Type: schema.DataType("string"),
+				Desc: "服务停止期间错过执行时间的处理方式: skip（默认，跳过）, run-once-on-start（启动后补跑一次，适合每日总结类任务）",
+				Enum: []string{cron.CatchUpSkip, cron.CatchUpRunOnce},
+			},
 		}),
 	}, nil
 }
@@ -74,6 +99,8 @@ func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Opt
 		return t.addJob(args)
 	case "list":
 		return t.listJobs()
+	case "show":
+		return t.showJob(args)
 	case "remove":
 		return t.removeJob(args)
 	}
@@ -100,14 +127,38 @@ func (t *Tool) addJob(args Args) (string, error) {
 		return "错误: 没有会话上下文", nil
 	}
 	var schedule *cron.Schedule
-	if args.EverySeconds > 0 {
+	if args.EverySeconds != 0 {
 		schedule = &cron.Schedule{Kind: "every", EveryMs: int(args.EverySeconds * 1000)}
+		if err := cron.ValidateSchedule(schedule); err != nil {
+			return fmt.Sprintf("错误: every_seconds 无效，%v", err), nil
+		}
 	} else if args.CronExpr != "" {
-		schedule = &cron.Schedule{Kind: "cron", Expr: args.CronExpr}
+		schedule = &cron.Schedule{Kind: "cron", Expr: strings.TrimSpace(args.CronExpr)}
+		if err := cron.ValidateSchedule(schedule); err != nil {
+			return fmt.Sprintf("错误: %v\n%s", err, cronExprHelp), nil
+		}
+	} else if args.At != "" {
+		at, err := time.Parse(time.RFC3339, args.At)
+		if err != nil {
+			return fmt.Sprintf("错误: at 参数需要 RFC3339 格式: %s", args.At), nil
+		}
+		if !at.After(time.Now()) {
+			return "错误: at 时间必须晚于当前时间", nil
+		}
+		schedule = &cron.Schedule{Kind: "at", AtMs: int(at.UnixMilli())}
 	} else {
-		return "错误: 需要 every_seconds 或 cron_expr 参数", nil
+		return "错误: 需要 every_seconds、cron_expr 或 at 参数", nil
+	}
+	if args.CatchUp != "" && args.CatchUp != cron.CatchUpSkip && args.CatchUp != cron.CatchUpRunOnce {
+		return fmt.Sprintf("错误: catch_up 只能是 %s 或 %s", cron.CatchUpSkip, cron.CatchUpRunOnce), nil
+	}
+	// 一次性提醒执行后自动删除
+	opts := cron.JobOptions{DeleteAfterRun: schedule.Kind == "at", CatchUp: args.CatchUp}
+	payload := cron.Payload{Message: args.Message, Deliver: true, Channel: t.Channel, To: t.ChatID, RunAgent: args.RunAgent}
+	job := t.CronService.AddJob(common.TruncateString(args.Message, 30), schedule, payload, opts)
+	if job.Payload.RunAgent {
+		return fmt.Sprintf("已创建 Agent 任务 '%s' (id: %s)，到期后将执行指令并发送结果", job.Name, job.ID), nil
 	}
-	job := t.CronService.AddJob(common.TruncateString(args.Message, 30), schedule, args.Message, true, t.Channel, t.ChatID, false)
 	return fmt.Sprintf("已创建任务 '%s' (id: %s)", job.Name, job.ID), nil
 }
 
@@ -119,11 +170,66 @@ func (t *Tool) listJobs() (string, error) {
 	}
 	var lines []string
 	for _, j := range jobs {
-		lines = append(lines, fmt.Sprintf("- %s (id: %s, %s)", j.Name, j.ID, j.Schedule.Kind))
+		kind := j.Schedule.Kind
+		if j.Payload.RunAgent {
+			kind += ", agent"
+		}
+		if j.State.LastStatus != "" {
+			kind += ", 上次: " + j.State.LastStatus
+		}
+		lines = append(lines, fmt.Sprintf("- %s (id: %s, %s)", j.Name, j.ID, kind))
 	}
 	return "计划任务:\n" + strings.Join(lines, "\n"), nil
 }
 
+// showJob 查看任务详情与最近的执行记录
+func (t *Tool) showJob(args Args) (string, error) {
+	if args.JobID == "" {
+		return "错误: 需要 job_id 参数", nil
+	}
+	job := t.CronService.GetJob(args.JobID)
+	if job == nil {
+		return fmt.Sprintf("任务 %s 未找到", args.JobID), nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "任务 '%s' (id: %s)\n", job.Name, job.ID)
+	fmt.Fprintf(&sb, "调度: %s\n", job.Schedule.Kind)
+	if job.Payload.RunAgent {
+		sb.WriteString("类型: Agent 执行\n")
+	} else {
+		sb.WriteString("类型: 提醒\n")
+	}
+	fmt.Fprintf(&sb, "消息: %s\n", job.Payload.Message)
+	if job.CatchUp == cron.CatchUpRunOnce {
+		sb.WriteString("错过执行时: 启动后补跑一次\n")
+	}
+	if job.State.NextRunAtMs > 0 {
+		fmt.Fprintf(&sb, "下次执行: %s\n", formatMs(job.State.NextRunAtMs))
+	}
+	if len(job.Runs) == 0 {
+		sb.WriteString("尚无执行记录")
+		return sb.String(), nil
+	}
+	sb.WriteString("最近执行:")
+	for i := len(job.Runs) - 1; i >= 0; i-- {
+		run := job.Runs[i]
+		line := fmt.Sprintf("\n- %s %s (%dms)", formatMs(run.AtMs), run.Status, run.DurationMs)
+		if run.Error != "" {
+			line += " 错误: " + run.Error
+		} else if run.Preview != "" {
+			line += " " + common.TruncateString(strings.ReplaceAll(run.Preview, "\n", " "), 80)
+		}
+		sb.WriteString(line)
+	}
+	return sb.String(), nil
+}
+
+// formatMs 格式化毫秒时间戳
+func formatMs(ms int) string {
+	return time.UnixMilli(int64(ms)).Format("2006-01-02 15:04:05")
+}
+
 // removeJob 删除任务
 func (t *Tool) removeJob(args Args) (string, error) {
 	if args.JobID == "" {
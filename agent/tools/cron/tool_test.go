@@ -2,7 +2,10 @@ package cron
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cloudwego/eino/schema"
 	"github.com/weibaohui/nanobot-go/cron"
@@ -117,8 +120,91 @@ func TestTool_addJob(t *testing.T) {
 			t.Errorf("addJob() 返回错误: %v", err)
 		}
 
-		if result != "错误: 需要 every_seconds 或 cron_expr 参数" {
-			t.Errorf("addJob() = %q, 期望 错误: 需要 every_seconds 或 cron_expr 参数", result)
+		if result != "错误: 需要 every_seconds、cron_expr 或 at 参数" {
+			t.Errorf("addJob() = %q, 期望 错误: 需要 every_seconds、cron_expr 或 at 参数", result)
+		}
+	})
+
+	t.Run("一次性提醒", func(t *testing.T) {
+		service := cron.NewService(filepath.Join(t.TempDir(), "cron.json"), zap.NewNop())
+		tool := &Tool{CronService: service, Channel: "websocket", ChatID: "chat-001"}
+
+		at := time.Now().Add(2 * time.Hour).Format(time.RFC3339)
+		result, err := tool.addJob(Args{Message: "喝水", At: at})
+		if err != nil {
+			t.Errorf("addJob() 返回错误: %v", err)
+		}
+		if !strings.HasPrefix(result, "已创建任务") {
+			t.Fatalf("addJob() = %q, 期望创建成功", result)
+		}
+		jobs := service.ListJobs()
+		if len(jobs) != 1 || jobs[0].Schedule.Kind != "at" || !jobs[0].DeleteAfterRun {
+			t.Errorf("一次性提醒应使用 at 调度并在执行后删除")
+		}
+	})
+
+	t.Run("Agent 任务", func(t *testing.T) {
+		service := cron.NewService(filepath.Join(t.TempDir(), "cron.json"), zap.NewNop())
+		tool := &Tool{CronService: service, Channel: "feishu", ChatID: "oc_123"}
+
+		result, _ := tool.addJob(Args{Message: "总结收件箱", EverySeconds: 86400, RunAgent: true})
+		if !strings.HasPrefix(result, "已创建 Agent 任务") {
+			t.Fatalf("addJob() = %q, 期望创建 Agent 任务", result)
+		}
+		jobs := service.ListJobs()
+		if len(jobs) != 1 || !jobs[0].Payload.RunAgent || jobs[0].Payload.Channel != "feishu" || jobs[0].Payload.To != "oc_123" {
+			t.Errorf("任务负载 = %+v, 期望由 Agent 执行并投递到当前会话", jobs[0].Payload)
+		}
+		if list, _ := tool.listJobs(); !strings.Contains(list, "every, agent") {
+			t.Errorf("listJobs() = %q, 期望标记 Agent 任务", list)
+		}
+	})
+
+	t.Run("无效的调度参数", func(t *testing.T) {
+		tool := &Tool{Channel: "websocket", ChatID: "chat-001"}
+
+		result, _ := tool.addJob(Args{Message: "喝水", CronExpr: "每天早上"})
+		if !strings.HasPrefix(result, "错误: 无效的 cron 表达式") || !strings.Contains(result, "0 9 * * *") {
+			t.Errorf("addJob() = %q, 期望返回错误及示例", result)
+		}
+		for _, seconds := range []float64{-60, 1} {
+			if result, _ := tool.addJob(Args{Message: "喝水", EverySeconds: seconds}); !strings.HasPrefix(result, "错误: every_seconds 无效") {
+				t.Errorf("every_seconds=%v: addJob() = %q", seconds, result)
+			}
+		}
+	})
+
+	t.Run("cron 表达式", func(t *testing.T) {
+		service := cron.NewService(filepath.Join(t.TempDir(), "cron.json"), zap.NewNop())
+		tool := &Tool{CronService: service, Channel: "websocket", ChatID: "chat-001"}
+
+		result, _ := tool.addJob(Args{Message: "早报", CronExpr: "0 9 * * *"})
+		if !strings.HasPrefix(result, "已创建任务") {
+			t.Fatalf("addJob() = %q, 期望创建成功", result)
+		}
+		if jobs := service.ListJobs(); len(jobs) != 1 || jobs[0].State.NextRunAtMs == 0 {
+			t.Error("cron 任务应计算出下次执行时间")
+		}
+	})
+
+	t.Run("补跑策略", func(t *testing.T) {
+		service := cron.NewService(filepath.Join(t.TempDir(), "cron.json"), zap.NewNop())
+		tool := &Tool{CronService: service, Channel: "feishu", ChatID: "oc_123"}
+
+		if result, _ := tool.addJob(Args{Message: "总结", EverySeconds: 60, CatchUp: "always"}); !strings.HasPrefix(result, "错误: catch_up") {
+			t.Errorf("addJob() = %q, 期望拒绝未知的补跑策略", result)
+		}
+		tool.addJob(Args{Message: "总结", EverySeconds: 60, CatchUp: cron.CatchUpRunOnce})
+		if jobs := service.ListJobs(); len(jobs) != 1 || jobs[0].CatchUp != cron.CatchUpRunOnce {
+			t.Error("任务应记录补跑策略")
+		}
+	})
+
+	t.Run("at 时间已过", func(t *testing.T) {
+		tool := &Tool{Channel: "websocket", ChatID: "chat-001"}
+		result, _ := tool.addJob(Args{Message: "喝水", At: "2000-01-01T00:00:00Z"})
+		if result != "错误: at 时间必须晚于当前时间" {
+			t.Errorf("addJob() = %q", result)
 		}
 	})
 }
@@ -139,6 +225,34 @@ func TestTool_removeJob(t *testing.T) {
 	})
 }
 
+// TestTool_showJob 测试查看任务详情与执行记录
+func TestTool_showJob(t *testing.T) {
+	service := cron.NewService(filepath.Join(t.TempDir(), "cron.json"), zap.NewNop())
+	tool := &Tool{CronService: service}
+
+	job := service.AddJob("总结", &cron.Schedule{Kind: "every", EveryMs: 60000}, cron.Payload{Message: "总结收件箱", RunAgent: true}, cron.JobOptions{})
+	result, _ := tool.showJob(Args{JobID: job.ID})
+	if !strings.Contains(result, "Agent 执行") || !strings.Contains(result, "尚无执行记录") {
+		t.Errorf("showJob() = %q", result)
+	}
+
+	job.Runs = append(job.Runs,
+		cron.RunRecord{AtMs: 1000, Status: "ok", Preview: "3 封未读"},
+		cron.RunRecord{AtMs: 2000, Status: "error", Error: "模型超时"},
+	)
+	result, _ = tool.showJob(Args{JobID: job.ID})
+	if !strings.Contains(result, "error (0ms) 错误: 模型超时") || !strings.Contains(result, "ok (0ms) 3 封未读") {
+		t.Errorf("showJob() = %q, 期望包含执行记录", result)
+	}
+	if strings.Index(result, "模型超时") > strings.Index(result, "3 封未读") {
+		t.Error("执行记录应按时间倒序显示")
+	}
+
+	if result, _ := tool.showJob(Args{JobID: "missing"}); result != "任务 missing 未找到" {
+		t.Errorf("showJob() = %q", result)
+	}
+}
+
 // TestTool_InvokableRun 测试 InvokableRun 方法
 func TestTool_InvokableRun(t *testing.T) {
 	tool := &Tool{}
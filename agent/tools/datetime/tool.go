@@ -0,0 +1,300 @@
+package datetime
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+)
+
+// parseLayouts 支持解析的时间格式
+var parseLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// namedLayouts 预置的格式名称
+var namedLayouts = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"iso":      time.RFC3339,
+	"date":     "2006-01-02",
+	"time":     "15:04:05",
+	"datetime": "2006-01-02 15:04:05",
+	"rfc1123":  time.RFC1123,
+	"chinese":  "2006年01月02日 15:04",
+}
+
+var (
+	offsetPattern    = regexp.MustCompile(`([+-]?\d+(?:\.\d+)?)\s*([a-zA-Z]+|个月|小时|分钟|星期|[秒分时天日周月年])`)
+	clockPattern     = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	timestampPattern = regexp.MustCompile(`^\d{10}(\d{3})?$`)
+)
+
+// weekdayNames 中文星期名称
+var weekdayNames = []string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+
+// Tool 日期时间工具
+// 提供当前时间查询、时间解析、时间偏移计算与格式化，避免模型自行推算日期出错
+type Tool struct {
+	Location *time.Location // 默认时区，为空时使用本地时区
+	Now      func() time.Time
+}
+
+// Args 日期时间工具参数
+type Args struct {
+	Action   string `json:"action"`
+	Time     string `json:"time"`
+	Timezone string `json:"timezone"`
+	Offset   string `json:"offset"`
+	Layout   string `json:"layout"`
+}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return "datetime"
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "日期时间计算。now: 获取当前时间；parse: 解析时间（ISO、常见格式、时间戳、today/tomorrow、\"2小时后\" 等）；add: 对时间加减偏移；format: 按格式输出。设置定时提醒前可先用 add 计算目标时间，再传给 cron 工具的 at 参数",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"action": {
+				Type:     schema.DataType("string"),
+				Desc:     "操作: now, parse, add, format",
+				Required: true,
+			},
+			"time": {
+				Type: schema.DataType("string"),
+				Desc: "输入时间，parse/add/format 使用，add/format 默认为当前时间",
+			},
+			"timezone": {
+				Type: schema.DataType("string"),
+				Desc: "IANA 时区，如 Asia/Shanghai，默认使用配置的时区",
+			},
+			"offset": {
+				Type: schema.DataType("string"),
+				Desc: "add 使用的偏移，如 2h、-30m、1d、\"3 days\"、\"1 month\"、\"2小时\"",
+			},
+			"layout": {
+				Type: schema.DataType("string"),
+				Desc: "format 使用的格式: rfc3339, date, time, datetime, rfc1123, chinese, unix，或 Go 时间格式",
+			},
+		}),
+	}, nil
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args Args
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+
+	loc, err := t.location(args.Timezone)
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+	now := t.now().In(loc)
+
+	switch args.Action {
+	case "now":
+		return describe(now), nil
+	case "parse":
+		if strings.TrimSpace(args.Time) == "" {
+			return "错误: 需要 time 参数", nil
+		}
+		parsed, err := parseTime(args.Time, now, loc)
+		if err != nil {
+			return fmt.Sprintf("错误: %s", err), nil
+		}
+		return describe(parsed), nil
+	case "add":
+		if strings.TrimSpace(args.Offset) == "" {
+			return "错误: 需要 offset 参数", nil
+		}
+		base := now
+		if strings.TrimSpace(args.Time) != "" {
+			if base, err = parseTime(args.Time, now, loc); err != nil {
+				return fmt.Sprintf("错误: %s", err), nil
+			}
+		}
+		result, err := applyOffset(base, args.Offset)
+		if err != nil {
+			return fmt.Sprintf("错误: %s", err), nil
+		}
+		return describe(result), nil
+	case "format":
+		base := now
+		if strings.TrimSpace(args.Time) != "" {
+			if base, err = parseTime(args.Time, now, loc); err != nil {
+				return fmt.Sprintf("错误: %s", err), nil
+			}
+		}
+		return formatTime(base, args.Layout), nil
+	}
+	return fmt.Sprintf("未知操作: %s", args.Action), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// now 返回当前时间
+func (t *Tool) now() time.Time {
+	if t.Now != nil {
+		return t.Now()
+	}
+	return time.Now()
+}
+
+// location 解析时区，未指定时使用默认时区
+func (t *Tool) location(name string) (*time.Location, error) {
+	if name = strings.TrimSpace(name); name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时区: %s", name)
+		}
+		return loc, nil
+	}
+	if t.Location != nil {
+		return t.Location, nil
+	}
+	return time.Local, nil
+}
+
+// describe 生成时间的多种表示，便于模型直接引用
+func describe(tm time.Time) string {
+	return fmt.Sprintf("时间: %s\n本地: %s %s\n时区: %s\nUnix 时间戳: %d",
+		tm.Format(time.RFC3339), tm.Format("2006-01-02 15:04:05"), weekdayNames[tm.Weekday()], tm.Location(), tm.Unix())
+}
+
+// formatTime 按指定格式输出时间
+func formatTime(tm time.Time, layout string) string {
+	layout = strings.TrimSpace(layout)
+	switch strings.ToLower(layout) {
+	case "":
+		return tm.Format(time.RFC3339)
+	case "unix":
+		return strconv.FormatInt(tm.Unix(), 10)
+	case "unixms":
+		return strconv.FormatInt(tm.UnixMilli(), 10)
+	}
+	if named, ok := namedLayouts[strings.ToLower(layout)]; ok {
+		return tm.Format(named)
+	}
+	return tm.Format(layout)
+}
+
+// parseTime 解析时间字符串，支持绝对时间、时间戳和相对时间
+func parseTime(input string, now time.Time, loc *time.Location) (time.Time, error) {
+	text := strings.TrimSpace(input)
+	lower := strings.ToLower(text)
+
+	switch lower {
+	case "now", "现在":
+		return now, nil
+	case "today", "今天":
+		return startOfDay(now), nil
+	case "tomorrow", "明天":
+		return startOfDay(now).AddDate(0, 0, 1), nil
+	case "yesterday", "昨天":
+		return startOfDay(now).AddDate(0, 0, -1), nil
+	}
+
+	if timestampPattern.MatchString(text) {
+		value, _ := strconv.ParseInt(text, 10, 64)
+		if len(text) == 13 {
+			return time.UnixMilli(value).In(loc), nil
+		}
+		return time.Unix(value, 0).In(loc), nil
+	}
+
+	if m := clockPattern.FindStringSubmatch(text); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour > 23 || minute > 59 {
+			return time.Time{}, fmt.Errorf("无效的时间: %s", text)
+		}
+		return time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc), nil
+	}
+
+	for _, layout := range parseLayouts {
+		if parsed, err := time.ParseInLocation(layout, text, loc); err == nil {
+			return parsed, nil
+		}
+	}
+
+	// 相对时间，如 "in 2 hours"、"3 days ago"、"2小时后"
+	if offsetPattern.MatchString(text) {
+		return applyOffset(now, text)
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", text)
+}
+
+// applyOffset 对时间应用偏移量
+// 支持 Go duration（2h30m）、英文单位（3 days）和中文单位（2小时），"ago"/"前" 表示向前偏移
+func applyOffset(base time.Time, offset string) (time.Time, error) {
+	text := strings.ToLower(strings.TrimSpace(offset))
+	if d, err := time.ParseDuration(text); err == nil {
+		return base.Add(d), nil
+	}
+
+	matches := offsetPattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return time.Time{}, fmt.Errorf("无法解析偏移: %s", offset)
+	}
+	sign := 1.0
+	if strings.Contains(text, "ago") || strings.Contains(text, "前") {
+		sign = -1
+	}
+
+	result := base
+	for _, m := range matches {
+		amount, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("无效的数值: %s", m[1])
+		}
+		amount *= sign
+		switch unit := m[2]; unit {
+		case "y", "yr", "yrs", "year", "years", "年":
+			result = result.AddDate(int(amount), 0, 0)
+		case "mo", "mon", "month", "months", "月", "个月":
+			result = result.AddDate(0, int(amount), 0)
+		case "w", "wk", "week", "weeks", "周", "星期":
+			result = result.AddDate(0, 0, int(amount*7))
+		case "d", "day", "days", "天", "日":
+			result = result.AddDate(0, 0, int(amount))
+		case "h", "hr", "hrs", "hour", "hours", "时", "小时":
+			result = result.Add(time.Duration(amount * float64(time.Hour)))
+		case "m", "min", "mins", "minute", "minutes", "分", "分钟":
+			result = result.Add(time.Duration(amount * float64(time.Minute)))
+		case "s", "sec", "secs", "second", "seconds", "秒":
+			result = result.Add(time.Duration(amount * float64(time.Second)))
+		default:
+			return time.Time{}, fmt.Errorf("未知的时间单位: %s", unit)
+		}
+	}
+	return result, nil
+}
+
+// startOfDay 返回当天零点
+func startOfDay(tm time.Time) time.Time {
+	return time.Date(tm.Year(), tm.Month(), tm.Day(), 0, 0, 0, 0, tm.Location())
+}
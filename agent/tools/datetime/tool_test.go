@@ -0,0 +1,88 @@
+package datetime
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTool 创建固定当前时间的工具
+func newTestTool(t *testing.T) *Tool {
+	t.Helper()
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("加载时区失败: %v", err)
+	}
+	return &Tool{
+		Location: loc,
+		Now: func() time.Time {
+			return time.Date(2025, 3, 10, 9, 30, 0, 0, loc)
+		},
+	}
+}
+
+// TestTool_Name 测试工具名称
+func TestTool_Name(t *testing.T) {
+	tool := &Tool{}
+	if tool.Name() != "datetime" {
+		t.Errorf("Name() = %q, 期望 datetime", tool.Name())
+	}
+}
+
+// TestTool_Run 测试执行工具
+func TestTool_Run(t *testing.T) {
+	tool := newTestTool(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		args string
+		want string
+	}{
+		{"当前时间", `{"action": "now"}`, "2025-03-10T09:30:00+08:00"},
+		{"指定时区", `{"action": "now", "timezone": "UTC"}`, "2025-03-10T01:30:00Z"},
+		{"解析日期", `{"action": "parse", "time": "2025-03-12 18:00"}`, "2025-03-12T18:00:00+08:00"},
+		{"解析明天", `{"action": "parse", "time": "tomorrow"}`, "2025-03-11T00:00:00+08:00"},
+		{"解析相对时间", `{"action": "parse", "time": "2小时后"}`, "2025-03-10T11:30:00+08:00"},
+		{"解析过去时间", `{"action": "parse", "time": "3 days ago"}`, "2025-03-07T09:30:00+08:00"},
+		{"解析时间戳", `{"action": "parse", "time": "1741570200"}`, "2025-03-10T09:30:00+08:00"},
+		{"增加时长", `{"action": "add", "offset": "2h"}`, "2025-03-10T11:30:00+08:00"},
+		{"增加月份", `{"action": "add", "time": "2025-01-31", "offset": "1 month"}`, "2025-03-03T00:00:00+08:00"},
+		{"格式化", `{"action": "format", "layout": "chinese"}`, "2025年03月10日 09:30"},
+		{"格式化时间戳", `{"action": "format", "layout": "unix"}`, "1741570200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Run(ctx, tt.args)
+			if err != nil {
+				t.Fatalf("Run() 返回错误: %v", err)
+			}
+			if !strings.Contains(result, tt.want) {
+				t.Errorf("Run() = %q, 期望包含 %q", result, tt.want)
+			}
+		})
+	}
+}
+
+// TestTool_RunErrors 测试错误参数
+func TestTool_RunErrors(t *testing.T) {
+	tool := newTestTool(t)
+	ctx := context.Background()
+
+	for _, args := range []string{
+		`{"action": "parse"}`,
+		`{"action": "parse", "time": "不是时间"}`,
+		`{"action": "add"}`,
+		`{"action": "add", "offset": "abc"}`,
+		`{"action": "now", "timezone": "Mars/Base"}`,
+	} {
+		result, err := tool.Run(ctx, args)
+		if err != nil {
+			t.Fatalf("Run(%s) 返回错误: %v", args, err)
+		}
+		if !strings.HasPrefix(result, "错误") {
+			t.Errorf("Run(%s) = %q, 期望返回错误", args, result)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/cloudwego/eino/components/tool"
@@ -25,7 +26,7 @@ func (t *Tool) Name() string {
 func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 	return &schema.ToolInfo{
 		Name: t.Name(),
-		Desc: "通过替换文本编辑文件",
+		Desc: "通过替换文本编辑文件，old_text 需在文件中唯一匹配",
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"path": {
 				Type:     schema.DataType("string"),
@@ -42,6 +43,10 @@ func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 				Desc:     "替换成的文本",
 				Required: true,
 			},
+			"replace_all": {
+				Type: schema.DataType("boolean"),
+				Desc: "是否替换所有匹配，默认 false。为 false 时 old_text 必须在文件中唯一",
+			},
 		}),
 	}, nil
 }
@@ -49,29 +54,73 @@ func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 // Run 执行工具逻辑
 func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	var args struct {
-		Path    string `json:"path"`
-		OldText string `json:"old_text"`
-		NewText string `json:"new_text"`
+		Path       string `json:"path"`
+		OldText    string `json:"old_text"`
+		NewText    string `json:"new_text"`
+		ReplaceAll bool   `json:"replace_all"`
 	}
 	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
 		return "", err
 	}
-	resolved := common.ResolvePath(args.Path, t.AllowedDir)
+	resolved, err := common.ResolveScopedPath(ctx, args.Path, t.AllowedDir)
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
 	data, err := os.ReadFile(resolved)
 	if err != nil {
 		return fmt.Sprintf("错误: 文件不存在: %s", args.Path), nil
 	}
 	content := string(data)
-	if !strings.Contains(content, args.OldText) {
+	if args.OldText == "" {
+		return "错误: old_text 不能为空", nil
+	}
+	lines := matchLines(content, args.OldText)
+	if len(lines) == 0 {
 		return "错误: old_text 在文件中未找到", nil
 	}
-	newContent := strings.Replace(content, args.OldText, args.NewText, 1)
+	if len(lines) > 1 && !args.ReplaceAll {
+		return fmt.Sprintf("错误: old_text 在文件中匹配到 %d 处（行: %s），请提供更多上下文使其唯一，或设置 replace_all 为 true", len(lines), joinLines(lines)), nil
+	}
+
+	count := 1
+	if args.ReplaceAll {
+		count = -1
+	}
+	newContent := strings.Replace(content, args.OldText, args.NewText, count)
 	if err := os.WriteFile(resolved, []byte(newContent), 0644); err != nil {
 		return "", err
 	}
+	if len(lines) > 1 {
+		return fmt.Sprintf("成功编辑 %s，替换了 %d 处", args.Path, len(lines)), nil
+	}
 	return fmt.Sprintf("成功编辑 %s", args.Path), nil
 }
 
+// matchLines 返回 old_text 每处（不重叠）匹配的起始行号
+func matchLines(content, oldText string) []int {
+	var lines []int
+	line, offset := 1, 0
+	for {
+		idx := strings.Index(content[offset:], oldText)
+		if idx < 0 {
+			return lines
+		}
+		line += strings.Count(content[offset:offset+idx], "\n")
+		lines = append(lines, line)
+		line += strings.Count(oldText, "\n")
+		offset += idx + len(oldText)
+	}
+}
+
+// joinLines 将行号列表格式化为字符串
+func joinLines(lines []int) string {
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		parts[i] = strconv.Itoa(line)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // InvokableRun 可直接调用的执行入口
 func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	return t.Run(ctx, argumentsInJSON, opts...)
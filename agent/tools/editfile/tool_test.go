@@ -2,8 +2,10 @@ package editfile
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cloudwego/eino/schema"
@@ -115,7 +117,30 @@ func TestTool_Run(t *testing.T) {
 		}
 	})
 
-	t.Run("只替换第一个匹配", func(t *testing.T) {
+	t.Run("多处匹配时报错", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		testFile := filepath.Join(tmpDir, "test.txt")
+		os.WriteFile(testFile, []byte("aaa\nbbb\naaa aaa"), 0644)
+
+		tool := &Tool{AllowedDir: tmpDir}
+		ctx := context.Background()
+
+		result, err := tool.Run(ctx, `{"path": "`+testFile+`", "old_text": "aaa", "new_text": "ccc"}`)
+		if err != nil {
+			t.Errorf("Run() 返回错误: %v", err)
+		}
+
+		if !strings.Contains(result, "匹配到 3 处（行: 1, 3, 3）") {
+			t.Errorf("Run() = %q, 期望包含匹配数与行号", result)
+		}
+
+		data, _ := os.ReadFile(testFile)
+		if string(data) != "aaa\nbbb\naaa aaa" {
+			t.Errorf("匹配不唯一时文件不应被修改, 得到 %q", string(data))
+		}
+	})
+
+	t.Run("替换所有匹配", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		testFile := filepath.Join(tmpDir, "test.txt")
 		os.WriteFile(testFile, []byte("aaa aaa aaa"), 0644)
@@ -123,18 +148,18 @@ func TestTool_Run(t *testing.T) {
 		tool := &Tool{AllowedDir: tmpDir}
 		ctx := context.Background()
 
-		result, err := tool.Run(ctx, `{"path": "`+testFile+`", "old_text": "aaa", "new_text": "bbb"}`)
+		result, err := tool.Run(ctx, `{"path": "`+testFile+`", "old_text": "aaa", "new_text": "bbb", "replace_all": true}`)
 		if err != nil {
 			t.Errorf("Run() 返回错误: %v", err)
 		}
 
-		if result == "" {
-			t.Error("Run() 不应该返回空结果")
+		if !strings.Contains(result, "替换了 3 处") {
+			t.Errorf("Run() = %q, 期望包含替换数量", result)
 		}
 
 		data, _ := os.ReadFile(testFile)
-		if string(data) != "bbb aaa aaa" {
-			t.Errorf("文件内容 = %q, 期望 bbb aaa aaa", string(data))
+		if string(data) != "bbb bbb bbb" {
+			t.Errorf("文件内容 = %q, 期望 bbb bbb bbb", string(data))
 		}
 	})
 }
@@ -215,3 +240,34 @@ func TestTool_ReplaceWithEmpty(t *testing.T) {
 		t.Errorf("文件内容 = %q, 期望 Hello ", string(data))
 	}
 }
+
+// TestTool_Run_OutsideAllowedDir 测试拒绝访问允许目录之外的路径
+func TestTool_Run_OutsideAllowedDir(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "ws")
+	sibling := filepath.Join(root, "ws-evil")
+	for _, dir := range []string{workspace, sibling} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+	}
+	secret := filepath.Join(sibling, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	tool := &Tool{AllowedDir: workspace}
+
+	for _, path := range []string{secret, filepath.Join(workspace, "..", "ws-evil", "secret.txt"), secret} {
+		args, _ := json.Marshal(map[string]string{"path": path, "old_text": "secret", "new_text": "leaked"})
+		result, err := tool.Run(context.Background(), string(args))
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if !strings.Contains(result, "不在允许的目录内") {
+			t.Errorf("Run(%q) = %q, 期望拒绝", path, result)
+		}
+	}
+	if data, _ := os.ReadFile(secret); string(data) != "secret" {
+		t.Errorf("允许目录之外的文件被修改: %q", data)
+	}
+}
@@ -0,0 +1,60 @@
+package exec
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+)
+
+// allowedSystemPaths 限制在工作区时仍允许命令引用的系统路径
+var allowedSystemPaths = map[string]bool{
+	"/dev/null":   true,
+	"/dev/stdin":  true,
+	"/dev/stdout": true,
+	"/dev/stderr": true,
+}
+
+// isShellSeparator 判断字符是否分隔命令中的单词，引号、重定向和 --opt=value 中的 = 都视为分隔
+func isShellSeparator(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', ';', '|', '&', '<', '>', '(', ')', '\'', '"', '`', '=':
+		return true
+	}
+	return false
+}
+
+// checkCommandPaths 校验命令中引用的路径都位于 allowedDir 内
+// 绝对路径、~ 开头的路径和包含 .. 的路径基于 workingDir 解析后校验，allowedDir 为空时不做限制
+// 只做词法检查，无法识别变量展开、命令替换等动态构造的路径，不能替代系统级沙箱
+func checkCommandPaths(command, workingDir, allowedDir string) error {
+	if allowedDir == "" {
+		return nil
+	}
+	for _, word := range strings.FieldsFunc(command, isShellSeparator) {
+		if !referencesOutside(word) || allowedSystemPaths[word] {
+			continue
+		}
+		path := word
+		if !filepath.IsAbs(path) && !strings.HasPrefix(path, "~") {
+			path = filepath.Join(workingDir, path)
+		}
+		if _, err := common.ValidatePath(path, allowedDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// referencesOutside 判断单词是否可能引用工作目录之外的路径
+func referencesOutside(word string) bool {
+	if strings.HasPrefix(word, "/") || strings.HasPrefix(word, "~") {
+		return true
+	}
+	for _, segment := range strings.Split(word, "/") {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package exec
+
+import "os/exec"
+
+// setProcessGroup 非 Unix 平台只终止 shell 进程，子进程占用输出管道时由 WaitDelay 保证命令及时返回
+func setProcessGroup(cmd *exec.Cmd) {}
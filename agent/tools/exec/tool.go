@@ -2,19 +2,25 @@ package exec
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
+	"time"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
 	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	"github.com/weibaohui/nanobot-go/utils"
 )
 
+// waitDelay 命令被终止后等待输出管道关闭的最长时间
+const waitDelay = time.Second
+
 // Tool 执行命令工具
 type Tool struct {
-	Timeout             int
+	Timeout             int // 命令超时时间（秒），0 表示只随回合取消
 	WorkingDir          string
-	RestrictToWorkspace bool
+	RestrictToWorkspace bool // 拒绝引用 WorkingDir 之外路径的命令
 }
 
 // Name 返回工具名称
@@ -45,15 +51,36 @@ func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Opt
 	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
 		return "", err
 	}
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(t.Timeout)*time.Second)
+		defer cancel()
+	}
+	allowedDir := ""
+	if t.RestrictToWorkspace {
+		allowedDir = t.WorkingDir
+	}
+	workingDir, allowedDir := common.ScopeDirs(ctx, t.WorkingDir, allowedDir)
+	if err := checkCommandPaths(args.Command, workingDir, allowedDir); err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+	// 回合取消或超时时终止整个进程组，避免 shell 启动的子进程残留
 	cmd := exec.CommandContext(ctx, "sh", "-c", args.Command)
-	cmd.Dir = t.WorkingDir
+	cmd.Dir = workingDir
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
 	output, err := cmd.CombinedOutput()
 	result := string(output)
-	if err != nil {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result += fmt.Sprintf("\n错误: 命令执行超时（%d 秒），已终止", t.Timeout)
+	case ctx.Err() != nil:
+		result += "\n错误: 命令已取消"
+	case err != nil:
 		result += fmt.Sprintf("\n错误: %s", err)
 	}
-	if len(result) > 10000 {
-		result = result[:10000] + "...(已截断)"
+	if truncated, ok := utils.TruncateRunes(result, 10000); ok {
+		result = truncated + "...(已截断)"
 	}
 	// 确保不返回空字符串，避免 Eino 框架构造无效的工具消息
 	// OpenAI API 要求工具消息必须有 content 字段
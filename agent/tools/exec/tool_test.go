@@ -2,10 +2,16 @@ package exec
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
 )
 
 // TestTool_Name 测试工具名称
@@ -172,6 +178,73 @@ func TestTool_RestrictToWorkspace(t *testing.T) {
 	}
 }
 
+// TestTool_Run_RestrictToWorkspace 测试限制在工作区时拒绝引用工作区外路径的命令
+func TestTool_Run_RestrictToWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "a.txt"), []byte("内容"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	tool := &Tool{WorkingDir: workspace, RestrictToWorkspace: true}
+
+	tests := []struct {
+		name    string
+		command string
+		allowed bool
+	}{
+		{"工作区内的相对路径", "cat a.txt", true},
+		{"工作区内的绝对路径", "cat " + filepath.Join(workspace, "a.txt"), true},
+		{"重定向到 /dev/null", "cat a.txt 2>/dev/null", true},
+		{"工作区外的绝对路径", "cat /etc/passwd", false},
+		{"引号中的绝对路径", `cat "/etc/passwd"`, false},
+		{"选项值中的路径", "ls --directory=/etc", false},
+		{"跳出工作区的相对路径", "cat ../a.txt", false},
+		{"主目录", "ls ~", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, _ := json.Marshal(map[string]string{"command": tt.command})
+			result, err := tool.Run(context.Background(), string(args))
+			if err != nil {
+				t.Fatalf("Run() 返回错误: %v", err)
+			}
+			if denied := strings.Contains(result, "不在允许的目录内"); denied == tt.allowed {
+				t.Errorf("Run(%q) = %q, 期望允许 = %v", tt.command, result, tt.allowed)
+			}
+		})
+	}
+
+	t.Run("未限制时不校验路径", func(t *testing.T) {
+		result, _ := (&Tool{WorkingDir: workspace}).Run(context.Background(), `{"command": "ls / >/dev/null && echo ok"}`)
+		if strings.TrimSpace(result) != "ok" {
+			t.Errorf("Run() = %q, 期望 ok", result)
+		}
+	})
+
+	t.Run("独立工作区优先于未限制的配置", func(t *testing.T) {
+		root := t.TempDir()
+		mine := filepath.Join(root, "ou_1")
+		other := filepath.Join(root, "ou_2")
+		for _, dir := range []string{mine, other} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("创建目录失败: %v", err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(other, "secret.txt"), []byte("secret"), 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+		ctx := common.WithWorkspace(context.Background(), mine)
+
+		result, _ := (&Tool{WorkingDir: root}).Run(ctx, `{"command": "cat ../ou_2/secret.txt"}`)
+		if !strings.Contains(result, "不在允许的目录内") {
+			t.Errorf("Run() = %q, 期望拒绝读取其他发送者的文件", result)
+		}
+		result, _ = (&Tool{WorkingDir: root}).Run(ctx, `{"command": "pwd"}`)
+		if strings.TrimSpace(result) != mine {
+			t.Errorf("Run() = %q, 期望工作目录 %s", result, mine)
+		}
+	})
+}
+
 // TestTool_ExecuteWithOutput 测试执行带输出的命令
 func TestTool_ExecuteWithOutput(t *testing.T) {
 	tool := &Tool{}
@@ -218,6 +291,44 @@ func TestTool_ContextCancellation(t *testing.T) {
 	}
 }
 
+// TestTool_CancelKillsProcessGroup 测试取消时终止命令启动的子进程
+func TestTool_CancelKillsProcessGroup(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	tool := &Tool{}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	result, err := tool.Run(ctx, fmt.Sprintf(`{"command": "(sleep 0.5; touch %s) & sleep 10"}`, marker))
+	if err != nil {
+		t.Fatalf("Run() 返回错误: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("取消后 %v 才返回", elapsed)
+	}
+	if !strings.Contains(result, "命令已取消") {
+		t.Errorf("Run() = %q, 期望包含 命令已取消", result)
+	}
+
+	time.Sleep(time.Second)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("取消后子进程仍在运行")
+	}
+}
+
+// TestTool_TimeoutKillsCommand 测试超时终止命令
+func TestTool_TimeoutKillsCommand(t *testing.T) {
+	tool := &Tool{Timeout: 1}
+	start := time.Now()
+	result, _ := tool.Run(context.Background(), `{"command": "sleep 10"}`)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("超时后 %v 才返回", elapsed)
+	}
+	if !strings.Contains(result, "命令执行超时（1 秒）") {
+		t.Errorf("Run() = %q", result)
+	}
+}
+
 // TestTool_LongOutput 测试长输出截断
 func TestTool_LongOutput(t *testing.T) {
 	tool := &Tool{}
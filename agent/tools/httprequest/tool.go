@@ -0,0 +1,225 @@
+package httprequest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+)
+
+const (
+	defaultMaxResponseBytes = 100000
+	defaultTimeout          = 30 * time.Second
+	maxRedirects            = 5
+)
+
+// allowedMethods 支持的 HTTP 方法
+var allowedMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+	http.MethodHead:   true,
+}
+
+// returnedHeaders 返回给模型的响应头子集
+var returnedHeaders = []string{"Content-Type", "Content-Length", "Location", "Date", "Etag", "Last-Modified", "Retry-After"}
+
+// Tool 通用 HTTP 请求工具
+// 只允许访问 AllowedHosts 中的主机，防止 SSRF
+type Tool struct {
+	AllowedHosts     []string      // 允许访问的主机名，支持 "*.example.com" 匹配子域名
+	MaxResponseBytes int           // 响应体最大字节数
+	Timeout          time.Duration // 请求超时时间
+}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return "http_request"
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "调用 REST API，发送 HTTP 请求并返回状态码、部分响应头和响应体。只能访问配置允许的主机",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"method": {
+				Type:     schema.DataType("string"),
+				Desc:     "HTTP 方法: GET、POST、PUT、PATCH、DELETE、HEAD，默认 GET",
+				Required: false,
+			},
+			"url": {
+				Type:     schema.DataType("string"),
+				Desc:     "请求 URL",
+				Required: true,
+			},
+			"headers": {
+				Type:     schema.DataType("object"),
+				Desc:     "请求头，键值对形式",
+				Required: false,
+			},
+			"body": {
+				Type:     schema.DataType("string"),
+				Desc:     "请求体文本",
+				Required: false,
+			},
+		}),
+	}, nil
+}
+
+// requestResult 请求结果
+type requestResult struct {
+	Status    int               `json:"status"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Truncated bool              `json:"truncated"`
+	Body      string            `json:"body"`
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Method  string            `json:"method"`
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(args.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !allowedMethods[method] {
+		return fmt.Sprintf("错误: 不支持的 HTTP 方法: %s", args.Method), nil
+	}
+	if err := t.checkURL(args.URL); err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("重定向次数超过 %d 次", maxRedirects)
+			}
+			// 重定向目标同样需要在白名单内
+			return t.checkURL(req.URL.String())
+		},
+	}
+
+	var body io.Reader
+	if args.Body != "" {
+		body = strings.NewReader(args.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, args.URL, body)
+	if err != nil {
+		return fmt.Sprintf("错误: 创建请求失败: %s", err), nil
+	}
+	for key, value := range args.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Sprintf("错误: 请求失败: %s", err), nil
+	}
+	defer resp.Body.Close()
+
+	maxBytes := t.MaxResponseBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxResponseBytes
+	}
+	// 多读一个字节用于判断是否截断
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return fmt.Sprintf("错误: 读取响应失败: %s", err), nil
+	}
+	truncated := len(data) > maxBytes
+	if truncated {
+		data = data[:maxBytes]
+	}
+
+	result := requestResult{
+		Status:    resp.StatusCode,
+		Headers:   pickHeaders(resp.Header),
+		Truncated: truncated,
+		Body:      string(data),
+	}
+	out, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("序列化结果失败: %w", err)
+	}
+	return string(out), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// checkURL 校验 URL 协议并检查主机是否在白名单内
+func (t *Tool) checkURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("无效的 URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("只支持 http/https 协议，当前: %s", parsed.Scheme)
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return fmt.Errorf("缺少域名")
+	}
+	if !t.isHostAllowed(host) {
+		return fmt.Errorf("主机 %s 不在允许列表中", host)
+	}
+	return nil
+}
+
+// isHostAllowed 检查主机是否允许访问，未配置白名单时拒绝所有主机
+func (t *Tool) isHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range t.AllowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// pickHeaders 提取需要返回的响应头
+func pickHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for _, key := range returnedHeaders {
+		if value := header.Get(key); value != "" {
+			headers[key] = value
+		}
+	}
+	return headers
+}
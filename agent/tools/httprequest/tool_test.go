@@ -0,0 +1,120 @@
+package httprequest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestTool_Name 测试工具名称
+func TestTool_Name(t *testing.T) {
+	tool := &Tool{}
+	if tool.Name() != "http_request" {
+		t.Errorf("Name() = %q, 期望 http_request", tool.Name())
+	}
+}
+
+// TestTool_isHostAllowed 测试主机白名单匹配
+func TestTool_isHostAllowed(t *testing.T) {
+	tool := &Tool{AllowedHosts: []string{"api.example.com", "*.internal.local"}}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"api.example.com", true},
+		{"API.example.com", true},
+		{"example.com", false},
+		{"svc.internal.local", true},
+		{"internal.local", false},
+		{"evil.com", false},
+	}
+	for _, tt := range tests {
+		if got := tool.isHostAllowed(tt.host); got != tt.want {
+			t.Errorf("isHostAllowed(%q) = %v, 期望 %v", tt.host, got, tt.want)
+		}
+	}
+
+	if (&Tool{}).isHostAllowed("api.example.com") {
+		t.Error("未配置白名单时应该拒绝所有主机")
+	}
+}
+
+// TestTool_Run 测试执行工具
+func TestTool_Run(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Internal", "secret")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + ":" + r.Header.Get("X-Token") + ":" + string(body)))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	t.Run("发送请求", func(t *testing.T) {
+		tool := &Tool{AllowedHosts: []string{"127.0.0.1"}}
+		args, _ := json.Marshal(map[string]any{
+			"method":  "post",
+			"url":     server.URL,
+			"headers": map[string]string{"X-Token": "abc"},
+			"body":    "hello",
+		})
+		out, err := tool.Run(ctx, string(args))
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		var result requestResult
+		if err := json.Unmarshal([]byte(out), &result); err != nil {
+			t.Fatalf("结果不是有效 JSON: %v, %s", err, out)
+		}
+		if result.Status != http.StatusCreated {
+			t.Errorf("Status = %d, 期望 201", result.Status)
+		}
+		if result.Body != "POST:abc:hello" {
+			t.Errorf("Body = %q", result.Body)
+		}
+		if result.Headers["Content-Type"] != "text/plain" {
+			t.Errorf("Content-Type = %q", result.Headers["Content-Type"])
+		}
+		if _, ok := result.Headers["X-Internal"]; ok {
+			t.Error("不应该返回未列出的响应头")
+		}
+	})
+
+	t.Run("响应截断", func(t *testing.T) {
+		tool := &Tool{AllowedHosts: []string{"127.0.0.1"}, MaxResponseBytes: 3}
+		out, _ := tool.Run(ctx, `{"url": "`+server.URL+`"}`)
+		var result requestResult
+		if err := json.Unmarshal([]byte(out), &result); err != nil {
+			t.Fatalf("结果不是有效 JSON: %v", err)
+		}
+		if !result.Truncated || result.Body != "GET" {
+			t.Errorf("Truncated = %v, Body = %q", result.Truncated, result.Body)
+		}
+	})
+
+	t.Run("主机不在白名单", func(t *testing.T) {
+		tool := &Tool{AllowedHosts: []string{"api.example.com"}}
+		out, err := tool.Run(ctx, `{"url": "`+server.URL+`"}`)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if !strings.Contains(out, "不在允许列表中") {
+			t.Errorf("Run() = %q, 期望拒绝访问", out)
+		}
+	})
+
+	t.Run("不支持的方法", func(t *testing.T) {
+		tool := &Tool{AllowedHosts: []string{"127.0.0.1"}}
+		out, _ := tool.Run(ctx, `{"method": "TRACE", "url": "`+server.URL+`"}`)
+		if !strings.HasPrefix(out, "错误: 不支持的 HTTP 方法") {
+			t.Errorf("Run() = %q", out)
+		}
+	})
+}
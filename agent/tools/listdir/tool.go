@@ -44,7 +44,10 @@ func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Opt
 	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
 		return "", err
 	}
-	resolved := common.ResolvePath(args.Path, t.AllowedDir)
+	resolved, err := common.ResolveScopedPath(ctx, args.Path, t.AllowedDir)
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
 	entries, err := os.ReadDir(resolved)
 	if err != nil {
 		return fmt.Sprintf("错误: 读取目录失败: %s", err), nil
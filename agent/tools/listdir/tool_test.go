@@ -2,8 +2,10 @@ package listdir
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cloudwego/eino/schema"
@@ -177,3 +179,31 @@ func TestTool_ListWithFilesAndDirs(t *testing.T) {
 		t.Error("Run() 不应该返回空结果")
 	}
 }
+
+// TestTool_Run_OutsideAllowedDir 测试拒绝访问允许目录之外的路径
+func TestTool_Run_OutsideAllowedDir(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "ws")
+	sibling := filepath.Join(root, "ws-evil")
+	for _, dir := range []string{workspace, sibling} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+	}
+	secret := filepath.Join(sibling, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	tool := &Tool{AllowedDir: workspace}
+
+	for _, path := range []string{secret, filepath.Join(workspace, "..", "ws-evil", "secret.txt"), sibling} {
+		args, _ := json.Marshal(map[string]string{"path": path})
+		result, err := tool.Run(context.Background(), string(args))
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if !strings.Contains(result, "不在允许的目录内") {
+			t.Errorf("Run(%q) = %q, 期望拒绝", path, result)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package message
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
 	"github.com/weibaohui/nanobot-go/agent/tools/common"
 	"github.com/weibaohui/nanobot-go/bus"
 )
@@ -15,9 +17,11 @@ type Sender interface {
 }
 
 type Tool struct {
-	SendCallback   func(msg any) error
+	SendCallback func(msg any) error
+	// DefaultChannel/DefaultChatID 没有正在处理的消息时（如内部调用）使用的默认目标
 	DefaultChannel string
 	DefaultChatID  string
+	Contacts       map[string]common.Contact // 联系人别名，可通过 contact 参数按名称指定目标
 }
 
 // Name 返回工具名称
@@ -27,6 +31,10 @@ func (t *Tool) Name() string {
 
 // Info 返回工具信息
 func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	contactDesc := "联系人别名，指定后无需 channel 和 chat_id"
+	if len(t.Contacts) > 0 {
+		contactDesc += "，可用联系人: " + strings.Join(common.ContactNames(t.Contacts), ", ")
+	}
 	return &schema.ToolInfo{
 		Name: t.Name(),
 		Desc: "发送消息给用户",
@@ -44,6 +52,10 @@ func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 				Type: schema.DataType("string"),
 				Desc: "目标聊天ID",
 			},
+			"contact": {
+				Type: schema.DataType("string"),
+				Desc: contactDesc,
+			},
 		}),
 	}, nil
 }
@@ -54,23 +66,32 @@ func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Opt
 		Content string `json:"content"`
 		Channel string `json:"channel"`
 		ChatID  string `json:"chat_id"`
+		Contact string `json:"contact"`
 	}
 	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
 		return "", err
 	}
 	channel := args.Channel
 	chatID := args.ChatID
-	if channel == "" {
-		channel = t.DefaultChannel
-	}
-	if channel == "user" {
-		channel = t.DefaultChannel
+	if args.Contact != "" {
+		contact, ok := t.Contacts[args.Contact]
+		if !ok {
+			return fmt.Sprintf("错误: 联系人 %s 不存在，可用联系人: %s", args.Contact, strings.Join(common.ContactNames(t.Contacts), ", ")), nil
+		}
+		channel, chatID = contact.Channel, contact.ChatID
 	}
-	if chatID == "" {
-		chatID = t.DefaultChatID
+	// 只有渠道也使用默认值时才沿用默认聊天ID，默认聊天ID不属于显式指定的其他渠道
+	if channel == "" || channel == "user" {
+		defaultChannel, defaultChatID := t.replyTarget(ctx)
+		channel = defaultChannel
+		if chatID == "" {
+			chatID = defaultChatID
+		}
+	} else if chatID == "" {
+		return fmt.Sprintf("错误: 指定渠道 %s 时需要 chat_id", channel), nil
 	}
 	if channel == "" || chatID == "" {
-		return "错误: 没有目标渠道或聊天ID", nil
+		return "错误: 没有目标渠道或聊天ID，请在参数中指定 channel 和 chat_id", nil
 	}
 	if t.SendCallback == nil {
 		return "错误: 消息发送未配置", nil
@@ -82,6 +103,14 @@ func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Opt
 	return fmt.Sprintf("消息已发送到 %s:%s", channel, chatID), nil
 }
 
+// replyTarget 返回默认的发送目标：处理消息时为当前消息所在的会话，否则为配置的默认目标
+func (t *Tool) replyTarget(ctx context.Context) (string, string) {
+	if channel, chatID := trace.GetChannel(ctx), trace.GetChatID(ctx); channel != "" && chatID != "" {
+		return channel, chatID
+	}
+	return t.DefaultChannel, t.DefaultChatID
+}
+
 // InvokableRun 可直接调用的执行入口
 func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	return t.Run(ctx, argumentsInJSON, opts...)
@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
 	"github.com/weibaohui/nanobot-go/bus"
 )
 
@@ -91,8 +93,8 @@ func TestTool_Run(t *testing.T) {
 			t.Errorf("Run() 返回错误: %v", err)
 		}
 
-		if result != "错误: 没有目标渠道或聊天ID" {
-			t.Errorf("Run() = %q, 期望 错误: 没有目标渠道或聊天ID", result)
+		if result != "错误: 没有目标渠道或聊天ID，请在参数中指定 channel 和 chat_id" {
+			t.Errorf("Run() = %q, 期望提示指定目标", result)
 		}
 	})
 
@@ -148,6 +150,63 @@ func TestTool_Run(t *testing.T) {
 			t.Errorf("Run() = %q, 期望 消息已发送到 websocket:chat-001", result)
 		}
 	})
+
+	t.Run("处理消息时默认发送到当前会话", func(t *testing.T) {
+		tool := &Tool{
+			SendCallback:   mockSendCallback,
+			DefaultChannel: "websocket",
+			DefaultChatID:  "chat-001",
+		}
+		ctx := trace.WithChatID(trace.WithSessionInfo(context.Background(), "feishu:oc_123", "feishu"), "oc_123")
+
+		for _, args := range []string{`{"content": "测试消息"}`, `{"content": "测试消息", "channel": "user"}`} {
+			result, err := tool.Run(ctx, args)
+			if err != nil {
+				t.Errorf("Run() 返回错误: %v", err)
+			}
+			if result != "消息已发送到 feishu:oc_123" {
+				t.Errorf("Run(%s) = %q, 期望 消息已发送到 feishu:oc_123", args, result)
+			}
+		}
+	})
+
+	t.Run("指定其他渠道但未指定聊天ID", func(t *testing.T) {
+		tool := &Tool{SendCallback: mockSendCallback}
+		ctx := trace.WithChatID(trace.WithSessionInfo(context.Background(), "feishu:oc_123", "feishu"), "oc_123")
+
+		result, err := tool.Run(ctx, `{"content": "测试消息", "channel": "dingtalk"}`)
+		if err != nil {
+			t.Errorf("Run() 返回错误: %v", err)
+		}
+		if result != "错误: 指定渠道 dingtalk 时需要 chat_id" {
+			t.Errorf("Run() = %q, 不应沿用当前会话的聊天ID", result)
+		}
+	})
+}
+
+// TestTool_RunContact 测试按联系人别名发送
+func TestTool_RunContact(t *testing.T) {
+	var sent *bus.OutboundMessage
+	tool := &Tool{
+		SendCallback: func(msg any) error {
+			sent = msg.(*bus.OutboundMessage)
+			return nil
+		},
+		DefaultChannel: "websocket",
+		DefaultChatID:  "chat-001",
+		Contacts:       map[string]common.Contact{"family-group": {Channel: "matrix", ChatID: "!abc:matrix.org"}},
+	}
+	ctx := context.Background()
+
+	result, _ := tool.Run(ctx, `{"content": "晚饭好了", "contact": "family-group"}`)
+	if result != "消息已发送到 matrix:!abc:matrix.org" || sent.Channel != "matrix" || sent.ChatID != "!abc:matrix.org" {
+		t.Errorf("Run() = %q, 发送目标 = %s:%s", result, sent.Channel, sent.ChatID)
+	}
+
+	result, _ = tool.Run(ctx, `{"content": "晚饭好了", "contact": "老板"}`)
+	if result != "错误: 联系人 老板 不存在，可用联系人: family-group" {
+		t.Errorf("Run() = %q", result)
+	}
 }
 
 // TestTool_SetContext 测试设置上下文
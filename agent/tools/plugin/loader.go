@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eino-contrib/jsonschema"
+)
+
+// namePattern 插件工具名称只允许字母、数字、下划线和连字符
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Spec 插件描述文件（<name>.json）的内容
+type Spec struct {
+	Name        string             `json:"name"`        // 工具名称，为空时使用文件名
+	Description string             `json:"description"` // 工具描述
+	Parameters  *jsonschema.Schema `json:"parameters"`  // 参数的 JSON Schema
+	Command     string             `json:"command"`     // 可执行文件（相对插件目录），为空时查找与描述文件同名的可执行文件
+	Timeout     int                `json:"timeout"`     // 超时时间（秒），0 使用全局配置
+}
+
+// LoadDir 加载目录中的所有插件工具
+// 每个插件由 <name>.json 描述文件和一个可执行文件组成；无效的插件会被跳过并在 errs 中返回原因
+func LoadDir(dir string, base Tool) (tools []*Tool, errs []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{fmt.Errorf("读取插件目录失败: %w", err)}
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		t, err := loadPlugin(dir, entry.Name(), base)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		if seen[t.Spec.Name] {
+			errs = append(errs, fmt.Errorf("%s: 工具名称重复: %s", entry.Name(), t.Spec.Name))
+			continue
+		}
+		seen[t.Spec.Name] = true
+		tools = append(tools, t)
+	}
+
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Spec.Name < tools[j].Spec.Name })
+	return tools, errs
+}
+
+// loadPlugin 解析单个描述文件并定位可执行文件
+func loadPlugin(dir, specFile string, base Tool) (*Tool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, specFile))
+	if err != nil {
+		return nil, err
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("解析描述文件失败: %w", err)
+	}
+
+	baseName := strings.TrimSuffix(specFile, ".json")
+	if spec.Name == "" {
+		spec.Name = baseName
+	}
+	if !namePattern.MatchString(spec.Name) {
+		return nil, fmt.Errorf("无效的工具名称: %q", spec.Name)
+	}
+	if strings.TrimSpace(spec.Description) == "" {
+		return nil, fmt.Errorf("缺少 description")
+	}
+
+	command, err := findExecutable(dir, baseName, spec.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	t := base
+	t.Spec = spec
+	t.Path = command
+	if spec.Timeout > 0 {
+		t.Timeout = spec.Timeout
+	}
+	return &t, nil
+}
+
+// findExecutable 查找插件的可执行文件，必须位于插件目录内
+func findExecutable(dir, baseName, command string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	if command != "" {
+		candidates = []string{command}
+	} else {
+		matches, _ := filepath.Glob(filepath.Join(absDir, baseName+".*"))
+		candidates = append(candidates, baseName)
+		for _, m := range matches {
+			if filepath.Ext(m) != ".json" {
+				candidates = append(candidates, filepath.Base(m))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		path := filepath.Join(absDir, candidate)
+		rel, err := filepath.Rel(absDir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("可执行文件必须位于插件目录内: %s", candidate)
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if info.Mode()&0111 == 0 {
+			return "", fmt.Errorf("文件不可执行: %s", candidate)
+		}
+		return path, nil
+	}
+	return "", fmt.Errorf("未找到可执行文件")
+}
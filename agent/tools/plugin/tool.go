@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+)
+
+// 默认限制
+const (
+	defaultTimeout        = 30
+	defaultMaxOutputBytes = 50000
+)
+
+// Tool 外部可执行文件插件工具
+// 调用时将 JSON 参数写入标准输入，从标准输出读取 JSON 结果
+type Tool struct {
+	Spec           Spec
+	Path           string // 可执行文件绝对路径
+	WorkingDir     string // 执行目录（工作区）
+	Timeout        int    // 超时时间（秒）
+	MaxOutputBytes int    // 标准输出最大字节数
+}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return t.Spec.Name
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	info := &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: t.Spec.Description,
+	}
+	if t.Spec.Parameters != nil {
+		info.ParamsOneOf = schema.NewParamsOneOfByJSONSchema(t.Spec.Parameters)
+	} else {
+		info.ParamsOneOf = schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{})
+	}
+	return info, nil
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	if strings.TrimSpace(argumentsInJSON) == "" {
+		argumentsInJSON = "{}"
+	}
+	if !json.Valid([]byte(argumentsInJSON)) {
+		return "错误: 参数不是有效的 JSON", nil
+	}
+
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	maxOutput := t.MaxOutputBytes
+	if maxOutput <= 0 {
+		maxOutput = defaultMaxOutputBytes
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, t.Path)
+	cmd.Dir, _ = common.ScopeDirs(ctx, t.WorkingDir, "")
+	cmd.Env = append(os.Environ(), "NANOBOT_WORKSPACE="+cmd.Dir, "NANOBOT_TOOL="+t.Name())
+	cmd.Stdin = strings.NewReader(argumentsInJSON)
+	stdout := &limitedBuffer{limit: maxOutput}
+	stderr := &limitedBuffer{limit: 2000}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// 超时后子进程派生的后台进程可能仍占用输出管道，限制等待时间
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Sprintf("错误: 插件执行超时（%d 秒）", timeout), nil
+	}
+	if err != nil {
+		msg := fmt.Sprintf("错误: 插件执行失败: %s", err)
+		if s := strings.TrimSpace(stderr.String()); s != "" {
+			msg += "\n" + s
+		}
+		return msg, nil
+	}
+	if stdout.truncated {
+		return fmt.Sprintf("错误: 插件输出超过 %d 字节", maxOutput), nil
+	}
+
+	return parseOutput(stdout.Bytes())
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// parseOutput 解析插件输出
+// 输出为 {"error": "..."} 时返回错误信息，为 {"result": ...} 时返回 result，其他 JSON 原样返回
+func parseOutput(output []byte) (string, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return "错误: 插件没有输出", nil
+	}
+	if !json.Valid(output) {
+		return "错误: 插件输出不是有效的 JSON", nil
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(output, &envelope); err == nil {
+		if raw, ok := envelope["error"]; ok {
+			var msg string
+			if json.Unmarshal(raw, &msg) != nil {
+				msg = string(raw)
+			}
+			if msg != "" {
+				return "错误: " + msg, nil
+			}
+		}
+		if raw, ok := envelope["result"]; ok {
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				return s, nil
+			}
+			return string(raw), nil
+		}
+	}
+	return string(output), nil
+}
+
+// limitedBuffer 超出上限后丢弃数据的缓冲区
+// 不嵌入 bytes.Buffer，避免 io.Copy 通过 ReadFrom 绕过长度限制
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write 写入数据，超出上限的部分被丢弃但不返回错误，避免子进程因管道关闭而异常退出
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if len(p) > remaining {
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// Bytes 返回已写入的数据
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// String 返回已写入的数据
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePlugin 在目录中写入插件描述文件和脚本
+func writePlugin(t *testing.T, dir, name, spec, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if script != "" {
+		if err := os.WriteFile(filepath.Join(dir, name+".sh"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestLoadDir 测试加载插件目录
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "echo", `{"description": "回显参数", "parameters": {"type": "object", "properties": {"text": {"type": "string"}}, "required": ["text"]}}`, `cat`)
+	writePlugin(t, dir, "nodesc", `{}`, `cat`)
+	writePlugin(t, dir, "noexec", `{"description": "没有可执行文件"}`, "")
+
+	tools, errs := LoadDir(dir, Tool{WorkingDir: dir, Timeout: 5})
+	if len(tools) != 1 || tools[0].Name() != "echo" {
+		t.Fatalf("tools = %v, 期望只加载 echo", tools)
+	}
+	if len(errs) != 2 {
+		t.Errorf("len(errs) = %d, 期望 2: %v", len(errs), errs)
+	}
+
+	info, err := tools[0].Info(context.Background())
+	if err != nil || info.Desc != "回显参数" || info.ParamsOneOf == nil {
+		t.Errorf("Info() = %+v, %v", info, err)
+	}
+
+	if tools, errs := LoadDir(filepath.Join(dir, "missing"), Tool{}); tools != nil || errs != nil {
+		t.Errorf("目录不存在时应返回空: %v, %v", tools, errs)
+	}
+}
+
+// TestTool_Run 测试执行插件
+func TestTool_Run(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "echo", `{"description": "回显"}`, `cat`)
+	writePlugin(t, dir, "wrap", `{"description": "包装结果"}`, `read input; echo "{\"result\": \"收到 $input\"}"`)
+	writePlugin(t, dir, "fail", `{"description": "返回错误"}`, `echo '{"error": "城市不存在"}'`)
+	writePlugin(t, dir, "text", `{"description": "非 JSON 输出"}`, `echo hello`)
+	writePlugin(t, dir, "slow", `{"description": "超时", "timeout": 1}`, `sleep 5`)
+	writePlugin(t, dir, "big", `{"description": "输出过大"}`, `head -c 1000 /dev/zero | tr '\0' a`)
+
+	tools, errs := LoadDir(dir, Tool{WorkingDir: dir, Timeout: 5, MaxOutputBytes: 100})
+	if len(errs) != 0 {
+		t.Fatalf("LoadDir() errs = %v", errs)
+	}
+	byName := make(map[string]*Tool)
+	for _, tool := range tools {
+		byName[tool.Name()] = tool
+	}
+
+	tests := []struct {
+		tool string
+		args string
+		want string
+	}{
+		{"echo", `{"text":"你好"}`, `{"text":"你好"}`},
+		{"wrap", `{}`, "收到 {}"},
+		{"fail", `{}`, "错误: 城市不存在"},
+		{"text", `{}`, "错误: 插件输出不是有效的 JSON"},
+		{"slow", `{}`, "错误: 插件执行超时（1 秒）"},
+		{"big", `{}`, "错误: 插件输出超过 100 字节"},
+		{"echo", `不是JSON`, "错误: 参数不是有效的 JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			got, err := byName[tt.tool].Run(context.Background(), tt.args)
+			if err != nil {
+				t.Fatalf("Run() 返回错误: %v", err)
+			}
+			if strings.TrimSpace(got) != tt.want {
+				t.Errorf("Run() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
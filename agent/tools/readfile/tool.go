@@ -1,18 +1,33 @@
 package readfile
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
 	"github.com/weibaohui/nanobot-go/agent/tools/common"
 )
 
+// 默认限制
+const (
+	defaultMaxBytes     = 1 << 20 // 允许读取的最大文件大小：1MB
+	defaultHexdumpBytes = 256     // 二进制文件十六进制预览的字节数
+	sniffBytes          = 8000    // 判断是否为二进制文件时检查的字节数
+)
+
 // Tool 读取文件工具
 type Tool struct {
-	AllowedDir string
+	AllowedDir   string
+	MaxBytes     int64 // 允许读取的最大文件大小（字节），0 使用默认值 1MB
+	HexdumpBytes int   // 二进制文件返回十六进制预览的字节数，0 使用默认值 256
 }
 
 // Name 返回工具名称
@@ -24,13 +39,21 @@ func (t *Tool) Name() string {
 func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 	return &schema.ToolInfo{
 		Name: t.Name(),
-		Desc: "读取指定路径的文件内容",
+		Desc: "读取指定路径的文本文件内容；二进制文件只返回开头部分的十六进制预览。大文件可指定 start_line/end_line 分段读取，返回带行号的内容",
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"path": {
 				Type:     schema.DataType("string"),
 				Desc:     "要读取的文件路径",
 				Required: true,
 			},
+			"start_line": {
+				Type: schema.DataType("integer"),
+				Desc: "起始行号（从 1 开始，包含），不指定行范围时读取整个文件",
+			},
+			"end_line": {
+				Type: schema.DataType("integer"),
+				Desc: "结束行号（包含），只指定 start_line 时读到文件末尾",
+			},
 		}),
 	}, nil
 }
@@ -38,17 +61,57 @@ func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 // Run 执行工具逻辑
 func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	var args struct {
-		Path string `json:"path"`
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
 	}
 	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
 		return "", err
 	}
-	resolved := common.ResolvePath(args.Path, t.AllowedDir)
-	data, err := os.ReadFile(resolved)
+	resolved, err := common.ResolveScopedPath(ctx, args.Path, t.AllowedDir)
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		return fmt.Sprintf("错误: 读取文件失败: %s", err), nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Sprintf("错误: 读取文件失败: %s", err), nil
+	}
+	if info.IsDir() {
+		return fmt.Sprintf("错误: %s 是目录，请使用 list_dir", args.Path), nil
+	}
+
+	// 先检查开头部分，二进制文件不论大小都只返回预览
+	head := make([]byte, max(sniffBytes, t.hexdumpBytes()))
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Sprintf("错误: 读取文件失败: %s", err), nil
+	}
+	head = head[:n]
+	if isBinary(head) {
+		preview := head[:min(len(head), t.hexdumpBytes())]
+		return fmt.Sprintf("%s 是二进制文件（%d 字节），不返回内容。前 %d 字节的十六进制预览:\n%s",
+			args.Path, info.Size(), len(preview), hex.Dump(preview)), nil
+	}
+
+	// 指定行范围时逐行读取，不受文件大小上限限制
+	if args.StartLine != 0 || args.EndLine != 0 {
+		return t.readLines(io.MultiReader(bytes.NewReader(head), f), args.Path, args.StartLine, args.EndLine), nil
+	}
+
+	if maxBytes := t.maxBytes(); info.Size() > maxBytes {
+		return fmt.Sprintf("错误: 文件大小 %d 字节超过上限 %d 字节，请指定 start_line/end_line 分段读取", info.Size(), maxBytes), nil
+	}
+	rest, err := io.ReadAll(f)
 	if err != nil {
 		return fmt.Sprintf("错误: 读取文件失败: %s", err), nil
 	}
-	result := string(data)
+	result := string(head) + string(rest)
 	// 确保不返回空字符串，避免 Eino 框架构造无效的工具消息
 	if result == "" {
 		result = "(命令执行完成，文件为空)"
@@ -60,3 +123,84 @@ func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Opt
 func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	return t.Run(ctx, argumentsInJSON, opts...)
 }
+
+// readLines 返回第 start 到 end 行（包含两端）并加上行号，end 为 0 表示读到文件末尾
+// 输出超过大小上限时截断，并提示从哪一行继续读取
+func (t *Tool) readLines(r io.Reader, path string, start, end int) string {
+	if start == 0 {
+		start = 1
+	}
+	if start < 0 || end < 0 {
+		return "错误: 行号必须为正整数"
+	}
+	if end != 0 && end < start {
+		return fmt.Sprintf("错误: end_line %d 小于 start_line %d", end, start)
+	}
+
+	maxBytes := t.maxBytes()
+	reader := bufio.NewReader(r)
+	var sb strings.Builder
+	total, last := 0, 0
+	truncated := false
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			total++
+			if total >= start && (end == 0 || total <= end) && !truncated {
+				numbered := fmt.Sprintf("%6d\t%s\n", total, strings.TrimRight(line, "\r\n"))
+				if int64(sb.Len()+len(numbered)) > maxBytes {
+					truncated = true
+				} else {
+					sb.WriteString(numbered)
+					last = total
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Sprintf("错误: 读取文件失败: %s", err)
+		}
+	}
+
+	if start > total {
+		return fmt.Sprintf("错误: start_line %d 超出文件总行数 %d", start, total)
+	}
+	if last == 0 {
+		return fmt.Sprintf("错误: 第 %d 行超过 %d 字节，请使用 exec 工具查看", start, maxBytes)
+	}
+	result := fmt.Sprintf("%s 共 %d 行，第 %d-%d 行:\n%s", path, total, start, last, sb.String())
+	if truncated {
+		result += fmt.Sprintf("...(输出超过 %d 字节已截断，可从第 %d 行继续读取)", maxBytes, last+1)
+	}
+	return result
+}
+
+// maxBytes 返回允许读取的最大文件大小
+func (t *Tool) maxBytes() int64 {
+	if t.MaxBytes > 0 {
+		return t.MaxBytes
+	}
+	return defaultMaxBytes
+}
+
+// hexdumpBytes 返回二进制文件十六进制预览的字节数
+func (t *Tool) hexdumpBytes() int {
+	if t.HexdumpBytes > 0 {
+		return t.HexdumpBytes
+	}
+	return defaultHexdumpBytes
+}
+
+// isBinary 根据文件开头判断是否为二进制文件：包含 NUL 字节或不是合法的 UTF-8
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	// 开头部分可能截断在多字节字符中间，忽略结尾不完整的字符
+	for i := 0; i < utf8.UTFMax && len(head) > 0 && !utf8.Valid(head); i++ {
+		head = head[:len(head)-1]
+	}
+	return !utf8.Valid(head)
+}
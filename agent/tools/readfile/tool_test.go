@@ -2,8 +2,10 @@ package readfile
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cloudwego/eino/schema"
@@ -251,3 +253,120 @@ func TestTool_ReadLargeFile(t *testing.T) {
 		t.Errorf("result 长度 = %d, 期望 1024", len(result))
 	}
 }
+
+// TestTool_Guards 测试文件大小和二进制文件限制
+func TestTool_Guards(t *testing.T) {
+	tmpDir := t.TempDir()
+	ctx := context.Background()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(tmpDir, name)
+		os.WriteFile(path, data, 0644)
+		return path
+	}
+
+	t.Run("超过大小上限返回错误", func(t *testing.T) {
+		path := write("big.txt", []byte(strings.Repeat("a", 100)))
+		result, _ := (&Tool{MaxBytes: 50}).Run(ctx, `{"path": "`+path+`"}`)
+		if result != "错误: 文件大小 100 字节超过上限 50 字节，请指定 start_line/end_line 分段读取" {
+			t.Errorf("Run() = %q", result)
+		}
+	})
+
+	t.Run("二进制文件返回十六进制预览", func(t *testing.T) {
+		path := write("data.bin", append([]byte("\x7fELF\x00\x01"), make([]byte, 100)...))
+		result, _ := (&Tool{HexdumpBytes: 16}).Run(ctx, `{"path": "`+path+`"}`)
+		if !strings.Contains(result, "是二进制文件（106 字节），不返回内容。前 16 字节的十六进制预览") ||
+			!strings.Contains(result, "7f 45 4c 46 00 01") || strings.Contains(result, "00000010") {
+			t.Errorf("Run() = %q", result)
+		}
+	})
+
+	t.Run("多字节字符跨越检查边界不视为二进制", func(t *testing.T) {
+		content := strings.Repeat("a", sniffBytes-1) + "中文"
+		path := write("utf8.txt", []byte(content))
+		if result, _ := (&Tool{}).Run(ctx, `{"path": "`+path+`"}`); result != content {
+			t.Errorf("Run() 长度 = %d, 期望 %d", len(result), len(content))
+		}
+	})
+
+	t.Run("目录返回错误", func(t *testing.T) {
+		if result, _ := (&Tool{}).Run(ctx, `{"path": "`+tmpDir+`"}`); !strings.HasPrefix(result, "错误: ") {
+			t.Errorf("Run() = %q", result)
+		}
+	})
+}
+
+// TestTool_LineRange 测试按行范围读取
+func TestTool_LineRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	os.WriteFile(path, []byte("package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n}\n"), 0644)
+	ctx := context.Background()
+	run := func(tool *Tool, args string) string {
+		result, err := tool.Run(ctx, `{"path": "`+path+`", `+args+`}`)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		return result
+	}
+
+	t.Run("返回指定行并带行号", func(t *testing.T) {
+		want := path + " 共 7 行，第 5-6 行:\n     5\tfunc main() {\n     6\t\tfmt.Println(1)\n"
+		if got := run(&Tool{}, `"start_line": 5, "end_line": 6`); got != want {
+			t.Errorf("Run() = %q, 期望 %q", got, want)
+		}
+	})
+
+	t.Run("只指定起始行时读到末尾", func(t *testing.T) {
+		if got := run(&Tool{}, `"start_line": 7`); !strings.HasSuffix(got, "第 7-7 行:\n     7\t}\n") {
+			t.Errorf("Run() = %q", got)
+		}
+	})
+
+	t.Run("超过大小上限时截断并提示续读位置", func(t *testing.T) {
+		got := run(&Tool{MaxBytes: 50}, `"start_line": 1`)
+		if !strings.Contains(got, "第 1-3 行") || !strings.HasSuffix(got, "可从第 4 行继续读取)") {
+			t.Errorf("Run() = %q", got)
+		}
+	})
+
+	t.Run("无效的行范围", func(t *testing.T) {
+		cases := map[string]string{
+			`"start_line": 9`:                "错误: start_line 9 超出文件总行数 7",
+			`"start_line": 5, "end_line": 2`: "错误: end_line 2 小于 start_line 5",
+			`"start_line": -1`:               "错误: 行号必须为正整数",
+		}
+		for args, want := range cases {
+			if got := run(&Tool{}, args); got != want {
+				t.Errorf("Run(%s) = %q, 期望 %q", args, got, want)
+			}
+		}
+	})
+}
+
+// TestTool_Run_OutsideAllowedDir 测试拒绝访问允许目录之外的路径
+func TestTool_Run_OutsideAllowedDir(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "ws")
+	sibling := filepath.Join(root, "ws-evil")
+	for _, dir := range []string{workspace, sibling} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+	}
+	secret := filepath.Join(sibling, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	tool := &Tool{AllowedDir: workspace}
+
+	for _, path := range []string{secret, filepath.Join(workspace, "..", "ws-evil", "secret.txt"), secret} {
+		args, _ := json.Marshal(map[string]string{"path": path})
+		result, err := tool.Run(context.Background(), string(args))
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if !strings.Contains(result, "不在允许的目录内") {
+			t.Errorf("Run(%q) = %q, 期望拒绝", path, result)
+		}
+	}
+}
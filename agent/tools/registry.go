@@ -17,6 +17,8 @@ type Registry struct {
 	mu          sync.RWMutex
 	hookManager *hooks.HookManager
 	logger      *zap.Logger
+	enabled     map[string]bool // 工具白名单，为空表示全部允许
+	disabled    map[string]bool // 工具黑名单
 }
 
 // NewRegistry 创建工具注册表
@@ -35,8 +37,37 @@ func (r *Registry) SetHookManager(hookManager *hooks.HookManager, logger *zap.Lo
 	r.logger = logger
 }
 
+// SetToolFilter 设置工具启用/禁用列表
+// enabled 为空表示允许所有工具；disabled 中的工具始终被拒绝
+// 需在注册工具之前调用，被过滤的工具不会进入注册表
+func (r *Registry) SetToolFilter(enabled, disabled []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enabled = toNameSet(enabled)
+	r.disabled = toNameSet(disabled)
+}
+
+// IsToolAllowed 检查工具是否允许注册
+func (r *Registry) IsToolAllowed(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.isAllowedLocked(name)
+}
+
+// isAllowedLocked 检查工具是否允许注册，调用方需持有锁
+func (r *Registry) isAllowedLocked(name string) bool {
+	if r.disabled[name] {
+		return false
+	}
+	if len(r.enabled) > 0 && !r.enabled[name] {
+		return false
+	}
+	return true
+}
+
 // Register 注册工具
 // 如果设置了 HookManager，工具将被自动包装以支持 Hook 事件
+// 被配置禁用的工具将被跳过
 func (r *Registry) Register(baseTool tool.BaseTool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -44,6 +75,12 @@ func (r *Registry) Register(baseTool tool.BaseTool) {
 	if name == "" {
 		return
 	}
+	if !r.isAllowedLocked(name) {
+		if r.logger != nil {
+			r.logger.Info("工具已被配置禁用，跳过注册", zap.String("名称", name))
+		}
+		return
+	}
 
 	// 如果设置了 HookManager 且工具支持 InvokableRun，包装它
 	if r.hookManager != nil {
@@ -127,6 +164,18 @@ func (r *Registry) Execute(ctx context.Context, name string, params map[string]a
 	return invokable.InvokableRun(ctx, string(argsJSON))
 }
 
+// toNameSet 将名称列表转换为集合
+func toNameSet(names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
+
 // resolveToolName 解析工具名称
 func (r *Registry) resolveToolName(ctx context.Context, baseTool tool.BaseTool) string {
 	if named, ok := baseTool.(NamedTool); ok {
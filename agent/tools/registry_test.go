@@ -90,6 +90,38 @@ func TestRegistry_Register(t *testing.T) {
 	})
 }
 
+// TestRegistry_SetToolFilter 测试工具启用/禁用过滤
+func TestRegistry_SetToolFilter(t *testing.T) {
+	t.Run("禁用列表", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.SetToolFilter(nil, []string{"exec"})
+		registry.Register(&mockTool{name: "exec"})
+		registry.Register(&mockTool{name: "read_file"})
+
+		if registry.Get("exec") != nil {
+			t.Error("被禁用的工具不应该被注册")
+		}
+		if registry.Get("read_file") == nil {
+			t.Error("未禁用的工具应该被注册")
+		}
+	})
+
+	t.Run("启用列表", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.SetToolFilter([]string{"read_file", "exec"}, []string{"exec"})
+
+		if !registry.IsToolAllowed("read_file") {
+			t.Error("read_file 应该被允许")
+		}
+		if registry.IsToolAllowed("exec") {
+			t.Error("禁用列表优先级应该高于启用列表")
+		}
+		if registry.IsToolAllowed("cron") {
+			t.Error("不在启用列表中的工具不应该被允许")
+		}
+	})
+}
+
 // TestRegistry_Get 测试获取工具
 func TestRegistry_Get(t *testing.T) {
 	registry := NewRegistry()
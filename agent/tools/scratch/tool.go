@@ -0,0 +1,161 @@
+package scratch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	"github.com/weibaohui/nanobot-go/session"
+)
+
+// Store 草稿变量存储接口
+type Store interface {
+	ScratchSet(key, name, value string) error
+	ScratchGet(key, name string) (string, bool)
+	ScratchList(key string) map[string]string
+}
+
+// SessionKeyFunc 从 context 中获取当前会话键
+type SessionKeyFunc func(ctx context.Context) string
+
+// SetTool 设置会话草稿变量工具
+type SetTool struct {
+	Store      Store
+	SessionKey SessionKeyFunc
+}
+
+// Name 返回工具名称
+func (t *SetTool) Name() string {
+	return "scratch_set"
+}
+
+// Info 返回工具信息
+func (t *SetTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "在当前会话的草稿区保存一个变量，用于跨轮次记住中间结果（如已查到的 ID、进度、待办）。value 为空时删除该变量",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"name": {
+				Type:     schema.DataType("string"),
+				Desc:     "变量名",
+				Required: true,
+			},
+			"value": {
+				Type:     schema.DataType("string"),
+				Desc:     "变量值，为空表示删除",
+				Required: false,
+			},
+		}),
+	}, nil
+}
+
+// Run 执行工具逻辑
+func (t *SetTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(args.Name)
+	if name == "" {
+		return "错误: 变量名不能为空", nil
+	}
+	key, errMsg := resolveSession(ctx, t.Store, t.SessionKey)
+	if errMsg != "" {
+		return errMsg, nil
+	}
+
+	if err := t.Store.ScratchSet(key, name, args.Value); err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+	if args.Value == "" {
+		return fmt.Sprintf("已删除草稿变量 %s", name), nil
+	}
+	return fmt.Sprintf("已保存草稿变量 %s", name), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *SetTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// GetTool 读取会话草稿变量工具
+type GetTool struct {
+	Store      Store
+	SessionKey SessionKeyFunc
+}
+
+// Name 返回工具名称
+func (t *GetTool) Name() string {
+	return "scratch_get"
+}
+
+// Info 返回工具信息
+func (t *GetTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "读取当前会话草稿区中保存的变量。不指定 name 时列出全部变量",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"name": {
+				Type:     schema.DataType("string"),
+				Desc:     "变量名，为空时列出全部",
+				Required: false,
+			},
+		}),
+	}, nil
+}
+
+// Run 执行工具逻辑
+func (t *GetTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Name string `json:"name"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+	key, errMsg := resolveSession(ctx, t.Store, t.SessionKey)
+	if errMsg != "" {
+		return errMsg, nil
+	}
+
+	if name := strings.TrimSpace(args.Name); name != "" {
+		value, ok := t.Store.ScratchGet(key, name)
+		if !ok {
+			return fmt.Sprintf("草稿变量 %s 不存在", name), nil
+		}
+		return value, nil
+	}
+
+	all := t.Store.ScratchList(key)
+	if len(all) == 0 {
+		return "草稿区为空", nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "草稿变量（%d 个）:\n", len(all))
+	for _, name := range session.ScratchNames(all) {
+		fmt.Fprintf(&sb, "- %s: %s\n", name, all[name])
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *GetTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// resolveSession 获取当前会话键，失败时返回面向模型的错误信息
+func resolveSession(ctx context.Context, store Store, sessionKey SessionKeyFunc) (string, string) {
+	if store == nil || sessionKey == nil {
+		return "", "错误: 草稿区未配置"
+	}
+	key := sessionKey(ctx)
+	if key == "" {
+		return "", "错误: 无法确定当前会话"
+	}
+	return key, ""
+}
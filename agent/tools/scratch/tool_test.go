@@ -0,0 +1,60 @@
+package scratch
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/session"
+	"go.uber.org/zap"
+)
+
+type sessionKeyCtx struct{}
+
+func sessionKeyFromCtx(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyCtx{}).(string)
+	return key
+}
+
+// TestScratchTools 测试草稿区工具
+func TestScratchTools(t *testing.T) {
+	store := session.NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), nil)
+	set := &SetTool{Store: store, SessionKey: sessionKeyFromCtx}
+	get := &GetTool{Store: store, SessionKey: sessionKeyFromCtx}
+	ctx := context.WithValue(context.Background(), sessionKeyCtx{}, "websocket:chat1")
+
+	t.Run("保存后读取", func(t *testing.T) {
+		result, err := set.InvokableRun(ctx, `{"name":"city","value":"杭州"}`)
+		if err != nil || result != "已保存草稿变量 city" {
+			t.Fatalf("scratch_set = %q, %v", result, err)
+		}
+		result, _ = get.InvokableRun(ctx, `{"name":"city"}`)
+		if result != "杭州" {
+			t.Errorf("scratch_get = %q, 期望 杭州", result)
+		}
+	})
+
+	t.Run("不指定变量名时列出全部", func(t *testing.T) {
+		_, _ = set.InvokableRun(ctx, `{"name":"budget","value":"3000"}`)
+		result, _ := get.InvokableRun(ctx, `{}`)
+		if !strings.Contains(result, "- budget: 3000") || !strings.Contains(result, "- city: 杭州") {
+			t.Errorf("列表结果 = %q", result)
+		}
+	})
+
+	t.Run("缺少会话或变量名时返回错误信息", func(t *testing.T) {
+		result, _ := get.InvokableRun(context.Background(), `{}`)
+		if !strings.HasPrefix(result, "错误:") {
+			t.Errorf("缺少会话时应返回错误信息，得到 %q", result)
+		}
+		result, _ = set.InvokableRun(ctx, `{"name":" "}`)
+		if result != "错误: 变量名不能为空" {
+			t.Errorf("空变量名结果 = %q", result)
+		}
+		result, _ = get.InvokableRun(ctx, `{"name":"missing"}`)
+		if result != "草稿变量 missing 不存在" {
+			t.Errorf("不存在的变量结果 = %q", result)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package structurededit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// encodeJSON 将 YAML 节点编码为 JSON，保持对象键的原有顺序
+func encodeJSON(node *yaml.Node, indent string) (string, error) {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, node, indent, 0); err != nil {
+		return "", err
+	}
+	buf.WriteByte('\n')
+	return buf.String(), nil
+}
+
+// writeJSON 递归写入 JSON
+func writeJSON(buf *bytes.Buffer, node *yaml.Node, indent string, depth int) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSON(buf, node.Content[0], indent, depth)
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias, indent, depth)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			buf.WriteString(strings.Repeat(indent, depth+1))
+			writeString(buf, node.Content[i].Value)
+			buf.WriteString(": ")
+			if err := writeJSON(buf, node.Content[i+1], indent, depth+1); err != nil {
+				return err
+			}
+			if i+2 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(indent, depth) + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i, item := range node.Content {
+			buf.WriteString(strings.Repeat(indent, depth+1))
+			if err := writeJSON(buf, item, indent, depth+1); err != nil {
+				return err
+			}
+			if i+1 < len(node.Content) {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat(indent, depth) + "]")
+	case yaml.ScalarNode:
+		return writeScalar(buf, node)
+	default:
+		return fmt.Errorf("不支持的节点类型: %v", node.Kind)
+	}
+	return nil
+}
+
+// writeScalar 写入标量值
+func writeScalar(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+	case "!!bool", "!!int", "!!float":
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("无法编码为 JSON: %s", node.Value)
+		}
+		buf.Write(data)
+	default:
+		writeString(buf, node.Value)
+	}
+	return nil
+}
+
+// writeString 写入 JSON 字符串，不转义 HTML 字符
+func writeString(buf *bytes.Buffer, s string) {
+	var tmp bytes.Buffer
+	enc := json.NewEncoder(&tmp)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	buf.Write(bytes.TrimRight(tmp.Bytes(), "\n"))
+}
+
+// detectIndent 检测 JSON 文本使用的缩进，默认两个空格
+func detectIndent(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
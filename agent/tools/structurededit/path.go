@@ -0,0 +1,207 @@
+package structurededit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// segment 路径中的一段，键名或数组下标
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// String 返回路径段的可读形式
+func (s segment) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return s.key
+}
+
+// parsePath 解析类 JSONPath 路径
+// 支持 a.b.c、a.items[0].name、$.a、a["key.with.dot"]
+func parsePath(path string) ([]segment, error) {
+	text := strings.TrimSpace(path)
+	text = strings.TrimPrefix(text, "$")
+	text = strings.TrimPrefix(text, ".")
+	if text == "" {
+		return nil, fmt.Errorf("路径不能为空")
+	}
+
+	var segments []segment
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(text[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("路径缺少 ]: %s", path)
+			}
+			inner := strings.TrimSpace(text[i+1 : i+end])
+			i += end + 1
+			if unquoted, err := strconv.Unquote(inner); err == nil {
+				segments = append(segments, segment{key: unquoted})
+				continue
+			}
+			if len(inner) >= 2 && inner[0] == '\'' && inner[len(inner)-1] == '\'' {
+				segments = append(segments, segment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("无效的数组下标: %s", inner)
+			}
+			segments = append(segments, segment{index: index, isIndex: true})
+		default:
+			end := strings.IndexAny(text[i:], ".[")
+			if end < 0 {
+				end = len(text) - i
+			}
+			segments = append(segments, segment{key: text[i : i+end]})
+			i += end
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("路径不能为空")
+	}
+	return segments, nil
+}
+
+// mappingValue 查找映射节点中键对应的值节点下标（键节点下标 + 1），未找到返回 -1
+func mappingValue(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// setValue 在文档中设置路径对应的值，缺失的中间映射会被自动创建
+func setValue(root *yaml.Node, segments []segment, value *yaml.Node) error {
+	node := root
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		if seg.isIndex {
+			if node.Kind != yaml.SequenceNode {
+				return fmt.Errorf("%s 不是数组", formatPath(segments[:i]))
+			}
+			switch {
+			case seg.index < len(node.Content):
+				if last {
+					node.Content[seg.index] = keepComments(node.Content[seg.index], value)
+					return nil
+				}
+			case seg.index == len(node.Content):
+				// 下标等于长度时追加元素
+				if last {
+					node.Content = append(node.Content, value)
+					return nil
+				}
+				node.Content = append(node.Content, newContainer(segments[i+1]))
+			default:
+				return fmt.Errorf("%s 下标越界，数组长度为 %d", formatPath(segments[:i+1]), len(node.Content))
+			}
+			node = node.Content[seg.index]
+			continue
+		}
+
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s 不是对象", formatPath(segments[:i]))
+		}
+		idx := mappingValue(node, seg.key)
+		if last {
+			if idx >= 0 {
+				node.Content[idx] = keepComments(node.Content[idx], value)
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg.key}, value)
+			}
+			return nil
+		}
+		if idx < 0 {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: seg.key}, newContainer(segments[i+1]))
+			idx = len(node.Content) - 1
+		}
+		node = node.Content[idx]
+	}
+	return nil
+}
+
+// deleteValue 删除路径对应的值
+func deleteValue(root *yaml.Node, segments []segment) error {
+	node := root
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		if seg.isIndex {
+			if node.Kind != yaml.SequenceNode {
+				return fmt.Errorf("%s 不是数组", formatPath(segments[:i]))
+			}
+			if seg.index >= len(node.Content) {
+				return fmt.Errorf("%s 不存在", formatPath(segments[:i+1]))
+			}
+			if last {
+				node.Content = append(node.Content[:seg.index], node.Content[seg.index+1:]...)
+				return nil
+			}
+			node = node.Content[seg.index]
+			continue
+		}
+
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s 不是对象", formatPath(segments[:i]))
+		}
+		idx := mappingValue(node, seg.key)
+		if idx < 0 {
+			return fmt.Errorf("%s 不存在", formatPath(segments[:i+1]))
+		}
+		if last {
+			node.Content = append(node.Content[:idx-1], node.Content[idx+1:]...)
+			return nil
+		}
+		node = node.Content[idx]
+	}
+	return nil
+}
+
+// keepComments 将旧节点的注释保留到新节点上
+func keepComments(old, value *yaml.Node) *yaml.Node {
+	if value.HeadComment == "" {
+		value.HeadComment = old.HeadComment
+	}
+	if value.LineComment == "" {
+		value.LineComment = old.LineComment
+	}
+	if value.FootComment == "" {
+		value.FootComment = old.FootComment
+	}
+	return value
+}
+
+// newContainer 根据下一段路径创建空的对象或数组节点
+func newContainer(next segment) *yaml.Node {
+	if next.isIndex {
+		return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+// formatPath 将路径段还原为字符串
+func formatPath(segments []segment) string {
+	if len(segments) == 0 {
+		return "根节点"
+	}
+	var b strings.Builder
+	for i, seg := range segments {
+		if !seg.isIndex && i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(seg.String())
+	}
+	return b.String()
+}
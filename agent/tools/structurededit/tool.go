@@ -0,0 +1,184 @@
+package structurededit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Tool 结构化编辑工具
+// 按路径修改 JSON/YAML 文件中的值，比文本替换更可靠
+type Tool struct {
+	AllowedDir string
+}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return "structured_edit"
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "按路径修改 JSON/YAML 文件中的值并返回 diff。路径示例: server.port、items[0].name、$.a[\"key.with.dot\"]",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"path": {
+				Type:     schema.DataType("string"),
+				Desc:     "文件路径（.json、.yaml 或 .yml）",
+				Required: true,
+			},
+			"action": {
+				Type:     schema.DataType("string"),
+				Desc:     "操作: set 或 delete",
+				Required: true,
+			},
+			"key": {
+				Type:     schema.DataType("string"),
+				Desc:     "要修改的字段路径",
+				Required: true,
+			},
+			"value": {
+				Type: schema.DataType("string"),
+				Desc: "set 使用的新值，按 JSON 解析（如 8080、true、{\"a\":1}），无法解析时作为字符串",
+			},
+		}),
+	}, nil
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Path   string `json:"path"`
+		Action string `json:"action"`
+		Key    string `json:"key"`
+		Value  string `json:"value"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+
+	format := fileFormat(args.Path)
+	if format == "" {
+		return "错误: 只支持 .json、.yaml、.yml 文件", nil
+	}
+	path := args.Path
+	workingDir, allowedDir := common.ScopeDirs(ctx, "", t.AllowedDir)
+	if workingDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	resolved, err := common.ValidatePath(path, allowedDir)
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+	segments, err := parsePath(args.Key)
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return fmt.Sprintf("错误: 文件不存在: %s", args.Path), nil
+	}
+	before := string(data)
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Sprintf("错误: 解析 %s 失败: %s", format, err), nil
+	}
+	if doc.Kind == 0 {
+		// 空文件视为空对象
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+
+	switch args.Action {
+	case "set":
+		err = setValue(root, segments, parseValue(args.Value))
+	case "delete":
+		err = deleteValue(root, segments)
+	default:
+		return fmt.Sprintf("未知操作: %s", args.Action), nil
+	}
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
+
+	after, err := encode(&doc, format, before)
+	if err != nil {
+		return fmt.Sprintf("错误: 序列化 %s 失败: %s", format, err), nil
+	}
+	if after == before {
+		return fmt.Sprintf("%s 内容未变化", args.Path), nil
+	}
+	if err := os.WriteFile(resolved, []byte(after), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("成功编辑 %s\n%s", args.Path, common.UnifiedDiff(filepath.Base(resolved), before, after)), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// fileFormat 根据扩展名判断文件格式
+func fileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return ""
+}
+
+// parseValue 将参数值解析为节点，JSON 解析失败时作为字符串处理
+func parseValue(value string) *yaml.Node {
+	var doc yaml.Node
+	trimmed := strings.TrimSpace(value)
+	// JSON 是 YAML 的子集，合法 JSON 可直接按 YAML 解析为节点
+	if json.Valid([]byte(trimmed)) && yaml.Unmarshal([]byte(trimmed), &doc) == nil && len(doc.Content) > 0 {
+		node := doc.Content[0]
+		// 清除流式风格，使写回 YAML 时采用块风格
+		clearStyle(node)
+		return node
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// clearStyle 递归清除节点风格
+func clearStyle(node *yaml.Node) {
+	if node.Kind != yaml.ScalarNode {
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// encode 按格式序列化文档
+func encode(doc *yaml.Node, format, original string) (string, error) {
+	if format == "json" {
+		return encodeJSON(doc, detectIndent(original))
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
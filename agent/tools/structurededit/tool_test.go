@@ -0,0 +1,143 @@
+package structurededit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runTool 执行工具并返回结果
+func runTool(t *testing.T, tool *Tool, args map[string]string) string {
+	t.Helper()
+	data, _ := json.Marshal(args)
+	result, err := tool.Run(context.Background(), string(data))
+	if err != nil {
+		t.Fatalf("Run() 返回错误: %v", err)
+	}
+	return result
+}
+
+// TestTool_Name 测试工具名称
+func TestTool_Name(t *testing.T) {
+	tool := &Tool{}
+	if tool.Name() != "structured_edit" {
+		t.Errorf("Name() = %q, 期望 structured_edit", tool.Name())
+	}
+}
+
+// TestParsePath 测试路径解析
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a.b.c", "a.b.c"},
+		{"$.items[0].name", "items[0].name"},
+		{`a["x.y"]`, "a.x.y"},
+	}
+	for _, tt := range tests {
+		segments, err := parsePath(tt.path)
+		if err != nil {
+			t.Errorf("parsePath(%q) 返回错误: %v", tt.path, err)
+			continue
+		}
+		if got := formatPath(segments); got != tt.want {
+			t.Errorf("parsePath(%q) = %q, 期望 %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{"", "$", "a[x]", "a[0"} {
+		if _, err := parsePath(path); err == nil {
+			t.Errorf("parsePath(%q) 应该返回错误", path)
+		}
+	}
+}
+
+// TestTool_JSON 测试编辑 JSON 文件
+func TestTool_JSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	original := "{\n    \"name\": \"bot\",\n    \"server\": {\n        \"port\": 8080\n    },\n    \"tags\": [\"a\"]\n}\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool := &Tool{AllowedDir: dir}
+
+	result := runTool(t, tool, map[string]string{"path": path, "action": "set", "key": "server.port", "value": "9090"})
+	if !strings.Contains(result, "-        \"port\": 8080") || !strings.Contains(result, "+        \"port\": 9090") {
+		t.Errorf("结果应包含 diff, 得到: %s", result)
+	}
+
+	runTool(t, tool, map[string]string{"path": path, "action": "set", "key": "tags[1]", "value": "b"})
+	runTool(t, tool, map[string]string{"path": path, "action": "set", "key": "db.enabled", "value": "true"})
+	runTool(t, tool, map[string]string{"path": path, "action": "delete", "key": "name"})
+
+	data, _ := os.ReadFile(path)
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("写回的文件不是合法 JSON: %v\n%s", err, data)
+	}
+	if _, ok := got["name"]; ok {
+		t.Error("name 应该被删除")
+	}
+	if tags := got["tags"].([]any); len(tags) != 2 || tags[1] != "b" {
+		t.Errorf("tags = %v", tags)
+	}
+	if db := got["db"].(map[string]any); db["enabled"] != true {
+		t.Errorf("db.enabled = %v", db["enabled"])
+	}
+	// 保持原有键顺序
+	if strings.Index(string(data), "server") > strings.Index(string(data), "tags") {
+		t.Errorf("键顺序应保持不变:\n%s", data)
+	}
+}
+
+// TestTool_YAML 测试编辑 YAML 文件并保留注释
+func TestTool_YAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	original := "# 服务配置\nserver:\n  port: 8080 # 端口\n  host: localhost\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool := &Tool{AllowedDir: dir}
+
+	runTool(t, tool, map[string]string{"path": path, "action": "set", "key": "server.port", "value": "9090"})
+	runTool(t, tool, map[string]string{"path": path, "action": "delete", "key": "server.host"})
+
+	data, _ := os.ReadFile(path)
+	want := "# 服务配置\nserver:\n  port: 9090 # 端口\n"
+	if string(data) != want {
+		t.Errorf("文件内容 = %q, 期望 %q", data, want)
+	}
+}
+
+// TestTool_Errors 测试错误情况
+func TestTool_Errors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.json")
+	if err := os.WriteFile(path, []byte(`{"a": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool := &Tool{AllowedDir: dir}
+
+	tests := []struct {
+		name string
+		args map[string]string
+	}{
+		{"不支持的格式", map[string]string{"path": filepath.Join(dir, "a.txt"), "action": "set", "key": "a"}},
+		{"超出允许目录", map[string]string{"path": "/etc/x.json", "action": "set", "key": "a"}},
+		{"删除不存在的键", map[string]string{"path": path, "action": "delete", "key": "b"}},
+		{"非对象节点", map[string]string{"path": path, "action": "set", "key": "a.b", "value": "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := runTool(t, tool, tt.args); !strings.HasPrefix(result, "错误") {
+				t.Errorf("Run() = %q, 期望返回错误", result)
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package task
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
 	"github.com/weibaohui/nanobot-go/agent/tools/common"
 	"go.uber.org/zap"
 )
@@ -15,16 +18,29 @@ type TaskInfo struct {
 	ID            string
 	Status        string
 	ResultSummary string
+	QueuePosition int       // 排队位置（从1开始），0 表示未排队
+	CreatedAt     time.Time // 创建时间，未知时为零值
+	Channel       string    // 发起任务的渠道
+	ChatID        string    // 发起任务的聊天ID
 }
 
 // Manager 任务管理器接口
 type Manager interface {
 	StartTask(ctx context.Context, work, channel, chatID string) (string, string, error)
+	StartTaskWithDelivery(ctx context.Context, work, channel, chatID, deliverChannel, deliverChatID string) (string, string, error)
 	GetTask(ctx context.Context, taskID string) (*TaskInfo, error)
+	GetTaskLogs(ctx context.Context, taskID string) ([]string, error)
 	StopTask(ctx context.Context, taskID string) (bool, string, error)
+	ResumeTask(ctx context.Context, taskID, answer string) (string, error)
+	// IsTaskOrigin 判断任务是否由指定渠道和聊天发起，任务不存在时 found 为 false
+	IsTaskOrigin(ctx context.Context, taskID, channel, chatID string) (owned, found bool)
 	ListTasks() ([]*TaskInfo, error)
+	ListTasksFiltered(ctx context.Context, from, to time.Time, status string) ([]*TaskInfo, error)
 }
 
+// listDateLayout 任务列表筛选的日期格式
+const listDateLayout = "2006-01-02"
+
 // StartTool 后台任务创建工具
 type StartTool struct {
 	Manager Manager
@@ -42,13 +58,21 @@ func (t *StartTool) Name() string {
 func (t *StartTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 	return &schema.ToolInfo{
 		Name: t.Name(),
-		Desc: "创建后台任务并返回任务ID",
+		Desc: "创建后台任务并返回任务ID。任务结果默认发回当前会话，用户要求发到其他地方时可指定投递渠道和聊天ID",
 		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
 			"work": {
 				Type:     schema.DataType("string"),
 				Desc:     "任务内容或目标",
 				Required: true,
 			},
+			"deliver_channel": {
+				Type: schema.DataType("string"),
+				Desc: "任务结果的投递渠道，如 feishu、dingtalk、matrix、websocket，默认当前渠道",
+			},
+			"deliver_chat_id": {
+				Type: schema.DataType("string"),
+				Desc: "任务结果的投递聊天ID，指定投递渠道时必填",
+			},
 		}),
 	}, nil
 }
@@ -56,7 +80,9 @@ func (t *StartTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 // Run 执行工具逻辑
 func (t *StartTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	var args struct {
-		Work string `json:"work"`
+		Work           string `json:"work"`
+		DeliverChannel string `json:"deliver_channel"`
+		DeliverChatID  string `json:"deliver_chat_id"`
 	}
 	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
 		return "", err
@@ -67,16 +93,61 @@ func (t *StartTool) Run(ctx context.Context, argumentsInJSON string, opts ...too
 	if t.Manager == nil {
 		return "错误: 任务管理器未配置", nil
 	}
-	taskID, status, err := t.Manager.StartTask(ctx, args.Work, t.Channel, t.ChatID)
+	channel, chatID := t.origin(ctx)
+	var taskID, status string
+	var err error
+	if args.DeliverChannel == "" && args.DeliverChatID == "" {
+		taskID, status, err = t.Manager.StartTask(ctx, args.Work, channel, chatID)
+	} else {
+		taskID, status, err = t.Manager.StartTaskWithDelivery(ctx, args.Work, channel, chatID, args.DeliverChannel, args.DeliverChatID)
+	}
 	if err != nil {
 		return fmt.Sprintf("错误: 创建任务失败: %s", err), nil
 	}
 	if t.Logger != nil {
 		t.Logger.Info("创建后台任务成功", zap.String("任务ID", taskID), zap.String("状态", status))
 	}
+	if status == "queued" {
+		// 并发已满时任务进入排队，告知用户排队位置
+		if info, err := t.Manager.GetTask(ctx, taskID); err == nil && info.QueuePosition > 0 {
+			return fmt.Sprintf("任务已创建，ID: %s，状态: %s，排队位置: %d", taskID, status, info.QueuePosition), nil
+		}
+	}
+	if args.DeliverChatID != "" {
+		return fmt.Sprintf("任务已创建，ID: %s，状态: %s，结果将投递到 %s:%s", taskID, status, deliverChannelOr(args.DeliverChannel, channel), args.DeliverChatID), nil
+	}
 	return fmt.Sprintf("任务已创建，ID: %s，状态: %s", taskID, status), nil
 }
 
+// origin 返回任务的发起渠道和聊天：处理消息时为当前消息所在的会话，否则为 SetContext 设置的值
+func (t *StartTool) origin(ctx context.Context) (string, string) {
+	if channel, chatID := turnOrigin(ctx); channel != "" && chatID != "" {
+		return channel, chatID
+	}
+	return t.Channel, t.ChatID
+}
+
+// turnOrigin 返回当前消息的渠道和聊天ID
+func turnOrigin(ctx context.Context) (string, string) {
+	return trace.GetChannel(ctx), trace.GetChatID(ctx)
+}
+
+// ownedByTurn 判断任务是否可由当前聊天操作
+// 任务属于其他聊天时返回 false，调用方按任务不存在回复，不泄露其他聊天的任务；任务不存在时交给原有流程报告错误
+func ownedByTurn(ctx context.Context, manager Manager, taskID string) bool {
+	channel, chatID := turnOrigin(ctx)
+	owned, found := manager.IsTaskOrigin(ctx, taskID, channel, chatID)
+	return owned || !found
+}
+
+// deliverChannelOr 投递渠道为空时使用当前渠道
+func deliverChannelOr(channel, fallback string) string {
+	if channel == "" {
+		return fallback
+	}
+	return channel
+}
+
 // InvokableRun 可直接调用的执行入口
 func (t *StartTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	return t.Run(ctx, argumentsInJSON, opts...)
@@ -128,6 +199,9 @@ func (t *GetTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.
 	if t.Manager == nil {
 		return "错误: 任务管理器未配置", nil
 	}
+	if !ownedByTurn(ctx, t.Manager, args.TaskID) {
+		return "错误: 查询任务失败: 任务不存在", nil
+	}
 	info, err := t.Manager.GetTask(ctx, args.TaskID)
 	if err != nil {
 		return fmt.Sprintf("错误: 查询任务失败: %s", err), nil
@@ -135,7 +209,23 @@ func (t *GetTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.
 	if t.Logger != nil {
 		t.Logger.Info("查询后台任务成功", zap.String("任务ID", info.ID), zap.String("状态", info.Status))
 	}
-	return fmt.Sprintf("任务ID: %s\n状态: %s\n结果摘要: %s", info.ID, info.Status, info.ResultSummary), nil
+	result := fmt.Sprintf("任务ID: %s\n状态: %s\n结果摘要: %s", info.ID, info.Status, info.ResultSummary)
+	if info.QueuePosition > 0 {
+		result += fmt.Sprintf("\n排队位置: %d", info.QueuePosition)
+	}
+
+	// 附加最近日志，便于用户查看任务进度；日志获取失败不影响状态查询
+	logs, err := t.Manager.GetTaskLogs(ctx, args.TaskID)
+	if err != nil {
+		if t.Logger != nil {
+			t.Logger.Warn("查询任务日志失败", zap.String("任务ID", args.TaskID), zap.Error(err))
+		}
+		return result, nil
+	}
+	if len(logs) > 0 {
+		result += "\n最近日志:\n" + strings.Join(logs, "\n")
+	}
+	return result, nil
 }
 
 // InvokableRun 可直接调用的执行入口
@@ -183,6 +273,9 @@ func (t *StopTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool
 	if t.Manager == nil {
 		return "错误: 任务管理器未配置", nil
 	}
+	if !ownedByTurn(ctx, t.Manager, args.TaskID) {
+		return "错误: 停止任务失败: 任务不存在", nil
+	}
 	stopped, status, err := t.Manager.StopTask(ctx, args.TaskID)
 	if err != nil {
 		return fmt.Sprintf("错误: 停止任务失败: %s", err), nil
@@ -198,6 +291,73 @@ func (t *StopTool) InvokableRun(ctx context.Context, argumentsInJSON string, opt
 	return t.Run(ctx, argumentsInJSON, opts...)
 }
 
+// ResumeTool 回复等待输入的后台任务工具
+type ResumeTool struct {
+	Manager Manager
+	Logger  *zap.Logger
+}
+
+// Name 返回工具名称
+func (t *ResumeTool) Name() string {
+	return "resume_task"
+}
+
+// Info 返回工具信息
+func (t *ResumeTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "向等待输入的后台任务提交用户的回复，任务将从中断处继续执行",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"task_id": {
+				Type:     schema.DataType("string"),
+				Desc:     "任务ID",
+				Required: true,
+			},
+			"answer": {
+				Type:     schema.DataType("string"),
+				Desc:     "用户对任务问题的回复",
+				Required: true,
+			},
+		}),
+	}, nil
+}
+
+// Run 执行工具逻辑
+func (t *ResumeTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		TaskID string `json:"task_id"`
+		Answer string `json:"answer"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+	if args.TaskID == "" {
+		return "错误: 任务ID不能为空", nil
+	}
+	if strings.TrimSpace(args.Answer) == "" {
+		return "错误: 回复内容不能为空", nil
+	}
+	if t.Manager == nil {
+		return "错误: 任务管理器未配置", nil
+	}
+	if !ownedByTurn(ctx, t.Manager, args.TaskID) {
+		return "错误: 恢复任务失败: 任务不存在", nil
+	}
+	status, err := t.Manager.ResumeTask(ctx, args.TaskID, args.Answer)
+	if err != nil {
+		return fmt.Sprintf("错误: 恢复任务失败: %s", err), nil
+	}
+	if t.Logger != nil {
+		t.Logger.Info("恢复后台任务", zap.String("任务ID", args.TaskID), zap.String("状态", status))
+	}
+	return fmt.Sprintf("任务ID: %s\n状态: %s", args.TaskID, status), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *ResumeTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
 // ListTool 后台任务列表工具
 type ListTool struct {
 	Manager Manager
@@ -212,21 +372,55 @@ func (t *ListTool) Name() string {
 // Info 返回工具信息
 func (t *ListTool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 	return &schema.ToolInfo{
-		Name:        t.Name(),
-		Desc:        "列出所有后台任务",
-		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{}),
+		Name: t.Name(),
+		Desc: "列出当前聊天发起的后台任务。不带参数时列出运行中和当天已结束的任务；指定状态或日期范围时查询历史任务，未指定开始日期时默认最近 7 天",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"status": {
+				Type: schema.DataType("string"),
+				Desc: "按状态筛选",
+				Enum: []string{"pending", "queued", "running", "waiting_input", "finished", "failed", "stopped"},
+			},
+			"from": {
+				Type: schema.DataType("string"),
+				Desc: "开始日期（含），格式 2006-01-02",
+			},
+			"to": {
+				Type: schema.DataType("string"),
+				Desc: "结束日期（含），格式 2006-01-02，默认今天",
+			},
+		}),
 	}, nil
 }
 
 // Run 执行工具逻辑
 func (t *ListTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args struct {
+		Status string `json:"status"`
+		From   string `json:"from"`
+		To     string `json:"to"`
+	}
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
 	if t.Manager == nil {
 		return "错误: 任务管理器未配置", nil
 	}
-	items, err := t.Manager.ListTasks()
+
+	var items []*TaskInfo
+	var err error
+	if args.Status == "" && args.From == "" && args.To == "" {
+		items, err = t.Manager.ListTasks()
+	} else {
+		from, to, parseErr := parseListRange(args.From, args.To)
+		if parseErr != nil {
+			return "错误: " + parseErr.Error(), nil
+		}
+		items, err = t.Manager.ListTasksFiltered(ctx, from, to, args.Status)
+	}
 	if err != nil {
 		return fmt.Sprintf("错误: 获取任务列表失败: %s", err), nil
 	}
+	items = ownTasks(ctx, items)
 	if t.Logger != nil {
 		t.Logger.Info("获取任务列表完成", zap.Int("数量", len(items)))
 	}
@@ -236,6 +430,12 @@ func (t *ListTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool
 	var result string
 	for i, item := range items {
 		line := fmt.Sprintf("任务ID: %s | 状态: %s | 摘要: %s", item.ID, item.Status, item.ResultSummary)
+		if item.QueuePosition > 0 {
+			line += fmt.Sprintf(" | 排队位置: %d", item.QueuePosition)
+		}
+		if !item.CreatedAt.IsZero() {
+			line += " | 创建时间: " + item.CreatedAt.Format("2006-01-02 15:04:05")
+		}
 		if i == 0 {
 			result = line
 		} else {
@@ -245,6 +445,42 @@ func (t *ListTool) Run(ctx context.Context, argumentsInJSON string, opts ...tool
 	return result, nil
 }
 
+// parseListRange 解析筛选日期范围，结束日期默认今天，开始日期默认结束日期前 6 天
+func parseListRange(fromStr, toStr string) (time.Time, time.Time, error) {
+	to := time.Now()
+	if toStr != "" {
+		parsed, err := time.ParseInLocation(listDateLayout, toStr, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("结束日期格式错误，应为 %s", listDateLayout)
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -6)
+	if fromStr != "" {
+		parsed, err := time.ParseInLocation(listDateLayout, fromStr, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("开始日期格式错误，应为 %s", listDateLayout)
+		}
+		from = parsed
+	}
+	if from.Format(listDateLayout) > to.Format(listDateLayout) {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始日期不能晚于结束日期")
+	}
+	return from, to, nil
+}
+
+// ownTasks 只保留当前聊天发起的任务
+func ownTasks(ctx context.Context, items []*TaskInfo) []*TaskInfo {
+	channel, chatID := turnOrigin(ctx)
+	own := make([]*TaskInfo, 0, len(items))
+	for _, item := range items {
+		if item.Channel == channel && item.ChatID == chatID {
+			own = append(own, item)
+		}
+	}
+	return own
+}
+
 // InvokableRun 可直接调用的执行入口
 func (t *ListTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
 	return t.Run(ctx, argumentsInJSON, opts...)
@@ -3,17 +3,25 @@ package task
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/weibaohui/nanobot-go/agent/hooks/trace"
 	"go.uber.org/zap"
 )
 
 // mockManager 模拟任务管理器
 type mockManager struct {
-	startTaskFunc func(ctx context.Context, work, channel, chatID string) (string, string, error)
-	getTaskFunc   func(ctx context.Context, taskID string) (*TaskInfo, error)
-	stopTaskFunc  func(ctx context.Context, taskID string) (bool, string, error)
-	listTasksFunc func() ([]*TaskInfo, error)
+	startTaskFunc  func(ctx context.Context, work, channel, chatID string) (string, string, error)
+	deliveryFunc   func(ctx context.Context, work, channel, chatID, deliverChannel, deliverChatID string) (string, string, error)
+	getTaskFunc    func(ctx context.Context, taskID string) (*TaskInfo, error)
+	getLogsFunc    func(ctx context.Context, taskID string) ([]string, error)
+	stopTaskFunc   func(ctx context.Context, taskID string) (bool, string, error)
+	resumeTaskFunc func(ctx context.Context, taskID, answer string) (string, error)
+	listTasksFunc  func() ([]*TaskInfo, error)
+	filteredFunc   func(ctx context.Context, from, to time.Time, status string) ([]*TaskInfo, error)
+	originFunc     func(ctx context.Context, taskID, channel, chatID string) (bool, bool)
 }
 
 func (m *mockManager) StartTask(ctx context.Context, work, channel, chatID string) (string, string, error) {
@@ -23,6 +31,13 @@ func (m *mockManager) StartTask(ctx context.Context, work, channel, chatID strin
 	return "task-001", "running", nil
 }
 
+func (m *mockManager) StartTaskWithDelivery(ctx context.Context, work, channel, chatID, deliverChannel, deliverChatID string) (string, string, error) {
+	if m.deliveryFunc != nil {
+		return m.deliveryFunc(ctx, work, channel, chatID, deliverChannel, deliverChatID)
+	}
+	return "task-001", "running", nil
+}
+
 func (m *mockManager) GetTask(ctx context.Context, taskID string) (*TaskInfo, error) {
 	if m.getTaskFunc != nil {
 		return m.getTaskFunc(ctx, taskID)
@@ -30,6 +45,13 @@ func (m *mockManager) GetTask(ctx context.Context, taskID string) (*TaskInfo, er
 	return &TaskInfo{ID: taskID, Status: "running", ResultSummary: "测试摘要"}, nil
 }
 
+func (m *mockManager) GetTaskLogs(ctx context.Context, taskID string) ([]string, error) {
+	if m.getLogsFunc != nil {
+		return m.getLogsFunc(ctx, taskID)
+	}
+	return []string{"2024-01-01 10:00:00 任务已创建"}, nil
+}
+
 func (m *mockManager) StopTask(ctx context.Context, taskID string) (bool, string, error) {
 	if m.stopTaskFunc != nil {
 		return m.stopTaskFunc(ctx, taskID)
@@ -37,6 +59,27 @@ func (m *mockManager) StopTask(ctx context.Context, taskID string) (bool, string
 	return true, "stopped", nil
 }
 
+func (m *mockManager) ResumeTask(ctx context.Context, taskID, answer string) (string, error) {
+	if m.resumeTaskFunc != nil {
+		return m.resumeTaskFunc(ctx, taskID, answer)
+	}
+	return "running", nil
+}
+
+func (m *mockManager) IsTaskOrigin(ctx context.Context, taskID, channel, chatID string) (bool, bool) {
+	if m.originFunc != nil {
+		return m.originFunc(ctx, taskID, channel, chatID)
+	}
+	return true, true
+}
+
+func (m *mockManager) ListTasksFiltered(ctx context.Context, from, to time.Time, status string) ([]*TaskInfo, error) {
+	if m.filteredFunc != nil {
+		return m.filteredFunc(ctx, from, to, status)
+	}
+	return nil, nil
+}
+
 func (m *mockManager) ListTasks() ([]*TaskInfo, error) {
 	if m.listTasksFunc != nil {
 		return m.listTasksFunc()
@@ -89,6 +132,29 @@ func TestStartTool_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("排队时返回位置", func(t *testing.T) {
+		tool := &StartTool{
+			Manager: &mockManager{
+				startTaskFunc: func(ctx context.Context, work, channel, chatID string) (string, string, error) {
+					return "000002", "queued", nil
+				},
+				getTaskFunc: func(ctx context.Context, taskID string) (*TaskInfo, error) {
+					return &TaskInfo{ID: taskID, Status: "queued", QueuePosition: 2}, nil
+				},
+			},
+		}
+		ctx := context.Background()
+
+		result, err := tool.Run(ctx, `{"work": "测试任务"}`)
+		if err != nil {
+			t.Errorf("Run() 返回错误: %v", err)
+		}
+
+		if result != "任务已创建，ID: 000002，状态: queued，排队位置: 2" {
+			t.Errorf("Run() = %q, 期望包含排队位置", result)
+		}
+	})
+
 	t.Run("空任务内容", func(t *testing.T) {
 		tool := &StartTool{
 			Manager: &mockManager{},
@@ -138,6 +204,51 @@ func TestStartTool_Run(t *testing.T) {
 			t.Errorf("Run() = %q, 期望错误提示", result)
 		}
 	})
+
+	t.Run("指定结果投递目标", func(t *testing.T) {
+		var gotChannel, gotChatID string
+		tool := &StartTool{
+			Channel: "websocket",
+			ChatID:  "chat1",
+			Manager: &mockManager{
+				deliveryFunc: func(ctx context.Context, work, channel, chatID, deliverChannel, deliverChatID string) (string, string, error) {
+					gotChannel, gotChatID = deliverChannel, deliverChatID
+					return "000003", "running", nil
+				},
+			},
+		}
+
+		result, err := tool.Run(context.Background(), `{"work": "生成周报", "deliver_channel": "feishu", "deliver_chat_id": "oc_123"}`)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if gotChannel != "feishu" || gotChatID != "oc_123" {
+			t.Errorf("投递目标 = %s:%s, 期望 feishu:oc_123", gotChannel, gotChatID)
+		}
+		if result != "任务已创建，ID: 000003，状态: running，结果将投递到 feishu:oc_123" {
+			t.Errorf("Run() = %q", result)
+		}
+	})
+
+	t.Run("发起渠道取自当前消息", func(t *testing.T) {
+		var gotChannel, gotChatID string
+		tool := &StartTool{
+			Manager: &mockManager{
+				startTaskFunc: func(ctx context.Context, work, channel, chatID string) (string, string, error) {
+					gotChannel, gotChatID = channel, chatID
+					return "000004", "running", nil
+				},
+			},
+		}
+
+		ctx := trace.WithChatID(trace.WithChannel(context.Background(), "feishu"), "oc_456")
+		if _, err := tool.Run(ctx, `{"work": "测试任务"}`); err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if gotChannel != "feishu" || gotChatID != "oc_456" {
+			t.Errorf("发起渠道 = %s:%s, 期望 feishu:oc_456", gotChannel, gotChatID)
+		}
+	})
 }
 
 // TestStartTool_SetContext 测试设置上下文
@@ -196,6 +307,46 @@ func TestGetTool_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("包含最近日志", func(t *testing.T) {
+		tool := &GetTool{
+			Manager: &mockManager{
+				getLogsFunc: func(ctx context.Context, taskID string) ([]string, error) {
+					return []string{"任务已创建", "任务启动"}, nil
+				},
+			},
+		}
+		ctx := context.Background()
+
+		result, err := tool.Run(ctx, `{"task_id": "task-001"}`)
+		if err != nil {
+			t.Errorf("Run() 返回错误: %v", err)
+		}
+
+		if !strings.Contains(result, "最近日志:\n任务已创建\n任务启动") {
+			t.Errorf("Run() = %q, 期望包含最近日志", result)
+		}
+	})
+
+	t.Run("日志查询失败不影响状态", func(t *testing.T) {
+		tool := &GetTool{
+			Manager: &mockManager{
+				getLogsFunc: func(ctx context.Context, taskID string) ([]string, error) {
+					return nil, errors.New("日志不可用")
+				},
+			},
+		}
+		ctx := context.Background()
+
+		result, err := tool.Run(ctx, `{"task_id": "task-001"}`)
+		if err != nil {
+			t.Errorf("Run() 返回错误: %v", err)
+		}
+
+		if strings.Contains(result, "最近日志") || !strings.Contains(result, "状态: running") {
+			t.Errorf("Run() = %q, 期望只包含状态", result)
+		}
+	})
+
 	t.Run("空任务ID", func(t *testing.T) {
 		tool := &GetTool{
 			Manager: &mockManager{},
@@ -227,6 +378,72 @@ func TestGetTool_Run(t *testing.T) {
 	})
 }
 
+// otherChatManager 返回任务由 feishu/oc_owner 发起的模拟管理器，调用 GetTask、StopTask 或 ResumeTask 时将 touched 置为 true
+func otherChatManager(touched *bool) *mockManager {
+	return &mockManager{
+		originFunc: func(ctx context.Context, taskID, channel, chatID string) (bool, bool) {
+			return channel == "feishu" && chatID == "oc_owner", true
+		},
+		getTaskFunc: func(ctx context.Context, taskID string) (*TaskInfo, error) {
+			*touched = true
+			return &TaskInfo{ID: taskID, Status: "finished", ResultSummary: "机密结果"}, nil
+		},
+		stopTaskFunc: func(ctx context.Context, taskID string) (bool, string, error) {
+			*touched = true
+			return true, "stopped", nil
+		},
+		resumeTaskFunc: func(ctx context.Context, taskID, answer string) (string, error) {
+			*touched = true
+			return "running", nil
+		},
+	}
+}
+
+// otherChatContext 返回来自其他聊天的消息上下文
+func otherChatContext() context.Context {
+	return trace.WithChatID(trace.WithChannel(context.Background(), "feishu"), "oc_other")
+}
+
+// TestTool_OtherChatTask 测试不能操作其他聊天发起的任务
+func TestTool_OtherChatTask(t *testing.T) {
+	t.Run("查询", func(t *testing.T) {
+		var touched bool
+		tool := &GetTool{Manager: otherChatManager(&touched)}
+		result, _ := tool.Run(otherChatContext(), `{"task_id": "000001"}`)
+		if result != "错误: 查询任务失败: 任务不存在" || touched {
+			t.Errorf("Run() = %q, 查询了任务 = %v", result, touched)
+		}
+	})
+
+	t.Run("停止", func(t *testing.T) {
+		var touched bool
+		tool := &StopTool{Manager: otherChatManager(&touched)}
+		result, _ := tool.Run(otherChatContext(), `{"task_id": "000001"}`)
+		if result != "错误: 停止任务失败: 任务不存在" || touched {
+			t.Errorf("Run() = %q, 停止了任务 = %v", result, touched)
+		}
+	})
+
+	t.Run("回复", func(t *testing.T) {
+		var touched bool
+		tool := &ResumeTool{Manager: otherChatManager(&touched)}
+		result, _ := tool.Run(otherChatContext(), `{"task_id": "000001", "answer": "继续"}`)
+		if result != "错误: 恢复任务失败: 任务不存在" || touched {
+			t.Errorf("Run() = %q, 恢复了任务 = %v", result, touched)
+		}
+	})
+
+	t.Run("发起聊天可以查询", func(t *testing.T) {
+		var touched bool
+		tool := &GetTool{Manager: otherChatManager(&touched)}
+		ctx := trace.WithChatID(trace.WithChannel(context.Background(), "feishu"), "oc_owner")
+		result, _ := tool.Run(ctx, `{"task_id": "000001"}`)
+		if !strings.Contains(result, "机密结果") {
+			t.Errorf("Run() = %q, 期望返回任务结果", result)
+		}
+	})
+}
+
 // TestStopTool_Name 测试工具名称
 func TestStopTool_Name(t *testing.T) {
 	tool := &StopTool{}
@@ -286,6 +503,41 @@ func TestStopTool_Run(t *testing.T) {
 	})
 }
 
+// TestResumeTool_Run 测试回复等待输入的任务
+func TestResumeTool_Run(t *testing.T) {
+	t.Run("正常执行", func(t *testing.T) {
+		var gotID, gotAnswer string
+		tool := &ResumeTool{
+			Manager: &mockManager{
+				resumeTaskFunc: func(ctx context.Context, taskID, answer string) (string, error) {
+					gotID, gotAnswer = taskID, answer
+					return "running", nil
+				},
+			},
+		}
+
+		result, err := tool.Run(context.Background(), `{"task_id": "000001", "answer": "使用 main 分支"}`)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if gotID != "000001" || gotAnswer != "使用 main 分支" {
+			t.Errorf("ResumeTask 参数 = (%q, %q)", gotID, gotAnswer)
+		}
+		if !strings.Contains(result, "running") {
+			t.Errorf("Run() = %q, 期望包含任务状态", result)
+		}
+	})
+
+	t.Run("空回复", func(t *testing.T) {
+		tool := &ResumeTool{Manager: &mockManager{}}
+
+		result, _ := tool.Run(context.Background(), `{"task_id": "000001", "answer": " "}`)
+		if result != "错误: 回复内容不能为空" {
+			t.Errorf("Run() = %q, 期望错误提示", result)
+		}
+	})
+}
+
 // TestListTool_Name 测试工具名称
 func TestListTool_Name(t *testing.T) {
 	tool := &ListTool{}
@@ -348,6 +600,34 @@ func TestListTool_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("只列出当前聊天的任务", func(t *testing.T) {
+		tasks := []*TaskInfo{
+			{ID: "000001", Status: "running", Channel: "feishu", ChatID: "oc_owner"},
+			{ID: "000002", Status: "running", Channel: "feishu", ChatID: "oc_other"},
+		}
+		tool := &ListTool{
+			Manager: &mockManager{
+				listTasksFunc: func() ([]*TaskInfo, error) {
+					return tasks, nil
+				},
+				filteredFunc: func(ctx context.Context, from, to time.Time, status string) ([]*TaskInfo, error) {
+					return tasks, nil
+				},
+			},
+		}
+		ctx := trace.WithChatID(trace.WithChannel(context.Background(), "feishu"), "oc_owner")
+
+		for _, args := range []string{`{}`, `{"status": "running"}`} {
+			result, err := tool.Run(ctx, args)
+			if err != nil {
+				t.Fatalf("Run() 返回错误: %v", err)
+			}
+			if !strings.Contains(result, "000001") || strings.Contains(result, "000002") {
+				t.Errorf("Run(%s) = %q, 期望只包含当前聊天的任务", args, result)
+			}
+		}
+	})
+
 	t.Run("无管理器", func(t *testing.T) {
 		tool := &ListTool{}
 		ctx := context.Background()
@@ -361,6 +641,61 @@ func TestListTool_Run(t *testing.T) {
 			t.Errorf("Run() = %q, 期望错误提示", result)
 		}
 	})
+
+	t.Run("按状态和日期筛选", func(t *testing.T) {
+		var gotFrom, gotTo time.Time
+		var gotStatus string
+		created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.Local)
+		tool := &ListTool{
+			Manager: &mockManager{
+				filteredFunc: func(ctx context.Context, from, to time.Time, status string) ([]*TaskInfo, error) {
+					gotFrom, gotTo, gotStatus = from, to, status
+					return []*TaskInfo{{ID: "000003", Status: status, CreatedAt: created}}, nil
+				},
+			},
+		}
+
+		result, err := tool.Run(context.Background(), `{"status": "failed", "from": "2024-03-01", "to": "2024-03-02"}`)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if gotStatus != "failed" || gotFrom.Format("2006-01-02") != "2024-03-01" || gotTo.Format("2006-01-02") != "2024-03-02" {
+			t.Errorf("筛选参数 = (%v, %v, %q)", gotFrom, gotTo, gotStatus)
+		}
+		if !strings.Contains(result, "创建时间: 2024-03-01 09:30:00") {
+			t.Errorf("Run() = %q, 期望包含创建时间", result)
+		}
+	})
+
+	t.Run("只指定状态时默认最近7天", func(t *testing.T) {
+		var days int
+		tool := &ListTool{
+			Manager: &mockManager{
+				filteredFunc: func(ctx context.Context, from, to time.Time, status string) ([]*TaskInfo, error) {
+					days = int(to.Sub(from).Hours()/24 + 0.5)
+					return nil, nil
+				},
+			},
+		}
+		if result, _ := tool.Run(context.Background(), `{"status": "finished"}`); result != "任务列表为空" {
+			t.Errorf("Run() = %q", result)
+		}
+		if days != 6 {
+			t.Errorf("默认范围 = %d 天, 期望 6", days)
+		}
+	})
+
+	t.Run("日期格式错误", func(t *testing.T) {
+		tool := &ListTool{Manager: &mockManager{}}
+		result, _ := tool.Run(context.Background(), `{"from": "2024/03/01"}`)
+		if !strings.HasPrefix(result, "错误: 开始日期格式错误") {
+			t.Errorf("Run() = %q, 期望日期格式错误提示", result)
+		}
+		result, _ = tool.Run(context.Background(), `{"from": "2024-03-02", "to": "2024-03-01"}`)
+		if result != "错误: 开始日期不能晚于结束日期" {
+			t.Errorf("Run() = %q, 期望日期范围错误提示", result)
+		}
+	})
 }
 
 // TestTaskInfo 测试任务信息结构
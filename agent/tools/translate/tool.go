@@ -0,0 +1,119 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+)
+
+const (
+	defaultTimeout = 60 * time.Second
+	maxTextLength  = 20000 // 单次翻译的最大字符数
+)
+
+// systemPrompt 翻译模型的系统提示词
+const systemPrompt = `你是专业翻译。请把用户给出的文本翻译成目标语言，要求：
+- 准确、通顺，符合目标语言的表达习惯
+- 保持原文格式不变：换行、Markdown 标记、列表、表格、代码块和链接地址原样保留，代码块中的代码不翻译
+- 专有名词没有通用译名时保留原文
+- 只输出译文，不要添加解释、注释或引号`
+
+// Tool 翻译工具
+// 使用单独配置的模型（通常是较便宜的小模型）完成翻译，不占用主对话的上下文
+type Tool struct {
+	Model   model.BaseChatModel
+	Timeout time.Duration
+}
+
+// Args 翻译参数
+type Args struct {
+	Text           string `json:"text"`
+	TargetLanguage string `json:"target_language"`
+	SourceLanguage string `json:"source_language"`
+}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return "translate"
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "将文本翻译成目标语言，保留原文的换行、Markdown 和代码块等格式",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"text": {
+				Type:     schema.DataType("string"),
+				Desc:     "要翻译的文本",
+				Required: true,
+			},
+			"target_language": {
+				Type:     schema.DataType("string"),
+				Desc:     "目标语言，如 \"英语\"、\"日语\"、\"zh-CN\"",
+				Required: true,
+			},
+			"source_language": {
+				Type: schema.DataType("string"),
+				Desc: "源语言，不填时自动识别",
+			},
+		}),
+	}, nil
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args Args
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(args.Text) == "" {
+		return "错误: 需要 text 参数", nil
+	}
+	target := strings.TrimSpace(args.TargetLanguage)
+	if target == "" {
+		return "错误: 需要 target_language 参数", nil
+	}
+	if len([]rune(args.Text)) > maxTextLength {
+		return fmt.Sprintf("错误: 文本过长（超过 %d 字符），请分段翻译", maxTextLength), nil
+	}
+	if t.Model == nil {
+		return "错误: 翻译模型未配置", nil
+	}
+
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	instruction := fmt.Sprintf("请翻译成%s", target)
+	if source := strings.TrimSpace(args.SourceLanguage); source != "" {
+		instruction = fmt.Sprintf("请从%s翻译成%s", source, target)
+	}
+	messages := []*schema.Message{
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(instruction + "：\n\n" + args.Text),
+	}
+	resp, err := t.Model.Generate(ctx, messages, model.WithTemperature(0.2))
+	if err != nil {
+		return fmt.Sprintf("错误: 翻译失败: %v", err), nil
+	}
+	translated := strings.TrimSpace(resp.Content)
+	if translated == "" {
+		return "错误: 翻译结果为空", nil
+	}
+	return translated, nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
@@ -0,0 +1,76 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// mockModel 记录输入并返回固定回复的模拟模型
+type mockModel struct {
+	reply string
+	err   error
+	input []*schema.Message
+}
+
+func (m *mockModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	m.input = input
+	if m.err != nil {
+		return nil, m.err
+	}
+	return schema.AssistantMessage(m.reply, nil), nil
+}
+
+func (m *mockModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	return nil, nil
+}
+
+// TestTool_Run 测试翻译
+func TestTool_Run(t *testing.T) {
+	t.Run("翻译并保留格式", func(t *testing.T) {
+		m := &mockModel{reply: "\n# Title\n\n- item\n"}
+		tool := &Tool{Model: m}
+
+		result, err := tool.Run(context.Background(), `{"text":"# 标题\n\n- 条目","target_language":"英语","source_language":"中文"}`)
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if result != "# Title\n\n- item" {
+			t.Errorf("Run() = %q", result)
+		}
+		if len(m.input) != 2 || !strings.Contains(m.input[0].Content, "保持原文格式") {
+			t.Errorf("系统提示 = %+v, 期望要求保留格式", m.input)
+		}
+		if !strings.HasPrefix(m.input[1].Content, "请从中文翻译成英语") || !strings.HasSuffix(m.input[1].Content, "# 标题\n\n- 条目") {
+			t.Errorf("用户消息 = %q", m.input[1].Content)
+		}
+	})
+
+	tests := []struct {
+		name string
+		tool *Tool
+		args string
+		want string
+	}{
+		{"缺少文本", &Tool{Model: &mockModel{}}, `{"target_language":"英语"}`, "错误: 需要 text 参数"},
+		{"缺少目标语言", &Tool{Model: &mockModel{}}, `{"text":"你好"}`, "错误: 需要 target_language 参数"},
+		{"未配置模型", &Tool{}, `{"text":"你好","target_language":"英语"}`, "错误: 翻译模型未配置"},
+		{"模型调用失败", &Tool{Model: &mockModel{err: errors.New("timeout")}}, `{"text":"你好","target_language":"英语"}`, "错误: 翻译失败: timeout"},
+		{"结果为空", &Tool{Model: &mockModel{reply: "  "}}, `{"text":"你好","target_language":"英语"}`, "错误: 翻译结果为空"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.tool.Run(context.Background(), tt.args)
+			if err != nil {
+				t.Fatalf("Run() 返回错误: %v", err)
+			}
+			if result != tt.want {
+				t.Errorf("Run() = %q, 期望 %q", result, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+)
+
+const (
+	openMeteoGeocodingURL = "https://geocoding-api.open-meteo.com"
+	openMeteoForecastURL  = "https://api.open-meteo.com"
+)
+
+// openMeteoGeocoding Open-Meteo 地理编码响应
+type openMeteoGeocoding struct {
+	Results []struct {
+		Name      string  `json:"name"`
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Country   string  `json:"country"`
+		Admin1    string  `json:"admin1"`
+	} `json:"results"`
+}
+
+// openMeteoForecast Open-Meteo 天气预报响应
+type openMeteoForecast struct {
+	Current struct {
+		Temperature  float64 `json:"temperature_2m"`
+		Humidity     float64 `json:"relative_humidity_2m"`
+		ApparentTemp float64 `json:"apparent_temperature"`
+		WeatherCode  int     `json:"weather_code"`
+		WindSpeed10m float64 `json:"wind_speed_10m"`
+	} `json:"current"`
+	Daily struct {
+		Time          []string   `json:"time"`
+		WeatherCode   []int      `json:"weather_code"`
+		TempMax       []float64  `json:"temperature_2m_max"`
+		TempMin       []float64  `json:"temperature_2m_min"`
+		Precipitation []*float64 `json:"precipitation_probability_max"`
+	} `json:"daily"`
+}
+
+// wmoDescriptions WMO 天气代码对应的描述
+var wmoDescriptions = map[int]string{
+	0: "晴", 1: "大部晴朗", 2: "多云", 3: "阴",
+	45: "雾", 48: "冻雾",
+	51: "小毛毛雨", 53: "毛毛雨", 55: "大毛毛雨", 56: "冻毛毛雨", 57: "强冻毛毛雨",
+	61: "小雨", 63: "中雨", 65: "大雨", 66: "冻雨", 67: "强冻雨",
+	71: "小雪", 73: "中雪", 75: "大雪", 77: "雪粒",
+	80: "小阵雨", 81: "阵雨", 82: "强阵雨", 85: "阵雪", 86: "强阵雪",
+	95: "雷阵雨", 96: "雷阵雨伴小冰雹", 99: "雷阵雨伴大冰雹",
+}
+
+// describeWMO 返回 WMO 天气代码的描述
+func describeWMO(code int) string {
+	if desc, ok := wmoDescriptions[code]; ok {
+		return desc
+	}
+	return fmt.Sprintf("天气代码 %d", code)
+}
+
+// queryOpenMeteo 通过 Open-Meteo 查询天气：先按地名查询经纬度，再查询天气预报
+func (t *Tool) queryOpenMeteo(ctx context.Context, location string, days int) (*Report, error) {
+	geocodingURL, forecastURL := openMeteoGeocodingURL, openMeteoForecastURL
+	if t.baseURL != "" {
+		geocodingURL, forecastURL = t.baseURL, t.baseURL
+	}
+
+	var geo openMeteoGeocoding
+	geoQuery := url.Values{"name": {location}, "count": {"1"}, "language": {"zh"}, "format": {"json"}}
+	if err := getJSON(ctx, geocodingURL+"/v1/search?"+geoQuery.Encode(), &geo); err != nil {
+		return nil, err
+	}
+	if len(geo.Results) == 0 {
+		return nil, fmt.Errorf("未找到地点 %s", location)
+	}
+	place := geo.Results[0]
+
+	query := url.Values{
+		"latitude":      {fmt.Sprintf("%.4f", place.Latitude)},
+		"longitude":     {fmt.Sprintf("%.4f", place.Longitude)},
+		"current":       {"temperature_2m,relative_humidity_2m,apparent_temperature,weather_code,wind_speed_10m"},
+		"daily":         {"weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max"},
+		"timezone":      {"auto"},
+		"forecast_days": {fmt.Sprintf("%d", days)},
+	}
+	if t.imperial() {
+		query.Set("temperature_unit", "fahrenheit")
+		query.Set("wind_speed_unit", "mph")
+	} else {
+		query.Set("wind_speed_unit", "ms")
+	}
+	var forecast openMeteoForecast
+	if err := getJSON(ctx, forecastURL+"/v1/forecast?"+query.Encode(), &forecast); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Location: joinNonEmpty(place.Name, place.Admin1, place.Country),
+		Current: Current{
+			Description: describeWMO(forecast.Current.WeatherCode),
+			Temperature: forecast.Current.Temperature,
+			FeelsLike:   forecast.Current.ApparentTemp,
+			Humidity:    int(math.Round(forecast.Current.Humidity)),
+			WindSpeed:   forecast.Current.WindSpeed10m,
+		},
+	}
+	daily := forecast.Daily
+	for i, date := range daily.Time {
+		if i >= len(daily.WeatherCode) || i >= len(daily.TempMax) || i >= len(daily.TempMin) {
+			break
+		}
+		day := DailyForecast{
+			Date:          date,
+			Description:   describeWMO(daily.WeatherCode[i]),
+			TempMin:       daily.TempMin[i],
+			TempMax:       daily.TempMax[i],
+			Precipitation: -1,
+		}
+		if i < len(daily.Precipitation) && daily.Precipitation[i] != nil {
+			day.Precipitation = int(math.Round(*daily.Precipitation[i]))
+		}
+		report.Forecast = append(report.Forecast, day)
+	}
+	return report, nil
+}
+
+// joinNonEmpty 用逗号连接非空且不重复的部分
+func joinNonEmpty(parts ...string) string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, p := range parts {
+		if p != "" && !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return strings.Join(result, ", ")
+}
@@ -0,0 +1,123 @@
+package weather
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"time"
+)
+
+const openWeatherMapURL = "https://api.openweathermap.org"
+
+// owmWeather OpenWeatherMap 天气描述
+type owmWeather struct {
+	Description string `json:"description"`
+}
+
+// owmCurrent OpenWeatherMap 当前天气响应
+type owmCurrent struct {
+	Name string `json:"name"`
+	Sys  struct {
+		Country string `json:"country"`
+	} `json:"sys"`
+	Weather []owmWeather `json:"weather"`
+	Main    struct {
+		Temp      float64 `json:"temp"`
+		FeelsLike float64 `json:"feels_like"`
+		Humidity  int     `json:"humidity"`
+	} `json:"main"`
+	Wind struct {
+		Speed float64 `json:"speed"`
+	} `json:"wind"`
+}
+
+// owmForecast OpenWeatherMap 5 天/3 小时预报响应
+type owmForecast struct {
+	List []struct {
+		Dt   int64 `json:"dt"`
+		Main struct {
+			TempMin float64 `json:"temp_min"`
+			TempMax float64 `json:"temp_max"`
+		} `json:"main"`
+		Weather []owmWeather `json:"weather"`
+		Pop     float64      `json:"pop"`
+	} `json:"list"`
+	City struct {
+		Timezone int64 `json:"timezone"` // 与 UTC 的偏移（秒）
+	} `json:"city"`
+}
+
+// queryOpenWeatherMap 通过 OpenWeatherMap 查询当前天气和按天汇总的预报
+func (t *Tool) queryOpenWeatherMap(ctx context.Context, location string, days int) (*Report, error) {
+	baseURL := openWeatherMapURL
+	if t.baseURL != "" {
+		baseURL = t.baseURL
+	}
+	units := "metric"
+	if t.imperial() {
+		units = "imperial"
+	}
+	query := url.Values{"q": {location}, "appid": {t.APIKey}, "units": {units}, "lang": {"zh_cn"}}
+
+	var current owmCurrent
+	if err := getJSON(ctx, baseURL+"/data/2.5/weather?"+query.Encode(), &current); err != nil {
+		return nil, err
+	}
+	report := &Report{
+		Location: joinNonEmpty(current.Name, current.Sys.Country),
+		Current: Current{
+			Description: firstDescription(current.Weather),
+			Temperature: current.Main.Temp,
+			FeelsLike:   current.Main.FeelsLike,
+			Humidity:    current.Main.Humidity,
+			WindSpeed:   current.Wind.Speed,
+		},
+	}
+
+	var forecast owmForecast
+	if err := getJSON(ctx, baseURL+"/data/2.5/forecast?"+query.Encode(), &forecast); err != nil {
+		return nil, err
+	}
+
+	// 3 小时一条的预报按当地日期汇总，天气描述取当天中午前后的一条
+	offset := time.Duration(forecast.City.Timezone) * time.Second
+	index := make(map[string]int)
+	for _, item := range forecast.List {
+		local := time.Unix(item.Dt, 0).UTC().Add(offset)
+		date := local.Format("2006-01-02")
+		pop := int(math.Round(item.Pop * 100))
+		i, ok := index[date]
+		if !ok {
+			if len(report.Forecast) >= days {
+				break
+			}
+			index[date] = len(report.Forecast)
+			report.Forecast = append(report.Forecast, DailyForecast{
+				Date:          date,
+				Description:   firstDescription(item.Weather),
+				TempMin:       item.Main.TempMin,
+				TempMax:       item.Main.TempMax,
+				Precipitation: pop,
+			})
+			continue
+		}
+		day := &report.Forecast[i]
+		day.TempMin = math.Min(day.TempMin, item.Main.TempMin)
+		day.TempMax = math.Max(day.TempMax, item.Main.TempMax)
+		if pop > day.Precipitation {
+			day.Precipitation = pop
+		}
+		if local.Hour() >= 11 && local.Hour() < 14 {
+			day.Description = firstDescription(item.Weather)
+		}
+	}
+	return report, nil
+}
+
+// firstDescription 返回第一条天气描述
+func firstDescription(weather []owmWeather) string {
+	if len(weather) == 0 {
+		return "未知"
+	}
+	return weather[0].Description
+}
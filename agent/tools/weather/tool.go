@@ -0,0 +1,201 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/schema"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+)
+
+// 支持的天气服务
+const (
+	ProviderOpenMeteo      = "open-meteo"     // 免费，无需 API Key（默认）
+	ProviderOpenWeatherMap = "openweathermap" // 需要 API Key
+)
+
+const (
+	defaultTimeout  = 15 * time.Second
+	defaultDays     = 3
+	maxForecastDays = 7
+)
+
+// Tool 天气查询工具，返回指定地点的当前天气和未来几天的预报
+type Tool struct {
+	Provider        string        // 天气服务: open-meteo（默认）, openweathermap
+	APIKey          string        // OpenWeatherMap API Key
+	Units           string        // 单位: metric（默认，摄氏度、m/s）, imperial（华氏度、mph）
+	DefaultLocation string        // 未指定地点时使用的默认地点
+	Timeout         time.Duration // 请求超时时间
+
+	baseURL string // 服务地址，为空时使用各服务的默认地址（测试时替换）
+}
+
+// Args 天气查询参数
+type Args struct {
+	Location string `json:"location"`
+	Days     int    `json:"days"`
+}
+
+// Report 天气查询结果
+type Report struct {
+	Location string
+	Current  Current
+	Forecast []DailyForecast
+}
+
+// Current 当前天气
+type Current struct {
+	Description string
+	Temperature float64
+	FeelsLike   float64
+	Humidity    int
+	WindSpeed   float64
+}
+
+// DailyForecast 单日预报
+type DailyForecast struct {
+	Date          string
+	Description   string
+	TempMin       float64
+	TempMax       float64
+	Precipitation int // 降水概率（%），未知时为 -1
+}
+
+// Name 返回工具名称
+func (t *Tool) Name() string {
+	return "weather"
+}
+
+// Info 返回工具信息
+func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
+	locationDesc := "地点名称，如 \"北京\"、\"Shanghai\"、\"London\""
+	if t.DefaultLocation != "" {
+		locationDesc += fmt.Sprintf("，不填时使用默认地点 %s", t.DefaultLocation)
+	}
+	return &schema.ToolInfo{
+		Name: t.Name(),
+		Desc: "查询指定地点的当前天气和未来几天的天气预报",
+		ParamsOneOf: schema.NewParamsOneOfByParams(map[string]*schema.ParameterInfo{
+			"location": {
+				Type:     schema.DataType("string"),
+				Desc:     locationDesc,
+				Required: t.DefaultLocation == "",
+			},
+			"days": {
+				Type: schema.DataType("integer"),
+				Desc: fmt.Sprintf("预报天数，1-%d，默认 %d", maxForecastDays, defaultDays),
+			},
+		}),
+	}, nil
+}
+
+// Run 执行工具逻辑
+func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	var args Args
+	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
+		return "", err
+	}
+	location := strings.TrimSpace(args.Location)
+	if location == "" {
+		location = t.DefaultLocation
+	}
+	if location == "" {
+		return "错误: 需要 location 参数", nil
+	}
+	days := args.Days
+	if days <= 0 {
+		days = defaultDays
+	}
+	if days > maxForecastDays {
+		days = maxForecastDays
+	}
+
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var report *Report
+	var err error
+	switch t.Provider {
+	case "", ProviderOpenMeteo:
+		report, err = t.queryOpenMeteo(ctx, location, days)
+	case ProviderOpenWeatherMap:
+		if t.APIKey == "" {
+			return "错误: 未配置 OpenWeatherMap API Key", nil
+		}
+		report, err = t.queryOpenWeatherMap(ctx, location, days)
+	default:
+		return fmt.Sprintf("错误: 不支持的天气服务 %s", t.Provider), nil
+	}
+	if err != nil {
+		return fmt.Sprintf("错误: 查询天气失败: %v", err), nil
+	}
+	return t.format(report), nil
+}
+
+// InvokableRun 可直接调用的执行入口
+func (t *Tool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	return t.Run(ctx, argumentsInJSON, opts...)
+}
+
+// imperial 是否使用英制单位
+func (t *Tool) imperial() bool {
+	return t.Units == "imperial"
+}
+
+// format 将查询结果格式化为文本
+func (t *Tool) format(r *Report) string {
+	tempUnit, windUnit := "°C", "m/s"
+	if t.imperial() {
+		tempUnit, windUnit = "°F", "mph"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "【位置】%s\n", r.Location)
+	c := r.Current
+	fmt.Fprintf(&sb, "【当前】%s，%.1f%s（体感 %.1f%s），湿度 %d%%，风速 %.1f %s",
+		c.Description, c.Temperature, tempUnit, c.FeelsLike, tempUnit, c.Humidity, c.WindSpeed, windUnit)
+	if len(r.Forecast) > 0 {
+		sb.WriteString("\n【预报】")
+		for _, d := range r.Forecast {
+			fmt.Fprintf(&sb, "\n- %s: %s，%.0f~%.0f%s", d.Date, d.Description, d.TempMin, d.TempMax, tempUnit)
+			if d.Precipitation >= 0 {
+				fmt.Fprintf(&sb, "，降水概率 %d%%", d.Precipitation)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// getJSON 发送 GET 请求并解析 JSON 响应
+func getJSON(ctx context.Context, apiURL string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Nanobot/1.0)")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, common.TruncateString(strings.TrimSpace(string(body)), 200))
+	}
+	return json.Unmarshal(body, out)
+}
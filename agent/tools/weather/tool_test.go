@@ -0,0 +1,123 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestServer 创建模拟天气服务，按路径返回固定响应，并记录最近一次请求的查询参数
+func newTestServer(t *testing.T, responses map[string]string, query *map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if query != nil {
+			(*query)[r.URL.Path] = r.URL.RawQuery
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.Error(w, `{"message":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestTool_OpenMeteo 测试通过 Open-Meteo 查询天气
+func TestTool_OpenMeteo(t *testing.T) {
+	queries := map[string]string{}
+	server := newTestServer(t, map[string]string{
+		"/v1/search": `{"results":[{"name":"北京","latitude":39.9075,"longitude":116.39723,"country":"中国","admin1":"北京"}]}`,
+		"/v1/forecast": `{
+			"current":{"temperature_2m":12.3,"relative_humidity_2m":40,"apparent_temperature":10.1,"weather_code":0,"wind_speed_10m":3.2},
+			"daily":{"time":["2026-10-16","2026-10-17"],"weather_code":[0,61],"temperature_2m_max":[18.2,15],"temperature_2m_min":[8.1,9],"precipitation_probability_max":[10,null]}
+		}`,
+	}, &queries)
+	tool := &Tool{baseURL: server.URL}
+
+	result, err := tool.Run(context.Background(), `{"location":"北京","days":2}`)
+	if err != nil {
+		t.Fatalf("Run() 返回错误: %v", err)
+	}
+	for _, want := range []string{
+		"【位置】北京, 中国",
+		"【当前】晴，12.3°C（体感 10.1°C），湿度 40%，风速 3.2 m/s",
+		"- 2026-10-16: 晴，8~18°C，降水概率 10%",
+		"- 2026-10-17: 小雨，9~15°C",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("结果缺少 %q:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "2026-10-17: 小雨，9~15°C，降水概率") {
+		t.Error("缺少降水概率时不应输出")
+	}
+	if !strings.Contains(queries["/v1/forecast"], "forecast_days=2") || !strings.Contains(queries["/v1/forecast"], "wind_speed_unit=ms") {
+		t.Errorf("预报请求参数 = %s", queries["/v1/forecast"])
+	}
+}
+
+// TestTool_OpenWeatherMap 测试通过 OpenWeatherMap 查询天气并按天汇总预报
+func TestTool_OpenWeatherMap(t *testing.T) {
+	queries := map[string]string{}
+	server := newTestServer(t, map[string]string{
+		"/data/2.5/weather": `{"name":"London","sys":{"country":"GB"},"weather":[{"description":"多云"}],"main":{"temp":50.5,"feels_like":48,"humidity":80},"wind":{"speed":5.1}}`,
+		"/data/2.5/forecast": `{"city":{"timezone":0},"list":[
+			{"dt":1792137600,"main":{"temp_min":45,"temp_max":47},"weather":[{"description":"小雨"}],"pop":0.6},
+			{"dt":1792152000,"main":{"temp_min":48,"temp_max":55},"weather":[{"description":"多云"}],"pop":0.2},
+			{"dt":1792224000,"main":{"temp_min":44,"temp_max":52},"weather":[{"description":"晴"}],"pop":0}
+		]}`,
+	}, &queries)
+	tool := &Tool{Provider: ProviderOpenWeatherMap, APIKey: "key", Units: "imperial", DefaultLocation: "London", baseURL: server.URL}
+
+	result, err := tool.Run(context.Background(), `{}`)
+	if err != nil {
+		t.Fatalf("Run() 返回错误: %v", err)
+	}
+	for _, want := range []string{
+		"【位置】London, GB",
+		"【当前】多云，50.5°F（体感 48.0°F），湿度 80%，风速 5.1 mph",
+		"- 2026-10-16: 多云，45~55°F，降水概率 60%",
+		"- 2026-10-17: 晴，44~52°F，降水概率 0%",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("结果缺少 %q:\n%s", want, result)
+		}
+	}
+	if !strings.Contains(queries["/data/2.5/weather"], "units=imperial") || !strings.Contains(queries["/data/2.5/weather"], "appid=key") {
+		t.Errorf("请求参数 = %s", queries["/data/2.5/weather"])
+	}
+}
+
+// TestTool_Run_Errors 测试参数与配置错误
+func TestTool_Run_Errors(t *testing.T) {
+	server := newTestServer(t, map[string]string{"/v1/search": `{}`}, nil)
+
+	tests := []struct {
+		name string
+		tool *Tool
+		args string
+		want string
+	}{
+		{"缺少地点", &Tool{}, `{}`, "错误: 需要 location 参数"},
+		{"未配置 API Key", &Tool{Provider: ProviderOpenWeatherMap}, `{"location":"北京"}`, "错误: 未配置 OpenWeatherMap API Key"},
+		{"不支持的服务", &Tool{Provider: "foo"}, `{"location":"北京"}`, "错误: 不支持的天气服务 foo"},
+		{"地点不存在", &Tool{baseURL: server.URL}, `{"location":"不存在的地方"}`, "错误: 查询天气失败: 未找到地点 不存在的地方"},
+		{"服务返回错误", &Tool{Provider: ProviderOpenWeatherMap, APIKey: "k", baseURL: server.URL}, `{"location":"北京"}`, "错误: 查询天气失败: HTTP 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.tool.Run(context.Background(), tt.args)
+			if err != nil {
+				t.Fatalf("Run() 返回错误: %v", err)
+			}
+			if !strings.HasPrefix(result, tt.want) {
+				t.Errorf("Run() = %q, 期望以 %q 开头", result, tt.want)
+			}
+		})
+	}
+}
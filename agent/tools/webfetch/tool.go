@@ -15,6 +15,7 @@ import (
 	"github.com/cloudwego/eino/schema"
 	readability "github.com/go-shiori/go-readability"
 	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	"github.com/weibaohui/nanobot-go/utils"
 )
 
 const (
@@ -226,10 +227,7 @@ func (t *Tool) fetchURL(ctx context.Context, rawURL, extractMode string, maxChar
 	}
 
 	// 截断内容
-	truncated := len(text) > maxChars
-	if truncated {
-		text = text[:maxChars]
-	}
+	text, truncated := utils.TruncateRunes(text, maxChars)
 
 	// 构建结果
 	result := fetchResult{
@@ -37,6 +37,10 @@ func (t *Tool) Info(ctx context.Context) (*schema.ToolInfo, error) {
 				Desc:     "要写入的内容",
 				Required: true,
 			},
+			"mode": {
+				Type: schema.DataType("string"),
+				Desc: "写入模式: overwrite（默认，覆盖）、append（追加到末尾）、create（仅新建，文件已存在时失败）",
+			},
 		}),
 	}, nil
 }
@@ -46,18 +50,57 @@ func (t *Tool) Run(ctx context.Context, argumentsInJSON string, opts ...tool.Opt
 	var args struct {
 		Path    string `json:"path"`
 		Content string `json:"content"`
+		Mode    string `json:"mode"`
 	}
 	if err := common.DecodeArgs(argumentsInJSON, &args); err != nil {
 		return "", err
 	}
-	resolved := common.ResolvePath(args.Path, t.AllowedDir)
+
+	flag := os.O_WRONLY | os.O_CREATE
+	switch args.Mode {
+	case "", "overwrite":
+		flag |= os.O_TRUNC
+	case "append":
+		flag |= os.O_APPEND
+	case "create":
+		flag |= os.O_EXCL
+	default:
+		return fmt.Sprintf("错误: 不支持的写入模式: %s", args.Mode), nil
+	}
+
+	resolved, err := common.ResolveScopedPath(ctx, args.Path, t.AllowedDir)
+	if err != nil {
+		return fmt.Sprintf("错误: %s", err), nil
+	}
 	if err := os.MkdirAll(filepath.Dir(resolved), 0755); err != nil {
 		return "", err
 	}
-	if err := os.WriteFile(resolved, []byte(args.Content), 0644); err != nil {
+	size, err := writeContent(resolved, flag, args.Content)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Sprintf("错误: 文件已存在: %s", args.Path), nil
+		}
 		return "", err
 	}
-	return fmt.Sprintf("成功写入 %d 字节到 %s", len(args.Content), args.Path), nil
+	return fmt.Sprintf("成功写入 %d 字节到 %s，文件大小: %d 字节", len(args.Content), args.Path, size), nil
+}
+
+// writeContent 按指定模式写入内容，返回写入后的文件大小
+func writeContent(path string, flag int, content string) (int64, error) {
+	f, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	return info.Size(), f.Close()
 }
 
 // InvokableRun 可直接调用的执行入口
@@ -2,8 +2,10 @@ package writefile
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/cloudwego/eino/schema"
@@ -131,6 +133,74 @@ func TestTool_Run(t *testing.T) {
 	})
 }
 
+// TestTool_RunModes 测试写入模式
+func TestTool_RunModes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("追加写入", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		testFile := filepath.Join(tmpDir, "log.txt")
+		os.WriteFile(testFile, []byte("line1\n"), 0644)
+
+		tool := &Tool{AllowedDir: tmpDir}
+		result, err := tool.Run(ctx, `{"path": "`+testFile+`", "content": "line2\n", "mode": "append"}`)
+		if err != nil {
+			t.Errorf("Run() 返回错误: %v", err)
+		}
+		if !strings.Contains(result, "文件大小: 12 字节") {
+			t.Errorf("Run() = %q, 期望包含文件大小", result)
+		}
+
+		data, _ := os.ReadFile(testFile)
+		if string(data) != "line1\nline2\n" {
+			t.Errorf("文件内容 = %q", string(data))
+		}
+	})
+
+	t.Run("仅新建且文件已存在", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		testFile := filepath.Join(tmpDir, "exists.txt")
+		os.WriteFile(testFile, []byte("original"), 0644)
+
+		tool := &Tool{AllowedDir: tmpDir}
+		result, err := tool.Run(ctx, `{"path": "`+testFile+`", "content": "new", "mode": "create"}`)
+		if err != nil {
+			t.Errorf("Run() 返回错误: %v", err)
+		}
+		if !strings.HasPrefix(result, "错误: 文件已存在") {
+			t.Errorf("Run() = %q, 期望返回文件已存在错误", result)
+		}
+
+		data, _ := os.ReadFile(testFile)
+		if string(data) != "original" {
+			t.Errorf("文件不应被修改, 得到 %q", string(data))
+		}
+	})
+
+	t.Run("仅新建", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		testFile := filepath.Join(tmpDir, "new.txt")
+
+		tool := &Tool{AllowedDir: tmpDir}
+		if _, err := tool.Run(ctx, `{"path": "`+testFile+`", "content": "new", "mode": "create"}`); err != nil {
+			t.Errorf("Run() 返回错误: %v", err)
+		}
+
+		data, _ := os.ReadFile(testFile)
+		if string(data) != "new" {
+			t.Errorf("文件内容 = %q, 期望 new", string(data))
+		}
+	})
+
+	t.Run("不支持的模式", func(t *testing.T) {
+		tool := &Tool{}
+		result, _ := tool.Run(ctx, `{"path": "/tmp/x.txt", "content": "x", "mode": "prepend"}`)
+		if !strings.HasPrefix(result, "错误: 不支持的写入模式") {
+			t.Errorf("Run() = %q", result)
+		}
+	})
+}
+
 // TestTool_InvokableRun 测试 InvokableRun 方法
 func TestTool_InvokableRun(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -211,3 +281,37 @@ func TestTool_WriteLargeContent(t *testing.T) {
 		t.Errorf("文件内容长度 = %d, 期望 1024", len(data))
 	}
 }
+
+// TestTool_Run_OutsideAllowedDir 测试拒绝访问允许目录之外的路径
+func TestTool_Run_OutsideAllowedDir(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "ws")
+	sibling := filepath.Join(root, "ws-evil")
+	for _, dir := range []string{workspace, sibling} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("创建目录失败: %v", err)
+		}
+	}
+	secret := filepath.Join(sibling, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatalf("写入测试文件失败: %v", err)
+	}
+	tool := &Tool{AllowedDir: workspace}
+
+	for _, path := range []string{secret, filepath.Join(workspace, "..", "ws-evil", "secret.txt"), filepath.Join(sibling, "new.txt")} {
+		args, _ := json.Marshal(map[string]string{"path": path, "content": "x"})
+		result, err := tool.Run(context.Background(), string(args))
+		if err != nil {
+			t.Fatalf("Run() 返回错误: %v", err)
+		}
+		if !strings.Contains(result, "不在允许的目录内") {
+			t.Errorf("Run(%q) = %q, 期望拒绝", path, result)
+		}
+	}
+	if data, _ := os.ReadFile(secret); string(data) != "secret" {
+		t.Errorf("允许目录之外的文件被修改: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(sibling, "new.txt")); !os.IsNotExist(err) {
+		t.Error("不应在允许目录之外创建文件")
+	}
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	"github.com/weibaohui/nanobot-go/bus"
+)
+
+// 工作区隔离方式
+const (
+	WorkspaceIsolationChannel = "channel" // 每个渠道一个子目录
+	WorkspaceIsolationSender  = "sender"  // 每个渠道下每个发送者一个子目录
+)
+
+// workspaceIsolated 返回是否启用了工作区隔离
+func (l *Loop) workspaceIsolated() bool {
+	if l.cfg == nil {
+		return false
+	}
+	switch l.cfg.Agents.Defaults.WorkspaceIsolation {
+	case WorkspaceIsolationChannel, WorkspaceIsolationSender:
+		return true
+	}
+	return false
+}
+
+// withTurnWorkspace 启用工作区隔离时，把回合的独立工作区注入 context
+// 文件工具只能访问该目录，多用户共用网关时互相看不到对方的文件
+// exec 和插件工具运行任意程序，无法可靠限制访问的路径，启用隔离时不注册
+func (l *Loop) withTurnWorkspace(ctx context.Context, msg *bus.InboundMessage) (context.Context, error) {
+	if l.cfg == nil {
+		return ctx, nil
+	}
+	var segments []string
+	switch l.cfg.Agents.Defaults.WorkspaceIsolation {
+	case WorkspaceIsolationChannel:
+		segments = []string{msg.Channel}
+	case WorkspaceIsolationSender:
+		segments = []string{msg.Channel, msg.SenderID}
+	default:
+		return ctx, nil
+	}
+
+	dir := l.workspace
+	for _, segment := range segments {
+		dir = filepath.Join(dir, workspaceSegment(segment))
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ctx, fmt.Errorf("创建工作区目录失败: %w", err)
+	}
+	return common.WithWorkspace(ctx, dir), nil
+}
+
+// workspaceSegment 把渠道名或发送者 ID 转换为安全的目录名，避免路径分隔符和 .. 跳出工作区
+func workspaceSegment(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', 0:
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
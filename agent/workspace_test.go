@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weibaohui/nanobot-go/agent/tools"
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	"github.com/weibaohui/nanobot-go/agent/tools/readfile"
+	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// TestLoop_WithTurnWorkspace 测试按渠道或发送者划分工作区
+func TestLoop_WithTurnWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	newLoop := func(isolation string) *Loop {
+		cfg := &config.Config{}
+		cfg.Agents.Defaults.WorkspaceIsolation = isolation
+		return &Loop{cfg: cfg, workspace: workspace}
+	}
+	msg := bus.NewInboundMessage("matrix", "@alice:example.org", "!room", "hi")
+
+	cases := map[string]string{
+		"":                        "",
+		WorkspaceIsolationChannel: filepath.Join(workspace, "matrix"),
+		WorkspaceIsolationSender:  filepath.Join(workspace, "matrix", "@alice_example.org"),
+	}
+	for isolation, want := range cases {
+		ctx, err := newLoop(isolation).withTurnWorkspace(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("withTurnWorkspace(%q) 返回错误: %v", isolation, err)
+		}
+		if got := common.WorkspaceFromContext(ctx); got != want {
+			t.Errorf("withTurnWorkspace(%q) 工作区 = %q, 期望 %q", isolation, got, want)
+		}
+		if want != "" {
+			if info, err := os.Stat(want); err != nil || !info.IsDir() {
+				t.Errorf("工作区目录 %s 未创建", want)
+			}
+		}
+	}
+
+	t.Run("发送者 ID 不能跳出工作区", func(t *testing.T) {
+		if got := workspaceSegment("../.."); strings.Contains(got, "/") {
+			t.Errorf("workspaceSegment() = %q", got)
+		}
+		if got := workspaceSegment(".."); got != "_" {
+			t.Errorf("workspaceSegment(..) = %q", got)
+		}
+	})
+
+	t.Run("文件工具只能访问本人的工作区", func(t *testing.T) {
+		loop := newLoop(WorkspaceIsolationSender)
+		bob, _ := loop.withTurnWorkspace(context.Background(), bus.NewInboundMessage("matrix", "bob", "!room", "hi"))
+		alice, _ := loop.withTurnWorkspace(context.Background(), msg)
+		os.WriteFile(filepath.Join(common.WorkspaceFromContext(bob), "secret.txt"), []byte("bob 的文件"), 0644)
+
+		tool := &readfile.Tool{}
+		if got, _ := tool.Run(bob, `{"path": "secret.txt"}`); got != "bob 的文件" {
+			t.Errorf("读取本人文件 = %q", got)
+		}
+		path := filepath.Join(common.WorkspaceFromContext(bob), "secret.txt")
+		if got, _ := tool.Run(alice, `{"path": "`+path+`"}`); !strings.HasPrefix(got, "错误: ") {
+			t.Errorf("读取他人文件 = %q, 期望返回错误", got)
+		}
+	})
+
+	t.Run("启用隔离时不提供 exec 和插件工具", func(t *testing.T) {
+		pluginDir := filepath.Join(workspace, "plugins")
+		os.MkdirAll(pluginDir, 0755)
+		os.WriteFile(filepath.Join(pluginDir, "hello.sh"), []byte("#!/bin/sh\necho hi\n"), 0755)
+		os.WriteFile(filepath.Join(pluginDir, "hello.json"), []byte(`{"name": "hello", "description": "打招呼", "command": "hello.sh"}`), 0644)
+		for isolation, want := range map[string]bool{"": true, WorkspaceIsolationSender: false} {
+			loop := newLoop(isolation)
+			loop.tools = tools.NewRegistry()
+			loop.context = NewContextBuilder(workspace)
+			loop.logger = zap.NewNop()
+			loop.registerDefaultTools()
+			if got := loop.tools.Get("exec") != nil; got != want {
+				t.Errorf("隔离方式 %q 注册 exec = %v, 期望 %v", isolation, got, want)
+			}
+			if loop.tools.Get("read_file") == nil {
+				t.Errorf("隔离方式 %q 未注册文件工具", isolation)
+			}
+			if got := loop.tools.Get("hello") != nil; got != want {
+				t.Errorf("隔离方式 %q 注册插件 = %v, 期望 %v", isolation, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package bus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditRecord 审计日志中的一条记录
+type AuditRecord struct {
+	Time        time.Time `json:"time"`
+	Direction   string    `json:"direction"` // inbound 或 outbound
+	Channel     string    `json:"channel"`
+	SenderID    string    `json:"sender_id,omitempty"`
+	ChatID      string    `json:"chat_id"`
+	ContentHash string    `json:"content_hash"`       // 内容的 SHA-256
+	Content     string    `json:"content,omitempty"`  // 仅在启用 includeContent 时记录原文
+	TraceID     string    `json:"trace_id,omitempty"` // 链路追踪 ID
+}
+
+// AuditLog 追加写入的消息审计日志
+// 按天轮转，文件名为 audit-YYYY-MM-DD.jsonl
+type AuditLog struct {
+	dir            string
+	includeContent bool
+	now            func() time.Time
+
+	mu   sync.Mutex
+	date string
+	file *os.File
+}
+
+// NewAuditLog 创建审计日志，includeContent 为 false 时只记录内容哈希
+func NewAuditLog(dir string, includeContent bool) (*AuditLog, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建审计目录失败: %w", err)
+	}
+	return &AuditLog{
+		dir:            dir,
+		includeContent: includeContent,
+		now:            time.Now,
+	}, nil
+}
+
+// RecordInbound 记录入站消息
+func (a *AuditLog) RecordInbound(msg *InboundMessage) error {
+	return a.write("inbound", msg.Channel, msg.SenderID, msg.ChatID, msg.Content, msg.TraceID)
+}
+
+// RecordOutbound 记录出站消息
+func (a *AuditLog) RecordOutbound(msg *OutboundMessage) error {
+	return a.write("outbound", msg.Channel, "", msg.ChatID, msg.Content, msg.TraceID)
+}
+
+// write 写入一条记录，日期变化时切换到新文件
+func (a *AuditLog) write(direction, channel, senderID, chatID, content, traceID string) error {
+	now := a.now()
+	sum := sha256.Sum256([]byte(content))
+	record := AuditRecord{
+		Time:        now,
+		Direction:   direction,
+		Channel:     channel,
+		SenderID:    senderID,
+		ChatID:      chatID,
+		ContentHash: hex.EncodeToString(sum[:]),
+		TraceID:     traceID,
+	}
+	if a.includeContent {
+		record.Content = content
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	date := now.Format("2006-01-02")
+	if a.file == nil || a.date != date {
+		if a.file != nil {
+			a.file.Close()
+		}
+		path := filepath.Join(a.dir, "audit-"+date+".jsonl")
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			a.file = nil
+			return fmt.Errorf("打开审计日志失败: %w", err)
+		}
+		a.file = f
+		a.date = date
+	}
+
+	_, err = a.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close 关闭当前审计日志文件
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
@@ -0,0 +1,86 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readAuditRecords 读取审计文件中的全部记录
+func readAuditRecords(t *testing.T, path string) []AuditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("打开审计文件失败: %v", err)
+	}
+	defer f.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("解析审计记录失败: %v", err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// TestAuditLog 测试审计日志写入与按天轮转
+func TestAuditLog(t *testing.T) {
+	t.Run("默认只记录哈希", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := NewAuditLog(dir, false)
+		if err != nil {
+			t.Fatalf("NewAuditLog() 返回错误: %v", err)
+		}
+		a.now = func() time.Time { return time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local) }
+
+		inbound := NewInboundMessage("cli", "user", "default", "你好")
+		inbound.TraceID = "trace-1"
+		if err := a.RecordInbound(inbound); err != nil {
+			t.Fatalf("RecordInbound() 返回错误: %v", err)
+		}
+		if err := a.RecordOutbound(NewOutboundMessage("cli", "default", "你好，有什么可以帮你？")); err != nil {
+			t.Fatalf("RecordOutbound() 返回错误: %v", err)
+		}
+		a.Close()
+
+		records := readAuditRecords(t, filepath.Join(dir, "audit-2026-01-02.jsonl"))
+		if len(records) != 2 {
+			t.Fatalf("len(records) = %d, 期望 2", len(records))
+		}
+		if records[0].Direction != "inbound" || records[0].SenderID != "user" || records[0].TraceID != "trace-1" || records[1].Direction != "outbound" {
+			t.Errorf("records = %+v", records)
+		}
+		if records[0].Content != "" || len(records[0].ContentHash) != 64 {
+			t.Errorf("未启用原文时不应记录内容: %+v", records[0])
+		}
+	})
+
+	t.Run("记录原文并按天轮转", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := NewAuditLog(dir, true)
+		if err != nil {
+			t.Fatalf("NewAuditLog() 返回错误: %v", err)
+		}
+		day := time.Date(2026, 1, 2, 23, 59, 0, 0, time.Local)
+		a.now = func() time.Time { return day }
+		a.RecordInbound(NewInboundMessage("cli", "user", "default", "第一天"))
+		day = day.Add(2 * time.Minute)
+		a.RecordInbound(NewInboundMessage("cli", "user", "default", "第二天"))
+		a.Close()
+
+		second := readAuditRecords(t, filepath.Join(dir, "audit-2026-01-03.jsonl"))
+		if len(second) != 1 || second[0].Content != "第二天" {
+			t.Errorf("第二天记录 = %+v", second)
+		}
+		if first := readAuditRecords(t, filepath.Join(dir, "audit-2026-01-02.jsonl")); len(first) != 1 {
+			t.Errorf("第一天记录数 = %d, 期望 1", len(first))
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package bus
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchmarkChats 基准测试中同一渠道下的聊天数
+const benchmarkChats = 100
+
+// BenchmarkDispatchStream_HandlerFilter 每个聊天订阅整个渠道、在回调中按聊天 ID 过滤
+func BenchmarkDispatchStream_HandlerFilter(b *testing.B) {
+	bus := NewMessageBus(nil)
+	delivered := 0
+	for i := 0; i < benchmarkChats; i++ {
+		chatID := fmt.Sprintf("chat%d", i)
+		bus.SubscribeStream("websocket", func(chunk *StreamChunk) error {
+			if chunk.ChatID != chatID {
+				return nil
+			}
+			delivered++
+			return nil
+		})
+	}
+	chunk := NewStreamChunk("websocket", "chat42", "你", "你", false)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.dispatchStreamToSubscribers(chunk)
+	}
+	if delivered != b.N {
+		b.Fatalf("delivered = %d, 期望 %d", delivered, b.N)
+	}
+}
+
+// BenchmarkDispatchStream_ChatTopic 每个聊天按聊天 ID 订阅，分发时只调用目标聊天的回调
+func BenchmarkDispatchStream_ChatTopic(b *testing.B) {
+	bus := NewMessageBus(nil)
+	delivered := 0
+	for i := 0; i < benchmarkChats; i++ {
+		bus.SubscribeStreamChat("websocket", fmt.Sprintf("chat%d", i), func(chunk *StreamChunk) error {
+			delivered++
+			return nil
+		})
+	}
+	chunk := NewStreamChunk("websocket", "chat42", "你", "你", false)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bus.dispatchStreamToSubscribers(chunk)
+	}
+	if delivered != b.N {
+		b.Fatalf("delivered = %d, 期望 %d", delivered, b.N)
+	}
+}
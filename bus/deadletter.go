@@ -0,0 +1,118 @@
+package bus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RetryPolicy 出站消息发送失败后的重试策略
+type RetryPolicy struct {
+	MaxAttempts    int           // 最大尝试次数（含首次发送），小于等于 1 表示不重试
+	InitialBackoff time.Duration // 首次重试前的等待时间，之后每次翻倍
+	MaxBackoff     time.Duration // 单次等待时间上限
+}
+
+// DefaultRetryPolicy 返回默认重试策略：最多 3 次，退避 1s 起步、上限 30s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// backoff 返回第 retry 次重试（从 1 开始）前需要等待的时间
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// SendStats 出站消息发送统计
+type SendStats struct {
+	Failures    int64 `json:"failures"`     // 发送失败次数（含重试失败）
+	Retries     int64 `json:"retries"`      // 重试次数
+	Recovered   int64 `json:"recovered"`    // 重试后发送成功的消息数
+	DeadLetters int64 `json:"dead_letters"` // 最终放弃并写入死信日志的消息数
+}
+
+// DeadLetterRecord 死信日志中的一条记录
+type DeadLetterRecord struct {
+	Time     time.Time `json:"time"`
+	Channel  string    `json:"channel"`
+	ChatID   string    `json:"chat_id"`
+	Content  string    `json:"content"`
+	Attempts int       `json:"attempts"` // 已尝试次数
+	Error    string    `json:"error"`    // 最后一次失败原因
+}
+
+// DeadLetterLog 追加写入的死信日志，记录多次重试后仍发送失败的出站消息
+// 文件名为 dead-letter.jsonl，便于人工排查或补发
+type DeadLetterLog struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewDeadLetterLog 在指定目录下创建死信日志
+func NewDeadLetterLog(dir string) (*DeadLetterLog, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建死信目录失败: %w", err)
+	}
+	return &DeadLetterLog{path: filepath.Join(dir, "dead-letter.jsonl")}, nil
+}
+
+// Record 写入一条死信记录
+func (d *DeadLetterLog) Record(msg *OutboundMessage, attempts int, sendErr error) error {
+	record := DeadLetterRecord{
+		Time:     time.Now(),
+		Channel:  msg.Channel,
+		ChatID:   msg.ChatID,
+		Content:  msg.Content,
+		Attempts: attempts,
+	}
+	if sendErr != nil {
+		record.Error = sendErr.Error()
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		f, err := os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("打开死信日志失败: %w", err)
+		}
+		d.file = f
+	}
+	_, err = d.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close 关闭死信日志文件
+func (d *DeadLetterLog) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
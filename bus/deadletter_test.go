@@ -0,0 +1,128 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// waitFor 在超时前轮询条件
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("等待条件超时")
+}
+
+// TestRetryPolicy_Backoff 测试退避时间计算
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, 期望 %v", i+1, got, w)
+		}
+	}
+}
+
+// TestMessageBus_RetryAndDeadLetter 测试发送失败后的重试与死信记录
+func TestMessageBus_RetryAndDeadLetter(t *testing.T) {
+	t.Run("重试后发送成功", func(t *testing.T) {
+		b := NewMessageBus(zap.NewNop())
+		b.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+		var calls atomic.Int32
+		b.SubscribeOutbound("test", func(msg *OutboundMessage) error {
+			if calls.Add(1) < 2 {
+				return errors.New("network error")
+			}
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		b.StartDispatcher(ctx)
+		b.PublishOutbound(NewOutboundMessage("test", "chat", "hello"))
+
+		waitFor(t, func() bool { return b.SendStats().Recovered == 1 })
+		stats := b.SendStats()
+		if stats.Failures != 1 || stats.Retries != 1 || stats.DeadLetters != 0 {
+			t.Errorf("统计 = %+v", stats)
+		}
+	})
+
+	t.Run("多次失败后写入死信日志", func(t *testing.T) {
+		dir := t.TempDir()
+		deadLetter, err := NewDeadLetterLog(dir)
+		if err != nil {
+			t.Fatalf("创建死信日志失败: %v", err)
+		}
+		defer deadLetter.Close()
+
+		b := NewMessageBus(zap.NewNop())
+		b.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+		b.SetDeadLetterLog(deadLetter)
+
+		var calls atomic.Int32
+		b.SubscribeOutbound("test", func(msg *OutboundMessage) error {
+			calls.Add(1)
+			return errors.New("rate limited")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		b.StartDispatcher(ctx)
+		b.PublishOutbound(NewOutboundMessage("test", "chat", "hello"))
+
+		waitFor(t, func() bool { return b.SendStats().DeadLetters == 1 })
+		if calls.Load() != 3 {
+			t.Errorf("发送次数 = %d, 期望 3", calls.Load())
+		}
+		if stats := b.SendStats(); stats.Failures != 3 || stats.Retries != 2 {
+			t.Errorf("统计 = %+v", stats)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "dead-letter.jsonl"))
+		if err != nil {
+			t.Fatalf("读取死信日志失败: %v", err)
+		}
+		var record DeadLetterRecord
+		if err := json.Unmarshal([]byte(strings.TrimSpace(string(data))), &record); err != nil {
+			t.Fatalf("解析死信记录失败: %v", err)
+		}
+		if record.ChatID != "chat" || record.Content != "hello" || record.Attempts != 3 || record.Error != "rate limited" {
+			t.Errorf("死信记录 = %+v", record)
+		}
+	})
+
+	t.Run("不重试时直接记为死信", func(t *testing.T) {
+		b := NewMessageBus(zap.NewNop())
+		b.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+		b.SubscribeOutbound("test", func(msg *OutboundMessage) error {
+			return errors.New("boom")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		b.StartDispatcher(ctx)
+		b.PublishOutbound(NewOutboundMessage("test", "chat", "hello"))
+
+		waitFor(t, func() bool { return b.SendStats().DeadLetters == 1 })
+		if b.SendStats().Retries != 0 {
+			t.Error("不应重试")
+		}
+	})
+}
@@ -11,10 +11,17 @@ type InboundMessage struct {
 	Timestamp time.Time      `json:"timestamp"` // 时间戳
 	Media     []string       `json:"media"`     // 媒体 URL 列表
 	Metadata  map[string]any `json:"metadata"`  // 渠道特定数据
+	TraceID   string         `json:"trace_id"`  // 链路追踪 ID，发布时生成，贯穿本条消息的整个处理回合
 }
 
-// SessionKey 返回会话的唯一标识符
+// SessionKeyMetadataKey Metadata 中指定会话键的字段，用于让内部消息（如定时任务）使用独立的会话
+const SessionKeyMetadataKey = "session_key"
+
+// SessionKey 返回会话的唯一标识符，默认为 channel:chatID，Metadata 中指定了会话键时使用指定值
 func (m *InboundMessage) SessionKey() string {
+	if key, ok := m.Metadata[SessionKeyMetadataKey].(string); ok && key != "" {
+		return key
+	}
 	return m.Channel + ":" + m.ChatID
 }
 
@@ -26,15 +33,41 @@ type OutboundMessage struct {
 	ReplyTo  string         `json:"reply_to,omitempty"`
 	Media    []string       `json:"media,omitempty"`
 	Metadata map[string]any `json:"metadata,omitempty"`
+	TraceID  string         `json:"trace_id,omitempty"` // 所属回合的链路追踪 ID
 }
 
+// StreamChunkTypeStatus 表示进度状态片段（如工具开始/结束），不属于回复正文
+const StreamChunkTypeStatus = "status"
+
+// StreamChunkTypeThinking 表示推理模型的思考过程片段，不属于回复正文
+const StreamChunkTypeThinking = "thinking"
+
 // StreamChunk 表示流式输出的一个片段
 type StreamChunk struct {
 	Channel string `json:"channel"`
 	ChatID  string `json:"chat_id"`
-	Delta   string `json:"delta"`   // 增量内容
-	Content string `json:"content"` // 累积内容
-	Done    bool   `json:"done"`    // 是否完成
+	Type    string `json:"type,omitempty"` // 片段类型：空为回复内容，status 为进度状态，thinking 为思考过程
+	Delta   string `json:"delta"`          // 增量内容
+	Content string `json:"content"`        // 累积内容（状态片段为状态文本）
+	Done    bool   `json:"done"`           // 是否完成
+}
+
+// IsStatus 判断是否为进度状态片段
+func (c *StreamChunk) IsStatus() bool {
+	return c.Type == StreamChunkTypeStatus
+}
+
+// IsThinking 判断是否为思考过程片段
+func (c *StreamChunk) IsThinking() bool {
+	return c.Type == StreamChunkTypeThinking
+}
+
+// TurnEnd 表示一个回合已完全结束，之后不会再有该回合的回复或流式片段
+// 渠道据此停止输入状态、收尾界面，而不必根据 StreamChunk.Done 推测
+type TurnEnd struct {
+	Channel string    `json:"channel"`
+	ChatID  string    `json:"chat_id"`
+	Time    time.Time `json:"time"`
 }
 
 // InterruptRequest 表示中断请求（需要用户输入）
@@ -90,3 +123,23 @@ func NewStreamChunk(channel, chatID, delta, content string, done bool) *StreamCh
 		Done:    done,
 	}
 }
+
+// NewStatusChunk 创建一个进度状态片段，富客户端可将其与回复内容分开展示
+func NewStatusChunk(channel, chatID, status string) *StreamChunk {
+	return &StreamChunk{
+		Channel: channel,
+		ChatID:  chatID,
+		Type:    StreamChunkTypeStatus,
+		Content: status,
+	}
+}
+
+// NewThinkingChunk 创建一个思考过程片段，富客户端可将其与回复内容分开展示
+func NewThinkingChunk(channel, chatID, reasoning string) *StreamChunk {
+	return &StreamChunk{
+		Channel: channel,
+		ChatID:  chatID,
+		Type:    StreamChunkTypeThinking,
+		Content: reasoning,
+	}
+}
@@ -44,6 +44,15 @@ func TestInboundMessage_SessionKey(t *testing.T) {
 			},
 			expected: "matrix:room:server",
 		},
+		{
+			name: "Metadata指定会话键",
+			msg: &InboundMessage{
+				Channel:  "feishu",
+				ChatID:   "oc_123",
+				Metadata: map[string]any{SessionKeyMetadataKey: "cron:job1"},
+			},
+			expected: "cron:job1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -232,3 +241,22 @@ func TestInterruptResponse(t *testing.T) {
 		t.Errorf("Answer = %q, 期望 选项A", resp.Answer)
 	}
 }
+
+// TestNewStatusChunk 测试创建进度状态片段
+func TestNewStatusChunk(t *testing.T) {
+	chunk := NewStatusChunk("websocket", "chat123", "正在调用工具 web_search...")
+
+	if !chunk.IsStatus() {
+		t.Errorf("Type = %q, 期望 status", chunk.Type)
+	}
+	if chunk.Content != "正在调用工具 web_search..." {
+		t.Errorf("Content = %q", chunk.Content)
+	}
+	if chunk.Delta != "" || chunk.Done {
+		t.Error("状态片段不应携带增量内容或完成标记")
+	}
+
+	if NewStreamChunk("websocket", "chat123", "a", "a", false).IsStatus() {
+		t.Error("普通流式片段不应被识别为状态片段")
+	}
+}
@@ -2,8 +2,13 @@ package bus
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -13,6 +18,17 @@ type OutboundCallback func(msg *OutboundMessage) error
 // StreamCallback 是流式消息的回调函数类型
 type StreamCallback func(chunk *StreamChunk) error
 
+// TurnEndCallback 是回合结束事件的回调函数类型
+type TurnEndCallback func(evt *TurnEnd)
+
+// InboundInterceptor 在入站消息进入队列前处理消息，返回 true 表示已处理、不再入队
+// 用于需要立即生效、不能排在进行中回合之后的控制命令（如 /stop）
+type InboundInterceptor func(msg *InboundMessage) bool
+
+// turnEndMetadataKey 出站队列中标记回合结束事件的 Metadata 键
+// 回合结束事件与回复走同一个出站队列，保证在该回合的回复分发之后才送达渠道
+const turnEndMetadataKey = "_turn_end"
+
 // MessageBus 是解耦渠道和代理核心的异步消息总线
 type MessageBus struct {
 	inbound             chan *InboundMessage
@@ -20,29 +36,185 @@ type MessageBus struct {
 	stream              chan *StreamChunk
 	outboundSubscribers map[string][]OutboundCallback
 	streamSubscribers   map[string][]StreamCallback
+	turnEndSubscribers  map[string][]TurnEndCallback
+	chatOutbound        map[chatTopic][]chatSubscription[OutboundCallback]
+	chatStream          map[chatTopic][]chatSubscription[StreamCallback]
+	nextSubscriptionID  uint64
 	mu                  sync.RWMutex
-	running             bool
 	logger              *zap.Logger
+	audit               *AuditLog
+	retry               RetryPolicy
+	deadLetter          *DeadLetterLog
+	interceptor         InboundInterceptor
+
+	// 发送统计
+	sendFailures atomic.Int64
+	sendRetries  atomic.Int64
+	sendRecovers atomic.Int64
+	deadLetters  atomic.Int64
+
+	// 已发布但尚未分发完成的出站消息数
+	pendingOutbound atomic.Int64
+
+	// 每个聊天待分发的出站消息，存在即表示该聊天的分发 goroutine 正在运行
+	// 同一聊天按发布顺序分发，不同聊天互不阻塞（如某个聊天的回复节奏等待）
+	chatQueues map[chatTopic][]*OutboundMessage
+	queueMu    sync.Mutex
+
+	// 入站队列已满时的处理方式与丢弃计数
+	dropWhenFull   bool
+	droppedInbound atomic.Int64
+
+	// 分发器生命周期：lifecycle 串行化启动与停止，dispatchers 跟踪分发循环及后台重试的 goroutine
+	lifecycle      sync.Mutex
+	dispatchCtx    context.Context
+	cancelDispatch context.CancelFunc
+	dispatchers    sync.WaitGroup
+}
+
+// chatTopic 按渠道和聊天 ID 订阅的主题
+type chatTopic struct {
+	channel string
+	chatID  string
+}
+
+// chatSubscription 单个聊天的订阅，id 用于取消订阅
+type chatSubscription[T any] struct {
+	id       uint64
+	callback T
+}
+
+// 入站队列默认容量
+const defaultInboundCapacity = 100
+
+// busyReply 入站队列已满、消息被丢弃时回复给用户的提示
+const busyReply = "当前消息较多，系统繁忙，请稍后再试。"
+
+// Options 消息总线配置
+type Options struct {
+	InboundCapacity int  // 入站队列容量，小于等于 0 时使用默认值 100
+	DropWhenFull    bool // 队列已满时丢弃新消息并回复繁忙提示；为 false 时阻塞发布方直到有空位
 }
 
 // NewMessageBus 创建一个新的消息总线
 func NewMessageBus(logger *zap.Logger) *MessageBus {
+	return NewMessageBusWithOptions(logger, Options{})
+}
+
+// NewMessageBusWithOptions 按配置创建消息总线，入站队列有界以限制突发流量下的内存占用
+func NewMessageBusWithOptions(logger *zap.Logger, opts Options) *MessageBus {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
+	capacity := opts.InboundCapacity
+	if capacity <= 0 {
+		capacity = defaultInboundCapacity
+	}
 	return &MessageBus{
-		inbound:             make(chan *InboundMessage, 100),
+		dropWhenFull:        opts.DropWhenFull,
+		inbound:             make(chan *InboundMessage, capacity),
 		outbound:            make(chan *OutboundMessage, 100),
 		stream:              make(chan *StreamChunk, 1000), // 流式消息需要更大的缓冲
 		outboundSubscribers: make(map[string][]OutboundCallback),
 		streamSubscribers:   make(map[string][]StreamCallback),
+		turnEndSubscribers:  make(map[string][]TurnEndCallback),
+		chatOutbound:        make(map[chatTopic][]chatSubscription[OutboundCallback]),
+		chatStream:          make(map[chatTopic][]chatSubscription[StreamCallback]),
+		chatQueues:          make(map[chatTopic][]*OutboundMessage),
 		logger:              logger,
+		retry:               DefaultRetryPolicy(),
 	}
 }
 
+// SetRetryPolicy 设置出站消息发送失败后的重试策略
+func (b *MessageBus) SetRetryPolicy(policy RetryPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.retry = policy
+}
+
+// SetDeadLetterLog 设置死信日志，为 nil 时最终失败的消息只记录到日志
+func (b *MessageBus) SetDeadLetterLog(deadLetter *DeadLetterLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deadLetter = deadLetter
+}
+
+// SendStats 返回出站消息发送统计
+func (b *MessageBus) SendStats() SendStats {
+	return SendStats{
+		Failures:    b.sendFailures.Load(),
+		Retries:     b.sendRetries.Load(),
+		Recovered:   b.sendRecovers.Load(),
+		DeadLetters: b.deadLetters.Load(),
+	}
+}
+
+// SetAuditLog 设置审计日志，为 nil 时不记录
+func (b *MessageBus) SetAuditLog(audit *AuditLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.audit = audit
+}
+
+// SetInboundInterceptor 设置入站消息拦截器，为 nil 时所有消息直接入队
+func (b *MessageBus) SetInboundInterceptor(interceptor InboundInterceptor) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.interceptor = interceptor
+}
+
 // PublishInbound 从渠道向代理发布消息
 func (b *MessageBus) PublishInbound(msg *InboundMessage) {
-	b.inbound <- msg
+	if msg.TraceID == "" {
+		msg.TraceID = uuid.NewString()
+	}
+	b.logger.Debug("收到入站消息",
+		zap.String("channel", msg.Channel),
+		zap.String("chat_id", msg.ChatID),
+		zap.String("sender_id", msg.SenderID),
+		zap.String("trace_id", msg.TraceID),
+	)
+	if audit := b.auditLog(); audit != nil {
+		if err := audit.RecordInbound(msg); err != nil {
+			b.logger.Warn("写入审计日志失败", zap.Error(err))
+		}
+	}
+	b.mu.RLock()
+	interceptor := b.interceptor
+	b.mu.RUnlock()
+	if interceptor != nil && interceptor(msg) {
+		return
+	}
+	if !b.dropWhenFull {
+		b.inbound <- msg
+		return
+	}
+
+	select {
+	case b.inbound <- msg:
+	default:
+		b.droppedInbound.Add(1)
+		b.logger.Warn("入站消息队列已满，丢弃消息",
+			zap.String("channel", msg.Channel),
+			zap.String("chat_id", msg.ChatID),
+			zap.String("trace_id", msg.TraceID),
+			zap.Int("capacity", cap(b.inbound)),
+		)
+		b.replyBusy(msg)
+	}
+}
+
+// replyBusy 向被丢弃消息的发送方回复繁忙提示，出站队列也已满时放弃回复
+func (b *MessageBus) replyBusy(msg *InboundMessage) {
+	reply := NewOutboundMessage(msg.Channel, msg.ChatID, busyReply)
+	reply.TraceID = msg.TraceID
+	b.pendingOutbound.Add(1)
+	select {
+	case b.outbound <- reply:
+	default:
+		b.pendingOutbound.Add(-1)
+	}
 }
 
 // ConsumeInbound 消费下一条入站消息（阻塞直到可用）
@@ -57,19 +229,67 @@ func (b *MessageBus) ConsumeInbound(ctx context.Context) (*InboundMessage, error
 
 // PublishOutbound 从代理向渠道发布响应
 func (b *MessageBus) PublishOutbound(msg *OutboundMessage) {
+	b.pendingOutbound.Add(1)
 	b.outbound <- msg
 }
 
+// SendOutbound 同步发送出站消息，等待订阅渠道发送完成并返回发送结果
+// 不经过出站队列，也不重试，失败原因直接返回给调用方（如消息工具回报给 Agent）
+func (b *MessageBus) SendOutbound(msg *OutboundMessage) error {
+	b.mu.RLock()
+	subscribers := b.outboundSubscribersLocked(msg)
+	audit := b.audit
+	b.mu.RUnlock()
+
+	if len(subscribers) == 0 {
+		return fmt.Errorf("渠道 %s 不存在或未启动", msg.Channel)
+	}
+	if audit != nil {
+		if err := audit.RecordOutbound(msg); err != nil {
+			b.logger.Warn("写入审计日志失败", zap.Error(err))
+		}
+	}
+
+	var errs []error
+	for _, callback := range subscribers {
+		if err := callback(msg); err != nil {
+			b.sendFailures.Add(1)
+			b.logger.Error("同步发送消息到渠道失败",
+				zap.String("channel", msg.Channel),
+				zap.String("chat_id", msg.ChatID),
+				traceField(msg.TraceID),
+				zap.Error(err),
+			)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // ConsumeOutbound 消费下一条出站消息（阻塞直到可用）
 func (b *MessageBus) ConsumeOutbound(ctx context.Context) (*OutboundMessage, error) {
 	select {
 	case msg := <-b.outbound:
+		b.pendingOutbound.Add(-1)
 		return msg, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 }
 
+// WaitOutboundFlushed 等待已发布的出站消息全部分发完成，超时返回 false
+// 用于关闭前确保回复已交给渠道发送
+func (b *MessageBus) WaitOutboundFlushed(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for b.pendingOutbound.Load() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return true
+}
+
 // PublishStream 发布流式消息片段
 func (b *MessageBus) PublishStream(chunk *StreamChunk) {
 	select {
@@ -96,59 +316,325 @@ func (b *MessageBus) SubscribeStream(channel string, callback StreamCallback) {
 	b.streamSubscribers[channel] = append(b.streamSubscribers[channel], callback)
 }
 
+// SubscribeTurnEnd 订阅特定渠道的回合结束事件
+func (b *MessageBus) SubscribeTurnEnd(channel string, callback TurnEndCallback) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.turnEndSubscribers[channel] = append(b.turnEndSubscribers[channel], callback)
+}
+
+// PublishTurnEnd 发布回合结束事件
+// 事件排在该回合已发布的回复之后，渠道收到时回复已交给渠道发送
+func (b *MessageBus) PublishTurnEnd(channel, chatID string) {
+	b.PublishOutbound(&OutboundMessage{
+		Channel:  channel,
+		ChatID:   chatID,
+		Metadata: map[string]any{turnEndMetadataKey: time.Now()},
+	})
+}
+
+// SubscribeOutboundChat 只订阅发往指定渠道中某个聊天的出站消息，返回取消订阅的函数
+// 分发时直接按渠道和聊天 ID 查找订阅者，其他聊天的消息不会调用该回调
+func (b *MessageBus) SubscribeOutboundChat(channel, chatID string, callback OutboundCallback) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	topic := chatTopic{channel: channel, chatID: chatID}
+	b.nextSubscriptionID++
+	id := b.nextSubscriptionID
+	b.chatOutbound[topic] = append(b.chatOutbound[topic], chatSubscription[OutboundCallback]{id: id, callback: callback})
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.chatOutbound[topic] = removeSubscription(b.chatOutbound[topic], id)
+		if len(b.chatOutbound[topic]) == 0 {
+			delete(b.chatOutbound, topic)
+		}
+	}
+}
+
+// SubscribeStreamChat 只订阅发往指定渠道中某个聊天的流式消息，返回取消订阅的函数
+func (b *MessageBus) SubscribeStreamChat(channel, chatID string, callback StreamCallback) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	topic := chatTopic{channel: channel, chatID: chatID}
+	b.nextSubscriptionID++
+	id := b.nextSubscriptionID
+	b.chatStream[topic] = append(b.chatStream[topic], chatSubscription[StreamCallback]{id: id, callback: callback})
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.chatStream[topic] = removeSubscription(b.chatStream[topic], id)
+		if len(b.chatStream[topic]) == 0 {
+			delete(b.chatStream, topic)
+		}
+	}
+}
+
+// removeSubscription 移除指定 id 的订阅，返回新的切片，不修改正在分发中的旧切片
+func removeSubscription[T any](subs []chatSubscription[T], id uint64) []chatSubscription[T] {
+	result := make([]chatSubscription[T], 0, len(subs))
+	for _, sub := range subs {
+		if sub.id != id {
+			result = append(result, sub)
+		}
+	}
+	return result
+}
+
+// outboundSubscribersLocked 返回出站消息的订阅者：渠道级订阅者及该聊天的订阅者，调用方需持有读锁
+func (b *MessageBus) outboundSubscribersLocked(msg *OutboundMessage) []OutboundCallback {
+	chatSubs := b.chatOutbound[chatTopic{channel: msg.Channel, chatID: msg.ChatID}]
+	if len(chatSubs) == 0 {
+		return b.outboundSubscribers[msg.Channel]
+	}
+	subscribers := make([]OutboundCallback, 0, len(b.outboundSubscribers[msg.Channel])+len(chatSubs))
+	subscribers = append(subscribers, b.outboundSubscribers[msg.Channel]...)
+	for _, sub := range chatSubs {
+		subscribers = append(subscribers, sub.callback)
+	}
+	return subscribers
+}
+
+// streamSubscribersLocked 返回流式片段的订阅者：渠道级订阅者及该聊天的订阅者，调用方需持有读锁
+func (b *MessageBus) streamSubscribersLocked(chunk *StreamChunk) []StreamCallback {
+	chatSubs := b.chatStream[chatTopic{channel: chunk.Channel, chatID: chunk.ChatID}]
+	if len(chatSubs) == 0 {
+		return b.streamSubscribers[chunk.Channel]
+	}
+	subscribers := make([]StreamCallback, 0, len(b.streamSubscribers[chunk.Channel])+len(chatSubs))
+	subscribers = append(subscribers, b.streamSubscribers[chunk.Channel]...)
+	for _, sub := range chatSubs {
+		subscribers = append(subscribers, sub.callback)
+	}
+	return subscribers
+}
+
 // StartDispatcher 启动出站消息分发器
+// 已在运行时不做任何操作；Stop 之后或 ctx 取消之后可以再次启动
 func (b *MessageBus) StartDispatcher(ctx context.Context) {
-	b.running = true
-	go b.dispatchLoop(ctx)
-	go b.streamDispatchLoop(ctx)
+	b.lifecycle.Lock()
+	defer b.lifecycle.Unlock()
+	if b.dispatchCtx != nil && b.dispatchCtx.Err() == nil {
+		return
+	}
+	// 上一轮分发器可能因 ctx 取消而退出，等它完全结束后再启动新一轮
+	b.dispatchers.Wait()
+
+	b.dispatchCtx, b.cancelDispatch = context.WithCancel(ctx)
+	b.dispatchers.Add(2)
+	go b.dispatchLoop(b.dispatchCtx)
+	go b.streamDispatchLoop(b.dispatchCtx)
+}
+
+// Running 返回分发器是否在运行
+func (b *MessageBus) Running() bool {
+	b.lifecycle.Lock()
+	defer b.lifecycle.Unlock()
+	return b.dispatchCtx != nil && b.dispatchCtx.Err() == nil
 }
 
 // dispatchLoop 分发出站消息给订阅的渠道
+// 消息按渠道和聊天 ID 交给对应聊天的分发 goroutine，渠道发送较慢时不阻塞其他聊天
 func (b *MessageBus) dispatchLoop(ctx context.Context) {
-	for b.running {
+	defer b.dispatchers.Done()
+	for {
 		select {
 		case msg := <-b.outbound:
-			b.dispatchToSubscribers(msg)
+			b.enqueueChat(ctx, msg)
 		case <-ctx.Done():
-			b.running = false
 			return
 		}
 	}
 }
 
+// enqueueChat 把消息加入所属聊天的队列，该聊天没有正在运行的分发 goroutine 时启动一个
+func (b *MessageBus) enqueueChat(ctx context.Context, msg *OutboundMessage) {
+	topic := chatTopic{channel: msg.Channel, chatID: msg.ChatID}
+	b.queueMu.Lock()
+	queue, running := b.chatQueues[topic]
+	b.chatQueues[topic] = append(queue, msg)
+	b.queueMu.Unlock()
+	if running {
+		return
+	}
+
+	b.dispatchers.Add(1)
+	go b.drainChat(ctx, topic)
+}
+
+// drainChat 按顺序分发一个聊天队列中的消息，队列为空时退出
+// ctx 取消后仍会分发完已入队的消息，渠道发送时自行决定是否跳过等待
+func (b *MessageBus) drainChat(ctx context.Context, topic chatTopic) {
+	defer b.dispatchers.Done()
+	for {
+		b.queueMu.Lock()
+		queue := b.chatQueues[topic]
+		if len(queue) == 0 {
+			delete(b.chatQueues, topic)
+			b.queueMu.Unlock()
+			return
+		}
+		msg := queue[0]
+		b.chatQueues[topic] = queue[1:]
+		b.queueMu.Unlock()
+
+		b.dispatchToSubscribers(ctx, msg)
+		b.pendingOutbound.Add(-1)
+	}
+}
+
 // streamDispatchLoop 分发流式消息给订阅的渠道
 func (b *MessageBus) streamDispatchLoop(ctx context.Context) {
-	for b.running {
+	defer b.dispatchers.Done()
+	for {
 		select {
 		case chunk := <-b.stream:
 			b.dispatchStreamToSubscribers(chunk)
 		case <-ctx.Done():
-			b.running = false
 			return
 		}
 	}
 }
 
+// flushOutbound 把出站队列中剩余的消息同步分发给渠道，队列为空时返回
+func (b *MessageBus) flushOutbound(ctx context.Context) int {
+	flushed := 0
+	for {
+		select {
+		case msg := <-b.outbound:
+			b.dispatchToSubscribers(ctx, msg)
+			b.pendingOutbound.Add(-1)
+			flushed++
+		default:
+			return flushed
+		}
+	}
+}
+
 // dispatchToSubscribers 将消息分发给订阅者
-func (b *MessageBus) dispatchToSubscribers(msg *OutboundMessage) {
+// 发送失败的订阅者会在后台按重试策略退避重试，不阻塞后续消息的分发
+func (b *MessageBus) dispatchToSubscribers(ctx context.Context, msg *OutboundMessage) {
+	if at, ok := msg.Metadata[turnEndMetadataKey].(time.Time); ok {
+		b.dispatchTurnEnd(&TurnEnd{Channel: msg.Channel, ChatID: msg.ChatID, Time: at})
+		return
+	}
+
 	b.mu.RLock()
-	subscribers := b.outboundSubscribers[msg.Channel]
+	subscribers := b.outboundSubscribersLocked(msg)
+	audit := b.audit
+	policy := b.retry
 	b.mu.RUnlock()
 
+	if audit != nil {
+		if err := audit.RecordOutbound(msg); err != nil {
+			b.logger.Warn("写入审计日志失败", zap.Error(err))
+		}
+	}
+
 	for _, callback := range subscribers {
 		if err := callback(msg); err != nil {
+			b.sendFailures.Add(1)
 			b.logger.Error("分发消息到渠道失败",
 				zap.String("channel", msg.Channel),
+				zap.String("chat_id", msg.ChatID),
+				traceField(msg.TraceID),
 				zap.Error(err),
 			)
+			if policy.MaxAttempts > 1 {
+				b.dispatchers.Add(1)
+				go func() {
+					defer b.dispatchers.Done()
+					b.retrySend(ctx, policy, callback, msg, err)
+				}()
+			} else {
+				b.recordDeadLetter(msg, 1, err)
+			}
 		}
 	}
 }
 
+// retrySend 按退避策略重试发送，全部失败或 ctx 取消后写入死信日志
+func (b *MessageBus) retrySend(ctx context.Context, policy RetryPolicy, callback OutboundCallback, msg *OutboundMessage, lastErr error) {
+	attempts := 1
+	for attempts < policy.MaxAttempts {
+		timer := time.NewTimer(policy.backoff(attempts))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			b.recordDeadLetter(msg, attempts, lastErr)
+			return
+		case <-timer.C:
+		}
+
+		attempts++
+		b.sendRetries.Add(1)
+		if lastErr = callback(msg); lastErr == nil {
+			b.sendRecovers.Add(1)
+			b.logger.Info("出站消息重试发送成功",
+				zap.String("channel", msg.Channel),
+				zap.String("chat_id", msg.ChatID),
+				traceField(msg.TraceID),
+				zap.Int("attempts", attempts),
+			)
+			return
+		}
+		b.sendFailures.Add(1)
+		b.logger.Warn("出站消息重试发送失败",
+			zap.String("channel", msg.Channel),
+			zap.String("chat_id", msg.ChatID),
+			traceField(msg.TraceID),
+			zap.Int("attempts", attempts),
+			zap.Error(lastErr),
+		)
+	}
+	b.recordDeadLetter(msg, attempts, lastErr)
+}
+
+// recordDeadLetter 记录最终发送失败的消息
+func (b *MessageBus) recordDeadLetter(msg *OutboundMessage, attempts int, sendErr error) {
+	b.deadLetters.Add(1)
+	b.logger.Error("出站消息最终发送失败，已放弃",
+		zap.String("channel", msg.Channel),
+		zap.String("chat_id", msg.ChatID),
+		traceField(msg.TraceID),
+		zap.Int("attempts", attempts),
+		zap.Error(sendErr),
+	)
+
+	b.mu.RLock()
+	deadLetter := b.deadLetter
+	b.mu.RUnlock()
+	if deadLetter == nil {
+		return
+	}
+	if err := deadLetter.Record(msg, attempts, sendErr); err != nil {
+		b.logger.Warn("写入死信日志失败", zap.Error(err))
+	}
+}
+
+// traceField 返回链路追踪 ID 的日志字段，不属于任何回合的消息（如心跳）不输出
+func traceField(traceID string) zap.Field {
+	if traceID == "" {
+		return zap.Skip()
+	}
+	return zap.String("trace_id", traceID)
+}
+
+// dispatchTurnEnd 将回合结束事件分发给订阅者
+func (b *MessageBus) dispatchTurnEnd(evt *TurnEnd) {
+	b.mu.RLock()
+	subscribers := b.turnEndSubscribers[evt.Channel]
+	b.mu.RUnlock()
+
+	for _, callback := range subscribers {
+		callback(evt)
+	}
+}
+
 // dispatchStreamToSubscribers 将流式消息分发给订阅者
 func (b *MessageBus) dispatchStreamToSubscribers(chunk *StreamChunk) {
 	b.mu.RLock()
-	subscribers := b.streamSubscribers[chunk.Channel]
+	subscribers := b.streamSubscribersLocked(chunk)
 	b.mu.RUnlock()
 
 	for _, callback := range subscribers {
@@ -161,9 +647,30 @@ func (b *MessageBus) dispatchStreamToSubscribers(chunk *StreamChunk) {
 	}
 }
 
-// Stop 停止分发器循环
+// auditLog 返回当前审计日志
+func (b *MessageBus) auditLog() *AuditLog {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.audit
+}
+
+// Stop 停止分发器并等待分发循环及后台重试退出，随后把出站队列中剩余的消息同步发送给渠道
+// 停止期间仍在退避等待的重试直接写入死信日志；Stop 之后可以再次调用 StartDispatcher
 func (b *MessageBus) Stop() {
-	b.running = false
+	b.lifecycle.Lock()
+	defer b.lifecycle.Unlock()
+	if b.cancelDispatch == nil {
+		return
+	}
+	b.cancelDispatch()
+	b.dispatchers.Wait()
+
+	// ctx 已取消，刷出时发送失败的消息不再重试
+	if flushed := b.flushOutbound(b.dispatchCtx); flushed > 0 {
+		b.logger.Info("停止分发器前已发送剩余出站消息", zap.Int("count", flushed))
+	}
+	b.dispatchers.Wait()
+	b.dispatchCtx, b.cancelDispatch = nil, nil
 }
 
 // InboundSize 返回待处理的入站消息数量
@@ -171,6 +678,16 @@ func (b *MessageBus) InboundSize() int {
 	return len(b.inbound)
 }
 
+// InboundCapacity 返回入站队列容量
+func (b *MessageBus) InboundCapacity() int {
+	return cap(b.inbound)
+}
+
+// DroppedInbound 返回因队列已满而被丢弃的入站消息数量
+func (b *MessageBus) DroppedInbound() int64 {
+	return b.droppedInbound.Load()
+}
+
 // OutboundSize 返回待处理的出站消息数量
 func (b *MessageBus) OutboundSize() int {
 	return len(b.outbound)
@@ -2,7 +2,11 @@ package bus
 
 import (
 	"context"
+	"errors"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -53,6 +57,17 @@ func TestMessageBus_PublishConsumeInbound(t *testing.T) {
 	if consumed.Content != "hello" {
 		t.Errorf("Content = %q, 期望 hello", consumed.Content)
 	}
+
+	if consumed.TraceID == "" {
+		t.Error("发布入站消息时应生成 TraceID")
+	}
+
+	traced := NewInboundMessage("test", "user1", "chat1", "hi")
+	traced.TraceID = "trace-1"
+	bus.PublishInbound(traced)
+	if consumed, _ := bus.ConsumeInbound(ctx); consumed.TraceID != "trace-1" {
+		t.Errorf("TraceID = %q, 已有的 TraceID 不应被替换", consumed.TraceID)
+	}
 }
 
 // TestMessageBus_ConsumeInbound_ContextCancel 测试上下文取消时的消费
@@ -241,17 +256,57 @@ func TestMessageBus_Stop(t *testing.T) {
 	ctx := context.Background()
 	bus.StartDispatcher(ctx)
 
-	if !bus.running {
+	if !bus.Running() {
 		t.Error("消息总线应该正在运行")
 	}
 
 	bus.Stop()
 
-	if bus.running {
+	if bus.Running() {
 		t.Error("消息总线应该已停止")
 	}
 }
 
+// TestMessageBus_RestartDispatcher 测试反复启动、停止分发器不泄漏 goroutine，停止时刷出剩余出站消息
+func TestMessageBus_RestartDispatcher(t *testing.T) {
+	bus := NewMessageBus(nil)
+	var delivered atomic.Int64
+	bus.SubscribeOutbound("test", func(msg *OutboundMessage) error {
+		delivered.Add(1)
+		return nil
+	})
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		bus.StartDispatcher(context.Background())
+		bus.StartDispatcher(context.Background()) // 重复启动不应产生额外的分发循环
+		bus.PublishOutbound(NewOutboundMessage("test", "chat", "msg"))
+		bus.Stop()
+		bus.Stop()
+	}
+	if got := delivered.Load(); got != 20 {
+		t.Errorf("已发送 %d 条消息, 期望 20", got)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutine 数从 %d 增加到 %d", before, after)
+	}
+
+	t.Run("ctx 取消后可以重新启动", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		bus.StartDispatcher(ctx)
+		cancel()
+		bus.StartDispatcher(context.Background())
+		defer bus.Stop()
+		if !bus.Running() {
+			t.Error("重新启动后分发器应该在运行")
+		}
+		bus.PublishOutbound(NewOutboundMessage("test", "chat", "msg"))
+		if !bus.WaitOutboundFlushed(time.Second) {
+			t.Error("重新启动后出站消息未被分发")
+		}
+	})
+}
+
 // TestMessageBus_MultipleSubscribers 测试多个订阅者
 func TestMessageBus_MultipleSubscribers(t *testing.T) {
 	bus := NewMessageBus(nil)
@@ -322,3 +377,241 @@ func TestMessageBus_SubscriberError(t *testing.T) {
 		t.Error("第二个订阅者应该被调用")
 	}
 }
+
+// TestMessageBus_WaitOutboundFlushed 测试等待出站消息分发完成
+func TestMessageBus_WaitOutboundFlushed(t *testing.T) {
+	bus := NewMessageBus(zap.NewNop())
+	bus.PublishOutbound(NewOutboundMessage("test", "chat", "msg"))
+
+	if bus.WaitOutboundFlushed(30 * time.Millisecond) {
+		t.Error("分发器未启动时不应视为已发送")
+	}
+
+	var sent bool
+	var mu sync.Mutex
+	bus.SubscribeOutbound("test", func(msg *OutboundMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = true
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus.StartDispatcher(ctx)
+
+	if !bus.WaitOutboundFlushed(time.Second) {
+		t.Fatal("出站消息应在超时前分发完成")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !sent {
+		t.Error("订阅者应已收到消息")
+	}
+}
+
+// TestMessageBus_BoundedInbound 测试入站队列有界，洪泛时丢弃并回复繁忙
+func TestMessageBus_BoundedInbound(t *testing.T) {
+	t.Run("丢弃模式下超出容量的消息被丢弃", func(t *testing.T) {
+		bus := NewMessageBusWithOptions(zap.NewNop(), Options{InboundCapacity: 10, DropWhenFull: true})
+		if bus.InboundCapacity() != 10 {
+			t.Fatalf("InboundCapacity() = %d, 期望 10", bus.InboundCapacity())
+		}
+
+		for i := 0; i < 50; i++ {
+			bus.PublishInbound(&InboundMessage{Channel: "test", ChatID: "chat", Content: "flood"})
+		}
+
+		if bus.InboundSize() != 10 {
+			t.Errorf("InboundSize() = %d, 期望 10", bus.InboundSize())
+		}
+		if bus.DroppedInbound() != 40 {
+			t.Errorf("DroppedInbound() = %d, 期望 40", bus.DroppedInbound())
+		}
+		if bus.OutboundSize() != 40 {
+			t.Errorf("繁忙回复数量 = %d, 期望 40", bus.OutboundSize())
+		}
+		reply, err := bus.ConsumeOutbound(context.Background())
+		if err != nil || reply.ChatID != "chat" || reply.Content != busyReply {
+			t.Errorf("繁忙回复 = %+v, err = %v", reply, err)
+		}
+	})
+
+	t.Run("阻塞模式下发布方等待空位", func(t *testing.T) {
+		bus := NewMessageBusWithOptions(zap.NewNop(), Options{InboundCapacity: 1})
+		bus.PublishInbound(&InboundMessage{Channel: "test", ChatID: "chat"})
+
+		published := make(chan struct{})
+		go func() {
+			bus.PublishInbound(&InboundMessage{Channel: "test", ChatID: "chat"})
+			close(published)
+		}()
+
+		select {
+		case <-published:
+			t.Fatal("队列已满时发布应阻塞")
+		case <-time.After(30 * time.Millisecond):
+		}
+
+		if _, err := bus.ConsumeInbound(context.Background()); err != nil {
+			t.Fatalf("ConsumeInbound() 错误: %v", err)
+		}
+		select {
+		case <-published:
+		case <-time.After(time.Second):
+			t.Fatal("有空位后发布应继续")
+		}
+		if bus.DroppedInbound() != 0 {
+			t.Error("阻塞模式不应丢弃消息")
+		}
+	})
+}
+
+// TestMessageBus_SendOutbound 测试同步发送并返回渠道的发送结果
+func TestMessageBus_SendOutbound(t *testing.T) {
+	bus := NewMessageBus(nil)
+	var received []string
+	bus.SubscribeOutbound("test", func(msg *OutboundMessage) error {
+		received = append(received, msg.Content)
+		return nil
+	})
+	bus.SubscribeOutbound("broken", func(msg *OutboundMessage) error {
+		return errors.New("token 已过期")
+	})
+
+	if err := bus.SendOutbound(NewOutboundMessage("test", "chat1", "hello")); err != nil {
+		t.Errorf("SendOutbound() 返回错误: %v", err)
+	}
+	if len(received) != 1 || received[0] != "hello" {
+		t.Errorf("渠道收到 %v, 期望 [hello]", received)
+	}
+
+	if err := bus.SendOutbound(NewOutboundMessage("broken", "chat1", "hello")); err == nil || !strings.Contains(err.Error(), "token 已过期") {
+		t.Errorf("SendOutbound() = %v, 期望返回渠道的发送错误", err)
+	}
+	if bus.SendStats().Failures != 1 {
+		t.Errorf("发送失败次数 = %d, 期望 1", bus.SendStats().Failures)
+	}
+
+	if err := bus.SendOutbound(NewOutboundMessage("missing", "chat1", "hello")); err == nil {
+		t.Error("渠道未订阅时应返回错误")
+	}
+}
+
+// TestMessageBus_SubscribeChat 测试按聊天 ID 订阅只收到发往该聊天的消息
+func TestMessageBus_SubscribeChat(t *testing.T) {
+	bus := NewMessageBus(nil)
+	var channelWide, chat1 []string
+	bus.SubscribeOutbound("websocket", func(msg *OutboundMessage) error {
+		channelWide = append(channelWide, msg.ChatID)
+		return nil
+	})
+	unsubscribe := bus.SubscribeOutboundChat("websocket", "chat1", func(msg *OutboundMessage) error {
+		chat1 = append(chat1, msg.Content)
+		return nil
+	})
+
+	var streamed []string
+	unsubscribeStream := bus.SubscribeStreamChat("websocket", "chat1", func(chunk *StreamChunk) error {
+		streamed = append(streamed, chunk.Delta)
+		return nil
+	})
+
+	bus.SendOutbound(NewOutboundMessage("websocket", "chat1", "给 chat1"))
+	bus.SendOutbound(NewOutboundMessage("websocket", "chat2", "给 chat2"))
+	bus.dispatchStreamToSubscribers(NewStreamChunk("websocket", "chat1", "a", "a", false))
+	bus.dispatchStreamToSubscribers(NewStreamChunk("websocket", "chat2", "b", "b", false))
+
+	if strings.Join(chat1, ",") != "给 chat1" || strings.Join(streamed, ",") != "a" {
+		t.Errorf("聊天订阅收到 %v / %v, 期望只有发往 chat1 的消息", chat1, streamed)
+	}
+	if len(channelWide) != 2 {
+		t.Errorf("渠道级订阅应收到全部消息, 收到 %v", channelWide)
+	}
+
+	unsubscribe()
+	unsubscribeStream()
+	bus.SendOutbound(NewOutboundMessage("websocket", "chat1", "取消后"))
+	bus.dispatchStreamToSubscribers(NewStreamChunk("websocket", "chat1", "c", "c", false))
+	if len(chat1) != 1 || len(streamed) != 1 {
+		t.Errorf("取消订阅后不应再收到消息: %v / %v", chat1, streamed)
+	}
+	if len(bus.chatOutbound) != 0 || len(bus.chatStream) != 0 {
+		t.Error("取消订阅后应清理空的主题")
+	}
+}
+
+// TestMessageBus_TurnEnd 测试回合结束事件在该回合的回复之后送达，且不会作为消息发送
+func TestMessageBus_TurnEnd(t *testing.T) {
+	bus := NewMessageBus(nil)
+	var mu sync.Mutex
+	var events []string
+	bus.SubscribeOutbound("matrix", func(msg *OutboundMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, "message:"+msg.Content)
+		return nil
+	})
+	bus.SubscribeTurnEnd("matrix", func(evt *TurnEnd) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, "turn_end:"+evt.ChatID)
+	})
+
+	bus.PublishOutbound(NewOutboundMessage("matrix", "!room", "第一条"))
+	bus.PublishOutbound(NewOutboundMessage("matrix", "!room", "第二条"))
+	bus.PublishTurnEnd("matrix", "!room")
+	bus.PublishTurnEnd("websocket", "chat1") // 没有订阅者时忽略
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus.StartDispatcher(ctx)
+	if !bus.WaitOutboundFlushed(time.Second) {
+		t.Fatal("出站消息未分发完成")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(events, ",") != "message:第一条,message:第二条,turn_end:!room" {
+		t.Errorf("事件顺序 = %v", events)
+	}
+}
+
+// TestMessageBus_DispatchPerChat 测试出站消息按聊天分发，慢聊天不阻塞其他聊天
+func TestMessageBus_DispatchPerChat(t *testing.T) {
+	bus := NewMessageBus(nil)
+	release := make(chan struct{})
+	delivered := make(chan string, 3)
+	bus.SubscribeOutbound("matrix", func(msg *OutboundMessage) error {
+		if msg.ChatID == "!slow" {
+			<-release
+		}
+		delivered <- msg.ChatID + ":" + msg.Content
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus.StartDispatcher(ctx)
+
+	bus.PublishOutbound(NewOutboundMessage("matrix", "!slow", "第一条"))
+	bus.PublishOutbound(NewOutboundMessage("matrix", "!slow", "第二条"))
+	bus.PublishOutbound(NewOutboundMessage("matrix", "!fast", "你好"))
+	select {
+	case got := <-delivered:
+		if got != "!fast:你好" {
+			t.Errorf("首先送达 %q, 期望 !fast:你好", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("其他聊天的消息不应被阻塞")
+	}
+
+	close(release)
+	for _, want := range []string{"!slow:第一条", "!slow:第二条"} {
+		if got := <-delivered; got != want {
+			t.Errorf("送达 %q, 期望 %q（同一聊天按顺序分发）", got, want)
+		}
+	}
+	if !bus.WaitOutboundFlushed(time.Second) {
+		t.Error("出站消息未分发完成")
+	}
+}
@@ -3,6 +3,7 @@ package channels
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/weibaohui/nanobot-go/bus"
@@ -13,19 +14,24 @@ type Channel interface {
 	Name() string
 	Start(ctx context.Context) error
 	Stop()
+	Capabilities() Capabilities
 }
 
 // BaseChannel 渠道基类
 type BaseChannel struct {
-	name string
-	bus  *bus.MessageBus
+	name   string
+	bus    *bus.MessageBus
+	pacing PacingConfig
+	caps   Capabilities
 }
 
 // NewBaseChannel 创建渠道基类
+// 默认能力为不限长度、支持 Markdown、不支持附件，渠道可通过 SetCapabilities 声明自己的能力
 func NewBaseChannel(name string, messageBus *bus.MessageBus) *BaseChannel {
 	return &BaseChannel{
 		name: name,
 		bus:  messageBus,
+		caps: Capabilities{Formats: []string{FormatMarkdown, FormatPlain}},
 	}
 }
 
@@ -47,15 +53,85 @@ func (c *BaseChannel) PublishInbound(msg *bus.InboundMessage) {
 	c.bus.PublishInbound(msg)
 }
 
+// SetPacing 设置出站消息节奏，需在 Start 之前调用
+func (c *BaseChannel) SetPacing(pacing PacingConfig) {
+	c.pacing = pacing
+}
+
+// newBaseChannelWithCapabilities 创建声明了自身能力的渠道基类
+func newBaseChannelWithCapabilities(name string, messageBus *bus.MessageBus, caps Capabilities) *BaseChannel {
+	c := NewBaseChannel(name, messageBus)
+	c.caps = caps
+	return c
+}
+
+// SetCapabilities 设置渠道能力，需在 Start 之前调用
+func (c *BaseChannel) SetCapabilities(caps Capabilities) {
+	c.caps = caps
+}
+
+// Capabilities 返回渠道能力
+func (c *BaseChannel) Capabilities() Capabilities {
+	return c.caps
+}
+
+// pacedPartsMetadataKey 出站消息 Metadata 中记录已等待过回复节奏的分段数，重试时不再重复等待
+const pacedPartsMetadataKey = "_paced_parts"
+
+// sentPartsMetadataKey 出站消息 Metadata 中记录已发送成功的分段数，重试时从失败的分段继续发送
+const sentPartsMetadataKey = "_sent_parts"
+
 // SubscribeOutbound 订阅出站消息
 // 使用 MessageBus 的订阅机制，确保所有渠道都能收到消息
-func (c *BaseChannel) SubscribeOutbound(ctx context.Context, handler func(msg *bus.OutboundMessage)) {
+// 发送前按渠道能力转换格式并切分超长消息，handler 每次只收到一条合规消息
+// 配置了回复节奏时，发送前先按节奏等待；等待期间 ctx 取消则立即发送，避免丢失回复
+// MessageBus 按聊天分发出站消息，等待只影响本聊天；重试时已等待过的分段不再等待
+// handler 返回的错误交由 MessageBus 处理（退避重试，最终写入死信日志）；重试时已发送的分段不再重复发送
+func (c *BaseChannel) SubscribeOutbound(ctx context.Context, handler func(msg *bus.OutboundMessage) error) {
 	c.bus.SubscribeOutbound(c.name, func(msg *bus.OutboundMessage) error {
-		handler(msg)
+		paced, _ := msg.Metadata[pacedPartsMetadataKey].(int)
+		sent, _ := msg.Metadata[sentPartsMetadataKey].(int)
+		for i, part := range c.caps.Prepare(msg) {
+			if i < sent {
+				continue
+			}
+			if delay := c.pacing.DelayFor(part.Content); i >= paced && delay > 0 {
+				waitPacing(ctx, delay)
+				setMetadata(msg, pacedPartsMetadataKey, i+1)
+			}
+			if err := handler(part); err != nil {
+				return err
+			}
+			setMetadata(msg, sentPartsMetadataKey, i+1)
+		}
 		return nil
 	})
 }
 
+// setMetadata 设置出站消息的 Metadata 字段
+func setMetadata(msg *bus.OutboundMessage, key string, value any) {
+	if msg.Metadata == nil {
+		msg.Metadata = make(map[string]any)
+	}
+	msg.Metadata[key] = value
+}
+
+// IsAllowed 检查发送者是否在白名单中
+// 白名单为空表示允许所有人；"*" 匹配任意发送者；比较时忽略大小写和首尾空白
+func IsAllowed(sender string, allow []string) bool {
+	if len(allow) == 0 {
+		return true
+	}
+	sender = strings.TrimSpace(sender)
+	for _, entry := range allow {
+		entry = strings.TrimSpace(entry)
+		if entry == "*" || (entry != "" && strings.EqualFold(entry, sender)) {
+			return true
+		}
+	}
+	return false
+}
+
 // Manager 渠道管理器
 type Manager struct {
 	channels map[string]Channel
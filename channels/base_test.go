@@ -199,6 +199,10 @@ func (m *mockChannel) Stop() {
 	m.stopped = true
 }
 
+func (m *mockChannel) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
 // TestManager_StartAll_WithError 测试启动时遇到错误
 func TestManager_StartAll_WithError(t *testing.T) {
 	messageBus := bus.NewMessageBus(zap.NewNop())
@@ -245,3 +249,27 @@ func TestManager_MultipleOperations(t *testing.T) {
 	// 停止所有
 	manager.StopAll()
 }
+
+// TestIsAllowed 测试白名单检查
+func TestIsAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		sender string
+		allow  []string
+		want   bool
+	}{
+		{"空白名单允许所有", "user1", nil, true},
+		{"精确匹配", "user1", []string{"user1"}, true},
+		{"忽略大小写", "@Alice:matrix.org", []string{"@alice:Matrix.org"}, true},
+		{"通配符", "anyone", []string{"admin", "*"}, true},
+		{"不在白名单", "user2", []string{"user1"}, false},
+		{"空发送者不匹配空条目", "", []string{""}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAllowed(tt.sender, tt.allow); got != tt.want {
+				t.Errorf("IsAllowed(%q, %v) = %v, 期望 %v", tt.sender, tt.allow, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package channels
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/weibaohui/nanobot-go/bus"
+)
+
+// 消息格式
+const (
+	FormatPlain    = "plain"    // 纯文本
+	FormatMarkdown = "markdown" // Markdown
+	FormatHTML     = "html"     // HTML
+)
+
+// Capabilities 渠道能力描述
+// 出站消息发送前按渠道能力转换格式、处理附件并切分超长消息，渠道的 Send 只需处理单条合规消息
+type Capabilities struct {
+	MaxMessageLength int      // 单条消息最大长度（字符），0 表示不限制
+	Formats          []string // 支持的消息格式，不含 markdown 时发送前去掉 Markdown 标记
+	Media            bool     // 是否支持发送媒体附件，不支持时附件以路径列表追加到正文
+}
+
+// Supports 判断渠道是否支持指定消息格式
+func (c Capabilities) Supports(format string) bool {
+	return slices.Contains(c.Formats, format)
+}
+
+// Prepare 按渠道能力处理出站消息，返回实际发送的一条或多条消息
+// 附件只随第一条消息发送
+func (c Capabilities) Prepare(msg *bus.OutboundMessage) []*bus.OutboundMessage {
+	content := msg.Content
+	media := msg.Media
+	if !c.Media && len(media) > 0 {
+		content = strings.TrimRight(content, "\n") + "\n\n附件:\n" + strings.Join(media, "\n")
+		media = nil
+	}
+	if !c.Supports(FormatMarkdown) && !c.Supports(FormatHTML) {
+		content = StripMarkdown(content)
+	}
+
+	parts := SplitMessage(content, c.MaxMessageLength)
+	if len(parts) == 1 && content == msg.Content && len(media) == len(msg.Media) {
+		return []*bus.OutboundMessage{msg}
+	}
+
+	prepared := make([]*bus.OutboundMessage, 0, len(parts))
+	for i, part := range parts {
+		out := *msg
+		out.Content = part
+		out.Media = nil
+		if i == 0 {
+			out.Media = media
+		}
+		prepared = append(prepared, &out)
+	}
+	return prepared
+}
+
+// SplitMessage 按最大长度（字符）切分消息，0 表示不切分
+// 优先在段落、换行处切分，其次在空白处，都没有时按长度硬切
+func SplitMessage(content string, maxLen int) []string {
+	if maxLen <= 0 || utf8.RuneCountInString(content) <= maxLen {
+		return []string{content}
+	}
+
+	var parts []string
+	runes := []rune(content)
+	for len(runes) > maxLen {
+		window := string(runes[:maxLen])
+		cut := -1
+		for _, sep := range []string{"\n\n", "\n", " "} {
+			if idx := strings.LastIndex(window, sep); idx > 0 {
+				cut = utf8.RuneCountInString(window[:idx])
+				break
+			}
+		}
+		if cut <= 0 {
+			cut = maxLen
+		}
+		parts = append(parts, strings.TrimRight(string(runes[:cut]), " \n"))
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), " \n"))
+	}
+	if len(runes) > 0 {
+		parts = append(parts, string(runes))
+	}
+	return parts
+}
+
+var (
+	mdHeadingRegex = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	mdFenceRegex   = regexp.MustCompile("(?m)^```.*\n?")
+	mdLinkRegex    = regexp.MustCompile(`!?\[([^\]]*)\]\(([^)\s]+)\)`)
+	mdEmphasis     = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// StripMarkdown 去掉常见的 Markdown 标记，用于只支持纯文本的渠道
+// 链接转换为 "文字 (地址)"，代码块保留内容
+func StripMarkdown(content string) string {
+	content = mdFenceRegex.ReplaceAllString(content, "")
+	content = mdHeadingRegex.ReplaceAllString(content, "")
+	content = mdLinkRegex.ReplaceAllString(content, "$1 ($2)")
+	return mdEmphasis.Replace(content)
+}
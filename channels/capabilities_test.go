@@ -0,0 +1,136 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// TestSplitMessage 测试按长度切分消息
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		maxLen  int
+		want    []string
+	}{
+		{"不限制长度", "第一段\n\n第二段", 0, []string{"第一段\n\n第二段"}},
+		{"未超过长度", "你好", 5, []string{"你好"}},
+		{"优先在段落处切分", "第一段内容\n\n第二段", 8, []string{"第一段内容", "第二段"}},
+		{"没有分隔符时硬切", "一二三四五六七", 3, []string{"一二三", "四五六", "七"}},
+		{"在空白处切分", "hello world foo", 12, []string{"hello world", "foo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitMessage(tt.content, tt.maxLen)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("SplitMessage() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStripMarkdown 测试去掉 Markdown 标记
+func TestStripMarkdown(t *testing.T) {
+	input := "## 标题\n**重点** 见 [文档](https://example.com)\n```go\nfmt.Println(`hi`)\n```\n"
+	want := "标题\n重点 见 文档 (https://example.com)\nfmt.Println(hi)\n"
+	if got := StripMarkdown(input); got != want {
+		t.Errorf("StripMarkdown() = %q, 期望 %q", got, want)
+	}
+}
+
+// TestCapabilities_Prepare 测试按渠道能力处理出站消息
+func TestCapabilities_Prepare(t *testing.T) {
+	t.Run("符合能力时原样返回", func(t *testing.T) {
+		msg := bus.NewOutboundMessage("feishu", "oc_1", "**你好**")
+		got := feishuCapabilities.Prepare(msg)
+		if len(got) != 1 || got[0] != msg {
+			t.Errorf("Prepare() = %+v", got)
+		}
+	})
+
+	t.Run("纯文本渠道去掉标记并追加附件", func(t *testing.T) {
+		msg := bus.NewOutboundMessage("dingtalk", "u1", "**报告**已生成")
+		msg.Media = []string{"/tmp/report.pdf"}
+		got := dingTalkCapabilities.Prepare(msg)
+		if len(got) != 1 || got[0].Content != "报告已生成\n\n附件:\n/tmp/report.pdf" || len(got[0].Media) != 0 {
+			t.Errorf("Prepare() = %+v", got[0])
+		}
+		if msg.Content != "**报告**已生成" {
+			t.Error("Prepare 不应修改原消息")
+		}
+	})
+
+	t.Run("超长消息切分并保留元数据", func(t *testing.T) {
+		caps := Capabilities{MaxMessageLength: 4, Formats: []string{FormatMarkdown}, Media: true}
+		msg := bus.NewOutboundMessage("matrix", "!r", "第一段\n第二段")
+		msg.Media = []string{"a.png"}
+		msg.Metadata["reply_to"] = "e1"
+		got := caps.Prepare(msg)
+		if len(got) != 2 || got[0].Content != "第一段" || got[1].Content != "第二段" {
+			t.Fatalf("Prepare() = %+v", got)
+		}
+		if len(got[0].Media) != 1 || len(got[1].Media) != 0 || got[1].Metadata["reply_to"] != "e1" {
+			t.Errorf("附件只随第一条发送且保留元数据: %+v, %+v", got[0], got[1])
+		}
+	})
+}
+
+// TestBaseChannel_SubscribeOutbound_Capabilities 测试出站消息按渠道能力切分后发送
+func TestBaseChannel_SubscribeOutbound_Capabilities(t *testing.T) {
+	messageBus := bus.NewMessageBus(zap.NewNop())
+	channel := newBaseChannelWithCapabilities("test", messageBus, Capabilities{MaxMessageLength: 3, Formats: []string{FormatPlain}})
+
+	var sent []string
+	channel.SubscribeOutbound(context.Background(), func(msg *bus.OutboundMessage) error {
+		sent = append(sent, msg.Content)
+		return nil
+	})
+	if err := messageBus.SendOutbound(bus.NewOutboundMessage("test", "c1", "**一二三四**")); err != nil {
+		t.Fatalf("SendOutbound() 返回错误: %v", err)
+	}
+	if strings.Join(sent, "|") != "一二三|四" {
+		t.Errorf("发送记录 = %q", sent)
+	}
+}
+
+// TestBaseChannel_SubscribeOutbound_ResumeParts 测试分段发送失败重试时从失败的分段继续
+func TestBaseChannel_SubscribeOutbound_ResumeParts(t *testing.T) {
+	messageBus := bus.NewMessageBus(zap.NewNop())
+	messageBus.SetRetryPolicy(bus.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	channel := newBaseChannelWithCapabilities("test", messageBus, Capabilities{MaxMessageLength: 1, Formats: []string{FormatPlain}})
+
+	var sent []string
+	failed := false
+	done := make(chan struct{})
+	channel.SubscribeOutbound(context.Background(), func(msg *bus.OutboundMessage) error {
+		if msg.Content == "二" && !failed {
+			failed = true
+			return errors.New("发送失败")
+		}
+		sent = append(sent, msg.Content)
+		if msg.Content == "三" {
+			close(done)
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messageBus.StartDispatcher(ctx)
+
+	messageBus.PublishOutbound(bus.NewOutboundMessage("test", "chat", "一二三"))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("重试后应发送完所有分段")
+	}
+	if strings.Join(sent, "|") != "一|二|三" {
+		t.Errorf("发送记录 = %q, 期望 一|二|三", sent)
+	}
+}
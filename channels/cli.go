@@ -37,9 +37,10 @@ func (c *CLIChannel) Start(ctx context.Context) error {
 	c.logger.Info("CLI 渠道已启动")
 
 	// 订阅出站消息
-	c.SubscribeOutbound(ctx, func(msg *bus.OutboundMessage) {
+	c.SubscribeOutbound(ctx, func(msg *bus.OutboundMessage) error {
 		fmt.Println("\n" + msg.Content)
 		fmt.Print("\n> ")
+		return nil
 	})
 
 	// 启动输入循环
@@ -103,11 +104,14 @@ func (c *CLIChannel) handleCommand(cmd string) {
   /exit    退出程序
   /clear   清空会话
   /status  显示状态`)
+		// 转发给 Agent，由其补充通用命令、可用工具和技能列表
+		c.PublishInbound(bus.NewInboundMessage("cli", "user", c.chatID, cmd))
 	case "/clear":
 		fmt.Println("会话已清空")
 	case "/status":
 		fmt.Println("状态: 运行中")
 	default:
-		fmt.Printf("未知命令: %s\n", cmd)
+		// 非本地命令交给 Agent 处理（如 /task cancel）
+		c.PublishInbound(bus.NewInboundMessage("cli", "user", c.chatID, cmd))
 	}
 }
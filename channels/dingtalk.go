@@ -87,13 +87,19 @@ type DingTalkConfig struct {
 	AllowFrom    []string `json:"allow_from"`
 }
 
+// dingTalkCapabilities 钉钉渠道能力：以 text 类型发送，正文上限约 20000 字节
+var dingTalkCapabilities = Capabilities{
+	MaxMessageLength: 6000,
+	Formats:          []string{FormatPlain},
+}
+
 // NewDingTalkChannel 创建钉钉渠道
 func NewDingTalkChannel(config *DingTalkConfig, messageBus *bus.MessageBus, logger *zap.Logger) *DingTalkChannel {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 	return &DingTalkChannel{
-		BaseChannel:  NewBaseChannel("dingtalk", messageBus),
+		BaseChannel:  newBaseChannelWithCapabilities("dingtalk", messageBus, dingTalkCapabilities),
 		config:       config,
 		logger:       logger,
 		sessionCache: make(map[string]*sessionContext),
@@ -129,11 +135,7 @@ func (c *DingTalkChannel) Start(ctx context.Context) error {
 	c.streamClient.RegisterChatBotCallbackRouter(c.onChatBotMessage)
 
 	// 订阅出站消息
-	c.SubscribeOutbound(ctx, func(msg *bus.OutboundMessage) {
-		if err := c.Send(msg); err != nil {
-			c.logger.Error("发送钉钉消息失败", zap.Error(err))
-		}
-	})
+	c.SubscribeOutbound(ctx, c.Send)
 
 	// 启动 Stream 客户端
 	c.bgTasks.Add(1)
@@ -180,6 +182,12 @@ func (c *DingTalkChannel) onChatBotMessage(ctx context.Context, data *chatbot.Bo
 	if senderID == "" {
 		senderID = data.SenderId
 	}
+	// 检查用户白名单
+	if !IsAllowed(senderID, c.config.AllowFrom) {
+		c.logger.Debug("钉钉消息发送者不在白名单中", zap.String("sender", senderID))
+		return []byte(""), nil
+	}
+
 	senderName := data.SenderNick
 	if senderName == "" {
 		senderName = "Unknown"
@@ -105,13 +105,19 @@ type FeishuConfig struct {
 	AllowFrom         []string `json:"allow_from"`
 }
 
+// feishuCapabilities 飞书渠道能力：以卡片发送，支持 Markdown 和表格，卡片内容上限约 30KB
+var feishuCapabilities = Capabilities{
+	MaxMessageLength: 8000,
+	Formats:          []string{FormatMarkdown},
+}
+
 // NewFeishuChannel 创建飞书渠道
 func NewFeishuChannel(config *FeishuConfig, messageBus *bus.MessageBus, logger *zap.Logger) *FeishuChannel {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 	return &FeishuChannel{
-		BaseChannel:     NewBaseChannel("feishu", messageBus),
+		BaseChannel:     newBaseChannelWithCapabilities("feishu", messageBus, feishuCapabilities),
 		config:          config,
 		logger:          logger,
 		processedMsgIDs: newSyncMap(1000),
@@ -145,11 +151,7 @@ func (c *FeishuChannel) Start(ctx context.Context) error {
 	)
 
 	// 订阅出站消息
-	c.SubscribeOutbound(ctx, func(msg *bus.OutboundMessage) {
-		if err := c.Send(msg); err != nil {
-			c.logger.Error("发送飞书消息失败", zap.Error(err))
-		}
-	})
+	c.SubscribeOutbound(ctx, c.Send)
 
 	c.logger.Info("飞书渠道已启动",
 		zap.String("app_id", c.config.AppID),
@@ -256,18 +258,9 @@ func (c *FeishuChannel) onMessageReceive(ctx context.Context, event *larkim.P2Me
 	go c.addReactionAndSave(messageID, "OnIt")
 
 	// 检查用户白名单
-	if len(c.config.AllowFrom) > 0 {
-		allowed := false
-		for _, u := range c.config.AllowFrom {
-			if senderID == u {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			c.logger.Debug("飞书消息发送者不在白名单中", zap.String("sender", senderID))
-			return nil
-		}
+	if !IsAllowed(senderID, c.config.AllowFrom) {
+		c.logger.Debug("飞书消息发送者不在白名单中", zap.String("sender", senderID))
+		return nil
 	}
 
 	c.logger.Info("收到飞书消息",
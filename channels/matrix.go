@@ -5,12 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/utils"
 	"github.com/yuin/goldmark"
 	"go.uber.org/zap"
 	"maunium.net/go/mautrix"
@@ -43,10 +46,55 @@ type MatrixChannel struct {
 	// 忽略自己发送的消息
 	botUserID id.UserID
 
-	typingMu     sync.Mutex
-	typingCancel map[id.RoomID]context.CancelFunc
+	// 按成员数判断的私聊房间缓存，m.direct 中没有记录的房间使用
+	memberDirect sync.Map // id.RoomID -> bool
+
+	// 启动时间，早于它的消息是补处理的停机期间消息
+	startedAt time.Time
+	// 是否还未完成启动后的第一次同步
+	catchUpPending bool
+
+	// 正在显示 typing 状态的房间，由一个协程统一发送和续期，保证同一房间的状态按顺序发送
+	typingMu    sync.Mutex
+	typingRooms map[id.RoomID]*typingState
+	typingWake  chan struct{}
+
+	// 同步重连状态
+	syncMu       sync.Mutex
+	syncAttempt  int       // 连续失败次数，同步成功后清零
+	syncFailedAt time.Time // 本次中断开始的时间
+	syncStats    MatrixSyncStats
+}
+
+// MatrixSyncStats Matrix 同步重连统计
+type MatrixSyncStats struct {
+	Reconnects int64         `json:"reconnects"` // 同步出错后的重连次数
+	Recoveries int64         `json:"recoveries"` // 重连后恢复同步的次数
+	Downtime   time.Duration `json:"downtime"`   // 累计中断时长（从出错到重新同步成功）
+	LastError  string        `json:"last_error"` // 最近一次同步错误
 }
 
+// typingState 房间 typing 状态
+type typingState struct {
+	since   time.Time // 开始显示的时间
+	active  bool      // 是否应显示 typing
+	pending bool      // 状态变化还未发送
+}
+
+// typing 状态参数
+const (
+	typingTimeout         = 30 * time.Second // 单次 typing 状态的有效期
+	typingRefreshInterval = 20 * time.Second // 续期间隔，需小于有效期
+	typingMaxDuration     = 10 * time.Minute // 最长显示时间，防止回合结束事件丢失时一直显示
+	defaultMaxTypingRooms = 50
+)
+
+// 同步重连退避参数
+const (
+	syncInitialBackoff = 3 * time.Second
+	syncMaxBackoff     = 5 * time.Minute
+)
+
 // MatrixConfig Matrix 配置
 type MatrixConfig struct {
 	Homeserver string   `json:"homeserver"` // Matrix 服务器地址，如 https://matrix.example.com
@@ -54,6 +102,19 @@ type MatrixConfig struct {
 	Token      string   `json:"token"`      // 访问令牌
 	AllowFrom  []string `json:"allowFrom"`  // 允许的用户白名单
 	DataDir    string   `json:"dataDir"`    // 数据存储目录，用于持久化同步状态
+
+	// CatchUpLimit 重启后每个房间补处理停机期间消息的最大条数，0 表示忽略停机期间的消息
+	// 依赖持久化的 next_batch，首次启动（没有同步状态）时仍忽略历史消息
+	CatchUpLimit int `json:"catchUpLimit"`
+
+	// MaxTypingRooms 同时显示 typing 状态的最大房间数，超出时新消息不显示 typing，0 使用默认值 50
+	MaxTypingRooms int `json:"maxTypingRooms"`
+}
+
+// matrixCapabilities Matrix 渠道能力：Markdown 转换为 HTML 发送，事件大小上限 64KB（含 HTML 和纯文本两份正文）
+var matrixCapabilities = Capabilities{
+	MaxMessageLength: 16000,
+	Formats:          []string{FormatHTML, FormatMarkdown, FormatPlain},
 }
 
 // NewMatrixChannel 创建 Matrix 渠道
@@ -62,10 +123,11 @@ func NewMatrixChannel(config *MatrixConfig, messageBus *bus.MessageBus, logger *
 		logger = zap.NewNop()
 	}
 	return &MatrixChannel{
-		BaseChannel:  NewBaseChannel("matrix", messageBus),
-		config:       config,
-		logger:       logger,
-		typingCancel: make(map[id.RoomID]context.CancelFunc),
+		BaseChannel: newBaseChannelWithCapabilities("matrix", messageBus, matrixCapabilities),
+		config:      config,
+		logger:      logger,
+		typingRooms: make(map[id.RoomID]*typingState),
+		typingWake:  make(chan struct{}, 1),
 	}
 }
 
@@ -103,19 +165,29 @@ func (c *MatrixChannel) Start(ctx context.Context) error {
 		return fmt.Errorf("创建 Matrix 存储失败: %w", err)
 	}
 	c.client.Store = c.store
+	// 过滤器按 ID 缓存在同步状态中，补处理条数变化时需要重新创建过滤器
+	filterKey := ""
+	if c.config.CatchUpLimit > 0 {
+		filterKey = fmt.Sprintf("timeline:%d", c.config.CatchUpLimit)
+	}
+	if err := c.store.ResetFilterIfChanged(filterKey); err != nil {
+		c.logger.Warn("保存 Matrix 同步状态失败", zap.Error(err))
+	}
+	c.startedAt = time.Now()
+	c.catchUpPending = c.config.CatchUpLimit > 0
 
 	// 创建同步器
 	c.syncer = mautrix.NewDefaultSyncer()
 	c.syncer.ParseEventContent = true
-	c.client.Syncer = c.syncer
+	c.client.Syncer = &matrixSyncer{DefaultSyncer: c.syncer, retryDelay: c.syncRetryDelay}
 
 	// 设置过滤器，只获取新消息，不同步历史状态和成员
 	// 这样可以大幅减少启动时的数据量
 	c.syncer.FilterJSON = &mautrix.Filter{
 		Room: &mautrix.RoomFilter{
-			// 时间线过滤器：只获取新消息，不获取历史
+			// 时间线过滤器：默认只获取新消息，开启补处理时每个房间最多返回 CatchUpLimit 条
 			Timeline: &mautrix.FilterPart{
-				Limit: 0, // 限制历史消息数量
+				Limit: c.config.CatchUpLimit, // 限制历史消息数量
 				Types: []event.Type{
 					event.EventMessage,
 					event.EventEncrypted,
@@ -135,9 +207,9 @@ func (c *MatrixChannel) Start(ctx context.Context) error {
 				Limit: 0, // 不获取
 			},
 		},
-		// 全局账户数据
+		// 全局账户数据：只获取 m.direct，用于区分私聊和群聊
 		AccountData: &mautrix.FilterPart{
-			Limit: 0, // 不获取
+			Types: []event.Type{event.AccountDataDirectChats},
 		},
 		// 在线状态
 		Presence: &mautrix.FilterPart{
@@ -145,7 +217,13 @@ func (c *MatrixChannel) Start(ctx context.Context) error {
 		},
 	}
 
+	// 同步成功时重置重连退避、限制补处理条数；需在可能中止处理的 DontProcessOldEvents 之前注册
+	c.syncer.OnSync(c.onSyncSuccess)
+	c.syncer.OnSync(c.limitCatchUp)
+	c.syncer.OnSync(c.trackDirectRooms)
+
 	// 注册忽略旧消息的处理器（机器人只处理启动后收到的消息）
+	// 首次启动或新加入的房间不处理历史消息；开启补处理时，从上次的 next_batch 继续同步的消息会被处理
 	c.syncer.OnSync(c.client.DontProcessOldEvents)
 
 	// 注册消息事件处理器
@@ -153,18 +231,14 @@ func (c *MatrixChannel) Start(ctx context.Context) error {
 	c.syncer.OnEventType(event.EventEncrypted, c.onEncryptedMessage)
 
 	// 订阅出站消息
-	c.SubscribeOutbound(ctx, func(msg *bus.OutboundMessage) {
-		if err := c.Send(msg); err != nil {
-			c.logger.Error("发送 Matrix 消息失败", zap.Error(err))
-		}
-	})
+	c.SubscribeOutbound(ctx, c.Send)
 
 	// 订阅心跳消息
-	c.bus.SubscribeOutbound("heartbeat", func(msg *bus.OutboundMessage) error {
-		if err := c.Send(msg); err != nil {
-			c.logger.Error("发送 Matrix 心跳消息失败", zap.Error(err))
-		}
-		return nil
+	c.bus.SubscribeOutbound("heartbeat", c.Send)
+
+	// 回合结束后停止 typing 状态；一个回合可能发送多条消息，不在 Send 中停止
+	c.bus.SubscribeTurnEnd("matrix", func(evt *bus.TurnEnd) {
+		c.stopTypingIndicator(id.RoomID(evt.ChatID))
 	})
 
 	c.logger.Info("Matrix 渠道已启动",
@@ -177,6 +251,10 @@ func (c *MatrixChannel) Start(ctx context.Context) error {
 	c.bgTasks.Add(1)
 	go c.runSync()
 
+	// 启动 typing 状态发送
+	c.bgTasks.Add(1)
+	go c.runTyping()
+
 	return nil
 }
 
@@ -196,92 +274,317 @@ func (c *MatrixChannel) runSync() {
 
 	for c.running {
 		err := c.client.SyncWithContext(c.ctx)
-		if err != nil {
-			if c.ctx.Err() != nil {
-				// 上下文被取消，正常退出
-				return
-			}
-			c.logger.Warn("Matrix 同步错误", zap.Error(err))
+		if c.ctx.Err() != nil {
+			// 上下文被取消，正常退出
+			return
 		}
-
 		if !c.running {
 			break
 		}
 
-		// 等待 3 秒后重连
 		select {
-		case <-time.After(3 * time.Second):
+		case <-time.After(c.syncRetryDelay(err)):
 		case <-c.ctx.Done():
 			return
 		}
 	}
 }
 
-// startTypingIndicator 启动房间 typing 状态刷新
+// matrixSyncer 同步请求失败时按渠道的指数退避等待，替代 DefaultSyncer 固定 10 秒的重试间隔
+type matrixSyncer struct {
+	*mautrix.DefaultSyncer
+	retryDelay func(err error) time.Duration
+}
+
+// OnFailedSync 实现 mautrix.Syncer 接口，返回重试前的等待时间，从不终止同步
+func (s *matrixSyncer) OnFailedSync(res *mautrix.RespSync, err error) (time.Duration, error) {
+	return s.retryDelay(err), nil
+}
+
+// syncRetryDelay 记录同步失败并返回重连前的等待时间
+// 同步请求失败（由 mautrix 内部重试）和同步中止（由 runSync 重新启动）共用同一退避状态
+func (c *MatrixChannel) syncRetryDelay(err error) time.Duration {
+	attempt, delay := c.recordSyncFailure(err)
+	c.logger.Warn("Matrix 同步错误，稍后重连",
+		zap.Error(err),
+		zap.Int("attempt", attempt),
+		zap.Duration("delay", delay),
+	)
+	return delay
+}
+
+// recordSyncFailure 记录一次同步失败，返回连续失败次数和重连前的等待时间
+func (c *MatrixChannel) recordSyncFailure(err error) (int, time.Duration) {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	c.syncAttempt++
+	if c.syncAttempt == 1 {
+		c.syncFailedAt = time.Now()
+	}
+	c.syncStats.Reconnects++
+	if err != nil {
+		c.syncStats.LastError = err.Error()
+	}
+	return c.syncAttempt, syncBackoff(c.syncAttempt)
+}
+
+// onSyncSuccess 同步成功时重置重连退避，并记录本次中断时长
+func (c *MatrixChannel) onSyncSuccess(ctx context.Context, resp *mautrix.RespSync, since string) bool {
+	c.syncMu.Lock()
+	attempts := c.syncAttempt
+	if attempts == 0 {
+		c.syncMu.Unlock()
+		return true
+	}
+	downtime := time.Since(c.syncFailedAt)
+	c.syncAttempt = 0
+	c.syncStats.Recoveries++
+	c.syncStats.Downtime += downtime
+	c.syncMu.Unlock()
+
+	c.logger.Info("Matrix 同步已恢复",
+		zap.Int("attempts", attempts),
+		zap.Duration("downtime", downtime),
+	)
+	return true
+}
+
+// limitCatchUp 限制启动后第一次同步补处理的消息数，每个房间只保留最近 CatchUpLimit 条
+// 服务器按过滤器返回的条数可能超过限制（如 Synapse 在增量同步时的处理），这里再截断一次
+func (c *MatrixChannel) limitCatchUp(ctx context.Context, resp *mautrix.RespSync, since string) bool {
+	if !c.catchUpPending {
+		return true
+	}
+	c.catchUpPending = false
+	if since == "" {
+		return true
+	}
+
+	total := 0
+	for roomID, room := range resp.Rooms.Join {
+		events := room.Timeline.Events
+		if len(events) > c.config.CatchUpLimit {
+			room.Timeline.Events = events[len(events)-c.config.CatchUpLimit:]
+		}
+		total += len(room.Timeline.Events)
+		resp.Rooms.Join[roomID] = room
+	}
+	if total > 0 {
+		c.logger.Info("补处理停机期间的 Matrix 消息", zap.Int("events", total), zap.Int("rooms", len(resp.Rooms.Join)))
+	}
+	return true
+}
+
+// trackDirectRooms 从同步响应中的 m.direct 账户数据更新私聊房间列表
+// 在同步监听器中处理，首次同步被 DontProcessOldEvents 跳过时也能拿到
+func (c *MatrixChannel) trackDirectRooms(ctx context.Context, resp *mautrix.RespSync, since string) bool {
+	for _, evt := range resp.AccountData.Events {
+		if evt.Type != event.AccountDataDirectChats {
+			continue
+		}
+		var direct event.DirectChatsEventContent
+		if err := json.Unmarshal(evt.Content.VeryRaw, &direct); err != nil {
+			c.logger.Warn("解析 Matrix m.direct 失败", zap.Error(err))
+			continue
+		}
+		c.updateDirectRooms(direct)
+	}
+	return true
+}
+
+// updateDirectRooms 用 m.direct 内容替换同步存储中的私聊房间列表
+func (c *MatrixChannel) updateDirectRooms(direct event.DirectChatsEventContent) {
+	var rooms []id.RoomID
+	for _, roomIDs := range direct {
+		rooms = append(rooms, roomIDs...)
+	}
+	if err := c.store.SetDirectRooms(rooms); err != nil {
+		c.logger.Warn("保存 Matrix 同步状态失败", zap.Error(err))
+	}
+}
+
+// isDirectRoom 判断房间是否为私聊
+// 优先使用 m.direct 账户数据；没有记录时按成员数判断，只有机器人和对方两人时视为私聊
+func (c *MatrixChannel) isDirectRoom(ctx context.Context, roomID id.RoomID) bool {
+	if c.store != nil && c.store.IsDirectRoom(roomID) {
+		return true
+	}
+	if direct, ok := c.memberDirect.Load(roomID); ok {
+		return direct.(bool)
+	}
+	if c.client == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	members, err := c.client.JoinedMembers(ctx, roomID)
+	if err != nil {
+		c.logger.Debug("获取 Matrix 房间成员失败", zap.String("room_id", string(roomID)), zap.Error(err))
+		return false
+	}
+	direct := len(members.Joined) == 2
+	c.memberDirect.Store(roomID, direct)
+	return direct
+}
+
+// SyncStats 返回同步重连统计
+func (c *MatrixChannel) SyncStats() MatrixSyncStats {
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+	return c.syncStats
+}
+
+// syncBackoff 返回第 attempt 次（从 1 开始）重连前的等待时间
+// 从 3 秒开始每次翻倍，加入 ±20% 的随机抖动避免多个实例同时重连，不超过 5 分钟
+func syncBackoff(attempt int) time.Duration {
+	d := syncInitialBackoff
+	for i := 1; i < attempt && d < syncMaxBackoff; i++ {
+		d *= 2
+	}
+	d = time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+	return min(d, syncMaxBackoff)
+}
+
+// startTypingIndicator 开始显示房间 typing 状态
+// 显示中的房间数达到上限时跳过，避免大量房间同时刷新
 func (c *MatrixChannel) startTypingIndicator(roomID id.RoomID) {
 	if c.client == nil {
 		return
 	}
 
 	c.typingMu.Lock()
-	if c.typingCancel == nil {
-		c.typingCancel = make(map[id.RoomID]context.CancelFunc)
+	if c.typingRooms == nil {
+		c.typingRooms = make(map[id.RoomID]*typingState)
 	}
-	if cancel, ok := c.typingCancel[roomID]; ok {
-		cancel()
+	if st, ok := c.typingRooms[roomID]; ok && st.active {
+		st.since = time.Now()
+		c.typingMu.Unlock()
+		return
 	}
-	typingCtx, cancel := context.WithCancel(context.Background())
-	c.typingCancel[roomID] = cancel
+	if c.activeTypingRooms() >= c.maxTypingRooms() {
+		c.typingMu.Unlock()
+		c.logger.Debug("typing 状态房间数已达上限，跳过",
+			zap.String("room_id", string(roomID)),
+			zap.Int("max", c.maxTypingRooms()),
+		)
+		return
+	}
+	c.typingRooms[roomID] = &typingState{since: time.Now(), active: true, pending: true}
 	c.typingMu.Unlock()
 
-	c.bgTasks.Add(1)
-	go func() {
-		defer c.bgTasks.Done()
-
-		typingTimeout := 30 * time.Second
-		refreshInterval := 20 * time.Second
-		c.sendTypingStatus(roomID, true, typingTimeout)
-
-		ticker := time.NewTicker(refreshInterval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-typingCtx.Done():
-				c.sendTypingStatus(roomID, false, 0)
-				return
-			case <-ticker.C:
-				c.sendTypingStatus(roomID, true, typingTimeout)
-			}
-		}
-	}()
+	c.wakeTyping()
 }
 
-// stopTypingIndicator 停止房间 typing 状态刷新
+// stopTypingIndicator 停止显示房间 typing 状态
 func (c *MatrixChannel) stopTypingIndicator(roomID id.RoomID) {
 	c.typingMu.Lock()
-	cancel, ok := c.typingCancel[roomID]
-	if ok {
-		delete(c.typingCancel, roomID)
+	st, ok := c.typingRooms[roomID]
+	if ok && st.active {
+		st.active = false
+		st.pending = true
 	}
 	c.typingMu.Unlock()
 
 	if ok {
-		cancel()
+		c.wakeTyping()
 	}
 }
 
-// stopAllTypingIndicators 停止所有房间 typing 状态刷新
+// stopAllTypingIndicators 停止显示所有房间 typing 状态
 func (c *MatrixChannel) stopAllTypingIndicators() {
 	c.typingMu.Lock()
-	cancels := make([]context.CancelFunc, 0, len(c.typingCancel))
-	for _, cancel := range c.typingCancel {
-		cancels = append(cancels, cancel)
+	for _, st := range c.typingRooms {
+		if st.active {
+			st.active = false
+			st.pending = true
+		}
+	}
+	c.typingMu.Unlock()
+
+	c.wakeTyping()
+}
+
+// activeTypingRooms 返回正在显示 typing 状态的房间数，调用方需持有 typingMu
+func (c *MatrixChannel) activeTypingRooms() int {
+	n := 0
+	for _, st := range c.typingRooms {
+		if st.active {
+			n++
+		}
+	}
+	return n
+}
+
+// maxTypingRooms 返回同时显示 typing 状态的最大房间数
+func (c *MatrixChannel) maxTypingRooms() int {
+	if c.config != nil && c.config.MaxTypingRooms > 0 {
+		return c.config.MaxTypingRooms
+	}
+	return defaultMaxTypingRooms
+}
+
+// wakeTyping 通知发送协程有状态变化
+func (c *MatrixChannel) wakeTyping() {
+	select {
+	case c.typingWake <- struct{}{}:
+	default:
+	}
+}
+
+// runTyping 发送 typing 状态变化，并定时为显示中的房间续期
+// 渠道停止时发送剩余的状态变化后退出
+func (c *MatrixChannel) runTyping() {
+	defer c.bgTasks.Done()
+
+	ticker := time.NewTicker(typingRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			c.flushTyping(false)
+			return
+		case <-c.typingWake:
+			c.flushTyping(false)
+		case <-ticker.C:
+			c.flushTyping(true)
+		}
+	}
+}
+
+// flushTyping 发送状态有变化的房间，refresh 为 true 时同时为所有显示中的房间续期
+// 超过最长显示时间的房间自动停止
+func (c *MatrixChannel) flushTyping(refresh bool) {
+	type update struct {
+		roomID id.RoomID
+		typing bool
+	}
+
+	now := time.Now()
+	var updates []update
+	c.typingMu.Lock()
+	for roomID, st := range c.typingRooms {
+		if st.active && now.Sub(st.since) > typingMaxDuration {
+			st.active = false
+			st.pending = true
+		}
+		if !st.pending && !(refresh && st.active) {
+			continue
+		}
+		updates = append(updates, update{roomID: roomID, typing: st.active})
+		st.pending = false
+		if !st.active {
+			delete(c.typingRooms, roomID)
+		}
 	}
-	c.typingCancel = make(map[id.RoomID]context.CancelFunc)
 	c.typingMu.Unlock()
 
-	for _, cancel := range cancels {
-		cancel()
+	for _, u := range updates {
+		timeout := time.Duration(0)
+		if u.typing {
+			timeout = typingTimeout
+		}
+		c.sendTypingStatus(u.roomID, u.typing, timeout)
 	}
 }
 
@@ -323,24 +626,16 @@ func (c *MatrixChannel) onMessage(ctx context.Context, evt *event.Event) {
 	}
 
 	// 检查用户白名单
-	if len(c.config.AllowFrom) > 0 {
-		allowed := false
-		for _, u := range c.config.AllowFrom {
-			if string(evt.Sender) == u {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			c.logger.Debug("消息发送者不在白名单中", zap.String("sender", string(evt.Sender)))
-			return
-		}
+	if !IsAllowed(string(evt.Sender), c.config.AllowFrom) {
+		c.logger.Debug("消息发送者不在白名单中", zap.String("sender", string(evt.Sender)))
+		return
 	}
 
-	// 判断是否是群组消息
-	// Matrix 房间 ID 以 ! 开头，群组和私聊的判断需要额外查询
-	// 这里简化处理：所有房间都按群组处理，除非是 DM（需要额外状态存储）
+	// 判断是私聊还是群聊
 	chatType := "group"
+	if c.isDirectRoom(ctx, evt.RoomID) {
+		chatType = "direct"
+	}
 
 	c.logger.Info("收到 Matrix 消息",
 		zap.String("sender", string(evt.Sender)),
@@ -363,6 +658,7 @@ func (c *MatrixChannel) onMessage(ctx context.Context, evt *event.Event) {
 			"sender":    string(evt.Sender),
 			"chat_type": chatType,
 			"msg_type":  string(content.MsgType),
+			"catch_up":  time.UnixMilli(evt.Timestamp).Before(c.startedAt),
 		},
 	})
 }
@@ -402,7 +698,12 @@ func (c *MatrixChannel) Stop() {
 	// 等待后台任务完成
 	c.bgTasks.Wait()
 
-	c.logger.Info("Matrix 渠道已停止")
+	stats := c.SyncStats()
+	c.logger.Info("Matrix 渠道已停止",
+		zap.Int64("reconnects", stats.Reconnects),
+		zap.Int64("recoveries", stats.Recoveries),
+		zap.Duration("downtime", stats.Downtime),
+	)
 }
 
 // Send 发送消息
@@ -416,13 +717,9 @@ func (c *MatrixChannel) Send(msg *bus.OutboundMessage) error {
 	}
 
 	roomID := id.RoomID(msg.ChatID)
-	defer c.stopTypingIndicator(roomID)
 
 	// 记录发送前的原始消息（便于调试 "Unable to render message" 问题）
-	preview := msg.Content
-	if len(preview) > 200 {
-		preview = preview[:200] + "..."
-	}
+	preview := utils.TruncateString(msg.Content, 200)
 	c.logger.Info("[Matrix] 准备发送消息",
 		zap.String("room_id", string(roomID)),
 		zap.String("channel", msg.Channel),
@@ -469,10 +766,7 @@ func markdownToHTML(md string) string {
 	var buf bytes.Buffer
 	if err := goldmark.Convert([]byte(md), &buf); err != nil {
 		// Markdown 转换失败，记录原始内容的前 100 字符便于调试
-		preview := md
-		if len(preview) > 100 {
-			preview = preview[:100] + "..."
-		}
+		preview := utils.TruncateString(md, 100)
 		zap.L().Warn("[Matrix] Markdown 转换失败",
 			zap.Error(err),
 			zap.String("content_preview", preview),
@@ -664,13 +958,18 @@ type FileSyncStore struct {
 
 	mu        sync.RWMutex
 	filterID  string
+	filterKey string // 创建过滤器时使用的配置，配置变化时需要重新创建
 	nextBatch string
+
+	directRooms map[id.RoomID]bool // m.direct 中记录的私聊房间
 }
 
 // syncData 用于 JSON 序列化的数据结构
 type syncData struct {
-	FilterID  string `json:"filter_id"`
-	NextBatch string `json:"next_batch"`
+	FilterID    string      `json:"filter_id"`
+	FilterKey   string      `json:"filter_key,omitempty"`
+	NextBatch   string      `json:"next_batch"`
+	DirectRooms []id.RoomID `json:"direct_rooms,omitempty"`
 }
 
 // NewFileSyncStore 创建文件同步存储
@@ -702,7 +1001,12 @@ func (s *FileSyncStore) load() error {
 
 	s.mu.Lock()
 	s.filterID = sd.FilterID
+	s.filterKey = sd.FilterKey
 	s.nextBatch = sd.NextBatch
+	s.directRooms = make(map[id.RoomID]bool, len(sd.DirectRooms))
+	for _, roomID := range sd.DirectRooms {
+		s.directRooms[roomID] = true
+	}
 	s.mu.Unlock()
 
 	return nil
@@ -713,8 +1017,13 @@ func (s *FileSyncStore) Save() error {
 	s.mu.RLock()
 	sd := syncData{
 		FilterID:  s.filterID,
+		FilterKey: s.filterKey,
 		NextBatch: s.nextBatch,
 	}
+	for roomID := range s.directRooms {
+		sd.DirectRooms = append(sd.DirectRooms, roomID)
+	}
+	slices.Sort(sd.DirectRooms)
 	s.mu.RUnlock()
 
 	data, err := json.MarshalIndent(sd, "", "  ")
@@ -746,6 +1055,37 @@ func (s *FileSyncStore) SaveFilterID(ctx context.Context, userID id.UserID, filt
 	return s.Save()
 }
 
+// ResetFilterIfChanged 过滤器配置变化时清除缓存的过滤器 ID，下次同步时按新配置重新创建
+func (s *FileSyncStore) ResetFilterIfChanged(key string) error {
+	s.mu.Lock()
+	if s.filterKey == key {
+		s.mu.Unlock()
+		return nil
+	}
+	s.filterID = ""
+	s.filterKey = key
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// IsDirectRoom 判断房间是否在 m.direct 记录的私聊房间中
+func (s *FileSyncStore) IsDirectRoom(roomID id.RoomID) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.directRooms[roomID]
+}
+
+// SetDirectRooms 替换私聊房间列表并保存
+func (s *FileSyncStore) SetDirectRooms(rooms []id.RoomID) error {
+	s.mu.Lock()
+	s.directRooms = make(map[id.RoomID]bool, len(rooms))
+	for _, roomID := range rooms {
+		s.directRooms[roomID] = true
+	}
+	s.mu.Unlock()
+	return s.Save()
+}
+
 // LoadNextBatch 实现 mautrix.SyncStore 接口
 func (s *FileSyncStore) LoadNextBatch(ctx context.Context, userID id.UserID) (string, error) {
 	s.mu.RLock()
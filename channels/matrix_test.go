@@ -3,13 +3,24 @@ package channels
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/weibaohui/nanobot-go/bus"
 	"go.uber.org/zap"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/event"
 	"maunium.net/go/mautrix/id"
 )
 
@@ -38,8 +49,8 @@ func TestNewMatrixChannel(t *testing.T) {
 			t.Errorf("Name() = %q, 期望 matrix", channel.Name())
 		}
 
-		if channel.typingCancel == nil {
-			t.Error("typingCancel map 不应该为 nil")
+		if channel.typingRooms == nil {
+			t.Error("typingRooms map 不应该为 nil")
 		}
 	})
 
@@ -148,21 +159,16 @@ func TestMatrixChannel_Stop(t *testing.T) {
 
 	// 设置一些状态
 	channel.running = true
-	channel.typingCancel = make(map[id.RoomID]context.CancelFunc)
-
-	// 添加一个模拟的 cancel 函数
-	ctx, cancel := context.WithCancel(context.Background())
-	channel.typingCancel[id.RoomID("room-1")] = cancel
+	// 添加一个显示中的 typing 状态
+	state := &typingState{since: time.Now(), active: true}
+	channel.typingRooms[id.RoomID("room-1")] = state
 
 	// 停止（不会 panic）
 	channel.Stop()
 
-	// 验证 context 被取消
-	select {
-	case <-ctx.Done():
-		// 预期行为
-	case <-time.After(100 * time.Millisecond):
-		t.Error("context 应该被取消")
+	// 验证 typing 状态被停止
+	if state.active || !state.pending {
+		t.Error("停止时应结束所有 typing 状态")
 	}
 
 	if channel.running {
@@ -389,61 +395,77 @@ func TestMatrixChannel_sendTypingStatus(t *testing.T) {
 
 // TestMatrixChannel_typingIndicator 测试 typing 指示器
 func TestMatrixChannel_typingIndicator(t *testing.T) {
-	config := &MatrixConfig{
-		Homeserver: "https://example.com",
-		UserID:     "@test:example.com",
-		Token:      "token",
+	var mu sync.Mutex
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req mautrix.ReqTyping
+		json.NewDecoder(r.Body).Decode(&req)
+		room, _ := url.PathUnescape(strings.Split(r.URL.EscapedPath(), "/")[5])
+		mu.Lock()
+		sent = append(sent, fmt.Sprintf("%s=%v", room, req.Typing))
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	takeSent := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		slices.Sort(sent)
+		got := strings.Join(sent, ",")
+		sent = nil
+		return got
 	}
-	messageBus := bus.NewMessageBus(zap.NewNop())
-	channel := NewMatrixChannel(config, messageBus, zap.NewNop())
 
-	channel.typingCancel = make(map[id.RoomID]context.CancelFunc)
+	config := &MatrixConfig{MaxTypingRooms: 2}
+	channel := NewMatrixChannel(config, bus.NewMessageBus(zap.NewNop()), zap.NewNop())
 
 	t.Run("启动 typing 指示器需要 client", func(t *testing.T) {
-		// client 为 nil 时不会添加 cancel
 		channel.startTypingIndicator(id.RoomID("!room:example.com"))
-
-		channel.typingMu.Lock()
-		_, ok := channel.typingCancel[id.RoomID("!room:example.com")]
-		channel.typingMu.Unlock()
-
-		// 因为 client 为 nil，所以不会添加
-		if ok {
-			t.Error("client 为 nil 时不应该添加 cancel 函数")
+		if len(channel.typingRooms) != 0 {
+			t.Error("client 为 nil 时不应该添加 typing 状态")
 		}
 	})
 
-	t.Run("停止 typing 指示器", func(t *testing.T) {
-		// 手动添加一个 cancel 函数
-		_, cancel := context.WithCancel(context.Background())
-		channel.typingCancel[id.RoomID("!room:example.com")] = cancel
+	channel.client, _ = mautrix.NewClient(server.URL, "@bot:example.com", "token")
 
-		channel.stopTypingIndicator(id.RoomID("!room:example.com"))
+	t.Run("超过上限的房间不显示 typing", func(t *testing.T) {
+		channel.startTypingIndicator("!a:example.com")
+		channel.startTypingIndicator("!b:example.com")
+		channel.startTypingIndicator("!c:example.com")
+		channel.flushTyping(false)
+		if got := takeSent(); got != "!a:example.com=true,!b:example.com=true" {
+			t.Errorf("发送记录 = %q", got)
+		}
+	})
 
-		channel.typingMu.Lock()
-		_, ok := channel.typingCancel[id.RoomID("!room:example.com")]
-		channel.typingMu.Unlock()
+	t.Run("停止后立即发送并释放名额", func(t *testing.T) {
+		channel.stopTypingIndicator("!a:example.com")
+		channel.startTypingIndicator("!c:example.com")
+		channel.flushTyping(false)
+		if got := takeSent(); got != "!a:example.com=false,!c:example.com=true" {
+			t.Errorf("发送记录 = %q", got)
+		}
+		if _, ok := channel.typingRooms["!a:example.com"]; ok {
+			t.Error("已停止的房间应从 typingRooms 中移除")
+		}
+	})
 
-		if ok {
-			t.Error("typingCancel 中不应该存在 room 的 cancel 函数")
+	t.Run("定时续期并结束超时的房间", func(t *testing.T) {
+		channel.typingRooms["!b:example.com"].since = time.Now().Add(-typingMaxDuration - time.Minute)
+		channel.flushTyping(true)
+		if got := takeSent(); got != "!b:example.com=false,!c:example.com=true" {
+			t.Errorf("发送记录 = %q", got)
 		}
 	})
 
 	t.Run("停止所有 typing 指示器", func(t *testing.T) {
-		// 手动添加多个
-		_, cancel1 := context.WithCancel(context.Background())
-		_, cancel2 := context.WithCancel(context.Background())
-		channel.typingCancel[id.RoomID("!room1:example.com")] = cancel1
-		channel.typingCancel[id.RoomID("!room2:example.com")] = cancel2
-
 		channel.stopAllTypingIndicators()
-
-		channel.typingMu.Lock()
-		count := len(channel.typingCancel)
-		channel.typingMu.Unlock()
-
-		if count != 0 {
-			t.Errorf("typingCancel 数量 = %d, 期望 0", count)
+		channel.flushTyping(false)
+		if got := takeSent(); got != "!c:example.com=false" {
+			t.Errorf("发送记录 = %q", got)
+		}
+		if len(channel.typingRooms) != 0 {
+			t.Errorf("typingRooms 数量 = %d, 期望 0", len(channel.typingRooms))
 		}
 	})
 }
@@ -617,3 +639,119 @@ func TestFileSyncStore(t *testing.T) {
 		}
 	})
 }
+
+// TestMatrixChannel_SyncBackoff 测试同步重连的指数退避和统计
+func TestMatrixChannel_SyncBackoff(t *testing.T) {
+	t.Run("等待时间指数增长并有上限", func(t *testing.T) {
+		for attempt, base := range map[int]time.Duration{1: 3 * time.Second, 2: 6 * time.Second, 4: 24 * time.Second} {
+			d := syncBackoff(attempt)
+			if d < base*8/10 || d > base*12/10 {
+				t.Errorf("syncBackoff(%d) = %v, 期望 %v ±20%%", attempt, d, base)
+			}
+		}
+		if d := syncBackoff(100); d > syncMaxBackoff || d < syncMaxBackoff*8/10 {
+			t.Errorf("syncBackoff(100) = %v, 期望不超过 %v", d, syncMaxBackoff)
+		}
+	})
+
+	t.Run("同步成功后重置退避", func(t *testing.T) {
+		channel := NewMatrixChannel(&MatrixConfig{}, bus.NewMessageBus(zap.NewNop()), zap.NewNop())
+		channel.recordSyncFailure(errors.New("连接被拒绝"))
+		if attempt, _ := channel.recordSyncFailure(errors.New("连接超时")); attempt != 2 {
+			t.Errorf("连续失败次数 = %d, 期望 2", attempt)
+		}
+
+		channel.onSyncSuccess(context.Background(), nil, "s1")
+		if attempt, _ := channel.recordSyncFailure(nil); attempt != 1 {
+			t.Errorf("同步成功后连续失败次数 = %d, 期望从 1 开始", attempt)
+		}
+		syncer := &matrixSyncer{retryDelay: channel.syncRetryDelay}
+		if delay, err := syncer.OnFailedSync(nil, errors.New("502 Bad Gateway")); err != nil || delay < 4*time.Second {
+			t.Errorf("OnFailedSync() = %v, %v, 期望按第 2 次失败退避", delay, err)
+		}
+		stats := channel.SyncStats()
+		if stats.Reconnects != 4 || stats.Recoveries != 1 || stats.Downtime <= 0 || stats.LastError != "502 Bad Gateway" {
+			t.Errorf("SyncStats() = %+v", stats)
+		}
+	})
+}
+
+// TestMatrixChannel_CatchUp 测试重启后补处理停机期间的消息
+func TestMatrixChannel_CatchUp(t *testing.T) {
+	t.Run("过滤器配置变化时重新创建", func(t *testing.T) {
+		storePath := filepath.Join(t.TempDir(), "sync.json")
+		os.WriteFile(storePath, []byte(`{"filter_id": "f1", "next_batch": "b1"}`), 0644)
+		store, _ := NewFileSyncStore(storePath, "@bot:example.com")
+
+		store.ResetFilterIfChanged("")
+		if store.filterID != "f1" {
+			t.Error("默认配置不应清除已有过滤器")
+		}
+		store.ResetFilterIfChanged("timeline:20")
+		reloaded, _ := NewFileSyncStore(storePath, "@bot:example.com")
+		if reloaded.filterID != "" || reloaded.filterKey != "timeline:20" || reloaded.nextBatch != "b1" {
+			t.Errorf("重新加载 = filter %q, key %q, batch %q", reloaded.filterID, reloaded.filterKey, reloaded.nextBatch)
+		}
+	})
+
+	t.Run("第一次同步每个房间只保留最近的消息", func(t *testing.T) {
+		channel := NewMatrixChannel(&MatrixConfig{CatchUpLimit: 2}, bus.NewMessageBus(zap.NewNop()), zap.NewNop())
+		channel.catchUpPending = true
+		newResp := func(n int) *mautrix.RespSync {
+			resp := &mautrix.RespSync{}
+			resp.Rooms.Join = map[id.RoomID]*mautrix.SyncJoinedRoom{"!dm:example.com": {}}
+			for i := range n {
+				resp.Rooms.Join["!dm:example.com"].Timeline.Events = append(resp.Rooms.Join["!dm:example.com"].Timeline.Events, &event.Event{ID: id.EventID(fmt.Sprintf("$%d", i))})
+			}
+			return resp
+		}
+
+		resp := newResp(5)
+		channel.limitCatchUp(context.Background(), resp, "b1")
+		events := resp.Rooms.Join["!dm:example.com"].Timeline.Events
+		if len(events) != 2 || events[0].ID != "$3" {
+			t.Errorf("保留的事件 = %d 条, 第一条 %v", len(events), events[0].ID)
+		}
+
+		resp = newResp(5)
+		channel.limitCatchUp(context.Background(), resp, "b2")
+		if len(resp.Rooms.Join["!dm:example.com"].Timeline.Events) != 5 {
+			t.Error("之后的同步不应截断")
+		}
+	})
+}
+
+// TestMatrixChannel_DirectRooms 测试区分私聊和群聊房间
+func TestMatrixChannel_DirectRooms(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "sync.json")
+	os.WriteFile(storePath, []byte(`{}`), 0644)
+	store, _ := NewFileSyncStore(storePath, "@bot:example.com")
+	channel := NewMatrixChannel(&MatrixConfig{}, bus.NewMessageBus(zap.NewNop()), zap.NewNop())
+	channel.store = store
+
+	resp := &mautrix.RespSync{}
+	resp.AccountData.Events = []*event.Event{{
+		Type:    event.AccountDataDirectChats,
+		Content: event.Content{VeryRaw: []byte(`{"@alice:example.com": ["!dm1:example.com"], "@bob:example.com": ["!dm2:example.com"]}`)},
+	}}
+	if !channel.trackDirectRooms(context.Background(), resp, "") {
+		t.Error("trackDirectRooms 不应中止同步处理")
+	}
+
+	ctx := context.Background()
+	if !channel.isDirectRoom(ctx, "!dm1:example.com") || !channel.isDirectRoom(ctx, "!dm2:example.com") {
+		t.Error("m.direct 中的房间应视为私聊")
+	}
+	channel.memberDirect.Store(id.RoomID("!pair:example.com"), true)
+	if !channel.isDirectRoom(ctx, "!pair:example.com") {
+		t.Error("只有两名成员的房间应视为私聊")
+	}
+	if channel.isDirectRoom(ctx, "!team:example.com") {
+		t.Error("无法确定时应按群聊处理")
+	}
+
+	reloaded, _ := NewFileSyncStore(storePath, "@bot:example.com")
+	if !reloaded.IsDirectRoom("!dm2:example.com") {
+		t.Error("私聊房间列表应持久化到同步存储")
+	}
+}
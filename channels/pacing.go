@@ -0,0 +1,61 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 回复节奏模式
+const (
+	PacingOff          = "off"          // 不延迟
+	PacingFixed        = "fixed"        // 固定延迟
+	PacingProportional = "proportional" // 按内容长度成比例延迟
+)
+
+// PacingConfig 出站消息节奏配置
+// 发送前按配置等待一段时间，让机器人在社交场景中不会瞬间回复
+type PacingConfig struct {
+	// Mode 延迟模式：off/fixed/proportional，为空等同于 off
+	Mode string
+	// Delay 固定延迟；proportional 模式下作为基础延迟
+	Delay time.Duration
+	// PerChar proportional 模式下每个字符增加的延迟
+	PerChar time.Duration
+	// MaxDelay 延迟上限，0 表示不限制
+	MaxDelay time.Duration
+}
+
+// DelayFor 计算发送指定内容前需要等待的时长
+func (p PacingConfig) DelayFor(content string) time.Duration {
+	var delay time.Duration
+	switch strings.ToLower(strings.TrimSpace(p.Mode)) {
+	case PacingFixed:
+		delay = p.Delay
+	case PacingProportional:
+		delay = p.Delay + time.Duration(utf8.RuneCountInString(content))*p.PerChar
+	default:
+		return 0
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// waitPacing 等待指定时长，context 取消时立即返回
+func waitPacing(ctx context.Context, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,121 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/bus"
+	"go.uber.org/zap"
+)
+
+// TestPacingConfig_DelayFor 测试回复延迟计算
+func TestPacingConfig_DelayFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		pacing  PacingConfig
+		content string
+		want    time.Duration
+	}{
+		{"未配置不延迟", PacingConfig{Delay: time.Second}, "你好", 0},
+		{"off 不延迟", PacingConfig{Mode: PacingOff, Delay: time.Second}, "你好", 0},
+		{"固定延迟", PacingConfig{Mode: PacingFixed, Delay: 500 * time.Millisecond}, "你好", 500 * time.Millisecond},
+		{"按字符数延迟", PacingConfig{Mode: PacingProportional, Delay: 100 * time.Millisecond, PerChar: 10 * time.Millisecond}, "你好呀", 130 * time.Millisecond},
+		{"不超过上限", PacingConfig{Mode: "Proportional", PerChar: time.Second, MaxDelay: 2 * time.Second}, "hello", 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pacing.DelayFor(tt.content); got != tt.want {
+				t.Errorf("DelayFor() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBaseChannel_SubscribeOutbound_Pacing 测试出站消息按节奏延迟发送
+func TestBaseChannel_SubscribeOutbound_Pacing(t *testing.T) {
+	t.Run("发送前等待配置的延迟", func(t *testing.T) {
+		messageBus := bus.NewMessageBus(zap.NewNop())
+		channel := NewBaseChannel("test", messageBus)
+		channel.SetPacing(PacingConfig{Mode: PacingFixed, Delay: 50 * time.Millisecond})
+
+		sent := make(chan time.Time, 1)
+		channel.SubscribeOutbound(context.Background(), func(msg *bus.OutboundMessage) error {
+			sent <- time.Now()
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		messageBus.StartDispatcher(ctx)
+
+		start := time.Now()
+		messageBus.PublishOutbound(bus.NewOutboundMessage("test", "chat", "hi"))
+		select {
+		case at := <-sent:
+			if at.Sub(start) < 50*time.Millisecond {
+				t.Errorf("延迟 %v 小于配置的 50ms", at.Sub(start))
+			}
+		case <-time.After(time.Second):
+			t.Fatal("未收到出站消息")
+		}
+	})
+
+	t.Run("取消后立即发送", func(t *testing.T) {
+		messageBus := bus.NewMessageBus(zap.NewNop())
+		channel := NewBaseChannel("test", messageBus)
+		channel.SetPacing(PacingConfig{Mode: PacingFixed, Delay: time.Hour})
+
+		channelCtx, stop := context.WithCancel(context.Background())
+		stop()
+		sent := make(chan struct{}, 1)
+		channel.SubscribeOutbound(channelCtx, func(msg *bus.OutboundMessage) error {
+			sent <- struct{}{}
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		messageBus.StartDispatcher(ctx)
+
+		messageBus.PublishOutbound(bus.NewOutboundMessage("test", "chat", "hi"))
+		select {
+		case <-sent:
+		case <-time.After(time.Second):
+			t.Fatal("context 取消后应立即发送")
+		}
+	})
+
+	t.Run("重试时不重复等待", func(t *testing.T) {
+		messageBus := bus.NewMessageBus(zap.NewNop())
+		messageBus.SetRetryPolicy(bus.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+		channel := NewBaseChannel("test", messageBus)
+		channel.SetPacing(PacingConfig{Mode: PacingFixed, Delay: 100 * time.Millisecond})
+
+		var attempts []time.Time
+		sent := make(chan struct{}, 1)
+		channel.SubscribeOutbound(context.Background(), func(msg *bus.OutboundMessage) error {
+			attempts = append(attempts, time.Now())
+			if len(attempts) == 1 {
+				return errors.New("发送失败")
+			}
+			sent <- struct{}{}
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		messageBus.StartDispatcher(ctx)
+
+		messageBus.PublishOutbound(bus.NewOutboundMessage("test", "chat", "hi"))
+		select {
+		case <-sent:
+		case <-time.After(time.Second):
+			t.Fatal("重试后应发送成功")
+		}
+		if gap := attempts[1].Sub(attempts[0]); gap >= 100*time.Millisecond {
+			t.Errorf("重试间隔 %v, 重试时不应再次等待回复节奏", gap)
+		}
+	})
+}
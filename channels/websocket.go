@@ -1,15 +1,28 @@
 package channels
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/utils"
 	"go.uber.org/zap"
 )
 
@@ -23,19 +36,110 @@ type WebSocketConfig struct {
 	AllowFrom []string
 	// EnableStreaming 是否启用流式输出（打字机效果）
 	EnableStreaming bool
+	// AuthToken 共享访问令牌，非空时连接必须携带该令牌
+	AuthToken string
+	// AuthTokens 令牌到用户 ID 的映射，使用其中的令牌连接时以对应用户 ID 作为发送者，AllowFrom 按用户 ID 校验
+	AuthTokens map[string]string
+	// UI 聊天页面配置
+	UI WebSocketUIConfig
+	// HistoryLimit 重新连接时发送给页面的历史消息条数，默认 20，小于 0 时不发送
+	HistoryLimit int
+	// AllowedOrigins 允许发起 WebSocket 连接的来源，如 "https://chat.example.com"
+	// 为空时只允许同源连接，包含 "*" 时允许所有来源（仅用于本地开发）
+	AllowedOrigins []string
 }
 
+// WebSocketUIConfig 聊天页面配置
+type WebSocketUIConfig struct {
+	// Dir 自定义页面目录，存在 index.html 时用它替代内置页面，目录下的其他文件通过 /static/ 访问
+	Dir string
+	// Title 页面标题，默认 nanobot
+	Title string
+	// Logo 标题前的图标（文本或 emoji），默认 🐈
+	Logo string
+	// ThemeColor 主题色，默认 #667eea
+	ThemeColor string
+	// AccentColor 渐变的辅助色，默认 #764ba2
+	AccentColor string
+	// Welcome 欢迎语，为空时使用内置欢迎语
+	Welcome string
+	// Tips 欢迎页的快捷提示，为空时使用内置提示
+	Tips []WebSocketUITip
+}
+
+// WebSocketUITip 欢迎页快捷提示
+type WebSocketUITip struct {
+	// Label 按钮上显示的文字
+	Label string
+	// Message 点击后发送的消息
+	Message string
+}
+
+// defaultUITips 内置的快捷提示
+var defaultUITips = []WebSocketUITip{
+	{Label: "👋 打个招呼", Message: "你好"},
+	{Label: "🗺️ 规划任务", Message: "帮我规划一次旅行"},
+	{Label: "💻 写代码", Message: "帮我写一段代码"},
+}
+
+// indexPageData 聊天页面模板变量
+type indexPageData struct {
+	Title       string
+	Logo        string
+	ThemeColor  template.CSS
+	AccentColor template.CSS
+	Welcome     string
+	Tips        []WebSocketUITip
+	WSPath      string
+}
+
+//go:embed web/index.html
+var defaultIndexHTML string
+
+// HistoryMessage 发送给页面的历史消息
+type HistoryMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// HistoryProvider 按 chatID 查询最近的历史消息
+type HistoryProvider func(ctx context.Context, chatID string, limit int) []HistoryMessage
+
+// sessionIDPattern 页面保存的会话 ID 格式
+var sessionIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
 // WebSocketChannel WebSocket 渠道
 type WebSocketChannel struct {
 	*BaseChannel
 	config    *WebSocketConfig
+	indexPage []byte // 渲染后的聊天页面
 	server    *http.Server
 	upgrader  websocket.Upgrader
-	clients   map[string]*websocket.Conn // chatID -> conn
+	clients   map[string]*wsClient // chatID -> 客户端连接
 	clientsMu sync.RWMutex
-	logger    *zap.Logger
+	// sessionOwners 会话 ID -> 创建会话的用户 ID，仅记录使用身份令牌的连接
+	sessionOwners map[string]string
+	history       HistoryProvider
+	logger        *zap.Logger
+}
+
+// wsClient 客户端连接
+// gorilla/websocket 的连接不支持并发写，出站消息、流式片段和回合结束事件来自不同的 goroutine，写入时需要加锁
+type wsClient struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// write 向连接写入一条文本消息
+func (w *wsClient) write(data []byte) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	return w.conn.WriteMessage(websocket.TextMessage, data)
 }
 
+// errClientNotConnected 目标会话没有在线的客户端连接
+var errClientNotConnected = errors.New("websocket 客户端未连接")
+
 // NewWebSocketChannel 创建 WebSocket 渠道
 func NewWebSocketChannel(config *WebSocketConfig, bus *bus.MessageBus, logger *zap.Logger) *WebSocketChannel {
 	if config == nil {
@@ -47,20 +151,53 @@ func NewWebSocketChannel(config *WebSocketConfig, bus *bus.MessageBus, logger *z
 	if config.Path == "" {
 		config.Path = "/ws"
 	}
+	if config.HistoryLimit == 0 {
+		config.HistoryLimit = 20
+	}
+
+	if logger == nil {
+		logger = zap.NewNop()
+	}
 
+	allowedOrigins := config.AllowedOrigins
 	return &WebSocketChannel{
 		BaseChannel: NewBaseChannel("websocket", bus),
+		indexPage:   renderIndexPage(config, logger),
 		config:      config,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 			CheckOrigin: func(r *http.Request) bool {
-				return true // 允许所有来源
+				return checkOrigin(r, allowedOrigins)
 			},
 		},
-		clients: make(map[string]*websocket.Conn),
-		logger:  logger,
+		clients:       make(map[string]*wsClient),
+		sessionOwners: make(map[string]string),
+		logger:        logger,
+	}
+}
+
+// checkOrigin 校验 WebSocket 握手请求的 Origin，防止跨站 WebSocket 劫持
+// 未携带 Origin 的请求（非浏览器客户端）直接放行；allowed 为空时只允许同源
+func checkOrigin(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
 	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if len(allowed) == 0 {
+		return strings.EqualFold(u.Host, r.Host)
+	}
+	for _, a := range allowed {
+		a = strings.TrimSuffix(strings.TrimSpace(a), "/")
+		if a == "*" || strings.EqualFold(a, origin) {
+			return true
+		}
+	}
+	return false
 }
 
 // Name 返回渠道名称
@@ -77,18 +214,20 @@ func (c *WebSocketChannel) Start(ctx context.Context) error {
 
 	// 静态页面
 	mux.HandleFunc("/", c.handleIndex)
+	if c.config.UI.Dir != "" {
+		// 自定义页面引用的图片、样式等静态资源
+		mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir(c.config.UI.Dir))))
+	}
 
 	// 订阅出站消息（用于非流式响应）
-	c.SubscribeOutbound(ctx, func(msg *bus.OutboundMessage) {
-		if msg.Channel != "websocket" {
-			return
-		}
-		c.sendToClient(msg.ChatID, msg.Content)
+	// 流式消息按连接的聊天单独订阅，见 handleWebSocket
+	c.SubscribeOutbound(ctx, func(msg *bus.OutboundMessage) error {
+		return c.sendToClient(msg.ChatID, msg.Content)
 	})
 
-	// 订阅流式消息（用于打字机效果）
-	c.bus.SubscribeStream("websocket", func(chunk *bus.StreamChunk) error {
-		return c.sendStreamChunk(chunk.ChatID, chunk)
+	// 订阅回合结束事件，通知页面收尾
+	c.bus.SubscribeTurnEnd("websocket", func(evt *bus.TurnEnd) {
+		c.sendTurnEnd(evt.ChatID, evt.Time)
 	})
 
 	c.server = &http.Server{
@@ -122,41 +261,105 @@ func (c *WebSocketChannel) Stop() {
 
 	// 关闭所有客户端连接
 	c.clientsMu.Lock()
-	for _, conn := range c.clients {
-		conn.Close()
+	for _, client := range c.clients {
+		client.conn.Close()
 	}
-	c.clients = make(map[string]*websocket.Conn)
+	c.clients = make(map[string]*wsClient)
 	c.clientsMu.Unlock()
 
 	c.logger.Info("WebSocket 渠道已停止")
 }
 
+// authRequired 是否启用了令牌认证
+func (c *WebSocketChannel) authRequired() bool {
+	return c.config.AuthToken != "" || len(c.config.AuthTokens) > 0
+}
+
+// authenticate 校验握手请求携带的令牌，返回令牌对应的用户 ID（共享令牌时为空）
+// 令牌可通过查询参数 token 或 Authorization: Bearer 请求头传递
+func (c *WebSocketChannel) authenticate(r *http.Request) (userID string, ok bool) {
+	if !c.authRequired() {
+		return "", true
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		return "", false
+	}
+	for t, id := range c.config.AuthTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			return id, true
+		}
+	}
+	if c.config.AuthToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.config.AuthToken)) == 1 {
+		return "", true
+	}
+	return "", false
+}
+
 // handleWebSocket 处理 WebSocket 连接
 func (c *WebSocketChannel) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, ok := c.authenticate(r)
+	if !ok {
+		c.logger.Warn("WebSocket 连接认证失败", zap.String("remote_addr", r.RemoteAddr))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	conn, err := c.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		c.logger.Error("WebSocket 升级失败", zap.Error(err))
+		c.logger.Error("WebSocket 升级失败",
+			zap.String("origin", r.Header.Get("Origin")),
+			zap.Error(err),
+		)
 		return
 	}
 	defer conn.Close()
 
-	// 生成 chatID
-	chatID := generateChatID(r)
+	// chatID 由页面保存的会话 ID 决定，重新连接时沿用同一会话；使用带身份的令牌时发送者为令牌对应的用户
+	sessionID, chatID := c.resolveSession(r, userID)
+	senderID := chatID
+	if userID != "" {
+		senderID = userID
+	}
+
+	// 注册前先下发会话 ID 和历史消息，避免与回复并发写入连接
+	c.sendSession(r.Context(), conn, sessionID, chatID)
 
 	// 注册客户端
+	client := &wsClient{conn: conn}
 	c.clientsMu.Lock()
-	c.clients[chatID] = conn
+	c.clients[chatID] = client
 	c.clientsMu.Unlock()
 
 	c.logger.Info("WebSocket 客户端连接",
 		zap.String("chat_id", chatID),
+		zap.String("sender_id", senderID),
 		zap.String("remote_addr", r.RemoteAddr),
 	)
 
+	// 只订阅本会话的流式消息（用于打字机效果），没有连接的会话不会触发分发
+	// 同一会话重新连接时新旧订阅短暂并存，只由当前连接发送，避免重复
+	unsubscribe := c.bus.SubscribeStreamChat("websocket", chatID, func(chunk *bus.StreamChunk) error {
+		c.clientsMu.RLock()
+		current := c.clients[chatID] == client
+		c.clientsMu.RUnlock()
+		if !current {
+			return nil
+		}
+		return c.sendStreamChunk(chatID, chunk)
+	})
+	defer unsubscribe()
+
 	// 清理连接
 	defer func() {
 		c.clientsMu.Lock()
-		delete(c.clients, chatID)
+		// 同一会话在新连接中打开时，保留新连接
+		if c.clients[chatID] == client {
+			delete(c.clients, chatID)
+		}
 		c.clientsMu.Unlock()
 		c.logger.Info("WebSocket 客户端断开", zap.String("chat_id", chatID))
 	}()
@@ -185,29 +388,20 @@ func (c *WebSocketChannel) handleWebSocket(w http.ResponseWriter, r *http.Reques
 		}
 
 		// 检查用户权限
-		if len(c.config.AllowFrom) > 0 {
-			allowed := false
-			for _, id := range c.config.AllowFrom {
-				if id == chatID || id == "*" {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				c.sendToClient(chatID, "抱歉，您没有权限使用此服务。")
-				continue
-			}
+		if !IsAllowed(senderID, c.config.AllowFrom) {
+			c.sendToClient(chatID, "抱歉，您没有权限使用此服务。")
+			continue
 		}
 
 		c.logger.Info("收到 WebSocket 消息",
 			zap.String("chat_id", chatID),
-			zap.String("content", truncate(msg.Content, 100)),
+			zap.String("content", utils.TruncateString(msg.Content, 100)),
 		)
 
 		// 发布入站消息
 		c.PublishInbound(&bus.InboundMessage{
 			Channel:   "websocket",
-			SenderID:  chatID,
+			SenderID:  senderID,
 			ChatID:    chatID,
 			Content:   msg.Content,
 			Timestamp: time.Now(),
@@ -215,20 +409,122 @@ func (c *WebSocketChannel) handleWebSocket(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// SetHistoryProvider 设置历史消息来源，页面连接时据此恢复之前的对话
+func (c *WebSocketChannel) SetHistoryProvider(provider HistoryProvider) {
+	c.history = provider
+}
+
+// sendSession 向页面发送会话 ID 及最近的历史消息
+func (c *WebSocketChannel) sendSession(ctx context.Context, conn *websocket.Conn, sessionID, chatID string) {
+	msg := struct {
+		Type     string           `json:"type"`
+		Session  string           `json:"session"`
+		Messages []HistoryMessage `json:"messages,omitempty"`
+	}{
+		Type:    "session",
+		Session: sessionID,
+	}
+	if c.history != nil && c.config.HistoryLimit > 0 {
+		msg.Messages = c.history(ctx, chatID, c.config.HistoryLimit)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.logger.Error("序列化会话消息失败", zap.Error(err))
+		return
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		c.logger.Error("发送会话消息失败", zap.Error(err))
+	}
+}
+
 // handleIndex 处理首页请求
 func (c *WebSocketChannel) handleIndex(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write([]byte(indexHTML))
+	w.Write(c.indexPage)
 }
 
-// sendToClient 发送消息给客户端
-func (c *WebSocketChannel) sendToClient(chatID, content string) {
+// renderIndexPage 渲染聊天页面
+// 配置了页面目录且其中存在 index.html 时使用自定义模板，读取或渲染失败时回退到内置页面
+func renderIndexPage(config *WebSocketConfig, logger *zap.Logger) []byte {
+	ui := config.UI
+	data := indexPageData{
+		Title:       ui.Title,
+		Logo:        ui.Logo,
+		ThemeColor:  cssColorOrDefault(ui.ThemeColor, "#667eea", logger),
+		AccentColor: cssColorOrDefault(ui.AccentColor, "#764ba2", logger),
+		Welcome:     ui.Welcome,
+		Tips:        ui.Tips,
+		WSPath:      config.Path,
+	}
+	if data.Title == "" {
+		data.Title = "nanobot"
+	}
+	if data.Logo == "" {
+		data.Logo = "🐈"
+	}
+	if len(data.Tips) == 0 {
+		data.Tips = defaultUITips
+	}
+
+	if ui.Dir != "" {
+		path := filepath.Join(ui.Dir, "index.html")
+		if content, err := os.ReadFile(path); err == nil {
+			page, err := executeIndexTemplate(string(content), data)
+			if err == nil {
+				logger.Info("使用自定义聊天页面", zap.String("path", path))
+				return page
+			}
+			logger.Warn("渲染自定义聊天页面失败，使用内置页面", zap.String("path", path), zap.Error(err))
+		} else if !os.IsNotExist(err) {
+			logger.Warn("读取自定义聊天页面失败，使用内置页面", zap.String("path", path), zap.Error(err))
+		}
+	}
+
+	page, err := executeIndexTemplate(defaultIndexHTML, data)
+	if err != nil {
+		// 内置模板随代码发布，渲染失败属于程序错误
+		panic(fmt.Sprintf("渲染内置聊天页面失败: %v", err))
+	}
+	return page
+}
+
+// executeIndexTemplate 解析并渲染页面模板
+func executeIndexTemplate(content string, data indexPageData) ([]byte, error) {
+	tmpl, err := template.New("index").Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// cssColorPattern 允许的主题色格式：#rgb/#rrggbb/#rrggbbaa、颜色名或 rgb()/hsl() 函数
+var cssColorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+|(rgb|rgba|hsl|hsla)\([0-9.,%\s]+\))$`)
+
+// cssColorOrDefault 校验颜色能否安全地写入样式表，为空或格式无效时返回默认值
+func cssColorOrDefault(color, def string, logger *zap.Logger) template.CSS {
+	color = strings.TrimSpace(color)
+	if cssColorPattern.MatchString(color) {
+		return template.CSS(color)
+	}
+	if color != "" {
+		logger.Warn("颜色格式无效，使用默认值", zap.String("color", color), zap.String("default", def))
+	}
+	return template.CSS(def)
+}
+
+// sendToClient 发送消息给客户端，客户端未连接或写入失败时返回错误，由消息总线重试
+func (c *WebSocketChannel) sendToClient(chatID, content string) error {
 	c.clientsMu.RLock()
-	conn, ok := c.clients[chatID]
+	client, ok := c.clients[chatID]
 	c.clientsMu.RUnlock()
 
 	if !ok {
-		return
+		return fmt.Errorf("%w: %s", errClientNotConnected, chatID)
 	}
 
 	msg := struct {
@@ -244,24 +540,63 @@ func (c *WebSocketChannel) sendToClient(chatID, content string) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		c.logger.Error("序列化消息失败", zap.Error(err))
-		return
+		return fmt.Errorf("marshal message: %w", err)
 	}
 
-	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+	if err := client.write(data); err != nil {
 		c.logger.Error("发送消息失败", zap.Error(err))
+		return fmt.Errorf("write websocket message: %w", err)
+	}
+	return nil
+}
+
+// sendTurnEnd 通知客户端回合已结束，页面据此隐藏输入指示器并结束未完成的流式消息
+func (c *WebSocketChannel) sendTurnEnd(chatID string, at time.Time) {
+	c.clientsMu.RLock()
+	client, ok := c.clients[chatID]
+	c.clientsMu.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(struct {
+		Type string `json:"type"`
+		Time string `json:"time"`
+	}{
+		Type: "done",
+		Time: at.Format("15:04:05"),
+	})
+	if err != nil {
+		c.logger.Error("序列化回合结束事件失败", zap.Error(err))
+		return
+	}
+
+	if err := client.write(data); err != nil {
+		c.logger.Error("发送回合结束事件失败", zap.Error(err))
 	}
 }
 
 // sendStreamChunk 发送流式片段给客户端（打字机效果）
 func (c *WebSocketChannel) sendStreamChunk(chatID string, chunk *bus.StreamChunk) error {
 	c.clientsMu.RLock()
-	conn, ok := c.clients[chatID]
+	client, ok := c.clients[chatID]
 	c.clientsMu.RUnlock()
 
 	if !ok {
 		return nil
 	}
 
+	msgType := "stream"
+	switch {
+	case chunk.IsStatus():
+		// 进度状态片段单独标记，前端不会将其拼接进回复正文
+		msgType = bus.StreamChunkTypeStatus
+	case chunk.IsThinking():
+		// 思考过程片段同样单独标记，由前端决定是否展示
+		msgType = bus.StreamChunkTypeThinking
+	}
+
 	msg := struct {
 		Type  string `json:"type"`
 		Delta string `json:"delta"`
@@ -269,7 +604,7 @@ func (c *WebSocketChannel) sendStreamChunk(chatID string, chunk *bus.StreamChunk
 		Time  string `json:"time"`
 		Done  bool   `json:"done"`
 	}{
-		Type:  "stream",
+		Type:  msgType,
 		Delta: chunk.Delta,
 		Text:  chunk.Content,
 		Time:  time.Now().Format("15:04:05"),
@@ -282,7 +617,7 @@ func (c *WebSocketChannel) sendStreamChunk(chatID string, chunk *bus.StreamChunk
 		return fmt.Errorf("marshal stream message: %w", err)
 	}
 
-	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+	if err := client.write(data); err != nil {
 		c.logger.Error("发送流式消息失败", zap.Error(err))
 		return fmt.Errorf("write websocket message: %w", err)
 	}
@@ -293,7 +628,7 @@ func (c *WebSocketChannel) sendStreamChunk(chatID string, chunk *bus.StreamChunk
 // StreamToClient 流式发送消息给客户端（打字机效果）
 func (c *WebSocketChannel) StreamToClient(chatID string, ch <-chan string) {
 	c.clientsMu.RLock()
-	conn, ok := c.clients[chatID]
+	client, ok := c.clients[chatID]
 	c.clientsMu.RUnlock()
 
 	if !ok {
@@ -324,7 +659,7 @@ func (c *WebSocketChannel) StreamToClient(chatID string, ch <-chan string) {
 			continue
 		}
 
-		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		if err := client.write(data); err != nil {
 			c.logger.Error("发送流式消息失败", zap.Error(err))
 			return
 		}
@@ -344,621 +679,48 @@ func (c *WebSocketChannel) StreamToClient(chatID string, ch <-chan string) {
 	}
 
 	data, _ := json.Marshal(doneMsg)
-	conn.WriteMessage(websocket.TextMessage, data)
+	client.write(data)
 }
 
-// generateChatID 生成 chatID
-func generateChatID(r *http.Request) string {
-	return fmt.Sprintf("ws_%s", r.RemoteAddr)
-}
+// resolveSession 确定连接的会话 ID 和 chatID
+// 使用带身份的令牌时会话归属于令牌对应的用户，带回其他用户的会话 ID 时拒绝沿用并分配新的会话
+func (c *WebSocketChannel) resolveSession(r *http.Request, userID string) (sessionID, chatID string) {
+	sessionID, chatID = generateChatID(r, userID)
+	if userID == "" || sessionID == "" {
+		return sessionID, chatID
+	}
 
-// truncate 截断字符串
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	c.clientsMu.Lock()
+	defer c.clientsMu.Unlock()
+	if owner, ok := c.sessionOwners[sessionID]; ok && owner != userID {
+		c.logger.Warn("WebSocket 会话属于其他用户，已分配新会话",
+			zap.String("user_id", userID),
+			zap.String("remote_addr", r.RemoteAddr),
+		)
+		fresh := r.Clone(r.Context())
+		fresh.URL.RawQuery = ""
+		sessionID, chatID = generateChatID(fresh, userID)
 	}
-	return s[:maxLen] + "..."
+	c.sessionOwners[sessionID] = userID
+	return sessionID, chatID
 }
 
-// indexHTML 是聊天页面的 HTML（带打字机效果）
-var indexHTML = `<!DOCTYPE html>
-<html lang="zh-CN">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>🐈 nanobot - AI 助手</title>
-    <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/github-markdown-css@5.5.1/github-markdown.min.css">
-    <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/highlight.js@11.9.0/styles/github.min.css">
-    <style>
-        * {
-            margin: 0;
-            padding: 0;
-            box-sizing: border-box;
-        }
-        body {
-            font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif;
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            min-height: 100vh;
-            display: flex;
-            justify-content: center;
-            align-items: center;
-            padding: 20px;
-        }
-        .chat-container {
-            width: 100%;
-            max-width: 800px;
-            height: 90vh;
-            background: #fff;
-            border-radius: 20px;
-            box-shadow: 0 20px 60px rgba(0,0,0,0.3);
-            display: flex;
-            flex-direction: column;
-            overflow: hidden;
-        }
-        .chat-header {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: white;
-            padding: 20px;
-            display: flex;
-            align-items: center;
-            gap: 15px;
-        }
-        .chat-header .logo {
-            font-size: 32px;
-        }
-        .chat-header h1 {
-            font-size: 20px;
-            font-weight: 600;
-        }
-        .chat-header .status {
-            margin-left: auto;
-            display: flex;
-            align-items: center;
-            gap: 8px;
-            font-size: 14px;
-        }
-        .chat-header .status-dot {
-            width: 10px;
-            height: 10px;
-            border-radius: 50%;
-            background: #4ade80;
-        }
-        .chat-header .status-dot.disconnected {
-            background: #f87171;
-        }
-        .chat-messages {
-            flex: 1;
-            overflow-y: auto;
-            padding: 20px;
-            background: #f8fafc;
-        }
-        .message {
-            margin-bottom: 16px;
-            display: flex;
-            flex-direction: column;
-        }
-        .message.user {
-            align-items: flex-end;
-        }
-        .message.assistant {
-            align-items: flex-start;
-        }
-        .message-bubble {
-            max-width: 80%;
-            padding: 12px 18px;
-            border-radius: 18px;
-            line-height: 1.6;
-            word-wrap: break-word;
-            white-space: pre-wrap;
-        }
-        .message.user .message-bubble {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: white;
-            border-bottom-right-radius: 4px;
-        }
-        .message.assistant .message-bubble {
-            background: white;
-            color: #1f2937;
-            border-bottom-left-radius: 4px;
-            box-shadow: 0 2px 8px rgba(0,0,0,0.1);
-        }
-        .message.assistant .message-bubble.markdown-body {
-            white-space: normal;
-            color: #1f2937;
-            background: white;
-        }
-        .message.assistant .message-bubble.markdown-body pre {
-            overflow: auto;
-        }
-        .message.assistant .message-bubble.markdown-body code {
-            white-space: pre;
-        }
-        .message-time {
-            font-size: 11px;
-            color: #9ca3af;
-            margin-top: 4px;
-            padding: 0 4px;
-        }
-        .cursor {
-            display: inline-block;
-            width: 8px;
-            height: 18px;
-            background: #667eea;
-            animation: blink 1s infinite;
-            vertical-align: text-bottom;
-            margin-left: 2px;
-        }
-        @keyframes blink {
-            0%, 50% { opacity: 1; }
-            51%, 100% { opacity: 0; }
-        }
-        .chat-input-container {
-            padding: 20px;
-            background: white;
-            border-top: 1px solid #e5e7eb;
-        }
-        .chat-input-wrapper {
-            display: flex;
-            gap: 12px;
-            align-items: flex-end;
-        }
-        .chat-input {
-            flex: 1;
-            padding: 14px 18px;
-            border: 2px solid #e5e7eb;
-            border-radius: 24px;
-            font-size: 15px;
-            outline: none;
-            transition: border-color 0.2s;
-            resize: none;
-            max-height: 120px;
-            font-family: inherit;
-        }
-        .chat-input:focus {
-            border-color: #667eea;
-        }
-        .send-button {
-            width: 50px;
-            height: 50px;
-            border: none;
-            border-radius: 50%;
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: white;
-            cursor: pointer;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-            transition: transform 0.2s, box-shadow 0.2s;
-        }
-        .send-button:hover {
-            transform: scale(1.05);
-            box-shadow: 0 4px 15px rgba(102, 126, 234, 0.4);
-        }
-        .send-button:disabled {
-            opacity: 0.5;
-            cursor: not-allowed;
-            transform: none;
-        }
-        .send-button svg {
-            width: 24px;
-            height: 24px;
-        }
-        .typing-indicator {
-            display: none;
-            align-items: center;
-            gap: 4px;
-            padding: 12px 18px;
-            background: white;
-            border-radius: 18px;
-            box-shadow: 0 2px 8px rgba(0,0,0,0.1);
-            margin-bottom: 16px;
-        }
-        .typing-indicator.show {
-            display: flex;
-        }
-        .typing-indicator span {
-            width: 8px;
-            height: 8px;
-            background: #9ca3af;
-            border-radius: 50%;
-            animation: typing 1.4s infinite;
-        }
-        .typing-indicator span:nth-child(2) {
-            animation-delay: 0.2s;
-        }
-        .typing-indicator span:nth-child(3) {
-            animation-delay: 0.4s;
-        }
-        @keyframes typing {
-            0%, 60%, 100% { transform: translateY(0); }
-            30% { transform: translateY(-8px); }
-        }
-        .welcome-message {
-            text-align: center;
-            padding: 40px 20px;
-            color: #6b7280;
-        }
-        .welcome-message h2 {
-            font-size: 24px;
-            margin-bottom: 10px;
-            color: #1f2937;
-        }
-        .welcome-message p {
-            font-size: 14px;
-            line-height: 1.6;
-        }
-        .welcome-message .tips {
-            margin-top: 20px;
-            display: flex;
-            gap: 10px;
-            flex-wrap: wrap;
-            justify-content: center;
-        }
-        .welcome-message .tip {
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            color: white;
-            padding: 8px 16px;
-            border-radius: 20px;
-            font-size: 13px;
-            cursor: pointer;
-            transition: transform 0.2s;
-        }
-        .welcome-message .tip:hover {
-            transform: scale(1.05);
-        }
-        @media (max-width: 600px) {
-            body {
-                padding: 0;
-            }
-            .chat-container {
-                height: 100vh;
-                border-radius: 0;
-            }
-            .chat-input {
-                font-size: 16px;
-            }
-        }
-    </style>
-</head>
-<body>
-    <div class="chat-container">
-        <div class="chat-header">
-            <span class="logo">🐈</span>
-            <h1>nanobot</h1>
-            <div class="status">
-                <span class="status-dot" id="statusDot"></span>
-                <span id="statusText">连接中...</span>
-            </div>
-        </div>
-        <div class="chat-messages" id="chatMessages">
-            <div class="welcome-message">
-                <h2>🐾 欢迎使用 nanobot</h2>
-                <p>我是一个 AI 助手，可以帮助你完成各种任务。<br>支持打字机效果实时输出！</p>
-                <div class="tips">
-                    <span class="tip" onclick="sendTip('你好')">👋 打个招呼</span>
-                    <span class="tip" onclick="sendTip('帮我规划一次旅行')">🗺️ 规划任务</span>
-                    <span class="tip" onclick="sendTip('帮我写一段代码')">💻 写代码</span>
-                </div>
-            </div>
-        </div>
-        <div class="typing-indicator" id="typingIndicator">
-            <span></span><span></span><span></span>
-        </div>
-        <div class="chat-input-container">
-            <div class="chat-input-wrapper">
-                <textarea
-                    class="chat-input"
-                    id="chatInput"
-                    placeholder="输入消息..."
-                    rows="1"
-                ></textarea>
-                <button class="send-button" id="sendButton" onclick="sendMessage()">
-                    <svg viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2">
-                        <path d="M22 2L11 13M22 2l-7 20-4-9-9-4 20-7z"/>
-                    </svg>
-                </button>
-            </div>
-        </div>
-    </div>
-
-    <script src="https://cdn.jsdelivr.net/npm/marked@12.0.2/marked.min.js"></script>
-    <script src="https://cdn.jsdelivr.net/npm/highlight.js@11.9.0/lib/highlight.min.js"></script>
-    <script>
-        let ws = null;
-        let connected = false;
-        let streamingMessage = null;
-        let streamingContent = '';
-        let isComposing = false; // 输入法组合状态
-        const messagesDiv = document.getElementById('chatMessages');
-        const chatInput = document.getElementById('chatInput');
-        const sendButton = document.getElementById('sendButton');
-        const statusDot = document.getElementById('statusDot');
-        const statusText = document.getElementById('statusText');
-        const typingIndicator = document.getElementById('typingIndicator');
-
-        marked.setOptions({
-            highlight: function(code, lang) {
-                if (lang && hljs.getLanguage(lang)) {
-                    return hljs.highlight(code, { language: lang }).value;
-                }
-                return hljs.highlightAuto(code).value;
-            },
-            breaks: true,
-            gfm: true
-        });
-
-        // 监听输入法组合事件
-        chatInput.addEventListener('compositionstart', function() {
-            isComposing = true;
-        });
-        chatInput.addEventListener('compositionend', function() {
-            isComposing = false;
-        });
-
-        // 使用 addEventListener 监听键盘事件
-        chatInput.addEventListener('keydown', function(event) {
-            handleKeyDown(event);
-        });
-
-        // 自动调整输入框高度
-        chatInput.addEventListener('input', function() {
-            this.style.height = 'auto';
-            this.style.height = Math.min(this.scrollHeight, 120) + 'px';
-        });
-
-        function connect() {
-            const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-            const wsUrl = protocol + '//' + window.location.host + '/ws';
-
-            ws = new WebSocket(wsUrl);
-
-            ws.onopen = function() {
-                connected = true;
-                statusDot.classList.remove('disconnected');
-                statusText.textContent = '已连接';
-                console.log('WebSocket 已连接');
-            };
-
-            ws.onclose = function() {
-                connected = false;
-                statusDot.classList.add('disconnected');
-                statusText.textContent = '已断开';
-                console.log('WebSocket 已断开');
-
-                // 5秒后重连
-                setTimeout(connect, 5000);
-            };
-
-            ws.onerror = function(error) {
-                console.error('WebSocket 错误:', error);
-            };
-
-            ws.onmessage = function(event) {
-                const data = JSON.parse(event.data);
-
-                if (data.type === 'stream') {
-                    // 真正的流式消息（打字机效果）
-                    handleStreamMessage(data);
-                } else if (data.type === 'message') {
-                    // 完整消息 - 使用前端打字机效果
-                    typewriterMessage('assistant', data.content, data.time);
-                }
-            };
-        }
-
-        function handleStreamMessage(data) {
-            // 如果是新消息开始，创建消息气泡
-            if (!streamingMessage) {
-                hideTyping();
-                streamingMessage = createMessageBubble('assistant');
-                streamingContent = '';
-            }
-
-            // 追加内容
-            if (data.delta) {
-                streamingContent += data.delta;
-                updateMessageContent(streamingMessage, streamingContent);
-            }
-
-            // 如果消息完成
-            if (data.done) {
-                // 移除光标，添加时间戳
-                finishMessage(streamingMessage, data.time || new Date().toLocaleTimeString('zh-CN', { hour: '2-digit', minute: '2-digit', second: '2-digit' }));
-                streamingMessage = null;
-                streamingContent = '';
-            }
-        }
-
-        function createMessageBubble(role) {
-            // 移除欢迎消息
-            const welcome = document.querySelector('.welcome-message');
-            if (welcome) {
-                welcome.remove();
-            }
-
-            const messageDiv = document.createElement('div');
-            messageDiv.className = 'message ' + role;
-
-            const bubbleDiv = document.createElement('div');
-            bubbleDiv.className = role === 'assistant' ? 'message-bubble markdown-body' : 'message-bubble';
-
-            // 添加闪烁光标
-            const cursor = document.createElement('span');
-            cursor.className = 'cursor';
-            bubbleDiv.appendChild(cursor);
-
-            messageDiv.appendChild(bubbleDiv);
-            messagesDiv.appendChild(messageDiv);
-
-            // 滚动到底部
-            messagesDiv.scrollTop = messagesDiv.scrollHeight;
-
-            return { div: messageDiv, bubble: bubbleDiv };
-        }
-
-        function renderMarkdown(content) {
-            return marked.parse(content || '');
-        }
-
-        function setBubbleContent(bubble, content) {
-            if (bubble.classList.contains('markdown-body')) {
-                bubble.innerHTML = renderMarkdown(content);
-            } else {
-                bubble.textContent = content;
-            }
-        }
-
-        function updateMessageContent(msgObj, content) {
-            // 保留光标
-            const cursor = msgObj.bubble.querySelector('.cursor');
-            setBubbleContent(msgObj.bubble, content);
-            if (cursor) {
-                msgObj.bubble.appendChild(cursor);
-            }
-
-            // 滚动到底部
-            messagesDiv.scrollTop = messagesDiv.scrollHeight;
-        }
-
-        function finishMessage(msgObj, time) {
-            // 移除光标
-            const cursor = msgObj.bubble.querySelector('.cursor');
-            if (cursor) {
-                cursor.remove();
-            }
-
-            // 添加时间戳
-            const timeDiv = document.createElement('div');
-            timeDiv.className = 'message-time';
-            timeDiv.textContent = time;
-            msgObj.div.appendChild(timeDiv);
-        }
-
-        function sendMessage() {
-            const content = chatInput.value.trim();
-            if (!content || !connected) return;
-
-            addMessage('user', content);
-            chatInput.value = '';
-            chatInput.style.height = 'auto';
-
-            ws.send(JSON.stringify({ content: content }));
-            showTyping();
-        }
-
-        function sendTip(text) {
-            chatInput.value = text;
-            sendMessage();
-        }
-
-        function addMessage(role, content, time) {
-            // 移除欢迎消息
-            const welcome = document.querySelector('.welcome-message');
-            if (welcome) {
-                welcome.remove();
-            }
-
-            const messageDiv = document.createElement('div');
-            messageDiv.className = 'message ' + role;
-
-            const bubbleDiv = document.createElement('div');
-            bubbleDiv.className = role === 'assistant' ? 'message-bubble markdown-body' : 'message-bubble';
-            setBubbleContent(bubbleDiv, content);
-
-            const timeDiv = document.createElement('div');
-            timeDiv.className = 'message-time';
-            timeDiv.textContent = time || new Date().toLocaleTimeString('zh-CN', { hour: '2-digit', minute: '2-digit', second: '2-digit' });
-
-            messageDiv.appendChild(bubbleDiv);
-            messageDiv.appendChild(timeDiv);
-            messagesDiv.appendChild(messageDiv);
-
-            // 滚动到底部
-            messagesDiv.scrollTop = messagesDiv.scrollHeight;
-        }
-
-        // 打字机效果显示消息
-        function typewriterMessage(role, content, time) {
-            hideTyping();
-
-            // 移除欢迎消息
-            const welcome = document.querySelector('.welcome-message');
-            if (welcome) {
-                welcome.remove();
-            }
-
-            const messageDiv = document.createElement('div');
-            messageDiv.className = 'message ' + role;
-
-            const bubbleDiv = document.createElement('div');
-            bubbleDiv.className = role === 'assistant' ? 'message-bubble markdown-body' : 'message-bubble';
-
-            // 添加闪烁光标
-            const cursor = document.createElement('span');
-            cursor.className = 'cursor';
-            bubbleDiv.appendChild(cursor);
-
-            const timeDiv = document.createElement('div');
-            timeDiv.className = 'message-time';
-            timeDiv.textContent = time || new Date().toLocaleTimeString('zh-CN', { hour: '2-digit', minute: '2-digit', second: '2-digit' });
-
-            messageDiv.appendChild(bubbleDiv);
-            messageDiv.appendChild(timeDiv);
-            messagesDiv.appendChild(messageDiv);
-
-            // 打字机效果
-            let index = 0;
-            const speed = 20; // 每个字符的延迟（毫秒）
-
-            function type() {
-                if (index < content.length) {
-                    bubbleDiv.textContent = content.substring(0, index + 1);
-                    bubbleDiv.appendChild(cursor);
-                    index++;
-                    messagesDiv.scrollTop = messagesDiv.scrollHeight;
-                    setTimeout(type, speed);
-                } else {
-                    // 完成，移除光标
-                    cursor.remove();
-                    if (role === 'assistant') {
-                        setBubbleContent(bubbleDiv, content);
-                    }
-                }
-            }
-
-            type();
-        }
-
-        function showTyping() {
-            typingIndicator.classList.add('show');
-            messagesDiv.scrollTop = messagesDiv.scrollHeight;
-        }
-
-        function hideTyping() {
-            typingIndicator.classList.remove('show');
-        }
-
-        function handleKeyDown(event) {
-            // 如果正在输入法组合中（如选中文本），不处理回车
-            // 检测方式：
-            // 1. 自定义 isComposing 标记（compositionstart/end 事件）
-            // 2. 原生 event.isComposing 属性
-            // 3. keyCode === 229（IME 激活时的特殊码）
-            if (isComposing || event.isComposing || event.keyCode === 229) {
-                return;
-            }
-            if (event.key === 'Enter' && !event.shiftKey) {
-                event.preventDefault();
-                sendMessage();
-            }
-        }
-
-        // 启动连接
-        connect();
-
-        // 聚焦输入框
-        chatInput.focus();
-    </script>
-</body>
-</html>
-`
+// generateChatID 生成会话 ID 和 chatID
+// 页面通过 session 参数带回之前分配的会话 ID 时沿用，否则分配新的随机 ID
+// userID 不为空时 chatID 按用户隔离，其他用户即使持有会话 ID 也无法访问该会话的历史和回复
+func generateChatID(r *http.Request, userID string) (sessionID, chatID string) {
+	prefix := "ws_"
+	if userID != "" {
+		prefix += userID + "_"
+	}
+	sessionID = r.URL.Query().Get("session")
+	if !sessionIDPattern.MatchString(sessionID) {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			// 随机数不可用时退回按连接地址区分
+			return "", fmt.Sprintf("%s%s", prefix, r.RemoteAddr)
+		}
+		sessionID = hex.EncodeToString(buf)
+	}
+	return sessionID, prefix + sessionID
+}
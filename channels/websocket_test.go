@@ -2,11 +2,19 @@ package channels
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/weibaohui/nanobot-go/bus"
+	"github.com/weibaohui/nanobot-go/utils"
 	"go.uber.org/zap"
 )
 
@@ -62,9 +70,9 @@ func TestTruncate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := truncate(tt.input, tt.maxLen)
+			result := utils.TruncateString(tt.input, tt.maxLen)
 			if result != tt.expected {
-				t.Errorf("truncate(%q, %d) = %q, 期望 %q", tt.input, tt.maxLen, result, tt.expected)
+				t.Errorf("TruncateString(%q, %d) = %q, 期望 %q", tt.input, tt.maxLen, result, tt.expected)
 			}
 		})
 	}
@@ -72,23 +80,45 @@ func TestTruncate(t *testing.T) {
 
 // TestGenerateChatID 测试生成 ChatID
 func TestGenerateChatID(t *testing.T) {
-	t.Run("普通地址", func(t *testing.T) {
+	t.Run("新连接分配随机会话", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/ws", nil)
 		req.RemoteAddr = "192.168.1.1:12345"
 
-		result := generateChatID(req)
-		if result[:3] != "ws_" {
-			t.Errorf("generateChatID() = %q, 应以 ws_ 开头", result)
+		sessionID, chatID := generateChatID(req, "")
+		if !sessionIDPattern.MatchString(sessionID) {
+			t.Errorf("sessionID = %q, 应为 32 位十六进制", sessionID)
+		}
+		if chatID != "ws_"+sessionID {
+			t.Errorf("chatID = %q, 期望 ws_%s", chatID, sessionID)
+		}
+		if other, _ := generateChatID(req, ""); other == sessionID {
+			t.Error("每次分配的会话 ID 应不同")
 		}
 	})
 
-	t.Run("本地地址", func(t *testing.T) {
-		req := httptest.NewRequest("GET", "/ws", nil)
-		req.RemoteAddr = "127.0.0.1:8080"
+	t.Run("沿用页面保存的会话", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/ws?session=0123456789abcdef0123456789abcdef", nil)
+
+		sessionID, chatID := generateChatID(req, "")
+		if sessionID != "0123456789abcdef0123456789abcdef" || chatID != "ws_0123456789abcdef0123456789abcdef" {
+			t.Errorf("generateChatID() = (%q, %q)", sessionID, chatID)
+		}
+	})
+
+	t.Run("带身份的连接按用户隔离", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/ws?session=0123456789abcdef0123456789abcdef", nil)
 
-		result := generateChatID(req)
-		if result[:3] != "ws_" {
-			t.Errorf("generateChatID() = %q, 应以 ws_ 开头", result)
+		_, chatID := generateChatID(req, "alice")
+		if chatID != "ws_alice_0123456789abcdef0123456789abcdef" {
+			t.Errorf("chatID = %q, 期望按用户隔离", chatID)
+		}
+	})
+
+	t.Run("忽略格式无效的会话", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/ws?session=../admin", nil)
+
+		if sessionID, _ := generateChatID(req, ""); sessionID == "../admin" {
+			t.Error("格式无效的会话 ID 不应被沿用")
 		}
 	})
 }
@@ -228,26 +258,350 @@ func TestTruncate_EdgeCases(t *testing.T) {
 		{"零长度", "Hello", 0, "..."},
 		{"长度为3", "Hello", 3, "Hel..."},
 		{"刚好长度4", "Hello", 4, "Hell..."},
-		{"中文(按字节截断)", "你好世界", 6, "你好..."},
+		{"中文按字符截断", "你好世界", 2, "你好..."},
+		{"中文刚好等于最大长度", "你好世界", 4, "你好世界"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := truncate(tt.input, tt.maxLen)
+			result := utils.TruncateString(tt.input, tt.maxLen)
 			if result != tt.expected {
-				t.Errorf("truncate(%q, %d) = %q, 期望 %q", tt.input, tt.maxLen, result, tt.expected)
+				t.Errorf("TruncateString(%q, %d) = %q, 期望 %q", tt.input, tt.maxLen, result, tt.expected)
 			}
 		})
 	}
 }
 
-// TestGenerateChatID_IPv6 测试 IPv6 地址
-func TestGenerateChatID_IPv6(t *testing.T) {
-	req := httptest.NewRequest("GET", "/ws", nil)
-	req.RemoteAddr = "[::1]:8080"
+// TestCheckOrigin 测试 WebSocket 来源校验
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{"未携带 Origin", "localhost:8088", "", nil, true},
+		{"默认允许同源", "localhost:8088", "http://localhost:8088", nil, true},
+		{"默认拒绝跨站", "localhost:8088", "https://evil.example.com", nil, false},
+		{"非法 Origin", "localhost:8088", "null", nil, false},
+		{"白名单匹配", "10.0.0.1:8088", "https://chat.example.com", []string{"https://chat.example.com/"}, true},
+		{"白名单不匹配", "10.0.0.1:8088", "https://evil.example.com", []string{"https://chat.example.com"}, false},
+		{"配置后不再默认放行同源", "localhost:8088", "http://localhost:8088", []string{"https://chat.example.com"}, false},
+		{"通配符允许所有来源", "localhost:8088", "https://evil.example.com", []string{"*"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/ws", nil)
+			r.Host = tt.host
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := checkOrigin(r, tt.allowed); got != tt.want {
+				t.Errorf("checkOrigin(%q) = %v, 期望 %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWebSocketChannel_Auth 测试 WebSocket 令牌认证与按令牌区分身份
+func TestWebSocketChannel_Auth(t *testing.T) {
+	messageBus := bus.NewMessageBus(zap.NewNop())
+	ch := NewWebSocketChannel(&WebSocketConfig{
+		AuthToken:  "shared",
+		AuthTokens: map[string]string{"alice-token": "alice"},
+		AllowFrom:  []string{"alice"},
+	}, messageBus, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(ch.handleWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	t.Run("未携带令牌时拒绝连接", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("期望 401, got resp = %v, err = %v", resp, err)
+		}
+	})
+
+	t.Run("令牌错误时拒绝连接", func(t *testing.T) {
+		header := http.Header{"Authorization": {"Bearer wrong"}}
+		if _, _, err := websocket.DefaultDialer.Dial(wsURL, header); err == nil {
+			t.Fatal("令牌错误时应拒绝连接")
+		}
+	})
+
+	t.Run("带身份令牌的发送者为对应用户", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=alice-token", nil)
+		if err != nil {
+			t.Fatalf("连接失败: %v", err)
+		}
+		defer conn.Close()
+		if err := conn.WriteJSON(map[string]string{"content": "你好"}); err != nil {
+			t.Fatalf("发送失败: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		msg, err := messageBus.ConsumeInbound(ctx)
+		if err != nil {
+			t.Fatalf("未收到入站消息: %v", err)
+		}
+		if msg.SenderID != "alice" {
+			t.Errorf("SenderID = %q, 期望 alice", msg.SenderID)
+		}
+	})
+
+	t.Run("共享令牌的连接不在允许列表中", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=shared", nil)
+		if err != nil {
+			t.Fatalf("连接失败: %v", err)
+		}
+		defer conn.Close()
+		if err := conn.WriteJSON(map[string]string{"content": "你好"}); err != nil {
+			t.Fatalf("发送失败: %v", err)
+		}
+		var reply map[string]any
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		// 跳过连接时下发的会话消息
+		for reply["type"] != "message" {
+			if err := conn.ReadJSON(&reply); err != nil {
+				t.Fatalf("读取回复失败: %v", err)
+			}
+		}
+		if content, _ := reply["content"].(string); !strings.Contains(content, "没有权限") {
+			t.Errorf("回复 = %v, 期望权限提示", reply)
+		}
+	})
+}
+
+// TestWebSocketChannel_IndexPage 测试聊天页面模板渲染
+func TestWebSocketChannel_IndexPage(t *testing.T) {
+	render := func(config *WebSocketConfig) string {
+		t.Helper()
+		ch := NewWebSocketChannel(config, bus.NewMessageBus(zap.NewNop()), zap.NewNop())
+		rec := httptest.NewRecorder()
+		ch.handleIndex(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Body.String()
+	}
+
+	t.Run("内置页面使用默认变量", func(t *testing.T) {
+		page := render(&WebSocketConfig{Path: "/chat"})
+		for _, want := range []string{"<h1>nanobot</h1>", "--theme-color: #667eea", "--accent-color: #764ba2", `"/chat"`, "打个招呼"} {
+			if !strings.Contains(page, want) {
+				t.Errorf("页面缺少 %q", want)
+			}
+		}
+	})
+
+	t.Run("自定义标题和主题色", func(t *testing.T) {
+		page := render(&WebSocketConfig{UI: WebSocketUIConfig{Title: "小助手", Logo: "🤖", ThemeColor: "#ff6600"}})
+		for _, want := range []string{"<h1>小助手</h1>", "🤖", "--theme-color: #ff6600"} {
+			if !strings.Contains(page, want) {
+				t.Errorf("页面缺少 %q", want)
+			}
+		}
+	})
+
+	t.Run("品牌配置", func(t *testing.T) {
+		page := render(&WebSocketConfig{UI: WebSocketUIConfig{
+			AccentColor: "rgb(10, 20, 30)",
+			Welcome:     "欢迎来到客服中心",
+			Tips:        []WebSocketUITip{{Label: "查订单", Message: "帮我查一下'订单'"}},
+		}})
+		for _, want := range []string{"--accent-color: rgb(10, 20, 30)", "<p>欢迎来到客服中心</p>", ">查订单</span>"} {
+			if !strings.Contains(page, want) {
+				t.Errorf("页面缺少 %q", want)
+			}
+		}
+		if strings.Contains(page, "打个招呼") {
+			t.Error("配置了快捷提示时不应显示内置提示")
+		}
+		if strings.Contains(page, "帮我查一下'订单'") {
+			t.Error("提示消息应按 JS 字符串转义")
+		}
+	})
+
+	t.Run("无效主题色回退默认值", func(t *testing.T) {
+		page := render(&WebSocketConfig{UI: WebSocketUIConfig{ThemeColor: "red; } body { display: none"}})
+		if !strings.Contains(page, "--theme-color: #667eea") {
+			t.Error("无效主题色应回退为默认值")
+		}
+	})
+
+	t.Run("自定义模板覆盖内置页面", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>{{.Title}} {{.WSPath}}</p>"), 0644)
+		page := render(&WebSocketConfig{UI: WebSocketUIConfig{Dir: dir, Title: "自定义"}})
+		if page != "<p>自定义 /ws</p>" {
+			t.Errorf("页面 = %q", page)
+		}
+	})
+
+	t.Run("自定义模板有误时回退内置页面", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>{{.Title</p>"), 0644)
+		page := render(&WebSocketConfig{UI: WebSocketUIConfig{Dir: dir}})
+		if !strings.Contains(page, "<h1>nanobot</h1>") {
+			t.Error("模板解析失败时应使用内置页面")
+		}
+	})
+}
+
+// TestWebSocketChannel_History 测试连接时下发会话 ID 和历史消息
+func TestWebSocketChannel_History(t *testing.T) {
+	ch := NewWebSocketChannel(&WebSocketConfig{HistoryLimit: 2}, bus.NewMessageBus(zap.NewNop()), zap.NewNop())
+	var gotChatID string
+	ch.SetHistoryProvider(func(ctx context.Context, chatID string, limit int) []HistoryMessage {
+		gotChatID = chatID
+		return []HistoryMessage{{Role: "user", Content: "你好"}, {Role: "assistant", Content: "你好，有什么可以帮你？"}}[:limit]
+	})
+	server := httptest.NewServer(http.HandlerFunc(ch.handleWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	readSession := func(url string) (string, []HistoryMessage) {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("连接失败: %v", err)
+		}
+		defer conn.Close()
+		var msg struct {
+			Type     string           `json:"type"`
+			Session  string           `json:"session"`
+			Messages []HistoryMessage `json:"messages"`
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("读取会话消息失败: %v", err)
+		}
+		if msg.Type != "session" {
+			t.Fatalf("Type = %q, 期望 session", msg.Type)
+		}
+		return msg.Session, msg.Messages
+	}
+
+	session, _ := readSession(wsURL)
+	again, messages := readSession(wsURL + "?session=" + session)
+	if again != session {
+		t.Errorf("重新连接的会话 = %q, 期望沿用 %q", again, session)
+	}
+	if gotChatID != "ws_"+session {
+		t.Errorf("查询历史的 chatID = %q", gotChatID)
+	}
+	if len(messages) != 2 || messages[1].Role != "assistant" {
+		t.Errorf("历史消息 = %+v", messages)
+	}
+}
+
+// TestWebSocketChannel_sendToClient 测试向客户端发送消息
+func TestWebSocketChannel_sendToClient(t *testing.T) {
+	ch := NewWebSocketChannel(&WebSocketConfig{}, bus.NewMessageBus(zap.NewNop()), zap.NewNop())
+
+	t.Run("客户端未连接时返回错误", func(t *testing.T) {
+		if err := ch.sendToClient("ws_missing", "你好"); !errors.Is(err, errClientNotConnected) {
+			t.Errorf("sendToClient() error = %v, 期望 errClientNotConnected", err)
+		}
+	})
+
+	t.Run("并发发送消息和流式片段", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(ch.handleWebSocket))
+		defer server.Close()
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("连接失败: %v", err)
+		}
+		defer conn.Close()
 
-	result := generateChatID(req)
-	if result[:3] != "ws_" {
-		t.Errorf("generateChatID() = %q, 应以 ws_ 开头", result)
+		var session struct {
+			Session string `json:"session"`
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.ReadJSON(&session); err != nil {
+			t.Fatalf("读取会话消息失败: %v", err)
+		}
+		chatID := "ws_" + session.Session
+
+		const n = 20
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if err := ch.sendToClient(chatID, "消息"); err != nil {
+					t.Errorf("sendToClient() 返回错误: %v", err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if err := ch.sendStreamChunk(chatID, &bus.StreamChunk{ChatID: chatID, Delta: "片段"}); err != nil {
+					t.Errorf("sendStreamChunk() 返回错误: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// 每条消息都应是完整的 JSON 帧
+		for i := 0; i < 2*n; i++ {
+			var msg map[string]any
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("读取第 %d 条消息失败: %v", i+1, err)
+			}
+		}
+	})
+}
+
+// TestWebSocketChannel_SessionOwner 测试带身份的连接不能沿用其他用户的会话
+func TestWebSocketChannel_SessionOwner(t *testing.T) {
+	ch := NewWebSocketChannel(&WebSocketConfig{
+		AuthTokens:   map[string]string{"alice-token": "alice", "bob-token": "bob"},
+		HistoryLimit: 10,
+	}, bus.NewMessageBus(zap.NewNop()), zap.NewNop())
+	var mu sync.Mutex
+	var historyChatIDs []string
+	ch.SetHistoryProvider(func(ctx context.Context, chatID string, limit int) []HistoryMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		historyChatIDs = append(historyChatIDs, chatID)
+		return nil
+	})
+	server := httptest.NewServer(http.HandlerFunc(ch.handleWebSocket))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	readSession := func(url string) string {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("连接失败: %v", err)
+		}
+		defer conn.Close()
+		var msg struct {
+			Session string `json:"session"`
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("读取会话消息失败: %v", err)
+		}
+		return msg.Session
+	}
+
+	aliceSession := readSession(wsURL + "?token=alice-token")
+	if again := readSession(wsURL + "?token=alice-token&session=" + aliceSession); again != aliceSession {
+		t.Errorf("同一用户重新连接的会话 = %q, 期望沿用 %q", again, aliceSession)
+	}
+	if bobSession := readSession(wsURL + "?token=bob-token&session=" + aliceSession); bobSession == aliceSession {
+		t.Error("其他用户不应沿用 alice 的会话")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, chatID := range historyChatIDs[:2] {
+		if chatID != "ws_alice_"+aliceSession {
+			t.Errorf("alice 查询历史的 chatID = %q", chatID)
+		}
+	}
+	if got := historyChatIDs[2]; !strings.HasPrefix(got, "ws_bob_") {
+		t.Errorf("bob 查询历史的 chatID = %q, 期望以 ws_bob_ 开头", got)
 	}
 }
@@ -55,6 +55,11 @@ func GetSkillsPath(workspace string) string {
 	return dir
 }
 
+// ExpandPath 展开路径中的 ~ 为用户主目录
+func ExpandPath(path string) string {
+	return expandPath(path)
+}
+
 // expandPath 展开路径中的 ~ 为用户主目录
 func expandPath(path string) string {
 	if len(path) > 0 && path[0] == '~' {
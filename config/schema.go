@@ -2,19 +2,33 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/weibaohui/nanobot-go/utils"
 )
 
 // CompressConfig 对话压缩配置
 type CompressConfig struct {
-	Enabled     bool   `json:"enabled"`     // 是否启用压缩功能
-	MinMessages int    `json:"minMessages"` // 最小消息数量阈值（默认20）
-	MinTokens   int    `json:"minTokens"`   // 最小 Token 用量阈值（默认50000）
-	Model       string `json:"model"`       // 压缩使用的模型（默认使用默认模型）
-	MaxHistory  int    `json:"maxHistory"`  // 压缩后保留的最大历史消息数（默认5）
+	Enabled     bool   `json:"enabled"`           // 是否启用压缩功能
+	MinMessages int    `json:"minMessages"`       // 最小消息数量阈值（默认20）
+	MinTokens   int    `json:"minTokens"`         // 最小 Token 用量阈值（默认50000）
+	Model       string `json:"model"`             // 压缩使用的模型（默认使用默认模型），建议配置较便宜的小模型
+	APIKey      string `json:"apiKey,omitempty"`  // 压缩模型专用 API Key，为空时按模型名匹配提供商
+	APIBase     string `json:"apiBase,omitempty"` // 压缩模型专用 API Base，仅在配置 APIKey 时生效
+	MaxHistory  int    `json:"maxHistory"`        // 压缩后保留的最大历史消息数（默认5）
+}
+
+// TasksConfig 后台任务配置
+type TasksConfig struct {
+	MaxConcurrentTasks int    `json:"maxConcurrentTasks"` // 最大并发任务数（默认3）
+	MaxQueuedTasks     int    `json:"maxQueuedTasks"`     // 并发已满时最多排队的任务数（0 表示不排队，直接拒绝）
+	Store              string `json:"store,omitempty"`    // 任务持久化存储: yaml（默认，按天写入文件）或 sqlite（建立索引，适合大量历史任务）
 }
 
 // ThinkingProcessConfig 思考过程配置
@@ -22,6 +36,9 @@ type CompressConfig struct {
 type ThinkingProcessConfig struct {
 	Enabled bool     `json:"enabled"` // 是否启用思考过程推送
 	Events  []string `json:"events"`  // 要监听的事件类型，如 ["tool_used", "tool_completed", "llm_call_end"]
+
+	ToolProgress bool `json:"toolProgress,omitempty"` // 是否以流式状态片段推送工具开始/结束进度
+	Reasoning    bool `json:"reasoning,omitempty"`    // 默认是否以 thinking 片段向用户展示推理模型的思考过程，会话中可用 /reasoning 覆盖
 }
 
 // DatabaseConfig 数据库配置
@@ -76,16 +93,118 @@ type MemoryStorageConfig struct {
 
 // Config 根配置结构
 type Config struct {
-	Agents          AgentsConfig          `json:"agents"`
-	Channels        ChannelsConfig        `json:"channels"`
-	Providers       ProvidersConfig       `json:"providers"`
-	Gateway         GatewayConfig         `json:"gateway"`
-	Tools           ToolsConfig           `json:"tools"`
-	Heartbeat       HeartbeatConfig       `json:"heartbeat"`
-	Compress        CompressConfig        `json:"compress"`
-	ThinkingProcess ThinkingProcessConfig `json:"thinkingProcess"` // 思考过程配置
-	Database        DatabaseConfig        `json:"database"`        // 数据库配置
-	Memory          MemoryConfig          `json:"memory"`          // 记忆模块配置
+	Agents          AgentsConfig             `json:"agents"`
+	Channels        ChannelsConfig           `json:"channels"`
+	Providers       ProvidersConfig          `json:"providers"`
+	Gateway         GatewayConfig            `json:"gateway"`
+	Tools           ToolsConfig              `json:"tools"`
+	Heartbeat       HeartbeatConfig          `json:"heartbeat"`
+	Compress        CompressConfig           `json:"compress"`
+	ThinkingProcess ThinkingProcessConfig    `json:"thinkingProcess"`    // 思考过程配置
+	Database        DatabaseConfig           `json:"database"`           // 数据库配置
+	Memory          MemoryConfig             `json:"memory"`             // 记忆模块配置
+	Tasks           TasksConfig              `json:"tasks"`              // 后台任务配置
+	Audit           AuditConfig              `json:"audit"`              // 消息审计日志配置
+	Delivery        DeliveryConfig           `json:"delivery"`           // 出站消息投递配置
+	Bus             BusConfig                `json:"bus"`                // 消息总线配置
+	Webhook         WebhookConfig            `json:"webhook"`            // 事件 Webhook 配置
+	Alerts          AlertsConfig             `json:"alerts"`             // 告警通知配置
+	LLMCache        LLMCacheConfig           `json:"llmCache"`           // LLM 响应缓存配置
+	Contacts        map[string]ContactConfig `json:"contacts,omitempty"` // 联系人别名，消息和广播工具可按名称指定目标
+	Digest          DigestConfig             `json:"digest"`             // 定时摘要配置
+	Log             LogConfig                `json:"log"`                // 日志输出配置
+	Sessions        SessionsConfig           `json:"sessions"`           // 会话配置
+}
+
+// SessionsConfig 会话配置
+type SessionsConfig struct {
+	ArchiveTranscripts bool `json:"archiveTranscripts"` // 清空会话前把对话记录导出为 Markdown，保存到工作区 transcripts 目录，默认关闭
+}
+
+// LogConfig 日志输出配置
+// 命令行 --debug 优先于配置的级别
+type LogConfig struct {
+	Level      string `json:"level,omitempty"`      // 日志级别: debug、info（默认）、warn、error
+	Format     string `json:"format,omitempty"`     // 输出格式: console（默认）或 json
+	Output     string `json:"output,omitempty"`     // 输出位置: stderr（默认）或 file
+	File       string `json:"file,omitempty"`       // 日志文件路径，output 为 file 时使用，默认工作区 .nanobot/logs/nanobot.log
+	MaxSizeMB  int    `json:"maxSizeMB,omitempty"`  // 单个日志文件的最大大小（MB），超过后轮转，默认 100
+	MaxBackups int    `json:"maxBackups,omitempty"` // 保留的轮转文件数，默认 5
+	MaxAgeDays int    `json:"maxAgeDays,omitempty"` // 轮转文件的保留天数，0 表示不按天数清理
+	Compress   bool   `json:"compress,omitempty"`   // 是否 gzip 压缩轮转文件
+}
+
+// DigestConfig 定时摘要配置
+// 按计划收集一段时间内的活动，交给 Agent 总结后发送到目标聊天
+type DigestConfig struct {
+	Enabled       bool   `json:"enabled"`                 // 是否启用，默认关闭
+	Schedule      string `json:"schedule,omitempty"`      // cron 表达式，默认 "0 18 * * *"（每天 18:00）
+	Timezone      string `json:"timezone,omitempty"`      // 调度时区，如 "Asia/Shanghai"，为空使用本地时区
+	Source        string `json:"source,omitempty"`        // 活动来源: sessions（默认，对话记录）、tasks（后台任务）、file:<工作区内文件路径>
+	LookbackHours int    `json:"lookbackHours,omitempty"` // 收集最近多少小时的活动，默认 24
+	Channel       string `json:"channel,omitempty"`       // 摘要发送的渠道
+	ChatID        string `json:"chatId,omitempty"`        // 摘要发送的聊天 ID
+	Contact       string `json:"contact,omitempty"`       // 联系人别名，配置后代替 channel/chatId
+	Prompt        string `json:"prompt,omitempty"`        // 总结提示词，为空使用默认提示词
+}
+
+// ContactConfig 联系人别名对应的消息目标
+type ContactConfig struct {
+	Channel string `json:"channel"` // 渠道名称，如 "matrix"
+	ChatID  string `json:"chatId"`  // 聊天 ID，如 Matrix 房间 ID
+}
+
+// LLMCacheConfig LLM 响应缓存配置
+// 启用后模型、消息、工具和采样参数完全相同的请求在有效期内直接返回缓存的回复；工具调用回合不使用缓存
+type LLMCacheConfig struct {
+	Enabled    bool `json:"enabled"`              // 是否启用，默认关闭
+	TTLSeconds int  `json:"ttlSeconds,omitempty"` // 缓存有效期（秒），默认 300
+	MaxEntries int  `json:"maxEntries,omitempty"` // 最多缓存的响应数，默认 256
+}
+
+// AlertsConfig 告警通知配置
+// 配置 Channel 和 ChatID 后，错误率过高、响应过慢等告警会通过消息总线发送给管理员
+type AlertsConfig struct {
+	Channel             string  `json:"channel,omitempty"`             // 接收告警的渠道，如 feishu
+	ChatID              string  `json:"chatId,omitempty"`              // 接收告警的聊天 ID
+	ErrorRate           float64 `json:"errorRate,omitempty"`           // 工具/模型调用错误率阈值（0-1），默认 0.5
+	MinSamples          int     `json:"minSamples,omitempty"`          // 计算错误率所需的最少调用次数，默认 10
+	WindowMinutes       int     `json:"windowMinutes,omitempty"`       // 错误率统计窗口（分钟），默认 10
+	SlowResponseSeconds int     `json:"slowResponseSeconds,omitempty"` // 单轮响应超过该时长（秒）视为过慢，默认 120
+	CooldownMinutes     int     `json:"cooldownMinutes,omitempty"`     // 同一类告警的最短间隔（分钟），默认 30
+}
+
+// WebhookConfig 事件 Webhook 配置
+// 配置 URL 后，Agent 生命周期事件（收发消息、工具调用、错误等）会以 JSON 批量 POST 到该地址
+type WebhookConfig struct {
+	URL             string            `json:"url,omitempty"`             // 接收事件的地址，为空表示不启用
+	Events          []string          `json:"events,omitempty"`          // 要推送的事件类型，为空时推送默认的生命周期事件
+	Headers         map[string]string `json:"headers,omitempty"`         // 附加请求头，如 Authorization
+	BatchSize       int               `json:"batchSize,omitempty"`       // 单次推送的最大事件数，默认 20
+	FlushIntervalMs int               `json:"flushIntervalMs,omitempty"` // 未攒满一批时的最长等待时间（毫秒），默认 2000
+	MaxAttempts     int               `json:"maxAttempts,omitempty"`     // 推送失败时的最大尝试次数（含首次），默认 3
+	Timeout         int               `json:"timeout,omitempty"`         // 单次请求超时时间（秒），默认 10
+}
+
+// BusConfig 消息总线配置
+type BusConfig struct {
+	InboundCapacity int  `json:"inboundCapacity,omitempty"` // 入站队列容量，默认 100
+	DropWhenFull    bool `json:"dropWhenFull,omitempty"`    // 队列已满时丢弃新消息并回复繁忙提示，默认阻塞渠道直到有空位
+}
+
+// AuditConfig 消息审计日志配置
+// 审计日志以 JSONL 格式写入工作区 .nanobot/audit/ 目录，按天轮转
+type AuditConfig struct {
+	Enabled        bool `json:"enabled"`        // 是否启用审计日志
+	IncludeContent bool `json:"includeContent"` // 是否记录消息原文（默认只记录内容哈希）
+}
+
+// DeliveryConfig 出站消息投递配置
+// 渠道发送失败时按指数退避重试，最终失败的消息写入 .nanobot/deadletter/dead-letter.jsonl
+type DeliveryConfig struct {
+	MaxAttempts  int `json:"maxAttempts,omitempty"`  // 最大尝试次数（含首次发送），默认 3，设为 1 表示不重试
+	BackoffMs    int `json:"backoffMs,omitempty"`    // 首次重试前等待的毫秒数，默认 1000，之后每次翻倍
+	MaxBackoffMs int `json:"maxBackoffMs,omitempty"` // 单次等待上限（毫秒），默认 30000
 }
 
 // HeartbeatConfig 心跳配置
@@ -108,17 +227,151 @@ type ActiveHours struct {
 
 // AgentsConfig 代理配置
 type AgentsConfig struct {
-	Defaults      AgentDefaults `json:"defaults"`
-	MaxIterations int           `json:"maxIterations"`
+	Defaults        AgentDefaults                `json:"defaults"`
+	MaxIterations   int                          `json:"maxIterations"`
+	ChannelPrompts  map[string]string            `json:"channelPrompts,omitempty"`  // 按渠道追加的系统提示，键为渠道名称，如 "feishu"
+	DeveloperPrompt string                       `json:"developerPrompt,omitempty"` // 开发者指引（如工具使用规范），始终附加在系统提示末尾
+	FastChat        FastChatConfig               `json:"fastChat"`                  // 闲聊快速模式配置
+	History         HistoryConfig                `json:"history"`                   // 每轮加载的会话历史窗口
+	Models          map[string]ModelCapabilities `json:"models,omitempty"`          // 按模型声明的能力，键为模型名称
+	Concurrency     int                          `json:"concurrency,omitempty"`     // 同时处理的入站消息数，同一会话的消息仍按顺序处理；小于等于 1 时逐条处理
+	Language        LanguageConfig               `json:"language"`                  // 回复语言配置
+	Roles           RoleModelsConfig             `json:"roles"`                     // 按用途配置的模型
+}
+
+// 模型用途
+const (
+	RoleChat     = "chat"     // 闲聊快速模式
+	RoleReact    = "react"    // 主 Agent 的工具调用循环
+	RoleTask     = "task"     // 后台任务
+	RoleCompress = "compress" // 对话压缩
+)
+
+// RoleModelsConfig 按用途配置模型，未配置的用途使用默认模型
+// 模型按名称匹配提供商，可以来自不同的提供商，例如闲聊使用便宜的小模型，工具调用和后台任务使用能力更强的模型
+type RoleModelsConfig struct {
+	Chat     string `json:"chat,omitempty"`     // 闲聊快速模式使用的模型
+	React    string `json:"react,omitempty"`    // 主 Agent 使用的模型
+	Task     string `json:"task,omitempty"`     // 后台任务使用的模型
+	Compress string `json:"compress,omitempty"` // 对话压缩使用的模型，compress.model 优先
+}
+
+// models 返回用途到模型的映射，包含未配置的用途
+func (c RoleModelsConfig) models() map[string]string {
+	return map[string]string{
+		RoleChat:     c.Chat,
+		RoleReact:    c.React,
+		RoleTask:     c.Task,
+		RoleCompress: c.Compress,
+	}
+}
+
+// LanguageConfig 回复语言配置
+type LanguageConfig struct {
+	Detect   bool   `json:"detect"`             // 检测用户消息的语言，提示模型使用相同的语言回复
+	Response string `json:"response,omitempty"` // 固定的回复语言，如 "英语"，配置后不再检测
+}
+
+// ModelCapabilities 模型能力声明
+type ModelCapabilities struct {
+	ToolCalling *bool `json:"toolCalling,omitempty"` // 是否支持原生工具调用（function calling），未配置视为支持
+}
+
+// SupportsToolCalling 返回配置中模型是否支持原生工具调用，未声明时视为支持
+func (c *Config) SupportsToolCalling(model string) bool {
+	if capabilities, ok := c.Agents.Models[model]; ok && capabilities.ToolCalling != nil {
+		return *capabilities.ToolCalling
+	}
+	return true
+}
+
+// DefaultHistoryMessages 未配置时每轮加载的最近历史消息数
+const DefaultHistoryMessages = 10
+
+// HistoryWindow 会话历史窗口
+type HistoryWindow struct {
+	MaxMessages int `json:"maxMessages,omitempty"` // 加载的最近历史消息数
+	MaxTokens   int `json:"maxTokens,omitempty"`   // 历史消息的估算 token 上限，超出时丢弃最早的消息，0 表示不限制
+}
+
+// HistoryConfig 会话历史窗口配置，可按渠道覆盖（有的渠道需要短上下文，有的需要长上下文）
+type HistoryConfig struct {
+	HistoryWindow
+	Channels map[string]HistoryWindow `json:"channels,omitempty"` // 按渠道覆盖，键为渠道名称，如 "feishu"
+}
+
+// Window 返回渠道使用的历史窗口：渠道未配置的项使用全局配置，消息数都未配置时为 DefaultHistoryMessages
+func (c HistoryConfig) Window(channel string) HistoryWindow {
+	window := c.HistoryWindow
+	if override, ok := c.Channels[channel]; ok {
+		if override.MaxMessages > 0 {
+			window.MaxMessages = override.MaxMessages
+		}
+		if override.MaxTokens > 0 {
+			window.MaxTokens = override.MaxTokens
+		}
+	}
+	if window.MaxMessages <= 0 {
+		window.MaxMessages = DefaultHistoryMessages
+	}
+	return window
+}
+
+// FastChatConfig 闲聊快速模式配置
+// 启用后问候、致谢等简短闲聊不加载工具和完整系统提示，直接调用模型回复以降低延迟
+type FastChatConfig struct {
+	Enabled  bool `json:"enabled"`            // 是否启用，默认关闭
+	MaxChars int  `json:"maxChars,omitempty"` // 视为闲聊的最大消息长度（字符），默认 30
 }
 
 // AgentDefaults 默认代理配置
 type AgentDefaults struct {
-	Workspace         string  `json:"workspace"`
-	Model             string  `json:"model"`
-	MaxTokens         int     `json:"maxTokens"`
-	Temperature       float64 `json:"temperature"`
-	MaxToolIterations int     `json:"maxToolIterations"`
+	Name               string   `json:"name,omitempty"` // Agent 名称，用于系统提示中的身份和日志，为空时为 nanobot
+	Workspace          string   `json:"workspace"`
+	WorkspaceIsolation string   `json:"workspaceIsolation,omitempty"` // 工作区隔离：channel 按渠道、sender 按渠道和发送者划分子目录，文件工具只能访问该子目录，优先于 tools.restrict；启用时不提供 exec 和插件工具；为空时共享工作区
+	Model              string   `json:"model"`
+	MaxTokens          int      `json:"maxTokens"`
+	Temperature        float64  `json:"temperature"`
+	MaxToolIterations  int      `json:"maxToolIterations"`
+	ToolErrorRetries   int      `json:"toolErrorRetries"`   // 单个回合内工具出错时反馈给模型重试的次数，0 表示不重试
+	ToolArgsRetries    int      `json:"toolArgsRetries"`    // 模型返回的工具调用参数不是合法 JSON 且无法修复时重新生成的次数，0 表示不重试
+	Timezone           string   `json:"timezone,omitempty"` // 默认时区，如 "Asia/Shanghai"，为空使用本地时区
+	Stop               []string `json:"stop,omitempty"`     // 默认停止序列，模型输出任一序列时停止生成，为空时不发送
+}
+
+// RoleModel 返回指定用途使用的模型，未配置该用途时返回默认模型
+func (c *Config) RoleModel(role string) string {
+	if model := strings.TrimSpace(c.Agents.Roles.models()[role]); model != "" {
+		return model
+	}
+	return c.Agents.Defaults.Model
+}
+
+// ValidateRoleModels 校验按用途配置的模型都能匹配到配置了 API Key 的提供商，未配置的用途不校验
+func (c *Config) ValidateRoleModels() error {
+	var errs []error
+	roles := c.Agents.Roles.models()
+	for _, role := range slices.Sorted(maps.Keys(roles)) {
+		model := strings.TrimSpace(roles[role])
+		if model == "" {
+			continue
+		}
+		if p := c.GetProvider(model); p == nil || p.APIKey == "" {
+			errs = append(errs, fmt.Errorf("%s 模型 %s 未匹配到配置了 API Key 的提供商", role, model))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DefaultAgentName 默认 Agent 名称
+const DefaultAgentName = "nanobot"
+
+// AgentName 返回 Agent 名称，未配置时返回 DefaultAgentName
+func (d AgentDefaults) AgentName() string {
+	if name := strings.TrimSpace(d.Name); name != "" {
+		return name
+	}
+	return DefaultAgentName
 }
 
 // ChannelsConfig 渠道配置
@@ -129,57 +382,133 @@ type ChannelsConfig struct {
 	Matrix    MatrixConfig    `json:"matrix"`
 }
 
+// IsEnabled 判断指定名称的渠道是否已启用
+func (c ChannelsConfig) IsEnabled(name string) bool {
+	switch strings.ToLower(name) {
+	case "websocket":
+		return c.WebSocket.Enabled
+	case "feishu":
+		return c.Feishu.Enabled
+	case "dingtalk":
+		return c.DingTalk.Enabled
+	case "matrix":
+		return c.Matrix.Enabled
+	}
+	return false
+}
+
+// MissingCredentials 返回已启用渠道缺少的必填凭证，键为渠道名称，所有渠道齐全时返回空
+func (c ChannelsConfig) MissingCredentials() map[string][]string {
+	missing := make(map[string][]string)
+	require := func(channel string, enabled bool, fields map[string]string) {
+		if !enabled {
+			return
+		}
+		for _, name := range slices.Sorted(maps.Keys(fields)) {
+			if strings.TrimSpace(fields[name]) == "" {
+				missing[channel] = append(missing[channel], name)
+			}
+		}
+	}
+	require("websocket", c.WebSocket.Enabled, map[string]string{"addr": c.WebSocket.Addr})
+	require("feishu", c.Feishu.Enabled, map[string]string{"appId": c.Feishu.AppID, "appSecret": c.Feishu.AppSecret})
+	require("dingtalk", c.DingTalk.Enabled, map[string]string{"clientId": c.DingTalk.ClientID, "clientSecret": c.DingTalk.ClientSecret})
+	require("matrix", c.Matrix.Enabled, map[string]string{"homeserver": c.Matrix.Homeserver, "userId": c.Matrix.UserID, "token": c.Matrix.Token})
+	return missing
+}
+
+// PacingConfig 出站消息节奏配置
+// 在发送回复前等待一段时间，避免在社交场景中"秒回"
+type PacingConfig struct {
+	Mode       string `json:"mode,omitempty"`       // 延迟模式：空/off 不延迟，fixed 固定延迟，proportional 按内容长度延迟
+	DelayMs    int    `json:"delayMs,omitempty"`    // 固定延迟（毫秒），proportional 模式下作为基础延迟
+	PerCharMs  int    `json:"perCharMs,omitempty"`  // proportional 模式下每个字符增加的延迟（毫秒）
+	MaxDelayMs int    `json:"maxDelayMs,omitempty"` // 延迟上限（毫秒），0 表示不限制
+}
+
 // WebSocketConfig WebSocket 渠道配置
 type WebSocketConfig struct {
-	Enabled   bool     `json:"enabled"`
-	Addr      string   `json:"addr"`      // 监听地址，如 ":8088"
-	Path      string   `json:"path"`      // WebSocket 路径，如 "/ws"
-	AllowFrom []string `json:"allowFrom"` // 允许的用户 ID 列表
+	Enabled        bool              `json:"enabled"`
+	Addr           string            `json:"addr"`                     // 监听地址，如 ":8088"
+	Path           string            `json:"path"`                     // WebSocket 路径，如 "/ws"
+	AllowFrom      []string          `json:"allowFrom"`                // 允许的用户 ID 列表
+	Pacing         PacingConfig      `json:"pacing,omitempty"`         // 回复节奏配置
+	AllowedOrigins []string          `json:"allowedOrigins,omitempty"` // 允许连接的页面来源，为空时只允许同源，"*" 允许所有来源（仅用于本地开发）
+	AuthToken      string            `json:"authToken,omitempty"`      // 共享访问令牌，非空时连接需携带 ?token= 或 Authorization: Bearer
+	AuthTokens     map[string]string `json:"authTokens,omitempty"`     // 令牌到用户 ID 的映射，按令牌区分身份，AllowFrom 按用户 ID 校验
+	HistoryLimit   int               `json:"historyLimit,omitempty"`   // 页面连接时恢复的历史消息条数，默认 20，小于 0 时不恢复
+	UI             WebSocketUIConfig `json:"ui,omitempty"`             // 聊天页面配置
+}
+
+// WebSocketUIConfig WebSocket 聊天页面配置
+type WebSocketUIConfig struct {
+	Dir         string           `json:"dir,omitempty"`         // 自定义页面目录，存在 index.html 时替代内置页面，其他文件通过 /static/ 访问
+	Title       string           `json:"title,omitempty"`       // 页面标题，默认 nanobot
+	Logo        string           `json:"logo,omitempty"`        // 标题前的图标，默认 🐈
+	ThemeColor  string           `json:"themeColor,omitempty"`  // 主题色，默认 #667eea
+	AccentColor string           `json:"accentColor,omitempty"` // 渐变辅助色，默认 #764ba2
+	Welcome     string           `json:"welcome,omitempty"`     // 欢迎语，为空时使用内置欢迎语
+	Tips        []WebSocketUITip `json:"tips,omitempty"`        // 欢迎页快捷提示，为空时使用内置提示
+}
+
+// WebSocketUITip 欢迎页快捷提示
+type WebSocketUITip struct {
+	Label   string `json:"label"`   // 按钮文字，如 "👋 打个招呼"
+	Message string `json:"message"` // 点击后发送的消息
 }
 
 // FeishuConfig 飞书渠道配置
 type FeishuConfig struct {
-	Enabled           bool     `json:"enabled"`
-	AppID             string   `json:"appId"`
-	AppSecret         string   `json:"appSecret"`
-	EncryptKey        string   `json:"encryptKey"`
-	VerificationToken string   `json:"verificationToken"`
-	AllowFrom         []string `json:"allowFrom"`
+	Enabled           bool         `json:"enabled"`
+	AppID             string       `json:"appId"`
+	AppSecret         string       `json:"appSecret"`
+	EncryptKey        string       `json:"encryptKey"`
+	VerificationToken string       `json:"verificationToken"`
+	AllowFrom         []string     `json:"allowFrom"`
+	Pacing            PacingConfig `json:"pacing,omitempty"` // 回复节奏配置
 }
 
 // DingTalkConfig 钉钉渠道配置
 type DingTalkConfig struct {
-	Enabled      bool     `json:"enabled"`
-	ClientID     string   `json:"clientId"`
-	ClientSecret string   `json:"clientSecret"`
-	AllowFrom    []string `json:"allowFrom"`
+	Enabled      bool         `json:"enabled"`
+	ClientID     string       `json:"clientId"`
+	ClientSecret string       `json:"clientSecret"`
+	AllowFrom    []string     `json:"allowFrom"`
+	Pacing       PacingConfig `json:"pacing,omitempty"` // 回复节奏配置
 }
 
 // MatrixConfig Matrix 渠道配置
 type MatrixConfig struct {
-	Enabled    bool     `json:"enabled"`
-	Homeserver string   `json:"homeserver"` // Matrix 服务器地址，如 https://matrix.example.com
-	UserID     string   `json:"userId"`     // 用户 ID，如 @nanobot:example.com
-	Token      string   `json:"token"`      // 访问令牌
-	AllowFrom  []string `json:"allowFrom"`  // 允许的用户白名单
-	DataDir    string   `json:"dataDir"`    // 数据存储目录，用于持久化同步状态
+	Enabled        bool         `json:"enabled"`
+	Homeserver     string       `json:"homeserver"`               // Matrix 服务器地址，如 https://matrix.example.com
+	UserID         string       `json:"userId"`                   // 用户 ID，如 @nanobot:example.com
+	Token          string       `json:"token"`                    // 访问令牌
+	AllowFrom      []string     `json:"allowFrom"`                // 允许的用户白名单
+	DataDir        string       `json:"dataDir"`                  // 数据存储目录，用于持久化同步状态
+	CatchUpLimit   int          `json:"catchUpLimit,omitempty"`   // 重启后每个房间补处理停机期间消息的最大条数，默认 0 忽略停机期间的消息
+	MaxTypingRooms int          `json:"maxTypingRooms,omitempty"` // 同时显示 typing 状态的最大房间数，默认 50
+	Pacing         PacingConfig `json:"pacing,omitempty"`         // 回复节奏配置
 }
 
 // ProvidersConfig LLM 提供商配置
 type ProvidersConfig struct {
-	Anthropic   ProviderConfig `json:"anthropic"`
-	OpenAI      ProviderConfig `json:"openai"`
-	OpenRouter  ProviderConfig `json:"openrouter"`
-	DeepSeek    ProviderConfig `json:"deepseek"`
-	Groq        ProviderConfig `json:"groq"`
-	Zhipu       ProviderConfig `json:"zhipu"`
-	DashScope   ProviderConfig `json:"dashscope"`
-	VLLM        ProviderConfig `json:"vllm"`
-	Gemini      ProviderConfig `json:"gemini"`
-	Moonshot    ProviderConfig `json:"moonshot"`
-	MiniMax     ProviderConfig `json:"minimax"`
-	AiHubMix    ProviderConfig `json:"aihubmix"`
-	SiliconFlow ProviderConfig `json:"siliconflow"`
+	Anthropic      ProviderConfig `json:"anthropic"`
+	OpenAI         ProviderConfig `json:"openai"`
+	OpenRouter     ProviderConfig `json:"openrouter"`
+	DeepSeek       ProviderConfig `json:"deepseek"`
+	Groq           ProviderConfig `json:"groq"`
+	Zhipu          ProviderConfig `json:"zhipu"`
+	DashScope      ProviderConfig `json:"dashscope"`
+	VLLM           ProviderConfig `json:"vllm"`
+	Gemini         ProviderConfig `json:"gemini"`
+	Moonshot       ProviderConfig `json:"moonshot"`
+	MiniMax        ProviderConfig `json:"minimax"`
+	AiHubMix       ProviderConfig `json:"aihubmix"`
+	SiliconFlow    ProviderConfig `json:"siliconflow"`
+	Debug          bool           `json:"debug,omitempty"`          // 在 debug 日志级别记录发往提供商的原始请求和响应（已脱敏、超长截断）
+	LogProbs       bool           `json:"logProbs,omitempty"`       // 请求输出 token 的 logprobs 并随对话记录保存，用于调试提示词；会增加响应体积，默认关闭
+	TopLogProbs    int            `json:"topLogProbs,omitempty"`    // 开启 logProbs 时每个位置额外返回的候选 token 数（最多 20）
+	MaxConcurrency int            `json:"maxConcurrency,omitempty"` // 同时发往同一提供商的最大请求数，超出的请求排队等待；0 表示不限制
 }
 
 // ProviderConfig LLM 提供商配置
@@ -189,10 +518,45 @@ type ProviderConfig struct {
 	ExtraHeaders map[string]string `json:"extraHeaders"`
 }
 
+// Secrets 返回配置中的所有密钥（提供商 API Key 和额外请求头、渠道凭证），用于日志脱敏
+func (c *Config) Secrets() []string {
+	p := c.Providers
+	secrets := []string{
+		c.Channels.Feishu.AppSecret, c.Channels.Feishu.EncryptKey, c.Channels.Feishu.VerificationToken,
+		c.Channels.DingTalk.ClientSecret, c.Channels.Matrix.Token, c.Compress.APIKey,
+		c.Channels.WebSocket.AuthToken, c.Tools.Weather.APIKey, c.Memory.Embedding.APIKey,
+	}
+	providers := []ProviderConfig{
+		p.Anthropic, p.OpenAI, p.OpenRouter, p.DeepSeek, p.Groq, p.Zhipu, p.DashScope,
+		p.VLLM, p.Gemini, p.Moonshot, p.MiniMax, p.AiHubMix, p.SiliconFlow,
+	}
+	for _, provider := range providers {
+		secrets = append(secrets, provider.APIKey)
+		// 额外请求头常用于携带鉴权令牌
+		for _, v := range provider.ExtraHeaders {
+			secrets = append(secrets, v)
+		}
+	}
+	for token := range c.Channels.WebSocket.AuthTokens {
+		secrets = append(secrets, token)
+	}
+	for _, v := range c.Webhook.Headers {
+		secrets = append(secrets, v)
+	}
+	result := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		if s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
 // GatewayConfig 网关配置
 type GatewayConfig struct {
-	Host string `json:"host"`
-	Port int    `json:"port"`
+	Host            string `json:"host"`
+	Port            int    `json:"port"`
+	ShutdownTimeout int    `json:"shutdownTimeout,omitempty"` // 关闭时等待进行中回合完成的秒数，默认 30
 }
 
 // WebSearchConfig 网络搜索工具配置
@@ -210,11 +574,90 @@ type ExecToolConfig struct {
 	Timeout int `json:"timeout"`
 }
 
+// ReadFileToolConfig 读取文件工具配置
+type ReadFileToolConfig struct {
+	MaxBytes     int64 `json:"maxBytes,omitempty"`     // 允许读取的最大文件大小（字节），默认 1MB
+	HexdumpBytes int   `json:"hexdumpBytes,omitempty"` // 二进制文件返回十六进制预览的字节数，默认 256
+}
+
+// HTTPToolConfig 通用 HTTP 请求工具配置
+type HTTPToolConfig struct {
+	Enabled          bool     `json:"enabled"`          // 是否启用 http_request 工具
+	AllowedHosts     []string `json:"allowedHosts"`     // 允许访问的主机名，支持 "*.example.com"
+	MaxResponseBytes int      `json:"maxResponseBytes"` // 响应体最大字节数
+	Timeout          int      `json:"timeout"`          // 请求超时（秒）
+}
+
+// WeatherToolConfig 天气查询工具配置
+type WeatherToolConfig struct {
+	Provider        string `json:"provider,omitempty"`        // 天气服务: open-meteo（默认，无需 API Key）, openweathermap
+	APIKey          string `json:"apiKey,omitempty"`          // OpenWeatherMap API Key
+	Units           string `json:"units,omitempty"`           // 单位: metric（默认）, imperial
+	DefaultLocation string `json:"defaultLocation,omitempty"` // 未指定地点时使用的默认地点
+	Timeout         int    `json:"timeout,omitempty"`         // 请求超时（秒），默认 15
+}
+
+// TranslateToolConfig 翻译工具配置
+type TranslateToolConfig struct {
+	Model string `json:"model,omitempty"` // 翻译使用的模型（默认使用默认模型），建议配置较便宜的小模型
+}
+
+// MessageToolConfig 消息工具配置
+type MessageToolConfig struct {
+	DefaultChannel string `json:"defaultChannel,omitempty"` // 未指定目标时使用的渠道，如后台任务、定时任务中的通知
+	DefaultChatID  string `json:"defaultChatId,omitempty"`  // 未指定目标时使用的聊天 ID，需与 DefaultChannel 同时配置
+}
+
+// BroadcastToolConfig 广播工具配置
+type BroadcastToolConfig struct {
+	Enabled        bool                `json:"enabled"`                  // 是否启用 broadcast 工具
+	Groups         map[string][]string `json:"groups,omitempty"`         // 命名目标分组，如 {"ops": ["feishu:oc_123", "matrix:!room:example.org"]}
+	AllowedTargets []string            `json:"allowedTargets,omitempty"` // 分组之外允许的目标，"渠道:*" 允许该渠道下所有聊天
+	MaxTargets     int                 `json:"maxTargets,omitempty"`     // 单次广播最多发送的目标数，默认 20
+}
+
+// PluginToolsConfig 外部可执行文件插件工具配置
+type PluginToolsConfig struct {
+	Dir            string `json:"dir,omitempty"`  // 插件目录，为空时使用工作区下的 plugins 目录
+	Timeout        int    `json:"timeout"`        // 单次执行超时（秒），插件描述文件可单独覆盖
+	MaxOutputBytes int    `json:"maxOutputBytes"` // 标准输出最大字节数
+}
+
 // ToolsConfig 工具配置
 type ToolsConfig struct {
-	Web                 WebToolsConfig `json:"web"`
-	Exec                ExecToolConfig `json:"exec"`
-	RestrictToWorkspace bool           `json:"restrictToWorkspace"`
+	Web                 WebToolsConfig      `json:"web"`
+	Exec                ExecToolConfig      `json:"exec"`
+	ReadFile            ReadFileToolConfig  `json:"readFile,omitempty"`
+	HTTP                HTTPToolConfig      `json:"http"`
+	Weather             WeatherToolConfig   `json:"weather"`
+	Translate           TranslateToolConfig `json:"translate"`
+	Message             MessageToolConfig   `json:"message,omitempty"`
+	Broadcast           BroadcastToolConfig `json:"broadcast,omitempty"`
+	Plugins             PluginToolsConfig   `json:"plugins"`
+	RestrictToWorkspace bool                `json:"restrictToWorkspace"`
+	Restrict            ToolRestrictConfig  `json:"restrict,omitempty"` // 按工具类型覆盖 restrictToWorkspace
+	Enabled             []string            `json:"enabled,omitempty"`  // 启用的工具白名单（为空表示全部启用）
+	Disabled            []string            `json:"disabled,omitempty"` // 禁用的工具列表，优先级高于 Enabled
+}
+
+// ToolRestrictConfig 按工具类型覆盖 restrictToWorkspace，未配置的类型使用 restrictToWorkspace
+// 例如只读排查系统时允许读取工作区外的文件，写入和命令仍限制在工作区内
+// 启用 workspaceIsolation 时文件工具都限制在回合的独立工作区内，这里的覆盖不再生效
+type ToolRestrictConfig struct {
+	Read  *bool `json:"read,omitempty"`  // 读取类工具：read_file、list_dir
+	Write *bool `json:"write,omitempty"` // 写入类工具：write_file、edit_file、structured_edit、apply_patch
+	Exec  *bool `json:"exec,omitempty"`  // 命令执行工具：exec，拒绝引用工作区外路径的命令（只做词法检查，不是沙箱）
+}
+
+// Resolve 返回读取、写入、命令执行工具是否限制在工作区内，未配置的类型使用 defaultRestrict
+func (c ToolRestrictConfig) Resolve(defaultRestrict bool) (read, write, exec bool) {
+	pick := func(override *bool) bool {
+		if override != nil {
+			return *override
+		}
+		return defaultRestrict
+	}
+	return pick(c.Read), pick(c.Write), pick(c.Exec)
 }
 
 // DefaultConfig 返回默认配置
@@ -227,6 +670,8 @@ func DefaultConfig() *Config {
 				MaxTokens:         8192,
 				Temperature:       0.7,
 				MaxToolIterations: 20,
+				ToolErrorRetries:  2,
+				ToolArgsRetries:   1,
 			},
 		},
 		ThinkingProcess: ThinkingProcessConfig{
@@ -258,6 +703,14 @@ func DefaultConfig() *Config {
 			Exec: ExecToolConfig{
 				Timeout: 60,
 			},
+			HTTP: HTTPToolConfig{
+				MaxResponseBytes: 100000,
+				Timeout:          30,
+			},
+			Plugins: PluginToolsConfig{
+				Timeout:        30,
+				MaxOutputBytes: 50000,
+			},
 		},
 		Heartbeat: HeartbeatConfig{
 			Every:       "30m",
@@ -277,6 +730,10 @@ func DefaultConfig() *Config {
 			MaxOpenConns: 1, // SQLite 建议单连接
 			MaxIdleConns: 1,
 		},
+		Tasks: TasksConfig{
+			MaxConcurrentTasks: 3,
+			MaxQueuedTasks:     5,
+		},
 		Memory: MemoryConfig{
 			Enabled: false, // 默认关闭，需要手动启用
 			Summarization: SummarizationConfig{
@@ -345,54 +802,96 @@ func (c *Config) GetWorkspacePath() string {
 	return GetWorkspacePath(c.Agents.Defaults.Workspace)
 }
 
-// GetProvider 获取匹配的提供商配置
-func (c *Config) GetProvider(model string) *ProviderConfig {
-	modelLower := model
-	if modelLower == "" {
-		modelLower = c.Agents.Defaults.Model
+// providerMatcher 提供商的模型名匹配规则
+type providerMatcher struct {
+	name     string          // 提供商名称，模型名以 "名称/" 开头时直接选中
+	keywords []string        // 模型名中包含的关键词
+	prefixes []string        // 该平台托管的 "Org/Model" 形式模型的组织前缀
+	config   *ProviderConfig // 提供商配置
+}
+
+// providerMatchers 返回所有提供商的匹配规则，顺序即同等匹配程度下的优先级
+func (c *Config) providerMatchers() []providerMatcher {
+	p := &c.Providers
+	return []providerMatcher{
+		{name: "siliconflow", keywords: []string{"siliconflow"}, config: &p.SiliconFlow,
+			// SiliconFlow 支持多种开源模型如 Qwen/, deepseek-ai/, meta-llama/ 等
+			prefixes: []string{"Qwen/", "deepseek-ai/", "meta-llama/", "THUDM/", "mistralai/", "google/"}},
+		{name: "openai", keywords: []string{"openai", "gpt"}, config: &p.OpenAI},
+		{name: "openrouter", keywords: []string{"openrouter"}, config: &p.OpenRouter},
+		{name: "aihubmix", keywords: []string{"aihubmix"}, config: &p.AiHubMix},
+		{name: "anthropic", keywords: []string{"anthropic", "claude"}, config: &p.Anthropic},
+		{name: "deepseek", keywords: []string{"deepseek"}, config: &p.DeepSeek},
+		{name: "groq", keywords: []string{"groq"}, config: &p.Groq},
+		{name: "zhipu", keywords: []string{"zhipu", "glm"}, config: &p.Zhipu},
+		{name: "dashscope", keywords: []string{"dashscope", "qwen"}, config: &p.DashScope},
+		{name: "vllm", keywords: []string{"vllm"}, config: &p.VLLM},
+		{name: "gemini", keywords: []string{"gemini"}, config: &p.Gemini},
+		{name: "moonshot", keywords: []string{"moonshot", "kimi"}, config: &p.Moonshot},
+		{name: "minimax", keywords: []string{"minimax"}, config: &p.MiniMax},
 	}
+}
 
-	// 按关键词匹配（优先级从高到低）
-	providers := []struct {
-		name     string
-		keywords []string
-		config   ProviderConfig
-	}{
+// 模型名与提供商的匹配程度，数值越大越具体
+const (
+	matchNone    = iota
+	matchKeyword // 模型名包含提供商关键词
+	matchPrefix  // 模型名以提供商托管的组织前缀开头，如 Qwen/
+	matchName    // 模型名以提供商名称开头，如 openrouter/
+)
 
-		{"siliconflow", []string{"siliconflow"}, c.Providers.SiliconFlow},
-		{"openai", []string{"openai", "gpt"}, c.Providers.OpenAI},
+// match 返回模型名与提供商的匹配程度及命中关键词的长度
+func (m providerMatcher) match(model string) (level, keywordLen int) {
+	if idx := strings.Index(model, "/"); idx > 0 && strings.EqualFold(model[:idx], m.name) {
+		return matchName, len(m.name)
 	}
-
-	for _, p := range providers {
-		for _, kw := range p.keywords {
-			if utils.ContainsInsensitive(modelLower, kw) && p.config.APIKey != "" {
-				return &p.config
-			}
+	for _, prefix := range m.prefixes {
+		if len(model) >= len(prefix) && strings.EqualFold(model[:len(prefix)], prefix) {
+			return matchPrefix, len(prefix)
 		}
 	}
+	for _, kw := range m.keywords {
+		if utils.ContainsInsensitive(model, kw) && len(kw) > keywordLen {
+			level, keywordLen = matchKeyword, len(kw)
+		}
+	}
+	return level, keywordLen
+}
 
-	// TODO 配置中改为 可配置多个模型，存在配置的进行匹配
-	// 改成 多个列表配置，意思为可以从这些模型中任选一个
-	// 现在这个模式配置，要从模型名称进行识别，不友好
-	// 特殊处理：模型格式为 "Org/Model" 时，检查常见云平台
-	// SiliconFlow 支持多种开源模型如 Qwen/, deepseek-ai/, meta-llama/ 等
-	if c.Providers.SiliconFlow.APIKey != "" {
-		siliconflowPrefixes := []string{"Qwen/", "deepseek-ai/", "meta-llama/", "THUDM/", "mistralai/", "google/"}
-		for _, prefix := range siliconflowPrefixes {
-			if utils.ContainsInsensitive(modelLower, prefix) {
-				return &c.Providers.SiliconFlow
-			}
+// GetProvider 获取匹配的提供商配置
+// 只考虑配置了 API Key 的提供商；模型同时匹配多个提供商时按以下顺序选择最具体的：
+// 模型名以提供商名称开头 > 以提供商托管的组织前缀开头 > 包含提供商关键词（关键词越长越具体），
+// 匹配程度相同时优先选择配置了 APIBase 的提供商，再按 providerMatchers 中的顺序
+func (c *Config) GetProvider(model string) *ProviderConfig {
+	if model == "" {
+		model = c.Agents.Defaults.Model
+	}
+
+	var best *ProviderConfig
+	bestLevel, bestLen := matchNone, 0
+	for _, m := range c.providerMatchers() {
+		if m.config.APIKey == "" {
+			continue
 		}
+		level, keywordLen := m.match(model)
+		if level == matchNone {
+			continue
+		}
+		better := level > bestLevel ||
+			(level == bestLevel && keywordLen > bestLen) ||
+			(level == bestLevel && keywordLen == bestLen && best.APIBase == "" && m.config.APIBase != "")
+		if better {
+			best, bestLevel, bestLen = m.config, level, keywordLen
+		}
+	}
+	if best != nil {
+		return best
 	}
 
 	// 回退：按优先级检查有 API key 的提供商
-	fallbackOrder := []ProviderConfig{
-		c.Providers.SiliconFlow,
-		c.Providers.OpenAI,
-	}
-	for _, cfg := range fallbackOrder {
-		if cfg.APIKey != "" {
-			return &cfg
+	for _, m := range c.providerMatchers() {
+		if m.config.APIKey != "" {
+			return m.config
 		}
 	}
 
@@ -417,6 +916,109 @@ func (c *Config) GetAPIBase(model string) string {
 	return ""
 }
 
+// CompressModel 返回对话压缩使用的模型、API Key 和 API Base
+// 未配置 Compress.Model 时使用 roles.compress，都未配置时使用默认模型；配置了 Compress.APIKey 时使用独立的提供商，否则按模型名匹配提供商
+func (c *Config) CompressModel() (model, apiKey, apiBase string) {
+	model = c.Compress.Model
+	if model == "" {
+		model = c.RoleModel(RoleCompress)
+	}
+	if c.Compress.APIKey != "" {
+		apiKey, apiBase = c.Compress.APIKey, c.Compress.APIBase
+	} else if p := c.GetProvider(model); p != nil {
+		apiKey, apiBase = p.APIKey, p.APIBase
+	}
+	if apiBase == "" {
+		apiBase = "https://api.openai.com/v1"
+	}
+	return model, apiKey, apiBase
+}
+
+// TranslateModel 返回翻译工具使用的模型及其提供商的 API Key、API Base
+func (c *Config) TranslateModel() (model, apiKey, apiBase string) {
+	model = c.Tools.Translate.Model
+	if model == "" {
+		model = c.Agents.Defaults.Model
+	}
+	if p := c.GetProvider(model); p != nil {
+		apiKey, apiBase = p.APIKey, p.APIBase
+	}
+	if apiBase == "" {
+		apiBase = "https://api.openai.com/v1"
+	}
+	return model, apiKey, apiBase
+}
+
+// ValidateCompress 校验对话压缩配置，未启用压缩时直接返回 nil
+func (c *Config) ValidateCompress() error {
+	if !c.Compress.Enabled {
+		return nil
+	}
+	if c.Compress.MinMessages < 0 || c.Compress.MinTokens < 0 || c.Compress.MaxHistory < 0 {
+		return fmt.Errorf("压缩阈值和保留消息数不能为负数")
+	}
+	if c.Compress.MinMessages == 0 && c.Compress.MinTokens == 0 {
+		return fmt.Errorf("minMessages 和 minTokens 至少需要配置一个")
+	}
+	model, apiKey, _ := c.CompressModel()
+	if model == "" {
+		return fmt.Errorf("未配置压缩模型，也未配置默认模型")
+	}
+	if apiKey == "" {
+		return fmt.Errorf("未找到压缩模型 %s 的 API Key", model)
+	}
+	return nil
+}
+
+// ValidateMessageTarget 校验消息工具的默认目标，未配置时直接返回 nil
+func (c *Config) ValidateMessageTarget() error {
+	target := c.Tools.Message
+	if target.DefaultChannel == "" && target.DefaultChatID == "" {
+		return nil
+	}
+	if target.DefaultChannel == "" || target.DefaultChatID == "" {
+		return fmt.Errorf("defaultChannel 和 defaultChatId 需要同时配置")
+	}
+	if !c.Channels.IsEnabled(target.DefaultChannel) {
+		return fmt.Errorf("默认渠道 %s 不存在或未启用", target.DefaultChannel)
+	}
+	return nil
+}
+
+// ValidateContacts 校验联系人别名，目标不完整或渠道未启用时返回错误
+func (c *Config) ValidateContacts() error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(c.Contacts)) {
+		contact := c.Contacts[name]
+		switch {
+		case contact.Channel == "" || contact.ChatID == "":
+			errs = append(errs, fmt.Errorf("联系人 %s 需要同时配置 channel 和 chatId", name))
+		case !c.Channels.IsEnabled(contact.Channel):
+			errs = append(errs, fmt.Errorf("联系人 %s 的渠道 %s 不存在或未启用", name, contact.Channel))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DigestTarget 返回定时摘要的发送目标，配置了联系人时按联系人解析
+func (c *Config) DigestTarget() (channel, chatID string, err error) {
+	channel, chatID = c.Digest.Channel, c.Digest.ChatID
+	if c.Digest.Contact != "" {
+		contact, ok := c.Contacts[c.Digest.Contact]
+		if !ok {
+			return "", "", fmt.Errorf("联系人 %s 不存在", c.Digest.Contact)
+		}
+		channel, chatID = contact.Channel, contact.ChatID
+	}
+	if channel == "" || chatID == "" {
+		return "", "", fmt.Errorf("未配置摘要发送目标，需要配置 channel 和 chatId 或 contact")
+	}
+	if !c.Channels.IsEnabled(channel) {
+		return "", "", fmt.Errorf("摘要目标渠道 %s 不存在或未启用", channel)
+	}
+	return channel, chatID, nil
+}
+
 // GetDatabaseDataDir 获取数据库数据目录的完整路径
 // 数据目录位于 workspace 下的 Database.DataDir 子目录
 func (c *Config) GetDatabaseDataDir() string {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -276,6 +277,131 @@ func TestConfig_GetAPIBase(t *testing.T) {
 	}
 }
 
+// TestConfig_GetAPIBase_Precedence 测试模型同时匹配多个提供商时的选择顺序
+func TestConfig_GetAPIBase_Precedence(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Providers.OpenAI = ProviderConfig{APIKey: "openai-key", APIBase: "https://gpt-gateway.example.com/v1"}
+	cfg.Providers.OpenRouter = ProviderConfig{APIKey: "openrouter-key", APIBase: "https://openrouter.ai/api/v1"}
+	cfg.Providers.SiliconFlow = ProviderConfig{APIKey: "silicon-key", APIBase: "https://api.siliconflow.cn/v1"}
+	cfg.Providers.DashScope = ProviderConfig{APIKey: "dashscope-key", APIBase: "https://dashscope.aliyuncs.com/compatible-mode/v1"}
+	cfg.Providers.DeepSeek = ProviderConfig{APIKey: "deepseek-key", APIBase: "https://api.deepseek.com/v1"}
+
+	tests := []struct {
+		name  string
+		model string
+		want  string
+	}{
+		{"关键词匹配", "gpt-4o", "https://gpt-gateway.example.com/v1"},
+		{"提供商名称前缀优先于关键词", "openrouter/openai/gpt-4o", "https://openrouter.ai/api/v1"},
+		{"组织前缀优先于关键词", "Qwen/Qwen2.5-72B-Instruct", "https://api.siliconflow.cn/v1"},
+		{"无组织前缀时按关键词", "qwen-max", "https://dashscope.aliyuncs.com/compatible-mode/v1"},
+		{"组织前缀不区分大小写", "deepseek-ai/DeepSeek-V3", "https://api.siliconflow.cn/v1"},
+		{"较长的关键词更具体", "gpt-deepseek-distill", "https://api.deepseek.com/v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.GetAPIBase(tt.model); got != tt.want {
+				t.Errorf("GetAPIBase(%q) = %q, 期望 %q", tt.model, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("匹配程度相同时优先配置了APIBase的提供商", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Providers.Zhipu = ProviderConfig{APIKey: "zhipu-key"}
+		cfg.Providers.DashScope = ProviderConfig{APIKey: "dashscope-key", APIBase: "https://dashscope.example.com/v1"}
+		// glm 与 qwen 关键词长度相同
+		if got := cfg.GetAPIBase("glm-qwen-merge"); got != "https://dashscope.example.com/v1" {
+			t.Errorf("GetAPIBase() = %q", got)
+		}
+	})
+
+	t.Run("未配置API Key的提供商不参与匹配", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Providers.OpenAI = ProviderConfig{APIKey: "openai-key"}
+		cfg.Providers.OpenRouter = ProviderConfig{APIBase: "https://openrouter.ai/api/v1"}
+		if got := cfg.GetAPIKey("openrouter/anthropic/claude-3"); got != "openai-key" {
+			t.Errorf("GetAPIKey() = %q, 期望回退到 openai-key", got)
+		}
+	})
+}
+
+// TestConfig_CompressModel 测试压缩模型解析
+func TestConfig_CompressModel(t *testing.T) {
+	t.Run("未配置时使用默认模型和匹配的提供商", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Agents.Defaults.Model = "gpt-4o"
+		cfg.Providers.OpenAI.APIKey = "main-key"
+
+		model, apiKey, apiBase := cfg.CompressModel()
+		if model != "gpt-4o" || apiKey != "main-key" || apiBase != "https://api.openai.com/v1" {
+			t.Errorf("CompressModel() = %q, %q, %q", model, apiKey, apiBase)
+		}
+	})
+
+	t.Run("独立的压缩模型提供商", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Providers.OpenAI.APIKey = "main-key"
+		cfg.Compress.Model = "qwen-turbo"
+		cfg.Compress.APIKey = "compress-key"
+		cfg.Compress.APIBase = "https://compress.example.com/v1"
+
+		model, apiKey, apiBase := cfg.CompressModel()
+		if model != "qwen-turbo" || apiKey != "compress-key" || apiBase != "https://compress.example.com/v1" {
+			t.Errorf("CompressModel() = %q, %q, %q", model, apiKey, apiBase)
+		}
+	})
+}
+
+// TestConfig_TranslateModel 测试翻译模型选择
+func TestConfig_TranslateModel(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Agents.Defaults.Model = "gpt-4o"
+	cfg.Providers.OpenAI.APIKey = "main-key"
+	cfg.Providers.DeepSeek.APIKey = "ds-key"
+	cfg.Providers.DeepSeek.APIBase = "https://api.deepseek.com/v1"
+
+	if model, apiKey, _ := cfg.TranslateModel(); model != "gpt-4o" || apiKey != "main-key" {
+		t.Errorf("未配置时应使用默认模型, got %q, %q", model, apiKey)
+	}
+
+	cfg.Tools.Translate.Model = "deepseek-chat"
+	model, apiKey, apiBase := cfg.TranslateModel()
+	if model != "deepseek-chat" || apiKey != "ds-key" || apiBase != "https://api.deepseek.com/v1" {
+		t.Errorf("TranslateModel() = %q, %q, %q", model, apiKey, apiBase)
+	}
+}
+
+// TestConfig_ValidateCompress 测试压缩配置校验
+func TestConfig_ValidateCompress(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.ValidateCompress(); err != nil {
+		t.Errorf("未启用压缩时不应报错: %v", err)
+	}
+
+	cfg.Compress.Enabled = true
+	if err := cfg.ValidateCompress(); err == nil {
+		t.Error("缺少 API Key 时应该报错")
+	}
+
+	cfg.Compress.APIKey = "compress-key"
+	if err := cfg.ValidateCompress(); err != nil {
+		t.Errorf("配置完整时不应报错: %v", err)
+	}
+
+	cfg.Compress.MaxHistory = -1
+	if err := cfg.ValidateCompress(); err == nil {
+		t.Error("负数的保留消息数应该报错")
+	}
+
+	cfg.Compress.MaxHistory = 5
+	cfg.Compress.MinMessages = 0
+	cfg.Compress.MinTokens = 0
+	if err := cfg.ValidateCompress(); err == nil {
+		t.Error("没有任何压缩阈值时应该报错")
+	}
+}
+
 // TestProviderConfig 测试提供商配置
 func TestProviderConfig(t *testing.T) {
 	cfg := DefaultConfig()
@@ -345,3 +471,157 @@ func TestChannelsConfig(t *testing.T) {
 		t.Errorf("AllowFrom 长度 = %d, 期望 2", len(cfg.Channels.WebSocket.AllowFrom))
 	}
 }
+
+// TestHistoryConfig_Window 测试按渠道覆盖的历史窗口
+func TestHistoryConfig_Window(t *testing.T) {
+	cfg := HistoryConfig{
+		HistoryWindow: HistoryWindow{MaxMessages: 20, MaxTokens: 4000},
+		Channels: map[string]HistoryWindow{
+			"dingtalk": {MaxMessages: 4},
+		},
+	}
+	if got := cfg.Window("dingtalk"); got.MaxMessages != 4 || got.MaxTokens != 4000 {
+		t.Errorf("Window(dingtalk) = %+v, 期望覆盖消息数并继承 token 上限", got)
+	}
+	if got := cfg.Window("feishu"); got.MaxMessages != 20 {
+		t.Errorf("Window(feishu) = %+v, 期望使用全局配置", got)
+	}
+	if got := (HistoryConfig{}).Window("feishu"); got.MaxMessages != DefaultHistoryMessages || got.MaxTokens != 0 {
+		t.Errorf("未配置时 Window() = %+v", got)
+	}
+}
+
+// TestConfig_SupportsToolCalling 测试按模型声明工具调用能力
+func TestConfig_SupportsToolCalling(t *testing.T) {
+	disabled := false
+	cfg := DefaultConfig()
+	cfg.Agents.Models = map[string]ModelCapabilities{
+		"gemma:2b": {ToolCalling: &disabled},
+		"qwen2.5":  {},
+	}
+
+	if cfg.SupportsToolCalling("gemma:2b") {
+		t.Error("声明不支持的模型应返回 false")
+	}
+	if !cfg.SupportsToolCalling("qwen2.5") || !cfg.SupportsToolCalling("gpt-4o") {
+		t.Error("未声明的模型应视为支持")
+	}
+}
+
+// TestChannelsConfig_MissingCredentials 测试检查已启用渠道的必填凭证
+func TestChannelsConfig_MissingCredentials(t *testing.T) {
+	var c ChannelsConfig
+	c.Feishu = FeishuConfig{Enabled: true, AppID: "cli_a"}
+	c.DingTalk = DingTalkConfig{Enabled: false}
+	c.Matrix = MatrixConfig{Enabled: true, Homeserver: "https://matrix.org", UserID: "@bot:matrix.org", Token: "t"}
+
+	missing := c.MissingCredentials()
+	if len(missing) != 1 || strings.Join(missing["feishu"], ",") != "appSecret" {
+		t.Errorf("MissingCredentials() = %v, 期望只有 feishu 缺少 appSecret", missing)
+	}
+}
+
+// TestConfig_ValidateMessageTarget 测试校验消息工具默认目标
+func TestConfig_ValidateMessageTarget(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.ValidateMessageTarget(); err != nil {
+		t.Errorf("未配置时 ValidateMessageTarget() = %v", err)
+	}
+
+	cfg.Tools.Message = MessageToolConfig{DefaultChannel: "feishu"}
+	if err := cfg.ValidateMessageTarget(); err == nil {
+		t.Error("只配置渠道时应返回错误")
+	}
+
+	cfg.Tools.Message.DefaultChatID = "oc_123"
+	if err := cfg.ValidateMessageTarget(); err == nil {
+		t.Error("渠道未启用时应返回错误")
+	}
+
+	cfg.Channels.Feishu.Enabled = true
+	if err := cfg.ValidateMessageTarget(); err != nil {
+		t.Errorf("ValidateMessageTarget() = %v", err)
+	}
+}
+
+// TestConfig_ValidateContacts 测试校验联系人别名
+func TestConfig_ValidateContacts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Channels.Matrix.Enabled = true
+	cfg.Contacts = map[string]ContactConfig{
+		"family-group": {Channel: "matrix", ChatID: "!abc:matrix.org"},
+	}
+	if err := cfg.ValidateContacts(); err != nil {
+		t.Errorf("ValidateContacts() = %v", err)
+	}
+
+	cfg.Contacts["老板"] = ContactConfig{Channel: "feishu", ChatID: "ou_boss"}
+	cfg.Contacts["同事"] = ContactConfig{Channel: "matrix"}
+	err := cfg.ValidateContacts()
+	if err == nil || !strings.Contains(err.Error(), "老板 的渠道 feishu 不存在或未启用") || !strings.Contains(err.Error(), "同事 需要同时配置") {
+		t.Errorf("ValidateContacts() = %v", err)
+	}
+}
+
+// TestConfig_DigestTarget 测试解析定时摘要的发送目标
+func TestConfig_DigestTarget(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Channels.Matrix.Enabled = true
+	cfg.Contacts = map[string]ContactConfig{"family-group": {Channel: "matrix", ChatID: "!abc:matrix.org"}}
+
+	if _, _, err := cfg.DigestTarget(); err == nil {
+		t.Error("未配置目标时应返回错误")
+	}
+
+	cfg.Digest.Contact = "family-group"
+	if channel, chatID, err := cfg.DigestTarget(); err != nil || channel != "matrix" || chatID != "!abc:matrix.org" {
+		t.Errorf("DigestTarget() = %s, %s, %v", channel, chatID, err)
+	}
+
+	cfg.Digest = DigestConfig{Channel: "feishu", ChatID: "oc_1"}
+	if _, _, err := cfg.DigestTarget(); err == nil {
+		t.Error("渠道未启用时应返回错误")
+	}
+}
+
+// TestConfig_RoleModels 测试按用途配置模型
+func TestConfig_RoleModels(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Agents.Defaults.Model = "openai/gpt-4o"
+	cfg.Agents.Roles = RoleModelsConfig{Chat: "deepseek-chat", Compress: "gpt-4o-mini"}
+
+	if got := cfg.RoleModel(RoleChat); got != "deepseek-chat" {
+		t.Errorf("RoleModel(chat) = %q, 期望 deepseek-chat", got)
+	}
+	if got := cfg.RoleModel(RoleTask); got != "openai/gpt-4o" {
+		t.Errorf("未配置的用途应使用默认模型，实际 %q", got)
+	}
+	if model, _, _ := cfg.CompressModel(); model != "gpt-4o-mini" {
+		t.Errorf("CompressModel() = %q, 期望使用 roles.compress", model)
+	}
+
+	err := cfg.ValidateRoleModels()
+	if err == nil || !strings.Contains(err.Error(), "chat 模型 deepseek-chat") || !strings.Contains(err.Error(), "compress 模型 gpt-4o-mini") {
+		t.Errorf("ValidateRoleModels() = %v, 期望提示各用途的模型缺少 API Key", err)
+	}
+	cfg.Providers.DeepSeek.APIKey = "deepseek-key"
+	if err := cfg.ValidateRoleModels(); err != nil {
+		t.Errorf("ValidateRoleModels() = %v, 期望通过", err)
+	}
+}
+
+// TestConfig_Secrets 测试收集用于日志脱敏的配置密钥
+func TestConfig_Secrets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Providers.OpenAI.APIKey = "openai-key"
+	cfg.Providers.VLLM.ExtraHeaders = map[string]string{"X-Auth-Token": "vllm-header-token"}
+	cfg.Memory.Embedding.APIKey = "embedding-key"
+	cfg.Webhook.Headers = map[string]string{"Authorization": "webhook-token"}
+
+	secrets := strings.Join(cfg.Secrets(), "\n")
+	for _, want := range []string{"openai-key", "vllm-header-token", "embedding-key", "webhook-token"} {
+		if !strings.Contains(secrets, want) {
+			t.Errorf("Secrets() 缺少 %q", want)
+		}
+	}
+}
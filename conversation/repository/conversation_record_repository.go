@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,6 +17,7 @@ type ConversationRecordRepository interface {
 	FindBySessionKey(ctx context.Context, sessionKey string, opts *models.QueryOptions) ([]models.ConversationRecord, error)
 	FindByTimeRange(ctx context.Context, startTime, endTime time.Time, opts *models.QueryOptions) ([]models.ConversationRecord, error)
 	FindByTraceIDRoleAndContent(ctx context.Context, traceID, role, content string) ([]models.ConversationRecord, error)
+	FindSessionKeysByPrefix(ctx context.Context, prefix string) ([]string, error)
 	CountBySessionKey(ctx context.Context, sessionKey string) (int64, error)
 	CountByTimeRange(ctx context.Context, startTime, endTime time.Time) (int64, error)
 	Count(ctx context.Context) (int64, error)
@@ -131,6 +133,22 @@ func (r *conversationRecordRepository) FindByTraceIDRoleAndContent(ctx context.C
 	return records, nil
 }
 
+// FindSessionKeysByPrefix 返回以 prefix 开头的所有会话键，按字母顺序排列
+func (r *conversationRecordRepository) FindSessionKeysByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	// 会话键中可能含有 _ 和 %，需要转义后再做前缀匹配
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	var keys []string
+	if err := r.db.WithContext(ctx).
+		Model(&models.ConversationRecord{}).
+		Distinct("session_key").
+		Where(`session_key LIKE ? ESCAPE '\'`, escaped+"%").
+		Order("session_key ASC").
+		Pluck("session_key", &keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
 func (r *conversationRecordRepository) CountBySessionKey(ctx context.Context, sessionKey string) (int64, error) {
 	var count int64
 	if err := r.db.WithContext(ctx).
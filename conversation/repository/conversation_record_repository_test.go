@@ -304,6 +304,30 @@ func TestConversationRecordRepository_CountBySessionKey(t *testing.T) {
 	}
 }
 
+func TestConversationRecordRepository_FindSessionKeysByPrefix(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewConversationRecordRepository(db)
+
+	for _, key := range []string{"cli:oc_1#fork-2", "cli:oc_1#fork-1", "cli:oc_1#fork-1", "cli:ocx1#fork-1", "cli:oc_1"} {
+		repo.Create(context.Background(), &models.ConversationRecord{
+			TraceID:    "trace",
+			EventType:  "prompt",
+			Timestamp:  time.Now(),
+			SessionKey: key,
+			Role:       "user",
+			Content:    "内容",
+		})
+	}
+
+	keys, err := repo.FindSessionKeysByPrefix(context.Background(), "cli:oc_1#fork-")
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "cli:oc_1#fork-1" || keys[1] != "cli:oc_1#fork-2" {
+		t.Errorf("会话键 = %v, 期望 [cli:oc_1#fork-1 cli:oc_1#fork-2]", keys)
+	}
+}
+
 func TestConversationRecordRepository_CountByTimeRange(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewConversationRecordRepository(db)
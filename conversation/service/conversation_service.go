@@ -18,17 +18,23 @@ var (
 
 // ConversationDTO 对话数据传输对象
 type ConversationDTO struct {
-	ID           uint           `json:"id"`
-	TraceID      string         `json:"trace_id"`
-	SpanID       string         `json:"span_id,omitempty"`
-	ParentSpanID string         `json:"parent_span_id,omitempty"`
-	EventType    string         `json:"event_type"`
-	Timestamp    time.Time      `json:"timestamp"`
-	SessionKey   string         `json:"session_key"`
-	Role         string         `json:"role"`
-	Content      string         `json:"content"`
-	TokenUsage   *TokenUsageDTO `json:"token_usage,omitempty"`
-	CreatedAt    time.Time      `json:"created_at"`
+	ID               uint           `json:"id"`
+	TraceID          string         `json:"trace_id"`
+	SpanID           string         `json:"span_id,omitempty"`
+	ParentSpanID     string         `json:"parent_span_id,omitempty"`
+	EventType        string         `json:"event_type"`
+	Timestamp        time.Time      `json:"timestamp"`
+	SessionKey       string         `json:"session_key"`
+	Role             string         `json:"role"`
+	Content          string         `json:"content"`
+	TokenUsage       *TokenUsageDTO `json:"token_usage,omitempty"`
+	FinishReason     string         `json:"finish_reason,omitempty"`     // 模型结束原因
+	ToolCalls        string         `json:"tool_calls,omitempty"`        // 助手消息发起的工具调用列表（JSON）
+	ToolCallID       string         `json:"tool_call_id,omitempty"`      // tool 消息对应的工具调用 ID
+	ToolName         string         `json:"tool_name,omitempty"`         // tool 消息对应的工具名称
+	LogProbs         string         `json:"logprobs,omitempty"`          // 输出 token 的对数概率（JSON）
+	ReasoningContent string         `json:"reasoning_content,omitempty"` // 推理模型的思考过程
+	CreatedAt        time.Time      `json:"created_at"`
 }
 
 // TokenUsageDTO Token 使用信息
@@ -243,6 +249,12 @@ func (s *conversationService) recordToDTO(record *models.ConversationRecord) Con
 		SessionKey:   record.SessionKey,
 		Role:         record.Role,
 		Content:      record.Content,
+		FinishReason: record.FinishReason,
+		ToolCalls:    record.ToolCalls,
+		ToolCallID:   record.ToolCallID,
+		ToolName:     record.ToolName,
+		LogProbs:     record.LogProbs,
+		ReasoningContent: record.ReasoningContent,
 		CreatedAt:    record.CreatedAt,
 	}
 
@@ -270,6 +282,12 @@ func (s *conversationService) dtoToRecord(dto *ConversationDTO) models.Conversat
 		SessionKey:   dto.SessionKey,
 		Role:         dto.Role,
 		Content:      dto.Content,
+		FinishReason: dto.FinishReason,
+		ToolCalls:    dto.ToolCalls,
+		ToolCallID:   dto.ToolCallID,
+		ToolName:     dto.ToolName,
+		LogProbs:     dto.LogProbs,
+		ReasoningContent: dto.ReasoningContent,
 		CreatedAt:    dto.CreatedAt,
 	}
 
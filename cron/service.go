@@ -9,16 +9,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/weibaohui/nanobot-go/utils"
 	"go.uber.org/zap"
 )
 
 // OnJobCallback 任务执行回调
 type OnJobCallback func(job *Job) (string, error)
 
+// OnJobFailedCallback 任务执行失败回调
+type OnJobFailedCallback func(job *Job, err error)
+
 // Service 定时任务服务
 type Service struct {
 	storePath string
 	onJob     OnJobCallback
+	onFailed  OnJobFailedCallback
 	store     *Store
 	mu        sync.RWMutex
 	running   bool
@@ -40,8 +45,11 @@ func NewService(storePath string, logger *zap.Logger) *Service {
 // Start 启动服务
 func (s *Service) Start(ctx context.Context) error {
 	s.loadStore()
+	s.mu.Lock()
+	s.running = true
 	s.recomputeNextRuns()
-	s.saveStore()
+	s.saveStoreLocked()
+	s.mu.Unlock()
 	s.armTimer(ctx)
 
 	s.logger.Info("定时任务服务已启动")
@@ -51,6 +59,8 @@ func (s *Service) Start(ctx context.Context) error {
 
 // Stop 停止服务
 func (s *Service) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.running = false
 	if s.timer != nil {
 		s.timer.Stop()
@@ -77,9 +87,13 @@ func (s *Service) loadStore() {
 // saveStore 保存任务存储
 func (s *Service) saveStore() {
 	s.mu.RLock()
-	store := s.store
-	s.mu.RUnlock()
+	defer s.mu.RUnlock()
+	s.saveStoreLocked()
+}
 
+// saveStoreLocked 保存任务存储，调用方需持有锁
+func (s *Service) saveStoreLocked() {
+	store := s.store
 	if store == nil {
 		return
 	}
@@ -96,17 +110,35 @@ func (s *Service) saveStore() {
 	}
 }
 
-// recomputeNextRuns 重新计算所有任务的下次运行时间
+// recomputeNextRuns 重新计算所有任务的下次运行时间，调用方需持有锁
+// 服务停止期间错过执行时间的任务按补跑策略处理：run-once-on-start 立即补跑一次，其余跳过
 func (s *Service) recomputeNextRuns() {
 	now := nowMs()
 	for _, job := range s.store.Jobs {
-		if job.Enabled {
-			job.State.NextRunAtMs = computeNextRun(&job.Schedule, now)
+		if !job.Enabled {
+			continue
+		}
+		missed := job.missedRun(now)
+		job.State.NextRunAtMs = computeNextRun(&job.Schedule, now)
+		if !missed {
+			continue
+		}
+		if job.CatchUp == CatchUpRunOnce {
+			s.logger.Info("任务错过了执行时间，启动时补跑一次",
+				zap.String("名称", job.Name),
+				zap.String("ID", job.ID),
+			)
+			job.State.NextRunAtMs = now
+		} else {
+			s.logger.Info("任务错过了执行时间，已跳过",
+				zap.String("名称", job.Name),
+				zap.String("ID", job.ID),
+			)
 		}
 	}
 }
 
-// getNextWakeMs 获取最早的下次运行时间
+// getNextWakeMs 获取最早的下次运行时间，调用方需持有锁
 func (s *Service) getNextWakeMs() int {
 	var minMs int
 	for _, job := range s.store.Jobs {
@@ -121,9 +153,15 @@ func (s *Service) getNextWakeMs() int {
 
 // armTimer 设置定时器
 func (s *Service) armTimer(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.timer != nil {
 		s.timer.Stop()
 	}
+	if !s.running {
+		return
+	}
 
 	nextWake := s.getNextWakeMs()
 	if nextWake == 0 {
@@ -136,7 +174,10 @@ func (s *Service) armTimer(ctx context.Context) {
 	}
 
 	s.timer = time.AfterFunc(time.Duration(delayMs)*time.Millisecond, func() {
-		if s.running {
+		s.mu.RLock()
+		running := s.running
+		s.mu.RUnlock()
+		if running {
 			s.onTimer(ctx)
 		}
 	})
@@ -146,12 +187,14 @@ func (s *Service) armTimer(ctx context.Context) {
 func (s *Service) onTimer(ctx context.Context) {
 	now := nowMs()
 
+	s.mu.RLock()
 	var dueJobs []*Job
 	for _, job := range s.store.Jobs {
 		if job.Enabled && job.State.NextRunAtMs > 0 && now >= job.State.NextRunAtMs {
 			dueJobs = append(dueJobs, job)
 		}
 	}
+	s.mu.RUnlock()
 
 	for _, job := range dueJobs {
 		s.executeJob(ctx, job)
@@ -162,30 +205,64 @@ func (s *Service) onTimer(ctx context.Context) {
 }
 
 // executeJob 执行任务
+// 回调在锁外基于任务快照执行，执行结果在持锁时写回存储中的任务
 func (s *Service) executeJob(ctx context.Context, job *Job) {
+	s.mu.RLock()
+	snapshot := job.clone()
+	s.mu.RUnlock()
+
 	startMs := nowMs()
 	s.logger.Info("执行定时任务",
-		zap.String("名称", job.Name),
-		zap.String("ID", job.ID),
+		zap.String("名称", snapshot.Name),
+		zap.String("ID", snapshot.ID),
 	)
 
 	var status string
 	var errMsg string
+	var run *RunRecord
+	var runErr error
 
 	if s.onJob != nil {
-		if _, err := s.onJob(job); err != nil {
+		result, err := s.onJob(snapshot)
+		if err != nil {
 			status = "error"
 			errMsg = err.Error()
+			runErr = err
 			s.logger.Error("任务执行失败",
-				zap.String("名称", job.Name),
+				zap.String("名称", snapshot.Name),
 				zap.Error(err),
 			)
 		} else {
 			status = "ok"
-			s.logger.Info("任务执行完成", zap.String("名称", job.Name))
+			s.logger.Info("任务执行完成", zap.String("名称", snapshot.Name))
+		}
+		run = &RunRecord{
+			AtMs:       startMs,
+			DurationMs: nowMs() - startMs,
+			Status:     status,
+			Error:      errMsg,
+			Preview:    utils.TruncateString(result, runPreviewLength),
 		}
 	}
 
+	s.mu.Lock()
+	s.applyRunLocked(job, startMs, status, errMsg, run)
+	s.mu.Unlock()
+
+	if runErr != nil && s.onFailed != nil {
+		s.onFailed(snapshot, runErr)
+	}
+}
+
+// applyRunLocked 把一次执行的结果写回任务并计算下次运行时间，调用方需持有锁
+// 执行期间任务已被删除时不做处理
+func (s *Service) applyRunLocked(job *Job, startMs int, status, errMsg string, run *RunRecord) {
+	if !s.hasJobLocked(job) {
+		return
+	}
+	if run != nil {
+		job.recordRun(*run)
+	}
 	job.State.LastRunAtMs = startMs
 	job.State.LastStatus = status
 	job.State.LastError = errMsg
@@ -222,8 +299,24 @@ func (s *Service) ListJobs() []*Job {
 	return jobs
 }
 
-// AddJob 添加任务
-func (s *Service) AddJob(name string, schedule *Schedule, message string, deliver bool, channel, to string, deleteAfterRun bool) *Job {
+// GetJob 按 ID 获取任务，不存在时返回 nil
+func (s *Service) GetJob(jobID string) *Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.store == nil {
+		return nil
+	}
+	for _, job := range s.store.Jobs {
+		if job.ID == jobID {
+			return job
+		}
+	}
+	return nil
+}
+
+// AddJob 添加任务，payload.Kind 为空时使用 "agent_turn"
+func (s *Service) AddJob(name string, schedule *Schedule, payload Payload, opts JobOptions) *Job {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -231,21 +324,25 @@ func (s *Service) AddJob(name string, schedule *Schedule, message string, delive
 		s.store = &Store{Version: 1}
 	}
 
+	if payload.Kind == "" {
+		payload.Kind = "agent_turn"
+	}
 	now := nowMs()
 	job := &Job{
 		ID:             generateID(),
 		Name:           name,
 		Enabled:        true,
 		Schedule:       *schedule,
-		Payload:        Payload{Kind: "agent_turn", Message: message, Deliver: deliver, Channel: channel, To: to},
+		Payload:        payload,
 		State:          State{NextRunAtMs: computeNextRun(schedule, now)},
 		CreatedAtMs:    now,
 		UpdatedAtMs:    now,
-		DeleteAfterRun: deleteAfterRun,
+		DeleteAfterRun: opts.DeleteAfterRun,
+		CatchUp:        opts.CatchUp,
 	}
 
 	s.store.Jobs = append(s.store.Jobs, job)
-	s.saveStore()
+	s.saveStoreLocked()
 
 	s.logger.Info("添加定时任务",
 		zap.String("名称", name),
@@ -262,13 +359,23 @@ func (s *Service) RemoveJob(jobID string) bool {
 
 	removed := s.removeJobByID(jobID)
 	if removed {
-		s.saveStore()
+		s.saveStoreLocked()
 		s.logger.Info("删除定时任务", zap.String("ID", jobID))
 	}
 	return removed
 }
 
-// removeJobByID 内部删除任务
+// hasJobLocked 判断任务是否仍在存储中，调用方需持有锁
+func (s *Service) hasJobLocked(job *Job) bool {
+	for _, j := range s.store.Jobs {
+		if j == job {
+			return true
+		}
+	}
+	return false
+}
+
+// removeJobByID 内部删除任务，调用方需持有锁
 func (s *Service) removeJobByID(jobID string) bool {
 	for i, job := range s.store.Jobs {
 		if job.ID == jobID {
@@ -284,6 +391,11 @@ func (s *Service) SetOnJobCallback(callback OnJobCallback) {
 	s.onJob = callback
 }
 
+// SetOnJobFailedCallback 设置任务执行失败回调
+func (s *Service) SetOnJobFailedCallback(callback OnJobFailedCallback) {
+	s.onFailed = callback
+}
+
 // Status 获取服务状态
 func (s *Service) Status() map[string]any {
 	s.mu.RLock()
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -117,6 +118,38 @@ func TestService_recomputeNextRuns(t *testing.T) {
 	}
 }
 
+// TestService_recomputeNextRuns_CatchUp 测试服务停止期间错过执行时间的补跑策略
+func TestService_recomputeNextRuns_CatchUp(t *testing.T) {
+	now := nowMs()
+	daily := Schedule{Kind: "every", EveryMs: 24 * 60 * 60 * 1000}
+	newJob := func(catchUp string, state State) *Job {
+		return &Job{Enabled: true, Schedule: daily, CatchUp: catchUp, State: state}
+	}
+
+	tests := []struct {
+		name   string
+		job    *Job
+		runNow bool
+	}{
+		{"补跑策略且错过", newJob(CatchUpRunOnce, State{NextRunAtMs: now - 3600*1000}), true},
+		{"默认跳过", newJob("", State{NextRunAtMs: now - 3600*1000}), false},
+		{"按上次执行时间推算错过", newJob(CatchUpRunOnce, State{LastRunAtMs: now - 2*daily.EveryMs}), true},
+		{"未错过", newJob(CatchUpRunOnce, State{NextRunAtMs: now + 3600*1000}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(filepath.Join(t.TempDir(), "jobs.json"), zap.NewNop())
+			service.store = &Store{Version: 1, Jobs: []*Job{tt.job}}
+			service.recomputeNextRuns()
+
+			next := tt.job.State.NextRunAtMs
+			if runNow := next <= nowMs(); runNow != tt.runNow {
+				t.Errorf("NextRunAtMs = %d, 立即执行 = %v, 期望 %v", next, runNow, tt.runNow)
+			}
+		})
+	}
+}
+
 // TestService_getNextWakeMs 测试获取最早唤醒时间
 func TestService_getNextWakeMs(t *testing.T) {
 	service := NewService("/tmp/test.json", zap.NewNop())
@@ -358,3 +391,108 @@ func TestService_ExecuteJob_OneTime_NoDelete(t *testing.T) {
 		t.Error("任务应该被禁用")
 	}
 }
+
+// TestService_StartRunsDueJobs 测试服务启动后按时执行到期任务
+func TestService_StartRunsDueJobs(t *testing.T) {
+	service := NewService(filepath.Join(t.TempDir(), "jobs.json"), zap.NewNop())
+	fired := make(chan *Job, 1)
+	service.SetOnJobCallback(func(job *Job) (string, error) {
+		select {
+		case fired <- job:
+		default:
+		}
+		return "ok", nil
+	})
+	service.AddJob("总结", &Schedule{Kind: "every", EveryMs: 20}, Payload{Message: "总结收件箱", RunAgent: true}, JobOptions{})
+
+	if err := service.Start(context.Background()); err != nil {
+		t.Fatalf("Start() 返回错误: %v", err)
+	}
+	defer service.Stop()
+
+	select {
+	case job := <-fired:
+		if !job.Payload.RunAgent || job.Payload.Kind != "agent_turn" {
+			t.Errorf("Payload = %+v", job.Payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("到期任务应被执行")
+	}
+}
+
+// TestService_ExecuteJob_RunHistory 测试执行记录与失败回调
+func TestService_ExecuteJob_RunHistory(t *testing.T) {
+	service := NewService(filepath.Join(t.TempDir(), "jobs.json"), zap.NewNop())
+	var failed []string
+	service.SetOnJobFailedCallback(func(job *Job, err error) {
+		failed = append(failed, job.ID+": "+err.Error())
+	})
+	fail := false
+	service.SetOnJobCallback(func(job *Job) (string, error) {
+		if fail {
+			return "", os.ErrNotExist
+		}
+		return "收件箱共 3 封未读邮件", nil
+	})
+
+	job := &Job{ID: "job-1", Name: "总结", Enabled: true, Schedule: Schedule{Kind: "every", EveryMs: 60000}}
+	service.store = &Store{Version: 1, Jobs: []*Job{job}}
+
+	for i := 0; i < maxRunHistory+2; i++ {
+		service.executeJob(context.Background(), job)
+	}
+	fail = true
+	service.executeJob(context.Background(), job)
+
+	if len(job.Runs) != maxRunHistory {
+		t.Fatalf("执行记录数 = %d, 期望 %d", len(job.Runs), maxRunHistory)
+	}
+	if first := job.Runs[0]; first.Status != "ok" || first.Preview != "收件箱共 3 封未读邮件" {
+		t.Errorf("成功记录 = %+v", first)
+	}
+	if last := job.Runs[len(job.Runs)-1]; last.Status != "error" || last.Error == "" {
+		t.Errorf("失败记录 = %+v", last)
+	}
+	if len(failed) != 1 {
+		t.Errorf("失败回调次数 = %d, 期望 1", len(failed))
+	}
+
+	// 执行记录随任务一起持久化
+	service.saveStore()
+	reloaded := NewService(service.storePath, zap.NewNop())
+	reloaded.loadStore()
+	if got := reloaded.GetJob("job-1"); got == nil || len(got.Runs) != maxRunHistory {
+		t.Error("执行记录应持久化到任务存储")
+	}
+}
+
+// TestService_ExecuteJob_CallbackOutsideLock 测试任务回调在锁外执行
+func TestService_ExecuteJob_CallbackOutsideLock(t *testing.T) {
+	service := NewService(filepath.Join(t.TempDir(), "jobs.json"), zap.NewNop())
+	job := &Job{ID: "job-1", Name: "总结", Enabled: true, Schedule: Schedule{Kind: "every", EveryMs: 60000}}
+	service.store = &Store{Version: 1, Jobs: []*Job{job}}
+
+	// 回调中访问服务不会死锁；执行期间任务被删除时不再写回执行结果
+	service.SetOnJobCallback(func(snapshot *Job) (string, error) {
+		if snapshot == job {
+			t.Error("回调应收到任务快照")
+		}
+		service.ListJobs()
+		service.RemoveJob(snapshot.ID)
+		return "ok", nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		service.executeJob(context.Background(), job)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("回调中访问服务不应死锁")
+	}
+	if len(service.store.Jobs) != 0 || len(job.Runs) != 0 {
+		t.Errorf("任务已删除，不应写回执行结果: jobs = %d, runs = %d", len(service.store.Jobs), len(job.Runs))
+	}
+}
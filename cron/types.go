@@ -1,7 +1,10 @@
 package cron
 
 import (
+	"fmt"
 	"time"
+
+	robfigcron "github.com/robfig/cron/v3"
 )
 
 // Schedule 调度定义
@@ -20,6 +23,9 @@ type Payload struct {
 	Deliver bool   `json:"deliver"` // 是否投递响应
 	Channel string `json:"channel"` // 渠道
 	To      string `json:"to"`      // 目标
+	// RunAgent 为 true 时任务消息作为提示交给 Agent 完整处理（可使用工具），投递 Agent 的回复；
+	// 为 false 时直接投递任务消息（提醒）
+	RunAgent bool `json:"runAgent"`
 }
 
 // State 任务状态
@@ -30,6 +36,33 @@ type State struct {
 	LastError   string `json:"lastError"`   // 上次错误
 }
 
+// 错过执行时间（服务停止期间到期）的补跑策略
+const (
+	CatchUpSkip    = "skip"              // 跳过错过的执行，等待下一次调度（默认）
+	CatchUpRunOnce = "run-once-on-start" // 启动时补跑一次，多次错过也只补跑一次
+)
+
+// JobOptions 添加任务的可选项
+type JobOptions struct {
+	DeleteAfterRun bool   // 一次性任务执行后删除
+	CatchUp        string // 补跑策略: CatchUpSkip, CatchUpRunOnce，为空时等同 CatchUpSkip
+}
+
+// maxRunHistory 每个任务保留的最近执行记录数
+const maxRunHistory = 10
+
+// runPreviewLength 执行记录中结果预览的最大长度（字符）
+const runPreviewLength = 200
+
+// RunRecord 一次任务执行记录
+type RunRecord struct {
+	AtMs       int    `json:"atMs"`              // 开始执行时间（毫秒）
+	DurationMs int    `json:"durationMs"`        // 执行耗时（毫秒）
+	Status     string `json:"status"`            // "ok", "error"
+	Error      string `json:"error,omitempty"`   // 错误信息
+	Preview    string `json:"preview,omitempty"` // 结果预览
+}
+
 // Job 定时任务
 type Job struct {
 	ID             string   `json:"id"`
@@ -41,6 +74,36 @@ type Job struct {
 	CreatedAtMs    int      `json:"createdAtMs"`
 	UpdatedAtMs    int      `json:"updatedAtMs"`
 	DeleteAfterRun bool     `json:"deleteAfterRun"`
+	CatchUp        string   `json:"catchUp,omitempty"` // 补跑策略: "skip", "run-once-on-start"
+	// Runs 最近的执行记录，按时间先后排列，最多保留 maxRunHistory 条
+	Runs []RunRecord `json:"runs,omitempty"`
+}
+
+// missedRun 判断任务在服务停止期间是否错过了计划执行时间
+func (j *Job) missedRun(nowMs int) bool {
+	if j.State.NextRunAtMs > 0 {
+		return j.State.NextRunAtMs <= nowMs
+	}
+	// 没有记录下次执行时间时，按上次执行时间推算
+	if j.Schedule.Kind == "every" && j.Schedule.EveryMs > 0 && j.State.LastRunAtMs > 0 {
+		return j.State.LastRunAtMs+j.Schedule.EveryMs <= nowMs
+	}
+	return false
+}
+
+// clone 返回任务的副本，执行记录单独复制
+func (j *Job) clone() *Job {
+	c := *j
+	c.Runs = append([]RunRecord(nil), j.Runs...)
+	return &c
+}
+
+// recordRun 追加一条执行记录，超出上限时丢弃最早的记录
+func (j *Job) recordRun(record RunRecord) {
+	j.Runs = append(j.Runs, record)
+	if len(j.Runs) > maxRunHistory {
+		j.Runs = j.Runs[len(j.Runs)-maxRunHistory:]
+	}
 }
 
 // Store 任务存储
@@ -70,10 +133,74 @@ func computeNextRun(schedule *Schedule, nowMs int) int {
 		return nowMs + schedule.EveryMs
 
 	case "cron":
-		// 简化实现：暂不支持 cron 表达式
-		// 实际应使用 robfig/cron 库
-		return 0
+		sched, loc, err := parseCronSchedule(schedule)
+		if err != nil {
+			return 0
+		}
+		next := sched.Next(time.UnixMilli(int64(nowMs)).In(loc))
+		if next.IsZero() {
+			return 0
+		}
+		return int(next.UnixMilli())
 	}
 
 	return 0
 }
+
+// 周期任务间隔的合理范围
+const (
+	MinEveryMs = 10 * 1000                 // 最小间隔 10 秒
+	MaxEveryMs = 366 * 24 * 60 * 60 * 1000 // 最大间隔一年，更长的周期请使用 cron 表达式
+)
+
+// ParseCronExpr 解析标准 5 段 cron 表达式（分 时 日 月 周），同时支持 @daily、@every 1h 等描述符
+func ParseCronExpr(expr string) (robfigcron.Schedule, error) {
+	return robfigcron.ParseStandard(expr)
+}
+
+// parseCronSchedule 解析 cron 调度的表达式与时区，未指定时区时使用本地时区
+func parseCronSchedule(schedule *Schedule) (robfigcron.Schedule, *time.Location, error) {
+	sched, err := ParseCronExpr(schedule.Expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc := time.Local
+	if schedule.Tz != "" {
+		if loc, err = time.LoadLocation(schedule.Tz); err != nil {
+			return nil, nil, err
+		}
+	}
+	return sched, loc, nil
+}
+
+// ValidateSchedule 校验调度定义，返回可直接展示给用户的错误
+func ValidateSchedule(schedule *Schedule) error {
+	switch schedule.Kind {
+	case "at":
+		if schedule.AtMs <= nowMs() {
+			return fmt.Errorf("执行时间必须晚于当前时间")
+		}
+	case "every":
+		if schedule.EveryMs < MinEveryMs {
+			return fmt.Errorf("间隔至少为 %d 秒", MinEveryMs/1000)
+		}
+		if schedule.EveryMs > MaxEveryMs {
+			return fmt.Errorf("间隔不能超过 %d 天，更长的周期请使用 cron 表达式", MaxEveryMs/(24*60*60*1000))
+		}
+	case "cron":
+		if schedule.Expr == "" {
+			return fmt.Errorf("cron 表达式不能为空")
+		}
+		if _, err := ParseCronExpr(schedule.Expr); err != nil {
+			return fmt.Errorf("无效的 cron 表达式 %q: %v", schedule.Expr, err)
+		}
+		if schedule.Tz != "" {
+			if _, err := time.LoadLocation(schedule.Tz); err != nil {
+				return fmt.Errorf("无效的时区 %q", schedule.Tz)
+			}
+		}
+	default:
+		return fmt.Errorf("未知的调度类型 %q", schedule.Kind)
+	}
+	return nil
+}
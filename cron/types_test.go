@@ -85,15 +85,29 @@ func TestComputeNextRun(t *testing.T) {
 		}
 	})
 
-	t.Run("cron 类型 - 暂不支持", func(t *testing.T) {
+	t.Run("cron 类型", func(t *testing.T) {
 		schedule := &Schedule{
 			Kind: "cron",
 			Expr: "0 * * * *",
+			Tz:   "UTC",
+		}
+
+		result := computeNextRun(schedule, now)
+		next := time.UnixMilli(int64(result)).UTC()
+		if result <= now || next.Minute() != 0 || next.Second() != 0 || result-now > 60*60*1000 {
+			t.Errorf("computeNextRun() = %s, 期望下一个整点", next)
+		}
+	})
+
+	t.Run("cron 类型 - 无效表达式", func(t *testing.T) {
+		schedule := &Schedule{
+			Kind: "cron",
+			Expr: "every morning",
 		}
 
 		result := computeNextRun(schedule, now)
 		if result != 0 {
-			t.Errorf("computeNextRun() = %d, 期望 0 (cron 暂不支持)", result)
+			t.Errorf("computeNextRun() = %d, 期望 0 (无效表达式)", result)
 		}
 	})
 
@@ -263,3 +277,33 @@ func TestComputeNextRun_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// TestValidateSchedule 测试调度定义校验
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		wantErr  bool
+	}{
+		{"标准 cron 表达式", Schedule{Kind: "cron", Expr: "0 9 * * 1-5"}, false},
+		{"描述符", Schedule{Kind: "cron", Expr: "@daily"}, false},
+		{"字段数量错误", Schedule{Kind: "cron", Expr: "0 9 * *"}, true},
+		{"字段超出范围", Schedule{Kind: "cron", Expr: "0 25 * * *"}, true},
+		{"空表达式", Schedule{Kind: "cron"}, true},
+		{"无效时区", Schedule{Kind: "cron", Expr: "0 9 * * *", Tz: "Mars/Base"}, true},
+		{"正常间隔", Schedule{Kind: "every", EveryMs: 60000}, false},
+		{"间隔过短", Schedule{Kind: "every", EveryMs: 1000}, true},
+		{"负间隔", Schedule{Kind: "every", EveryMs: -60000}, true},
+		{"间隔过长", Schedule{Kind: "every", EveryMs: MaxEveryMs + 1}, true},
+		{"过去的时间", Schedule{Kind: "at", AtMs: nowMs() - 1000}, true},
+		{"未知类型", Schedule{Kind: "weekly"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchedule(&tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSchedule 默认调度：每天 18:00
+	DefaultSchedule = "0 18 * * *"
+
+	// DefaultLookbackHours 默认收集最近 24 小时的活动
+	DefaultLookbackHours = 24
+
+	// DefaultPrompt 默认总结提示词
+	DefaultPrompt = `请把以下最近 %d 小时的活动整理成一份简洁的摘要，包括：主要事项、已完成的工作、待办和需要关注的问题。
+直接输出摘要正文，不要复述原始记录。`
+)
+
+// SummarizeFunc 把提示词交给 Agent 处理并返回摘要
+type SummarizeFunc func(ctx context.Context, prompt string) (string, error)
+
+// SendFunc 把摘要发送到目标聊天，返回渠道的发送结果
+type SendFunc func(channel, chatID, content string) error
+
+// Service 定时摘要服务
+// 按计划从活动来源收集最近的活动，交给 Agent 总结后发送到目标聊天；没有新活动时跳过
+type Service struct {
+	cfg       config.DigestConfig
+	channel   string
+	chatID    string
+	source    Source
+	summarize SummarizeFunc
+	send      SendFunc
+	cron      *cron.Cron
+	logger    *zap.Logger
+}
+
+// NewService 创建定时摘要服务，调度表达式或时区无效时返回错误
+func NewService(logger *zap.Logger, cfg config.DigestConfig, channel, chatID string, source Source, summarize SummarizeFunc, send SendFunc) (*Service, error) {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	loc := time.Local
+	if cfg.Timezone != "" {
+		l, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时区 %s: %w", cfg.Timezone, err)
+		}
+		loc = l
+	}
+	if cfg.Schedule == "" {
+		cfg.Schedule = DefaultSchedule
+	}
+	if _, err := cron.ParseStandard(cfg.Schedule); err != nil {
+		return nil, fmt.Errorf("无效的调度表达式 %q: %w", cfg.Schedule, err)
+	}
+	if cfg.LookbackHours <= 0 {
+		cfg.LookbackHours = DefaultLookbackHours
+	}
+
+	return &Service{
+		cfg:       cfg,
+		channel:   channel,
+		chatID:    chatID,
+		source:    source,
+		summarize: summarize,
+		send:      send,
+		cron:      cron.New(cron.WithLocation(loc)),
+		logger:    logger,
+	}, nil
+}
+
+// Start 启动定时摘要服务
+func (s *Service) Start(ctx context.Context) error {
+	if _, err := s.cron.AddFunc(s.cfg.Schedule, func() {
+		if _, err := s.RunNow(ctx); err != nil {
+			s.logger.Error("定时摘要执行失败", zap.Error(err))
+		}
+	}); err != nil {
+		return fmt.Errorf("添加摘要定时任务失败: %w", err)
+	}
+	s.cron.Start()
+	s.logger.Info("定时摘要服务已启动",
+		zap.String("调度", s.cfg.Schedule),
+		zap.String("来源", s.sourceName()),
+		zap.String("目标", s.channel+":"+s.chatID),
+	)
+	return nil
+}
+
+// Stop 停止定时摘要服务
+func (s *Service) Stop() {
+	if s.cron != nil {
+		s.cron.Stop()
+	}
+}
+
+// RunNow 立即生成并发送一次摘要，返回摘要内容；没有新活动时返回空字符串
+func (s *Service) RunNow(ctx context.Context) (string, error) {
+	until := time.Now()
+	since := until.Add(-time.Duration(s.cfg.LookbackHours) * time.Hour)
+
+	activity, err := s.source(ctx, since, until)
+	if err != nil {
+		return "", fmt.Errorf("收集活动失败: %w", err)
+	}
+	if strings.TrimSpace(activity) == "" {
+		s.logger.Info("定时摘要: 没有新活动，跳过")
+		return "", nil
+	}
+
+	summary, err := s.summarize(ctx, s.buildPrompt(activity))
+	if err != nil {
+		return "", fmt.Errorf("生成摘要失败: %w", err)
+	}
+	if strings.TrimSpace(summary) == "" {
+		return "", fmt.Errorf("生成的摘要为空")
+	}
+	if err := s.send(s.channel, s.chatID, summary); err != nil {
+		return summary, fmt.Errorf("发送摘要失败: %w", err)
+	}
+	s.logger.Info("定时摘要已发送",
+		zap.String("目标", s.channel+":"+s.chatID),
+		zap.Int("活动字符数", len([]rune(activity))),
+	)
+	return summary, nil
+}
+
+// buildPrompt 拼接总结提示词和活动记录
+func (s *Service) buildPrompt(activity string) string {
+	prompt := s.cfg.Prompt
+	if prompt == "" {
+		prompt = fmt.Sprintf(DefaultPrompt, s.cfg.LookbackHours)
+	}
+	return prompt + "\n\n--- 活动记录 ---\n" + activity
+}
+
+// sourceName 返回活动来源名称
+func (s *Service) sourceName() string {
+	if s.cfg.Source == "" {
+		return "sessions"
+	}
+	return s.cfg.Source
+}
@@ -0,0 +1,194 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tasktools "github.com/weibaohui/nanobot-go/agent/tools/task"
+	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/internal/models"
+	"go.uber.org/zap"
+)
+
+// mockRecords 返回固定对话记录的模拟仓库
+type mockRecords struct {
+	records []models.ConversationRecord
+}
+
+func (m *mockRecords) FindByTimeRange(ctx context.Context, startTime, endTime time.Time, opts *models.QueryOptions) ([]models.ConversationRecord, error) {
+	return m.records, nil
+}
+
+// mockTasks 返回固定任务列表的模拟任务管理器
+type mockTasks struct {
+	tasks []*tasktools.TaskInfo
+}
+
+func (m *mockTasks) ListTasksFiltered(ctx context.Context, from, to time.Time, status string) ([]*tasktools.TaskInfo, error) {
+	return m.tasks, nil
+}
+
+// TestNewSource 测试按配置创建活动来源
+func TestNewSource(t *testing.T) {
+	workspace := t.TempDir()
+	records := &mockRecords{}
+
+	if _, err := NewSource("", workspace, records, nil); err != nil {
+		t.Errorf("默认来源返回错误: %v", err)
+	}
+	if _, err := NewSource("sessions", workspace, nil, nil); err == nil {
+		t.Error("未启用数据库时 sessions 来源应返回错误")
+	}
+	if _, err := NewSource("tasks", workspace, nil, nil); err == nil {
+		t.Error("未启用后台任务时 tasks 来源应返回错误")
+	}
+	if _, err := NewSource("file:notes/today.md", workspace, nil, nil); err != nil {
+		t.Errorf("工作区内文件返回错误: %v", err)
+	}
+	if _, err := NewSource("file:../secret.txt", workspace, nil, nil); err == nil {
+		t.Error("工作区外文件应返回错误")
+	}
+	if _, err := NewSource("email", workspace, nil, nil); err == nil {
+		t.Error("不支持的来源应返回错误")
+	}
+}
+
+// TestSources 测试各活动来源收集的内容
+func TestSources(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+	ctx := context.Background()
+
+	t.Run("会话按会话分组并排除摘要会话", func(t *testing.T) {
+		source := SessionsSource(&mockRecords{records: []models.ConversationRecord{
+			{SessionKey: "feishu:oc_1", Role: "user", Content: "帮我订会议室", Timestamp: now},
+			{SessionKey: "matrix:!r", Role: "user", Content: "天气怎么样", Timestamp: now},
+			{SessionKey: "feishu:oc_1", Role: "assistant", Content: "已预订 3 楼会议室", Timestamp: now},
+			{SessionKey: SessionKey, Role: "assistant", Content: "上一次的摘要", Timestamp: now},
+			{SessionKey: "feishu:oc_1", Role: "assistant", Content: "", Timestamp: now},
+		}})
+		got, err := source(ctx, since, now)
+		if err != nil {
+			t.Fatalf("source() 返回错误: %v", err)
+		}
+		if strings.Count(got, "## 会话") != 2 || strings.Contains(got, "上一次的摘要") {
+			t.Errorf("source() = %q", got)
+		}
+		first := strings.Index(got, "帮我订会议室")
+		if first < 0 || !strings.Contains(got[first:strings.Index(got, "## 会话 matrix")], "已预订 3 楼会议室") {
+			t.Errorf("同一会话的消息应放在一起: %q", got)
+		}
+	})
+
+	t.Run("任务按创建时间过滤", func(t *testing.T) {
+		source := TasksSource(&mockTasks{tasks: []*tasktools.TaskInfo{
+			{ID: "t1", Status: "finished", ResultSummary: "周报已生成", CreatedAt: now.Add(-time.Hour)},
+			{ID: "t0", Status: "finished", ResultSummary: "很久以前", CreatedAt: now.Add(-48 * time.Hour)},
+		}})
+		got, _ := source(ctx, since, now)
+		if !strings.Contains(got, "任务 t1（finished）: 周报已生成") || strings.Contains(got, "t0") {
+			t.Errorf("source() = %q", got)
+		}
+	})
+
+	t.Run("文件未修改时视为没有活动", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "log.md")
+		source := FileSource(path)
+		if got, err := source(ctx, since, now); got != "" || err != nil {
+			t.Errorf("文件不存在时 source() = %q, %v", got, err)
+		}
+
+		os.WriteFile(path, []byte("今天完成了部署\n"), 0644)
+		if got, _ := source(ctx, since, now); got != "今天完成了部署" {
+			t.Errorf("source() = %q", got)
+		}
+
+		old := now.Add(-48 * time.Hour)
+		os.Chtimes(path, old, old)
+		if got, _ := source(ctx, since, now); got != "" {
+			t.Errorf("文件未修改时 source() = %q", got)
+		}
+	})
+
+	t.Run("超长内容保留最近部分", func(t *testing.T) {
+		got := tail(strings.Repeat("旧", 10)+strings.Repeat("新", 5), 5)
+		if got != "...(更早的内容已省略)\n新新新新新" {
+			t.Errorf("tail() = %q", got)
+		}
+	})
+}
+
+// TestService_RunNow 测试生成并发送摘要
+func TestService_RunNow(t *testing.T) {
+	ctx := context.Background()
+	newService := func(activity string, sendErr error) (*Service, *string, *[]string) {
+		var prompt string
+		var sent []string
+		service, err := NewService(zap.NewNop(), config.DigestConfig{LookbackHours: 12}, "feishu", "oc_1",
+			func(ctx context.Context, since, until time.Time) (string, error) {
+				if until.Sub(since) != 12*time.Hour {
+					t.Errorf("收集时间段 = %v, 期望 12h", until.Sub(since))
+				}
+				return activity, nil
+			},
+			func(ctx context.Context, p string) (string, error) {
+				prompt = p
+				return "今日摘要：完成部署", nil
+			},
+			func(channel, chatID, content string) error {
+				sent = append(sent, channel+":"+chatID+"="+content)
+				return sendErr
+			},
+		)
+		if err != nil {
+			t.Fatalf("NewService() 返回错误: %v", err)
+		}
+		return service, &prompt, &sent
+	}
+
+	t.Run("总结并发送", func(t *testing.T) {
+		service, prompt, sent := newService("[10:00] user: 部署服务", nil)
+		summary, err := service.RunNow(ctx)
+		if err != nil || summary != "今日摘要：完成部署" {
+			t.Fatalf("RunNow() = %q, %v", summary, err)
+		}
+		if !strings.Contains(*prompt, "最近 12 小时") || !strings.HasSuffix(*prompt, "[10:00] user: 部署服务") {
+			t.Errorf("提示词 = %q", *prompt)
+		}
+		if len(*sent) != 1 || (*sent)[0] != "feishu:oc_1=今日摘要：完成部署" {
+			t.Errorf("发送记录 = %v", *sent)
+		}
+	})
+
+	t.Run("没有活动时跳过", func(t *testing.T) {
+		service, prompt, sent := newService("  ", nil)
+		if summary, err := service.RunNow(ctx); summary != "" || err != nil {
+			t.Errorf("RunNow() = %q, %v", summary, err)
+		}
+		if *prompt != "" || len(*sent) != 0 {
+			t.Error("没有活动时不应调用 Agent 或发送")
+		}
+	})
+
+	t.Run("发送失败返回错误", func(t *testing.T) {
+		service, _, _ := newService("活动", errors.New("渠道不可用"))
+		if _, err := service.RunNow(ctx); err == nil || !strings.Contains(err.Error(), "渠道不可用") {
+			t.Errorf("RunNow() error = %v", err)
+		}
+	})
+}
+
+// TestNewService_InvalidConfig 测试无效的调度配置
+func TestNewService_InvalidConfig(t *testing.T) {
+	if _, err := NewService(nil, config.DigestConfig{Schedule: "每天六点"}, "feishu", "oc_1", nil, nil, nil); err == nil {
+		t.Error("无效的调度表达式应返回错误")
+	}
+	if _, err := NewService(nil, config.DigestConfig{Timezone: "Mars/Base"}, "feishu", "oc_1", nil, nil, nil); err == nil {
+		t.Error("无效的时区应返回错误")
+	}
+}
@@ -0,0 +1,141 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/agent/tools/common"
+	tasktools "github.com/weibaohui/nanobot-go/agent/tools/task"
+	"github.com/weibaohui/nanobot-go/internal/models"
+)
+
+// SessionKey 生成摘要使用的会话，收集会话活动时排除，避免摘要总结上一次的摘要
+const SessionKey = "digest:"
+
+// maxSourceChars 交给 Agent 总结的活动文本最大长度（字符），超出时保留最近的部分
+const maxSourceChars = 20000
+
+// Source 收集 [since, until] 时间段内的活动，返回交给 Agent 总结的文本；没有活动时返回空字符串
+type Source func(ctx context.Context, since, until time.Time) (string, error)
+
+// RecordFinder 按时间范围查询对话记录
+type RecordFinder interface {
+	FindByTimeRange(ctx context.Context, startTime, endTime time.Time, opts *models.QueryOptions) ([]models.ConversationRecord, error)
+}
+
+// TaskLister 按时间范围查询后台任务
+type TaskLister interface {
+	ListTasksFiltered(ctx context.Context, from, to time.Time, status string) ([]*tasktools.TaskInfo, error)
+}
+
+// NewSource 按配置创建活动来源
+// source 为 sessions（默认）、tasks 或 file:<路径>，文件路径相对工作区且不能超出工作区
+func NewSource(source, workspace string, records RecordFinder, tasks TaskLister) (Source, error) {
+	switch {
+	case source == "" || source == "sessions":
+		if records == nil {
+			return nil, fmt.Errorf("sessions 来源需要启用数据库记录对话")
+		}
+		return SessionsSource(records), nil
+	case source == "tasks":
+		if tasks == nil {
+			return nil, fmt.Errorf("tasks 来源需要启用后台任务")
+		}
+		return TasksSource(tasks), nil
+	case strings.HasPrefix(source, "file:"):
+		path := strings.TrimSpace(strings.TrimPrefix(source, "file:"))
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workspace, path)
+		}
+		path, err := common.ValidatePath(path, workspace)
+		if err != nil {
+			return nil, err
+		}
+		return FileSource(path), nil
+	}
+	return nil, fmt.Errorf("不支持的摘要来源: %s", source)
+}
+
+// SessionsSource 收集时间段内各会话的用户消息和助手回复，按会话分组
+func SessionsSource(records RecordFinder) Source {
+	return func(ctx context.Context, since, until time.Time) (string, error) {
+		list, err := records.FindByTimeRange(ctx, since, until, &models.QueryOptions{Roles: []string{"user", "assistant"}})
+		if err != nil {
+			return "", fmt.Errorf("查询对话记录失败: %w", err)
+		}
+
+		var order []string
+		bySession := make(map[string][]string)
+		for _, r := range list {
+			content := strings.TrimSpace(r.Content)
+			if content == "" || r.SessionKey == SessionKey {
+				continue
+			}
+			if _, ok := bySession[r.SessionKey]; !ok {
+				order = append(order, r.SessionKey)
+			}
+			bySession[r.SessionKey] = append(bySession[r.SessionKey],
+				fmt.Sprintf("[%s] %s: %s", r.Timestamp.Local().Format("01-02 15:04"), r.Role, content))
+		}
+
+		var sb strings.Builder
+		for _, key := range order {
+			fmt.Fprintf(&sb, "## 会话 %s\n%s\n\n", key, strings.Join(bySession[key], "\n"))
+		}
+		return tail(sb.String(), maxSourceChars), nil
+	}
+}
+
+// TasksSource 收集时间段内创建的后台任务及其状态和结果摘要
+func TasksSource(tasks TaskLister) Source {
+	return func(ctx context.Context, since, until time.Time) (string, error) {
+		list, err := tasks.ListTasksFiltered(ctx, since, until, "")
+		if err != nil {
+			return "", fmt.Errorf("查询后台任务失败: %w", err)
+		}
+
+		var sb strings.Builder
+		for _, task := range list {
+			// 任务按日期筛选，这里再按时间精确过滤
+			if task.CreatedAt.Before(since) || task.CreatedAt.After(until) {
+				continue
+			}
+			fmt.Fprintf(&sb, "- [%s] 任务 %s（%s）: %s\n", task.CreatedAt.Local().Format("01-02 15:04"), task.ID, task.Status, strings.TrimSpace(task.ResultSummary))
+		}
+		return tail(sb.String(), maxSourceChars), nil
+	}
+}
+
+// FileSource 读取指定文件作为活动来源，文件在时间段内没有修改时视为没有活动
+func FileSource(path string) Source {
+	return func(ctx context.Context, since, until time.Time) (string, error) {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.ModTime().Before(since) {
+			return "", nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("读取文件失败: %w", err)
+		}
+		return tail(strings.TrimSpace(string(data)), maxSourceChars), nil
+	}
+}
+
+// tail 返回文本最后 maxChars 个字符，截断时在开头注明
+func tail(s string, maxChars int) string {
+	runes := []rune(s)
+	if len(runes) <= maxChars {
+		return s
+	}
+	return "...(更早的内容已省略)\n" + string(runes[len(runes)-maxChars:])
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/robfig/cron/v3"
 	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/utils"
 	"go.uber.org/zap"
 )
 
@@ -175,10 +176,7 @@ func (s *Service) getAckMaxChars() int {
 
 // truncateResponse 截断响应消息
 func truncateResponse(response string, maxChars int) string {
-	if len(response) <= maxChars {
-		return response
-	}
-	return response[:maxChars] + "..."
+	return utils.TruncateString(response, maxChars)
 }
 
 // Start 启动心跳服务
@@ -19,6 +19,12 @@ type ConversationRecord struct {
 	TotalTokens      int       `gorm:"type:integer;default:0" json:"total_tokens"`
 	ReasoningTokens  int       `gorm:"type:integer;default:0" json:"reasoning_tokens"`
 	CachedTokens     int       `gorm:"type:integer;default:0" json:"cached_tokens"`
+	FinishReason     string    `gorm:"type:text" json:"finish_reason,omitempty"`     // 模型结束原因，如 length、content_filter
+	ToolCalls        string    `gorm:"type:text" json:"tool_calls,omitempty"`        // 助手消息发起的工具调用列表（JSON）
+	ToolCallID       string    `gorm:"type:text" json:"tool_call_id,omitempty"`      // tool 消息对应的工具调用 ID
+	ToolName         string    `gorm:"type:text" json:"tool_name,omitempty"`         // tool 消息对应的工具名称
+	LogProbs         string    `gorm:"type:text" json:"logprobs,omitempty"`          // 输出 token 的对数概率（JSON），仅在开启 logProbs 时记录
+	ReasoningContent string    `gorm:"type:text" json:"reasoning_content,omitempty"` // 推理模型的思考过程，仅用于查看，不会回放给模型
 	CreatedAt        time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP" json:"created_at"`
 }
 
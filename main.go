@@ -1,12 +1,16 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -25,14 +29,17 @@ import (
 	"github.com/weibaohui/nanobot-go/channels"
 	"github.com/weibaohui/nanobot-go/config"
 	"github.com/weibaohui/nanobot-go/cron"
+	"github.com/weibaohui/nanobot-go/digest"
 	"github.com/weibaohui/nanobot-go/heartbeat"
 	memoryhandler "github.com/weibaohui/nanobot-go/memory/handler"
 	memoryjob "github.com/weibaohui/nanobot-go/memory/job"
 	memoryrepo "github.com/weibaohui/nanobot-go/memory/repository"
 	memoryservice "github.com/weibaohui/nanobot-go/memory/service"
 	"github.com/weibaohui/nanobot-go/session"
+	"github.com/weibaohui/nanobot-go/utils"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 
 	"github.com/weibaohui/nanobot-go/internal/models"
 )
@@ -50,6 +57,18 @@ func (a *convRepoAdapter) FindBySessionKey(ctx context.Context, sessionKey strin
 	return a.repo.FindBySessionKey(ctx, sessionKey, opts)
 }
 
+func (a *convRepoAdapter) FindSessionKeysByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	return a.repo.FindSessionKeysByPrefix(ctx, prefix)
+}
+
+func (a *convRepoAdapter) CreateBatch(ctx context.Context, records []models.ConversationRecord) error {
+	return a.repo.CreateBatch(ctx, records)
+}
+
+func (a *convRepoAdapter) DeleteByID(ctx context.Context, id uint) error {
+	return a.repo.DeleteByID(ctx, id)
+}
+
 var (
 	version   = "dev"
 	buildDate = "unknown"
@@ -67,6 +86,9 @@ var (
 	gatewayVerbose bool
 )
 
+// logRedactor 日志脱敏器，加载配置后会注册配置中的密钥
+var logRedactor = utils.NewRedactor()
+
 var rootCmd = &cobra.Command{
 	Use:   "nanobot",
 	Short: "🐈 nanobot - 个人 AI 助手",
@@ -87,6 +109,25 @@ var onboardCmd = &cobra.Command{
 	Run:   runOnboard,
 }
 
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "配置管理",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "校验配置",
+	Long:  `校验配置文件，并检查默认模型在提供商处是否存在。`,
+	Run:   runConfigValidate,
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "诊断运行环境",
+	Long:  `依次检查配置、模型提供商连通性与延迟、工作区写权限和已启用渠道的凭证，输出诊断报告。`,
+	Run:   runDoctor,
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "显示版本",
@@ -103,6 +144,9 @@ func init() {
 
 	rootCmd.AddCommand(gatewayCmd)
 	rootCmd.AddCommand(onboardCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
@@ -121,6 +165,39 @@ func runGateway(cmd *cobra.Command, args []string) {
 
 	cfg, workspacePath := loadConfigAndWorkspace(logger)
 
+	// 按配置重新创建日志，配置无效时继续输出到 stderr
+	if configured, err := newLogger(cfg.Log, debugGlobal || gatewayVerbose, filepath.Join(workspacePath, ".nanobot", "logs", "nanobot.log")); err != nil {
+		logger.Error("日志配置无效，继续输出到 stderr", zap.Error(err))
+	} else {
+		logger = configured
+		defer logger.Sync()
+	}
+
+	// 按用途配置的模型无效时改用默认模型
+	if err := cfg.ValidateRoleModels(); err != nil {
+		logger.Error("按用途配置的模型无效，已改用默认模型", zap.Error(err))
+		cfg.Agents.Roles = config.RoleModelsConfig{}
+	}
+
+	// 压缩配置无效时禁用压缩，不影响主流程
+	if err := cfg.ValidateCompress(); err != nil {
+		logger.Error("对话压缩配置无效，已禁用压缩", zap.Error(err))
+		cfg.Compress.Enabled = false
+	}
+	// 消息工具默认目标无效时不使用，避免通知发往不存在的渠道
+	if err := cfg.ValidateMessageTarget(); err != nil {
+		logger.Error("消息工具默认目标无效，已忽略", zap.Error(err))
+		cfg.Tools.Message = config.MessageToolConfig{}
+	}
+	if err := cfg.ValidateContacts(); err != nil {
+		logger.Warn("联系人配置有误", zap.Error(err))
+	}
+
+	// 多个实例共用日志时按 Agent 名称区分
+	if cfg.Agents.Defaults.Name != "" {
+		logger = logger.With(zap.String("agent", cfg.Agents.Defaults.AgentName()))
+	}
+
 	logger.Info("nanobot gateway 启动中",
 		zap.Int("端口", gatewayPort),
 		zap.String("工作区", workspacePath),
@@ -128,12 +205,67 @@ func runGateway(cmd *cobra.Command, args []string) {
 		zap.String("构建时间", buildDate),
 	)
 
-	messageBus := bus.NewMessageBus(logger)
+	messageBus := bus.NewMessageBusWithOptions(logger, bus.Options{
+		InboundCapacity: cfg.Bus.InboundCapacity,
+		DropWhenFull:    cfg.Bus.DropWhenFull,
+	})
 
 	dataDir := filepath.Join(workspacePath, ".nanobot")
 
+	// 初始化消息审计日志（如果启用）
+	if cfg.Audit.Enabled {
+		auditLog, err := bus.NewAuditLog(filepath.Join(dataDir, "audit"), cfg.Audit.IncludeContent)
+		if err != nil {
+			logger.Error("初始化审计日志失败", zap.Error(err))
+		} else {
+			messageBus.SetAuditLog(auditLog)
+			defer auditLog.Close()
+			logger.Info("消息审计日志已启用", zap.Bool("记录原文", cfg.Audit.IncludeContent))
+		}
+	}
+
+	// 初始化出站消息重试策略和死信日志
+	messageBus.SetRetryPolicy(retryPolicy(cfg.Delivery))
+	if deadLetter, err := bus.NewDeadLetterLog(filepath.Join(dataDir, "deadletter")); err != nil {
+		logger.Error("初始化死信日志失败", zap.Error(err))
+	} else {
+		messageBus.SetDeadLetterLog(deadLetter)
+		defer deadLetter.Close()
+	}
+	defer func() {
+		stats := messageBus.SendStats()
+		logger.Info("出站消息发送统计",
+			zap.Int64("失败次数", stats.Failures),
+			zap.Int64("重试次数", stats.Retries),
+			zap.Int64("重试成功", stats.Recovered),
+			zap.Int64("死信数量", stats.DeadLetters),
+		)
+	}()
+	defer func() {
+		for _, stats := range agent.ToolStatsSnapshot() {
+			logger.Info("工具调用统计",
+				zap.String("工具", stats.Name),
+				zap.Int64("调用次数", stats.Calls),
+				zap.Int64("失败次数", stats.Failures),
+				zap.Duration("平均耗时", stats.AvgLatency()),
+				zap.Duration("最长耗时", stats.MaxLatency),
+			)
+		}
+	}()
+	if cfg.Providers.MaxConcurrency > 0 {
+		defer func() {
+			stats := agent.ProviderRequestStatsSnapshot()
+			logger.Info("提供商请求并发限制统计",
+				zap.Int64("请求数", stats.Requests),
+				zap.Int64("排队次数", stats.Waits),
+				zap.Duration("累计排队时间", stats.WaitTime),
+			)
+		}()
+	}
+
 	// 初始化数据库和对话记录仓库
 	var convRepo session.ConversationRecordRepository
+	var recordRepo repository.ConversationRecordRepository
 	var dbClient *database.Client
 	if dbConfig := database.NewConfigFromConfig(cfg); dbConfig != nil {
 		var err error
@@ -145,7 +277,8 @@ func runGateway(cmd *cobra.Command, args []string) {
 				logger.Error("初始化数据库 schema 失败", zap.Error(err))
 				dbClient.Close()
 			} else {
-				convRepo = newConvRepoAdapter(repository.NewConversationRecordRepository(dbClient.DB()))
+				recordRepo = repository.NewConversationRecordRepository(dbClient.DB())
+				convRepo = newConvRepoAdapter(recordRepo)
 				logger.Info("数据库和对话记录仓库已初始化")
 			}
 		}
@@ -227,6 +360,17 @@ func runGateway(cmd *cobra.Command, args []string) {
 		)
 	}
 
+	// 如果启用了工具进度推送，注册 ToolProgressObserver
+	if cfg.ThinkingProcess.ToolProgress {
+		hookSystem.Register(observers.NewToolProgressObserver(messageBus, logger, nil))
+		logger.Info("工具进度观察器已启用")
+	}
+
+	// 注册 ReasoningObserver，按会话设置（/reasoning）或全局配置决定是否推送思考过程，默认隐藏
+	hookSystem.Register(observers.NewReasoningObserver(messageBus, func(sessionKey string) bool {
+		return sessionManager.ShowReasoning(sessionKey, cfg.ThinkingProcess.Reasoning)
+	}, logger, nil))
+
 	// 注册 SQLiteObserver - 负责将所有事件存储到 SQLite 数据库
 	if sqliteObserver, err := observers.NewSQLiteObserverFromConfig(cfg, logger, nil); err != nil {
 		logger.Error("创建 SQLite 观察器失败", zap.Error(err))
@@ -235,6 +379,23 @@ func runGateway(cmd *cobra.Command, args []string) {
 		logger.Info("SQLite 观察器已注册到 Hook 系统", zap.String("db_path", sqliteObserver.GetDBPath()))
 	}
 
+	// 配置了 Webhook 地址时，注册 WebhookObserver 将生命周期事件推送到外部系统
+	if cfg.Webhook.URL != "" {
+		webhookObserver := observers.NewWebhookObserver(cfg.Webhook, logger)
+		hookSystem.Register(webhookObserver)
+		defer webhookObserver.Close()
+		logger.Info("事件 Webhook 已启用", zap.String("url", cfg.Webhook.URL))
+	}
+
+	// 配置了告警接收渠道时，注册 AlertObserver 将错误率过高、响应过慢等告警发送给管理员
+	if alertObserver := observers.NewAlertObserverFromConfig(cfg.Alerts, messageBus, logger); alertObserver != nil {
+		hookSystem.Register(alertObserver)
+		logger.Info("告警通知已启用",
+			zap.String("channel", cfg.Alerts.Channel),
+			zap.String("chat_id", cfg.Alerts.ChatID),
+		)
+	}
+
 	// 注意：Eino Callback 已移除，事件通过 provider.go 直接触发
 	// 如需恢复，取消下面这行的注释：
 	// callbacks.AppendGlobalHandlers(hookSystem.EinoHandler())
@@ -370,7 +531,7 @@ func runGateway(cmd *cobra.Command, args []string) {
 	channelManager.Register(cliChannel)
 
 	// 注册配置中启用的渠道
-	registerChannels(channelManager, cfg, messageBus, logger)
+	registerChannels(channelManager, cfg, messageBus, sessionManager, logger)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -378,6 +539,12 @@ func runGateway(cmd *cobra.Command, args []string) {
 	// 启动消息分发器，将出站消息分发给各渠道
 	messageBus.StartDispatcher(ctx)
 
+	cronService.SetOnJobCallback(cronJobHandler(ctx, loop, messageBus))
+	// 任务执行失败时发出事件，由告警等观察器处理
+	cronService.SetOnJobFailedCallback(func(job *cron.Job, err error) {
+		event := hookevents.NewCronJobFailedEvent(hooks.GetTraceID(ctx), job.ID, job.Name, err)
+		hookSystem.Dispatch(ctx, event, "cron", "cron:"+job.ID)
+	})
 	if err := cronService.Start(ctx); err != nil {
 		logger.Error("启动定时任务服务失败", zap.Error(err))
 	}
@@ -447,6 +614,9 @@ func runGateway(cmd *cobra.Command, args []string) {
 		logger.Error("启动心跳服务失败", zap.Error(err))
 	}
 
+	// 启动定时摘要服务（如果启用）
+	digestService := newDigestService(ctx, cfg, workspacePath, loop, messageBus, recordRepo, logger)
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -460,6 +630,30 @@ func runGateway(cmd *cobra.Command, args []string) {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
+	logger.Info("正在关闭，停止接收新消息...")
+	loop.Stop()
+
+	// 排空阶段：等待进行中的回合完成并把回复交给渠道发送
+	drainTimeout := 30 * time.Second
+	if cfg.Gateway.ShutdownTimeout > 0 {
+		drainTimeout = time.Duration(cfg.Gateway.ShutdownTimeout) * time.Second
+	}
+	drainStart := time.Now()
+	if loop.Drain(drainTimeout) {
+		logger.Info("进行中的回合已完成")
+	} else {
+		logger.Warn("等待进行中的回合超时，强制关闭", zap.Duration("timeout", drainTimeout))
+	}
+	if remaining := drainTimeout - time.Since(drainStart); remaining > 0 && !messageBus.WaitOutboundFlushed(remaining) {
+		logger.Warn("等待出站消息发送超时", zap.Int("待发送", messageBus.OutboundSize()))
+	}
+	if pending := messageBus.InboundSize(); pending > 0 {
+		logger.Warn("仍有未处理的入站消息", zap.Int("数量", pending))
+	}
+
+	// 停止分发器，把仍在队列中的出站消息发送出去
+	messageBus.Stop()
+
 	logger.Info("正在关闭...")
 	cancel()
 
@@ -479,10 +673,134 @@ func runGateway(cmd *cobra.Command, args []string) {
 
 	cronService.Stop()
 	heartbeatService.Stop()
+	if digestService != nil {
+		digestService.Stop()
+	}
 	channelManager.StopAll()
 	logger.Info("已关闭")
 }
 
+// ========== Config 命令实现 ==========
+
+func runConfigValidate(cmd *cobra.Command, args []string) {
+	logger := initLogger(debugGlobal)
+	defer logger.Sync()
+
+	cfg, _ := loadConfigAndWorkspace(logger)
+
+	failed := false
+	if err := cfg.ValidateRoleModels(); err != nil {
+		fmt.Printf("✗ 按用途配置的模型: %s\n", err)
+		failed = true
+	}
+	if err := cfg.ValidateCompress(); err != nil {
+		fmt.Printf("✗ 对话压缩配置: %s\n", err)
+		failed = true
+	}
+	if err := cfg.ValidateMessageTarget(); err != nil {
+		fmt.Printf("✗ 消息工具默认目标: %s\n", err)
+		failed = true
+	}
+	if err := cfg.ValidateContacts(); err != nil {
+		fmt.Printf("✗ 联系人: %s\n", err)
+		failed = true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := agent.ValidateDefaultModel(ctx, cfg); err != nil {
+		fmt.Printf("✗ 默认模型 %s: %s\n", cfg.Agents.Defaults.Model, err)
+		failed = true
+	} else {
+		fmt.Printf("✓ 默认模型 %s 可用\n", cfg.Agents.Defaults.Model)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println("✓ 配置有效")
+}
+
+// ========== Doctor 命令实现 ==========
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	logger := initLogger(debugGlobal)
+	defer logger.Sync()
+
+	cfg, workspacePath := loadConfigAndWorkspace(logger)
+
+	failed := false
+	fmt.Println("配置")
+	configOK := true
+	if err := cfg.ValidateRoleModels(); err != nil {
+		fmt.Printf("  ✗ 按用途配置的模型: %s\n", err)
+		configOK = false
+	}
+	if err := cfg.ValidateCompress(); err != nil {
+		fmt.Printf("  ✗ 对话压缩配置: %s\n", err)
+		configOK = false
+	}
+	if err := cfg.ValidateMessageTarget(); err != nil {
+		fmt.Printf("  ✗ 消息工具默认目标: %s\n", err)
+		configOK = false
+	}
+	if err := cfg.ValidateContacts(); err != nil {
+		fmt.Printf("  ✗ 联系人: %s\n", err)
+		configOK = false
+	}
+	if configOK {
+		fmt.Println("  ✓ 配置有效")
+	} else {
+		failed = true
+	}
+
+	fmt.Println("模型提供商")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := agent.ValidateDefaultModel(ctx, cfg); err != nil {
+		fmt.Printf("  ✗ 默认模型 %s: %s\n", cfg.Agents.Defaults.Model, err)
+		failed = true
+	} else if latency, err := agent.PingDefaultModel(ctx, cfg); err != nil {
+		fmt.Printf("  ✗ 默认模型 %s 补全失败: %s\n", cfg.Agents.Defaults.Model, err)
+		failed = true
+	} else {
+		fmt.Printf("  ✓ 默认模型 %s 可用，补全延迟 %s\n", cfg.Agents.Defaults.Model, latency.Round(time.Millisecond))
+	}
+
+	fmt.Println("工作区")
+	if err := checkWorkspaceWritable(workspacePath); err != nil {
+		fmt.Printf("  ✗ %s 不可写: %s\n", workspacePath, err)
+		failed = true
+	} else {
+		fmt.Printf("  ✓ %s 可写\n", workspacePath)
+	}
+
+	fmt.Println("渠道")
+	missing := cfg.Channels.MissingCredentials()
+	for _, channel := range slices.Sorted(maps.Keys(missing)) {
+		fmt.Printf("  ✗ %s 缺少: %s\n", channel, strings.Join(missing[channel], ", "))
+		failed = true
+	}
+	if len(missing) == 0 {
+		fmt.Println("  ✓ 已启用渠道的凭证齐全")
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println("✓ 所有检查通过")
+}
+
+// checkWorkspaceWritable 在工作区创建并删除一个临时文件，检查写权限
+func checkWorkspaceWritable(workspacePath string) error {
+	f, err := os.CreateTemp(workspacePath, ".nanobot-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
 // ========== Onboard 命令实现 ==========
 
 func runOnboard(cmd *cobra.Command, args []string) {
@@ -612,7 +930,21 @@ func createWorkspaceTemplates(workspace string) {
 
 // ========== 辅助函数 ==========
 
+// 日志文件轮转默认值
+const (
+	defaultLogMaxSizeMB  = 100
+	defaultLogMaxBackups = 5
+)
+
+// initLogger 创建输出到 stderr 的日志，用于加载配置之前
 func initLogger(debug bool) *zap.Logger {
+	logger, _ := newLogger(config.LogConfig{}, debug, "")
+	return logger
+}
+
+// newLogger 按日志配置创建日志，debug 为 true 时使用 debug 级别
+// 未配置日志文件路径时写入 defaultFile
+func newLogger(cfg config.LogConfig, debug bool, defaultFile string) (*zap.Logger, error) {
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "time",
 		LevelKey:       "level",
@@ -628,17 +960,58 @@ func initLogger(debug bool) *zap.Logger {
 	}
 
 	level := zapcore.InfoLevel
+	if cfg.Level != "" {
+		l, err := zapcore.ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, fmt.Errorf("无效的日志级别 %q", cfg.Level)
+		}
+		level = l
+	}
 	if debug {
 		level = zapcore.DebugLevel
 	}
 
-	core := zapcore.NewCore(
-		zapcore.NewConsoleEncoder(encoderConfig),
-		zapcore.AddSync(os.Stderr),
-		level,
-	)
+	var encoder zapcore.Encoder
+	switch cfg.Format {
+	case "", "console":
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	case "json":
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	default:
+		return nil, fmt.Errorf("无效的日志格式 %q，可选 console、json", cfg.Format)
+	}
+
+	var output zapcore.WriteSyncer
+	switch cfg.Output {
+	case "", "stderr":
+		output = zapcore.AddSync(os.Stderr)
+	case "file":
+		path := cfg.File
+		if path == "" {
+			path = defaultFile
+		}
+		if path == "" {
+			return nil, fmt.Errorf("未配置日志文件路径")
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("创建日志目录失败: %w", err)
+		}
+		output = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    cmp.Or(cfg.MaxSizeMB, defaultLogMaxSizeMB),
+			MaxBackups: cmp.Or(cfg.MaxBackups, defaultLogMaxBackups),
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		})
+	default:
+		return nil, fmt.Errorf("无效的日志输出 %q，可选 stderr、file", cfg.Output)
+	}
+
+	core := zapcore.NewCore(encoder, output, level)
 
-	return zap.New(core, zap.AddCaller())
+	// 写入前对 Bearer 令牌、API Key 等敏感信息脱敏
+	return zap.New(utils.NewRedactingCore(core, logRedactor), zap.AddCaller()), nil
 }
 
 func loadConfigAndWorkspace(logger *zap.Logger) (*config.Config, string) {
@@ -656,6 +1029,7 @@ func loadConfigAndWorkspace(logger *zap.Logger) (*config.Config, string) {
 	if err != nil {
 		logger.Fatal("加载配置失败", zap.Error(err))
 	}
+	logRedactor.AddSecrets(cfg.Secrets()...)
 
 	// 如果配置文件中指定了 workspace 且命令行未指定，使用配置中的路径
 	if agentWorkspace == "" && cfg.Agents.Defaults.Workspace != "" {
@@ -751,16 +1125,151 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// retryPolicy 将配置文件中的投递配置转换为消息总线重试策略，未配置的字段使用默认值
+func retryPolicy(cfg config.DeliveryConfig) bus.RetryPolicy {
+	policy := bus.DefaultRetryPolicy()
+	if cfg.MaxAttempts > 0 {
+		policy.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.BackoffMs > 0 {
+		policy.InitialBackoff = time.Duration(cfg.BackoffMs) * time.Millisecond
+	}
+	if cfg.MaxBackoffMs > 0 {
+		policy.MaxBackoff = time.Duration(cfg.MaxBackoffMs) * time.Millisecond
+	}
+	return policy
+}
+
+// pacingConfig 将配置文件中的回复节奏配置转换为渠道配置
+func pacingConfig(cfg config.PacingConfig) channels.PacingConfig {
+	return channels.PacingConfig{
+		Mode:     cfg.Mode,
+		Delay:    time.Duration(cfg.DelayMs) * time.Millisecond,
+		PerChar:  time.Duration(cfg.PerCharMs) * time.Millisecond,
+		MaxDelay: time.Duration(cfg.MaxDelayMs) * time.Millisecond,
+	}
+}
+
+// webSocketUITips 将配置文件中的快捷提示转换为渠道配置
+func webSocketUITips(tips []config.WebSocketUITip) []channels.WebSocketUITip {
+	result := make([]channels.WebSocketUITip, 0, len(tips))
+	for _, tip := range tips {
+		result = append(result, channels.WebSocketUITip{Label: tip.Label, Message: tip.Message})
+	}
+	return result
+}
+
+// webSocketHistory 从会话记录中读取 WebSocket 页面的历史消息，只保留用户与助手的对话
+func webSocketHistory(sessions *session.Manager) channels.HistoryProvider {
+	return func(ctx context.Context, chatID string, limit int) []channels.HistoryMessage {
+		key := sessions.ResolveKey("websocket:" + chatID)
+		var messages []channels.HistoryMessage
+		for _, msg := range sessions.GetHistory(ctx, key, limit) {
+			role, _ := msg["role"].(string)
+			content, _ := msg["content"].(string)
+			// 工具消息和只发起工具调用的助手消息不展示
+			if (role != "user" && role != "assistant") || content == "" {
+				continue
+			}
+			messages = append(messages, channels.HistoryMessage{Role: role, Content: content})
+		}
+		return messages
+	}
+}
+
+// cronJobHandler 返回定时任务的执行回调：run_agent 任务交给 Agent 完整处理（可使用工具），
+// 每个任务使用独立的会话 cron:<任务ID>；其余任务直接投递任务消息
+func cronJobHandler(ctx context.Context, loop *agent.Loop, messageBus *bus.MessageBus) cron.OnJobCallback {
+	return func(job *cron.Job) (string, error) {
+		payload := job.Payload
+		response := payload.Message
+		if payload.RunAgent {
+			channel, chatID := payload.Channel, payload.To
+			if channel == "" || chatID == "" {
+				channel, chatID = "cron", job.ID
+			}
+			var err error
+			response, err = loop.ProcessDirect(ctx, payload.Message, "cron:"+job.ID, channel, chatID)
+			if err != nil {
+				return response, err
+			}
+		}
+		if payload.Deliver && payload.Channel != "" && payload.To != "" {
+			messageBus.PublishOutbound(bus.NewOutboundMessage(payload.Channel, payload.To, response))
+		}
+		return response, nil
+	}
+}
+
+// newDigestService 按配置创建并启动定时摘要服务，未启用或配置无效时返回 nil
+// 摘要由 Agent 在独立会话 digest: 中生成，通过渠道同步发送
+func newDigestService(ctx context.Context, cfg *config.Config, workspacePath string, loop *agent.Loop, messageBus *bus.MessageBus, recordRepo repository.ConversationRecordRepository, logger *zap.Logger) *digest.Service {
+	if !cfg.Digest.Enabled {
+		return nil
+	}
+	channel, chatID, err := cfg.DigestTarget()
+	if err != nil {
+		logger.Error("定时摘要配置无效，已禁用", zap.Error(err))
+		return nil
+	}
+
+	var records digest.RecordFinder
+	if recordRepo != nil {
+		records = recordRepo
+	}
+	var tasks digest.TaskLister
+	if taskManager := loop.GetTaskManager(); taskManager != nil {
+		tasks = taskManager
+	}
+	source, err := digest.NewSource(cfg.Digest.Source, workspacePath, records, tasks)
+	if err != nil {
+		logger.Error("定时摘要来源无效，已禁用", zap.Error(err))
+		return nil
+	}
+
+	summarize := func(ctx context.Context, prompt string) (string, error) {
+		return loop.ProcessDirect(ctx, prompt, digest.SessionKey, "digest", "digest")
+	}
+	send := func(channel, chatID, content string) error {
+		return messageBus.SendOutbound(bus.NewOutboundMessage(channel, chatID, content))
+	}
+	service, err := digest.NewService(logger, cfg.Digest, channel, chatID, source, summarize, send)
+	if err != nil {
+		logger.Error("定时摘要配置无效，已禁用", zap.Error(err))
+		return nil
+	}
+	if err := service.Start(ctx); err != nil {
+		logger.Error("启动定时摘要服务失败", zap.Error(err))
+		return nil
+	}
+	return service
+}
+
 // registerChannels 根据配置注册启用的渠道
-func registerChannels(mgr *channels.Manager, cfg *config.Config, messageBus *bus.MessageBus, logger *zap.Logger) {
+func registerChannels(mgr *channels.Manager, cfg *config.Config, messageBus *bus.MessageBus, sessions *session.Manager, logger *zap.Logger) {
 	// WebSocket 渠道（默认启用）
 	if cfg.Channels.WebSocket.Enabled {
 		wsConfig := &channels.WebSocketConfig{
-			Addr:      cfg.Channels.WebSocket.Addr,
-			Path:      cfg.Channels.WebSocket.Path,
-			AllowFrom: cfg.Channels.WebSocket.AllowFrom,
+			Addr:           cfg.Channels.WebSocket.Addr,
+			Path:           cfg.Channels.WebSocket.Path,
+			AllowFrom:      cfg.Channels.WebSocket.AllowFrom,
+			AllowedOrigins: cfg.Channels.WebSocket.AllowedOrigins,
+			AuthToken:      cfg.Channels.WebSocket.AuthToken,
+			AuthTokens:     cfg.Channels.WebSocket.AuthTokens,
+			HistoryLimit:   cfg.Channels.WebSocket.HistoryLimit,
+			UI: channels.WebSocketUIConfig{
+				Dir:         cfg.Channels.WebSocket.UI.Dir,
+				Title:       cfg.Channels.WebSocket.UI.Title,
+				Logo:        cfg.Channels.WebSocket.UI.Logo,
+				ThemeColor:  cfg.Channels.WebSocket.UI.ThemeColor,
+				AccentColor: cfg.Channels.WebSocket.UI.AccentColor,
+				Welcome:     cfg.Channels.WebSocket.UI.Welcome,
+				Tips:        webSocketUITips(cfg.Channels.WebSocket.UI.Tips),
+			},
 		}
 		ws := channels.NewWebSocketChannel(wsConfig, messageBus, logger)
+		ws.SetPacing(pacingConfig(cfg.Channels.WebSocket.Pacing))
+		ws.SetHistoryProvider(webSocketHistory(sessions))
 		mgr.Register(ws)
 		if wsConfig.Addr != "" {
 			logger.Info("已注册 WebSocket 渠道", zap.String("addr", wsConfig.Addr), zap.String("path", wsConfig.Path))
@@ -777,6 +1286,7 @@ func registerChannels(mgr *channels.Manager, cfg *config.Config, messageBus *bus
 			AllowFrom:    cfg.Channels.DingTalk.AllowFrom,
 		}
 		dingtalk := channels.NewDingTalkChannel(dingtalkConfig, messageBus, logger)
+		dingtalk.SetPacing(pacingConfig(cfg.Channels.DingTalk.Pacing))
 		mgr.Register(dingtalk)
 		logger.Info("已注册钉钉渠道")
 	}
@@ -784,13 +1294,16 @@ func registerChannels(mgr *channels.Manager, cfg *config.Config, messageBus *bus
 	// Matrix 渠道
 	if cfg.Channels.Matrix.Enabled {
 		matrixConfig := &channels.MatrixConfig{
-			Homeserver: cfg.Channels.Matrix.Homeserver,
-			UserID:     cfg.Channels.Matrix.UserID,
-			Token:      cfg.Channels.Matrix.Token,
-			AllowFrom:  cfg.Channels.Matrix.AllowFrom,
-			DataDir:    cfg.Channels.Matrix.DataDir,
+			Homeserver:     cfg.Channels.Matrix.Homeserver,
+			UserID:         cfg.Channels.Matrix.UserID,
+			Token:          cfg.Channels.Matrix.Token,
+			AllowFrom:      cfg.Channels.Matrix.AllowFrom,
+			DataDir:        cfg.Channels.Matrix.DataDir,
+			CatchUpLimit:   cfg.Channels.Matrix.CatchUpLimit,
+			MaxTypingRooms: cfg.Channels.Matrix.MaxTypingRooms,
 		}
 		matrix := channels.NewMatrixChannel(matrixConfig, messageBus, logger)
+		matrix.SetPacing(pacingConfig(cfg.Channels.Matrix.Pacing))
 		mgr.Register(matrix)
 		logger.Info("已注册 Matrix 渠道",
 			zap.String("homeserver", matrixConfig.Homeserver),
@@ -808,6 +1321,7 @@ func registerChannels(mgr *channels.Manager, cfg *config.Config, messageBus *bus
 			AllowFrom:         cfg.Channels.Feishu.AllowFrom,
 		}
 		feishu := channels.NewFeishuChannel(feishuConfig, messageBus, logger)
+		feishu.SetPacing(pacingConfig(cfg.Channels.Feishu.Pacing))
 		mgr.Register(feishu)
 		logger.Info("已注册飞书渠道",
 			zap.String("app_id", feishuConfig.AppID),
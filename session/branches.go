@@ -0,0 +1,125 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/weibaohui/nanobot-go/internal/models"
+	"go.uber.org/zap"
+)
+
+// branchesFile 数据目录中保存各会话当前使用分支的文件
+const branchesFile = "branches.json"
+
+// branchExists 判断会话键是否已被使用：已在内存中，或数据库中已有该会话键的对话记录
+func (m *Manager) branchExists(ctx context.Context, key string) (bool, error) {
+	m.mu.RLock()
+	_, cached := m.cache[key]
+	m.mu.RUnlock()
+	if cached || m.convRepo == nil {
+		return cached, nil
+	}
+	records, err := m.convRepo.FindBySessionKey(ctx, key, &models.QueryOptions{Limit: 1})
+	if err != nil {
+		return false, fmt.Errorf("查询对话记录失败: %w", err)
+	}
+	return len(records) > 0, nil
+}
+
+// ListBranches 列出原始会话及其所有分支的会话键，按字母顺序排列，原始会话键排在最前
+// 包括内存中的分支、数据库中已有对话记录的分支以及当前使用的分支，重启后与 Switch 的判断保持一致
+func (m *Manager) ListBranches(ctx context.Context, key string) ([]string, error) {
+	base := BaseKey(key)
+	seen := make(map[string]bool)
+	m.mu.RLock()
+	for cached := range m.cache {
+		if cached != base && BaseKey(cached) == base {
+			seen[cached] = true
+		}
+	}
+	if active, ok := m.active[base]; ok {
+		seen[active] = true
+	}
+	m.mu.RUnlock()
+
+	if m.convRepo != nil {
+		persisted, err := m.convRepo.FindSessionKeysByPrefix(ctx, base+forkSeparator)
+		if err != nil {
+			return nil, fmt.Errorf("查询对话记录失败: %w", err)
+		}
+		for _, branch := range persisted {
+			seen[branch] = true
+		}
+	}
+
+	keys := make([]string, 0, len(seen)+1)
+	for branch := range seen {
+		keys = append(keys, branch)
+	}
+	sort.Strings(keys)
+	return append([]string{base}, keys...), nil
+}
+
+// branchesPath 返回分支映射文件路径，未配置数据目录时返回空字符串
+func (m *Manager) branchesPath() string {
+	if m.dataDir == "" {
+		return ""
+	}
+	return filepath.Join(m.dataDir, branchesFile)
+}
+
+// loadActive 从磁盘加载各会话当前使用的分支
+func (m *Manager) loadActive() {
+	path := m.branchesPath()
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) && m.logger != nil {
+			m.logger.Warn("读取会话分支失败", zap.Error(err))
+		}
+		return
+	}
+	var active map[string]string
+	if err := json.Unmarshal(data, &active); err != nil {
+		if m.logger != nil {
+			m.logger.Warn("解析会话分支失败", zap.Error(err))
+		}
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for base, key := range active {
+		m.active[base] = key
+	}
+}
+
+// saveActive 将各会话当前使用的分支写入磁盘，写入按调用顺序串行执行，保证文件中是最新的映射
+func (m *Manager) saveActive() error {
+	path := m.branchesPath()
+	if path == "" {
+		return nil
+	}
+	m.activeSaveMu.Lock()
+	defer m.activeSaveMu.Unlock()
+
+	m.mu.RLock()
+	data, err := json.MarshalIndent(m.active, "", "  ")
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入会话分支失败: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
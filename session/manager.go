@@ -2,9 +2,16 @@ package session
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/cloudwego/eino/schema"
 	"github.com/weibaohui/nanobot-go/config"
 	"github.com/weibaohui/nanobot-go/internal/models"
 	"go.uber.org/zap"
@@ -26,6 +33,22 @@ type Session struct {
 	Messages  []Message `json:"messages"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+
+	Temperature *float64 `json:"temperature,omitempty"` // 会话级温度覆盖，nil 表示使用默认值
+	MaxTokens   int      `json:"maxTokens,omitempty"`   // 会话级最大输出 token 覆盖，0 表示使用默认值
+
+	ShowReasoning *bool `json:"showReasoning,omitempty"` // 会话级是否向用户展示思考过程，nil 表示使用全局配置
+
+	Scratch       map[string]string `json:"scratch,omitempty"`      // 会话级草稿变量，供 Agent 跨轮次保存中间状态
+	Summary       string            `json:"summary,omitempty"`      // 压缩后的早期对话摘要
+	SummaryUntil  time.Time         `json:"summaryUntil,omitempty"` // 摘要覆盖的最后一条对话记录时间
+	ClearedAt     time.Time         `json:"clearedAt,omitempty"`    // 最近一次清空会话的时间
+	scratchLoaded bool              // 是否已从磁盘加载草稿变量
+
+	Pins       []string `json:"pins,omitempty"` // 置顶笔记，始终注入上下文，不受历史裁剪和对话压缩影响
+	pinsLoaded bool     // 是否已从磁盘加载置顶笔记
+
+	stateLoaded bool // 是否已从磁盘加载会话状态（清空时间）
 }
 
 // AddMessage 添加消息到会话
@@ -60,6 +83,9 @@ func (s *Session) Clear() {
 // ConversationRecordRepository 对话记录仓库接口
 type ConversationRecordRepository interface {
 	FindBySessionKey(ctx context.Context, sessionKey string, opts *models.QueryOptions) ([]models.ConversationRecord, error)
+	FindSessionKeysByPrefix(ctx context.Context, prefix string) ([]string, error)
+	CreateBatch(ctx context.Context, records []models.ConversationRecord) error
+	DeleteByID(ctx context.Context, id uint) error
 }
 
 // Manager 会话管理器
@@ -67,18 +93,26 @@ type Manager struct {
 	cfg      *config.Config
 	logger   *zap.Logger
 	cache    map[string]*Session
+	active   map[string]string // 原始会话键 -> 当前使用的分支会话键，持久化到数据目录
 	mu       sync.RWMutex
 	convRepo ConversationRecordRepository
+	dataDir  string // 数据目录，用于持久化会话草稿变量
+
+	activeSaveMu sync.Mutex // 串行化分支映射的写入
 }
 
 // NewManager 创建会话管理器
 func NewManager(cfg *config.Config, logger *zap.Logger, dataDir string, convRepo ConversationRecordRepository) *Manager {
-	return &Manager{
+	m := &Manager{
 		cfg:      cfg,
 		logger:   logger,
 		cache:    make(map[string]*Session),
+		active:   make(map[string]string),
 		convRepo: convRepo,
+		dataDir:  dataDir,
 	}
+	m.loadActive()
+	return m
 }
 
 // GetHistory 从 ConversationRecordRepository 获取会话历史记录
@@ -88,10 +122,10 @@ func (m *Manager) GetHistory(ctx context.Context, sessionKey string, maxMessages
 		return nil
 	}
 
-	// 从数据库查询最近的对话记录
+	// 从数据库查询最近的对话记录（倒序取最新的记录，再按时间升序排列）
 	records, err := m.convRepo.FindBySessionKey(ctx, sessionKey, &models.QueryOptions{
 		OrderBy: "timestamp",
-		Order:   "ASC",
+		Order:   "DESC",
 		Limit:   maxMessages * 2,
 	})
 	if err != nil {
@@ -100,6 +134,13 @@ func (m *Manager) GetHistory(ctx context.Context, sessionKey string, maxMessages
 			zap.Error(err))
 		return nil
 	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	// 已被摘要覆盖或清空之前的记录不再加载
+	summary, summaryUntil := m.Summary(sessionKey)
+	records = recordsAfter(recordsAfter(records, summaryUntil), m.ClearedAt(sessionKey))
 
 	// 筛选出2小时之内的消息
 	cutoffTime := time.Now().Add(-2 * time.Hour)
@@ -115,13 +156,32 @@ func (m *Manager) GetHistory(ctx context.Context, sessionKey string, maxMessages
 		filteredRecords = filteredRecords[len(filteredRecords)-maxMessages:]
 	}
 
-	// 转换为 map 格式
+	// 转换为 map 格式，存在摘要时放在最前面
 	var history []map[string]any
-	for _, record := range filteredRecords {
+	if summary != "" {
 		history = append(history, map[string]any{
+			"role":    "system",
+			"content": summaryPrefix + summary,
+		})
+	}
+	for _, record := range filteredRecords {
+		msg := map[string]any{
 			"role":    record.Role,
 			"content": record.Content,
-		})
+		}
+		if record.ToolCalls != "" {
+			var toolCalls []schema.ToolCall
+			if err := json.Unmarshal([]byte(record.ToolCalls), &toolCalls); err != nil {
+				m.logger.Warn("解析工具调用记录失败", zap.Uint("id", record.ID), zap.Error(err))
+			} else {
+				msg["tool_calls"] = toolCalls
+			}
+		}
+		if record.ToolCallID != "" {
+			msg["tool_call_id"] = record.ToolCallID
+			msg["name"] = record.ToolName
+		}
+		history = append(history, msg)
 	}
 
 	return history
@@ -149,3 +209,282 @@ func (m *Manager) GetOrCreate(key string) *Session {
 
 	return session
 }
+
+// SetTemperature 设置会话级温度覆盖，传 nil 恢复默认
+func (m *Manager) SetTemperature(key string, temperature *float64) {
+	session := m.GetOrCreate(key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session.Temperature = temperature
+	session.UpdatedAt = time.Now()
+}
+
+// SetMaxTokens 设置会话级最大输出 token 覆盖，传 0 恢复默认
+func (m *Manager) SetMaxTokens(key string, maxTokens int) {
+	session := m.GetOrCreate(key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session.MaxTokens = maxTokens
+	session.UpdatedAt = time.Now()
+}
+
+// ModelOverrides 获取会话级模型参数覆盖，会话不存在时返回零值
+func (m *Manager) ModelOverrides(key string) (temperature *float64, maxTokens int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.cache[key]
+	if !ok {
+		return nil, 0
+	}
+	return session.Temperature, session.MaxTokens
+}
+
+// SetShowReasoning 设置会话级是否向用户展示思考过程，传 nil 恢复全局配置
+func (m *Manager) SetShowReasoning(key string, show *bool) {
+	session := m.GetOrCreate(key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session.ShowReasoning = show
+	session.UpdatedAt = time.Now()
+}
+
+// ShowReasoning 返回会话是否向用户展示思考过程，会话不存在或未设置时返回 defaultShow
+func (m *Manager) ShowReasoning(key string, defaultShow bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if session, ok := m.cache[key]; ok && session.ShowReasoning != nil {
+		return *session.ShowReasoning
+	}
+	return defaultShow
+}
+
+// forkSeparator 分支会话键中原始键与分支编号之间的分隔符
+const forkSeparator = "#fork-"
+
+// BaseKey 返回会话键对应的原始会话键（去掉分支后缀）
+func BaseKey(key string) string {
+	if idx := strings.Index(key, forkSeparator); idx >= 0 {
+		return key[:idx]
+	}
+	return key
+}
+
+// ResolveKey 返回原始会话键当前使用的会话键，未切换到分支或 m 为 nil 时原样返回
+func (m *Manager) ResolveKey(key string) string {
+	if m == nil {
+		return key
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if active, ok := m.active[key]; ok {
+		return active
+	}
+	return key
+}
+
+// Fork 复制会话当前使用的分支（消息、对话记录及 token 用量、模型参数覆盖）到新的会话键，并切换到新分支
+// 新分支与原会话相互独立，可通过 Switch 切回；重启前创建的分支（数据库中已有记录）的编号不会被重用
+func (m *Manager) Fork(ctx context.Context, key string) (string, error) {
+	base := BaseKey(key)
+	source := m.ResolveKey(base)
+	src := m.GetOrCreate(source)
+	m.loadScratch(src)
+	m.loadPins(src)
+	m.loadState(src)
+
+	newKey := ""
+	for n := 1; newKey == ""; n++ {
+		candidate := fmt.Sprintf("%s%s%d", base, forkSeparator, n)
+		exists, err := m.branchExists(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			continue
+		}
+		// 先占用会话键，避免并发分支使用相同的键
+		m.mu.Lock()
+		if _, taken := m.cache[candidate]; !taken {
+			newKey = candidate
+			m.cache[newKey] = &Session{Key: newKey}
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	fork := &Session{
+		Key:       newKey,
+		Messages:  append([]Message(nil), src.Messages...),
+		CreatedAt: now,
+		UpdatedAt: now,
+		MaxTokens: src.MaxTokens,
+		Scratch:   copyScratch(src.Scratch),
+		// 对话记录按原时间戳复制，摘要可以直接沿用
+		Summary:      src.Summary,
+		SummaryUntil: src.SummaryUntil,
+		ClearedAt:    src.ClearedAt,
+		Pins:         slices.Clone(src.Pins),
+		// 分支的草稿变量、置顶笔记和状态来自源会话，不再从磁盘加载
+		scratchLoaded: true,
+		pinsLoaded:    true,
+		stateLoaded:   true,
+	}
+	if src.Temperature != nil {
+		temperature := *src.Temperature
+		fork.Temperature = &temperature
+	}
+	if src.ShowReasoning != nil {
+		show := *src.ShowReasoning
+		fork.ShowReasoning = &show
+	}
+	m.cache[newKey] = fork
+	m.mu.Unlock()
+
+	if err := m.copyRecords(ctx, source, newKey); err != nil {
+		m.mu.Lock()
+		delete(m.cache, newKey)
+		m.mu.Unlock()
+		return "", err
+	}
+
+	m.mu.Lock()
+	m.active[base] = newKey
+	m.mu.Unlock()
+	if err := m.saveActive(); err != nil {
+		m.logger.Warn("保存会话分支失败", zap.String("session", base), zap.Error(err))
+	}
+
+	if err := m.saveScratch(newKey, fork.Scratch); err != nil {
+		m.logger.Warn("保存分支草稿变量失败", zap.String("session", newKey), zap.Error(err))
+	}
+	if err := m.savePins(newKey, fork.Pins); err != nil {
+		m.logger.Warn("保存分支置顶笔记失败", zap.String("session", newKey), zap.Error(err))
+	}
+	if !fork.ClearedAt.IsZero() {
+		if err := m.saveState(newKey, persistedState{ClearedAt: fork.ClearedAt}); err != nil {
+			m.logger.Warn("保存分支状态失败", zap.String("session", newKey), zap.Error(err))
+		}
+	}
+	return newKey, nil
+}
+
+// copyRecords 复制对话记录到新会话键，保留 token 用量
+func (m *Manager) copyRecords(ctx context.Context, from, to string) error {
+	if m.convRepo == nil {
+		return nil
+	}
+	records, err := m.convRepo.FindBySessionKey(ctx, from, &models.QueryOptions{OrderBy: "timestamp", Order: "ASC"})
+	if err != nil {
+		return fmt.Errorf("读取对话记录失败: %w", err)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	copied := make([]models.ConversationRecord, len(records))
+	for i, record := range records {
+		record.ID = 0
+		record.SessionKey = to
+		copied[i] = record
+	}
+	if err := m.convRepo.CreateBatch(ctx, copied); err != nil {
+		return fmt.Errorf("复制对话记录失败: %w", err)
+	}
+	return nil
+}
+
+// Switch 将原始会话切换到指定分支，target 为原始会话键时切回主线
+func (m *Manager) Switch(ctx context.Context, key, target string) error {
+	base := BaseKey(key)
+	if BaseKey(target) != base {
+		return fmt.Errorf("会话 %s 不属于 %s", target, base)
+	}
+	if target != base {
+		exists, err := m.branchExists(ctx, target)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("分支不存在: %s", target)
+		}
+	}
+
+	m.mu.Lock()
+	if target == base {
+		delete(m.active, base)
+	} else {
+		m.active[base] = target
+	}
+	m.mu.Unlock()
+	return m.saveActive()
+}
+
+// ListSessions 列出所有会话键（含分支），按字母顺序排列
+func (m *Manager) ListSessions() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.cache))
+	for key := range m.cache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ErrNoUserMessage 会话中没有可回退的用户消息
+var ErrNoUserMessage = errors.New("会话中没有可重试的用户消息")
+
+// RewindLastTurn 删除最后一条用户消息及其之后的所有消息（助手回复、工具调用等），返回被删除的用户消息内容
+// 用于 /retry 和 /edit：调用方随后以原消息或修改后的消息重新发起对话
+// 只回退模型仍能看到的对话，清空或被摘要覆盖之前的记录不受影响
+func (m *Manager) RewindLastTurn(ctx context.Context, key string) (string, error) {
+	content, found := m.rewindMessages(key)
+
+	if m.convRepo != nil {
+		records, err := m.convRepo.FindBySessionKey(ctx, key, &models.QueryOptions{OrderBy: "timestamp", Order: "ASC"})
+		if err != nil {
+			return "", fmt.Errorf("读取对话记录失败: %w", err)
+		}
+		_, summaryUntil := m.Summary(key)
+		records = recordsAfter(recordsAfter(records, summaryUntil), m.ClearedAt(key))
+		content, found = "", false
+		last := -1
+		for i, record := range records {
+			if record.Role == "user" {
+				last = i
+			}
+		}
+		if last >= 0 {
+			content, found = records[last].Content, true
+			for _, record := range records[last:] {
+				if err := m.convRepo.DeleteByID(ctx, record.ID); err != nil {
+					return "", fmt.Errorf("删除对话记录失败: %w", err)
+				}
+			}
+		}
+	}
+
+	if !found {
+		return "", ErrNoUserMessage
+	}
+	return content, nil
+}
+
+// rewindMessages 截断内存中的会话消息到最后一条用户消息之前
+func (m *Manager) rewindMessages(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.cache[key]
+	if !ok {
+		return "", false
+	}
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		if session.Messages[i].Role == "user" {
+			content := session.Messages[i].Content
+			session.Messages = session.Messages[:i]
+			session.UpdatedAt = time.Now()
+			return content, true
+		}
+	}
+	return "", false
+}
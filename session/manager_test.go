@@ -2,9 +2,13 @@ package session
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/cloudwego/eino/schema"
 	"github.com/weibaohui/nanobot-go/config"
 	"github.com/weibaohui/nanobot-go/internal/models"
 	"go.uber.org/zap"
@@ -152,6 +156,32 @@ func (m *mockConvRepo) FindBySessionKey(ctx context.Context, sessionKey string,
 	return result, nil
 }
 
+func (m *mockConvRepo) FindSessionKeysByPrefix(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for _, record := range m.records {
+		if strings.HasPrefix(record.SessionKey, prefix) && !slices.Contains(keys, record.SessionKey) {
+			keys = append(keys, record.SessionKey)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (m *mockConvRepo) CreateBatch(ctx context.Context, records []models.ConversationRecord) error {
+	m.records = append(m.records, records...)
+	return nil
+}
+
+func (m *mockConvRepo) DeleteByID(ctx context.Context, id uint) error {
+	for i, r := range m.records {
+		if r.ID == id {
+			m.records = append(m.records[:i], m.records[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
 // TestManager_GetHistory 测试从仓库获取历史记录
 func TestManager_GetHistory(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -193,6 +223,42 @@ func TestManager_GetHistory(t *testing.T) {
 	}
 }
 
+// TestManager_GetHistory_ToolMessages 测试历史记录保留工具调用与工具结果的结构
+func TestManager_GetHistory_ToolMessages(t *testing.T) {
+	now := time.Now()
+	mockRepo := &mockConvRepo{
+		records: []models.ConversationRecord{
+			{
+				SessionKey: "test-session",
+				Role:       "assistant",
+				ToolCalls:  `[{"id":"call_1","type":"function","function":{"name":"read_file","arguments":"{\"path\":\"a.txt\"}"}}]`,
+				Timestamp:  now.Add(-2 * time.Minute),
+			},
+			{
+				SessionKey: "test-session",
+				Role:       "tool",
+				Content:    "文件内容",
+				ToolCallID: "call_1",
+				ToolName:   "read_file",
+				Timestamp:  now.Add(-1 * time.Minute),
+			},
+		},
+	}
+	manager := NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), mockRepo)
+
+	history := manager.GetHistory(context.Background(), "test-session", 10)
+	if len(history) != 2 {
+		t.Fatalf("历史记录数量 = %d, 期望 2", len(history))
+	}
+	toolCalls, ok := history[0]["tool_calls"].([]schema.ToolCall)
+	if !ok || len(toolCalls) != 1 || toolCalls[0].ID != "call_1" || toolCalls[0].Function.Name != "read_file" {
+		t.Errorf("tool_calls = %#v", history[0]["tool_calls"])
+	}
+	if history[1]["tool_call_id"] != "call_1" || history[1]["name"] != "read_file" {
+		t.Errorf("tool 消息 = %#v", history[1])
+	}
+}
+
 // TestManager_GetHistory_NoRepo 测试没有仓库时返回空历史
 func TestManager_GetHistory_NoRepo(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -242,3 +308,153 @@ func TestManager_GetHistory_FilterByTime(t *testing.T) {
 		t.Errorf("内容 = %v, 期望 '1小时前的消息'", history[0]["content"])
 	}
 }
+
+// TestManager_Fork 测试会话分支
+func TestManager_Fork(t *testing.T) {
+	now := time.Now()
+	repo := &mockConvRepo{
+		records: []models.ConversationRecord{
+			{ID: 1, SessionKey: "cli:default", Role: "user", Content: "你好", Timestamp: now, TotalTokens: 0},
+			{ID: 2, SessionKey: "cli:default", Role: "assistant", Content: "你好！", Timestamp: now, TotalTokens: 42},
+		},
+	}
+	manager := NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), repo)
+	manager.GetOrCreate("cli:default").AddMessage("user", "你好")
+	temperature := 0.2
+	manager.SetTemperature("cli:default", &temperature)
+	show := true
+	manager.SetShowReasoning("cli:default", &show)
+
+	newKey, err := manager.Fork(context.Background(), "cli:default")
+	if err != nil {
+		t.Fatalf("Fork() 返回错误: %v", err)
+	}
+	if newKey != "cli:default#fork-1" {
+		t.Errorf("newKey = %q, 期望 cli:default#fork-1", newKey)
+	}
+	if got := manager.ResolveKey("cli:default"); got != newKey {
+		t.Errorf("ResolveKey() = %q, 期望切换到分支 %q", got, newKey)
+	}
+
+	// 分支独立于原会话
+	fork := manager.GetOrCreate(newKey)
+	fork.AddMessage("user", "分支消息")
+	if len(manager.GetOrCreate("cli:default").Messages) != 1 {
+		t.Error("修改分支不应影响原会话")
+	}
+	if temp, _ := manager.ModelOverrides(newKey); temp == nil || *temp != 0.2 || temp == &temperature {
+		t.Errorf("分支温度 = %v, 期望复制 0.2", temp)
+	}
+	if !manager.ShowReasoning(newKey, false) {
+		t.Error("分支应沿用原会话的思考过程展示设置")
+	}
+
+	// 对话记录及 token 用量被复制
+	copied, _ := repo.FindBySessionKey(context.Background(), newKey, nil)
+	if len(copied) != 2 || copied[1].TotalTokens != 42 || copied[0].ID != 0 {
+		t.Errorf("复制的记录 = %+v", copied)
+	}
+
+	sessions := manager.ListSessions()
+	if len(sessions) != 2 || sessions[1] != newKey {
+		t.Errorf("ListSessions() = %v", sessions)
+	}
+
+	// 从分支再分支时编号递增，切回主线
+	second, _ := manager.Fork(context.Background(), "cli:default")
+	if second != "cli:default#fork-2" {
+		t.Errorf("第二个分支 = %q", second)
+	}
+	if err := manager.Switch(context.Background(), "cli:default", "cli:default"); err != nil || manager.ResolveKey("cli:default") != "cli:default" {
+		t.Errorf("切回主线失败: %v", err)
+	}
+	if err := manager.Switch(context.Background(), "cli:default", "cli:other#fork-1"); err == nil {
+		t.Error("切换到其他会话的分支应返回错误")
+	}
+}
+
+// TestManager_ForkAfterRestart 测试重启后分支编号不重用，当前分支保持不变
+func TestManager_ForkAfterRestart(t *testing.T) {
+	now := time.Now()
+	repo := &mockConvRepo{
+		records: []models.ConversationRecord{
+			{ID: 1, SessionKey: "cli:default", Role: "user", Content: "你好", Timestamp: now},
+		},
+	}
+	dataDir := t.TempDir()
+	manager := NewManager(config.DefaultConfig(), zap.NewNop(), dataDir, repo)
+	first, err := manager.Fork(context.Background(), "cli:default")
+	if err != nil {
+		t.Fatalf("Fork() 返回错误: %v", err)
+	}
+
+	restarted := NewManager(config.DefaultConfig(), zap.NewNop(), dataDir, repo)
+	if got := restarted.ResolveKey("cli:default"); got != first {
+		t.Errorf("重启后 ResolveKey() = %q, 期望 %q", got, first)
+	}
+	second, err := restarted.Fork(context.Background(), "cli:default")
+	if err != nil {
+		t.Fatalf("Fork() 返回错误: %v", err)
+	}
+	if second != "cli:default#fork-2" {
+		t.Errorf("重启后的新分支 = %q, 期望 cli:default#fork-2", second)
+	}
+
+	// 切换到重启前创建的分支并持久化
+	if err := restarted.Switch(context.Background(), "cli:default", first); err != nil {
+		t.Fatalf("Switch() 返回错误: %v", err)
+	}
+	if got := NewManager(config.DefaultConfig(), zap.NewNop(), dataDir, repo).ResolveKey("cli:default"); got != first {
+		t.Errorf("重启后 ResolveKey() = %q, 期望 %q", got, first)
+	}
+
+	// 重启后列出的分支与可切换的分支一致
+	branches, err := NewManager(config.DefaultConfig(), zap.NewNop(), dataDir, repo).ListBranches(context.Background(), "cli:default")
+	if err != nil {
+		t.Fatalf("ListBranches() 返回错误: %v", err)
+	}
+	if strings.Join(branches, ",") != "cli:default,"+first+","+second {
+		t.Errorf("重启后 ListBranches() = %v", branches)
+	}
+}
+
+// TestManager_RewindLastTurn 测试回退最后一轮对话
+func TestManager_RewindLastTurn(t *testing.T) {
+	now := time.Now()
+	repo := &mockConvRepo{
+		records: []models.ConversationRecord{
+			{ID: 1, SessionKey: "k", Role: "user", Content: "第一问", Timestamp: now},
+			{ID: 2, SessionKey: "k", Role: "assistant", Content: "第一答", Timestamp: now.Add(time.Second)},
+			{ID: 3, SessionKey: "k", Role: "user", Content: "第二问", Timestamp: now.Add(2 * time.Second)},
+			{ID: 4, SessionKey: "k", Role: "tool", Content: "web_search()", Timestamp: now.Add(3 * time.Second)},
+			{ID: 5, SessionKey: "k", Role: "assistant", Content: "第二答", Timestamp: now.Add(4 * time.Second)},
+		},
+	}
+	manager := NewManager(config.DefaultConfig(), zap.NewNop(), t.TempDir(), repo)
+
+	content, err := manager.RewindLastTurn(context.Background(), "k")
+	if err != nil {
+		t.Fatalf("RewindLastTurn() 返回错误: %v", err)
+	}
+	if content != "第二问" {
+		t.Errorf("content = %q, 期望 第二问", content)
+	}
+	if len(repo.records) != 2 || repo.records[1].Content != "第一答" {
+		t.Errorf("剩余记录 = %+v", repo.records)
+	}
+
+	if _, err := manager.RewindLastTurn(context.Background(), "empty"); !errors.Is(err, ErrNoUserMessage) {
+		t.Errorf("无消息时 err = %v, 期望 ErrNoUserMessage", err)
+	}
+
+	// 清空会话后不再回退清空之前的对话
+	if _, err := manager.Clear(context.Background(), "k"); err != nil {
+		t.Fatalf("Clear() 返回错误: %v", err)
+	}
+	if _, err := manager.RewindLastTurn(context.Background(), "k"); !errors.Is(err, ErrNoUserMessage) {
+		t.Errorf("清空后 err = %v, 期望 ErrNoUserMessage", err)
+	}
+	if len(repo.records) != 2 {
+		t.Errorf("清空后不应删除记录，剩余记录 = %+v", repo.records)
+	}
+}
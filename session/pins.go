@@ -0,0 +1,176 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// 置顶笔记限制，置顶内容每轮都会注入上下文，需要保持简短
+const (
+	MaxPins     = 20
+	MaxPinChars = 500
+)
+
+// pinnedPrefix 注入上下文的置顶笔记前缀
+const pinnedPrefix = "以下是用户置顶的重要信息，回答时始终参考：\n"
+
+// ErrPinNotFound 指定编号的置顶笔记不存在
+var ErrPinNotFound = errors.New("置顶笔记不存在")
+
+// Pin 添加一条置顶笔记，返回添加后的笔记数量
+// 置顶笔记始终注入上下文，不受历史裁剪和对话压缩影响，修改后立即持久化
+func (m *Manager) Pin(key, text string) (int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, fmt.Errorf("置顶内容不能为空")
+	}
+	if utf8.RuneCountInString(text) > MaxPinChars {
+		return 0, fmt.Errorf("置顶内容过长，最多 %d 个字符", MaxPinChars)
+	}
+	session := m.GetOrCreate(key)
+	m.loadPins(session)
+
+	m.mu.Lock()
+	if len(session.Pins) >= MaxPins {
+		m.mu.Unlock()
+		return 0, fmt.Errorf("置顶笔记数量已达上限 %d", MaxPins)
+	}
+	session.Pins = append(session.Pins, text)
+	session.UpdatedAt = time.Now()
+	snapshot := append([]string(nil), session.Pins...)
+	m.mu.Unlock()
+
+	return len(snapshot), m.savePins(key, snapshot)
+}
+
+// Unpin 删除第 index 条置顶笔记（从 1 开始），返回被删除的内容
+func (m *Manager) Unpin(key string, index int) (string, error) {
+	session := m.GetOrCreate(key)
+	m.loadPins(session)
+
+	m.mu.Lock()
+	if index < 1 || index > len(session.Pins) {
+		m.mu.Unlock()
+		return "", ErrPinNotFound
+	}
+	removed := session.Pins[index-1]
+	session.Pins = slices.Delete(session.Pins, index-1, index)
+	session.UpdatedAt = time.Now()
+	snapshot := append([]string(nil), session.Pins...)
+	m.mu.Unlock()
+
+	return removed, m.savePins(key, snapshot)
+}
+
+// UnpinAll 删除全部置顶笔记，返回删除的数量
+func (m *Manager) UnpinAll(key string) (int, error) {
+	session := m.GetOrCreate(key)
+	m.loadPins(session)
+
+	m.mu.Lock()
+	count := len(session.Pins)
+	session.Pins = nil
+	session.UpdatedAt = time.Now()
+	m.mu.Unlock()
+
+	return count, m.savePins(key, nil)
+}
+
+// Pins 返回会话置顶笔记的副本
+func (m *Manager) Pins(key string) []string {
+	session := m.GetOrCreate(key)
+	m.loadPins(session)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), session.Pins...)
+}
+
+// PinnedContext 返回注入上下文的置顶笔记文本，没有置顶笔记时返回空字符串
+func (m *Manager) PinnedContext(key string) string {
+	pins := m.Pins(key)
+	if len(pins) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(pinnedPrefix)
+	for _, pin := range pins {
+		fmt.Fprintf(&sb, "- %s\n", pin)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// pinsPath 返回会话置顶笔记文件路径，未配置数据目录时返回空字符串
+func (m *Manager) pinsPath(key string) string {
+	if m.dataDir == "" {
+		return ""
+	}
+	return filepath.Join(m.dataDir, "pins", url.PathEscape(key)+".json")
+}
+
+// loadPins 首次访问时从磁盘加载会话置顶笔记
+func (m *Manager) loadPins(session *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session.pinsLoaded {
+		return
+	}
+	session.pinsLoaded = true
+
+	path := m.pinsPath(session.Key)
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) && m.logger != nil {
+			m.logger.Warn("读取会话置顶笔记失败", zap.String("session", session.Key), zap.Error(err))
+		}
+		return
+	}
+	var pins []string
+	if err := json.Unmarshal(data, &pins); err != nil {
+		if m.logger != nil {
+			m.logger.Warn("解析会话置顶笔记失败", zap.String("session", session.Key), zap.Error(err))
+		}
+		return
+	}
+	session.Pins = pins
+}
+
+// savePins 将会话置顶笔记写入磁盘，笔记为空时删除文件
+func (m *Manager) savePins(key string, pins []string) error {
+	path := m.pinsPath(key)
+	if path == "" {
+		return nil
+	}
+	if len(pins) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除置顶笔记文件失败: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建置顶笔记目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入置顶笔记失败: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
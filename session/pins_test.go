@@ -0,0 +1,58 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// TestManager_Pins 测试置顶笔记的增删和持久化
+func TestManager_Pins(t *testing.T) {
+	dataDir := t.TempDir()
+	manager := NewManager(config.DefaultConfig(), zap.NewNop(), dataDir, nil)
+
+	if got := manager.PinnedContext("cli:default"); got != "" {
+		t.Errorf("没有置顶笔记时 PinnedContext() = %q", got)
+	}
+	if _, err := manager.Pin("cli:default", "  "); err == nil {
+		t.Error("空内容应返回错误")
+	}
+	if _, err := manager.Pin("cli:default", strings.Repeat("长", MaxPinChars+1)); err == nil {
+		t.Error("超长内容应返回错误")
+	}
+	for _, text := range []string{"我对花生过敏", "项目使用 Go 1.26", "回复使用简体中文"} {
+		if _, err := manager.Pin("cli:default", text); err != nil {
+			t.Fatalf("Pin(%q) 返回错误: %v", text, err)
+		}
+	}
+	if removed, err := manager.Unpin("cli:default", 2); err != nil || removed != "项目使用 Go 1.26" {
+		t.Errorf("Unpin(2) = (%q, %v)", removed, err)
+	}
+	if _, err := manager.Unpin("cli:default", 3); err != ErrPinNotFound {
+		t.Errorf("Unpin(3) 错误 = %v, 期望 ErrPinNotFound", err)
+	}
+
+	t.Run("重启后从数据目录恢复", func(t *testing.T) {
+		reloaded := NewManager(config.DefaultConfig(), zap.NewNop(), dataDir, nil)
+		want := pinnedPrefix + "- 我对花生过敏\n- 回复使用简体中文"
+		if got := reloaded.PinnedContext("cli:default"); got != want {
+			t.Errorf("PinnedContext() = %q, 期望 %q", got, want)
+		}
+	})
+
+	t.Run("分支继承置顶笔记", func(t *testing.T) {
+		forkKey, err := manager.Fork(t.Context(), "cli:default")
+		if err != nil {
+			t.Fatalf("Fork() 返回错误: %v", err)
+		}
+		if pins := manager.Pins(forkKey); len(pins) != 2 {
+			t.Errorf("分支置顶笔记 = %v, 期望继承 2 条", pins)
+		}
+		manager.UnpinAll(forkKey)
+		if pins := manager.Pins("cli:default"); len(pins) != 2 {
+			t.Error("修改分支置顶笔记不应影响原会话")
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// 草稿变量限制，避免 Agent 把草稿当作大文件存储
+const (
+	MaxScratchEntries    = 100
+	MaxScratchValueChars = 8000
+)
+
+// ScratchSet 设置会话草稿变量，value 为空时删除该变量
+// 修改后立即持久化到数据目录，进程重启后仍可读取
+func (m *Manager) ScratchSet(key, name, value string) error {
+	if utf8.RuneCountInString(value) > MaxScratchValueChars {
+		return fmt.Errorf("草稿变量值过长，最多 %d 个字符", MaxScratchValueChars)
+	}
+	session := m.GetOrCreate(key)
+	m.loadScratch(session)
+
+	m.mu.Lock()
+	if value == "" {
+		delete(session.Scratch, name)
+	} else {
+		if _, exists := session.Scratch[name]; !exists && len(session.Scratch) >= MaxScratchEntries {
+			m.mu.Unlock()
+			return fmt.Errorf("草稿变量数量已达上限 %d", MaxScratchEntries)
+		}
+		if session.Scratch == nil {
+			session.Scratch = make(map[string]string)
+		}
+		session.Scratch[name] = value
+	}
+	session.UpdatedAt = time.Now()
+	snapshot := copyScratch(session.Scratch)
+	m.mu.Unlock()
+
+	return m.saveScratch(key, snapshot)
+}
+
+// ScratchGet 获取会话草稿变量
+func (m *Manager) ScratchGet(key, name string) (string, bool) {
+	session := m.GetOrCreate(key)
+	m.loadScratch(session)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := session.Scratch[name]
+	return value, ok
+}
+
+// ScratchList 返回会话全部草稿变量的副本
+func (m *Manager) ScratchList(key string) map[string]string {
+	session := m.GetOrCreate(key)
+	m.loadScratch(session)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return copyScratch(session.Scratch)
+}
+
+// ScratchNames 返回按字母排序的草稿变量名
+func ScratchNames(scratch map[string]string) []string {
+	names := make([]string, 0, len(scratch))
+	for name := range scratch {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// scratchPath 返回会话草稿变量文件路径，未配置数据目录时返回空字符串
+func (m *Manager) scratchPath(key string) string {
+	if m.dataDir == "" {
+		return ""
+	}
+	return filepath.Join(m.dataDir, "scratch", url.PathEscape(key)+".json")
+}
+
+// loadScratch 首次访问时从磁盘加载会话草稿变量
+func (m *Manager) loadScratch(session *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session.scratchLoaded {
+		return
+	}
+	session.scratchLoaded = true
+
+	path := m.scratchPath(session.Key)
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) && m.logger != nil {
+			m.logger.Warn("读取会话草稿变量失败", zap.String("session", session.Key), zap.Error(err))
+		}
+		return
+	}
+	var scratch map[string]string
+	if err := json.Unmarshal(data, &scratch); err != nil {
+		if m.logger != nil {
+			m.logger.Warn("解析会话草稿变量失败", zap.String("session", session.Key), zap.Error(err))
+		}
+		return
+	}
+	session.Scratch = scratch
+}
+
+// saveScratch 将会话草稿变量写入磁盘，变量为空时删除文件
+func (m *Manager) saveScratch(key string, scratch map[string]string) error {
+	path := m.scratchPath(key)
+	if path == "" {
+		return nil
+	}
+	if len(scratch) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除草稿变量文件失败: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建草稿变量目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(scratch, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入草稿变量失败: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// copyScratch 复制草稿变量
+func copyScratch(scratch map[string]string) map[string]string {
+	if len(scratch) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(scratch))
+	for name, value := range scratch {
+		copied[name] = value
+	}
+	return copied
+}
@@ -0,0 +1,69 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/weibaohui/nanobot-go/config"
+	"go.uber.org/zap"
+)
+
+// TestManager_Scratch 测试会话草稿变量
+func TestManager_Scratch(t *testing.T) {
+	dir := t.TempDir()
+	manager := NewManager(config.DefaultConfig(), zap.NewNop(), dir, nil)
+
+	t.Run("设置读取与删除", func(t *testing.T) {
+		if err := manager.ScratchSet("cli:default", "order_id", "A1024"); err != nil {
+			t.Fatalf("ScratchSet() 错误: %v", err)
+		}
+		if value, ok := manager.ScratchGet("cli:default", "order_id"); !ok || value != "A1024" {
+			t.Errorf("ScratchGet() = %q, %v", value, ok)
+		}
+		if _, ok := manager.ScratchGet("cli:other", "order_id"); ok {
+			t.Error("草稿变量不应跨会话共享")
+		}
+
+		if err := manager.ScratchSet("cli:default", "tmp", "x"); err != nil {
+			t.Fatalf("ScratchSet() 错误: %v", err)
+		}
+		if err := manager.ScratchSet("cli:default", "tmp", ""); err != nil {
+			t.Fatalf("删除草稿变量错误: %v", err)
+		}
+		if _, ok := manager.ScratchGet("cli:default", "tmp"); ok {
+			t.Error("空值应删除变量")
+		}
+	})
+
+	t.Run("重启后从磁盘恢复", func(t *testing.T) {
+		reloaded := NewManager(config.DefaultConfig(), zap.NewNop(), dir, nil)
+		all := reloaded.ScratchList("cli:default")
+		if len(all) != 1 || all["order_id"] != "A1024" {
+			t.Errorf("ScratchList() = %v", all)
+		}
+	})
+
+	t.Run("值过长被拒绝", func(t *testing.T) {
+		err := manager.ScratchSet("cli:default", "big", strings.Repeat("字", MaxScratchValueChars+1))
+		if err == nil {
+			t.Error("超过长度上限应返回错误")
+		}
+	})
+
+	t.Run("分支继承草稿变量且相互独立", func(t *testing.T) {
+		forkKey, err := manager.Fork(context.Background(), "cli:default")
+		if err != nil {
+			t.Fatalf("Fork() 错误: %v", err)
+		}
+		if value, _ := manager.ScratchGet(forkKey, "order_id"); value != "A1024" {
+			t.Errorf("分支草稿变量 = %q, 期望 A1024", value)
+		}
+		if err := manager.ScratchSet(forkKey, "order_id", "B2048"); err != nil {
+			t.Fatalf("ScratchSet() 错误: %v", err)
+		}
+		if value, _ := manager.ScratchGet("cli:default", "order_id"); value != "A1024" {
+			t.Errorf("修改分支不应影响原会话，得到 %q", value)
+		}
+	})
+}
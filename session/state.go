@@ -0,0 +1,85 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// persistedState 会话需要跨重启保留的状态
+type persistedState struct {
+	ClearedAt time.Time `json:"clearedAt"` // 最近一次清空会话的时间
+}
+
+// ClearedAt 返回会话最近一次清空的时间，未清空过时返回零值
+func (m *Manager) ClearedAt(key string) time.Time {
+	session := m.GetOrCreate(key)
+	m.loadState(session)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return session.ClearedAt
+}
+
+// statePath 返回会话状态文件路径，未配置数据目录时返回空字符串
+func (m *Manager) statePath(key string) string {
+	if m.dataDir == "" {
+		return ""
+	}
+	return filepath.Join(m.dataDir, "state", url.PathEscape(key)+".json")
+}
+
+// loadState 首次访问时从磁盘加载会话状态
+func (m *Manager) loadState(session *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session.stateLoaded {
+		return
+	}
+	session.stateLoaded = true
+
+	path := m.statePath(session.Key)
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) && m.logger != nil {
+			m.logger.Warn("读取会话状态失败", zap.String("session", session.Key), zap.Error(err))
+		}
+		return
+	}
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		if m.logger != nil {
+			m.logger.Warn("解析会话状态失败", zap.String("session", session.Key), zap.Error(err))
+		}
+		return
+	}
+	session.ClearedAt = state.ClearedAt
+}
+
+// saveState 将会话状态写入磁盘
+func (m *Manager) saveState(key string, state persistedState) error {
+	path := m.statePath(key)
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建会话状态目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入会话状态失败: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
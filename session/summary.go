@@ -0,0 +1,60 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/internal/models"
+)
+
+// summaryPrefix 注入到历史中的摘要消息前缀
+const summaryPrefix = "以下是之前对话的摘要：\n"
+
+// SetSummary 设置会话摘要，until 之前（含）的对话记录由摘要代替，不再加载到历史中
+func (m *Manager) SetSummary(key, summary string, until time.Time) {
+	session := m.GetOrCreate(key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session.Summary = summary
+	session.SummaryUntil = until
+	session.UpdatedAt = time.Now()
+}
+
+// Summary 返回会话摘要及其覆盖的截止时间，没有摘要时返回空字符串
+func (m *Manager) Summary(key string) (string, time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.cache[key]
+	if !ok {
+		return "", time.Time{}
+	}
+	return session.Summary, session.SummaryUntil
+}
+
+// RecordsSinceSummary 返回摘要之后的全部对话记录（按时间升序），没有摘要时返回全部记录
+func (m *Manager) RecordsSinceSummary(ctx context.Context, key string) ([]models.ConversationRecord, error) {
+	if m.convRepo == nil {
+		return nil, nil
+	}
+	records, err := m.convRepo.FindBySessionKey(ctx, key, &models.QueryOptions{OrderBy: "timestamp", Order: "ASC"})
+	if err != nil {
+		return nil, fmt.Errorf("读取对话记录失败: %w", err)
+	}
+	_, until := m.Summary(key)
+	return recordsAfter(records, until), nil
+}
+
+// recordsAfter 过滤出 until 之后的记录，until 为零值时原样返回
+func recordsAfter(records []models.ConversationRecord, until time.Time) []models.ConversationRecord {
+	if until.IsZero() {
+		return records
+	}
+	var result []models.ConversationRecord
+	for _, record := range records {
+		if record.Timestamp.After(until) {
+			result = append(result, record)
+		}
+	}
+	return result
+}
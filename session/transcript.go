@@ -0,0 +1,135 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/weibaohui/nanobot-go/internal/models"
+	"go.uber.org/zap"
+)
+
+// TranscriptDir 工作区中保存会话记录的目录
+const TranscriptDir = "transcripts"
+
+// maxFilenameChars 由会话键生成的文件名最大长度（字符）
+const maxFilenameChars = 100
+
+// transcriptRoleNames 导出时显示的角色名称，不在其中的角色（工具结果等）不导出
+var transcriptRoleNames = map[string]string{
+	"user":      "用户",
+	"assistant": "助手",
+}
+
+// Clear 清空会话：之前的消息、摘要不再加载到历史中，对话记录仍保留在数据库中；清空时间持久化，重启后仍然生效
+// 启用 sessions.archiveTranscripts 时先把对话记录导出为 Markdown，返回导出的文件路径
+func (m *Manager) Clear(ctx context.Context, key string) (string, error) {
+	var path string
+	if m.cfg != nil && m.cfg.Sessions.ArchiveTranscripts {
+		var err error
+		path, err = m.ArchiveTranscript(ctx, key, filepath.Join(m.cfg.GetWorkspacePath(), TranscriptDir))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	session := m.GetOrCreate(key)
+	m.loadState(session)
+	now := time.Now()
+	m.mu.Lock()
+	session.Clear()
+	session.Summary = ""
+	session.SummaryUntil = now
+	session.ClearedAt = now
+	m.mu.Unlock()
+	if err := m.saveState(key, persistedState{ClearedAt: now}); err != nil {
+		return "", fmt.Errorf("保存清空时间失败: %w", err)
+	}
+
+	m.logger.Info("会话已清空", zap.String("session_key", key), zap.String("transcript", path))
+	return path, nil
+}
+
+// ArchiveTranscript 把会话上次清空以来的对话导出为 Markdown 文件，保存到 dir 目录，返回文件路径
+// 没有可导出的消息时不创建文件，返回空字符串
+func (m *Manager) ArchiveTranscript(ctx context.Context, key, dir string) (string, error) {
+	messages, err := m.transcriptMessages(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建会话记录目录失败: %w", err)
+	}
+	now := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.md", safeFilename(key), now.Format("20060102-150405")))
+	if err := os.WriteFile(path, []byte(ExportMarkdown(key, messages, now)), 0644); err != nil {
+		return "", fmt.Errorf("写入会话记录失败: %w", err)
+	}
+	return path, nil
+}
+
+// transcriptMessages 返回会话上次清空以来的消息，优先读取数据库中的对话记录，未配置数据库时使用内存中的消息
+func (m *Manager) transcriptMessages(ctx context.Context, key string) ([]Message, error) {
+	clearedAt := m.ClearedAt(key)
+	m.mu.RLock()
+	var cached []Message
+	if session, ok := m.cache[key]; ok {
+		cached = append(cached, session.Messages...)
+	}
+	m.mu.RUnlock()
+
+	if m.convRepo == nil {
+		return cached, nil
+	}
+	records, err := m.convRepo.FindBySessionKey(ctx, key, &models.QueryOptions{OrderBy: "timestamp", Order: "ASC"})
+	if err != nil {
+		return nil, fmt.Errorf("读取对话记录失败: %w", err)
+	}
+	messages := make([]Message, 0, len(records))
+	for _, record := range recordsAfter(records, clearedAt) {
+		messages = append(messages, Message{Role: record.Role, Content: record.Content, Timestamp: record.Timestamp})
+	}
+	return messages, nil
+}
+
+// ExportMarkdown 把会话消息导出为 Markdown，只包含用户消息和助手回复
+func ExportMarkdown(key string, messages []Message, exportedAt time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# 会话记录 %s\n\n导出时间: %s\n", key, exportedAt.Format("2006-01-02 15:04:05"))
+	for _, msg := range messages {
+		role, ok := transcriptRoleNames[msg.Role]
+		content := strings.TrimSpace(msg.Content)
+		if !ok || content == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n## %s · %s\n\n%s\n", role, msg.Timestamp.Local().Format("2006-01-02 15:04:05"), content)
+	}
+	return sb.String()
+}
+
+// safeFilename 把会话键转换为可用作文件名的字符串，字母、数字、点、横线以外的字符替换为下划线
+func safeFilename(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.Trim(name, ".")
+	if utf8.RuneCountInString(name) > maxFilenameChars {
+		name = string([]rune(name)[:maxFilenameChars])
+	}
+	if name == "" {
+		return "session"
+	}
+	return name
+}
@@ -0,0 +1,125 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weibaohui/nanobot-go/config"
+	"github.com/weibaohui/nanobot-go/internal/models"
+	"go.uber.org/zap"
+)
+
+// TestSafeFilename 测试会话键转换为文件名
+func TestSafeFilename(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"cli:default", "cli_default"},
+		{"feishu:oc_123#fork-1", "feishu_oc_123_fork-1"},
+		{"../etc/passwd", "_etc_passwd"},
+		{"matrix:!room:例子.org", "matrix__room_例子.org"},
+		{"", "session"},
+	}
+	for _, tt := range tests {
+		if got := safeFilename(tt.key); got != tt.want {
+			t.Errorf("safeFilename(%q) = %q, 期望 %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+// TestExportMarkdown 测试导出 Markdown 只包含用户消息和助手回复
+func TestExportMarkdown(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	md := ExportMarkdown("cli:default", []Message{
+		{Role: "user", Content: "你好", Timestamp: ts},
+		{Role: "assistant", Content: "", Timestamp: ts},
+		{Role: "tool", Content: "工具结果", Timestamp: ts},
+		{Role: "assistant", Content: "你好！", Timestamp: ts},
+	}, ts)
+
+	for _, want := range []string{"# 会话记录 cli:default", "## 用户 · 2026-01-02 03:04:05\n\n你好\n", "## 助手 · 2026-01-02 03:04:05\n\n你好！\n"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("导出结果应包含 %q，实际:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "工具结果") || strings.Count(md, "## 助手") != 1 {
+		t.Errorf("不应导出工具结果和空回复，实际:\n%s", md)
+	}
+}
+
+// TestManager_Clear 测试清空会话及导出会话记录
+func TestManager_Clear(t *testing.T) {
+	now := time.Now()
+	newManager := func(archive bool) (*Manager, string) {
+		workspace := t.TempDir()
+		cfg := config.DefaultConfig()
+		cfg.Agents.Defaults.Workspace = workspace
+		cfg.Sessions.ArchiveTranscripts = archive
+		repo := &mockConvRepo{records: []models.ConversationRecord{
+			{ID: 1, SessionKey: "cli:default", Role: "user", Content: "第一个问题", Timestamp: now.Add(-time.Minute)},
+			{ID: 2, SessionKey: "cli:default", Role: "assistant", Content: "第一个回答", Timestamp: now.Add(-time.Minute)},
+		}}
+		return NewManager(cfg, zap.NewNop(), t.TempDir(), repo), workspace
+	}
+
+	t.Run("启用导出时清空前保存会话记录", func(t *testing.T) {
+		manager, workspace := newManager(true)
+		path, err := manager.Clear(context.Background(), "cli:default")
+		if err != nil {
+			t.Fatalf("Clear() 返回错误: %v", err)
+		}
+		if filepath.Dir(path) != filepath.Join(workspace, TranscriptDir) || !strings.HasPrefix(filepath.Base(path), "cli_default-") {
+			t.Errorf("path = %q, 期望保存在工作区 transcripts 目录", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("读取会话记录失败: %v", err)
+		}
+		if !strings.Contains(string(data), "第一个问题") || !strings.Contains(string(data), "第一个回答") {
+			t.Errorf("会话记录内容不完整:\n%s", data)
+		}
+		if history := manager.GetHistory(context.Background(), "cli:default", 10); len(history) != 0 {
+			t.Errorf("清空后不应加载之前的历史，实际 %d 条", len(history))
+		}
+
+		// 再次清空时没有新的对话，不创建文件
+		path, err = manager.Clear(context.Background(), "cli:default")
+		if err != nil || path != "" {
+			t.Errorf("Clear() = (%q, %v), 期望没有新对话时不导出", path, err)
+		}
+	})
+
+	t.Run("未启用导出时只清空会话", func(t *testing.T) {
+		manager, workspace := newManager(false)
+		path, err := manager.Clear(context.Background(), "cli:default")
+		if err != nil || path != "" {
+			t.Fatalf("Clear() = (%q, %v), 期望不导出", path, err)
+		}
+		if _, err := os.Stat(filepath.Join(workspace, TranscriptDir)); !os.IsNotExist(err) {
+			t.Error("未启用导出时不应创建 transcripts 目录")
+		}
+		if history := manager.GetHistory(context.Background(), "cli:default", 10); len(history) != 0 {
+			t.Errorf("清空后不应加载之前的历史，实际 %d 条", len(history))
+		}
+	})
+
+	t.Run("重启后清空仍然生效", func(t *testing.T) {
+		repo := &mockConvRepo{records: []models.ConversationRecord{
+			{ID: 1, SessionKey: "cli:default", Role: "user", Content: "第一个问题", Timestamp: now.Add(-time.Minute)},
+		}}
+		dataDir := t.TempDir()
+		if _, err := NewManager(config.DefaultConfig(), zap.NewNop(), dataDir, repo).Clear(context.Background(), "cli:default"); err != nil {
+			t.Fatalf("Clear() 返回错误: %v", err)
+		}
+
+		restarted := NewManager(config.DefaultConfig(), zap.NewNop(), dataDir, repo)
+		if history := restarted.GetHistory(context.Background(), "cli:default", 10); len(history) != 0 {
+			t.Errorf("重启后不应加载清空之前的历史，实际 %d 条", len(history))
+		}
+	})
+}
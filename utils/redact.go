@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedMask 脱敏后的替换文本
+const redactedMask = "***"
+
+// minSecretLen 配置密钥的最小长度，过短的值容易误伤普通文本
+const minSecretLen = 6
+
+var (
+	// bearerPattern 匹配 Authorization 头中的 Bearer 令牌
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	// apiKeyPattern 匹配 OpenAI / Anthropic 等以 sk- 开头的密钥，以及 Google 的 AIza 密钥
+	apiKeyPattern = regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{16,}|\bAIza[0-9A-Za-z_\-]{35}`)
+	// keyValuePattern 匹配 api_key=xxx、"token": "xxx" 形式的键值对
+	keyValuePattern = regexp.MustCompile(`(?i)((?:api[_-]?key|access[_-]?token|token|secret|password)["']?\s*[:=]\s*["']?)([^\s"'&,;]+)`)
+)
+
+// Redactor 敏感信息脱敏器
+// 除内置的通用模式外，还会替换通过 AddSecrets 注册的配置密钥
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// NewRedactor 创建脱敏器
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// AddSecrets 注册需要脱敏的密钥原文，空值和过短的值会被忽略
+func (r *Redactor) AddSecrets(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range secrets {
+		s = strings.TrimSpace(s)
+		if len(s) < minSecretLen {
+			continue
+		}
+		r.secrets = append(r.secrets, s)
+	}
+	// 先替换较长的密钥，避免前缀相同时只替换了一部分
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+// Redact 返回脱敏后的文本
+func (r *Redactor) Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	r.mu.RLock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedMask)
+	}
+	r.mu.RUnlock()
+
+	s = bearerPattern.ReplaceAllString(s, "${1}"+redactedMask)
+	s = apiKeyPattern.ReplaceAllString(s, redactedMask)
+	s = keyValuePattern.ReplaceAllString(s, "${1}"+redactedMask)
+	return s
+}
+
+// redactField 对单个日志字段脱敏，处理字符串、错误类型和 zap.Any、zap.Object 等结构化字段
+func (r *Redactor) redactField(f zapcore.Field) zapcore.Field {
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = r.Redact(f.String)
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			f.Interface = []byte(r.Redact(string(b)))
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: r.Redact(err.Error())}
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(interface{ String() string }); ok && s != nil {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: r.Redact(s.String())}
+		}
+	case zapcore.ReflectType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		return r.redactStructured(f)
+	}
+	return f
+}
+
+// redactStructured 将结构化字段编码为 JSON 后脱敏
+// 先整体脱敏以覆盖 "token": "xxx" 这类键值对，再逐个脱敏字符串值以覆盖值中转义过的 JSON 文本
+// 脱敏后的 JSON 无效时以字符串输出；无法编码的字段原样返回，写入时同样无法编码
+func (r *Redactor) redactStructured(f zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	data, err := json.Marshal(enc.Fields[f.Key])
+	if err != nil {
+		return f
+	}
+	redacted := r.Redact(string(data))
+	var value any
+	dec := json.NewDecoder(strings.NewReader(redacted))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redacted}
+	}
+	return zapcore.Field{Key: f.Key, Type: zapcore.ReflectType, Interface: r.redactValue(value)}
+}
+
+// redactValue 递归脱敏 JSON 解码结果中的字符串
+func (r *Redactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.Redact(v)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = r.redactValue(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = r.redactValue(item)
+		}
+	}
+	return v
+}
+
+// redactFields 对字段列表脱敏，返回新的切片
+func (r *Redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = r.redactField(f)
+	}
+	return out
+}
+
+// redactingCore 在写入前对消息和字段脱敏的 zap Core
+type redactingCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+// NewRedactingCore 包装 zap Core，使所有日志在写入前经过脱敏
+func NewRedactingCore(core zapcore.Core, redactor *Redactor) zapcore.Core {
+	return &redactingCore{Core: core, redactor: redactor}
+}
+
+// With 为子 logger 附加字段，字段同样经过脱敏
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redactor.redactFields(fields)), redactor: c.redactor}
+}
+
+// Check 判断是否记录该日志，需要将自身加入以便 Write 时脱敏
+func (c *redactingCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// Write 脱敏后写入日志
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.Redact(entry.Message)
+	return c.Core.Write(entry, c.redactor.redactFields(fields))
+}
@@ -20,10 +20,27 @@ func HasSuffixInsensitive(s, suffix string) bool {
 }
 
 // TruncateString 截断字符串到指定长度（按 Unicode 字符计算），超出部分用 "..." 省略
+// 日志、预览等需要截断的场景都应使用该函数，避免按字节截断破坏多字节字符
 func TruncateString(s string, maxLen int) string {
+	truncated, ok := TruncateRunes(s, maxLen)
+	if !ok {
+		return s
+	}
+	return truncated + "..."
+}
+
+// TruncateRunes 按 Unicode 字符截断字符串，不追加省略号，返回值 ok 表示是否发生了截断
+func TruncateRunes(s string, maxLen int) (string, bool) {
+	if maxLen < 0 {
+		maxLen = 0
+	}
+	// 字节数不超过上限时字符数也一定不超过，无需转换
+	if len(s) <= maxLen {
+		return s, false
+	}
 	runes := []rune(s)
 	if len(runes) <= maxLen {
-		return s
+		return s, false
 	}
-	return string(runes[:maxLen]) + "..."
+	return string(runes[:maxLen]), true
 }
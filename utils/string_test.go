@@ -1,6 +1,10 @@
 package utils
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
 
 // TestContainsInsensitive 测试不区分大小写的包含检查
 func TestContainsInsensitive(t *testing.T) {
@@ -92,6 +96,20 @@ func TestHasSuffixInsensitive(t *testing.T) {
 	}
 }
 
+// TestTruncateRunes 测试按字符截断不产生非法 UTF-8
+func TestTruncateRunes(t *testing.T) {
+	s := strings.Repeat("中文", 50)
+	for n := 0; n <= 100; n++ {
+		result, truncated := TruncateRunes(s, n)
+		if !utf8.ValidString(result) {
+			t.Fatalf("TruncateRunes(%d) 产生了非法 UTF-8: %q", n, result)
+		}
+		if utf8.RuneCountInString(result) != n || truncated != (n < 100) {
+			t.Fatalf("TruncateRunes(%d) = (%d 个字符, %v)", n, utf8.RuneCountInString(result), truncated)
+		}
+	}
+}
+
 // TestTruncateString 测试字符串截断
 func TestTruncateString(t *testing.T) {
 	tests := []struct {
@@ -105,7 +123,10 @@ func TestTruncateString(t *testing.T) {
 		{"需要截断", "hello world", 5, "hello..."},
 		{"空字符串", "", 5, ""},
 		{"中文截断", "你好世界测试", 4, "你好世界..."},
+		{"中文字节数超过但字符数未超过", "你好世界", 4, "你好世界"},
+		{"中英混合在边界处截断", "ab你好cd", 3, "ab你..."},
 		{"maxLen为0", "hello", 0, "..."},
+		{"maxLen为负数", "hello", -1, "..."},
 	}
 
 	for _, tt := range tests {